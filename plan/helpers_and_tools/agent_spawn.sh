@@ -8,11 +8,41 @@ REPO=$(basename "$ROOT")
 PARENT=$(dirname "$ROOT")
 MAX_SUBAGENTS=${MAX_SUBAGENTS:-2}
 LOCK_TIMEOUT=${AGENT_LOCK_TIMEOUT:-7200}  # 2 hours default
+MAINLINE=${AGENT_MAINLINE:-main}  # the repository's mainline branch: main, master, develop...
+BASE_REF=${AGENT_BASE_REF:-$MAINLINE}  # e.g. origin/main when the dashboard fetched first
+OUTPUT_FORMAT=${AGENT_OUTPUT_FORMAT:-}  # json: leave the agent's result for the dashboard
+RUN_ID=${AGENT_RUN_ID:-$(date +%Y%m%dT%H%M%S)-$$}  # names this run's log
+AGENT_LOG_MAX_BYTES=${AGENT_LOG_MAX_BYTES:-10485760}  # rotate the run's log to .1 past this
+SESSION_ID=${AGENT_SESSION_ID:-}  # claude session ID, so the dashboard can find the transcript
+RELAUNCH=${AGENT_RELAUNCH:-}  # fresh or resume when a task goes back to an agent
+RESUME_WORKTREE=${AGENT_RESUME_WORKTREE:-}  # resume: the worktree the earlier session ran in
+RESUME_SESSION=${AGENT_RESUME_SESSION:-}  # resume: the claude session to continue
+FEEDBACK=${AGENT_FEEDBACK:-}  # review comments from the dashboard's change request
 
 # Arguments
 TASK_ID=${1:?usage: $0 TASK_ID "TITLE"}
 TITLE=${2:-""}
 
+# Sandbox: with AGENT_SANDBOX=1 the agent may only write inside its worktree
+# (plus the git metadata commits need and the main task.json). Refuse to
+# launch rather than run unconfined.
+AGENT_SANDBOX=${AGENT_SANDBOX:-0}
+SANDBOX_TOOL=""
+if [[ "$AGENT_SANDBOX" == "1" ]]; then
+    if [[ -n "${AGENT_PRIMARY_CHECKOUT:-}" && "$AGENT_PRIMARY_CHECKOUT" != "$ROOT" ]]; then
+        echo "❌ Sandbox root mismatch: expected $AGENT_PRIMARY_CHECKOUT, found $ROOT"
+        exit 1
+    fi
+    if [[ "$(uname)" == "Darwin" ]] && command -v sandbox-exec >/dev/null 2>&1; then
+        SANDBOX_TOOL="sandbox-exec"
+    elif command -v bwrap >/dev/null 2>&1; then
+        SANDBOX_TOOL="bwrap"
+    else
+        echo "❌ AGENT_SANDBOX is set but neither sandbox-exec nor bwrap is available"
+        exit 1
+    fi
+fi
+
 # Ensure git worktree list is clean
 git -C "$ROOT" worktree prune >/dev/null 2>&1
 
@@ -60,13 +90,89 @@ prepare_worktree() {
     git reset --hard HEAD >/dev/null 2>&1
     git clean -fd >/dev/null 2>&1
     
-    # Just use the current main branch state
-    git checkout --detach main >/dev/null 2>&1
+    # Start from the base ref's current state
+    git checkout --detach "$BASE_REF" >/dev/null 2>&1
     
     # Create task branch
     git checkout -b "task_${task_id}" >/dev/null 2>&1
 }
 
+# Function to run a command confined to the worktree when sandboxing. The
+# repository's hooks and config stay read-only, as do the files that tell git
+# where the worktree's metadata is, so nothing the agent writes runs when git
+# is used there afterwards.
+run_confined() {
+    local worktree_dir="$1"
+    shift
+
+    local admin_dir="$ROOT/.git/worktrees/$(basename "$worktree_dir")"
+    local linked
+    linked=$(sed -n 's/^gitdir: //p' "$worktree_dir/.git" 2>/dev/null || true)
+    if [[ "$(dirname "$linked")" == "$ROOT/.git/worktrees" ]]; then
+        admin_dir="$linked"
+    fi
+    local writable=("$worktree_dir" "$admin_dir" "$ROOT/.git/objects" "$ROOT/.git/refs" "$ROOT/.git/logs")
+    local read_only=("$worktree_dir/.git" "$admin_dir/commondir" "$admin_dir/gitdir" "$admin_dir/config.worktree")
+
+    case "$SANDBOX_TOOL" in
+        sandbox-exec)
+            # Last matching rule wins: deny all writes, allow the roots, then
+            # deny the git pointers inside them again
+            local profile="(version 1)
+(allow default)
+(deny file-write*)
+(allow file-write*"
+            local path
+            for path in "${writable[@]}"; do
+                profile+="
+    (subpath \"$path\")"
+            done
+            profile+="
+    (literal \"$ROOT/plan/task.json\")
+    (subpath \"$HOME/.claude\")
+    (literal \"$HOME/.claude.json\")
+    (subpath \"/private/tmp\")
+    (subpath \"/private/var/folders\")
+    (subpath \"/dev\"))
+(deny file-write*"
+            for path in "${read_only[@]}"; do
+                profile+="
+    (literal \"$path\")"
+            done
+            profile+=")"
+            sandbox-exec -p "$profile" "$@"
+            ;;
+        bwrap)
+            local binds=() path
+            for path in "${writable[@]}" "$ROOT/plan/task.json" "$HOME/.claude" "$HOME/.claude.json"; do
+                [[ -e "$path" ]] && binds+=(--bind "$path" "$path")
+            done
+            # Later binds cover earlier ones
+            for path in "${read_only[@]}"; do
+                [[ -e "$path" ]] && binds+=(--ro-bind "$path" "$path")
+            done
+            bwrap --ro-bind / / --dev /dev --proc /proc --tmpfs /tmp "${binds[@]}" \
+                --chdir "$worktree_dir" --die-with-parent "$@"
+            ;;
+        *)
+            "$@"
+            ;;
+    esac
+}
+
+# Function to append a line of agent output to the run's log, rotating it
+# to a single .1 copy once it passes the size cap
+AGENT_LOG_BYTES=0
+agent_log() {
+    local entry="[$(date '+%Y-%m-%d %H:%M:%S')] $1"
+    AGENT_LOG_BYTES=$((AGENT_LOG_BYTES + ${#entry} + 1))
+    if (( AGENT_LOG_BYTES > AGENT_LOG_MAX_BYTES )); then
+        mv -f "$AGENT_LOG" "$AGENT_LOG.1" 2>/dev/null || true
+        AGENT_LOG_BYTES=$(( ${#entry} + 1 ))
+    fi
+    printf '%s\n' "$entry" >> "$AGENT_LOG"
+}
+
 # Clean up any stale locks first
 echo "Checking for stale locks..."
 stale_cleaned=0
@@ -108,16 +214,29 @@ fi
 WORKTREE_DIR=""
 WORKTREE_NUM=""
 
-# First, try to find an existing available worktree
-for i in $(seq 1 "$MAX_SUBAGENTS"); do
-    dir="$PARENT/${REPO}-subagent$i"
-    if [[ -d "$dir" ]] && is_worktree_available "$dir"; then
-        WORKTREE_DIR="$dir"
-        WORKTREE_NUM="$i"
-        echo "Reusing existing worktree: subagent$i"
-        break
+# A resumed session has to continue in the worktree it started in
+if [[ "$RELAUNCH" == "resume" ]]; then
+    if [[ ! -d "$RESUME_WORKTREE" ]] || ! is_worktree_available "$RESUME_WORKTREE"; then
+        echo "❌ Worktree $RESUME_WORKTREE is not available to resume in"
+        exit 1
     fi
-done
+    WORKTREE_DIR="$RESUME_WORKTREE"
+    WORKTREE_NUM="${RESUME_WORKTREE##*-subagent}"
+    echo "Resuming in worktree: subagent$WORKTREE_NUM"
+fi
+
+# First, try to find an existing available worktree
+if [[ -z "$WORKTREE_DIR" ]]; then
+    for i in $(seq 1 "$MAX_SUBAGENTS"); do
+        dir="$PARENT/${REPO}-subagent$i"
+        if [[ -d "$dir" ]] && is_worktree_available "$dir"; then
+            WORKTREE_DIR="$dir"
+            WORKTREE_NUM="$i"
+            echo "Reusing existing worktree: subagent$i"
+            break
+        fi
+    done
+fi
 
 # If no available worktree found, try to create a new one
 if [[ -z "$WORKTREE_DIR" ]]; then
@@ -137,7 +256,7 @@ if [[ -z "$WORKTREE_DIR" ]]; then
             WORKTREE_DIR="$dir"
             WORKTREE_NUM="$i"
             echo "Creating new worktree: subagent$i"
-            git -C "$ROOT" worktree add --detach "$dir" main >/dev/null 2>&1
+            git -C "$ROOT" worktree add --detach "$dir" "$BASE_REF" >/dev/null 2>&1
             break
         fi
     done
@@ -148,9 +267,14 @@ if [[ -z "$WORKTREE_DIR" ]]; then
     exit 1
 fi
 
-# Prepare the worktree
-echo "Preparing worktree for task #$TASK_ID..."
-prepare_worktree "$WORKTREE_DIR" "$TASK_ID"
+# Prepare the worktree; a resumed agent keeps the branch it already has
+if [[ "$RELAUNCH" == "resume" ]]; then
+    echo "Checking out task_$TASK_ID to resume..."
+    git -C "$WORKTREE_DIR" checkout "task_${TASK_ID}" >/dev/null 2>&1
+else
+    echo "Preparing worktree for task #$TASK_ID..."
+    prepare_worktree "$WORKTREE_DIR" "$TASK_ID"
+fi
 
 # Create the prompt
 PROMPT="Review plan.md and task.json.
@@ -162,6 +286,22 @@ IMPORTANT: When you complete the task:
 3. The task.json status update must be on main branch so the Task Dashboard can see it immediately
 
 Note: You're working in a separate worktree. Your task work goes on task_$TASK_ID branch, but the status update goes to main branch task.json."
+if [[ "$RELAUNCH" == "resume" ]]; then
+    PROMPT="Task #$TASK_ID: $TITLE has been sent back to you. Check task.json and plan.md for feedback, then pick up where you left off on branch task_$TASK_ID.
+
+When you are done, commit to task_$TASK_ID and set task #$TASK_ID back to 'pending_review' in $ROOT/plan/task.json (main branch)."
+fi
+if [[ -n "$FEEDBACK" ]]; then
+    PROMPT="$PROMPT
+
+Address this review feedback:
+$FEEDBACK"
+fi
+if [[ -n "$SANDBOX_TOOL" ]]; then
+    PROMPT="$PROMPT
+
+You are sandboxed: you can only write inside $WORKTREE_DIR, plus $ROOT/plan/task.json for the status update."
+fi
 
 # Launch the agent and capture PID
 (
@@ -182,8 +322,12 @@ EOF
     LOG_DIR="$ROOT/logs"
     LOG_FILE="$LOG_DIR/universal_logs-$(date +%Y-%m-%d).log"
     
-    # Ensure log directory exists
-    mkdir -p "$LOG_DIR"
+    # Each run's full output goes to its own log; the universal log only
+    # records where
+    AGENT_LOG="$LOG_DIR/agents/task_${TASK_ID}_${RUN_ID}.log"
+    
+    # Ensure log directories exist
+    mkdir -p "$LOG_DIR/agents"
     
     # Log start of agent
     echo "[$(date '+%Y-%m-%d %H:%M:%S')] INFO subagent$WORKTREE_NUM: Starting Claude agent for task #$TASK_ID" >> "$LOG_FILE"
@@ -191,17 +335,38 @@ EOF
     # Run Claude (ensure PATH includes common locations)
     export PATH="$PATH:/usr/local/bin:/Users/aplucche/.nvm/versions/node/v20.16.0/bin"
     
+    CLAUDE_FLAGS=(--add-dir "$WORKTREE_DIR" --dangerously-skip-permissions)
+    if [[ -n "$RESUME_SESSION" ]]; then
+        CLAUDE_FLAGS+=(--resume "$RESUME_SESSION")
+    elif [[ -n "$SESSION_ID" ]]; then
+        CLAUDE_FLAGS+=(--session-id "$SESSION_ID")
+    fi
+    
     # Capture all Claude output and redirect to logs with timestamps
     {
-        echo "[$(date '+%Y-%m-%d %H:%M:%S')] INFO subagent$WORKTREE_NUM: Claude agent output begins ---"
-        claude "$PROMPT" --dangerously-skip-permissions 2>&1 | while IFS= read -r line; do
-            echo "[$(date '+%Y-%m-%d %H:%M:%S')] INFO subagent$WORKTREE_NUM: $line"
-        done
+        echo "[$(date '+%Y-%m-%d %H:%M:%S')] INFO subagent$WORKTREE_NUM: Claude agent output begins in $AGENT_LOG ---"
+        if [[ "$OUTPUT_FORMAT" == "json" ]]; then
+            # The result goes to logs/agent_results for the dashboard to
+            # collect; it's renamed into place so it's never read half-written
+            RESULT_DIR="$LOG_DIR/agent_results"
+            RESULT_FILE="$RESULT_DIR/task_${TASK_ID}-$(date +%s).json"
+            mkdir -p "$RESULT_DIR"
+            run_confined "$WORKTREE_DIR" claude -p "$PROMPT" --output-format json "${CLAUDE_FLAGS[@]}" \
+                > "$RESULT_FILE.tmp" 2> >(while IFS= read -r line; do
+                    agent_log "$line"
+                done) || true
+            mv "$RESULT_FILE.tmp" "$RESULT_FILE"
+            echo "[$(date '+%Y-%m-%d %H:%M:%S')] INFO subagent$WORKTREE_NUM: Result saved to $RESULT_FILE"
+        else
+            run_confined "$WORKTREE_DIR" claude "$PROMPT" "${CLAUDE_FLAGS[@]}" 2>&1 | while IFS= read -r line; do
+                agent_log "$line"
+            done
+        fi
         echo "[$(date '+%Y-%m-%d %H:%M:%S')] INFO subagent$WORKTREE_NUM: Claude agent output ends ---"
     } >> "$LOG_FILE"
     
-    # Switch back to detached main to allow branch deletion
-    git checkout --detach main >/dev/null 2>&1
+    # Switch back to the detached mainline to allow branch deletion
+    git checkout --detach "$MAINLINE" >/dev/null 2>&1
     
     # Clean up lock file when done
     rm -f .agent_state
@@ -211,6 +376,7 @@ AGENT_PID=$!
 
 echo "✅ Launched subagent$WORKTREE_NUM → task #$TASK_ID (pid: $AGENT_PID)"
 echo "   Working in: $WORKTREE_DIR"
+echo "   Log: $ROOT/logs/agents/task_${TASK_ID}_${RUN_ID}.log"
 
 # Update the lock file with the actual agent PID
 sleep 0.5  # Give the subshell time to create the file
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// agentDashboardWindow is how far back the dashboard looks for agent runs
+const agentDashboardWindow = 7 * 24 * time.Hour
+
+// agentDashboardRecentRuns is how many runs the dashboard lists
+const agentDashboardRecentRuns = 20
+
+// agentEstimateWindow is how far back duration estimates look for runs
+const agentEstimateWindow = 90 * 24 * time.Hour
+
+// agentEstimateMinSamples is how many similar runs an estimate needs before
+// it is preferred over a broader group
+const agentEstimateMinSamples = 3
+
+// Worktree states on the agent dashboard
+const (
+	WorktreeIdle   = "idle"   // not on a task branch
+	WorktreeBusy   = "busy"   // its task is in doing
+	WorktreeReview = "review" // its task is waiting for review
+	WorktreeStale  = "stale"  // its task is finished or gone
+)
+
+// Agent run outcomes
+const (
+	RunRunning     = "running"
+	RunReview      = "review" // the agent finished and the task moved on
+	RunApproved    = "approved"
+	RunRejected    = "rejected"
+	RunFailed      = "failed"      // the agent never started
+	RunInterrupted = "interrupted" // the agent died, usually in a crash, before handing the task on
+)
+
+// AgentWorktreeState is an agent worktree and the task it holds
+type AgentWorktreeState struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Branch    string `json:"branch,omitempty"`
+	TaskID    int    `json:"taskId,omitempty"`
+	TaskTitle string `json:"taskTitle,omitempty"`
+	State     string `json:"state"`
+}
+
+// AgentRun is one agent launch for a task, from the journal
+type AgentRun struct {
+	RunID           string     `json:"runId,omitempty"` // names the run's log; empty for runs before logs were kept
+	TaskID          int        `json:"taskId"`
+	TaskTitle       string     `json:"taskTitle"`
+	SpawnedTitle    string     `json:"spawnedTitle,omitempty"` // the title the agent was given, whatever it's been edited to since
+	Branch          string     `json:"branch,omitempty"`       // the branch the agent was told to commit to
+	SessionID       string     `json:"sessionId,omitempty"`    // claude's session, for GetAgentTranscript
+	Relaunch        string     `json:"relaunch,omitempty"`     // fresh or resume; empty for a task's first run
+	Priority        string     `json:"priority,omitempty"`     // the task's priority at launch
+	Started         time.Time  `json:"started"`
+	Ended           *time.Time `json:"ended,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds,omitempty"`
+	Outcome         string     `json:"outcome"`
+	Error           string     `json:"error,omitempty"`
+	// Result is what the agent reported on exit, when it ran in JSON
+	// result mode
+	Result *AgentResult `json:"result,omitempty"`
+}
+
+// AgentDashboard is everything the agent sidebar shows for the active
+// repository
+type AgentDashboard struct {
+	Worktrees         []AgentWorktreeState `json:"worktrees"`
+	WorktreeError     string               `json:"worktreeError,omitempty"`
+	Queued            []Task               `json:"queued"` // todo tasks, in the order they should start
+	RecentRuns        []AgentRun           `json:"recentRuns"`
+	Runs              int                  `json:"runs"` // runs in the window, including failed launches
+	FailureRate       float64              `json:"failureRate"`
+	AverageRunSeconds float64              `json:"averageRunSeconds"`
+	Quota             QuotaStatus          `json:"quota"`
+	Since             time.Time            `json:"since"`
+}
+
+// agentWorktreeStates matches worktrees to the tasks their task_N branches
+// belong to
+func agentWorktreeStates(worktrees []GitWorktree, tasks []Task) []AgentWorktreeState {
+	states := make([]AgentWorktreeState, 0, len(worktrees))
+	for _, worktree := range worktrees {
+		state := AgentWorktreeState{
+			Name:   filepath.Base(worktree.Path),
+			Path:   worktree.Path,
+			Branch: worktree.Branch,
+			State:  WorktreeIdle,
+		}
+		if id, ok := taskBranchID(worktree.Branch); ok {
+			state.TaskID = id
+			state.State = WorktreeStale
+			if task, found := findTask(tasks, id); found {
+				state.TaskTitle = task.Title
+				switch task.Status {
+				case StatusDoing:
+					state.State = WorktreeBusy
+				case StatusPendingReview:
+					state.State = WorktreeReview
+				}
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// taskBranch returns the branch an agent works on for a task
+func taskBranch(taskID int) string {
+	return fmt.Sprintf("task_%d", taskID)
+}
+
+// taskBranchID returns the task ID of a task_N branch
+func taskBranchID(branch string) (int, bool) {
+	if !strings.HasPrefix(branch, "task_") {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(branch, "task_"))
+	return id, err == nil
+}
+
+// queuedTasks returns the todo tasks, highest priority first
+func queuedTasks(tasks []Task) []Task {
+	queued := []Task{}
+	for _, task := range tasks {
+		if task.Status == StatusTodo {
+			queued = append(queued, task)
+		}
+	}
+	return sortByPriority(queued)
+}
+
+// sortByPriority sorts tasks highest priority first, then by ID, and
+// returns them
+func sortByPriority(tasks []Task) []Task {
+	rank := map[TaskPriority]int{}
+	for i, priority := range AllPriorities() {
+		rank[priority] = i
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if rank[tasks[i].Priority] != rank[tasks[j].Priority] {
+			return rank[tasks[i].Priority] < rank[tasks[j].Priority]
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+// agentRuns rebuilds agent runs from journal entries in time order. A run
+// starts at a launch and ends when its task leaves doing or is reviewed, or
+// when the agent reports its result. A reported result is trusted over the
+// task's moves: a failed agent fails its run even if the task moved on.
+func agentRuns(entries []JournalEntry, tasks []Task) []AgentRun {
+	runs := []AgentRun{}
+	open := map[int]int{}   // task ID -> index of its running run
+	latest := map[int]int{} // task ID -> index of its newest launch
+	for _, entry := range entries {
+		switch entry.Type {
+		case EventAgentLaunched:
+			open[entry.TaskID] = len(runs)
+			latest[entry.TaskID] = len(runs)
+			run := AgentRun{TaskID: entry.TaskID, Started: entry.Time, Outcome: RunRunning}
+			if priority, ok := entry.Data["priority"].(string); ok {
+				run.Priority = priority
+			}
+			run.RunID, _ = entry.Data["runId"].(string)
+			run.SpawnedTitle, _ = entry.Data["title"].(string)
+			run.Branch, _ = entry.Data["branch"].(string)
+			run.SessionID, _ = entry.Data["sessionId"].(string)
+			run.Relaunch, _ = entry.Data["relaunch"].(string)
+			if run.Branch == "" {
+				// Launches journaled before the branch was recorded
+				run.Branch = taskBranch(entry.TaskID)
+			}
+			runs = append(runs, run)
+		case EventAgentFailed:
+			ended := entry.Time
+			runID, _ := entry.Data["runId"].(string)
+			runs = append(runs, AgentRun{
+				RunID:   runID,
+				TaskID:  entry.TaskID,
+				Started: entry.Time,
+				Ended:   &ended,
+				Outcome: RunFailed,
+				Error:   fmt.Sprint(entry.Data["error"]),
+			})
+		case EventAgentResult:
+			i, ok := latest[entry.TaskID]
+			if !ok || runs[i].Result != nil {
+				continue
+			}
+			result := agentResultFromEntry(entry)
+			runs[i].Result = &result
+			if result.SessionID != "" && (runs[i].SessionID == "" || runs[i].Relaunch == RelaunchResume) {
+				// A resumed session may carry on under a new ID
+				runs[i].SessionID = result.SessionID
+			}
+			if _, running := open[entry.TaskID]; running {
+				ended := entry.Time
+				runs[i].Ended = &ended
+				runs[i].Outcome = RunReview
+				delete(open, entry.TaskID)
+			}
+			if result.DurationSeconds > 0 {
+				runs[i].DurationSeconds = result.DurationSeconds
+			}
+			// A reviewer's verdict stands
+			if !result.Success && runs[i].Outcome == RunReview {
+				runs[i].Outcome = RunFailed
+				runs[i].Error = result.Message
+			}
+		case EventAgentInterrupted:
+			i, ok := open[entry.TaskID]
+			if !ok {
+				continue
+			}
+			ended := entry.Time
+			runs[i].Ended = &ended
+			runs[i].Outcome = RunInterrupted
+			runs[i].Error = "the agent stopped before handing the task on"
+			delete(open, entry.TaskID)
+		case EventTaskMoved, EventTaskApproved, EventTaskRejected:
+			i, ok := open[entry.TaskID]
+			if !ok {
+				continue
+			}
+			outcome := RunReview
+			switch entry.Type {
+			case EventTaskMoved:
+				if fmt.Sprint(entry.Data["from"]) != string(StatusDoing) {
+					continue
+				}
+			case EventTaskApproved:
+				outcome = RunApproved
+			case EventTaskRejected:
+				outcome = RunRejected
+			}
+			ended := entry.Time
+			runs[i].Ended = &ended
+			runs[i].DurationSeconds = ended.Sub(runs[i].Started).Seconds()
+			runs[i].Outcome = outcome
+			delete(open, entry.TaskID)
+		}
+	}
+	for i := range runs {
+		if task, ok := findTask(tasks, runs[i].TaskID); ok {
+			runs[i].TaskTitle = task.Title
+			if runs[i].Priority == "" {
+				runs[i].Priority = string(task.Priority)
+			}
+		}
+	}
+	return runs
+}
+
+// summarizeAgentRuns fills in the dashboard's run statistics. Failed
+// launches, interrupted agents and rejected work count as failures; running
+// agents don't count until they finish. Interrupted runs have no meaningful
+// duration.
+func summarizeAgentRuns(dashboard *AgentDashboard, runs []AgentRun) {
+	dashboard.Runs = len(runs)
+	finished, failed, timed := 0, 0, 0
+	total := 0.0
+	for _, run := range runs {
+		if run.Outcome == RunRunning {
+			continue
+		}
+		finished++
+		if run.Outcome == RunFailed || run.Outcome == RunInterrupted || run.Outcome == RunRejected {
+			failed++
+		}
+		if run.Outcome != RunFailed && run.Outcome != RunInterrupted {
+			timed++
+			total += run.DurationSeconds
+		}
+	}
+	if finished > 0 {
+		dashboard.FailureRate = float64(failed) / float64(finished)
+	}
+	if timed > 0 {
+		dashboard.AverageRunSeconds = total / float64(timed)
+	}
+
+	// Newest first
+	recent := []AgentRun{}
+	for i := len(runs) - 1; i >= 0 && len(recent) < agentDashboardRecentRuns; i-- {
+		recent = append(recent, runs[i])
+	}
+	dashboard.RecentRuns = recent
+}
+
+// Which past runs an AgentDurationEstimate is based on
+const (
+	EstimateByEpic     = "epic"     // runs on tasks under the same top-level task
+	EstimateByPriority = "priority" // runs on tasks of the same priority
+	EstimateByAll      = "all"      // every run
+)
+
+// AgentDurationEstimate is how long an agent is expected to take on a task
+type AgentDurationEstimate struct {
+	TaskID        int     `json:"taskId"`
+	Basis         string  `json:"basis,omitempty"` // empty when there is no history
+	Samples       int     `json:"samples"`
+	MedianSeconds float64 `json:"medianSeconds"`
+	P90Seconds    float64 `json:"p90Seconds"`
+}
+
+// estimateAgentDuration estimates task's run time from the durations of
+// finished runs, using the narrowest group of similar tasks that has enough
+// samples
+func estimateAgentDuration(task Task, tasks []Task, runs []AgentRun) AgentDurationEstimate {
+	estimate := AgentDurationEstimate{TaskID: task.ID}
+	epic := rootTaskID(task, tasks)
+	hasEpic := epic != task.ID
+
+	byEpic, byPriority, all := []time.Duration{}, []time.Duration{}, []time.Duration{}
+	for _, run := range runs {
+		if run.Ended == nil || run.Outcome == RunFailed || run.Outcome == RunInterrupted || run.TaskID == task.ID {
+			continue
+		}
+		duration := run.Ended.Sub(run.Started)
+		all = append(all, duration)
+		if run.Priority == string(task.Priority) {
+			byPriority = append(byPriority, duration)
+		}
+		if past, ok := findTask(tasks, run.TaskID); ok && hasEpic && rootTaskID(past, tasks) == epic {
+			byEpic = append(byEpic, duration)
+		}
+	}
+
+	durations := all
+	estimate.Basis = EstimateByAll
+	if len(byEpic) >= agentEstimateMinSamples {
+		durations, estimate.Basis = byEpic, EstimateByEpic
+	} else if len(byPriority) >= agentEstimateMinSamples {
+		durations, estimate.Basis = byPriority, EstimateByPriority
+	}
+	if len(durations) == 0 {
+		estimate.Basis = ""
+		return estimate
+	}
+	estimate.Samples = len(durations)
+	estimate.MedianSeconds = percentile(durations, 0.5).Seconds()
+	estimate.P90Seconds = percentile(durations, 0.9).Seconds()
+	return estimate
+}
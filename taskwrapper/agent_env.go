@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// agentSecretPrefix marks an agent environment value that names a secret
+// in the keychain rather than holding the value itself
+const agentSecretPrefix = "secret:"
+
+// agentEnvNamePattern matches the variable and secret names agents can be given
+var agentEnvNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedAgentEnv are variables the spawner sets or relies on; letting a
+// task override them would change how its agent is launched
+var reservedAgentEnv = map[string]bool{"PATH": true, "HOME": true, "USER": true}
+
+// reservedAgentEnvPrefixes are the prefixes of the spawner's own variables
+var reservedAgentEnvPrefixes = []string{"TASK_", "AGENT_"}
+
+// AgentEnvironment is what the active repository injects into its agents:
+// variables for every run, variables for one task's runs over those, and
+// the names of the secrets values can refer to as "secret:NAME"
+type AgentEnvironment struct {
+	Repository map[string]string         `json:"repository"`
+	Tasks      map[int]map[string]string `json:"tasks"`
+	Secrets    []string                  `json:"secrets"`
+}
+
+// agentEnv is the environment resolved for one launch
+type agentEnv struct {
+	vars    []string // NAME=value, sorted by name
+	secrets []string // values that came from the keychain
+}
+
+// agentEnvKey carries a launch's injected environment in its context
+type agentEnvKey struct{}
+
+// withAgentEnv gives the agent service the variables to add to one launch
+func withAgentEnv(ctx context.Context, env agentEnv) context.Context {
+	return context.WithValue(ctx, agentEnvKey{}, env)
+}
+
+// agentEnvFrom returns the environment carried by ctx, or none
+func agentEnvFrom(ctx context.Context) agentEnv {
+	if ctx == nil {
+		return agentEnv{}
+	}
+	env, _ := ctx.Value(agentEnvKey{}).(agentEnv)
+	return env
+}
+
+// mask replaces the secret values in s, which the redactor's patterns
+// wouldn't recognize
+func (e agentEnv) mask(s string) string {
+	for _, secret := range e.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redactedText)
+		}
+	}
+	return s
+}
+
+// agentSecretAccount names one of a repository's agent secrets in the keychain
+func agentSecretAccount(repoPath, name string) string {
+	sum := sha256.Sum256([]byte(repoPath))
+	return "env-" + hex.EncodeToString(sum[:8]) + "-" + name
+}
+
+// validateAgentEnv checks names are usable and not the spawner's own, and
+// that every secret referred to has been stored
+func validateAgentEnv(env map[string]string, secrets []string) error {
+	stored := make(map[string]bool, len(secrets))
+	for _, name := range secrets {
+		stored[name] = true
+	}
+	for name, value := range env {
+		if !agentEnvNamePattern.MatchString(name) {
+			return ValidationError("invalid environment variable name", nil).WithContext("name", name)
+		}
+		if reservedAgentEnvName(name) {
+			return ValidationError("environment variable is set by the spawner", nil).WithContext("name", name)
+		}
+		if secret, ok := agentSecretRef(value); ok && !stored[secret] {
+			return NotFoundError("secret not found", nil).WithContext("name", name).WithContext("secret", secret)
+		}
+	}
+	return nil
+}
+
+// reservedAgentEnvName reports whether name is one of the spawner's variables
+func reservedAgentEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	if reservedAgentEnv[upper] {
+		return true
+	}
+	for _, prefix := range reservedAgentEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// agentSecretRef returns the secret a value refers to, if it refers to one
+func agentSecretRef(value string) (string, bool) {
+	if !strings.HasPrefix(value, agentSecretPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, agentSecretPrefix), true
+}
+
+// agentSecretReferenced reports whether any variable in env refers to secret
+func agentSecretReferenced(env map[string]string, secret string) bool {
+	for _, value := range env {
+		if ref, ok := agentSecretRef(value); ok && ref == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAgentEnv merges a task's variables over the repository's and
+// reads the secrets they refer to from the keychain
+func resolveAgentEnv(keys KeyStore, repoPath string, repoEnv, taskEnv map[string]string) (agentEnv, error) {
+	merged := make(map[string]string, len(repoEnv)+len(taskEnv))
+	for name, value := range repoEnv {
+		merged[name] = value
+	}
+	for name, value := range taskEnv {
+		merged[name] = value
+	}
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var env agentEnv
+	for _, name := range names {
+		value := merged[name]
+		if secret, ok := agentSecretRef(value); ok {
+			if keys == nil {
+				return agentEnv{}, NotFoundError("secret not found", nil).WithContext("secret", secret)
+			}
+			stored, err := keys.Get(agentSecretAccount(repoPath, secret))
+			if err != nil {
+				return agentEnv{}, NotFoundError("secret not found", err).WithContext("secret", secret)
+			}
+			value = string(stored)
+			env.secrets = append(env.secrets, value)
+		}
+		env.vars = append(env.vars, name+"="+value)
+	}
+	return env, nil
+}
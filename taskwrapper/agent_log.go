@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// agentLogDefaultTail is how many lines GetAgentLog returns when asked for 0
+const agentLogDefaultTail = 200
+
+// agentRunIDPattern is what a run ID looks like; anything else could escape
+// the agent log directory
+var agentRunIDPattern = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// agentLogPattern matches a run's log, task_<id>_<run ID>.log, and its
+// rotated copy
+var agentLogPattern = regexp.MustCompile(`^task_(\d+)_([0-9A-Za-z-]+)\.log(\.1)?$`)
+
+// AgentLog is the end of one agent run's output
+type AgentLog struct {
+	RunID  string   `json:"runId"`
+	TaskID int      `json:"taskId"`
+	Lines  []string `json:"lines"`
+	// Truncated is set when earlier output exists beyond the lines returned
+	// or was rotated away past the size cap
+	Truncated bool `json:"truncated"`
+}
+
+// agentRunKey carries a launch's run ID in its context
+type agentRunKey struct{}
+
+// newAgentRunID returns a run ID that sorts by launch time
+func newAgentRunID(now time.Time) string {
+	return now.UTC().Format("20060102T150405") + "-" + uuid.NewString()[:8]
+}
+
+// withAgentRunID tells the agent service which run a launch belongs to
+func withAgentRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, agentRunKey{}, runID)
+}
+
+// agentRunIDFrom returns the run ID carried by ctx, or ""
+func agentRunIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	runID, _ := ctx.Value(agentRunKey{}).(string)
+	return runID
+}
+
+// agentLogsDir is where agent_spawn.sh writes each run's output
+func agentLogsDir(repoPath string) string {
+	return filepath.Join(getLogDirectory(repoPath), "agents")
+}
+
+// findAgentLog returns the log of a run and the task it was for
+func findAgentLog(dir, runID string) (string, int, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("task_*_%s.log", runID)))
+	if err != nil {
+		return "", 0, err
+	}
+	for _, path := range matches {
+		if match := agentLogPattern.FindStringSubmatch(filepath.Base(path)); match != nil && match[2] == runID {
+			taskID, _ := strconv.Atoi(match[1])
+			return path, taskID, nil
+		}
+	}
+	return "", 0, os.ErrNotExist
+}
+
+// tailAgentLog returns the last n lines of a run's output, reaching into the
+// rotated copy when the current file is shorter
+func tailAgentLog(path string, n int) ([]string, bool, error) {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	lines := splitLogLines(string(current))
+	rotated, err := os.ReadFile(path + ".1")
+	hasRotated := err == nil
+	if hasRotated && len(lines) < n {
+		lines = append(splitLogLines(string(rotated)), lines...)
+	}
+	truncated := hasRotated
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+		truncated = true
+	}
+	return lines, truncated, nil
+}
+
+// splitLogLines splits output into lines, dropping the final newline
+func splitLogLines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return []string{}
+	}
+	return strings.Split(output, "\n")
+}
+
+// PruneAgentLogs deletes agent run logs past the log retention window
+func (as *AgentService) PruneAgentLogs() (int, error) {
+	as.mu.RLock()
+	dir := agentLogsDir(as.projectRoot)
+	retention := as.logRetention
+	as.mu.RUnlock()
+
+	return pruneAgentLogs(dir, retention, time.Now())
+}
+
+// pruneAgentLogs deletes run logs last written before the retention window
+// and returns how many went
+func pruneAgentLogs(dir string, retention time.Duration, now time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.Add(-retention)
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !agentLogPattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Ways to recover a task whose agent died
+const (
+	RecoverRequeue = "requeue" // free the worktree and put the task back in todo
+	RecoverAdopt   = "adopt"   // keep the worktree and its work, and send the task to review
+	RecoverResume  = "resume"  // continue the dead agent's session in its worktree
+)
+
+// interruptGrace is how long a launch gets to write its lock before a
+// missing agent counts as dead
+const interruptGrace = time.Minute
+
+// agentLock is what agent_spawn.sh writes to a worktree's .agent_state
+type agentLock struct {
+	PID     int
+	TaskID  int
+	Title   string
+	Started time.Time // zero when the lock doesn't say
+}
+
+// InterruptedTask is a task left in doing by an agent that is no longer
+// running, typically after a crash or reboot
+type InterruptedTask struct {
+	TaskID   int       `json:"taskId"`
+	Title    string    `json:"title"`
+	RunID    string    `json:"runId,omitempty"`
+	Started  time.Time `json:"started"`            // when the dead agent was launched
+	Worktree string    `json:"worktree,omitempty"` // the worktree still on the task branch
+	PID      int       `json:"pid,omitempty"`      // the dead agent's pid, from its stale lock
+	Fixes    []string  `json:"fixes"`
+}
+
+// validRecovery reports whether fix is one of the Recover values
+func validRecovery(fix string) bool {
+	return fix == RecoverRequeue || fix == RecoverAdopt || fix == RecoverResume
+}
+
+// readAgentLock parses the lock file of the worktree at path
+func readAgentLock(path string) (agentLock, error) {
+	file, err := os.Open(filepath.Join(path, agentLockFile))
+	if err != nil {
+		return agentLock{}, err
+	}
+	defer file.Close()
+
+	lock := agentLock{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "pid":
+			lock.PID, _ = strconv.Atoi(value)
+		case "task_id":
+			lock.TaskID, _ = strconv.Atoi(value)
+		case "task_title":
+			lock.Title = value
+		case "started":
+			if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+				lock.Started = time.Unix(unix, 0)
+			}
+		}
+	}
+	return lock, scanner.Err()
+}
+
+// processAlive reports whether a process with pid is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// findInterrupted returns the doing tasks whose latest run was interrupted,
+// or is still open although no live agent holds a worktree for it. Runs
+// younger than interruptGrace are left alone. alive reports whether a pid is
+// running; branches are the task branches that still exist.
+func findInterrupted(tasks []Task, runs []AgentRun, worktrees []GitWorktree, branches []string, now time.Time, alive func(int) bool) []InterruptedTask {
+	latest := map[int]AgentRun{}
+	for _, run := range runs {
+		latest[run.TaskID] = run
+	}
+	locks := map[string]agentLock{}
+	for _, worktree := range worktrees {
+		if lock, err := readAgentLock(worktree.Path); err == nil {
+			locks[worktree.Path] = lock
+		}
+	}
+
+	interrupted := []InterruptedTask{}
+	for _, task := range tasks {
+		run, ok := latest[task.ID]
+		if task.Status != StatusDoing || !ok {
+			continue
+		}
+		if run.Outcome != RunInterrupted && (run.Outcome != RunRunning || now.Sub(run.Started) < interruptGrace) {
+			continue
+		}
+		item := InterruptedTask{TaskID: task.ID, Title: task.Title, RunID: run.RunID, Started: run.Started}
+		running := false
+		for _, worktree := range worktrees {
+			lock, locked := locks[worktree.Path]
+			onTask := worktree.Branch == taskBranch(task.ID)
+			if !onTask && (!locked || lock.TaskID != task.ID) {
+				continue
+			}
+			if locked && alive(lock.PID) {
+				running = true
+				break
+			}
+			if onTask {
+				item.Worktree = worktree.Path
+				item.PID = lock.PID
+			}
+		}
+		if running {
+			continue
+		}
+
+		item.Fixes = []string{RecoverRequeue}
+		for _, branch := range branches {
+			if branch == taskBranch(task.ID) {
+				item.Fixes = append(item.Fixes, RecoverAdopt)
+			}
+		}
+		if run.SessionID != "" && item.Worktree != "" {
+			item.Fixes = append(item.Fixes, RecoverResume)
+		}
+		interrupted = append(interrupted, item)
+	}
+	return interrupted
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// How an agent is relaunched on a task that already had one
+const (
+	RelaunchFresh  = "fresh"  // a clean branch from main, in whichever worktree is free
+	RelaunchResume = "resume" // the existing branch and worktree, continuing the earlier session
+)
+
+// claudeProjectSlugPattern matches the characters claude replaces with "-"
+// when naming a working directory's session folder
+var claudeProjectSlugPattern = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// AgentRelaunch is how a launch picks up a task's earlier run; the zero
+// value is a first launch
+type AgentRelaunch struct {
+	Mode      string
+	Worktree  string // resume: where the earlier session ran
+	SessionID string // resume: the claude session to continue
+	Feedback  string // review feedback the agent should address
+}
+
+// agentRelaunchKey carries a launch's relaunch mode in its context
+type agentRelaunchKey struct{}
+
+// validRelaunchMode reports whether mode is one of the relaunch modes
+func validRelaunchMode(mode string) bool {
+	return mode == RelaunchFresh || mode == RelaunchResume
+}
+
+// withAgentRelaunch tells the agent service how to pick up the earlier run
+func withAgentRelaunch(ctx context.Context, relaunch AgentRelaunch) context.Context {
+	return context.WithValue(ctx, agentRelaunchKey{}, relaunch)
+}
+
+// agentRelaunchFrom returns the relaunch carried by ctx, or a first launch
+func agentRelaunchFrom(ctx context.Context) AgentRelaunch {
+	if ctx == nil {
+		return AgentRelaunch{}
+	}
+	relaunch, _ := ctx.Value(agentRelaunchKey{}).(AgentRelaunch)
+	return relaunch
+}
+
+// claudeProjectDir is where claude keeps the sessions started in dir
+func claudeProjectDir(projectsDir, dir string) string {
+	return filepath.Join(projectsDir, claudeProjectSlugPattern.ReplaceAllString(filepath.Clean(dir), "-"))
+}
+
+// sessionWorktree returns the worktree a claude session ran in. claude only
+// resumes a session from the directory that started it.
+func sessionWorktree(projectsDir, sessionID string, worktrees []GitWorktree) (string, bool) {
+	if !sessionIDPattern.MatchString(sessionID) {
+		return "", false
+	}
+	for _, worktree := range worktrees {
+		if _, err := os.Stat(filepath.Join(claudeProjectDir(projectsDir, worktree.Path), sessionID+".jsonl")); err == nil {
+			return worktree.Path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// agentResultMessageLimit caps how much of an agent's final message is
+// journaled
+const agentResultMessageLimit = 2000
+
+// agentResultPattern matches the result files agent_spawn.sh writes in JSON
+// mode: task_<id>-<unix start>.json
+var agentResultPattern = regexp.MustCompile(`^task_(\d+)-\d+\.json$`)
+
+// AgentResult is what an agent run in JSON result mode reported when it
+// exited
+type AgentResult struct {
+	Success         bool    `json:"success"`
+	CostUSD         float64 `json:"costUsd"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Turns           int     `json:"turns,omitempty"`
+	Message         string  `json:"message"`
+	SessionID       string  `json:"sessionId,omitempty"`
+}
+
+// claudeResult is the object `claude -p --output-format json` prints.
+// Older CLIs call the cost cost_usd.
+type claudeResult struct {
+	Type         string   `json:"type"`
+	Subtype      string   `json:"subtype"`
+	IsError      bool     `json:"is_error"`
+	DurationMs   float64  `json:"duration_ms"`
+	NumTurns     int      `json:"num_turns"`
+	Result       string   `json:"result"`
+	SessionID    string   `json:"session_id"`
+	TotalCostUSD *float64 `json:"total_cost_usd"`
+	CostUSD      *float64 `json:"cost_usd"`
+}
+
+// agentResultsDir is where agent_spawn.sh leaves results for the dashboard
+func agentResultsDir(repoPath string) string {
+	return filepath.Join(getLogDirectory(repoPath), "agent_results")
+}
+
+// parseAgentResult reads a claude JSON result
+func parseAgentResult(data []byte) (AgentResult, error) {
+	var raw claudeResult
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return AgentResult{}, fmt.Errorf("invalid agent result: %v", err)
+	}
+	if raw.Type != "result" {
+		return AgentResult{}, fmt.Errorf("invalid agent result: type %q", raw.Type)
+	}
+	result := AgentResult{
+		Success:         !raw.IsError && raw.Subtype == "success",
+		DurationSeconds: raw.DurationMs / 1000,
+		Turns:           raw.NumTurns,
+		Message:         raw.Result,
+		SessionID:       raw.SessionID,
+	}
+	if raw.TotalCostUSD != nil {
+		result.CostUSD = *raw.TotalCostUSD
+	} else if raw.CostUSD != nil {
+		result.CostUSD = *raw.CostUSD
+	}
+	if len(result.Message) > agentResultMessageLimit {
+		result.Message = result.Message[:agentResultMessageLimit]
+	}
+	return result, nil
+}
+
+// pendingAgentResults lists the result files waiting in dir, oldest run
+// first, with the task each belongs to
+func pendingAgentResults(dir string) ([]string, map[string]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []string{}
+	taskIDs := map[string]int{}
+	for _, entry := range entries {
+		match := agentResultPattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		id, _ := strconv.Atoi(match[1])
+		path := filepath.Join(dir, entry.Name())
+		files = append(files, path)
+		taskIDs[path] = id
+	}
+	sort.Strings(files)
+	return files, taskIDs, nil
+}
+
+// CollectResults hands the results agents in JSON result mode left in the
+// logs directory to record, then deletes them. It returns how many were
+// collected; unreadable results are set aside with an .invalid suffix.
+func (as *AgentService) CollectResults(record func(taskID int, result AgentResult)) (int, error) {
+	as.resultsMu.Lock()
+	defer as.resultsMu.Unlock()
+
+	files, taskIDs, err := pendingAgentResults(agentResultsDir(as.GetProjectRoot()))
+	if err != nil {
+		return 0, err
+	}
+	collected := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return collected, err
+		}
+		result, err := parseAgentResult(data)
+		if err != nil {
+			as.logger.ErrorWithFields("Unreadable agent result", err, map[string]interface{}{
+				"file":    file,
+				"task_id": taskIDs[file],
+			})
+			os.Rename(file, file+".invalid")
+			continue
+		}
+		record(taskIDs[file], result)
+		if err := os.Remove(file); err != nil {
+			return collected, err
+		}
+		collected++
+	}
+	return collected, nil
+}
+
+// agentResultData is the journal data recorded for a result
+func agentResultData(result AgentResult) map[string]interface{} {
+	return map[string]interface{}{
+		"success":         result.Success,
+		"costUsd":         result.CostUSD,
+		"durationSeconds": result.DurationSeconds,
+		"turns":           result.Turns,
+		"message":         result.Message,
+		"sessionId":       result.SessionID,
+	}
+}
+
+// agentResultFromEntry reads a result back out of its journal entry
+func agentResultFromEntry(entry JournalEntry) AgentResult {
+	result := AgentResult{}
+	result.Success, _ = entry.Data["success"].(bool)
+	result.CostUSD, _ = entry.Data["costUsd"].(float64)
+	result.DurationSeconds, _ = entry.Data["durationSeconds"].(float64)
+	if turns, ok := entry.Data["turns"].(float64); ok {
+		result.Turns = int(turns)
+	}
+	result.Message, _ = entry.Data["message"].(string)
+	result.SessionID, _ = entry.Data["sessionId"].(string)
+	return result
+}
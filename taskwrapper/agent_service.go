@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -16,21 +16,195 @@ import (
 type AgentService struct {
 	projectRoot   string
 	logger        Logger
+	errorHandler  *ErrorHandler
 	mu            sync.RWMutex
+	spawnMu       sync.Mutex // held from picking a worktree until its lock is written
+	warmMu        sync.Mutex // keeps two warm-ups from creating the same worktree
+	resultsMu     sync.Mutex // keeps an agent result from being collected twice
 	ctx           context.Context
 	pathValidator *PathValidator
+	perf          *PerformanceRecorder
+	runner        ProcessRunner
+	git           GitClient
+	audit         *AuditService
+	redactor      *Redactor
+	sandbox       bool                // confine each agent's writes to its worktree
+	scriptPins    map[string]string   // helper script name -> pinned SHA-256; nil runs any script
+	mainline      string              // how main is updated before a spawn, one of the Mainline modes
+	mainlineName  string              // the branch agents start from and approvals merge into; empty is main
+	approvalMode  string              // where approvals merge, one of the Approve modes
+	signing       *SigningConfig      // how merge commits are signed; nil leaves it to git
+	claude        *ClaudeCapabilities // last probe of the claude CLI
+	logLimit      int64               // bytes of output kept in a run's log before it rotates
+	logRetention  time.Duration       // how long run logs are kept
+	localizer     *Localizer          // locale of agent prompts; nil is DefaultLocale
+	spawner       string              // how agents are launched, one of the Spawner modes
+	maxAgents     int                 // agents running at once; 0 is the spawner's default
 }
 
+// spawnWorktreePrefix starts the line agent_spawn.sh prints with the worktree it chose
+const spawnWorktreePrefix = "Working in:"
+
 // NewAgentService creates a new agent service
 func NewAgentService(projectRoot string, logger Logger) *AgentService {
+	runner := NewExecRunner()
+	return NewAgentServiceWithClients(projectRoot, logger, NewGitClient(runner), runner)
+}
+
+// NewAgentServiceWithClients creates an agent service that runs git and
+// helper scripts through the given implementations
+func NewAgentServiceWithClients(projectRoot string, logger Logger, git GitClient, runner ProcessRunner) *AgentService {
 	securityConfig := DefaultSecurityConfig()
 	return &AgentService{
 		projectRoot:   projectRoot,
 		logger:        logger,
+		errorHandler:  NewErrorHandler(logger),
 		pathValidator: NewPathValidator(securityConfig, logger),
+		perf:          NewPerformanceRecorder(logger),
+		runner:        runner,
+		git:           git,
+		redactor:      DefaultRedactor(),
+		logLimit:      defaultLogMaxSizeMB * 1024 * 1024,
+		logRetention:  defaultLogRetentionDays * 24 * time.Hour,
+	}
+}
+
+// SetPerformanceRecorder sets where git and script latencies are recorded
+func (as *AgentService) SetPerformanceRecorder(perf *PerformanceRecorder) {
+	as.perf = perf
+}
+
+// SetErrorHandler sets the handler used to recover panics while waiting
+// on natively launched agents
+func (as *AgentService) SetErrorHandler(errorHandler *ErrorHandler) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.errorHandler = errorHandler
+}
+
+// SetAuditLog records branch force-deletes into audit
+func (as *AgentService) SetAuditLog(audit *AuditService) {
+	as.audit = audit
+}
+
+// SetRedactor replaces the patterns masked out of spawn output in errors
+func (as *AgentService) SetRedactor(redactor *Redactor) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.redactor = redactor
+}
+
+// SetLocalizer sets the locale of the prompts agents are given
+func (as *AgentService) SetLocalizer(localizer *Localizer) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.localizer = localizer
+}
+
+// SetSandbox turns per-agent write confinement on or off. agent_spawn.sh
+// enforces it with sandbox-exec or bwrap; the service checks the result.
+func (as *AgentService) SetSandbox(enabled bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.sandbox = enabled
+}
+
+// SetScriptChecksums pins the helper scripts the service may execute;
+// nil or empty turns verification off
+func (as *AgentService) SetScriptChecksums(pins map[string]string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.scriptPins = pins
+}
+
+// SetSpawner sets whether agents are launched by agent_spawn.sh or natively
+func (as *AgentService) SetSpawner(mode string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.spawner = mode
+}
+
+// SetMaxAgents caps how many agents run at once, for either spawner; 0
+// leaves it to the spawner
+func (as *AgentService) SetMaxAgents(n int) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.maxAgents = n
+}
+
+// SetMainlineSync sets how main is brought up to date before each spawn
+func (as *AgentService) SetMainlineSync(mode string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.mainline = mode
+}
+
+// SetMainlineBranch sets the branch agents start from and approved tasks
+// merge into; empty uses main
+func (as *AgentService) SetMainlineBranch(branch string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.mainlineName = branch
+}
+
+// SetApprovalMode sets where approving a task merges its branch
+func (as *AgentService) SetApprovalMode(mode string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.approvalMode = mode
+}
+
+// SetSigning sets how the merge commits of approvals are signed; nil leaves
+// it to git's configuration
+func (as *AgentService) SetSigning(signing *SigningConfig) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.signing = signing
+}
+
+// CheckSigning signs a throwaway commit with signing to show it works
+func (as *AgentService) CheckSigning(signing *SigningConfig) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+	
+	return as.git.CheckSigning(context.Background(), projectRoot, signing)
+}
+
+// mainlineBranch returns the repository's mainline branch (must be called
+// with the lock held)
+func (as *AgentService) mainlineBranch() string {
+	if as.mainlineName == "" {
+		return defaultMainlineBranch
+	}
+	return as.mainlineName
+}
+
+// SetLogLimit caps each agent run's log; past it the spawner rotates the
+// log to a single .1 copy
+func (as *AgentService) SetLogLimit(maxBytes int64) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.logLimit = maxBytes
+}
+
+// SetLogRetention keeps agent run logs for days; 0 keeps the default
+func (as *AgentService) SetLogRetention(days int) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.logRetention = defaultLogRetentionDays * 24 * time.Hour
+	if days > 0 {
+		as.logRetention = time.Duration(days) * 24 * time.Hour
 	}
 }
 
+// SetSecurityConfig replaces the policy project roots and scripts are checked against
+func (as *AgentService) SetSecurityConfig(config *SecurityConfig) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.pathValidator = NewPathValidator(config, as.logger)
+}
+
 // SetProjectRoot sets the project root directory
 func (as *AgentService) SetProjectRoot(root string) {
 	as.mu.Lock()
@@ -38,6 +212,13 @@ func (as *AgentService) SetProjectRoot(root string) {
 	as.projectRoot = root
 }
 
+// GetProjectRoot returns the repository agents are launched in
+func (as *AgentService) GetProjectRoot() string {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.projectRoot
+}
+
 // SetContext sets the application context
 func (as *AgentService) SetContext(ctx context.Context) {
 	as.ctx = ctx
@@ -45,80 +226,300 @@ func (as *AgentService) SetContext(ctx context.Context) {
 
 // LaunchClaudeAgent starts a Claude Code agent for the given task
 func (as *AgentService) LaunchClaudeAgent(task Task) error {
+	return as.LaunchClaudeAgentContext(as.ctx, task)
+}
+
+// LaunchClaudeAgentContext starts a Claude Code agent, stopping the spawner if ctx is cancelled
+func (as *AgentService) LaunchClaudeAgentContext(ctx context.Context, task Task) error {
 	as.mu.RLock()
 	projectRoot := as.projectRoot
+	pathValidator := as.pathValidator
+	sandbox := as.sandbox
+	localizer := as.localizer
+	pins := as.scriptPins
+	mainline := as.mainline
+	mainlineBranch := as.mainlineBranch()
+	// Only a probe already made counts; launching never waits on one
+	jsonResults := as.claude != nil && as.claude.Supports(ClaudeFlagOutputFormat)
+	logLimit := as.logLimit
+	spawner := as.spawner
+	maxAgents := as.maxAgents
 	as.mu.RUnlock()
 
 	// Validate project root path
-	validRoot, err := as.pathValidator.ValidatePath(projectRoot)
+	validRoot, err := pathValidator.ValidatePath(projectRoot)
 	if err != nil {
 		return fmt.Errorf("invalid project root: %w", err)
 	}
 
-	// Use the agent_spawn.sh script
-	scriptPath := filepath.Join(validRoot, "plan", "helpers_and_tools", "agent_spawn.sh")
+	// Use the agent_spawn.sh script, unless spawning natively
+	scriptPath := helperScriptPath(validRoot, "agent_spawn.sh")
+	native := spawner == SpawnerNative
+	if spawner == SpawnerAuto {
+		_, err := os.Stat(scriptPath)
+		native = os.IsNotExist(err)
+	}
 	
 	// Validate script path
-	validScript, err := as.pathValidator.ValidateExecutable(scriptPath)
-	if err != nil {
-		return fmt.Errorf("invalid script path: %w", err)
+	validScript := ""
+	if !native {
+		validScript, err = pathValidator.ValidateExecutable(scriptPath)
+		if err != nil {
+			return fmt.Errorf("invalid script path: %w", err)
+		}
+		if err := verifyHelperScript(pins, validScript); err != nil {
+			return err
+		}
 	}
 	
-	// Sanitize task title to prevent command injection
-	sanitizedTitle := as.pathValidator.SanitizeFilename(task.Title)
+	// The title is passed as argv, never through a shell; only control
+	// characters are replaced
+	title := sanitizeArg(task.Title)
 	
 	// Create command with timeout context
-	ctx := as.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	baseRef := mainlineBranch
+	if mainline != MainlineLocal {
+		reportProgress(ctx, -1, "Updating "+mainlineBranch+" from "+mainlineRemote)
+		ref, err := as.syncMainline(ctx, validRoot, mainline, mainlineBranch)
+		if err != nil {
+			// An offline or diverged mainline shouldn't stop the agent; it
+			// starts from the local branch as it would without the step
+			as.logger.ErrorWithFields("Failed to update the mainline before spawning, using the local branch", err, map[string]interface{}{
+				"task_id": task.ID,
+				"mode":    mainline,
+				"branch":  mainlineBranch,
+			})
+		} else {
+			baseRef = ref
+		}
+	}
+	sessionID := agentSessionIDFrom(ctx)
+	relaunch := agentRelaunchFrom(ctx)
+	injected := agentEnvFrom(ctx)
+	runID := agentRunIDFrom(ctx)
+	if runID == "" {
+		runID = newAgentRunID(time.Now())
+	}
+	reportProgress(ctx, -1, "Spawning agent worktree")
 	
 	// Set a reasonable timeout for agent spawning (30 seconds)
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	
-	// Create the command with validated inputs
-	cmd := exec.CommandContext(ctx, validScript, strconv.Itoa(task.ID), sanitizedTitle)
-	cmd.Dir = validRoot
+	if native {
+		as.logger.InfoWithFields("Launching Claude agent for task natively", map[string]interface{}{
+			"task_id":    task.ID,
+			"task_title": task.Title,
+			"run_id":     runID,
+			"work_dir":   projectRoot,
+		})
+		start := time.Now()
+		worktree, err := as.spawnNative(ctx, agentSpawn{
+			Task:      task,
+			Title:     title,
+			Root:      validRoot,
+			BaseRef:   baseRef,
+			Mainline:  mainlineBranch,
+			RunID:     runID,
+			SessionID: sessionID,
+			Relaunch:  relaunch,
+			Env: append([]string{
+				"PATH=/usr/local/bin:/usr/bin:/bin",
+				"HOME=" + os.Getenv("HOME"),
+				"USER=" + os.Getenv("USER"),
+			}, injected.vars...),
+			JSON:      jsonResults,
+			Sandbox:   sandbox,
+			LogLimit:  logLimit,
+			MaxAgents: maxAgents,
+			Localizer: localizer,
+		})
+		as.perf.Record(OpAgentSpawn, time.Since(start), err)
+		if err != nil {
+			as.logger.ErrorWithFields("Failed to launch Claude agent", err, map[string]interface{}{
+				"task_id": task.ID,
+			})
+			return fmt.Errorf("failed to launch agent for task #%d: %w", task.ID, err)
+		}
+		if sandbox {
+			return as.checkConfined(ctx, validRoot, worktree, task.ID)
+		}
+		return nil
+	}
 	
-	// Set restricted environment
-	cmd.Env = []string{
-		"PATH=/usr/local/bin:/usr/bin:/bin",
-		"HOME=" + os.Getenv("HOME"),
-		"USER=" + os.Getenv("USER"),
-		"TASK_ID=" + strconv.Itoa(task.ID),
-		"TASK_TITLE=" + sanitizedTitle,
+	// Create the command with validated inputs and a restricted environment
+	cmd := Command{
+		Name: validScript,
+		Args: []string{strconv.Itoa(task.ID), title},
+		Dir:  validRoot,
+		Env: []string{
+			"PATH=/usr/local/bin:/usr/bin:/bin",
+			"HOME=" + os.Getenv("HOME"),
+			"USER=" + os.Getenv("USER"),
+			"TASK_ID=" + strconv.Itoa(task.ID),
+			"TASK_TITLE=" + title,
+			"AGENT_BASE_REF=" + baseRef,
+			"AGENT_MAINLINE=" + mainlineBranch,
+			"AGENT_RUN_ID=" + runID,
+			"AGENT_LOG_MAX_BYTES=" + strconv.FormatInt(logLimit, 10),
+		},
+	}
+	if sessionID != "" {
+		// Lets the run's transcript be found in claude's session files
+		cmd.Env = append(cmd.Env, "AGENT_SESSION_ID="+sessionID)
+	}
+	if relaunch.Mode != "" {
+		cmd.Env = append(cmd.Env, "AGENT_RELAUNCH="+relaunch.Mode)
+	}
+	if relaunch.Mode == RelaunchResume {
+		cmd.Env = append(cmd.Env, "AGENT_RESUME_WORKTREE="+relaunch.Worktree, "AGENT_RESUME_SESSION="+relaunch.SessionID)
 	}
+	if relaunch.Feedback != "" {
+		cmd.Env = append(cmd.Env, "AGENT_FEEDBACK="+relaunch.Feedback)
+	}
+	if maxAgents > 0 {
+		cmd.Env = append(cmd.Env, "MAX_SUBAGENTS="+strconv.Itoa(maxAgents))
+	}
+	if jsonResults {
+		// The spawner leaves the agent's result in logs/agent_results
+		cmd.Env = append(cmd.Env, "AGENT_OUTPUT_FORMAT=json")
+	}
+	if sandbox {
+		// The spawner refuses to launch if it can't confine the agent
+		cmd.Env = append(cmd.Env, "AGENT_SANDBOX=1", "AGENT_PRIMARY_CHECKOUT="+validRoot)
+	}
+	// Variables configured for this repository and task, for this run only
+	cmd.Env = append(cmd.Env, injected.vars...)
 	
 	// Log the launch
 	as.logger.InfoWithFields("Launching Claude agent for task", map[string]interface{}{
 		"task_id":    task.ID,
 		"task_title": task.Title,
-		"script":     scriptPath,
+		"run_id":     runID,
+		"command":    shellJoin(cmd.Name, cmd.Args...),
 		"work_dir":   projectRoot,
 	})
 	
 	// Capture output for logging
-	output, err := cmd.CombinedOutput()
+	start := time.Now()
+	output, err := as.runner.CombinedOutput(ctx, cmd)
+	as.perf.Record(OpAgentSpawn, time.Since(start), err)
 	if err != nil {
 		as.logger.ErrorWithFields("Failed to launch Claude agent", err, map[string]interface{}{
 			"task_id": task.ID,
-			"output":  string(output),
+			"output":  injected.mask(string(output)),
 		})
-		return fmt.Errorf("failed to launch agent for task #%d: %v - %s", task.ID, err, string(output))
+		// The error is journaled and audited, so keep secrets out of it
+		as.mu.RLock()
+		redacted := as.redactor.Redact(injected.mask(string(output)))
+		as.mu.RUnlock()
+		return fmt.Errorf("failed to launch agent for task #%d: %v - %s", task.ID, err, redacted)
 	}
 	
 	as.logger.InfoWithFields("Agent spawner completed", map[string]interface{}{
 		"task_id": task.ID,
-		"output":  string(output),
+		"output":  injected.mask(string(output)),
 	})
 	
+	if sandbox {
+		return as.checkConfined(ctx, validRoot, parseSpawnWorktree(string(output)), task.ID)
+	}
+	
+	return nil
+}
+
+// checkConfined verifies a sandboxed agent's worktree, logging the outcome
+func (as *AgentService) checkConfined(ctx context.Context, projectRoot, worktree string, taskID int) error {
+	if err := as.verifyAgentWorktree(ctx, projectRoot, worktree, taskID); err != nil {
+		as.logger.ErrorWithFields("Agent worktree failed verification", err, map[string]interface{}{
+			"task_id":  taskID,
+			"worktree": worktree,
+		})
+		return fmt.Errorf("agent for task #%d is not confined to its worktree: %w", taskID, err)
+	}
+	as.logger.InfoWithFields("Agent confined to worktree", map[string]interface{}{
+		"task_id":  taskID,
+		"worktree": worktree,
+	})
 	return nil
 }
 
+// syncMainline fetches origin and, depending on mode, fast-forwards the local
+// mainline branch or picks its origin counterpart. It returns the ref the
+// agent's worktree should start from.
+func (as *AgentService) syncMainline(ctx context.Context, projectRoot, mode, branch string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, mainlineSyncTimeout)
+	defer cancel()
+	
+	start := time.Now()
+	err := as.git.Fetch(ctx, projectRoot, mainlineRemote)
+	as.perf.Record(OpGitFetch, time.Since(start), err)
+	if err != nil {
+		return "", err
+	}
+	upstream := mainlineRemote + "/" + branch
+	if mode == MainlineOrigin {
+		return upstream, nil
+	}
+	if err := as.git.FastForward(ctx, projectRoot, branch, upstream); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// parseSpawnWorktree returns the worktree agent_spawn.sh reported, or ""
+func parseSpawnWorktree(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, spawnWorktreePrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, spawnWorktreePrefix))
+		}
+	}
+	return ""
+}
+
+// verifyAgentWorktree checks that worktree, the agent's only writable root, is
+// a linked worktree on the task's branch and not the primary checkout or
+// anywhere inside it
+func (as *AgentService) verifyAgentWorktree(ctx context.Context, projectRoot, worktree string, taskID int) error {
+	if worktree == "" {
+		return fmt.Errorf("spawner did not report the agent's worktree")
+	}
+	worktree = filepath.Clean(worktree)
+	rel, err := filepath.Rel(projectRoot, worktree)
+	outside := err == nil && (rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+	if !outside {
+		return fmt.Errorf("worktree %s is inside the primary checkout", worktree)
+	}
+
+	worktrees, err := as.git.ListWorktrees(ctx, projectRoot)
+	if err != nil {
+		return fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	branchName := taskBranch(taskID)
+	for _, wt := range worktrees {
+		if filepath.Clean(wt.Path) != worktree {
+			continue
+		}
+		if wt.Branch != branchName {
+			return fmt.Errorf("worktree %s is on %q, not %s", worktree, wt.Branch, branchName)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is not a worktree of %s", worktree, projectRoot)
+}
+
 // ApproveTask merges the task branch and marks task as approved
 func (as *AgentService) ApproveTask(taskID int, taskTitle string) error {
-	branchName := fmt.Sprintf("task_%d", taskID)
+	return as.ApproveTaskContext(as.ctx, taskID, taskTitle)
+}
+
+// ApproveTaskContext merges the task branch, aborting the merge if ctx is cancelled
+func (as *AgentService) ApproveTaskContext(ctx context.Context, taskID int, taskTitle string) error {
+	branchName := taskBranch(taskID)
 	
 	as.logger.InfoWithFields("Approving task", map[string]interface{}{
 		"task_id": taskID,
@@ -126,16 +527,33 @@ func (as *AgentService) ApproveTask(taskID int, taskTitle string) error {
 	})
 	
 	// Check if branch exists
+	reportProgress(ctx, 0.1, "Checking branch "+branchName)
 	if err := as.checkBranchExists(branchName); err != nil {
 		return fmt.Errorf("branch validation failed: %v", err)
 	}
+	if err := jobCancelled(ctx); err != nil {
+		return err
+	}
+	
+	// A merge that fails to sign is left half done, so find out first
+	as.mu.RLock()
+	signing := as.signing
+	as.mu.RUnlock()
+	if signing != nil {
+		reportProgress(ctx, 0.2, "Checking commit signing")
+		if err := as.CheckSigning(signing); err != nil {
+			return fmt.Errorf("commit signing check failed: %v", err)
+		}
+	}
 	
 	// Merge the branch
-	if err := as.mergeBranch(branchName, taskID, taskTitle); err != nil {
+	reportProgress(ctx, 0.3, "Merging "+branchName)
+	if err := as.mergeBranch(ctx, branchName, taskID, taskTitle); err != nil {
 		return fmt.Errorf("merge failed: %v", err)
 	}
 	
 	// Delete the branch after successful merge
+	reportProgress(ctx, 0.9, "Deleting branch "+branchName)
 	if err := as.deleteBranch(branchName); err != nil {
 		as.logger.InfoWithFields("Warning: Failed to delete branch", map[string]interface{}{
 			"branch": branchName,
@@ -153,7 +571,7 @@ func (as *AgentService) ApproveTask(taskID int, taskTitle string) error {
 
 // RejectTask deletes the task branch and marks task as rejected
 func (as *AgentService) RejectTask(taskID int, taskTitle string) error {
-	branchName := fmt.Sprintf("task_%d", taskID)
+	branchName := taskBranch(taskID)
 	
 	as.logger.InfoWithFields("Rejecting task", map[string]interface{}{
 		"task_id": taskID,
@@ -176,26 +594,59 @@ func (as *AgentService) RejectTask(taskID int, taskTitle string) error {
 	return nil
 }
 
-// GetAgentStatus returns the current status of all subagents
+// GetAgentStatus returns the current status of all subagents, read from
+// git and the worktrees' lock files. agent_status.sh is run instead when
+// the repository spawns with the script, or when git can't be read.
 func (as *AgentService) GetAgentStatus() (AgentStatusInfo, error) {
 	as.mu.RLock()
 	projectRoot := as.projectRoot
+	spawner := as.spawner
+	maxAgents := as.maxAgents
 	as.mu.RUnlock()
 
-	scriptPath := filepath.Join(projectRoot, "plan", "helpers_and_tools", "agent_status.sh")
-	
-	cmd := exec.Command(scriptPath)
-	cmd.Dir = projectRoot
+	if spawner != SpawnerScript {
+		start := time.Now()
+		info, err := as.inspectAgentStatus(projectRoot, maxAgents)
+		as.perf.Record(OpAgentStatus, time.Since(start), err)
+		if err == nil {
+			return info, nil
+		}
+		if _, statErr := os.Stat(helperScriptPath(projectRoot, "agent_status.sh")); statErr != nil {
+			as.logger.Error("Failed to get agent status", err)
+			return AgentStatusInfo{}, err
+		}
+		as.logger.Error("Failed to inspect agent worktrees, falling back to agent_status.sh", err)
+	}
+	return as.scriptAgentStatus()
+}
+
+// scriptAgentStatus runs agent_status.sh and parses what it prints
+func (as *AgentService) scriptAgentStatus() (AgentStatusInfo, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	pins := as.scriptPins
+	maxAgents := as.maxAgents
+	as.mu.RUnlock()
+
+	scriptPath := helperScriptPath(projectRoot, "agent_status.sh")
+	if err := verifyHelperScript(pins, scriptPath); err != nil {
+		return AgentStatusInfo{}, err
+	}
 	
-	// Add context cancellation if available
-	if as.ctx != nil {
-		ctx, cancel := context.WithTimeout(as.ctx, 10*time.Second)
-		defer cancel()
-		cmd = exec.CommandContext(ctx, scriptPath)
-		cmd.Dir = projectRoot
+	ctx := as.ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 	
-	output, err := cmd.Output()
+	cmd := Command{Name: scriptPath, Dir: projectRoot}
+	if maxAgents > 0 {
+		cmd.Env = append(os.Environ(), "MAX_SUBAGENTS="+strconv.Itoa(maxAgents))
+	}
+	start := time.Now()
+	output, err := as.runner.Output(ctx, cmd)
+	as.perf.Record(OpAgentStatus, time.Since(start), err)
 	if err != nil {
 		as.logger.Error("Failed to get agent status", err)
 		return AgentStatusInfo{}, fmt.Errorf("failed to run agent_status.sh: %v", err)
@@ -204,97 +655,582 @@ func (as *AgentService) GetAgentStatus() (AgentStatusInfo, error) {
 	return as.parseAgentStatus(string(output)), nil
 }
 
-// Private helper methods
+// ClaudeCapabilities probes the installed claude CLI for its version and
+// flags. The result is cached for claudeProbeTTL unless refresh is set.
+func (as *AgentService) ClaudeCapabilities(refresh bool) ClaudeCapabilities {
+	as.mu.RLock()
+	cached := as.claude
+	as.mu.RUnlock()
+	if cached != nil && !refresh && time.Since(cached.ProbedAt) < claudeProbeTTL {
+		return *cached
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	caps := ClaudeCapabilities{}
+	version, err := as.runner.Output(ctx, Command{Name: "claude", Args: []string{"--version"}})
+	if err != nil {
+		caps.Error = err.Error()
+	} else {
+		// Without --help the version is still worth recording
+		help, _ := as.runner.Output(ctx, Command{Name: "claude", Args: []string{"--help"}})
+		caps = parseClaudeCapabilities(string(version), string(help))
+	}
+	caps.ProbedAt = time.Now()
+	
+	as.mu.Lock()
+	as.claude = &caps
+	as.mu.Unlock()
+	as.logger.InfoWithFields("Probed claude CLI", map[string]interface{}{
+		"installed": caps.Installed,
+		"version":   caps.Version,
+		"flags":     caps.Flags,
+	})
+	return caps
+}
 
-func (as *AgentService) checkBranchExists(branchName string) error {
+// FindTaskWorktree returns the worktree directory that has the task's branch checked out
+func (as *AgentService) FindTaskWorktree(taskID int) (string, error) {
 	as.mu.RLock()
 	projectRoot := as.projectRoot
 	as.mu.RUnlock()
 
-	cmd := exec.Command("git", "branch", "--list", branchName)
-	cmd.Dir = projectRoot
-	
-	output, err := cmd.CombinedOutput()
+	worktrees, err := as.git.ListWorktrees(context.Background(), projectRoot)
 	if err != nil {
-		return fmt.Errorf("git branch check failed: %v", err)
+		return "", err
 	}
 	
-	if len(strings.TrimSpace(string(output))) == 0 {
-		return fmt.Errorf("branch %s not found", branchName)
+	branchName := taskBranch(taskID)
+	for _, worktree := range worktrees {
+		if worktree.Branch == branchName {
+			return worktree.Path, nil
+		}
 	}
 	
+	return "", fmt.Errorf("no worktree found for task #%d", taskID)
+}
+
+// RunCheck runs a shell command in dir, returning its combined output
+func (as *AgentService) RunCheck(dir, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reviewCheckTimeout)
+	defer cancel()
+	output, err := as.runner.CombinedOutput(ctx, Command{Name: "sh", Args: []string{"-c", command}, Dir: dir})
+	return string(output), err
+}
+
+// StopAgent terminates an agent started by agent_spawn.sh: the processes
+// under its subshell, claude among them, then the subshell itself
+func (as *AgentService) StopAgent(pid int) error {
+	// pkill fails when the agent has no children left, which is fine
+	as.runner.CombinedOutput(context.Background(), Command{Name: "pkill", Args: []string{"-TERM", "-P", strconv.Itoa(pid)}})
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to stop agent %d: %v", pid, err)
+	}
 	return nil
 }
 
-func (as *AgentService) mergeBranch(branchName string, taskID int, taskTitle string) error {
+// ListWorktrees returns the agents' worktrees, leaving out the primary checkout
+func (as *AgentService) ListWorktrees() ([]GitWorktree, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+
+	worktrees, err := as.git.ListWorktrees(context.Background(), projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	agents := []GitWorktree{}
+	for _, worktree := range worktrees {
+		if filepath.Clean(worktree.Path) != filepath.Clean(projectRoot) {
+			agents = append(agents, worktree)
+		}
+	}
+	return agents, nil
+}
+
+// ListTaskBranches returns the local task_* branches
+func (as *AgentService) ListTaskBranches() ([]string, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+	
+	return as.git.ListBranches(context.Background(), projectRoot, "task_*")
+}
+
+// BranchExists reports whether the repository has a local branch
+func (as *AgentService) BranchExists(branch string) (bool, error) {
 	as.mu.RLock()
 	projectRoot := as.projectRoot
 	as.mu.RUnlock()
+	
+	return as.git.BranchExists(context.Background(), projectRoot, branch)
+}
 
-	mergeCmd := exec.Command("git", "merge", branchName, "--no-ff", "-m", 
-		fmt.Sprintf("Merge task #%d: %s", taskID, taskTitle))
-	mergeCmd.Dir = projectRoot
+// ListTaskMerges returns the merge commits on the mainline since sinceTag,
+// or since the newest tag when it's empty, with the tag used; "" means the
+// whole history
+func (as *AgentService) ListTaskMerges(sinceTag string) ([]GitCommit, string, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
 	
-	// Add context cancellation if available
-	if as.ctx != nil {
-		ctx, cancel := context.WithTimeout(as.ctx, 30*time.Second)
-		defer cancel()
-		mergeCmd = exec.CommandContext(ctx, "git", "merge", branchName, "--no-ff", "-m", 
-			fmt.Sprintf("Merge task #%d: %s", taskID, taskTitle))
-		mergeCmd.Dir = projectRoot
+	ctx := context.Background()
+	if sinceTag == "" {
+		tag, err := as.git.LatestTag(ctx, projectRoot, mainline)
+		if err != nil {
+			return nil, "", err
+		}
+		sinceTag = tag
+	}
+	revRange := mainline
+	if sinceTag != "" {
+		revRange = sinceTag + ".." + mainline
 	}
+	commits, err := as.git.ListMerges(ctx, projectRoot, revRange)
+	return commits, sinceTag, err
+}
+
+// CommitToMainline commits one file of the primary checkout, which must have
+// the mainline checked out, signed like merges are
+func (as *AgentService) CommitToMainline(path, message string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	signing := as.signing
+	as.mu.RUnlock()
 	
-	output, err := mergeCmd.CombinedOutput()
+	ctx := context.Background()
+	current, err := as.git.CurrentBranch(ctx, projectRoot)
 	if err != nil {
-		as.logger.ErrorWithFields("Git merge failed", err, map[string]interface{}{
-			"branch": branchName,
-			"output": string(output),
-		})
-		return fmt.Errorf("git merge failed: %v - %s", err, string(output))
+		return err
+	}
+	if current != mainline {
+		return fmt.Errorf("the checkout is on %q, not %s", current, mainline)
 	}
+	return as.git.CommitFile(ctx, projectRoot, path, message, signing)
+}
+
+// MainlineMerges returns every merge commit on the mainline, newest first
+func (as *AgentService) MainlineMerges() ([]GitCommit, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
 	
-	return nil
+	return as.git.ListMerges(context.Background(), projectRoot, mainline)
 }
 
-func (as *AgentService) deleteBranch(branchName string) error {
+// TagMainline puts an annotated tag on the mainline's head, signed like
+// merges are, and reports whether it was signed. An existing tag is never
+// moved.
+func (as *AgentService) TagMainline(tag, message string) (bool, error) {
 	as.mu.RLock()
 	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	signing := as.signing
 	as.mu.RUnlock()
+	
+	ctx := context.Background()
+	exists, err := as.git.TagExists(ctx, projectRoot, tag)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, fmt.Errorf("tag %s already exists", tag)
+	}
+	if err := as.git.CreateTag(ctx, projectRoot, tag, mainline, message, signing); err != nil {
+		return false, err
+	}
+	return signing != nil, nil
+}
+
+// TaskBranchDiff returns the patch of a task branch against the mainline,
+// limited to paths when any are given, and the mainline's name
+func (as *AgentService) TaskBranchDiff(taskID int, paths ...string) (string, string, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
+	
+	patch, err := as.git.Diff(context.Background(), projectRoot, mainline+"..."+taskBranch(taskID), paths...)
+	return patch, mainline, err
+}
+
+// TaskBranchFiles returns the files a task branch changed, with their line
+// counts, and the mainline's name
+func (as *AgentService) TaskBranchFiles(taskID int) ([]DiffFileSummary, string, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
+	
+	output, err := as.git.DiffSummary(context.Background(), projectRoot, mainline+"..."+taskBranch(taskID))
+	if err != nil {
+		return nil, mainline, err
+	}
+	return parseDiffSummary(output), mainline, nil
+}
 
-	cmd := exec.Command("git", "branch", "-d", branchName)
-	cmd.Dir = projectRoot
+// CompareBranches sets two branches side by side: the commits each has
+// that the other lacks, what each changed since forking from the mainline,
+// and the diff from a's tip to b's
+func (as *AgentService) CompareBranches(a, b string) (BranchComparison, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
 	
-	output, err := cmd.CombinedOutput()
+	ctx := context.Background()
+	sides := []BranchSide{}
+	changed := [][]DiffFileSummary{}
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		commits, err := as.git.ListCommits(ctx, projectRoot, pair[1]+".."+pair[0])
+		if err != nil {
+			return BranchComparison{}, err
+		}
+		if commits == nil {
+			commits = []GitCommit{}
+		}
+		summary, err := as.git.DiffSummary(ctx, projectRoot, mainline+"..."+pair[0])
+		if err != nil {
+			return BranchComparison{}, err
+		}
+		files := parseDiffSummary(summary)
+		sides = append(sides, branchSide(pair[0], commits, files))
+		changed = append(changed, files)
+	}
+	
+	between, err := as.git.DiffSummary(ctx, projectRoot, a+".."+b)
+	if err != nil {
+		return BranchComparison{}, err
+	}
+	patch, err := as.git.Diff(ctx, projectRoot, a+".."+b)
 	if err != nil {
-		return fmt.Errorf("git branch delete failed: %v - %s", err, string(output))
+		return BranchComparison{}, err
+	}
+	diff, truncated := parseDiff(patch, maxDiffLines)
+	return BranchComparison{
+		Base:      mainline,
+		A:         sides[0],
+		B:         sides[1],
+		Files:     compareBranchFiles(changed[0], changed[1], parseDiffSummary(between)),
+		Diff:      diff,
+		Truncated: truncated,
+	}, nil
+}
+
+// FileOwners returns the recent human authors of each path on the
+// mainline. The current user is left out: agents commit as them.
+func (as *AgentService) FileOwners(paths []string) ([]FileOwnership, error) {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
+	
+	ctx := context.Background()
+	self, err := as.git.UserEmail(ctx, projectRoot)
+	if err != nil {
+		return nil, err
+	}
+	owners := []FileOwnership{}
+	for _, path := range paths {
+		authors, err := as.git.FileAuthors(ctx, projectRoot, mainline, ownershipWindow, path)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, FileOwnership{Path: path, Authors: humanAuthors(authors, self)})
+	}
+	return owners, nil
+}
+
+// DeleteTaskBranch force-deletes a task_* branch, merged or not
+func (as *AgentService) DeleteTaskBranch(branchName string) error {
+	if _, ok := taskBranchID(branchName); !ok {
+		return fmt.Errorf("%s is not a task branch", branchName)
+	}
+	return as.forceDeleteBranch(branchName)
+}
+
+// WorktreeIsClean reports whether an agent worktree has no local changes
+func (as *AgentService) WorktreeIsClean(path string) (bool, error) {
+	return as.git.IsClean(context.Background(), path)
+}
+
+// RemoveWorktree deletes an agent worktree. The primary checkout is never
+// removed.
+func (as *AgentService) RemoveWorktree(path string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+
+	if filepath.Clean(path) == filepath.Clean(projectRoot) {
+		return fmt.Errorf("refusing to remove the primary checkout")
+	}
+	err := as.git.RemoveWorktree(context.Background(), projectRoot, path)
+	if as.audit != nil {
+		as.audit.Record(AuditWorktreeRemoved, 0, map[string]interface{}{
+			"worktree": path,
+		}, err)
+	}
+	return err
+}
+
+// AddWorktree checks the mainline out, detached, into a new agent worktree at
+// path, the state agent_spawn.sh leaves an idle worktree in
+func (as *AgentService) AddWorktree(path string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
+
+	err := as.git.AddWorktree(context.Background(), projectRoot, path, mainline)
+	if as.audit != nil {
+		as.audit.Record(AuditWorktreeAdded, 0, map[string]interface{}{
+			"worktree": path,
+		}, err)
+	}
+	return err
+}
+
+// RefreshWorktree moves an idle agent worktree up to the current mainline
+func (as *AgentService) RefreshWorktree(path string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	as.mu.RUnlock()
+
+	if filepath.Clean(path) == filepath.Clean(projectRoot) {
+		return fmt.Errorf("refusing to detach the primary checkout")
+	}
+	return as.git.CheckoutDetached(context.Background(), path, mainline)
+}
+
+// generateTaskPrompt builds the instruction handed to a Claude agent for a
+// task in l's locale, followed by its description and notes when it has them
+func generateTaskPrompt(task Task, l *Localizer) string {
+	prompt := l.T(MsgPromptTask, task.ID, task.Title, task.ID)
+	if description := strings.TrimSpace(task.Description); description != "" {
+		prompt += "\n\n" + l.T(MsgPromptDescription) + "\n" + description
+	}
+	if notes := strings.TrimSpace(task.Notes); notes != "" {
+		prompt += "\n\n" + l.T(MsgPromptNotes) + "\n" + notes
+	}
+	return prompt
+}
+
+// Private helper methods
+
+func (as *AgentService) checkBranchExists(branchName string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+
+	exists, err := as.git.BranchExists(context.Background(), projectRoot, branchName)
+	if err != nil {
+		return err
+	}
+	
+	if !exists {
+		return fmt.Errorf("branch %s not found", branchName)
 	}
 	
 	return nil
 }
 
-func (as *AgentService) forceDeleteBranch(branchName string) error {
+func (as *AgentService) mergeBranch(ctx context.Context, branchName string, taskID int, taskTitle string) error {
 	as.mu.RLock()
 	projectRoot := as.projectRoot
+	mainline := as.mainlineBranch()
+	approvalMode := as.approvalMode
+	signing := as.signing
 	as.mu.RUnlock()
 
-	cmd := exec.Command("git", "branch", "-D", branchName)
-	cmd.Dir = projectRoot
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mergeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	
+	if approvalMode == ApproveInWorktree {
+		return as.mergeInWorktree(mergeCtx, projectRoot, mainline, branchName, fmt.Sprintf("Merge task #%d: %s", taskID, taskTitle), signing)
+	}
+	dir, cleanup, err := as.mainlineCheckout(mergeCtx, projectRoot, mainline)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	
-	output, err := cmd.CombinedOutput()
+	start := time.Now()
+	err = as.git.Merge(mergeCtx, dir, branchName, fmt.Sprintf("Merge task #%d: %s", taskID, taskTitle), signing)
+	as.perf.Record(OpGitMerge, time.Since(start), err)
+	if err != nil && (mergeCtx.Err() != nil || dir != projectRoot) {
+		// Don't leave a half-finished merge behind when the job is
+		// cancelled, or in a checkout the user can't see
+		as.git.AbortMerge(context.Background(), dir)
+	}
 	if err != nil {
-		return fmt.Errorf("git branch force delete failed: %v - %s", err, string(output))
+		as.logger.ErrorWithFields("Git merge failed", err, map[string]interface{}{
+			"branch": branchName,
+		})
+		return err
 	}
 	
 	return nil
 }
 
-// parseAgentStatus parses the output from agent_status.sh script
+// mergeInWorktree merges branch on top of the mainline in a temporary
+// detached worktree, then fast-forwards the mainline to the result. The
+// user's checkout only moves if it has the mainline checked out, and git
+// refuses the fast-forward rather than overwrite local changes there.
+func (as *AgentService) mergeInWorktree(ctx context.Context, projectRoot, mainline, branch, message string, signing *SigningConfig) error {
+	exists, err := as.git.BranchExists(ctx, projectRoot, mainline)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("mainline branch %s does not exist", mainline)
+	}
+	parent, err := os.MkdirTemp("", "taskwrapper-merge-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(parent)
+	path := filepath.Join(parent, filepath.Base(projectRoot))
+	if err := as.git.AddWorktree(ctx, projectRoot, path, mainline); err != nil {
+		return err
+	}
+	defer func() {
+		if err := as.git.RemoveWorktree(context.Background(), projectRoot, path); err != nil {
+			as.logger.Error("Failed to remove temporary merge worktree", err)
+		}
+	}()
+	
+	start := time.Now()
+	err = as.git.Merge(ctx, path, branch, message, signing)
+	as.perf.Record(OpGitMerge, time.Since(start), err)
+	if err != nil {
+		as.git.AbortMerge(context.Background(), path)
+		as.logger.ErrorWithFields("Git merge failed", err, map[string]interface{}{
+			"branch": branch,
+		})
+		return err
+	}
+	merged, err := as.git.HeadCommit(ctx, path)
+	if err != nil {
+		return err
+	}
+	
+	// A branch checked out somewhere can only be moved from that checkout
+	dir := projectRoot
+	worktrees, err := as.git.ListWorktrees(ctx, projectRoot)
+	if err != nil {
+		return err
+	}
+	for _, worktree := range worktrees {
+		if worktree.Branch == mainline {
+			dir = worktree.Path
+		}
+	}
+	if err := as.git.FastForward(ctx, dir, mainline, merged); err != nil {
+		return fmt.Errorf("merged in a temporary worktree but could not move %s: %w", mainline, err)
+	}
+	as.logger.InfoWithFields("Merged in a temporary worktree", map[string]interface{}{
+		"branch":   branch,
+		"mainline": mainline,
+		"commit":   merged,
+	})
+	return nil
+}
+
+// mainlineCheckout returns a checkout of the mainline branch to merge into:
+// the primary checkout when it's on the mainline, another worktree that has
+// it checked out, or else a temporary worktree, removed by cleanup
+func (as *AgentService) mainlineCheckout(ctx context.Context, projectRoot, mainline string) (string, func(), error) {
+	current, err := as.git.CurrentBranch(ctx, projectRoot)
+	if err != nil {
+		return "", nil, err
+	}
+	if current == mainline {
+		return projectRoot, func() {}, nil
+	}
+	exists, err := as.git.BranchExists(ctx, projectRoot, mainline)
+	if err != nil {
+		return "", nil, err
+	}
+	if !exists {
+		return "", nil, fmt.Errorf("mainline branch %s does not exist", mainline)
+	}
+	worktrees, err := as.git.ListWorktrees(ctx, projectRoot)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, worktree := range worktrees {
+		if worktree.Branch == mainline {
+			return worktree.Path, func() {}, nil
+		}
+	}
+	
+	// Merging must not switch the branch the user has checked out
+	parent, err := os.MkdirTemp("", "taskwrapper-merge-")
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(parent, filepath.Base(projectRoot))
+	if err := as.git.AddBranchWorktree(ctx, projectRoot, path, mainline); err != nil {
+		os.RemoveAll(parent)
+		return "", nil, err
+	}
+	as.logger.InfoWithFields("Merging in a temporary worktree", map[string]interface{}{
+		"branch":   mainline,
+		"checkout": current,
+		"worktree": path,
+	})
+	cleanup := func() {
+		if err := as.git.RemoveWorktree(context.Background(), projectRoot, path); err != nil {
+			as.logger.Error("Failed to remove temporary merge worktree", err)
+		}
+		os.RemoveAll(parent)
+	}
+	return path, cleanup, nil
+}
+
+func (as *AgentService) deleteBranch(branchName string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+
+	return as.git.DeleteBranch(context.Background(), projectRoot, branchName, false)
+}
+
+func (as *AgentService) forceDeleteBranch(branchName string) error {
+	as.mu.RLock()
+	projectRoot := as.projectRoot
+	as.mu.RUnlock()
+
+	err := as.git.DeleteBranch(context.Background(), projectRoot, branchName, true)
+	if as.audit != nil {
+		taskID := 0
+		fmt.Sscanf(branchName, "task_%d", &taskID)
+		as.audit.Record(AuditBranchForceDeleted, taskID, map[string]interface{}{
+			"branch": branchName,
+		}, err)
+	}
+	return err
+}
+
+// parseAgentStatus parses the output from agent_status.sh script. It is
+// only used when the status can't be read natively; see inspectAgentStatus.
 func (as *AgentService) parseAgentStatus(output string) AgentStatusInfo {
 	lines := strings.Split(strings.TrimSpace(output), "\n")
 	
 	info := AgentStatusInfo{
 		Worktrees:    []AgentWorktree{},
-		MaxSubagents: 5, // Default from agent_status.sh
+		MaxSubagents: defaultMaxSubagents,
 	}
 	
 	for _, line := range lines {
@@ -347,7 +1283,7 @@ func (as *AgentService) parseAgentStatus(output string) AgentStatusInfo {
 						if taskStart := strings.Index(statusPart, "Task #"); taskStart != -1 {
 							taskInfo := statusPart[taskStart:]
 							if colonIdx := strings.Index(taskInfo, ":"); colonIdx != -1 {
-								taskIDStr := strings.TrimSpace(taskInfo[5:colonIdx])
+								taskIDStr := strings.TrimSpace(taskInfo[len("Task #"):colonIdx])
 								taskTitle := strings.TrimSpace(taskInfo[colonIdx+1:])
 								worktree.TaskID = taskIDStr
 								worktree.TaskTitle = taskTitle
@@ -0,0 +1,44 @@
+package main
+
+import "time"
+
+// What to do with running agents when the app quits
+const (
+	ShutdownWait      = "wait"      // stay in the tray and quit once the agents finish
+	ShutdownTerminate = "terminate" // stop the agents and quit
+	ShutdownDetach    = "detach"    // quit and leave the agents running, to be picked up on next launch
+)
+
+// shutdownPollInterval is how often a waiting shutdown checks on the agents
+const shutdownPollInterval = 5 * time.Second
+
+// RunningAgent is an agent process holding a worktree
+type RunningAgent struct {
+	TaskID   int    `json:"taskId"`
+	Title    string `json:"title"`
+	Worktree string `json:"worktree"`
+	PID      int    `json:"pid"`
+}
+
+// validShutdownChoice reports whether choice is one of the Shutdown values
+func validShutdownChoice(choice string) bool {
+	return choice == ShutdownWait || choice == ShutdownTerminate || choice == ShutdownDetach
+}
+
+// runningAgents returns the worktrees whose lock names a live process. alive
+// reports whether a pid is running.
+func runningAgents(worktrees []GitWorktree, tasks []Task, alive func(int) bool) []RunningAgent {
+	agents := []RunningAgent{}
+	for _, worktree := range worktrees {
+		lock, err := readAgentLock(worktree.Path)
+		if err != nil || !alive(lock.PID) {
+			continue
+		}
+		agent := RunningAgent{TaskID: lock.TaskID, Worktree: worktree.Path, PID: lock.PID}
+		if task, ok := findTask(tasks, lock.TaskID); ok {
+			agent.Title = task.Title
+		}
+		agents = append(agents, agent)
+	}
+	return agents
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How agents are launched
+const (
+	SpawnerAuto   = ""       // agent_spawn.sh when the repository has it, else natively
+	SpawnerNative = "native" // the agent service creates the worktree and runs claude itself
+	SpawnerScript = "script" // always agent_spawn.sh
+)
+
+const (
+	// defaultMaxAgents is how many agents run at once when the repository
+	// doesn't say, agent_spawn.sh's MAX_SUBAGENTS default
+	defaultMaxAgents = 2
+	// agentLockTimeout is how long a live agent may hold its worktree
+	// before the spawner takes it back, agent_spawn.sh's AGENT_LOCK_TIMEOUT
+	agentLockTimeout = 2 * time.Hour
+)
+
+// SpawnerSettings is how a repository's agents are launched
+type SpawnerSettings struct {
+	Mode      string `json:"mode"`                // one of the Spawner modes
+	MaxAgents int    `json:"maxAgents,omitempty"` // agents running at once; 0 is the spawner's default
+}
+
+// validSpawner reports whether mode is one of the Spawner modes
+func validSpawner(mode string) bool {
+	return mode == SpawnerAuto || mode == SpawnerNative || mode == SpawnerScript
+}
+
+// agentSpawn is one launch handed to the native spawner
+type agentSpawn struct {
+	Task      Task
+	Title     string // the task title, sanitized
+	Root      string // the validated primary checkout
+	BaseRef   string // where a fresh task branch starts
+	Mainline  string // what the worktree goes back to when the agent exits
+	RunID     string
+	SessionID string
+	Relaunch  AgentRelaunch
+	Env       []string
+	JSON      bool // leave claude's JSON result in logs/agent_results
+	Sandbox   bool
+	LogLimit  int64
+	MaxAgents int
+	Localizer *Localizer
+}
+
+// spawnNative does what agent_spawn.sh does without the script: takes a free
+// subagent worktree, or makes one, puts it on the task's branch, and starts
+// claude there in the background. It returns the agent's worktree.
+func (as *AgentService) spawnNative(ctx context.Context, s agentSpawn) (string, error) {
+	starter, ok := as.runner.(ProcessStarter)
+	if !ok {
+		return "", fmt.Errorf("the process runner can't start agents")
+	}
+	sandboxTool := ""
+	if s.Sandbox {
+		tool, err := findSandboxTool()
+		if err != nil {
+			return "", err
+		}
+		sandboxTool = tool
+	}
+	if s.MaxAgents <= 0 {
+		s.MaxAgents = defaultMaxAgents
+	}
+
+	// A worktree only counts as taken once its lock is written, so launches
+	// pick and lock theirs one at a time
+	as.spawnMu.Lock()
+	defer as.spawnMu.Unlock()
+
+	// Worktrees deleted by hand would otherwise still count against the limit
+	if err := as.git.PruneWorktrees(ctx, s.Root); err != nil {
+		as.logger.Error("Failed to prune worktrees before spawning", err)
+	}
+	worktree, err := as.allocateWorktree(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	branch := taskBranch(s.Task.ID)
+	if s.Relaunch.Mode == RelaunchResume {
+		// A resumed agent keeps the branch it already has
+		err = as.git.CheckoutBranch(ctx, worktree, branch)
+	} else {
+		err = as.prepareWorktree(ctx, worktree, s.BaseRef, branch)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare %s for task #%d: %w", worktree, s.Task.ID, err)
+	}
+
+	if err := os.MkdirAll(agentLogsDir(s.Root), 0755); err != nil {
+		return "", err
+	}
+	runLog := newAgentLogWriter(filepath.Join(agentLogsDir(s.Root), fmt.Sprintf("task_%d_%s.log", s.Task.ID, s.RunID)), s.LogLimit)
+	var stdout io.Writer = runLog
+	resultPath := ""
+	var result *os.File
+	if s.JSON {
+		// The result is renamed into place so it's never read half-written
+		if err := os.MkdirAll(agentResultsDir(s.Root), 0755); err != nil {
+			return "", err
+		}
+		resultPath = filepath.Join(agentResultsDir(s.Root), fmt.Sprintf("task_%d-%d.json", s.Task.ID, time.Now().Unix()))
+		if result, err = os.Create(resultPath + ".tmp"); err != nil {
+			return "", err
+		}
+		stdout = result
+	}
+
+	argv := append([]string{"claude"}, claudeSpawnArgs(s, nativePrompt(s, worktree, sandboxTool != ""), worktree)...)
+	if sandboxTool != "" {
+		argv = confineCommand(sandboxTool, worktree, s.Root, os.Getenv("HOME"), argv)
+	}
+	env := s.Env
+	if claude, err := exec.LookPath("claude"); err == nil {
+		// claude is usually a node script installed next to its node
+		env = prependPath(env, filepath.Dir(claude))
+	}
+	process, err := starter.Start(Command{Name: argv[0], Args: argv[1:], Dir: worktree, Env: env}, stdout, runLog)
+	if err != nil {
+		runLog.Close()
+		if result != nil {
+			result.Close()
+			os.Remove(resultPath + ".tmp")
+		}
+		return "", fmt.Errorf("failed to start claude for task #%d: %w", s.Task.ID, err)
+	}
+	pid := process.Pid()
+	if err := writeAgentLock(worktree, pid, s.Task.ID, s.Title, strings.TrimPrefix(filepath.Base(worktree), filepath.Base(s.Root)+"-"), time.Now()); err != nil {
+		// Without its lock the worktree would be handed to the next task
+		as.StopAgent(pid)
+		return "", fmt.Errorf("failed to lock %s for task #%d: %w", worktree, s.Task.ID, err)
+	}
+	as.logger.InfoWithFields("Launched agent natively", map[string]interface{}{
+		"task_id":  s.Task.ID,
+		"run_id":   s.RunID,
+		"pid":      pid,
+		"worktree": worktree,
+	})
+
+	as.mu.RLock()
+	errorHandler := as.errorHandler
+	as.mu.RUnlock()
+	errorHandler.Go("agent wait", func() {
+		err := process.Wait()
+		runLog.Close()
+		if result != nil {
+			result.Close()
+			if err := os.Rename(resultPath+".tmp", resultPath); err != nil {
+				as.logger.Error("Failed to save agent result", err)
+			}
+		}
+		as.logger.InfoWithFields("Agent exited", map[string]interface{}{
+			"task_id": s.Task.ID,
+			"run_id":  s.RunID,
+			"pid":     pid,
+			"error":   fmt.Sprint(err),
+		})
+		// Back on a detached mainline, so the task branch can be deleted
+		if err := as.git.CheckoutDetached(context.Background(), worktree, s.Mainline); err != nil {
+			as.logger.Error("Failed to detach worktree after agent exited", err)
+		}
+		os.Remove(filepath.Join(worktree, agentLockFile))
+	})
+	return worktree, nil
+}
+
+// allocateWorktree picks the worktree for a launch: the earlier one when
+// resuming, else the first free subagent worktree, else a new one in the
+// first empty slot. Locks left by dead or overdue agents are cleared first.
+func (as *AgentService) allocateWorktree(ctx context.Context, s agentSpawn) (string, error) {
+	now := time.Now()
+	for n := 1; n <= s.MaxAgents; n++ {
+		clearStaleLock(agentWorktreePath(s.Root, n), now, processAlive)
+	}
+
+	if s.Relaunch.Mode == RelaunchResume {
+		worktree := s.Relaunch.Worktree
+		clearStaleLock(worktree, now, processAlive)
+		if info, err := os.Stat(worktree); err != nil || !info.IsDir() || worktreeLocked(worktree) {
+			return "", fmt.Errorf("worktree %s is not available to resume in", worktree)
+		}
+		return worktree, nil
+	}
+
+	for n := 1; n <= s.MaxAgents; n++ {
+		path := agentWorktreePath(s.Root, n)
+		if info, err := os.Stat(path); err == nil && info.IsDir() && !worktreeLocked(path) {
+			return path, nil
+		}
+	}
+
+	worktrees, err := as.git.ListWorktrees(ctx, s.Root)
+	if err != nil {
+		return "", fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	existing := 0
+	for _, worktree := range worktrees {
+		if isSubagentWorktree(s.Root, worktree.Path) {
+			existing++
+		}
+	}
+	if existing >= s.MaxAgents {
+		return "", fmt.Errorf("all %d subagent worktrees are busy", s.MaxAgents)
+	}
+	slots := freeWorktreeSlots(s.Root, worktrees, s.MaxAgents)
+	if len(slots) == 0 {
+		return "", fmt.Errorf("failed to allocate a worktree: every slot up to %d is taken", s.MaxAgents)
+	}
+	path := agentWorktreePath(s.Root, slots[0])
+	if err := as.git.AddWorktree(ctx, s.Root, path, s.BaseRef); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// prepareWorktree throws away what an earlier agent left in worktree and
+// starts branch there from baseRef
+func (as *AgentService) prepareWorktree(ctx context.Context, worktree, baseRef, branch string) error {
+	if err := as.git.ResetWorktree(ctx, worktree); err != nil {
+		return err
+	}
+	if err := as.git.CheckoutDetached(ctx, worktree, baseRef); err != nil {
+		return err
+	}
+	return as.git.CheckoutNewBranch(ctx, worktree, branch)
+}
+
+// isSubagentWorktree reports whether path is one of the subagent worktrees
+// of projectRoot, in any slot
+func isSubagentWorktree(projectRoot, path string) bool {
+	path = filepath.Clean(path)
+	if filepath.Dir(path) != filepath.Dir(projectRoot) {
+		return false
+	}
+	slot, ok := strings.CutPrefix(filepath.Base(path), filepath.Base(projectRoot)+"-subagent")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(slot)
+	return err == nil
+}
+
+// clearStaleLock removes the lock of the worktree at path when its agent is
+// gone or has held it past agentLockTimeout, reporting whether it did. A
+// lock without a pid or start time is stale.
+func clearStaleLock(path string, now time.Time, alive func(int) bool) bool {
+	lock, err := readAgentLock(path)
+	if os.IsNotExist(err) {
+		return false
+	}
+	if err == nil && lock.PID > 0 && !lock.Started.IsZero() && alive(lock.PID) && now.Sub(lock.Started) <= agentLockTimeout {
+		return false
+	}
+	return os.Remove(filepath.Join(path, agentLockFile)) == nil
+}
+
+// writeAgentLock writes the lock agent_spawn.sh keeps in a busy worktree
+func writeAgentLock(worktree string, pid, taskID int, title, slot string, started time.Time) error {
+	var lock bytes.Buffer
+	fmt.Fprintf(&lock, "status=busy\npid=%d\ntask_id=%d\ntask_title=%s\n", pid, taskID, title)
+	fmt.Fprintf(&lock, "started=%d\nstarted_human=%s\nworktree=%s\n", started.Unix(), started.Format(time.UnixDate), slot)
+	return os.WriteFile(filepath.Join(worktree, agentLockFile), lock.Bytes(), 0644)
+}
+
+// nativePrompt is what the agent is asked to do, in the spawn's locale
+func nativePrompt(s agentSpawn, worktree string, sandboxed bool) string {
+	l := s.Localizer
+	taskFile := filepath.Join(s.Root, "plan", "task.json")
+	var prompt string
+	if s.Relaunch.Mode == RelaunchResume {
+		prompt = l.T(MsgPromptResume, s.Task.ID, s.Title, s.Task.ID)
+	} else {
+		prompt = generateTaskPrompt(s.Task, l) + "\n\n" + l.T(MsgPromptWorktree, s.Task.ID, taskFile)
+	}
+	if s.Relaunch.Feedback != "" {
+		prompt += "\n\n" + l.T(MsgPromptFeedback) + "\n" + s.Relaunch.Feedback
+	}
+	if sandboxed {
+		prompt += "\n\n" + l.T(MsgPromptSandbox, worktree, taskFile)
+	}
+	return prompt
+}
+
+// claudeSpawnArgs are the claude arguments agent_spawn.sh would pass
+func claudeSpawnArgs(s agentSpawn, prompt, worktree string) []string {
+	args := []string{prompt}
+	if s.JSON {
+		args = []string{"-p", prompt, ClaudeFlagOutputFormat, "json"}
+	}
+	args = append(args, ClaudeFlagAddDir, worktree, ClaudeFlagSkipPermissions)
+	if s.Relaunch.Mode == RelaunchResume && s.Relaunch.SessionID != "" {
+		args = append(args, ClaudeFlagResume, s.Relaunch.SessionID)
+	} else if s.SessionID != "" {
+		args = append(args, ClaudeFlagSessionID, s.SessionID)
+	}
+	return args
+}
+
+// findSandboxTool returns the tool that can confine an agent here
+func findSandboxTool() (string, error) {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("sandbox-exec"); err == nil {
+			return "sandbox-exec", nil
+		}
+	}
+	if _, err := exec.LookPath("bwrap"); err == nil {
+		return "bwrap", nil
+	}
+	return "", fmt.Errorf("sandboxing is on but neither sandbox-exec nor bwrap is available")
+}
+
+// confineCommand wraps argv so it may only write inside worktree, the git
+// metadata its commits need, the main task.json and claude's own state, as
+// agent_spawn.sh's run_confined does. The repository's hooks and config stay
+// read-only, as do the files that tell git where the worktree's metadata is,
+// so nothing the agent writes runs when the app uses git there afterwards.
+func confineCommand(tool, worktree, root, home string, argv []string) []string {
+	taskFile := filepath.Join(root, "plan", "task.json")
+	gitDir := filepath.Join(root, ".git")
+	adminDir := worktreeAdminDir(worktree, gitDir)
+	writable := []string{worktree, adminDir}
+	for _, name := range []string{"objects", "refs", "logs"} {
+		writable = append(writable, filepath.Join(gitDir, name))
+	}
+	readOnly := []string{filepath.Join(worktree, ".git"), filepath.Join(adminDir, "commondir"),
+		filepath.Join(adminDir, "gitdir"), filepath.Join(adminDir, "config.worktree")}
+	switch tool {
+	case "sandbox-exec":
+		// Last matching rule wins: deny all writes, allow the roots, then
+		// deny the git pointers inside them again
+		var profile strings.Builder
+		profile.WriteString("(version 1)\n(allow default)\n(deny file-write*)\n(allow file-write*")
+		for _, path := range writable {
+			fmt.Fprintf(&profile, "\n    (subpath %q)", path)
+		}
+		fmt.Fprintf(&profile, "\n    (literal %q)\n    (subpath %q)\n    (literal %q)", taskFile, filepath.Join(home, ".claude"), filepath.Join(home, ".claude.json"))
+		profile.WriteString("\n    (subpath \"/private/tmp\")\n    (subpath \"/private/var/folders\")\n    (subpath \"/dev\"))\n(deny file-write*")
+		for _, path := range readOnly {
+			fmt.Fprintf(&profile, "\n    (literal %q)", path)
+		}
+		profile.WriteString(")")
+		return append([]string{"sandbox-exec", "-p", profile.String()}, argv...)
+	case "bwrap":
+		args := []string{"bwrap", "--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc", "--tmpfs", "/tmp"}
+		for _, path := range append(writable, taskFile, filepath.Join(home, ".claude"), filepath.Join(home, ".claude.json")) {
+			if _, err := os.Stat(path); err == nil {
+				args = append(args, "--bind", path, path)
+			}
+		}
+		// Later binds cover earlier ones
+		for _, path := range readOnly {
+			if _, err := os.Stat(path); err == nil {
+				args = append(args, "--ro-bind", path, path)
+			}
+		}
+		args = append(args, "--chdir", worktree, "--die-with-parent")
+		return append(args, argv...)
+	}
+	return argv
+}
+
+// worktreeAdminDir is the directory under gitDir/worktrees holding a linked
+// worktree's HEAD and index, as named by the worktree's .git file. A .git
+// file pointing anywhere else is ignored in favour of git's default name.
+func worktreeAdminDir(worktree, gitDir string) string {
+	fallback := filepath.Join(gitDir, "worktrees", filepath.Base(worktree))
+	data, err := os.ReadFile(filepath.Join(worktree, ".git"))
+	if err != nil {
+		return fallback
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return fallback
+	}
+	dir = filepath.Clean(strings.TrimSpace(dir))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(worktree, dir)
+	}
+	if filepath.Dir(dir) != filepath.Join(gitDir, "worktrees") {
+		return fallback
+	}
+	return dir
+}
+
+// prependPath puts dir at the front of the PATH in env
+func prependPath(env []string, dir string) []string {
+	out := make([]string, 0, len(env)+1)
+	found := false
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, "PATH="); ok {
+			kv = "PATH=" + dir + string(os.PathListSeparator) + value
+			found = true
+		}
+		out = append(out, kv)
+	}
+	if !found {
+		out = append(out, "PATH="+dir)
+	}
+	return out
+}
+
+// agentLogWriter writes an agent's output to its run log a line at a time,
+// each stamped like agent_spawn.sh's, and keeps a single .1 copy once the
+// log passes limit bytes
+type agentLogWriter struct {
+	path    string
+	limit   int64
+	size    int64
+	partial []byte
+}
+
+// newAgentLogWriter appends to the run log at path
+func newAgentLogWriter(path string, limit int64) *agentLogWriter {
+	w := &agentLogWriter{path: path, limit: limit}
+	if info, err := os.Stat(path); err == nil {
+		w.size = info.Size()
+	}
+	return w
+}
+
+func (w *agentLogWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Close writes out a last line that had no newline
+func (w *agentLogWriter) Close() error {
+	if len(w.partial) == 0 {
+		return nil
+	}
+	line := string(w.partial)
+	w.partial = nil
+	return w.writeLine(line)
+}
+
+func (w *agentLogWriter) writeLine(line string) error {
+	entry := fmt.Sprintf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), line)
+	if w.limit > 0 && w.size+int64(len(entry)) > w.limit {
+		os.Rename(w.path, w.path+".1")
+		w.size = 0
+	}
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	n, err := file.WriteString(entry)
+	w.size += int64(n)
+	return err
+}
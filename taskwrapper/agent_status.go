@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of a subagent worktree in AgentStatusInfo, as agent_status.sh
+// reports them
+const (
+	AgentSlotIdle  = "idle"  // no agent holds it
+	AgentSlotBusy  = "busy"  // locked by a running agent
+	AgentSlotStale = "stale" // locked by an agent that is no longer running
+)
+
+// inspectAgentStatus reads the subagent worktrees from git and each one's
+// lock file, without agent_status.sh
+func (as *AgentService) inspectAgentStatus(projectRoot string, maxAgents int) (AgentStatusInfo, error) {
+	ctx := as.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	worktrees, err := as.git.ListWorktrees(ctx, projectRoot)
+	if err != nil {
+		return AgentStatusInfo{}, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	if maxAgents <= 0 {
+		maxAgents = defaultMaxAgents
+	}
+	return agentStatus(projectRoot, worktrees, maxAgents, processAlive), nil
+}
+
+// agentStatus reports the subagent worktrees of projectRoot among
+// worktrees, in slot order. alive reports whether a lock's pid is running.
+func agentStatus(projectRoot string, worktrees []GitWorktree, maxAgents int, alive func(int) bool) AgentStatusInfo {
+	info := AgentStatusInfo{Worktrees: []AgentWorktree{}, MaxSubagents: maxAgents}
+	subagents := []GitWorktree{}
+	for _, worktree := range worktrees {
+		if isSubagentWorktree(projectRoot, worktree.Path) {
+			subagents = append(subagents, worktree)
+		}
+	}
+	sort.Slice(subagents, func(i, j int) bool {
+		return subagentSlot(projectRoot, subagents[i].Path) < subagentSlot(projectRoot, subagents[j].Path)
+	})
+
+	for _, worktree := range subagents {
+		status := AgentWorktree{
+			Name:   filepath.Base(worktree.Path),
+			Status: AgentSlotIdle,
+			Path:   worktree.Path,
+			Branch: worktree.Branch,
+		}
+		lock, err := readAgentLock(worktree.Path)
+		switch {
+		case os.IsNotExist(err):
+		case err == nil && lock.PID > 0 && alive(lock.PID):
+			status.Status = AgentSlotBusy
+		default:
+			status.Status = AgentSlotStale
+		}
+		if status.Status != AgentSlotIdle {
+			if lock.TaskID > 0 {
+				status.TaskID = strconv.Itoa(lock.TaskID)
+			}
+			status.TaskTitle = lock.Title
+			if lock.PID > 0 {
+				status.PID = strconv.Itoa(lock.PID)
+			}
+			if !lock.Started.IsZero() {
+				started := lock.Started.UTC()
+				status.StartedAt = &started
+				status.Started = started.Format(time.RFC3339)
+			}
+		}
+
+		info.TotalWorktrees++
+		switch status.Status {
+		case AgentSlotIdle:
+			info.IdleCount++
+		case AgentSlotBusy:
+			info.BusyCount++
+		case AgentSlotStale:
+			info.StaleCount++
+		}
+		info.Worktrees = append(info.Worktrees, status)
+	}
+	return info
+}
+
+// subagentSlot is the slot number of a subagent worktree
+func subagentSlot(projectRoot, path string) int {
+	slot, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), filepath.Base(projectRoot)+"-subagent"))
+	return slot
+}
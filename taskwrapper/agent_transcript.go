@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// transcriptTextLimit caps the text kept from one transcript entry; tool
+// results in particular can be whole files
+const transcriptTextLimit = 4000
+
+// transcriptLineLimit is the longest session file line that is read
+const transcriptLineLimit = 16 * 1024 * 1024
+
+// sessionIDPattern is what a claude session ID looks like; anything else
+// could escape the session directory
+var sessionIDPattern = regexp.MustCompile(`^[0-9A-Fa-f-]+$`)
+
+// Where a transcript was read from
+const (
+	TranscriptSession = "session" // claude's own session file
+	TranscriptResult  = "result"  // only the final message of a JSON result
+)
+
+// Kinds of transcript entry
+const (
+	TranscriptText       = "text"
+	TranscriptThinking   = "thinking"
+	TranscriptToolUse    = "tool_use"
+	TranscriptToolResult = "tool_result"
+)
+
+// TranscriptEntry is one message, or one part of a message, of an agent's
+// conversation
+type TranscriptEntry struct {
+	Time      *time.Time `json:"time,omitempty"`
+	Role      string     `json:"role"` // user or assistant
+	Kind      string     `json:"kind"`
+	Tool      string     `json:"tool,omitempty"` // the tool called, for tool_use
+	Text      string     `json:"text"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+// AgentTranscript is the conversation an agent run had with claude
+type AgentTranscript struct {
+	TaskID    int               `json:"taskId"`
+	RunID     string            `json:"runId"`
+	SessionID string            `json:"sessionId"`
+	Source    string            `json:"source"`
+	Entries   []TranscriptEntry `json:"entries"`
+}
+
+// sessionLine is the part of a line of a claude session file the
+// transcript uses
+type sessionLine struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"` // a string, or a list of blocks
+	} `json:"message"`
+}
+
+// sessionBlock is one block of a message's content
+type sessionBlock struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text"`
+	Thinking string          `json:"thinking"`
+	Name     string          `json:"name"`
+	Input    json.RawMessage `json:"input"`
+	Content  json.RawMessage `json:"content"` // a tool result's, again a string or blocks
+}
+
+// agentSessionKey carries a launch's claude session ID in its context
+type agentSessionKey struct{}
+
+// newAgentSessionID returns a session ID for claude's --session-id
+func newAgentSessionID() string {
+	return uuid.NewString()
+}
+
+// withAgentSessionID tells the agent service which session ID to start
+// claude with
+func withAgentSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, agentSessionKey{}, sessionID)
+}
+
+// agentSessionIDFrom returns the session ID carried by ctx, or ""
+func agentSessionIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	sessionID, _ := ctx.Value(agentSessionKey{}).(string)
+	return sessionID
+}
+
+// claudeProjectsDir is where claude keeps session files, one directory per
+// working directory
+func claudeProjectsDir() (string, error) {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "projects"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".claude", "projects"), nil
+}
+
+// findSessionFile returns the session file for sessionID. The agent's
+// worktree, and so the project directory, isn't recorded, so every project
+// is searched.
+func findSessionFile(projectsDir, sessionID string) (string, error) {
+	if !sessionIDPattern.MatchString(sessionID) {
+		return "", fmt.Errorf("invalid session ID %q", sessionID)
+	}
+	matches, err := filepath.Glob(filepath.Join(projectsDir, "*", sessionID+".jsonl"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", os.ErrNotExist
+	}
+	return matches[0], nil
+}
+
+// readSessionTranscript reads the conversation out of a claude session file,
+// skipping lines it doesn't understand
+func readSessionTranscript(path string) ([]TranscriptEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries := []TranscriptEntry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), transcriptLineLimit)
+	for scanner.Scan() {
+		var line sessionLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.Type != "user" && line.Type != "assistant" {
+			continue
+		}
+		var at *time.Time
+		if !line.Timestamp.IsZero() {
+			timestamp := line.Timestamp
+			at = &timestamp
+		}
+		for _, entry := range transcriptEntries(line.Type, line.Message.Content) {
+			entry.Time = at
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// transcriptEntries splits a message's content into entries
+func transcriptEntries(role string, content json.RawMessage) []TranscriptEntry {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return []TranscriptEntry{newTranscriptEntry(role, TranscriptText, "", text)}
+	}
+	var blocks []sessionBlock
+	if json.Unmarshal(content, &blocks) != nil {
+		return nil
+	}
+	entries := []TranscriptEntry{}
+	for _, block := range blocks {
+		switch block.Type {
+		case TranscriptText:
+			entries = append(entries, newTranscriptEntry(role, TranscriptText, "", block.Text))
+		case TranscriptThinking:
+			entries = append(entries, newTranscriptEntry(role, TranscriptThinking, "", block.Thinking))
+		case TranscriptToolUse:
+			entries = append(entries, newTranscriptEntry(role, TranscriptToolUse, block.Name, string(block.Input)))
+		case TranscriptToolResult:
+			entries = append(entries, newTranscriptEntry(role, TranscriptToolResult, "", blockText(block.Content)))
+		}
+	}
+	return entries
+}
+
+// blockText flattens a tool result's content to text
+func blockText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) == nil {
+		return text
+	}
+	var blocks []sessionBlock
+	if json.Unmarshal(content, &blocks) != nil {
+		return string(content)
+	}
+	parts := []string{}
+	for _, block := range blocks {
+		if block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// newTranscriptEntry builds an entry, capping its text
+func newTranscriptEntry(role, kind, tool, text string) TranscriptEntry {
+	entry := TranscriptEntry{Role: role, Kind: kind, Tool: tool, Text: text}
+	if len(entry.Text) > transcriptTextLimit {
+		entry.Text = entry.Text[:transcriptTextLimit]
+		entry.Truncated = true
+	}
+	return entry
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// RepoTask is a task on the aggregate board, tagged with the repository it
+// belongs to so edits can be routed back there
+type RepoTask struct {
+	Task
+	RepoID   string `json:"repoId"`
+	RepoName string `json:"repoName"`
+}
+
+// RepoBoardError reports a repository left off the aggregate board
+type RepoBoardError struct {
+	RepoID   string `json:"repoId"`
+	RepoName string `json:"repoName"`
+	Error    string `json:"error"`
+}
+
+// AggregateBoard is every configured repository's tasks on one board
+type AggregateBoard struct {
+	Tasks  []RepoTask       `json:"tasks"`
+	Errors []RepoBoardError `json:"errors"` // repositories that could not be read
+}
+
+// repositoryTaskFile returns the task.json of repo
+func repositoryTaskFile(repo Repository) string {
+	return filepath.Join(repo.Path, "plan", "task.json")
+}
+
+// tagRepoTasks tags tasks with repo, in task ID order
+func tagRepoTasks(repo Repository, tasks []Task) []RepoTask {
+	tagged := make([]RepoTask, 0, len(tasks))
+	for _, task := range tasks {
+		tagged = append(tagged, RepoTask{Task: task, RepoID: repo.ID, RepoName: repo.Name})
+	}
+	sort.SliceStable(tagged, func(i, j int) bool { return tagged[i].ID < tagged[j].ID })
+	return tagged
+}
+
+// findTask returns the task with id
+func findTask(tasks []Task, id int) (Task, bool) {
+	for _, task := range tasks {
+		if task.ID == id {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
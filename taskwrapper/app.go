@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -24,6 +28,11 @@ type Task struct {
 	Priority TaskPriority `json:"priority"`
 	Deps     []int        `json:"deps"`   // array of task IDs this task depends on
 	Parent   *int         `json:"parent"` // parent task ID, null if top-level
+	Estimate int          `json:"estimate,omitempty"` // story points, 0 if unestimated
+	Description string `json:"description,omitempty"` // markdown: what the task is and how to know it's done
+	Notes string `json:"notes,omitempty"` // markdown: context agents and humans leave each other as work goes on
+	Tags []string `json:"tags,omitempty"` // lowercase labels; triage rules route new tasks by them
+	Triaged bool `json:"triaged,omitempty"` // a triage rule has routed the task out of untriaged backlog
 }
 
 // Terminal represents a running terminal session
@@ -34,6 +43,13 @@ type Terminal struct {
 	Conn    *websocket.Conn
 	Done    chan bool
 	Buffer  *TerminalBuffer
+	Epoch   string // new for every shell, so clients can tell a restart from a reconnect
+	console *restrictedConsole // runs allowlisted commands in place of Cmd and Pty in restricted mode
+	throttle outputThrottle // output statistics, and the pause for programs that flood the terminal
+
+	// connMu serializes writes to Conn and keeps output from being sent
+	// while a reconnecting client is replayed
+	connMu sync.Mutex
 }
 
 // TerminalBuffer stores recent terminal output for reconnection
@@ -42,22 +58,32 @@ type TerminalBuffer struct {
 	MaxLines int
 	MaxBytes int
 	mu       sync.Mutex
+	redactor *Redactor // masks secrets before output is kept
+	ends     []int64   // output offset after each kept line
+	start    int64     // output offset of the first kept line
+	total    int64     // bytes of output seen, kept or not
 }
 
 // TerminalMessage represents messages sent between frontend and backend
 type TerminalMessage struct {
-	Type string `json:"type"`
-	Data string `json:"data"`
+	Type    string `json:"type"`
+	Data    string `json:"data"`
+	Epoch   string `json:"epoch,omitempty"`   // set on hello
+	Offset  int64  `json:"offset,omitempty"`  // output offset after this message, on hello, history and output
+	Skipped int64  `json:"skipped,omitempty"` // bytes dropped while output was paused, on paused and resumed
 }
 
 // AgentWorktree represents a single subagent worktree
 type AgentWorktree struct {
-	Name      string `json:"name"`
-	Status    string `json:"status"`
-	TaskID    string `json:"taskId,omitempty"`
-	TaskTitle string `json:"taskTitle,omitempty"`
-	PID       string `json:"pid,omitempty"`
-	Started   string `json:"started,omitempty"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	TaskID    string     `json:"taskId,omitempty"`
+	TaskTitle string     `json:"taskTitle,omitempty"`
+	PID       string     `json:"pid,omitempty"`
+	Started   string     `json:"started,omitempty"`
+	Path      string     `json:"path,omitempty"`
+	Branch    string     `json:"branch,omitempty"`    // empty on a detached HEAD
+	StartedAt *time.Time `json:"startedAt,omitempty"` // when its agent was launched, from the lock
 }
 
 // AgentStatusInfo represents the overall agent status
@@ -67,11 +93,14 @@ type AgentStatusInfo struct {
 	IdleCount     int            `json:"idleCount"`
 	BusyCount     int            `json:"busyCount"`
 	MaxSubagents  int            `json:"maxSubagents"`
+	StaleCount    int            `json:"staleCount"` // locked by an agent that is no longer running
 }
 
 // Logger interface for structured logging
 type Logger interface {
+	Debug(message string)
 	Info(message string)
+	Warn(message string)
 	Error(message string, err error)
 	InfoWithFields(message string, fields map[string]interface{})
 	ErrorWithFields(message string, err error, fields map[string]interface{})
@@ -79,33 +108,105 @@ type Logger interface {
 
 // Service Interfaces
 
+// FileUtilsInterface defines the atomic file operations services persist with
+type FileUtilsInterface interface {
+	AtomicWriteJSON(filePath string, data interface{}) error
+	AtomicWrite(filePath string, data []byte) error
+	CleanupOldBackups(pattern string, maxAge time.Duration) error
+	ReadFile(filePath string) ([]byte, error)
+	SetBackupDir(dir string)
+	BackupDir(filePath string) string
+	SetCipher(cipher *Cipher)
+	Encrypted() bool
+}
+
 // TaskServiceInterface defines the task service contract
 type TaskServiceInterface interface {
 	LoadTasks() ([]Task, error)
 	SaveTasks(tasks []Task) error
+	CreateTask(title string, priority string) (Task, error)
 	UpdateTask(task Task) error
 	MoveTask(taskID int, newStatus string) error
 	GetTasksByStatus(status string) ([]Task, error)
 	GetTasks() []Task
 	SetTaskFile(path string)
+	GetTaskFile() string
+	Flush() error
+	DiskChanged() bool
+	Watch(stop <-chan struct{}, changed func()) error
+	OnLoadProgress(fn func(TaskLoadProgress))
+	ListBackups() ([]TaskBackup, error)
+	PreviewBackup(name string) (TaskBackupPreview, error)
+	RestoreBackup(name string) error
+	ResolveConflict(keepMine bool) error
+}
+
+// PlanServiceInterface defines the plan service contract
+type PlanServiceInterface interface {
+	LoadPlan() (string, error)
+	SavePlan(content string) error
+	LoadPlanChunk(offset int64, length int) (PlanChunk, error)
+	SetPlanFile(path string)
+	GetPlanFile() string
 }
 
 // TerminalServiceInterface defines the terminal service contract
 type TerminalServiceInterface interface {
 	StartTerminalSession() string
+	StartTerminalSessionIn(dir string) string
 	HandleWebSocket(w http.ResponseWriter, r *http.Request)
 	CleanupTerminal(terminalID string)
 	GetTerminal(terminalID string) (*Terminal, bool)
 	SetContext(ctx context.Context)
+	SetErrorHandler(errorHandler *ErrorHandler)
+	WebSocketStatus() (bool, error)
+	TerminalIssued(terminalID string) bool
+	TerminalTitles() map[string]string
+	TerminalStats() []TerminalStats
+	ResumeOutput(terminalID string) error
+	SetRestriction(config RestrictedTerminalConfig) error
+	SetBroadcastGroup(name string, terminalIDs []string) (TerminalBroadcastGroup, error)
+	BroadcastGroups() []TerminalBroadcastGroup
+	PreviewBroadcast(group, input string, now time.Time) (TerminalBroadcast, error)
+	Broadcast(token string, now time.Time) (TerminalBroadcastResult, error)
 }
 
 // AgentServiceInterface defines the agent service contract
 type AgentServiceInterface interface {
 	LaunchClaudeAgent(task Task) error
+	LaunchClaudeAgentContext(ctx context.Context, task Task) error
 	ApproveTask(taskID int, taskTitle string) error
+	ApproveTaskContext(ctx context.Context, taskID int, taskTitle string) error
 	RejectTask(taskID int, taskTitle string) error
 	GetAgentStatus() (AgentStatusInfo, error)
+	FindTaskWorktree(taskID int) (string, error)
+	RunCheck(dir, command string) (string, error)
+	StopAgent(pid int) error
+	ListWorktrees() ([]GitWorktree, error)
+	WorktreeIsClean(path string) (bool, error)
+	RemoveWorktree(path string) error
+	AddWorktree(path string) error
+	RefreshWorktree(path string) error
+	WorktreeDiskUsage(tasks []Task, worktrees WorktreeConfig) (WorktreeDiskUsage, error)
+	PruneWorktrees(tasks []Task, worktrees WorktreeConfig) (WorktreePrune, error)
+	WarmWorktrees(tasks []Task, worktrees WorktreeConfig, limit int) (WorktreeWarmup, error)
+	CollectResults(record func(taskID int, result AgentResult)) (int, error)
+	PruneAgentLogs() (int, error)
+	ClaudeCapabilities(refresh bool) ClaudeCapabilities
+	ListTaskBranches() ([]string, error)
+	BranchExists(branch string) (bool, error)
+	CheckSigning(signing *SigningConfig) error
+	ListTaskMerges(sinceTag string) ([]GitCommit, string, error)
+	CommitToMainline(path, message string) error
+	MainlineMerges() ([]GitCommit, error)
+	TagMainline(tag, message string) (bool, error)
+	TaskBranchDiff(taskID int, paths ...string) (string, string, error)
+	TaskBranchFiles(taskID int) ([]DiffFileSummary, string, error)
+	CompareBranches(a, b string) (BranchComparison, error)
+	FileOwners(paths []string) ([]FileOwnership, error)
+	DeleteTaskBranch(branchName string) error
 	SetProjectRoot(root string)
+	GetProjectRoot() string
 	SetContext(ctx context.Context)
 }
 
@@ -119,7 +220,67 @@ type ConfigServiceInterface interface {
 	SetActiveRepository(id string) error
 	ValidateRepositoryPath(path string) (*RepositoryInfo, error)
 	FindRepositories(searchPath string) ([]Repository, error)
+	FindRepositoriesContext(ctx context.Context, searchPath string) ([]Repository, error)
 	GetActiveRepositoryPath() (string, error)
+	GetRemoteConfig() RemoteConfig
+	GetEditor() string
+	SetEditor(command string) error
+	GetQuickAddHotkey() string
+	SetQuickAddHotkey(spec string) error
+	GetLoggingConfig() LoggingConfig
+	GetTelemetryConfig() TelemetryConfig
+	SetTelemetryEnabled(enabled bool) error
+	SetLogLevel(level string) error
+	SetLoggingConfig(logging LoggingConfig) error
+	GetBackupConfig() BackupConfig
+	SetBackupDir(dir string) error
+	GetIntegrityConfig() IntegrityConfig
+	GetTaskFileConfig() TaskFileConfig
+	GetPlanFileConfig() PlanFileConfig
+	SetPlanFileConfig(plan PlanFileConfig) error
+	SetRepositorySync(id string, sync *SyncConfig) error
+	ConfirmRepositoryAction(id, action string) error
+	SetRepositoryScriptChecksums(id string, pins map[string]string) error
+	SetRepositoryMainlineSync(id, mode string) error
+	SetRepositoryMainlineBranch(id, branch string) error
+	SetRepositoryApprovalMode(id, mode string) error
+	GetFeatureFlags() map[string]bool
+	SetFeatureFlag(name string, enabled *bool) error
+	SetRepositoryFeatureFlag(id, name string, enabled *bool) error
+	GetLocale() string
+	SetLocale(locale string) error
+	GetTimezone() string
+	SetTimezone(timezone string) error
+	SetRepositoryAgentHours(id string, hours *ScheduleWindow) error
+	SetRepositoryAgentEnv(id string, taskID int, env map[string]string) error
+	SetRepositoryAgentSecrets(id string, names []string) error
+	SetRepositorySigning(id string, signing *SigningConfig) error
+	SetRepositoryChangelogCommit(id string, commit bool) error
+	SetRepositoryReviewChecks(id string, checks []string) error
+	SetRepositoryTriage(id string, triage TriageConfig) error
+	SetRepositorySpawner(id, mode string, maxAgents int) error
+	GetSecurityPolicy() SecurityPolicy
+	SetSecurityPolicy(policy SecurityPolicy) error
+	GetSafeMode() bool
+	GetQuotaConfig() QuotaConfig
+	SetQuotaConfig(quota QuotaConfig) error
+	SetSafeMode(enabled bool) error
+	GetRedactionConfig() RedactionConfig
+	GetSandboxAgents() bool
+	SetSandboxAgents(enabled bool) error
+	SetRedactionConfig(redaction RedactionConfig) error
+	GetAutomationConfig() AutomationConfig
+	SetAutomationConfig(automation AutomationConfig) error
+	GetStaleConfig() StaleConfig
+	SetStaleConfig(stale StaleConfig) error
+	GetSLAConfig() SLAConfig
+	SetSLAConfig(sla SLAConfig) error
+	GetWorktreeConfig() WorktreeConfig
+	SetWorktreeConfig(worktrees WorktreeConfig) error
+	GetTerminalLayout() TerminalLayout
+	SetTerminalLayout(layout TerminalLayout) error
+	GetRestrictedTerminal() RestrictedTerminalConfig
+	SetRestrictedTerminal(restricted RestrictedTerminalConfig) error
 }
 
 // Helper methods for TerminalBuffer
@@ -133,21 +294,54 @@ func NewTerminalBuffer() *TerminalBuffer {
 	}
 }
 
-// AddLine adds a line to the terminal buffer
-func (tb *TerminalBuffer) AddLine(line string) {
+// AddLine adds a line to the terminal buffer and returns the output offset
+// after it. Offsets count output as it came from the shell, before redaction.
+func (tb *TerminalBuffer) AddLine(line string) int64 {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 	
-	tb.Lines = append(tb.Lines, line)
+	tb.total += int64(len(line))
+	tb.Lines = append(tb.Lines, tb.redactor.Redact(line))
+	tb.ends = append(tb.ends, tb.total)
 	
 	// Keep only the last MaxLines and respect MaxBytes limit
 	for len(tb.Lines) > tb.MaxLines || tb.getTotalBytes() > tb.MaxBytes {
 		if len(tb.Lines) > 0 {
+			tb.start = tb.ends[0]
 			tb.Lines = tb.Lines[1:]
+			tb.ends = tb.ends[1:]
 		} else {
 			break
 		}
 	}
+	return tb.total
+}
+
+// Since returns the kept lines after output offset, with the offset after
+// each. ok is false when output from offset on is no longer all kept, in
+// which case every kept line is returned.
+func (tb *TerminalBuffer) Since(offset int64) (lines []string, ends []int64, ok bool) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	
+	ok = offset >= tb.start && offset <= tb.total
+	if !ok {
+		offset = tb.start
+	}
+	for i, end := range tb.ends {
+		if end > offset {
+			lines = append(lines, tb.Lines[i])
+			ends = append(ends, end)
+		}
+	}
+	return lines, ends, ok
+}
+
+// Offset returns the bytes of output seen so far
+func (tb *TerminalBuffer) Offset() int64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.total
 }
 
 // getTotalBytes calculates total bytes in buffer (must be called with lock held)
@@ -177,11 +371,98 @@ type App struct {
 
 	// Services
 	taskService     TaskServiceInterface
+	planService     PlanServiceInterface
 	terminalService TerminalServiceInterface
 	agentService    AgentServiceInterface
 	configService   ConfigServiceInterface
+	importService   *ImportService
+	editorService   *EditorService
+	fileTransfer    *FileTransferService
+	snapshots       *SnapshotService
+	syncService     *SyncService
+	journalService  *JournalService
+	auditService    *AuditService
+	quota           *QuotaService
+	diagnostics     *DiagnosticsService
+	healthService   *HealthService
+	jobService      *JobService
+	telemetry       *TelemetryService
+	perf            *PerformanceRecorder
 	logger          Logger
 	errorHandler    *ErrorHandler
+	trayService     *TrayService
+	hotkeyService   *HotkeyService
+	keys            KeyStore
+	automation      *AutomationService
+	milestones      *MilestoneService
+	releases        *ReleaseService
+	reviewComments  *ReviewCommentService
+	commandHistory  *CommandHistoryService
+	
+	// terminalsMu guards restoredPanes, the sessions RestoreTerminalLayout
+	// opened, so the frontend reloading doesn't open a second set
+	terminalsMu   sync.Mutex
+	restoredPanes []TerminalPane
+	
+	// taskWatchStop ends the task.json watch started at startup
+	taskWatchStop chan struct{}
+
+	// backupDir holds task.json and plan.md backups; empty keeps them next to the files
+	backupDir string
+	
+	// localizer formats notifications, reports and prompts; guarded by mu
+	localizer *Localizer
+	
+	// eventHook sees every runtime event before it is emitted (used by tests)
+	eventHook func(name RuntimeEvent, data []interface{})
+	
+	// headless is set in --serve mode, where no Wails runtime is available
+	headless        bool
+	autoPilotPaused bool
+	
+	// safeMode disables agents, terminals and git; safeModeForced is set by
+	// --safe-mode, which the UI can't turn off
+	safeMode       bool
+	safeModeForced bool
+	
+	// readOnly is set when another instance owns the config; instanceLock
+	// is held otherwise, for desktop and --serve runs
+	readOnly     bool
+	instanceLock *InstanceLock
+	
+	// shutdownChoice is what the user chose to do with running agents on
+	// quit; empty until they're asked
+	shutdownChoice string
+	
+	// quitting is set once Quit is called, so beforeClose lets it through
+	// rather than hiding the window in the tray
+	quitting bool
+}
+
+// AppDependencies are the collaborators an App is built from. Logger and the
+// task, terminal and agent services are required; ConfigService is nil in
+// fallback mode, and the remaining fields default to fresh instances.
+type AppDependencies struct {
+	Logger          Logger
+	TaskService     TaskServiceInterface
+	PlanService     PlanServiceInterface
+	TerminalService TerminalServiceInterface
+	AgentService    AgentServiceInterface
+	ConfigService   ConfigServiceInterface
+	RepoPath        string          // active repository; logs and the journal live under it
+	BackupDir       string          // where backups go; empty keeps them next to the files
+	KeyStore        KeyStore        // encryption keys; defaults to the OS keychain
+	Security        *SecurityConfig // path and origin policy; nil keeps the defaults
+	SafeMode        bool            // force safe mode on regardless of the config
+	ReadOnly        bool            // another instance owns the config: safe mode, no saves, no background work
+	Redactor        *Redactor       // secrets masked in logs and output; nil keeps the defaults
+	Localizer       *Localizer      // locale of backend-generated strings; nil is DefaultLocale
+	ErrorHandler    *ErrorHandler
+	Journal         *JournalService
+	Audit           *AuditService
+	Quota           *QuotaService
+	Telemetry       *TelemetryService
+	Performance     *PerformanceRecorder
 }
 
 // NewApp creates a new App application struct with dependency injection
@@ -206,29 +487,67 @@ func NewApp() *App {
 	}
 	
 	// Update logger with correct log directory
-	logDir := getLogDirectory(activeRepo.Path)
-	logger = NewFileLogger(logDir)
+	logDir := repositoryLogDir(configService.GetLoggingConfig().Dir, *activeRepo)
+	fileLogger := NewFileLogger(logDir)
+	if err := fileLogger.Configure(configService.GetLoggingConfig()); err != nil {
+		fileLogger.Error("Invalid logging configuration, using defaults", err)
+	}
+	redactor, err := NewRedactor(configService.GetRedactionConfig())
+	if err != nil {
+		fileLogger.Error("Invalid redaction patterns, using defaults", err)
+		redactor = DefaultRedactor()
+	}
+	fileLogger.SetRedactor(redactor)
+	logger = fileLogger
+	localizer, err := loadLocalizer(configService.GetLocale(), localeCatalogDir())
+	if err != nil {
+		logger.Error("Invalid locale, using the default", err)
+		localizer = DefaultLocalizer()
+	}
 	
 	// Initialize services
 	taskFile := filepath.Join(activeRepo.Path, "plan", "task.json")
-	taskService := NewTaskService(taskFile, logger)
 	
 	// Get security config
-	securityConfig := DefaultSecurityConfig()
-	terminalService := NewTerminalService(logger, securityConfig.AllowedOrigins)
+	securityConfig := NewSecurityConfig(configService.GetSecurityPolicy())
 	
-	agentService := NewAgentService(activeRepo.Path, logger)
+	telemetry := NewTelemetryService(telemetryFilePath(), logger)
+	telemetry.SetEnabled(configService.GetTelemetryConfig().Enabled)
 	
-	app := &App{
-		taskService:     taskService,
-		terminalService: terminalService,
-		agentService:    agentService,
-		configService:   configService,
-		logger:          logger,
-		errorHandler:    NewErrorHandler(logger),
+	taskService := NewTaskService(taskFile, logger)
+	taskService.SetChecksums(configService.GetIntegrityConfig().Checksums)
+	taskService.SetStripVolatile(configService.GetTaskFileConfig().StripVolatile)
+	
+	agentService := NewAgentService(activeRepo.Path, logger)
+	agentService.SetSandbox(configService.GetSandboxAgents())
+	agentService.SetScriptChecksums(activeRepo.ScriptChecksums)
+	agentService.SetMainlineSync(activeRepo.MainlineSync)
+	agentService.SetMainlineBranch(activeRepo.MainlineBranch)
+	agentService.SetApprovalMode(activeRepo.ApprovalMode)
+	agentService.SetSigning(activeRepo.Signing)
+	agentService.SetSpawner(activeRepo.Spawner)
+	agentService.SetMaxAgents(activeRepo.MaxAgents)
+	if maxSizeMB := configService.GetLoggingConfig().MaxSizeMB; maxSizeMB > 0 {
+		agentService.SetLogLimit(int64(maxSizeMB) * 1024 * 1024)
 	}
+	agentService.SetLogRetention(configService.GetLoggingConfig().RetentionDays)
 	
-	return app
+	return NewAppWithDependencies(AppDependencies{
+		Logger:          logger,
+		TaskService:     taskService,
+		TerminalService: NewTerminalService(logger, securityConfig),
+		AgentService:    agentService,
+		ConfigService:   configService,
+		RepoPath:        activeRepo.Path,
+		BackupDir:       repositoryBackupDir(configService.GetBackupConfig().Dir, *activeRepo),
+		Telemetry:       telemetry,
+		Security:        securityConfig,
+		SafeMode:        safeModeFlag,
+		ReadOnly:        readOnlyFlag,
+		Quota:           NewQuotaService(quotaFilePath(), configService.GetQuotaConfig(), logger),
+		Redactor:        redactor,
+		Localizer:       localizer,
+	})
 }
 
 // newAppWithoutConfig creates an app without configuration (fallback)
@@ -238,31 +557,311 @@ func newAppWithoutConfig(logger Logger) *App {
 	repo := tempConfigMgr.detectCurrentRepository()
 	
 	// Update logger with correct log directory
-	logDir := getLogDirectory(repo.Path)
-	logger = NewFileLogger(logDir)
+	logger = NewFileLogger(getLogDirectory(repo.Path))
 	
 	// Initialize services with fallback repository
 	taskFile := filepath.Join(repo.Path, "plan", "task.json")
-	taskService := NewTaskService(taskFile, logger)
 	
 	// Get security config
 	securityConfig := DefaultSecurityConfig()
-	terminalService := NewTerminalService(logger, securityConfig.AllowedOrigins)
 	
-	agentService := NewAgentService(repo.Path, logger)
+	return NewAppWithDependencies(AppDependencies{
+		Logger:          logger,
+		TaskService:     NewTaskService(taskFile, logger),
+		TerminalService: NewTerminalService(logger, securityConfig),
+		AgentService:    NewAgentService(repo.Path, logger),
+		ConfigService:   nil, // No config service in fallback mode
+		RepoPath:        repo.Path,
+		BackupDir:       repositoryBackupDir("", Repository{Path: repo.Path}),
+		Telemetry:       NewTelemetryService(telemetryFilePath(), logger),
+		SafeMode:        safeModeFlag,
+		ReadOnly:        readOnlyFlag,
+		Quota:           NewQuotaService(quotaFilePath(), QuotaConfig{}, logger),
+	})
+}
+
+// NewAppWithDependencies assembles an App from injected services, filling
+// in defaults for optional collaborators. Tests use it to swap in fakes.
+func NewAppWithDependencies(deps AppDependencies) *App {
+	logger := deps.Logger
+	logDir := getLogDirectory(deps.RepoPath)
+	
+	if deps.PlanService == nil {
+		deps.PlanService = NewPlanService(filepath.Join(deps.RepoPath, "plan", "plan.md"), logger)
+	}
+	if deps.ErrorHandler == nil {
+		deps.ErrorHandler = newCrashReportingErrorHandler(logDir, logger)
+	}
+	if deps.Journal == nil {
+		deps.Journal = NewJournalService(logDir, deps.RepoPath, logger)
+	}
+	if deps.Audit == nil {
+		deps.Audit = NewAuditService(logDir, deps.RepoPath, logger)
+	}
+	if deps.Quota == nil {
+		limits := QuotaConfig{}
+		if deps.ConfigService != nil {
+			limits = deps.ConfigService.GetQuotaConfig()
+		}
+		deps.Quota = NewQuotaService("", limits, logger)
+	}
+	if deps.Telemetry == nil {
+		deps.Telemetry = NewTelemetryService("", logger)
+	}
+	if deps.Performance == nil {
+		deps.Performance = NewPerformanceRecorder(logger)
+	}
+	if deps.KeyStore == nil {
+		deps.KeyStore = newSystemKeyStore(NewExecRunner())
+	}
+	
+	// Services that time their file and git operations share one recorder
+	type perfRecorded interface {
+		SetPerformanceRecorder(perf *PerformanceRecorder)
+	}
+	for _, service := range []interface{}{deps.TaskService, deps.PlanService, deps.AgentService} {
+		if recorded, ok := service.(perfRecorded); ok {
+			recorded.SetPerformanceRecorder(deps.Performance)
+		}
+	}
+	
+	// Branch force-deletes happen inside the agent service, so it audits them
+	// itself, as the file transfer service does transfers
+	type audited interface {
+		SetAuditLog(audit *AuditService)
+	}
+	if service, ok := deps.AgentService.(audited); ok {
+		service.SetAuditLog(deps.Audit)
+	}
+	fileTransfer := NewFileTransferService(logger)
+	fileTransfer.SetAuditLog(deps.Audit)
 	
 	app := &App{
-		taskService:     taskService,
-		terminalService: terminalService,
-		agentService:    agentService,
-		configService:   nil, // No config service in fallback mode
+		taskService:     deps.TaskService,
+		planService:     deps.PlanService,
+		terminalService: deps.TerminalService,
+		agentService:    deps.AgentService,
+		configService:   deps.ConfigService,
+		importService:   NewImportService(logger),
+		editorService:   NewEditorService(logger),
+		fileTransfer:    fileTransfer,
+		snapshots:       NewSnapshotService(logger),
+		syncService:     NewSyncService(logger),
+		journalService:  deps.Journal,
+		commandHistory:  NewCommandHistoryService(logDir, deps.RepoPath, logger),
+		auditService:    deps.Audit,
+		quota:           deps.Quota,
+		milestones:      NewMilestoneService(filepath.Join(deps.RepoPath, "plan", "milestones.json"), logger),
+		releases:        NewReleaseService(filepath.Join(deps.RepoPath, "plan", "releases.json"), logger),
+		reviewComments:  NewReviewCommentService(filepath.Join(deps.RepoPath, "plan", "review_comments.json"), logger),
+		hotkeyService:   NewHotkeyService(logger),
+		diagnostics:     NewDiagnosticsService(logger),
+		healthService:   NewHealthService(logger),
+		jobService:      NewJobService(logger),
+		telemetry:       deps.Telemetry,
+		perf:            deps.Performance,
 		logger:          logger,
-		errorHandler:    NewErrorHandler(logger),
+		errorHandler:    deps.ErrorHandler,
+		keys:            deps.KeyStore,
+		safeMode:        deps.SafeMode || deps.ReadOnly || (deps.ConfigService != nil && deps.ConfigService.GetSafeMode()),
+		safeModeForced:  deps.SafeMode || deps.ReadOnly,
+		readOnly:        deps.ReadOnly,
+	}
+	if deps.BackupDir != "" {
+		app.useBackupDir(deps.RepoPath, deps.BackupDir)
+	}
+	app.useEncryption(deps.RepoPath)
+	if deps.Security != nil {
+		app.applySecurityConfig(deps.Security)
+	}
+	if deps.Redactor != nil {
+		app.applyRedactor(deps.Redactor)
+	}
+	if deps.Localizer != nil {
+		app.applyLocalizer(deps.Localizer)
+	}
+	if deps.ReadOnly {
+		app.applyReadOnly()
+	}
+	if deps.ConfigService != nil {
+		if err := deps.TerminalService.SetRestriction(deps.ConfigService.GetRestrictedTerminal()); err != nil {
+			logger.Error("Invalid restricted terminal allowlist, terminals stay unrestricted", err)
+		}
+		app.applyPlanLimit(deps.ConfigService.GetPlanFileConfig())
 	}
 	
+	// Commands run in terminals go to the repository's command history
+	type commandCapturing interface {
+		OnCommand(record func(record CommandRecord))
+	}
+	if service, ok := deps.TerminalService.(commandCapturing); ok {
+		service.OnCommand(app.commandHistory.Record)
+	}
+	
+	// Tabs follow the titles shells and programs set
+	type titleReporting interface {
+		OnTitle(changed func(terminalID, title string))
+	}
+	if service, ok := deps.TerminalService.(titleReporting); ok {
+		service.OnTitle(func(terminalID, title string) {
+			app.emitEvent(RuntimeTerminalTitle, TerminalTitleEvent{TerminalID: terminalID, Title: title})
+		})
+	}
+	
+	var rules []AutomationRule
+	if deps.ConfigService != nil {
+		rules = deps.ConfigService.GetAutomationConfig().Rules
+	}
+	app.automation = NewAutomationService(app, rules, logger)
 	return app
 }
 
+// applyReadOnly makes the services that save tasks, the plan and the config
+// refuse to
+func (a *App) applyReadOnly() {
+	type readOnlyable interface {
+		SetReadOnly(readOnly bool)
+	}
+	for _, service := range []interface{}{a.taskService, a.planService, a.configService} {
+		if r, ok := service.(readOnlyable); ok {
+			r.SetReadOnly(true)
+		}
+	}
+}
+
+// applySecurityConfig hands the path and origin policy to the services that enforce it
+func (a *App) applySecurityConfig(config *SecurityConfig) {
+	type securityConfigurable interface {
+		SetSecurityConfig(config *SecurityConfig)
+	}
+	for _, service := range []interface{}{a.agentService, a.terminalService, a.editorService, a.fileTransfer} {
+		if configurable, ok := service.(securityConfigurable); ok {
+			configurable.SetSecurityConfig(config)
+		}
+	}
+}
+
+// applyRedactor hands the secret patterns to everything that writes logs or keeps output
+func (a *App) applyRedactor(redactor *Redactor) {
+	type redacting interface {
+		SetRedactor(redactor *Redactor)
+	}
+	for _, service := range []interface{}{a.logger, a.terminalService, a.agentService} {
+		if r, ok := service.(redacting); ok {
+			r.SetRedactor(redactor)
+		}
+	}
+}
+
+// applyLocalizer switches the locale of backend-generated strings
+func (a *App) applyLocalizer(localizer *Localizer) {
+	a.mu.Lock()
+	a.localizer = localizer
+	a.mu.Unlock()
+	if l, ok := a.agentService.(interface{ SetLocalizer(*Localizer) }); ok {
+		l.SetLocalizer(localizer)
+	}
+}
+
+// messages returns the localizer for notifications, reports and job titles
+func (a *App) messages() *Localizer {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.localizer
+}
+
+// summarizer describes events in the current locale
+func (a *App) summarizer() summarizer {
+	return summarizer{l: a.messages(), tasks: a.taskService.GetTasks}
+}
+
+// useBackupDir points task and plan backups at dir, moving any backups
+// still sitting in the repository's plan directory there
+func (a *App) useBackupDir(repoPath, dir string) {
+	if dir == "" {
+		return
+	}
+	previous := a.backupDir
+	if previous == "" {
+		previous = filepath.Join(repoPath, "plan")
+	}
+	if moved, err := migrateBackups(previous, dir); err != nil {
+		a.logger.Error("Failed to move backups", err)
+	} else if moved > 0 {
+		a.logger.InfoWithFields("Moved backups", map[string]interface{}{
+			"from":  previous,
+			"to":    dir,
+			"count": moved,
+		})
+	}
+	
+	type backupConfigurable interface {
+		SetBackupDir(dir string)
+	}
+	for _, service := range []interface{}{a.taskService, a.planService, a.milestones, a.releases, a.reviewComments} {
+		if configurable, ok := service.(backupConfigurable); ok {
+			configurable.SetBackupDir(dir)
+		}
+	}
+	a.backupDir = dir
+}
+
+// useEncryption loads the repository's key from the keychain when its files
+// are encrypted, and turns encryption off for plain repositories
+func (a *App) useEncryption(repoPath string) {
+	var cipher *Cipher
+	if a.IsRepositoryEncrypted() {
+		loaded, err := loadRepositoryCipher(a.keys, repoPath)
+		if err != nil {
+			a.logger.Error("Repository is encrypted but its key could not be loaded", err)
+		} else {
+			cipher = loaded
+		}
+	}
+	a.setCipher(cipher)
+}
+
+// setCipher turns encryption on (or off, for nil) in the services that write
+// plan data
+func (a *App) setCipher(cipher *Cipher) {
+	type encryptable interface {
+		SetCipher(cipher *Cipher)
+	}
+	for _, service := range []interface{}{a.taskService, a.planService, a.milestones, a.releases, a.reviewComments} {
+		if e, ok := service.(encryptable); ok {
+			e.SetCipher(cipher)
+		}
+	}
+}
+
+// newCrashReportingErrorHandler creates an error handler that writes crash
+// reports to logDir/crashes
+func newCrashReportingErrorHandler(logDir string, logger Logger) *ErrorHandler {
+	errorHandler := NewErrorHandler(logger)
+	errorHandler.SetCrashReportDir(filepath.Join(logDir, "crashes"))
+	return errorHandler
+}
+
+// telemetryFilePath returns where usage counters are stored, or "" if the
+// config directory is unavailable
+func telemetryFilePath() string {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "telemetry.json")
+}
+
+// quotaFilePath returns where recent agent launches are counted, or "" if
+// the config directory is unavailable
+func quotaFilePath() string {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "launches.json")
+}
+
 // getLogDirectory determines the correct log directory based on repository path
 func getLogDirectory(repoPath string) string {
 	return filepath.Join(repoPath, "logs")
@@ -276,33 +875,282 @@ func (a *App) startup(ctx context.Context) {
 	a.terminalService.SetContext(ctx)
 	a.agentService.SetContext(ctx)
 	
+	// Stream long-running operation progress to the frontend
+	a.jobService.OnUpdate(func(job Job) {
+		a.emitEvent(RuntimeJobProgress, job)
+	})
+	a.taskService.OnLoadProgress(func(progress TaskLoadProgress) {
+		a.emitEvent(RuntimeTasksLoading, progress)
+	})
+	
+	// Route recovered panics from service goroutines to the journal and UI
+	a.terminalService.SetErrorHandler(a.errorHandler)
+	type recovering interface {
+		SetErrorHandler(errorHandler *ErrorHandler)
+	}
+	for _, service := range []interface{}{a.taskService, a.agentService, a.editorService, a.hotkeyService} {
+		if r, ok := service.(recovering); ok {
+			r.SetErrorHandler(a.errorHandler)
+		}
+	}
+	a.errorHandler.OnCrash(func(report CrashReport) {
+		a.recordEvent(EventAppCrashed, 0, map[string]interface{}{
+			"goroutine": report.Goroutine,
+			"panic":     report.Panic,
+			"report":    report.File,
+		})
+		a.emitEvent(RuntimeAppCrash, report)
+	})
+	
 	// Load tasks on startup
 	if _, err := a.taskService.LoadTasks(); err != nil {
 		a.logger.Error("Failed to load tasks on startup", err)
 	} else {
 		a.logger.Info("Tasks loaded successfully on startup")
 	}
+	
+	// Self-check so the UI can explain a degraded board instead of showing blank columns
+	if report := a.GetHealth(); report.Status != HealthOK {
+		a.logger.InfoWithFields("Startup self-check found problems", map[string]interface{}{
+			"status": report.Status,
+		})
+		a.emitEvent(RuntimeHealthDegraded, report)
+	}
+	
+	// Background work belongs to the instance that owns the config
+	if !a.readOnly {
+		// Check stale-task automation rules while the app runs
+		a.automation.Start(automationCheckInterval)
+		
+		// Have idle worktrees ready before the first launch
+		a.errorHandler.Go("worktree warm-up", a.automation.keepWorktreesWarm)
+		
+		// Find tasks whose agents died with the last session
+		a.errorHandler.Go("interrupted agent check", a.recoverInterruptedAgents)
+		
+		// Notice agents editing task.json, such as handing a task over for review
+		a.taskWatchStop = make(chan struct{})
+		stop := a.taskWatchStop
+		a.errorHandler.Go("task file watch", func() { a.watchTaskFile(stop) })
+	}
+	
+	if !a.headless {
+		a.trayService = NewTrayService(a, a.logger)
+		a.trayService.Start()
+		
+		hotkey := ""
+		if a.configService != nil {
+			hotkey = a.configService.GetQuickAddHotkey()
+		}
+		// A missing hotkey is not fatal; the error is already logged
+		a.hotkeyService.Register(hotkey, a.openQuickAdd)
+		
+		if !a.readOnly {
+			a.startRestAPI()
+		}
+	}
+}
+
+// startRestAPI serves the REST API alongside the desktop window when the
+// remote config gives it an address
+func (a *App) startRestAPI() {
+	if a.configService == nil {
+		return
+	}
+	config := a.configService.GetRemoteConfig()
+	if config.API == "" {
+		return
+	}
+	server := NewRemoteServer(a, nil, config, a.logger)
+	a.errorHandler.Go("rest api server", func() {
+		if err := server.ListenAndServeAPI(config.API); err != nil {
+			a.logger.Error("REST API server failed", err)
+		}
+	})
+}
+
+// shutdown is called when the app is about to quit
+func (a *App) shutdown(ctx context.Context) {
+	if a.trayService != nil {
+		a.trayService.Stop()
+	}
+	a.hotkeyService.Unregister()
+	a.automation.Stop()
+	
+	// Tell terminal clients the app is going away rather than letting them
+	// see a dropped connection
+	if closer, ok := a.terminalService.(interface{ CloseConnections() }); ok {
+		closer.CloseConnections()
+	}
+	if a.taskWatchStop != nil {
+		close(a.taskWatchStop)
+	}
+	if err := a.taskService.Flush(); err != nil {
+		a.logger.Error("Failed to save journaled task edits", err)
+	}
+	if err := a.telemetry.Flush(); err != nil {
+		a.logger.Error("Failed to save telemetry", err)
+	}
+	if a.instanceLock != nil {
+		a.instanceLock.Release()
+	}
+	a.logger.Info("Application shutting down")
+}
+
+// emitEvent sends an event to the frontend when running under Wails. name
+// must be listed in runtimeEvents, with data matching its payload type.
+func (a *App) emitEvent(name RuntimeEvent, data ...interface{}) {
+	if a.eventHook == nil && (a.ctx == nil || a.headless) {
+		return
+	}
+	// Every event ends with its summary, for screen readers and notifications
+	var payload interface{}
+	if len(data) > 0 {
+		payload = data[0]
+	}
+	data = append(data[:len(data):len(data)], a.summarizer().runtime(name, payload))
+	if a.eventHook != nil {
+		a.eventHook(name, data)
+	}
+	if a.ctx == nil || a.headless {
+		return
+	}
+	runtime.EventsEmit(a.ctx, string(name), data...)
+}
+
+// recordEvent appends an action to the journal and notifies the frontend
+func (a *App) recordEvent(eventType string, taskID int, data map[string]interface{}) {
+	if a.journalService == nil {
+		return
+	}
+	a.telemetry.Increment(eventType)
+	entry := a.journalService.Record(eventType, taskID, data)
+	entry.Summary = a.summarizer().entry(entry)
+	a.emitEvent(RuntimeJournalEntry, entry)
+	a.automation.Dispatch(entry)
 }
 
 // Task-related API methods
 
 // LoadTasks reloads tasks from disk and returns them
 func (a *App) LoadTasks() ([]Task, error) {
-	return a.taskService.LoadTasks()
+	before := a.taskService.GetTasks()
+	tasks, err := a.taskService.LoadTasks()
+	if err == nil {
+		a.noticeStatusChanges(before, tasks, false)
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Type == ErrorTypeCorrupted {
+		// Let the frontend offer the newest valid backup for restore
+		file, _ := appErr.Context["file"].(string)
+		backup, _ := appErr.Context["backup"].(string)
+		a.emitEvent(RuntimeTasksCorrupted, TasksCorruptedEvent{File: file, Backup: backup})
+	}
+	return tasks, err
 }
 
 // SaveTasks writes tasks to the plan/task.json file with atomic operation
 func (a *App) SaveTasks(tasks []Task) error {
-	return a.taskService.SaveTasks(tasks)
+	if err := a.taskService.SaveTasks(tasks); err != nil {
+		return err
+	}
+	a.recordEvent(EventTasksSaved, 0, map[string]interface{}{
+		"count": len(tasks),
+	})
+	return nil
+}
+
+// CreateTask adds a new backlog task to the active repository. #tags in the
+// title become the task's tags, and the repository's triage rules may route
+// it by them; a task without a priority gets the triage default.
+func (a *App) CreateTask(title string, priority string) (Task, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return Task{}, ValidationError("task title must not be empty", nil)
+	}
+	if priority != "" && !TaskPriority(priority).Valid() {
+		return Task{}, ValidationError("invalid task priority", nil).WithContext("priority", priority)
+	}
+	triage := a.triageConfig()
+	title, tags := titleTags(title)
+	if priority == "" {
+		priority = string(triage.defaultPriority())
+	}
+	task, err := a.taskService.CreateTask(title, priority)
+	if err != nil {
+		return task, err
+	}
+	if len(tags) > 0 {
+		task.Tags = tags
+		triage.triage(&task)
+		if err := a.taskService.UpdateTask(task); err != nil {
+			return task, err
+		}
+	}
+	a.recordEvent(EventTaskCreated, task.ID, map[string]interface{}{
+		"title":    task.Title,
+		"priority": task.Priority,
+		"status":   task.Status,
+		"tags":     task.Tags,
+	})
+	return task, nil
 }
 
 // UpdateTask updates a specific task
 func (a *App) UpdateTask(task Task) error {
-	return a.taskService.UpdateTask(task)
+	if err := a.taskService.UpdateTask(task); err != nil {
+		return err
+	}
+	a.recordEvent(EventTaskUpdated, task.ID, map[string]interface{}{
+		"title":    task.Title,
+		"status":   task.Status,
+		"priority": task.Priority,
+	})
+	return nil
+}
+
+// UpdateTaskDescription replaces a task's description and notes, both
+// markdown; agents get them in their prompt
+func (a *App) UpdateTaskDescription(taskID int, description, notes string) error {
+	task, ok := findTask(a.taskService.GetTasks(), taskID)
+	if !ok {
+		return NotFoundError("task not found", nil).WithContext("task_id", taskID)
+	}
+	task.Description = description
+	task.Notes = notes
+	if err := a.taskService.UpdateTask(task); err != nil {
+		return err
+	}
+	a.recordEvent(EventTaskUpdated, taskID, map[string]interface{}{
+		"descriptionBytes": len(description),
+		"notesBytes":       len(notes),
+	})
+	return nil
 }
 
-// MoveTask moves a task to a different status column
+// MoveTask moves a task to a different status column. A task can't move
+// from todo to doing until its dependencies are done.
 func (a *App) MoveTask(taskID int, newStatus string) error {
+	return a.moveTask(taskID, newStatus, false, false)
+}
+
+// ForceMoveTask moves a task like MoveTask, starting it even though some of
+// its dependencies aren't done
+func (a *App) ForceMoveTask(taskID int, newStatus string) error {
+	return a.moveTask(taskID, newStatus, false, true)
+}
+
+// GetBlockedTasks lists the backlog and todo tasks waiting on dependencies
+// that aren't done
+func (a *App) GetBlockedTasks() []BlockedTask {
+	return blockedTasks(a.taskService.GetTasks())
+}
+
+// moveTask moves a task and launches an agent on todo → doing. When wait is
+// true the agent launch runs synchronously (used by the CLI, which exits
+// as soon as the command returns). force lets a task start before its
+// dependencies are done.
+func (a *App) moveTask(taskID int, newStatus string, wait, force bool) error {
 	// Wrap in error handler for panic recovery
 	return a.errorHandler.WithRecover(func() error {
 		// Get the task to check the old status
@@ -332,170 +1180,4337 @@ func (a *App) MoveTask(taskID int, newStatus string) error {
 				WithContext("task_id", taskID)
 		}
 		
+		moved := map[string]interface{}{
+			"from": oldStatus,
+			"to":   updatedTask.Status,
+		}
+		if oldStatus == StatusTodo && updatedTask.Status == StatusDoing {
+			if deps := unfinishedDeps(updatedTask, doneTaskIDs(tasks)); len(deps) > 0 {
+				if !force {
+					return ConflictError("task is blocked by unfinished dependencies", nil).
+						WithContext("task_id", taskID).
+						WithContext("blocked_by", deps)
+				}
+				moved["overrodeDeps"] = deps
+			}
+		}
+		
+		// A first agent launch here needs confirming before the task moves
+		if oldStatus == StatusTodo && updatedTask.Status == StatusDoing && !a.IsAutoPilotPaused() && !a.IsSafeMode() && a.featureEnabled(FeatureAutoPilot) && a.withinAgentHours(time.Now()) {
+			if err := a.requireConfirmation(ConfirmAgentSpawn); err != nil {
+				return err
+			}
+		}
+		
 		// Move the task
 		if err := a.taskService.MoveTask(taskID, newStatus); err != nil {
 			return a.errorHandler.Handle(err)
 		}
+		a.recordEvent(EventTaskMoved, taskID, moved)
 		
 		// Only launch Claude agent if moving from "todo" to "doing"
 		if oldStatus == StatusTodo && updatedTask.Status == StatusDoing {
-			go func() {
-				defer a.errorHandler.RecoverPanic()
-				if err := a.agentService.LaunchClaudeAgent(updatedTask); err != nil {
+			if a.IsAutoPilotPaused() {
+				a.logger.InfoWithFields("Auto-pilot paused, not launching agent", map[string]interface{}{
+					"task_id": taskID,
+				})
+				return nil
+			}
+			if a.IsSafeMode() {
+				a.logger.InfoWithFields("Safe mode on, not launching agent", map[string]interface{}{
+					"task_id": taskID,
+				})
+				return nil
+			}
+			if !a.featureEnabled(FeatureAutoPilot) {
+				a.logger.InfoWithFields("Auto-pilot feature off, not launching agent", map[string]interface{}{
+					"task_id": taskID,
+				})
+				return nil
+			}
+			if now := time.Now(); !a.withinAgentHours(now) {
+				a.logger.InfoWithFields("Outside agent hours, not launching agent", map[string]interface{}{
+					"task_id": taskID,
+					"opens":   a.agentHours().Next(now, a.timezone()).UTC().Format(time.RFC3339),
+				})
+				return nil
+			}
+
+			if wait {
+				if err := a.launchAgent(updatedTask); err != nil {
+					return a.errorHandler.Handle(err)
+				}
+				return nil
+			}
+			
+			a.errorHandler.Go("agent launch", func() {
+				if err := a.launchAgent(updatedTask); err != nil {
 					a.errorHandler.Handle(err)
 				}
-			}()
+			})
 		}
 		
 		return nil
 	})
 }
 
-// GetTasksByStatus returns tasks filtered by status
-func (a *App) GetTasksByStatus(status string) ([]Task, error) {
-	return a.taskService.GetTasksByStatus(status)
+// launchAgent starts a Claude agent for task and journals the outcome
+func (a *App) launchAgent(task Task) error {
+	return a.relaunchAgent(task, AgentRelaunch{})
 }
 
-// ApproveTask merges the task branch and marks task as done
-func (a *App) ApproveTask(taskID int) error {
-	// Get task info
-	tasks := a.taskService.GetTasks()
-	var task Task
-	found := false
-	
-	for _, t := range tasks {
-		if t.ID == taskID {
-			if t.Status != StatusPendingReview {
-				return fmt.Errorf("task %d is not in pending_review status", taskID)
-			}
-			task = t
-			found = true
-			break
-		}
+// relaunchAgent starts a Claude agent for task, picking up its earlier run
+// as relaunch says, and journals the outcome
+func (a *App) relaunchAgent(task Task, relaunch AgentRelaunch) error {
+	if err := a.requireExecution("launching agents"); err != nil {
+		return err
 	}
-	
-	if !found {
-		return fmt.Errorf("task with ID %d not found", taskID)
+	if err := a.requireConfirmation(ConfirmAgentSpawn); err != nil {
+		return err
 	}
-	
-	// Approve through agent service
-	if err := a.agentService.ApproveTask(taskID, task.Title); err != nil {
+	if err := a.requireClaude(); err != nil {
+		a.recordEvent(EventAgentFailed, task.ID, map[string]interface{}{
+			"error": err.Error(),
+		})
 		return err
 	}
-	
-	// Update task status to done
-	task.Status = StatusDone
-	if err := a.taskService.UpdateTask(task); err != nil {
-		return fmt.Errorf("failed to update task status after approval: %v", err)
+	env, err := a.agentEnv(task.ID)
+	if err != nil {
+		a.recordEvent(EventAgentFailed, task.ID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	if a.GetWorktreeConfig().MaxTotalGB > 0 {
+		// Make room before the spawner picks or creates a worktree
+		if _, err := a.PruneWorktrees(); err != nil {
+			a.logger.Error("Failed to keep worktrees within budget", err)
+		}
+	}
+	if err := a.quota.Acquire(a.agentService.GetProjectRoot()); err != nil {
+		a.emitEvent(RuntimeQuotaExceeded, a.GetQuotaStatus())
+		a.recordEvent(EventAgentFailed, task.ID, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	
+	title := a.messages().T(MsgJobAgentLaunch, task.ID)
+	runID := newAgentRunID(time.Now())
+	sessionID := ""
+	if relaunch.Mode != RelaunchResume && a.agentService.ClaudeCapabilities(false).Supports(ClaudeFlagSessionID) {
+		// Naming the session up front lets its transcript be found even
+		// without a JSON result
+		sessionID = newAgentSessionID()
+	}
+	err = a.runJob(JobKindAgentLaunch, title, func(job *JobHandle) error {
+		ctx := withAgentRelaunch(withAgentSessionID(withAgentRunID(job.Context(), runID), sessionID), relaunch)
+		ctx = withAgentEnv(ctx, env)
+		return a.agentService.LaunchClaudeAgentContext(ctx, task)
+	})
+	a.auditService.Record(AuditAgentSpawned, task.ID, map[string]interface{}{
+		"title": task.Title,
+		"runId": runID,
+	}, err)
+	if err != nil {
+		a.recordEvent(EventAgentFailed, task.ID, map[string]interface{}{
+			"error": err.Error(),
+			"runId": runID,
+		})
+		return err
+	}
+	launched := map[string]interface{}{
+		"title":    task.Title,
+		"priority": task.Priority,
+		"runId":    runID,
+		"branch":   taskBranch(task.ID),
+	}
+	if sessionID != "" {
+		launched["sessionId"] = sessionID
+	}
+	if relaunch.Mode != "" {
+		launched["relaunch"] = relaunch.Mode
+	}
+	if relaunch.SessionID != "" {
+		// Until a result says otherwise, the run carries on that session
+		launched["sessionId"] = relaunch.SessionID
+	}
+	a.recordEvent(EventAgentLaunched, task.ID, launched)
+	if a.GetWorktreeConfig().WarmPool > 0 {
+		// The launch took an idle worktree; replace it before the next one
+		a.errorHandler.Go("worktree warm-up", a.automation.keepWorktreesWarm)
+	}
+	return nil
+}
+
+// GetTasksByStatus returns tasks filtered by status
+func (a *App) GetTasksByStatus(status string) ([]Task, error) {
+	return a.taskService.GetTasksByStatus(status)
+}
+
+// PlanSprint proposes backlog tasks to pull into todo within capacityPoints,
+// by priority and dependencies. Unestimated tasks count as one point.
+// Nothing moves until the proposal is passed to AcceptSprintPlan.
+func (a *App) PlanSprint(capacityPoints int) (SprintPlan, error) {
+	if capacityPoints <= 0 {
+		return SprintPlan{}, ValidationError("sprint capacity must be at least one point", nil).
+			WithContext("capacity", capacityPoints)
+	}
+	return planSprint(a.taskService.GetTasks(), capacityPoints), nil
+}
+
+// AcceptSprintPlan moves the accepted backlog tasks to todo in a single write:
+// either every task moves or none do
+func (a *App) AcceptSprintPlan(taskIDs []int) error {
+	tasks := a.taskService.GetTasks()
+	accepted := make(map[int]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		task, ok := findTask(tasks, id)
+		if !ok {
+			return NotFoundError("task not found", nil).WithContext("task_id", id)
+		}
+		if task.Status != StatusBacklog {
+			return ConflictError("task is no longer in the backlog", nil).
+				WithContext("task_id", id).
+				WithContext("status", task.Status)
+		}
+		accepted[id] = true
+	}
+	
+	inBacklog := map[int]bool{}
+	for _, task := range tasks {
+		if task.Status == StatusBacklog {
+			inBacklog[task.ID] = true
+		}
+	}
+	for i, task := range tasks {
+		if !accepted[task.ID] {
+			continue
+		}
+		if !sprintReady(task, inBacklog, accepted) {
+			return ConflictError("task depends on backlog tasks that are not in the sprint", nil).
+				WithContext("task_id", task.ID)
+		}
+		tasks[i].Status = StatusTodo
+	}
+	
+	if err := a.taskService.SaveTasks(tasks); err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if !accepted[task.ID] {
+			continue
+		}
+		a.recordEvent(EventTaskMoved, task.ID, map[string]interface{}{
+			"from":   StatusBacklog,
+			"to":     StatusTodo,
+			"sprint": true,
+		})
+	}
+	return nil
+}
+
+// GetBoardLayout groups the board into swimlanes by parent, priority or
+// milestone, with done counts per lane
+func (a *App) GetBoardLayout(groupBy string) (BoardLayout, error) {
+	milestones := []Milestone{}
+	if strings.EqualFold(strings.TrimSpace(groupBy), GroupByMilestone) {
+		var err error
+		if milestones, err = a.milestones.List(); err != nil {
+			return BoardLayout{}, err
+		}
+	}
+	return buildBoardLayout(groupBy, a.taskService.GetTasks(), milestones)
+}
+
+// ApproveTask merges the task branch and marks task as done
+func (a *App) ApproveTask(taskID int) error {
+	// Get task info
+	tasks := a.taskService.GetTasks()
+	var task Task
+	found := false
+	
+	for _, t := range tasks {
+		if t.ID == taskID {
+			if t.Status != StatusPendingReview {
+				return ConflictError("task is not awaiting review", nil).
+					WithContext("task_id", taskID).
+					WithContext("status", t.Status)
+			}
+			task = t
+			found = true
+			break
+		}
+	}
+	
+	if !found {
+		return NotFoundError("task not found", nil).WithContext("task_id", taskID)
+	}
+	
+	if err := a.requireExecution("merging task branches"); err != nil {
+		return err
+	}
+	if err := a.requireConfirmation(ConfirmMerge); err != nil {
+		return err
+	}
+	
+	// Approve through agent service, describing the merge as the agent was
+	// asked to do it
+	spawnedTitle, branch := a.spawnMetadata(task)
+	title := a.messages().T(MsgJobMerge, taskID)
+	err := a.runJob(JobKindMerge, title, func(job *JobHandle) error {
+		return a.agentService.ApproveTaskContext(job.Context(), taskID, spawnedTitle)
+	})
+	a.auditService.Record(AuditBranchMerged, taskID, map[string]interface{}{
+		"branch": branch,
+		"title":  spawnedTitle,
+	}, err)
+	if err != nil {
+		return err
+	}
+	
+	// Update task status to done
+	task.Status = StatusDone
+	if err := a.taskService.UpdateTask(task); err != nil {
+		return fmt.Errorf("failed to update task status after approval: %v", err)
+	}
+	a.recordEvent(EventTaskApproved, taskID, map[string]interface{}{
+		"title":        task.Title,
+		"spawnedTitle": spawnedTitle,
+		"branch":       branch,
+	})
+	
+	return nil
+}
+
+// RejectTask deletes the task branch and marks task as done with NOT MERGED prefix
+func (a *App) RejectTask(taskID int) error {
+	// Get task info
+	tasks := a.taskService.GetTasks()
+	var task Task
+	found := false
+	
+	for _, t := range tasks {
+		if t.ID == taskID {
+			if t.Status != StatusPendingReview {
+				return ConflictError("task is not awaiting review", nil).
+					WithContext("task_id", taskID).
+					WithContext("status", t.Status)
+			}
+			task = t
+			found = true
+			break
+		}
+	}
+	
+	if !found {
+		return NotFoundError("task not found", nil).WithContext("task_id", taskID)
+	}
+	
+	if err := a.requireExecution("deleting task branches"); err != nil {
+		return err
+	}
+	
+	// Reject through agent service
+	spawnedTitle, branch := a.spawnMetadata(task)
+	if err := a.agentService.RejectTask(taskID, spawnedTitle); err != nil {
+		return err
+	}
+	
+	// Update task with NOT MERGED prefix and done status
+	if task.Title != "" && !strings.HasPrefix(task.Title, "NOT MERGED: ") {
+		task.Title = "NOT MERGED: " + task.Title
+	}
+	task.Status = StatusDone
+	
+	if err := a.taskService.UpdateTask(task); err != nil {
+		return fmt.Errorf("failed to update task status after rejection: %v", err)
+	}
+	a.recordEvent(EventTaskRejected, taskID, map[string]interface{}{
+		"title":        task.Title,
+		"spawnedTitle": spawnedTitle,
+		"branch":       branch,
+	})
+	
+	return nil
+}
+
+// spawnMetadata returns the title and branch task's latest agent was launched
+// with. Tasks with no journaled launch fall back to the live title.
+func (a *App) spawnMetadata(task Task) (string, string) {
+	if a.journalService != nil {
+		entries, err := a.journalService.Query(JournalQuery{
+			Types:  []string{EventAgentLaunched},
+			TaskID: task.ID,
+			Limit:  1,
+		})
+		if err != nil {
+			a.logger.Error("Failed to read the agent launch for a task", err)
+		}
+		if runs := agentRuns(entries, nil); len(runs) > 0 && runs[0].SpawnedTitle != "" {
+			return runs[0].SpawnedTitle, runs[0].Branch
+		}
+	}
+	return task.Title, taskBranch(task.ID)
+}
+
+// ImportBoard imports a Trello or GitHub Projects export into the task file.
+// mapping maps source column names to task statuses; it may be empty.
+func (a *App) ImportBoard(format string, data []byte, mapping map[string]string) ([]Task, error) {
+	existing, err := a.taskService.LoadTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing tasks: %v", err)
+	}
+	
+	imported, err := a.importService.ImportBoard(format, data, mapping, existing, a.triageConfig())
+	if err != nil {
+		a.logger.Error("Failed to import board", err)
+		return nil, err
+	}
+	
+	if len(imported) == 0 {
+		return imported, nil
+	}
+	
+	merged := append(append([]Task{}, existing...), imported...)
+	if err := a.taskService.SaveTasks(merged); err != nil {
+		return nil, fmt.Errorf("failed to save imported tasks: %v", err)
+	}
+	
+	a.logger.InfoWithFields("Board imported", map[string]interface{}{
+		"format": format,
+		"count":  len(imported),
+	})
+	a.recordEvent(EventTasksImported, 0, map[string]interface{}{
+		"format": format,
+		"count":  len(imported),
+	})
+	
+	return imported, nil
+}
+
+// ListTaskBackups returns the automatic backups of task.json, newest first
+func (a *App) ListTaskBackups() ([]TaskBackup, error) {
+	return a.taskService.ListBackups()
+}
+
+// PreviewTaskBackup shows how a backup differs from the current tasks
+func (a *App) PreviewTaskBackup(name string) (TaskBackupPreview, error) {
+	return a.taskService.PreviewBackup(name)
+}
+
+// RestoreTaskBackup replaces the current tasks with a backup's
+func (a *App) RestoreTaskBackup(name string) error {
+	if err := a.taskService.RestoreBackup(name); err != nil {
+		a.logger.Error("Failed to restore task backup", err)
+		return err
+	}
+	a.recordEvent(EventTasksRestored, 0, map[string]interface{}{
+		"backup": name,
+	})
+	return nil
+}
+
+// ResolveTaskConflict settles edits made in the app that conflict with
+// edits to task.json on disk, which LoadTasks reports as a conflict:
+// keepMine writes the app's edits over the file, otherwise they are dropped
+func (a *App) ResolveTaskConflict(keepMine bool) error {
+	if err := a.taskService.ResolveConflict(keepMine); err != nil {
+		return err
+	}
+	_, err := a.LoadTasks()
+	return err
+}
+
+// Aggregate board API methods
+
+// LoadAllTasks returns the tasks of every configured repository, each tagged
+// with its repository. Repositories that can't be read are reported in
+// Errors rather than failing the whole board.
+func (a *App) LoadAllTasks() (AggregateBoard, error) {
+	board := AggregateBoard{Tasks: []RepoTask{}, Errors: []RepoBoardError{}}
+	if a.configService == nil {
+		return board, fmt.Errorf("configuration not initialized")
+	}
+	repos, err := a.configService.GetRepositories()
+	if err != nil {
+		return board, err
+	}
+	
+	for _, repo := range repos {
+		tasks, err := a.loadRepositoryTasks(repo)
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to load tasks from %s", repo.Path), err)
+			board.Errors = append(board.Errors, RepoBoardError{RepoID: repo.ID, RepoName: repo.Name, Error: err.Error()})
+			continue
+		}
+		board.Tasks = append(board.Tasks, tagRepoTasks(repo, tasks)...)
+	}
+	return board, nil
+}
+
+// loadRepositoryTasks returns repo's tasks. The active board may hold edits
+// not yet folded into task.json, so its tasks come from memory.
+func (a *App) loadRepositoryTasks(repo Repository) ([]Task, error) {
+	if a.isActiveRepository(repo.ID) {
+		return a.taskService.GetTasks(), nil
+	}
+	service, err := a.repositoryTaskService(repo)
+	if err != nil {
+		return nil, err
+	}
+	return service.LoadTasks()
+}
+
+// MoveRepoTask moves a task on the aggregate board in the repository it
+// belongs to. Agents only launch in the active repository, so starting a
+// task elsewhere asks the user to switch first.
+func (a *App) MoveRepoTask(repoID string, taskID int, newStatus string) error {
+	if a.isActiveRepository(repoID) {
+		return a.MoveTask(taskID, newStatus)
+	}
+	status, err := ParseTaskStatus(newStatus)
+	if err != nil {
+		return ValidationError("invalid task status", err).
+			WithContext("task_id", taskID).
+			WithContext("new_status", newStatus)
+	}
+	
+	return a.withRepositoryTasks(repoID, func(repo Repository, service *TaskService) (map[string]interface{}, error) {
+		task, ok := findTask(service.GetTasks(), taskID)
+		if !ok {
+			return nil, NotFoundError("task not found", nil).
+				WithContext("task_id", taskID).
+				WithContext("repository", repo.Name)
+		}
+		if task.Status == StatusTodo && status == StatusDoing {
+			return nil, ConflictError("switch to this repository to launch its agent", nil).
+				WithContext("task_id", taskID).
+				WithContext("repository", repo.Name)
+		}
+		if err := service.MoveTask(taskID, newStatus); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"from": task.Status, "to": status}, nil
+	}, EventTaskMoved, taskID)
+}
+
+// UpdateRepoTask updates a task on the aggregate board in the repository it
+// belongs to
+func (a *App) UpdateRepoTask(repoID string, task Task) error {
+	if a.isActiveRepository(repoID) {
+		return a.UpdateTask(task)
+	}
+	return a.withRepositoryTasks(repoID, func(repo Repository, service *TaskService) (map[string]interface{}, error) {
+		if err := service.UpdateTask(task); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"title":    task.Title,
+			"status":   task.Status,
+			"priority": task.Priority,
+		}, nil
+	}, EventTaskUpdated, task.ID)
+}
+
+// withRepositoryTasks runs edit against an inactive repository's tasks,
+// writes them back and journals eventType into that repository's journal
+func (a *App) withRepositoryTasks(repoID string, edit func(Repository, *TaskService) (map[string]interface{}, error), eventType string, taskID int) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	repo, err := a.findRepository(repoID)
+	if err != nil {
+		return err
+	}
+	service, err := a.repositoryTaskService(repo)
+	if err != nil {
+		return err
+	}
+	if _, err := service.LoadTasks(); err != nil {
+		return err
+	}
+	data, err := edit(repo, service)
+	if err != nil {
+		return err
+	}
+	if err := service.Flush(); err != nil {
+		return err
+	}
+	
+	data["repository"] = repo.Name
+	a.telemetry.Increment(eventType)
+	NewJournalService(getLogDirectory(repo.Path), repo.Path, a.logger).Record(eventType, taskID, data)
+	return nil
+}
+
+// repositoryTaskService opens repo's task.json with the same backup,
+// encryption and format settings as the active board
+func (a *App) repositoryTaskService(repo Repository) (*TaskService, error) {
+	service := NewTaskService(repositoryTaskFile(repo), a.logger)
+	service.SetChecksums(a.configService.GetIntegrityConfig().Checksums)
+	service.SetStripVolatile(a.configService.GetTaskFileConfig().StripVolatile)
+	if dir := repositoryBackupDir(a.configService.GetBackupConfig().Dir, repo); dir != "" {
+		service.SetBackupDir(dir)
+	}
+	if fileIsEncrypted(service.GetTaskFile()) {
+		cipher, err := loadRepositoryCipher(a.keys, repo.Path)
+		if err != nil {
+			return nil, PermissionError("repository is encrypted but its key could not be loaded", err).
+				WithContext("repository", repo.Name)
+		}
+		service.SetCipher(cipher)
+	}
+	return service, nil
+}
+
+// isActiveRepository reports whether repoID names the active repository
+func (a *App) isActiveRepository(repoID string) bool {
+	if a.configService == nil {
+		return false
+	}
+	active, err := a.configService.GetActiveRepository()
+	return err == nil && active.ID == repoID
+}
+
+// findRepository returns the configured repository with id
+func (a *App) findRepository(id string) (Repository, error) {
+	repos, err := a.configService.GetRepositories()
+	if err != nil {
+		return Repository{}, err
+	}
+	for _, repo := range repos {
+		if repo.ID == id {
+			return repo, nil
+		}
+	}
+	return Repository{}, NotFoundError("repository not found", nil).WithContext("repository", id)
+}
+
+// Plan-related API methods
+
+// LoadPlan loads the plan.md file and returns its content
+func (a *App) LoadPlan() (string, error) {
+	return a.planService.LoadPlan()
+}
+
+// SavePlan saves content to the plan.md file
+func (a *App) SavePlan(content string) error {
+	if err := a.planService.SavePlan(content); err != nil {
+		return err
+	}
+	a.recordEvent(EventPlanSaved, 0, map[string]interface{}{
+		"bytes": len(content),
+	})
+	return nil
+}
+
+// LoadPlanChunk returns up to length bytes of plan.md from offset, for a
+// document over the plan size limit. Load from each chunk's Next until EOF;
+// a length of 0 is the largest chunk.
+func (a *App) LoadPlanChunk(offset int64, length int) (PlanChunk, error) {
+	return a.planService.LoadPlanChunk(offset, length)
+}
+
+// GetPlanFileConfig returns the plan.md size limit
+func (a *App) GetPlanFileConfig() PlanFileConfig {
+	if a.configService == nil {
+		return PlanFileConfig{}
+	}
+	return a.configService.GetPlanFileConfig()
+}
+
+// SetPlanFileConfig saves the plan.md size limit and applies it
+func (a *App) SetPlanFileConfig(plan PlanFileConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if plan.MaxSizeKB < 0 {
+		return ValidationError("plan size limit must not be negative", nil).
+			WithContext("maxSizeKB", plan.MaxSizeKB)
+	}
+	if err := a.configService.SetPlanFileConfig(plan); err != nil {
+		return err
+	}
+	
+	a.applyPlanLimit(plan)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"planMaxSizeKB": plan.MaxSizeKB,
+	})
+	return nil
+}
+
+// applyPlanLimit hands the plan.md size limit to the plan service
+func (a *App) applyPlanLimit(plan PlanFileConfig) {
+	type limited interface {
+		SetMaxSize(bytes int64)
+	}
+	if service, ok := a.planService.(limited); ok {
+		service.SetMaxSize(int64(plan.MaxSizeKB) * 1024)
+	}
+}
+
+// Milestone API methods
+
+// GetMilestones returns the active repository's milestones
+func (a *App) GetMilestones() ([]Milestone, error) {
+	return a.milestones.List()
+}
+
+// GetMilestoneProgress returns each milestone with how many of its tasks are done
+func (a *App) GetMilestoneProgress() ([]MilestoneProgress, error) {
+	milestones, err := a.milestones.List()
+	if err != nil {
+		return nil, err
+	}
+	tasks := a.taskService.GetTasks()
+	now := a.localNow()
+	progress := make([]MilestoneProgress, 0, len(milestones))
+	for _, milestone := range milestones {
+		progress = append(progress, milestoneProgress(milestone, tasks, now))
+	}
+	return progress, nil
+}
+
+// CreateMilestone adds a milestone holding taskIDs, in order. dueDate is
+// YYYY-MM-DD or empty.
+func (a *App) CreateMilestone(name, dueDate string, taskIDs []int) (Milestone, error) {
+	if err := a.requireTasks(taskIDs); err != nil {
+		return Milestone{}, err
+	}
+	milestone, err := a.milestones.Create(Milestone{Name: name, DueDate: dueDate, TaskIDs: taskIDs})
+	if err != nil {
+		return Milestone{}, err
+	}
+	a.recordEvent(EventMilestoneCreated, 0, map[string]interface{}{
+		"milestone": milestone.ID,
+		"name":      milestone.Name,
+		"tasks":     len(milestone.TaskIDs),
+	})
+	return milestone, nil
+}
+
+// UpdateMilestone replaces a milestone's name, due date and tasks
+func (a *App) UpdateMilestone(milestone Milestone) (Milestone, error) {
+	if err := a.requireTasks(milestone.TaskIDs); err != nil {
+		return Milestone{}, err
+	}
+	milestone, err := a.milestones.Update(milestone)
+	if err != nil {
+		return Milestone{}, err
+	}
+	a.recordEvent(EventMilestoneUpdated, 0, map[string]interface{}{
+		"milestone": milestone.ID,
+		"name":      milestone.Name,
+		"tasks":     len(milestone.TaskIDs),
+	})
+	return milestone, nil
+}
+
+// DeleteMilestone removes a milestone; its tasks stay on the board
+func (a *App) DeleteMilestone(id string) error {
+	if err := a.milestones.Delete(id); err != nil {
+		return err
+	}
+	a.recordEvent(EventMilestoneDeleted, 0, map[string]interface{}{
+		"milestone": id,
+	})
+	return nil
+}
+
+// GetCriticalPath returns the longest chain of unfinished dependent tasks,
+// weighted by estimate, and each task's slack. With a milestone ID only its
+// tasks and what they depend on are considered; empty means the whole board.
+func (a *App) GetCriticalPath(milestoneID string) (CriticalPath, error) {
+	var scope []int
+	if milestoneID != "" {
+		milestones, err := a.milestones.List()
+		if err != nil {
+			return CriticalPath{}, err
+		}
+		found := false
+		for _, milestone := range milestones {
+			if milestone.ID == milestoneID {
+				scope, found = append([]int{}, milestone.TaskIDs...), true
+				break
+			}
+		}
+		if !found {
+			return CriticalPath{}, NotFoundError("milestone not found", nil).WithContext("milestone", milestoneID)
+		}
+	}
+	path, cycle := computeCriticalPath(a.taskService.GetTasks(), scope)
+	if cycle != nil {
+		return CriticalPath{}, ConflictError("task dependencies form a cycle", nil).WithContext("task_ids", cycle)
+	}
+	path.MilestoneID = milestoneID
+	return path, nil
+}
+
+// requireTasks refuses task IDs that aren't on the board
+func (a *App) requireTasks(taskIDs []int) error {
+	known := map[int]bool{}
+	for _, task := range a.taskService.GetTasks() {
+		known[task.ID] = true
+	}
+	for _, id := range taskIDs {
+		if !known[id] {
+			return ValidationError("task not found", nil).WithContext("task_id", id)
+		}
+	}
+	return nil
+}
+
+// Snapshot API methods
+
+// CreateSnapshot archives the active repository's plan directory
+func (a *App) CreateSnapshot(label string) (SnapshotInfo, error) {
+	if err := a.taskService.Flush(); err != nil {
+		return SnapshotInfo{}, err
+	}
+	if strings.TrimSpace(label) == "" {
+		label = "Snapshot " + a.localNow().Format("2006-01-02 15:04")
+	}
+	info, err := a.snapshots.Create(a.agentService.GetProjectRoot(), label)
+	if err != nil {
+		a.logger.Error("Failed to create snapshot", err)
+		return SnapshotInfo{}, err
+	}
+	a.recordEvent(EventSnapshotCreated, 0, map[string]interface{}{
+		"id":    info.ID,
+		"label": info.Label,
+	})
+	return info, nil
+}
+
+// ListSnapshots returns the active repository's snapshots, newest first
+func (a *App) ListSnapshots() ([]SnapshotInfo, error) {
+	return a.snapshots.List(a.agentService.GetProjectRoot())
+}
+
+// RestoreSnapshot replaces plan/ files with those from a snapshot. The
+// current state is snapshotted first so the restore can itself be undone.
+func (a *App) RestoreSnapshot(id string) error {
+	repoPath := a.agentService.GetProjectRoot()
+	if err := a.taskService.Flush(); err != nil {
+		return err
+	}
+	if _, err := a.snapshots.Create(repoPath, "Before restore"); err != nil {
+		return fmt.Errorf("failed to snapshot current state before restore: %w", err)
+	}
+	
+	info, err := a.snapshots.Restore(repoPath, id)
+	if err != nil {
+		a.logger.Error("Failed to restore snapshot", err)
+		return err
+	}
+	
+	// Drop in-memory state so the restored task.json is taken as-is
+	a.taskService.SetTaskFile(a.taskService.GetTaskFile())
+	if _, err := a.taskService.LoadTasks(); err != nil {
+		return fmt.Errorf("failed to load restored tasks: %w", err)
+	}
+	a.recordEvent(EventSnapshotRestored, 0, map[string]interface{}{
+		"id":    info.ID,
+		"label": info.Label,
+	})
+	return nil
+}
+
+// DeleteSnapshot removes a snapshot
+func (a *App) DeleteSnapshot(id string) error {
+	return a.snapshots.Delete(a.agentService.GetProjectRoot(), id)
+}
+
+// Journal API methods
+
+// GetJournal returns recorded actions matching query, oldest first
+func (a *App) GetJournal(query JournalQuery) ([]JournalEntry, error) {
+	if a.journalService == nil {
+		return nil, fmt.Errorf("journal not initialized")
+	}
+	entries, err := a.journalService.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	summaries := a.summarizer()
+	for i := range entries {
+		entries[i].Summary = summaries.entry(entries[i])
+	}
+	return entries, nil
+}
+
+// GetRuntimeEvents lists the events the backend sends and their payloads, so
+// the frontend can check its listeners against the contract
+func (a *App) GetRuntimeEvents() []RuntimeEventSpec {
+	return append([]RuntimeEventSpec{}, runtimeEvents...)
+}
+
+// Command API methods
+
+// GetCommands lists the commands ExecuteCommand accepts, sorted by name
+func (a *App) GetCommands() []CommandSpec {
+	specs := make([]CommandSpec, 0, len(commands))
+	for _, cmd := range commands {
+		specs = append(specs, cmd.spec)
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+// ExecuteCommand runs a named command with JSON-style arguments. It is the
+// single entry point for the command palette and automation, and every
+// attempt is written to the audit log.
+func (a *App) ExecuteCommand(name string, args map[string]interface{}) (interface{}, error) {
+	cmd, ok := commands[name]
+	if !ok {
+		err := NotFoundError("unknown command", nil).WithContext("command", name)
+		a.auditService.Record(AuditCommandExecuted, 0, map[string]interface{}{"command": name}, err)
+		return nil, err
+	}
+	
+	a.telemetry.Increment("feature.command")
+	taskID, _ := commandArgs(args).Int("taskId")
+	result, err := cmd.run(a, commandArgs(args))
+	a.auditService.Record(AuditCommandExecuted, taskID, map[string]interface{}{
+		"command": name,
+		"args":    args,
+	}, err)
+	if err != nil {
+		a.logger.ErrorWithFields("Command failed", err, map[string]interface{}{
+			"command": name,
+		})
+		return nil, err
+	}
+	return result, nil
+}
+
+// Audit API methods
+
+// GetAuditLog returns audited privileged operations matching query, oldest first
+func (a *App) GetAuditLog(query AuditQuery) ([]AuditEntry, error) {
+	return a.auditService.Query(query)
+}
+
+// VerifyAuditLog checks the audit log's hash chain for edits and truncation
+func (a *App) VerifyAuditLog() (AuditVerification, error) {
+	return a.auditService.Verify()
+}
+
+// Terminal-related API methods
+
+// StartTerminalSession creates a new terminal session and returns its ID
+// In safe mode no session is created and the ID is empty.
+func (a *App) StartTerminalSession() string {
+	terminalID, err := a.startTerminal(0, "")
+	if err != nil {
+		a.logger.Error("Terminal session refused", err)
+	}
+	return terminalID
+}
+
+// startTerminal issues a terminal session starting in dir for taskID (0 if
+// not for a task) and audits it
+func (a *App) startTerminal(taskID int, dir string) (string, error) {
+	if err := a.requireExecution("opening terminals"); err != nil {
+		return "", err
+	}
+	a.telemetry.Increment("feature.terminal")
+	terminalID := a.terminalService.StartTerminalSessionIn(dir)
+	details := map[string]interface{}{
+		"terminal_id": terminalID,
+	}
+	if dir != "" {
+		details["dir"] = dir
+	}
+	a.auditService.Record(AuditTerminalCreated, taskID, details, nil)
+	return terminalID, nil
+}
+
+// GetTerminalLayout returns the saved terminal layout
+func (a *App) GetTerminalLayout() TerminalLayout {
+	return a.configService.GetTerminalLayout()
+}
+
+// SaveTerminalLayout stores the sessions, split, titles and working
+// directories to reopen on the next start
+func (a *App) SaveTerminalLayout(layout TerminalLayout) error {
+	layout, err := layout.normalize()
+	if err != nil {
+		return ValidationError(err.Error(), err)
+	}
+	return a.configService.SetTerminalLayout(layout)
+}
+
+// RestoreTerminalLayout opens a shell for every pane of the saved layout and
+// returns it with the session IDs filled in. Calling it again while those
+// sessions are alive returns them instead of opening more; panes whose
+// directory is gone start in the project root.
+func (a *App) RestoreTerminalLayout() (TerminalLayout, error) {
+	if err := a.requireExecution("opening terminals"); err != nil {
+		return TerminalLayout{}, err
+	}
+	layout := a.configService.GetTerminalLayout()
+	
+	a.terminalsMu.Lock()
+	defer a.terminalsMu.Unlock()
+	
+	if len(a.restoredPanes) == len(layout.Panes) && len(layout.Panes) > 0 {
+		alive := true
+		for _, pane := range a.restoredPanes {
+			alive = alive && a.terminalService.TerminalIssued(pane.TerminalID)
+		}
+		if alive {
+			layout.Panes = append([]TerminalPane(nil), a.restoredPanes...)
+			return layout, nil
+		}
+	}
+	
+	panes := make([]TerminalPane, 0, len(layout.Panes))
+	for _, pane := range layout.Panes {
+		dir := pane.Dir
+		if dir != "" {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				a.logger.Warn(fmt.Sprintf("Terminal directory %s is gone, starting in the project root", dir))
+				dir = a.agentService.GetProjectRoot()
+			}
+		}
+		terminalID, err := a.startTerminal(0, dir)
+		if err != nil {
+			return TerminalLayout{}, err
+		}
+		pane.Dir = dir
+		pane.TerminalID = terminalID
+		panes = append(panes, pane)
+	}
+	a.restoredPanes = panes
+	layout.Panes = append([]TerminalPane(nil), panes...)
+	return layout, nil
+}
+
+// SetTerminalBroadcastGroup puts terminals in a named input-broadcast
+// group, replacing its members; no terminals removes the group
+func (a *App) SetTerminalBroadcastGroup(name string, terminalIDs []string) (TerminalBroadcastGroup, error) {
+	return a.terminalService.SetBroadcastGroup(name, terminalIDs)
+}
+
+// GetTerminalBroadcastGroups returns the input-broadcast groups
+func (a *App) GetTerminalBroadcastGroups() []TerminalBroadcastGroup {
+	return a.terminalService.BroadcastGroups()
+}
+
+// PreviewTerminalBroadcast shows which terminals input would be typed into,
+// with a token for BroadcastTerminalInput once the user confirms
+func (a *App) PreviewTerminalBroadcast(group, input string) (TerminalBroadcast, error) {
+	if err := a.requireExecution("broadcasting terminal input"); err != nil {
+		return TerminalBroadcast{}, err
+	}
+	return a.terminalService.PreviewBroadcast(group, input, time.Now())
+}
+
+// BroadcastTerminalInput writes a confirmed broadcast to every terminal of
+// its group and audits it
+func (a *App) BroadcastTerminalInput(token string) (TerminalBroadcastResult, error) {
+	if err := a.requireExecution("broadcasting terminal input"); err != nil {
+		return TerminalBroadcastResult{}, err
+	}
+	result, err := a.terminalService.Broadcast(token, time.Now())
+	if err != nil {
+		return result, err
+	}
+	a.auditService.Record(AuditTerminalBroadcast, 0, map[string]interface{}{
+		"group":   result.Group,
+		"written": result.Written,
+		"skipped": result.Skipped,
+	}, nil)
+	return result, nil
+}
+
+// GetRestrictedTerminalConfig returns whether terminals only run allowlisted
+// commands, and which
+func (a *App) GetRestrictedTerminalConfig() RestrictedTerminalConfig {
+	if a.configService == nil {
+		return RestrictedTerminalConfig{}
+	}
+	return a.configService.GetRestrictedTerminal()
+}
+
+// SetRestrictedTerminalConfig validates, saves and applies restricted
+// terminal mode. Turning it on closes the shells already open.
+func (a *App) SetRestrictedTerminalConfig(restricted RestrictedTerminalConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if restricted.Enabled && len(restricted.Commands) == 0 {
+		return ValidationError("restricted terminals need at least one allowed command", nil)
+	}
+	if _, err := newCommandAllowlist(restricted.Commands); err != nil {
+		return ValidationError(err.Error(), err)
+	}
+	if err := a.configService.SetRestrictedTerminal(restricted); err != nil {
+		return err
+	}
+	if err := a.terminalService.SetRestriction(restricted); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"restrictedTerminal": restricted.Enabled,
+		"allowedCommands":    len(restricted.Commands),
+	})
+	return nil
+}
+
+// GetTerminalTitles returns the window title of each terminal that set one,
+// by terminal ID, for tabs opened before the titles were sent
+func (a *App) GetTerminalTitles() map[string]string {
+	return a.terminalService.TerminalTitles()
+}
+
+// GetTerminalStats returns each terminal's output throughput and how much
+// was dropped, by terminal ID
+func (a *App) GetTerminalStats() []TerminalStats {
+	return a.terminalService.TerminalStats()
+}
+
+// ResumeTerminalOutput shows a terminal's output again after it was paused
+// for flooding
+func (a *App) ResumeTerminalOutput(terminalID string) error {
+	return a.terminalService.ResumeOutput(terminalID)
+}
+
+// GetTerminalProtocol returns the heartbeat timings, backoff and close
+// codes terminal WebSocket clients reconnect by
+func (a *App) GetTerminalProtocol() TerminalProtocol {
+	return terminalProtocol
+}
+
+// SearchCommandHistory finds commands run in this repository's terminals
+// that contain every word of query, newest first
+func (a *App) SearchCommandHistory(query string) ([]CommandRecord, error) {
+	return a.commandHistory.Search(query)
+}
+
+// Agent-related API methods
+
+// GetAgentStatus returns the current status of all subagents
+func (a *App) GetAgentStatus() (AgentStatusInfo, error) {
+	if err := a.requireExecution("running agent_status.sh"); err != nil {
+		return AgentStatusInfo{}, err
+	}
+	return a.agentService.GetAgentStatus()
+}
+
+// GetAgentDashboard gathers agent worktrees, the todo queue and recent runs
+// from the journal for the agent sidebar. Worktrees come from git rather than
+// agent_status.sh; if git fails the rest of the dashboard is still returned.
+func (a *App) GetAgentDashboard() (AgentDashboard, error) {
+	tasks := a.taskService.GetTasks()
+	dashboard := AgentDashboard{
+		Worktrees: []AgentWorktreeState{},
+		Queued:    queuedTasks(tasks),
+		Quota:     a.GetQuotaStatus(),
+		Since:     time.Now().Add(-agentDashboardWindow),
+	}
+	
+	worktrees, err := a.agentService.ListWorktrees()
+	if err != nil {
+		a.logger.Error("Failed to list agent worktrees", err)
+		dashboard.WorktreeError = err.Error()
+	} else {
+		dashboard.Worktrees = agentWorktreeStates(worktrees, tasks)
+	}
+	
+	runs, err := a.agentRunHistory(dashboard.Since, tasks)
+	if err != nil {
+		return dashboard, err
+	}
+	summarizeAgentRuns(&dashboard, runs)
+	return dashboard, nil
+}
+
+// PredictAgentDuration estimates how long an agent will take on a task from
+// past runs of similar tasks: the same epic if there are enough of them,
+// then the same priority, then every run
+func (a *App) PredictAgentDuration(taskID int) (AgentDurationEstimate, error) {
+	tasks := a.taskService.GetTasks()
+	task, ok := findTask(tasks, taskID)
+	if !ok {
+		return AgentDurationEstimate{}, NotFoundError("task not found", nil).WithContext("task_id", taskID)
+	}
+	runs, err := a.agentRunHistory(time.Now().Add(-agentEstimateWindow), tasks)
+	if err != nil {
+		return AgentDurationEstimate{}, err
+	}
+	return estimateAgentDuration(task, tasks, runs), nil
+}
+
+// SimulateCompletion forecasts, day by day, when the todo column (and the
+// backlog, if asked) would drain, from past agent run times and failure
+// rate, the queue policy and how many agents run at once. Zero params use
+// the repository's subagent limit, defaultAgentHoursPerDay and priority order.
+func (a *App) SimulateCompletion(params SimulationParams) (CompletionForecast, error) {
+	switch {
+	case params.MaxAgents < 0:
+		return CompletionForecast{}, ValidationError("agents must not be negative", nil).WithContext("maxAgents", params.MaxAgents)
+	case params.HoursPerDay < 0 || params.HoursPerDay > 24:
+		return CompletionForecast{}, ValidationError("hours per day must be between 0 and 24", nil).
+			WithContext("hoursPerDay", params.HoursPerDay)
+	case params.Policy != "" && params.Policy != QueueByPriority && params.Policy != QueueByID:
+		return CompletionForecast{}, ValidationError("queue policy must be priority or fifo", nil).WithContext("policy", params.Policy)
+	}
+	if params.MaxAgents == 0 {
+		params.MaxAgents = a.subagentLimit()
+	}
+	if params.HoursPerDay == 0 {
+		params.HoursPerDay = defaultAgentHoursPerDay
+	}
+	if params.Policy == "" {
+		params.Policy = QueueByPriority
+	}
+	
+	tasks := a.taskService.GetTasks()
+	runs, err := a.agentRunHistory(time.Now().Add(-agentEstimateWindow), tasks)
+	if err != nil {
+		return CompletionForecast{}, err
+	}
+	return simulateCompletion(tasks, runs, params, a.localNow()), nil
+}
+
+// RelaunchAgent sends a task back to an agent after feedback or a failed
+// run, moving it to doing. mode fresh deletes the task branch and starts
+// over from main; mode resume checks the branch out again in the worktree
+// the last run used and continues its claude session.
+func (a *App) RelaunchAgent(taskID int, mode string) error {
+	return a.relaunchTask(taskID, mode, "")
+}
+
+// relaunchTask is RelaunchAgent with review feedback for the agent
+func (a *App) relaunchTask(taskID int, mode, feedback string) error {
+	if !validRelaunchMode(mode) {
+		return ValidationError("relaunch mode must be fresh or resume", nil).
+			WithContext("mode", mode)
+	}
+	if err := a.requireExecution("launching agents"); err != nil {
+		return err
+	}
+	// Check before the branch is touched or the task moves
+	if err := a.requireConfirmation(ConfirmAgentSpawn); err != nil {
+		return err
+	}
+	if err := a.requireClaude(); err != nil {
+		return err
+	}
+	task, ok := findTask(a.taskService.GetTasks(), taskID)
+	if !ok {
+		return NotFoundError("task not found", nil).WithContext("task_id", taskID)
+	}
+	if task.Status != StatusTodo && task.Status != StatusDoing && task.Status != StatusPendingReview {
+		return ConflictError(fmt.Sprintf("a %s task can't go back to an agent", task.Status), nil).
+			WithContext("task_id", taskID)
+	}
+	worktrees, err := a.agentService.ListWorktrees()
+	if err != nil {
+		return err
+	}
+	for _, worktree := range worktrees {
+		if worktree.Branch == taskBranch(taskID) && worktreeLocked(worktree.Path) {
+			return ConflictError("an agent is still working on this task", nil).
+				WithContext("task_id", taskID).
+				WithContext("worktree", worktree.Path)
+		}
+	}
+	
+	relaunch := AgentRelaunch{Mode: mode}
+	if mode == RelaunchResume {
+		if relaunch, err = a.resumableRun(task, worktrees); err != nil {
+			return err
+		}
+	} else if err := a.discardTaskBranch(taskID, worktrees); err != nil {
+		return err
+	}
+	relaunch.Feedback = feedback
+	
+	if task.Status != StatusDoing {
+		if err := a.taskService.MoveTask(taskID, string(StatusDoing)); err != nil {
+			return err
+		}
+		a.recordEvent(EventTaskMoved, taskID, map[string]interface{}{
+			"from":     task.Status,
+			"to":       StatusDoing,
+			"relaunch": mode,
+		})
+		task.Status = StatusDoing
+	}
+	return a.relaunchAgent(task, relaunch)
+}
+
+// resumableRun finds the session of task's latest run and the worktree it
+// ran in, refusing when either is gone
+func (a *App) resumableRun(task Task, worktrees []GitWorktree) (AgentRelaunch, error) {
+	if !a.agentService.ClaudeCapabilities(false).Supports(ClaudeFlagResume) {
+		return AgentRelaunch{}, ConflictError("the installed claude CLI can't resume sessions; "+claudeUpgradeHint, nil)
+	}
+	runs, err := a.agentRunHistory(time.Time{}, []Task{task})
+	if err != nil {
+		return AgentRelaunch{}, err
+	}
+	sessionID := ""
+	for _, run := range runs {
+		if run.TaskID == task.ID && run.SessionID != "" {
+			sessionID = run.SessionID
+		}
+	}
+	if sessionID == "" {
+		return AgentRelaunch{}, ConflictError("no earlier agent session to resume; relaunch fresh instead", nil).
+			WithContext("task_id", task.ID)
+	}
+	projectsDir, err := claudeProjectsDir()
+	if err != nil {
+		return AgentRelaunch{}, err
+	}
+	worktree, ok := sessionWorktree(projectsDir, sessionID, worktrees)
+	if !ok {
+		return AgentRelaunch{}, ConflictError("the worktree of the earlier session is gone; relaunch fresh instead", nil).
+			WithContext("sessionId", sessionID)
+	}
+	if worktreeLocked(worktree) {
+		return AgentRelaunch{}, ConflictError("another agent is using the worktree of the earlier session", nil).
+			WithContext("worktree", worktree)
+	}
+	branches, err := a.agentService.ListTaskBranches()
+	if err != nil {
+		return AgentRelaunch{}, err
+	}
+	if !slices.Contains(branches, taskBranch(task.ID)) {
+		return AgentRelaunch{}, ConflictError("the task branch is gone; relaunch fresh instead", nil).
+			WithContext("branch", taskBranch(task.ID))
+	}
+	return AgentRelaunch{Mode: RelaunchResume, Worktree: worktree, SessionID: sessionID}, nil
+}
+
+// discardTaskBranch detaches any worktree from the task branch and deletes
+// it, so a fresh agent starts it again from main
+func (a *App) discardTaskBranch(taskID int, worktrees []GitWorktree) error {
+	branch := taskBranch(taskID)
+	for _, worktree := range worktrees {
+		if worktree.Branch == branch {
+			if err := a.agentService.RefreshWorktree(worktree.Path); err != nil {
+				return err
+			}
+		}
+	}
+	branches, err := a.agentService.ListTaskBranches()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(branches, branch) {
+		return nil
+	}
+	if err := a.agentService.DeleteTaskBranch(branch); err != nil {
+		return err
+	}
+	a.recordEvent(EventBranchDeleted, taskID, map[string]interface{}{
+		"branch":   branch,
+		"relaunch": RelaunchFresh,
+	})
+	return nil
+}
+
+// StartAgentsForColumn launches agents for up to maxAgents unblocked todo tasks,
+// highest priority first, as one burst: the first-launch confirmation is
+// asked for once, and no more agents start than there are free worktrees.
+// The tasks move to doing right away; agents launch one after another in the
+// background.
+func (a *App) StartAgentsForColumn(status string, maxAgents int) (ColumnLaunch, error) {
+	return a.startAgentsForColumn(status, maxAgents, false)
+}
+
+// startAgentsForColumn is StartAgentsForColumn; when wait is true it returns
+// once every agent has launched
+func (a *App) startAgentsForColumn(status string, maxAgents int, wait bool) (ColumnLaunch, error) {
+	column, err := ParseTaskStatus(status)
+	if err != nil || column != StatusTodo {
+		return ColumnLaunch{}, ValidationError("agents can only be started for the todo column", err).
+			WithContext("status", status)
+	}
+	if maxAgents <= 0 {
+		return ColumnLaunch{}, ValidationError("at least one agent must be requested", nil).
+			WithContext("max", maxAgents)
+	}
+	if err := a.requireExecution("launching agents"); err != nil {
+		return ColumnLaunch{}, err
+	}
+	if err := a.requireConfirmation(ConfirmAgentSpawn); err != nil {
+		return ColumnLaunch{}, err
+	}
+	
+	tasks := a.taskService.GetTasks()
+	slots, err := a.freeAgentSlots(tasks)
+	if err != nil {
+		return ColumnLaunch{}, err
+	}
+	limit := maxAgents
+	if slots < limit {
+		limit = slots
+	}
+	launch := columnLaunchPlan(tasks, limit)
+	launch.Slots = slots
+	
+	started := []Task{}
+	for _, taskID := range launch.Started {
+		if err := a.taskService.MoveTask(taskID, string(StatusDoing)); err != nil {
+			return launch, a.errorHandler.Handle(err)
+		}
+		a.recordEvent(EventTaskMoved, taskID, map[string]interface{}{
+			"from": StatusTodo,
+			"to":   StatusDoing,
+		})
+		task, _ := findTask(tasks, taskID)
+		task.Status = StatusDoing
+		started = append(started, task)
+	}
+	
+	// One at a time, so the spawner never hands two agents the same worktree
+	launchAll := func() {
+		for _, task := range started {
+			if err := a.launchAgent(task); err != nil {
+				a.errorHandler.Handle(err)
+			}
+		}
+	}
+	if wait {
+		launchAll()
+	} else if len(started) > 0 {
+		a.errorHandler.Go("column agent launch", launchAll)
+	}
+	return launch, nil
+}
+
+// freeAgentSlots returns how many more agents can start: the subagent limit
+// less the worktrees already holding a task in doing or review
+func (a *App) freeAgentSlots(tasks []Task) (int, error) {
+	limit := a.subagentLimit()
+	worktrees, err := a.agentService.ListWorktrees()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count agent worktrees: %v", err)
+	}
+	for _, worktree := range agentWorktreeStates(worktrees, tasks) {
+		if worktree.State == WorktreeBusy || worktree.State == WorktreeReview {
+			limit--
+		}
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return limit, nil
+}
+
+// subagentLimit returns how many agent worktrees the spawner may use
+func (a *App) subagentLimit() int {
+	if a.configService != nil {
+		if activeRepo, err := a.configService.GetActiveRepository(); err == nil && activeRepo.MaxAgents > 0 {
+			return activeRepo.MaxAgents
+		}
+	}
+	if status, err := a.agentService.GetAgentStatus(); err == nil && status.MaxSubagents > 0 {
+		return status.MaxSubagents
+	}
+	return defaultMaxSubagents
+}
+
+// GetAgentLog returns the last tailLines lines an agent run wrote, or
+// agentLogDefaultTail lines when tailLines is 0. Run IDs are on AgentRun.
+func (a *App) GetAgentLog(runID string, tailLines int) (AgentLog, error) {
+	if !agentRunIDPattern.MatchString(runID) {
+		return AgentLog{}, ValidationError("invalid run ID", nil).WithContext("runId", runID)
+	}
+	if tailLines < 0 {
+		return AgentLog{}, ValidationError("tail lines must not be negative", nil).WithContext("tailLines", tailLines)
+	}
+	if tailLines == 0 {
+		tailLines = agentLogDefaultTail
+	}
+	path, taskID, err := findAgentLog(agentLogsDir(a.agentService.GetProjectRoot()), runID)
+	if err != nil {
+		return AgentLog{}, NotFoundError("no log for this agent run", err).WithContext("runId", runID)
+	}
+	lines, truncated, err := tailAgentLog(path, tailLines)
+	if err != nil {
+		return AgentLog{}, fmt.Errorf("failed to read agent log: %v", err)
+	}
+	return AgentLog{RunID: runID, TaskID: taskID, Lines: lines, Truncated: truncated}, nil
+}
+
+// GetAgentTranscript returns the conversation an agent run had with claude,
+// from claude's session file, or just its final message when only a JSON
+// result is left. Run IDs and session IDs are on AgentRun.
+func (a *App) GetAgentTranscript(taskID int, runID string) (AgentTranscript, error) {
+	if !agentRunIDPattern.MatchString(runID) {
+		return AgentTranscript{}, ValidationError("invalid run ID", nil).WithContext("runId", runID)
+	}
+	runs, err := a.agentRunHistory(time.Time{}, a.taskService.GetTasks())
+	if err != nil {
+		return AgentTranscript{}, err
+	}
+	var run *AgentRun
+	for i := range runs {
+		if runs[i].TaskID == taskID && runs[i].RunID == runID {
+			run = &runs[i]
+		}
+	}
+	if run == nil {
+		return AgentTranscript{}, NotFoundError("agent run not found", nil).
+			WithContext("task_id", taskID).
+			WithContext("runId", runID)
+	}
+	if run.SessionID == "" {
+		return AgentTranscript{}, NotFoundError("this agent run has no session ID", nil).
+			WithContext("runId", runID)
+	}
+	
+	transcript := AgentTranscript{TaskID: taskID, RunID: runID, SessionID: run.SessionID}
+	projectsDir, err := claudeProjectsDir()
+	if err == nil {
+		var path string
+		if path, err = findSessionFile(projectsDir, run.SessionID); err == nil {
+			if transcript.Entries, err = readSessionTranscript(path); err != nil {
+				return AgentTranscript{}, fmt.Errorf("failed to read agent transcript: %v", err)
+			}
+			transcript.Source = TranscriptSession
+			return transcript, nil
+		}
+	}
+	if run.Result == nil || run.Result.Message == "" {
+		return AgentTranscript{}, NotFoundError("no transcript for this agent run", err).
+			WithContext("sessionId", run.SessionID)
+	}
+	transcript.Source = TranscriptResult
+	transcript.Entries = []TranscriptEntry{newTranscriptEntry("assistant", TranscriptText, "", run.Result.Message)}
+	return transcript, nil
+}
+
+// CollectAgentResults journals the results agents in JSON result mode left in
+// the logs directory, so their runs show the agent's own verdict, cost and
+// final message. It returns how many were collected; unreadable results are
+// set aside with an .invalid suffix.
+func (a *App) CollectAgentResults() (int, error) {
+	if a.journalService == nil {
+		return 0, nil
+	}
+	return a.agentService.CollectResults(func(taskID int, result AgentResult) {
+		a.recordEvent(EventAgentResult, taskID, agentResultData(result))
+	})
+}
+
+// agentRunHistory rebuilds agent runs journaled since the given time, after
+// picking up results agents have left since the last look
+func (a *App) agentRunHistory(since time.Time, tasks []Task) ([]AgentRun, error) {
+	if a.journalService == nil {
+		return []AgentRun{}, nil
+	}
+	if _, err := a.CollectAgentResults(); err != nil {
+		a.logger.Error("Failed to collect agent results", err)
+	}
+	entries, err := a.journalService.Query(JournalQuery{
+		Types: []string{EventAgentLaunched, EventAgentFailed, EventAgentResult, EventAgentInterrupted, EventTaskMoved, EventTaskApproved, EventTaskRejected},
+		Since: since,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return agentRuns(entries, tasks), nil
+}
+
+// Worktree disk API methods
+
+// GetWorktreeDiskUsage measures each agent worktree, least recently used
+// first, against the configured disk budget
+func (a *App) GetWorktreeDiskUsage() (WorktreeDiskUsage, error) {
+	return a.agentService.WorktreeDiskUsage(a.taskService.GetTasks(), a.GetWorktreeConfig())
+}
+
+// PruneWorktrees removes idle, clean worktrees, least recently used first,
+// until the pool fits the disk budget. Worktrees holding a task in doing or
+// review, or with local changes, are never removed.
+func (a *App) PruneWorktrees() (WorktreePrune, error) {
+	if err := a.requireExecution("pruning worktrees"); err != nil {
+		return WorktreePrune{Removed: []string{}}, err
+	}
+	prune, err := a.agentService.PruneWorktrees(a.taskService.GetTasks(), a.GetWorktreeConfig())
+	if err != nil {
+		return prune, err
+	}
+	if len(prune.Removed) > 0 {
+		a.recordEvent(EventWorktreesPruned, 0, map[string]interface{}{
+			"removed":    prune.Removed,
+			"freedBytes": prune.FreedBytes,
+		})
+	}
+	return prune, nil
+}
+
+// WarmWorktrees tops up the pool of idle worktrees checked out on main, so
+// the spawner can reuse one instead of creating it at launch. Idle worktrees
+// already in the pool are moved up to the current main.
+func (a *App) WarmWorktrees() (WorktreeWarmup, error) {
+	worktrees := a.GetWorktreeConfig()
+	if err := a.requireExecution("warming worktrees"); err != nil {
+		return WorktreeWarmup{Target: worktrees.WarmPool, Created: []string{}, Refreshed: []string{}}, err
+	}
+	warmup, err := a.agentService.WarmWorktrees(a.taskService.GetTasks(), worktrees, a.subagentLimit())
+	if err != nil {
+		return warmup, err
+	}
+	if len(warmup.Created) > 0 {
+		a.recordEvent(EventWorktreesWarmed, 0, map[string]interface{}{
+			"created": warmup.Created,
+			"warm":    warmup.Warm,
+		})
+	}
+	return warmup, nil
+}
+
+// GetWorktreeConfig returns the worktree disk budget and warm pool size
+func (a *App) GetWorktreeConfig() WorktreeConfig {
+	if a.configService == nil {
+		return WorktreeConfig{}
+	}
+	return a.configService.GetWorktreeConfig()
+}
+
+// SetWorktreeConfig saves the worktree disk budget and warm pool size
+func (a *App) SetWorktreeConfig(worktrees WorktreeConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if worktrees.MaxTotalGB < 0 {
+		return ValidationError("worktree budget must not be negative", nil).
+			WithContext("maxTotalGB", worktrees.MaxTotalGB)
+	}
+	if worktrees.WarmPool < 0 {
+		return ValidationError("warm worktree pool must not be negative", nil).
+			WithContext("warmPool", worktrees.WarmPool)
+	}
+	if err := a.configService.SetWorktreeConfig(worktrees); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"worktreeBudgetGB": worktrees.MaxTotalGB,
+		"warmWorktrees":    worktrees.WarmPool,
+	})
+	return nil
+}
+
+// Branch reconciliation API methods
+
+// ReconcileBranches matches the task_* branches against the board, reporting
+// branches whose task is gone and tasks awaiting review with no branch
+func (a *App) ReconcileBranches() (BranchReconciliation, error) {
+	branches, err := a.agentService.ListTaskBranches()
+	if err != nil {
+		return BranchReconciliation{}, err
+	}
+	return reconcileBranches(branches, a.taskService.GetTasks()), nil
+}
+
+// FixBranchOrphan applies one of an orphan's fixes: create_task adds a
+// pending_review stub for a branch without a task, delete_branch drops that
+// branch, and requeue_agent moves a review without a branch back to todo
+func (a *App) FixBranchOrphan(taskID int, fix string) error {
+	report, err := a.ReconcileBranches()
+	if err != nil {
+		return err
+	}
+	orphan, ok := findOrphan(report, taskID)
+	if !ok {
+		return ConflictError("task and branch are no longer out of step", nil).
+			WithContext("task_id", taskID)
+	}
+	applies := false
+	for _, f := range orphan.Fixes {
+		applies = applies || f == fix
+	}
+	if !applies {
+		return ValidationError(fmt.Sprintf("%s does not fix a %s orphan", fix, orphan.Kind), nil).
+			WithContext("task_id", taskID).
+			WithContext("fix", fix)
+	}
+	
+	switch fix {
+	case FixCreateTask:
+		stub := Task{
+			ID:       taskID,
+			Title:    "Recovered branch " + orphan.Branch,
+			Status:   StatusPendingReview,
+			Priority: PriorityMedium,
+			Deps:     []int{},
+		}
+		if err := a.taskService.SaveTasks(append(a.taskService.GetTasks(), stub)); err != nil {
+			return err
+		}
+		a.recordEvent(EventTaskCreated, taskID, map[string]interface{}{
+			"title":    stub.Title,
+			"priority": stub.Priority,
+			"branch":   orphan.Branch,
+		})
+	case FixDeleteBranch:
+		if err := a.requireExecution("deleting task branches"); err != nil {
+			return err
+		}
+		if err := a.agentService.DeleteTaskBranch(orphan.Branch); err != nil {
+			return err
+		}
+		a.recordEvent(EventBranchDeleted, taskID, map[string]interface{}{
+			"branch": orphan.Branch,
+		})
+	case FixRequeue:
+		return a.MoveTask(taskID, string(StatusTodo))
+	}
+	return nil
+}
+
+// Interrupted agent API methods
+
+// GetInterruptedTasks finds tasks left in doing by agents that died, such as
+// in a crash, with the fixes each allows. Newly found runs are journaled as
+// interrupted so the run history stops counting them as running.
+func (a *App) GetInterruptedTasks() ([]InterruptedTask, error) {
+	if err := a.requireExecution("checking agent worktrees"); err != nil {
+		return nil, err
+	}
+	tasks := a.taskService.GetTasks()
+	runs, err := a.agentRunHistory(time.Time{}, tasks)
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := a.agentService.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	branches, err := a.agentService.ListTaskBranches()
+	if err != nil {
+		return nil, err
+	}
+	
+	interrupted := findInterrupted(tasks, runs, worktrees, branches, time.Now(), processAlive)
+	open := map[int]bool{}
+	for _, run := range runs {
+		open[run.TaskID] = run.Outcome == RunRunning
+	}
+	for _, item := range interrupted {
+		if !open[item.TaskID] {
+			continue
+		}
+		a.recordEvent(EventAgentInterrupted, item.TaskID, map[string]interface{}{
+			"runId":    item.RunID,
+			"worktree": item.Worktree,
+			"pid":      item.PID,
+		})
+	}
+	return interrupted, nil
+}
+
+// RecoverInterruptedTask applies one of an interrupted task's fixes: requeue
+// frees its worktree and moves it back to todo, adopt keeps the worktree on
+// the task branch and moves it to pending_review, and resume relaunches the
+// dead agent's session. A stale lock left by the agent is cleared first.
+func (a *App) RecoverInterruptedTask(taskID int, fix string) error {
+	if !validRecovery(fix) {
+		return ValidationError("recovery must be requeue, adopt or resume", nil).
+			WithContext("fix", fix)
+	}
+	interrupted, err := a.GetInterruptedTasks()
+	if err != nil {
+		return err
+	}
+	var item InterruptedTask
+	found := false
+	for _, candidate := range interrupted {
+		if candidate.TaskID == taskID {
+			item, found = candidate, true
+		}
+	}
+	if !found {
+		return ConflictError("the task's agent was not interrupted", nil).
+			WithContext("task_id", taskID)
+	}
+	if !slices.Contains(item.Fixes, fix) {
+		return ValidationError(fmt.Sprintf("%s can't recover this task", fix), nil).
+			WithContext("task_id", taskID).
+			WithContext("fix", fix)
+	}
+	
+	if item.Worktree != "" {
+		if err := os.Remove(filepath.Join(item.Worktree, agentLockFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	status := StatusPendingReview
+	switch fix {
+	case RecoverResume:
+		return a.RelaunchAgent(taskID, RelaunchResume)
+	case RecoverRequeue:
+		if item.Worktree != "" {
+			if err := a.agentService.RefreshWorktree(item.Worktree); err != nil {
+				return err
+			}
+		}
+		status = StatusTodo
+	}
+	if err := a.taskService.MoveTask(taskID, string(status)); err != nil {
+		return err
+	}
+	a.recordEvent(EventTaskMoved, taskID, map[string]interface{}{
+		"from":     StatusDoing,
+		"to":       status,
+		"recovery": fix,
+	})
+	return nil
+}
+
+// recoverInterruptedAgents looks for agents that died while the app was
+// down and tells the frontend, which offers their fixes. Agents detached at
+// the last quit that are still running need nothing: their runs are still
+// open and finish as usual.
+func (a *App) recoverInterruptedAgents() {
+	if a.IsSafeMode() {
+		return
+	}
+	if agents, err := a.GetRunningAgents(); err == nil && len(agents) > 0 {
+		a.logger.InfoWithFields("Picked up agents left running by the last session", map[string]interface{}{
+			"count": len(agents),
+		})
+	}
+	interrupted, err := a.GetInterruptedTasks()
+	if err != nil {
+		a.logger.Error("Failed to check for interrupted agents", err)
+		return
+	}
+	if len(interrupted) == 0 {
+		return
+	}
+	a.logger.InfoWithFields("Found tasks whose agents died", map[string]interface{}{
+		"count": len(interrupted),
+	})
+	a.emitEvent(RuntimeAgentsInterrupted, interrupted)
+}
+
+// Editor-related API methods
+
+// OpenInEditor opens a file or folder in the configured editor, at line if > 0
+func (a *App) OpenInEditor(path string, line int) error {
+	if err := a.requireExecution("opening an editor"); err != nil {
+		return err
+	}
+	a.telemetry.Increment("feature.editor")
+	editor := ""
+	if a.configService != nil {
+		editor = a.configService.GetEditor()
+	}
+	return a.editorService.Open(editor, path, line)
+}
+
+// OpenTaskWorktreeInEditor opens the worktree an agent is using for a task
+func (a *App) OpenTaskWorktreeInEditor(taskID int) error {
+	if err := a.requireExecution("opening an editor"); err != nil {
+		return err
+	}
+	worktree, err := a.agentService.FindTaskWorktree(taskID)
+	if err != nil {
+		return err
+	}
+	return a.OpenInEditor(worktree, 0)
+}
+
+// SetEditorCommand sets the editor command used by OpenInEditor
+func (a *App) SetEditorCommand(command string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	command = strings.TrimSpace(command)
+	if err := a.configService.SetEditor(command); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"editor": command,
+	})
+	return nil
+}
+
+// File transfer API methods
+
+// UploadFileToRepo writes data to path, relative to the repository root, so
+// remote clients can bring small files over without scp. Nothing is written
+// by a read-only instance or in safe mode.
+func (a *App) UploadFileToRepo(path string, data []byte) (FileTransfer, error) {
+	if a.readOnly {
+		return FileTransfer{}, PermissionError(readOnlyReason, nil)
+	}
+	if err := a.requireExecution("Uploading files"); err != nil {
+		return FileTransfer{}, err
+	}
+	return a.fileTransfer.Upload(a.agentService.GetProjectRoot(), path, data)
+}
+
+// DownloadFile returns the contents of path, relative to the repository root
+func (a *App) DownloadFile(path string) ([]byte, error) {
+	data, _, err := a.fileTransfer.Download(a.agentService.GetProjectRoot(), path)
+	return data, err
+}
+
+// Locale API methods
+
+// GetLocaleSettings returns the locale of notifications, reports and agent
+// prompts, and the locales with a message catalog
+func (a *App) GetLocaleSettings() LocaleSettings {
+	return LocaleSettings{
+		Locale:    a.messages().Locale(),
+		Available: availableLocales(localeCatalogDir()),
+	}
+}
+
+// SetLocale switches notifications, reports and agent prompts to locale;
+// "" goes back to DefaultLocale. A user catalog for it must be valid.
+func (a *App) SetLocale(locale string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	localizer, err := loadLocalizer(locale, localeCatalogDir())
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetLocale(locale); err != nil {
+		return err
+	}
+	
+	a.applyLocalizer(localizer)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"locale": localizer.Locale(),
+	})
+	return nil
+}
+
+// Time zone API methods
+
+// GetTimezone returns the zone times are shown, reported and scheduled in
+func (a *App) GetTimezone() TimezoneSettings {
+	name := ""
+	if a.configService != nil {
+		name = a.configService.GetTimezone()
+	}
+	return timezoneSettings(name, a.timezone(), time.Now())
+}
+
+// SetTimezone sets the zone times are shown, reported and scheduled in, by
+// its IANA name such as Europe/Berlin; "" follows the system. Stored times
+// stay in UTC.
+func (a *App) SetTimezone(name string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	name = strings.TrimSpace(name)
+	if _, err := loadTimezone(name); err != nil {
+		return err
+	}
+	if err := a.configService.SetTimezone(name); err != nil {
+		return err
+	}
+	
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"timezone": name,
+	})
+	return nil
+}
+
+// GetAgentHours returns when auto-pilot may launch the active repository's
+// agents, or nil if at any time
+func (a *App) GetAgentHours() *ScheduleWindow {
+	return a.agentHours()
+}
+
+// SetAgentHours limits, for the active repository, auto-pilot launches to
+// a weekly window in the configured zone; nil lifts the limit. Tasks moved
+// to doing outside it wait there without an agent, as when paused.
+func (a *App) SetAgentHours(hours *ScheduleWindow) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if hours != nil {
+		validated, err := validateSchedule(*hours)
+		if err != nil {
+			return err
+		}
+		hours = &validated
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryAgentHours(activeRepo.ID, hours); err != nil {
+		return err
+	}
+	
+	described := ""
+	if hours != nil {
+		described = hours.String()
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"agentHours": described,
+	})
+	return nil
+}
+
+// timezone is the configured zone, or the system's if it can't be loaded
+func (a *App) timezone() *time.Location {
+	if a.configService == nil {
+		return time.Local
+	}
+	loc, err := loadTimezone(a.configService.GetTimezone())
+	if err != nil {
+		a.logger.Error("Failed to load the configured time zone, using the system's", err)
+		return time.Local
+	}
+	return loc
+}
+
+// localNow is the current time in the configured zone, for reports and
+// anything else that starts a new day at midnight
+func (a *App) localNow() time.Time {
+	return time.Now().In(a.timezone())
+}
+
+// agentHours returns the active repository's agent hours, or nil
+func (a *App) agentHours() *ScheduleWindow {
+	if a.configService == nil {
+		return nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return nil
+	}
+	return activeRepo.AgentHours
+}
+
+// withinAgentHours reports whether auto-pilot may launch an agent at now
+func (a *App) withinAgentHours(now time.Time) bool {
+	hours := a.agentHours()
+	return hours == nil || hours.Contains(now, a.timezone())
+}
+
+// Feature flag API methods
+
+// GetFeatureFlags returns every known feature flag with its default, its
+// global and active-repository settings, and whether it is on
+func (a *App) GetFeatureFlags() []FeatureFlag {
+	if a.configService == nil {
+		return resolveFeatureFlags(nil, nil)
+	}
+	var repoFlags map[string]bool
+	if repo, err := a.configService.GetActiveRepository(); err == nil {
+		repoFlags = repo.FeatureFlags
+	}
+	return resolveFeatureFlags(a.configService.GetFeatureFlags(), repoFlags)
+}
+
+// SetFeatureFlag turns a feature on or off globally or for the active
+// repository; nil clears the setting so the next scope out decides
+func (a *App) SetFeatureFlag(name, scope string, enabled *bool) error {
+	if _, ok := findFeatureFlag(name); !ok {
+		return ValidationError("unknown feature flag", nil).WithContext("flag", name)
+	}
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	switch scope {
+	case FeatureScopeGlobal:
+		if err := a.configService.SetFeatureFlag(name, enabled); err != nil {
+			return err
+		}
+	case FeatureScopeRepository:
+		repo, err := a.configService.GetActiveRepository()
+		if err != nil {
+			return err
+		}
+		if err := a.configService.SetRepositoryFeatureFlag(repo.ID, name, enabled); err != nil {
+			return err
+		}
+	default:
+		return ValidationError("feature flag scope must be global or repository", nil).WithContext("scope", scope)
+	}
+	
+	details := map[string]interface{}{"featureFlag": name, "scope": scope}
+	if enabled != nil {
+		details["enabled"] = *enabled
+	}
+	a.recordEvent(EventConfigChanged, 0, details)
+	return nil
+}
+
+// featureEnabled reports whether a known feature flag is on for the active
+// repository
+func (a *App) featureEnabled(name string) bool {
+	for _, flag := range a.GetFeatureFlags() {
+		if flag.Name == name {
+			return flag.Enabled
+		}
+	}
+	return false
+}
+
+// Window, auto-pilot and safe mode API methods
+
+// IsAutoPilotPaused reports whether automatic agent launches are suspended
+func (a *App) IsAutoPilotPaused() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.autoPilotPaused
+}
+
+// SetAutoPilotPaused suspends or resumes agent launches on todo → doing moves
+func (a *App) SetAutoPilotPaused(paused bool) {
+	a.mu.Lock()
+	a.autoPilotPaused = paused
+	a.mu.Unlock()
+	
+	a.logger.InfoWithFields("Auto-pilot state changed", map[string]interface{}{
+		"paused": paused,
+	})
+	a.emitEvent(RuntimeAutoPilotChanged, paused)
+	a.recordEvent(EventAutoPilotChanged, 0, map[string]interface{}{
+		"paused": paused,
+	})
+}
+
+// IsSafeMode reports whether agents, terminals and git operations are disabled
+func (a *App) IsSafeMode() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.safeMode
+}
+
+// IsReadOnly reports whether another instance owns the config, leaving this
+// one to show the board without changing it
+func (a *App) IsReadOnly() bool {
+	return a.readOnly
+}
+
+// holdInstanceLock keeps lock until shutdown; focus requests from later
+// instances bring this one's window forward
+func (a *App) holdInstanceLock(lock *InstanceLock) {
+	a.instanceLock = lock
+	lock.OnFocus(a.ShowWindow)
+}
+
+// SetSafeMode turns safe mode on or off and saves the choice. Safe mode
+// forced by --safe-mode can't be turned off.
+func (a *App) SetSafeMode(enabled bool) error {
+	if !enabled && a.readOnly {
+		return ConflictError(readOnlyReason, nil)
+	}
+	if !enabled && a.safeModeForced {
+		return ConflictError("safe mode was enabled with --safe-mode", nil)
+	}
+	if a.configService != nil {
+		if err := a.configService.SetSafeMode(enabled); err != nil {
+			return err
+		}
+	}
+	
+	a.mu.Lock()
+	a.safeMode = enabled
+	a.mu.Unlock()
+	
+	a.logger.InfoWithFields("Safe mode changed", map[string]interface{}{
+		"enabled": enabled,
+	})
+	a.emitEvent(RuntimeSafeModeChanged, enabled)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"safeMode": enabled,
+	})
+	return nil
+}
+
+// requireExecution refuses action while safe mode is on
+func (a *App) requireExecution(action string) error {
+	if a.IsSafeMode() {
+		return PermissionError(action+" is disabled in safe mode", nil)
+	}
+	return nil
+}
+
+// ShowWindow brings the main window to the front
+func (a *App) ShowWindow() {
+	if a.ctx == nil || a.headless {
+		return
+	}
+	runtime.WindowShow(a.ctx)
+	runtime.WindowUnminimise(a.ctx)
+}
+
+// Quit exits the application
+func (a *App) Quit() {
+	if a.ctx == nil || a.headless {
+		return
+	}
+	a.mu.Lock()
+	a.quitting = true
+	a.mu.Unlock()
+	runtime.Quit(a.ctx)
+}
+
+// Shutdown API methods
+
+// GetRunningAgents lists the agents still working in their worktrees
+func (a *App) GetRunningAgents() ([]RunningAgent, error) {
+	worktrees, err := a.agentService.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	return runningAgents(worktrees, a.taskService.GetTasks(), processAlive), nil
+}
+
+// beforeClose hides the window rather than closing it while the tray is up
+// to reopen it from, and holds a quit back while agents are running until
+// the user picks what happens to them through ConfirmShutdown. A read-only
+// instance doesn't own the agents and never asks.
+func (a *App) beforeClose(ctx context.Context) bool {
+	a.mu.RLock()
+	choice, quitting := a.shutdownChoice, a.quitting
+	a.mu.RUnlock()
+	if !quitting && a.trayService.Ready() {
+		runtime.WindowHide(ctx)
+		return true
+	}
+	if choice != "" || a.readOnly {
+		return false
+	}
+	agents, err := a.GetRunningAgents()
+	if err != nil {
+		a.logger.Error("Failed to check for running agents before quitting", err)
+		return false
+	}
+	if len(agents) == 0 {
+		return false
+	}
+	a.ShowWindow()
+	a.emitEvent(RuntimeShutdownRequested, agents)
+	return true
+}
+
+// ConfirmShutdown answers a shutdown request: wait lets the running agents
+// finish and quits after them, terminate stops them first, and detach
+// leaves them running for the next launch to pick up
+func (a *App) ConfirmShutdown(choice string) error {
+	if !validShutdownChoice(choice) {
+		return ValidationError("shutdown choice must be wait, terminate or detach", nil).
+			WithContext("choice", choice)
+	}
+	agents, err := a.GetRunningAgents()
+	if err != nil {
+		return err
+	}
+	
+	switch choice {
+	case ShutdownWait:
+		a.logger.InfoWithFields("Quitting once agents finish", map[string]interface{}{
+			"agents": len(agents),
+		})
+		a.errorHandler.Go("shutdown wait", a.quitAfterAgents)
+		return nil
+	case ShutdownTerminate:
+		for _, agent := range agents {
+			if err := a.agentService.StopAgent(agent.PID); err != nil {
+				return err
+			}
+			a.recordEvent(EventAgentInterrupted, agent.TaskID, map[string]interface{}{
+				"worktree":   agent.Worktree,
+				"pid":        agent.PID,
+				"terminated": true,
+			})
+		}
+	case ShutdownDetach:
+		taskIDs := []int{}
+		for _, agent := range agents {
+			taskIDs = append(taskIDs, agent.TaskID)
+		}
+		a.recordEvent(EventAgentsDetached, 0, map[string]interface{}{
+			"tasks": taskIDs,
+		})
+	}
+	a.quitWith(choice)
+	return nil
+}
+
+// quitAfterAgents polls until no agent is running, then quits
+func (a *App) quitAfterAgents() {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		agents, err := a.GetRunningAgents()
+		if err != nil {
+			a.logger.Error("Failed to check on running agents", err)
+			continue
+		}
+		if len(agents) == 0 {
+			a.quitWith(ShutdownWait)
+			return
+		}
+	}
+}
+
+// quitWith records the shutdown choice, so beforeClose lets the quit
+// through, and quits
+func (a *App) quitWith(choice string) {
+	a.mu.Lock()
+	a.shutdownChoice = choice
+	a.mu.Unlock()
+	a.Quit()
+}
+
+// Quick-add API methods
+
+// openQuickAdd shows the window and asks the frontend to open the capture prompt
+func (a *App) openQuickAdd() {
+	defer a.errorHandler.RecoverGoroutine("quick-add hotkey")
+	a.telemetry.Increment("feature.quick_add")
+	a.ShowWindow()
+	a.emitEvent(RuntimeQuickAddOpen)
+}
+
+// SetQuickAddHotkey changes the system-wide quick-add hotkey and re-registers it
+func (a *App) SetQuickAddHotkey(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if _, err := ParseHotkey(spec); spec != "" && err != nil {
+		return err
+	}
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if err := a.configService.SetQuickAddHotkey(spec); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"quickAddHotkey": spec,
+	})
+	if a.headless {
+		return nil
+	}
+	return a.hotkeyService.Register(spec, a.openQuickAdd)
+}
+
+// Backup API methods
+
+// GetBackupDirectory returns where the active repository's backups are kept
+func (a *App) GetBackupDirectory() string {
+	if a.backupDir == "" {
+		return filepath.Dir(a.taskService.GetTaskFile())
+	}
+	return a.backupDir
+}
+
+// SetBackupDirectory changes the base directory for backups (empty restores
+// the default) and moves the active repository's backups there
+func (a *App) SetBackupDirectory(baseDir string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	baseDir = strings.TrimSpace(baseDir)
+	if baseDir != "" && !filepath.IsAbs(baseDir) {
+		return ValidationError("backup directory must be an absolute path", nil).WithContext("dir", baseDir)
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetBackupDir(baseDir); err != nil {
+		return err
+	}
+	
+	a.useBackupDir(activeRepo.Path, repositoryBackupDir(baseDir, *activeRepo))
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"backupDir": baseDir,
+	})
+	return nil
+}
+
+// Encryption API methods
+
+// IsRepositoryEncrypted reports whether the active repository's task.json or
+// plan.md is encrypted at rest
+func (a *App) IsRepositoryEncrypted() bool {
+	return fileIsEncrypted(a.taskService.GetTaskFile()) || fileIsEncrypted(a.planService.GetPlanFile())
+}
+
+// encryptableFiles lists the active repository's plan data: backups first,
+// so the files agents and the board read are rewritten last
+func (a *App) encryptableFiles() []string {
+	files := []string{}
+	backups, _ := filepath.Glob(filepath.Join(a.GetBackupDirectory(), "*.backup.*"))
+	for _, backup := range backups {
+		if !strings.Contains(filepath.Base(backup), ".tmp.") {
+			files = append(files, backup)
+		}
+	}
+	return append(files, a.taskService.GetTaskFile(), a.planService.GetPlanFile(), a.milestones.GetMilestoneFile(), a.releases.GetReleaseFile(), a.reviewComments.GetReviewCommentFile())
+}
+
+// EncryptRepository encrypts the active repository's task.json, plan.md and
+// their backups. The key is kept in the OS keychain; an interrupted run can be
+// repeated and reuses it.
+func (a *App) EncryptRepository() error {
+	repoPath := a.agentService.GetProjectRoot()
+	if err := a.taskService.Flush(); err != nil {
+		return err
+	}
+	
+	cipher, err := loadRepositoryCipher(a.keys, repoPath)
+	if errors.Is(err, ErrKeyNotFound) {
+		var key []byte
+		if key, err = GenerateKey(); err == nil {
+			if err = a.keys.Set(repositoryKeyAccount(repoPath), key); err == nil {
+				cipher, err = NewCipher(key)
+			}
+		}
+	}
+	if err != nil {
+		a.logger.Error("Failed to load repository encryption key", err)
+		return fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	
+	// Files already encrypted by an interrupted run are opened with the same key
+	count, err := recryptFiles(a.encryptableFiles(), cipher, cipher)
+	a.setCipher(cipher)
+	if err != nil {
+		a.logger.Error("Failed to encrypt repository", err)
+		return err
+	}
+	
+	a.logger.InfoWithFields("Repository encrypted", map[string]interface{}{
+		"path":  repoPath,
+		"files": count,
+	})
+	a.recordEvent(EventRepoEncrypted, 0, map[string]interface{}{
+		"files": count,
+	})
+	return nil
+}
+
+// DecryptRepository writes the active repository's plan data back in plain
+// text and removes its key from the keychain
+func (a *App) DecryptRepository() error {
+	repoPath := a.agentService.GetProjectRoot()
+	if err := a.taskService.Flush(); err != nil {
+		return err
+	}
+	
+	cipher, err := loadRepositoryCipher(a.keys, repoPath)
+	if err != nil {
+		a.logger.Error("Failed to load repository encryption key", err)
+		return fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	count, err := recryptFiles(a.encryptableFiles(), cipher, nil)
+	if err != nil {
+		a.logger.Error("Failed to decrypt repository", err)
+		return err
+	}
+	a.setCipher(nil)
+	if err := a.keys.Delete(repositoryKeyAccount(repoPath)); err != nil {
+		a.logger.Error("Failed to remove encryption key from keychain", err)
+	}
+	
+	a.logger.InfoWithFields("Repository decrypted", map[string]interface{}{
+		"path":  repoPath,
+		"files": count,
+	})
+	a.recordEvent(EventRepoDecrypted, 0, map[string]interface{}{
+		"files": count,
+	})
+	return nil
+}
+
+// Quota API methods
+
+// GetQuotaStatus returns agent launches used against the limits, overall
+// and for the active repository
+func (a *App) GetQuotaStatus() QuotaStatus {
+	return a.quota.Status(a.agentService.GetProjectRoot())
+}
+
+// SetQuotaConfig saves and applies agent launch limits; zero means no limit
+func (a *App) SetQuotaConfig(quota QuotaConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	for name, limit := range map[string]int{
+		"hourlyLaunches":     quota.HourlyLaunches,
+		"dailyLaunches":      quota.DailyLaunches,
+		"repoHourlyLaunches": quota.RepoHourlyLaunches,
+		"repoDailyLaunches":  quota.RepoDailyLaunches,
+	} {
+		if limit < 0 {
+			return ValidationError("launch limits can't be negative", nil).WithContext(name, limit)
+		}
+	}
+	if err := a.configService.SetQuotaConfig(quota); err != nil {
+		return err
+	}
+	
+	a.quota.SetLimits(quota)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"quota": quota,
+	})
+	return nil
+}
+
+// Agent sandbox API methods
+
+// IsAgentSandboxEnabled reports whether agents may only write inside their worktree
+func (a *App) IsAgentSandboxEnabled() bool {
+	if a.configService == nil {
+		return false
+	}
+	return a.configService.GetSandboxAgents()
+}
+
+// SetAgentSandbox confines agents launched from now on to their worktree, so
+// they can't modify the primary checkout
+func (a *App) SetAgentSandbox(enabled bool) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if err := a.configService.SetSandboxAgents(enabled); err != nil {
+		return err
+	}
+	
+	type sandboxable interface {
+		SetSandbox(enabled bool)
+	}
+	if agents, ok := a.agentService.(sandboxable); ok {
+		agents.SetSandbox(enabled)
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"sandboxAgents": enabled,
+	})
+	return nil
+}
+
+// Helper script API methods
+
+// GetHelperScripts compares the active repository's helper scripts with
+// their pinned checksums
+func (a *App) GetHelperScripts() ([]HelperScriptStatus, error) {
+	if a.configService == nil {
+		return helperScriptStatuses(a.agentService.GetProjectRoot(), nil), nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return nil, err
+	}
+	return helperScriptStatuses(activeRepo.Path, activeRepo.ScriptChecksums), nil
+}
+
+// PinHelperScripts records the checksums of the active repository's helper
+// scripts as they are now; from then on only those exact scripts run
+func (a *App) PinHelperScripts() error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	pins, err := pinHelperScripts(activeRepo.Path)
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryScriptChecksums(activeRepo.ID, pins); err != nil {
+		return err
+	}
+	
+	a.applyScriptChecksums(pins)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"scriptChecksums": pins,
+	})
+	return nil
+}
+
+// UnpinHelperScripts stops verifying the active repository's helper scripts
+func (a *App) UnpinHelperScripts() error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryScriptChecksums(activeRepo.ID, nil); err != nil {
+		return err
+	}
+	
+	a.applyScriptChecksums(nil)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"scriptChecksums": nil,
+	})
+	return nil
+}
+
+// applyScriptChecksums hands the pinned checksums to the agent service
+func (a *App) applyScriptChecksums(pins map[string]string) {
+	type scriptPinned interface {
+		SetScriptChecksums(pins map[string]string)
+	}
+	if agents, ok := a.agentService.(scriptPinned); ok {
+		agents.SetScriptChecksums(pins)
+	}
+}
+
+// Mainline sync API methods
+
+// GetMainlineSync returns how the active repository's main is brought up to
+// date before an agent is spawned
+func (a *App) GetMainlineSync() (string, error) {
+	if a.configService == nil {
+		return MainlineLocal, nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return "", err
+	}
+	return activeRepo.MainlineSync, nil
+}
+
+// SetMainlineSync sets, for the active repository, whether agents start from
+// local main as it is, from local main fast-forwarded to origin, or from
+// origin/main
+func (a *App) SetMainlineSync(mode string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if !validMainlineSync(mode) {
+		return ValidationError("mainline sync must be empty, fast_forward or origin", nil).
+			WithContext("mode", mode)
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryMainlineSync(activeRepo.ID, mode); err != nil {
+		return err
+	}
+	
+	a.applyMainlineSync(mode)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"mainlineSync": mode,
+	})
+	return nil
+}
+
+// applyMainlineSync hands the mainline sync mode to the agent service
+func (a *App) applyMainlineSync(mode string) {
+	type mainlineSynced interface {
+		SetMainlineSync(mode string)
+	}
+	if agents, ok := a.agentService.(mainlineSynced); ok {
+		agents.SetMainlineSync(mode)
+	}
+}
+
+// GetMainlineBranch returns the branch the active repository's agents start
+// from and its approved tasks merge into
+func (a *App) GetMainlineBranch() (string, error) {
+	if a.configService == nil {
+		return defaultMainlineBranch, nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return "", err
+	}
+	if activeRepo.MainlineBranch == "" {
+		return defaultMainlineBranch, nil
+	}
+	return activeRepo.MainlineBranch, nil
+}
+
+// SetMainlineBranch sets the active repository's mainline branch, such as
+// master or develop; empty goes back to main. Outside safe mode the branch
+// must already exist.
+func (a *App) SetMainlineBranch(branch string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	branch = strings.TrimSpace(branch)
+	if !validMainlineBranch(branch) {
+		return ValidationError("mainline branch is not a valid branch name", nil).
+			WithContext("branch", branch)
+	}
+	if branch == defaultMainlineBranch {
+		branch = ""
+	}
+	if branch != "" && !a.IsSafeMode() {
+		exists, err := a.agentService.BranchExists(branch)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return NotFoundError("mainline branch does not exist", nil).
+				WithContext("branch", branch)
+		}
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryMainlineBranch(activeRepo.ID, branch); err != nil {
+		return err
+	}
+	
+	a.applyMainlineBranch(branch)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"mainlineBranch": branch,
+	})
+	return nil
+}
+
+// applyMainlineBranch hands the mainline branch to the agent service
+func (a *App) applyMainlineBranch(branch string) {
+	type mainlineBranched interface {
+		SetMainlineBranch(branch string)
+	}
+	if agents, ok := a.agentService.(mainlineBranched); ok {
+		agents.SetMainlineBranch(branch)
+	}
+}
+
+// GetApprovalMode returns where approving a task in the active repository
+// merges its branch
+func (a *App) GetApprovalMode() (string, error) {
+	if a.configService == nil {
+		return ApproveInCheckout, nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return "", err
+	}
+	return activeRepo.ApprovalMode, nil
+}
+
+// SetApprovalMode sets, for the active repository, whether approvals merge
+// in the checkout that has the mainline or in a temporary worktree that
+// leaves the user's working directory alone
+func (a *App) SetApprovalMode(mode string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if !validApprovalMode(mode) {
+		return ValidationError("approval mode must be empty or worktree", nil).
+			WithContext("mode", mode)
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryApprovalMode(activeRepo.ID, mode); err != nil {
+		return err
+	}
+	
+	a.applyApprovalMode(mode)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"approvalMode": mode,
+	})
+	return nil
+}
+
+// applyApprovalMode hands the approval mode to the agent service
+func (a *App) applyApprovalMode(mode string) {
+	type approvalModed interface {
+		SetApprovalMode(mode string)
+	}
+	if agents, ok := a.agentService.(approvalModed); ok {
+		agents.SetApprovalMode(mode)
+	}
+}
+
+// Spawner API methods
+
+// GetSpawnerSettings returns how the active repository's agents are
+// launched and how many run at once
+func (a *App) GetSpawnerSettings() (SpawnerSettings, error) {
+	if a.configService == nil {
+		return SpawnerSettings{}, nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return SpawnerSettings{}, err
+	}
+	return SpawnerSettings{Mode: activeRepo.Spawner, MaxAgents: activeRepo.MaxAgents}, nil
+}
+
+// SetSpawnerSettings sets, for the active repository, whether agents are
+// launched by agent_spawn.sh or natively, and how many may run at once
+func (a *App) SetSpawnerSettings(settings SpawnerSettings) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if !validSpawner(settings.Mode) {
+		return ValidationError("spawner must be empty, native or script", nil).
+			WithContext("mode", settings.Mode)
+	}
+	if settings.MaxAgents < 0 {
+		return ValidationError("max agents must not be negative", nil).
+			WithContext("maxAgents", settings.MaxAgents)
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositorySpawner(activeRepo.ID, settings.Mode, settings.MaxAgents); err != nil {
+		return err
+	}
+	
+	a.applySpawner(settings)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"spawner":   settings.Mode,
+		"maxAgents": settings.MaxAgents,
+	})
+	return nil
+}
+
+// applySpawner hands the spawner settings to the agent service
+func (a *App) applySpawner(settings SpawnerSettings) {
+	type spawnerSet interface {
+		SetSpawner(mode string)
+		SetMaxAgents(n int)
+	}
+	if agents, ok := a.agentService.(spawnerSet); ok {
+		agents.SetSpawner(settings.Mode)
+		agents.SetMaxAgents(settings.MaxAgents)
+	}
+}
+
+// Commit signing API methods
+
+// GetSigningConfig returns how the active repository's merge commits are
+// signed, or nil if the app leaves it to git
+func (a *App) GetSigningConfig() (*SigningConfig, error) {
+	if a.configService == nil {
+		return nil, fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return nil, err
+	}
+	return activeRepo.Signing, nil
+}
+
+// SetSigningConfig sets how the active repository's merge commits are
+// signed; nil leaves it to git. Outside safe mode a throwaway commit is
+// signed first, and the setting is refused if that fails.
+func (a *App) SetSigningConfig(cfg *SigningConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if cfg != nil {
+		cfg.Key = strings.TrimSpace(cfg.Key)
+		if err := cfg.validate(); err != nil {
+			return ValidationError(err.Error(), nil).WithContext("format", cfg.Format)
+		}
+		if !a.IsSafeMode() {
+			if err := a.agentService.CheckSigning(cfg); err != nil {
+				return ValidationError("signing a test commit failed", err).
+					WithContext("format", cfg.Format).
+					WithContext("key", cfg.Key)
+			}
+		}
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositorySigning(activeRepo.ID, cfg); err != nil {
+		return err
+	}
+	
+	a.applySigning(cfg)
+	format := ""
+	if cfg != nil {
+		format = cfg.Format
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"signingFormat": format,
+	})
+	return nil
+}
+
+// CheckSigning signs a throwaway commit with the active repository's signing
+// configuration, so a broken key shows up before an approval needs it
+func (a *App) CheckSigning() (SigningCheck, error) {
+	if err := a.requireExecution("checking commit signing"); err != nil {
+		return SigningCheck{}, err
+	}
+	cfg, err := a.GetSigningConfig()
+	if err != nil {
+		return SigningCheck{}, err
+	}
+	if cfg == nil {
+		return SigningCheck{}, ValidationError("commit signing is not configured", nil)
+	}
+	check := SigningCheck{Format: cfg.Format, Key: cfg.Key, OK: true}
+	if err := a.agentService.CheckSigning(cfg); err != nil {
+		check.OK = false
+		check.Error = err.Error()
+	}
+	return check, nil
+}
+
+// applySigning hands the signing configuration to the agent service
+func (a *App) applySigning(cfg *SigningConfig) {
+	type signer interface {
+		SetSigning(signing *SigningConfig)
+	}
+	if agents, ok := a.agentService.(signer); ok {
+		agents.SetSigning(cfg)
+	}
+}
+
+// Changelog API methods
+
+// PreviewChangelog groups the tasks merged since sinceTag, or since the
+// newest tag when it's empty, into a CHANGELOG.md section without writing it
+func (a *App) PreviewChangelog(sinceTag string) (ChangelogPreview, error) {
+	if err := a.requireExecution("reading git history"); err != nil {
+		return ChangelogPreview{}, err
+	}
+	commits, since, err := a.agentService.ListTaskMerges(strings.TrimSpace(sinceTag))
+	if err != nil {
+		return ChangelogPreview{}, err
+	}
+	return buildChangelog(since, commits, a.taskService.GetTasks(), a.localNow(), a.messages()), nil
+}
+
+// GenerateChangelog writes the section PreviewChangelog builds to the top of
+// the repository's CHANGELOG.md, replacing an earlier Unreleased section,
+// and commits the file when the repository auto-commits its changelog
+func (a *App) GenerateChangelog(sinceTag string) (ChangelogPreview, error) {
+	preview, err := a.PreviewChangelog(sinceTag)
+	if err != nil {
+		return preview, err
+	}
+	path := filepath.Join(a.agentService.GetProjectRoot(), changelogFileName)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return preview, err
+	}
+	if err := NewFileUtils(a.logger).AtomicWrite(path, []byte(insertChangelogSection(string(existing), preview.Markdown))); err != nil {
+		return preview, err
+	}
+	preview.File = path
+	
+	if a.GetChangelogAutoCommit() {
+		message := "Update changelog"
+		if preview.Since != "" {
+			message += " since " + preview.Since
+		}
+		if err := a.agentService.CommitToMainline(changelogFileName, message); err != nil {
+			return preview, ConflictError("changelog written but not committed", err).
+				WithContext("file", path)
+		}
+		preview.Committed = true
+	}
+	
+	entries := 0
+	for _, group := range preview.Groups {
+		entries += len(group.Entries)
+	}
+	a.recordEvent(EventChangelogWritten, 0, map[string]interface{}{
+		"since":     preview.Since,
+		"entries":   entries,
+		"committed": preview.Committed,
+	})
+	return preview, nil
+}
+
+// GetChangelogAutoCommit reports whether GenerateChangelog commits the
+// active repository's changelog
+func (a *App) GetChangelogAutoCommit() bool {
+	if a.configService == nil {
+		return false
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return false
+	}
+	return activeRepo.ChangelogCommit
+}
+
+// SetChangelogAutoCommit sets whether GenerateChangelog commits the active
+// repository's changelog to the mainline
+func (a *App) SetChangelogAutoCommit(enabled bool) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryChangelogCommit(activeRepo.ID, enabled); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"changelogAutoCommit": enabled,
+	})
+	return nil
+}
+
+// Release API methods
+
+// GetReleases returns the active repository's releases, newest first
+func (a *App) GetReleases() ([]Release, error) {
+	return a.releases.List()
+}
+
+// CreateRelease tags the mainline as version once every listed task has a
+// merge commit on it. The tag's message is the changelog of those tasks,
+// and the release is recorded in plan/releases.json.
+func (a *App) CreateRelease(version string, taskIDs []int) (Release, error) {
+	version = strings.TrimSpace(version)
+	if !validReleaseVersion(version) {
+		return Release{}, ValidationError("version must be a valid tag name", nil).
+			WithContext("version", version)
+	}
+	if len(taskIDs) == 0 {
+		return Release{}, ValidationError("a release needs at least one task", nil)
+	}
+	if err := a.requireExecution("tagging a release"); err != nil {
+		return Release{}, err
+	}
+	if _, found, err := a.releases.Find(version); err != nil {
+		return Release{}, err
+	} else if found {
+		return Release{}, ConflictError("release already exists", nil).WithContext("version", version)
+	}
+	
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, id := range taskIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	merges, err := a.agentService.MainlineMerges()
+	if err != nil {
+		return Release{}, err
+	}
+	commits, unmerged := releaseCommits(merges, ids)
+	if len(unmerged) > 0 {
+		return Release{}, ConflictError("tasks are not merged into the mainline", nil).
+			WithContext("tasks", fmt.Sprint(unmerged))
+	}
+	
+	_, since, err := a.agentService.ListTaskMerges("")
+	if err != nil {
+		return Release{}, err
+	}
+	changelog := buildChangelog(since, commits, a.taskService.GetTasks(), a.localNow(), a.messages())
+	changelog.Version = version
+	changelog.Markdown = renderChangelogSection(changelog, a.messages())
+	
+	signed, err := a.agentService.TagMainline(version, a.messages().T(MsgReleaseTag, version)+"\n\n"+changelog.Markdown)
+	if err != nil {
+		return Release{}, err
+	}
+	release := Release{
+		Version:   version,
+		Date:      changelog.Date.UTC(),
+		Since:     since,
+		TaskIDs:   ids,
+		Groups:    changelog.Groups,
+		Changelog: changelog.Markdown,
+		Signed:    signed,
+	}
+	if err := a.releases.Add(release); err != nil {
+		return release, fmt.Errorf("tagged %s but could not record the release: %w", version, err)
+	}
+	
+	a.recordEvent(EventReleaseCreated, 0, map[string]interface{}{
+		"version": version,
+		"since":   since,
+		"tasks":   ids,
+		"signed":  signed,
+	})
+	return release, nil
+}
+
+// Diff API methods
+
+// GetTaskDiff returns a task branch's changes against the mainline as files
+// and hunks, with changed words marked on edited lines and a language hint
+// per file, so the diff viewer renders without parsing the patch itself
+func (a *App) GetTaskDiff(taskID int) (TaskDiff, error) {
+	if err := a.requireTaskBranch(taskID); err != nil {
+		return TaskDiff{}, err
+	}
+	branch := taskBranch(taskID)
+	patch, base, err := a.agentService.TaskBranchDiff(taskID)
+	if err != nil {
+		return TaskDiff{}, err
+	}
+	files, truncated := parseDiff(patch, maxDiffLines)
+	return TaskDiff{TaskID: taskID, Branch: branch, Base: base, Files: files, Truncated: truncated}, nil
+}
+
+// GetTaskDiffSummary lists the files a task branch changed with their line
+// counts, flagging the ones too large to load at once. Reviews show it first
+// and fetch each file's diff with GetTaskDiffFile.
+func (a *App) GetTaskDiffSummary(taskID int) (TaskDiffSummary, error) {
+	if err := a.requireTaskBranch(taskID); err != nil {
+		return TaskDiffSummary{}, err
+	}
+	files, base, err := a.agentService.TaskBranchFiles(taskID)
+	if err != nil {
+		return TaskDiffSummary{}, err
+	}
+	summary := TaskDiffSummary{TaskID: taskID, Branch: taskBranch(taskID), Base: base, Files: files}
+	for _, file := range files {
+		summary.Additions += file.Additions
+		summary.Deletions += file.Deletions
+	}
+	return summary, nil
+}
+
+// GetTaskDiffFile returns one page of one file's diff on a task branch.
+// path is the file's new path, or its old path if it was deleted; page
+// counts from zero and the result says how many pages there are.
+func (a *App) GetTaskDiffFile(taskID int, path string, page int) (DiffFilePage, error) {
+	if page < 0 {
+		return DiffFilePage{}, ValidationError("page must not be negative", nil)
+	}
+	summary, err := a.GetTaskDiffSummary(taskID)
+	if err != nil {
+		return DiffFilePage{}, err
+	}
+	var changed *DiffFileSummary
+	for i := range summary.Files {
+		if summary.Files[i].NewPath == path || summary.Files[i].OldPath == path {
+			changed = &summary.Files[i]
+			break
+		}
+	}
+	if changed == nil {
+		return DiffFilePage{}, NotFoundError("file not changed on the task branch", nil).
+			WithContext("path", path)
+	}
+	
+	paths := []string{changed.path()}
+	if changed.Status == FileRenamed {
+		paths = []string{changed.OldPath, changed.NewPath}
+	}
+	patch, _, err := a.agentService.TaskBranchDiff(taskID, paths...)
+	if err != nil {
+		return DiffFilePage{}, err
+	}
+	file := DiffFile{OldPath: changed.OldPath, NewPath: changed.NewPath, Status: changed.Status, Language: changed.Language, Binary: changed.Binary}
+	if files, _ := parsePatch(patch, 0, false); len(files) > 0 {
+		file = files[0]
+	}
+	file.Additions, file.Deletions = changed.Additions, changed.Deletions
+	
+	// Word segments are only worked out for the page being sent
+	pages := paginateHunks(file.Hunks, diffPageLines)
+	if page >= len(pages) {
+		return DiffFilePage{}, ValidationError("page out of range", nil).
+			WithContext("pages", len(pages))
+	}
+	file.Hunks = pages[page]
+	for _, hunk := range file.Hunks {
+		addWordSegments(hunk.Lines)
+	}
+	return DiffFilePage{TaskID: taskID, File: file, Page: page, Pages: len(pages)}, nil
+}
+
+// CompareBranches compares two attempts at the same work, such as a task
+// branch and a retry of it: the commits each has that the other doesn't,
+// what each changed against the mainline file by file, and the diff from
+// a to b
+func (a *App) CompareBranches(branchA, branchB string) (BranchComparison, error) {
+	branchA, branchB = strings.TrimSpace(branchA), strings.TrimSpace(branchB)
+	for _, branch := range []string{branchA, branchB} {
+		if branch == "" || !validMainlineBranch(branch) {
+			return BranchComparison{}, ValidationError("invalid branch name", nil).WithContext("branch", branch)
+		}
+	}
+	if branchA == branchB {
+		return BranchComparison{}, ValidationError("pick two different branches to compare", nil)
+	}
+	if err := a.requireExecution("reading git history"); err != nil {
+		return BranchComparison{}, err
+	}
+	for _, branch := range []string{branchA, branchB} {
+		exists, err := a.agentService.BranchExists(branch)
+		if err != nil {
+			return BranchComparison{}, err
+		}
+		if !exists {
+			return BranchComparison{}, NotFoundError("branch not found", nil).WithContext("branch", branch)
+		}
+	}
+	return a.agentService.CompareBranches(branchA, branchB)
+}
+
+// GetReviewOwners lists, for the files a task branch changed, the people
+// who recently committed to them on the mainline, so the reviewer knows who
+// else should look. New files have no history and are left out.
+func (a *App) GetReviewOwners(taskID int) (ReviewOwnership, error) {
+	summary, err := a.GetTaskDiffSummary(taskID)
+	if err != nil {
+		return ReviewOwnership{}, err
+	}
+	ownership := ReviewOwnership{Files: []FileOwnership{}, Reviewers: []FileAuthor{}}
+	paths := []string{}
+	for _, file := range summary.Files {
+		if file.OldPath == "" {
+			continue
+		}
+		if len(paths) == maxOwnershipFiles {
+			ownership.Truncated = true
+			break
+		}
+		paths = append(paths, file.OldPath)
+	}
+	if len(paths) == 0 {
+		return ownership, nil
+	}
+	if ownership.Files, err = a.agentService.FileOwners(paths); err != nil {
+		return ReviewOwnership{}, err
+	}
+	ownership.Reviewers = suggestedReviewers(ownership.Files)
+	return ownership, nil
+}
+
+// requireTaskBranch refuses diffs in safe mode and of tasks with no branch
+func (a *App) requireTaskBranch(taskID int) error {
+	if err := a.requireExecution("reading git history"); err != nil {
+		return err
+	}
+	branch := taskBranch(taskID)
+	exists, err := a.agentService.BranchExists(branch)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return NotFoundError("task branch not found", nil).WithContext("branch", branch)
+	}
+	return nil
+}
+
+// Review comment API methods
+
+// GetReviewComments returns the comments on a task's diff, oldest first
+func (a *App) GetReviewComments(taskID int) ([]ReviewComment, error) {
+	return a.reviewComments.List(taskID)
+}
+
+// AddReviewComment comments on a line of a task's diff. Line 0 comments on
+// the whole file; the side defaults to new and the author to the current user.
+func (a *App) AddReviewComment(comment ReviewComment) (ReviewComment, error) {
+	if _, ok := findTask(a.taskService.GetTasks(), comment.TaskID); !ok {
+		return ReviewComment{}, NotFoundError("task not found", nil).WithContext("task_id", comment.TaskID)
+	}
+	return a.reviewComments.Add(comment, time.Now().UTC())
+}
+
+// UpdateReviewComment changes the body of a comment not yet sent to the agent
+func (a *App) UpdateReviewComment(id, body string) (ReviewComment, error) {
+	return a.reviewComments.Update(id, body, time.Now().UTC())
+}
+
+// DeleteReviewComment removes a comment
+func (a *App) DeleteReviewComment(id string) error {
+	_, err := a.reviewComments.Delete(id)
+	return err
+}
+
+// RequestChanges sends a task under review back to an agent, relaunched as
+// mode, with summary and the comments not yet sent compiled into its
+// prompt. The comments are marked sent once the agent is launched.
+func (a *App) RequestChanges(taskID int, summary, mode string) error {
+	task, ok := findTask(a.taskService.GetTasks(), taskID)
+	if !ok {
+		return NotFoundError("task not found", nil).WithContext("task_id", taskID)
+	}
+	if task.Status != StatusPendingReview {
+		return ConflictError("only tasks in review can have changes requested", nil).
+			WithContext("task_id", taskID).
+			WithContext("status", task.Status)
+	}
+	comments, err := a.reviewComments.List(taskID)
+	if err != nil {
+		return err
+	}
+	pending := []ReviewComment{}
+	ids := []string{}
+	for _, comment := range comments {
+		if comment.Submitted == nil {
+			pending = append(pending, comment)
+			ids = append(ids, comment.ID)
+		}
+	}
+	if len(pending) == 0 && strings.TrimSpace(summary) == "" {
+		return ValidationError("add a comment or a summary of the changes wanted", nil)
+	}
+	
+	if err := a.relaunchTask(taskID, mode, reviewFeedback(task, summary, pending)); err != nil {
+		return err
+	}
+	if err := a.reviewComments.MarkSubmitted(ids, time.Now().UTC()); err != nil {
+		a.logger.Error("Failed to mark review comments as sent", err)
+	}
+	a.recordEvent(EventChangesRequested, taskID, map[string]interface{}{
+		"comments": len(pending),
+		"mode":     mode,
+	})
+	return nil
+}
+
+// Triage API methods
+
+// GetTriageConfig returns how the active repository triages new tasks
+func (a *App) GetTriageConfig() (TriageConfig, error) {
+	if a.configService == nil {
+		return TriageConfig{}, nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return TriageConfig{}, err
+	}
+	if activeRepo.Triage == nil {
+		return TriageConfig{Rules: []TriageRule{}}, nil
+	}
+	return *activeRepo.Triage, nil
+}
+
+// SetTriageConfig sets, for the active repository, the priority new tasks
+// get without one and the tag rules that route them to backlog or todo
+func (a *App) SetTriageConfig(triage TriageConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	triage, err := validateTriage(triage)
+	if err != nil {
+		return err
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryTriage(activeRepo.ID, triage); err != nil {
+		return err
+	}
+	
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"triageDefaultPriority": triage.DefaultPriority,
+		"triageRules":           len(triage.Rules),
+	})
+	return nil
+}
+
+// RunTriage applies the active repository's triage rules to the backlog
+// tasks no rule has routed yet, such as those agents added to task.json or
+// created before a rule for their tag existed
+func (a *App) RunTriage() (TriageResult, error) {
+	triage := a.triageConfig()
+	result := TriageResult{Triaged: []int{}, Untriaged: []int{}}
+	for _, task := range a.taskService.GetTasks() {
+		if !untriaged(task) {
+			continue
+		}
+		routed := task
+		rule, ok := triage.triage(&routed)
+		if !ok {
+			result.Untriaged = append(result.Untriaged, task.ID)
+			continue
+		}
+		if err := a.taskService.UpdateTask(routed); err != nil {
+			return result, err
+		}
+		result.Triaged = append(result.Triaged, task.ID)
+		if routed.Status != task.Status {
+			a.recordEvent(EventTaskMoved, task.ID, map[string]interface{}{
+				"from":   task.Status,
+				"to":     routed.Status,
+				"triage": rule.Tag,
+			})
+		} else {
+			a.recordEvent(EventTaskUpdated, task.ID, map[string]interface{}{
+				"priority": routed.Priority,
+				"triage":   rule.Tag,
+			})
+		}
+	}
+	return result, nil
+}
+
+// triageConfig returns the active repository's triage config, or the
+// defaults when it has none
+func (a *App) triageConfig() TriageConfig {
+	triage, err := a.GetTriageConfig()
+	if err != nil {
+		return TriageConfig{}
+	}
+	return triage
+}
+
+// Review check API methods
+
+// GetReviewChecks returns the commands run in an agent's worktree when it
+// hands its task over for review
+func (a *App) GetReviewChecks() ([]string, error) {
+	if a.configService == nil {
+		return []string{}, nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return nil, err
+	}
+	if activeRepo.ReviewChecks == nil {
+		return []string{}, nil
+	}
+	return activeRepo.ReviewChecks, nil
+}
+
+// SetReviewChecks sets, for the active repository, the commands run in an
+// agent's worktree when it moves its task from doing to pending_review,
+// such as "go test ./..."
+func (a *App) SetReviewChecks(checks []string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	cleaned := make([]string, 0, len(checks))
+	for _, check := range checks {
+		check = strings.TrimSpace(check)
+		if check == "" {
+			return ValidationError("review checks must not be empty", nil)
+		}
+		cleaned = append(cleaned, check)
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryReviewChecks(activeRepo.ID, cleaned); err != nil {
+		return err
+	}
+	
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"reviewChecks": cleaned,
+	})
+	return nil
+}
+
+// RunReviewChecks runs the review checks in the task's worktree, journals
+// the outcome and tells the frontend the task is ready for review. Checks
+// that can't run (safe mode, no worktree) are skipped, not failed.
+func (a *App) RunReviewChecks(taskID int) (ReviewReadyEvent, error) {
+	task, ok := findTask(a.taskService.GetTasks(), taskID)
+	if !ok {
+		return ReviewReadyEvent{}, NotFoundError("task not found", nil).
+			WithContext("task_id", taskID)
+	}
+	commands, err := a.GetReviewChecks()
+	if err != nil {
+		return ReviewReadyEvent{}, err
+	}
+	
+	ready := ReviewReadyEvent{TaskID: taskID, Title: task.Title, Branch: taskBranch(taskID), Checks: []ReviewCheck{}}
+	worktree := ""
+	if len(commands) > 0 {
+		if err := a.requireExecution("running review checks"); err != nil {
+			ready.Skipped = err.Error()
+		} else if worktree, err = a.agentService.FindTaskWorktree(taskID); err != nil {
+			ready.Skipped = err.Error()
+		}
+	}
+	failed := 0
+	for _, command := range commands {
+		if ready.Skipped != "" {
+			break
+		}
+		start := time.Now()
+		output, err := a.agentService.RunCheck(worktree, command)
+		check := ReviewCheck{
+			Command:         command,
+			Passed:          err == nil,
+			Output:          outputTail(output, reviewCheckOutputTail),
+			DurationSeconds: time.Since(start).Seconds(),
+		}
+		if err != nil {
+			check.Error = err.Error()
+			failed++
+		}
+		ready.Checks = append(ready.Checks, check)
+	}
+	if ownership, err := a.GetReviewOwners(taskID); err == nil {
+		ready.Ownership = &ownership
+	}
+	
+	data := map[string]interface{}{
+		"branch": ready.Branch,
+		"checks": len(ready.Checks),
+		"failed": failed,
+	}
+	if ready.Skipped != "" {
+		data["skipped"] = ready.Skipped
+	}
+	a.recordEvent(EventReviewChecked, taskID, data)
+	a.emitEvent(RuntimeReviewReady, ready)
+	return ready, nil
+}
+
+// syncExternalEdits reloads task.json if something outside the app, usually
+// an agent, has edited it since it was last read or written
+func (a *App) syncExternalEdits(wait bool) error {
+	if !a.taskService.DiskChanged() {
+		return nil
+	}
+	before := a.taskService.GetTasks()
+	tasks, err := a.taskService.LoadTasks()
+	if err != nil {
+		return err
+	}
+	a.emitEvent(RuntimeTasksChanged, tasks)
+	a.noticeStatusChanges(before, tasks, wait)
+	return nil
+}
+
+// noticeStatusChanges journals the moves made on disk between two reads of
+// task.json and runs the review checks of tasks an agent handed over for
+// review; synchronously when wait is true
+func (a *App) noticeStatusChanges(before, after []Task, wait bool) {
+	for _, change := range statusChanges(before, after) {
+		taskID := change.Task.ID
+		a.recordEvent(EventTaskMoved, taskID, map[string]interface{}{
+			"from":     change.From,
+			"to":       change.Task.Status,
+			"external": true,
+		})
+		if !change.handedForReview() {
+			continue
+		}
+		check := func() {
+			if _, err := a.RunReviewChecks(taskID); err != nil {
+				a.logger.ErrorWithFields("Failed to run review checks", err, map[string]interface{}{
+					"task_id": taskID,
+				})
+			}
+		}
+		if wait {
+			check()
+		} else {
+			a.errorHandler.Go("review checks", check)
+		}
+	}
+}
+
+// watchTaskFile reloads task.json when it is edited outside the app until
+// stop is closed, polling for edits if the OS can't watch it
+func (a *App) watchTaskFile(stop <-chan struct{}) {
+	lastErr := ""
+	reload := func() {
+		// A broken file stays broken between edits; log it once
+		err := a.syncExternalEdits(false)
+		if err != nil && err.Error() != lastErr {
+			a.logger.Error("Failed to reload task file edited on disk", err)
+		}
+		lastErr = ""
+		if err != nil {
+			lastErr = err.Error()
+		}
+	}
+	err := a.taskService.Watch(stop, reload)
+	if err == nil {
+		return
+	}
+	a.logger.Error("Failed to watch the task file, polling it instead", err)
+	
+	ticker := time.NewTicker(taskWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			reload()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Redaction API methods
+
+// GetRedactionConfig returns the extra secret patterns and whether the
+// built-in ones are on
+func (a *App) GetRedactionConfig() RedactionConfig {
+	if a.configService == nil {
+		return RedactionConfig{}
+	}
+	return a.configService.GetRedactionConfig()
+}
+
+// SetRedactionConfig validates, saves and applies secret patterns
+func (a *App) SetRedactionConfig(redaction RedactionConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	redactor, err := NewRedactor(redaction)
+	if err != nil {
+		return err
+	}
+	if err := a.configService.SetRedactionConfig(redaction); err != nil {
+		return err
+	}
+	
+	a.applyRedactor(redactor)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"redactionPatterns": len(redaction.Patterns),
+		"redactionDefaults": !redaction.DisableDefaults,
+	})
+	return nil
+}
+
+// Automation API methods
+
+// GetAutomationRules returns the board automation rules
+func (a *App) GetAutomationRules() []AutomationRule {
+	return a.automation.Rules()
+}
+
+// SetAutomationRules validates, saves and applies the board automation rules
+func (a *App) SetAutomationRules(rules []AutomationRule) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if err := ValidateAutomationRules(rules); err != nil {
+		return err
+	}
+	if err := a.configService.SetAutomationConfig(AutomationConfig{Rules: rules}); err != nil {
+		return err
+	}
+	
+	a.automation.SetRules(rules)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"automationRules": len(rules),
+	})
+	return nil
+}
+
+// Stale task API methods
+
+// GetStaleTasks reports tasks stuck in a status longer than its threshold in
+// days. thresholds override the configured ones for this call; statuses left
+// out keep them.
+func (a *App) GetStaleTasks(thresholds map[string]int) (StaleReport, error) {
+	if err := validateStaleThresholds(thresholds); err != nil {
+		return StaleReport{}, err
+	}
+	return a.automation.staleReport(thresholds, time.Now())
+}
+
+// GetStaleConfig returns the saved stale thresholds, with defaults filled in
+func (a *App) GetStaleConfig() StaleConfig {
+	stale := StaleConfig{}
+	if a.configService != nil {
+		stale = a.configService.GetStaleConfig()
+	}
+	stale.Thresholds = effectiveStaleThresholds(stale.Thresholds)
+	return stale
+}
+
+// SetStaleConfig validates and saves the stale thresholds and notification setting
+func (a *App) SetStaleConfig(stale StaleConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if err := validateStaleThresholds(stale.Thresholds); err != nil {
+		return err
+	}
+	if err := a.configService.SetStaleConfig(stale); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"staleThresholds": stale.Thresholds,
+		"staleNotify":     stale.Notify,
+	})
+	return nil
+}
+
+// SLA API methods
+
+// GetSLAStatus returns every task's turnaround against the target for its
+// priority
+func (a *App) GetSLAStatus() ([]TaskSLA, error) {
+	return a.automation.slaStatus(time.Now())
+}
+
+// GetSLAConfig returns the SLA targets per priority
+func (a *App) GetSLAConfig() SLAConfig {
+	if a.configService == nil {
+		return SLAConfig{}
+	}
+	return a.configService.GetSLAConfig()
+}
+
+// SetSLAConfig validates and saves the SLA targets per priority
+func (a *App) SetSLAConfig(sla SLAConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if err := validateSLATargets(sla.Targets); err != nil {
+		return err
+	}
+	if err := a.configService.SetSLAConfig(sla); err != nil {
+		return err
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"slaTargets": sla.Targets,
+	})
+	return nil
+}
+
+// Confirmation API methods
+
+// NeedsConfirmation reports whether action hasn't been allowed yet in the
+// active repository, so the UI can ask before attempting it
+func (a *App) NeedsConfirmation(action string) bool {
+	return a.requireConfirmation(action) != nil
+}
+
+// ConfirmRepositoryAction records the user's go-ahead for action in the
+// active repository; it isn't asked for there again
+func (a *App) ConfirmRepositoryAction(action string) error {
+	if action != ConfirmAgentSpawn && action != ConfirmMerge {
+		return ValidationError("unknown action", nil).WithContext("action", action)
+	}
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if err := a.configService.ConfirmRepositoryAction(activeRepo.ID, action); err != nil {
+		return err
+	}
+	
+	a.logger.InfoWithFields("Repository action confirmed", map[string]interface{}{
+		"action":     action,
+		"repository": activeRepo.Path,
+	})
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"confirmed": action,
+	})
+	return nil
+}
+
+// requireConfirmation refuses action until the user has confirmed it for the
+// active repository, and asks the UI to prompt. Without a config there's
+// nowhere to remember the answer, so nothing is asked.
+func (a *App) requireConfirmation(action string) error {
+	if a.configService == nil {
+		return nil
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil || activeRepo.HasConfirmed(action) {
+		return nil
 	}
 	
-	return nil
+	a.emitEvent(RuntimeConfirmationRequired, ConfirmationRequest{
+		Action:     action,
+		Repository: activeRepo.Path,
+		Name:       activeRepo.Name,
+	})
+	return PermissionError(action+" has not been confirmed for this repository", nil).
+		WithContext("action", action).
+		WithContext("repository", activeRepo.Path)
 }
 
-// RejectTask deletes the task branch and marks task as done with NOT MERGED prefix
-func (a *App) RejectTask(taskID int) error {
-	// Get task info
-	tasks := a.taskService.GetTasks()
-	var task Task
-	found := false
+// Security API methods
+
+// GetSecurityPolicy returns the enforced security policy, with defaults
+// filled in for anything the user hasn't set
+func (a *App) GetSecurityPolicy() SecurityPolicy {
+	stored := SecurityPolicy{}
+	if a.configService != nil {
+		stored = a.configService.GetSecurityPolicy()
+	}
+	return EffectiveSecurityPolicy(stored)
+}
+
+// UpdateSecurityPolicy validates, saves and applies a security policy. A
+// policy that would lock out the active repository is refused.
+func (a *App) UpdateSecurityPolicy(policy SecurityPolicy) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	cleaned, err := ValidateSecurityPolicy(policy)
+	if err != nil {
+		return err
+	}
 	
-	for _, t := range tasks {
-		if t.ID == taskID {
-			if t.Status != StatusPendingReview {
-				return fmt.Errorf("task %d is not in pending_review status", taskID)
-			}
-			task = t
-			found = true
-			break
+	config := NewSecurityConfig(cleaned)
+	if repoPath := a.agentService.GetProjectRoot(); repoPath != "" {
+		if _, err := NewPathValidator(config, a.logger).ValidatePath(repoPath); err != nil {
+			return ValidationError("policy would block the active repository", err).WithContext("path", repoPath)
 		}
 	}
-	
-	if !found {
-		return fmt.Errorf("task with ID %d not found", taskID)
+	if err := a.configService.SetSecurityPolicy(cleaned); err != nil {
+		return err
 	}
 	
-	// Reject through agent service
-	if err := a.agentService.RejectTask(taskID, task.Title); err != nil {
+	a.applySecurityConfig(config)
+	a.logger.InfoWithFields("Security policy updated", map[string]interface{}{
+		"allowedPaths":    config.AllowedPaths,
+		"restrictedPaths": config.RestrictedPaths,
+		"maxPathDepth":    config.MaxPathDepth,
+	})
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"securityPolicy": true,
+	})
+	return nil
+}
+
+// Agent environment API methods
+
+// GetAgentEnvironment returns the variables the active repository injects
+// into its agents. Secret values stay in the keychain; only their names
+// are returned.
+func (a *App) GetAgentEnvironment() (AgentEnvironment, error) {
+	if a.configService == nil {
+		return AgentEnvironment{}, fmt.Errorf("configuration not initialized")
+	}
+	repo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return AgentEnvironment{}, err
+	}
+	env := AgentEnvironment{
+		Repository: repo.AgentEnv,
+		Tasks:      repo.TaskEnv,
+		Secrets:    repo.AgentSecrets,
+	}
+	if env.Repository == nil {
+		env.Repository = map[string]string{}
+	}
+	if env.Tasks == nil {
+		env.Tasks = map[int]map[string]string{}
+	}
+	if env.Secrets == nil {
+		env.Secrets = []string{}
+	}
+	return env, nil
+}
+
+// SetAgentEnv sets the variables injected into every agent in the active
+// repository or, with a task ID, only into that task's agents, over the
+// repository's. A value of "secret:NAME" is read from the keychain at
+// launch. An empty env clears them.
+func (a *App) SetAgentEnv(taskID int, env map[string]string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	repo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if taskID != 0 {
+		if _, ok := findTask(a.taskService.GetTasks(), taskID); !ok {
+			return NotFoundError("task not found", nil).WithContext("task_id", taskID)
+		}
+	}
+	if err := validateAgentEnv(env, repo.AgentSecrets); err != nil {
+		return err
+	}
+	if err := a.configService.SetRepositoryAgentEnv(repo.ID, taskID, env); err != nil {
 		return err
 	}
 	
-	// Update task with NOT MERGED prefix and done status
-	if task.Title != "" && !strings.HasPrefix(task.Title, "NOT MERGED: ") {
-		task.Title = "NOT MERGED: " + task.Title
+	// Only the names are journaled; values may be credentials
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	a.recordEvent(EventConfigChanged, taskID, map[string]interface{}{
+		"agentEnv": names,
+	})
+	return nil
+}
+
+// SetAgentSecret stores a secret agents in the active repository can be
+// given as "secret:NAME". The value goes to the OS keychain, never the
+// config; an empty value deletes the secret unless a variable still uses it.
+func (a *App) SetAgentSecret(name, value string) error {
+	if !agentEnvNamePattern.MatchString(name) {
+		return ValidationError("invalid secret name", nil).WithContext("name", name)
+	}
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	repo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	found := false
+	for _, stored := range repo.AgentSecrets {
+		if stored == name {
+			found = true
+		} else {
+			names = append(names, stored)
+		}
+	}
+	account := agentSecretAccount(repo.Path, name)
+	if value == "" {
+		if !found {
+			return NotFoundError("secret not found", nil).WithContext("secret", name)
+		}
+		inUse := agentSecretReferenced(repo.AgentEnv, name)
+		for _, env := range repo.TaskEnv {
+			inUse = inUse || agentSecretReferenced(env, name)
+		}
+		if inUse {
+			return ConflictError("secret is still used by an agent environment variable", nil).WithContext("secret", name)
+		}
+		if err := a.keys.Delete(account); err != nil {
+			return err
+		}
+	} else {
+		if err := a.keys.Set(account, []byte(value)); err != nil {
+			return err
+		}
+		names = append(names, name)
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		names = nil
+	}
+	if err := a.configService.SetRepositoryAgentSecrets(repo.ID, names); err != nil {
+		return err
 	}
-	task.Status = StatusDone
 	
-	if err := a.taskService.UpdateTask(task); err != nil {
-		return fmt.Errorf("failed to update task status after rejection: %v", err)
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"agentSecret": name,
+		"stored":      value != "",
+	})
+	return nil
+}
+
+// agentEnv resolves the variables to inject into an agent for taskID
+func (a *App) agentEnv(taskID int) (agentEnv, error) {
+	if a.configService == nil {
+		return agentEnv{}, nil
+	}
+	repo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		// Without a repository there is nothing configured to inject
+		return agentEnv{}, nil
+	}
+	return resolveAgentEnv(a.keys, repo.Path, repo.AgentEnv, repo.TaskEnv[taskID])
+}
+
+// Sync API methods
+
+// GetSyncConfig returns the active repository's sync remote, or nil if it
+// isn't synced
+func (a *App) GetSyncConfig() (*SyncConfig, error) {
+	if a.configService == nil {
+		return nil, fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return nil, err
+	}
+	return activeRepo.Sync, nil
+}
+
+// SetSyncConfig sets the active repository's sync remote; nil turns sync
+// off. A non-empty secret is stored in the OS keychain, never in the config.
+func (a *App) SetSyncConfig(cfg *SyncConfig, secret string) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	activeRepo, err := a.configService.GetActiveRepository()
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		if _, err := newSyncRemote(*cfg, secret); err != nil {
+			return err
+		}
+	}
+	if secret != "" {
+		if err := a.keys.Set(syncSecretAccount(activeRepo.Path), []byte(secret)); err != nil {
+			return err
+		}
+	}
+	if err := a.configService.SetRepositorySync(activeRepo.ID, cfg); err != nil {
+		return err
 	}
 	
+	provider := ""
+	if cfg != nil {
+		provider = cfg.Provider
+	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"syncProvider": provider,
+	})
 	return nil
 }
 
-// Plan-related API methods
+// syncRemote connects to the active repository's sync remote. The secret
+// comes from TASKWRAPPER_SYNC_SECRET when set, otherwise from the keychain.
+func (a *App) syncRemote() (SyncRemote, string, error) {
+	cfg, err := a.GetSyncConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg == nil {
+		return nil, "", ValidationError("sync is not configured for this repository", nil)
+	}
+	repoPath := a.agentService.GetProjectRoot()
+	secret := os.Getenv("TASKWRAPPER_SYNC_SECRET")
+	if secret == "" {
+		if stored, err := a.keys.Get(syncSecretAccount(repoPath)); err == nil {
+			secret = string(stored)
+		}
+	}
+	remote, err := newSyncRemote(*cfg, secret)
+	return remote, repoPath, err
+}
 
-// LoadPlan loads the plan.md file and returns its content
-func (a *App) LoadPlan() (string, error) {
-	activeRepoPath, err := a.getActiveRepositoryPath()
+// PushPlan uploads the plan directory to the sync remote. It fails with a
+// conflict when another machine pushed since the last sync, unless force is set.
+func (a *App) PushPlan(force bool) (SyncResult, error) {
+	remote, repoPath, err := a.syncRemote()
 	if err != nil {
-		return "", err
+		return SyncResult{}, err
+	}
+	if err := a.taskService.Flush(); err != nil {
+		return SyncResult{}, err
+	}
+	
+	var result SyncResult
+	err = a.runJob(JobKindSync, a.messages().T(MsgJobPush), func(job *JobHandle) error {
+		var pushErr error
+		result, pushErr = a.syncService.Push(job.Context(), repoPath, remote, force)
+		return pushErr
+	})
+	if err != nil {
+		a.logger.Error("Failed to push plan directory", err)
+		return result, err
+	}
+	a.recordEvent(EventPlanPushed, 0, map[string]interface{}{
+		"revision": result.Revision,
+		"files":    len(result.Pushed),
+		"force":    force,
+	})
+	return result, nil
+}
+
+// PullPlan downloads plan files changed on the sync remote and reloads the
+// tasks. Files changed on both sides are returned as conflicts and left
+// alone, unless force is set.
+func (a *App) PullPlan(force bool) (SyncResult, error) {
+	remote, repoPath, err := a.syncRemote()
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if err := a.taskService.Flush(); err != nil {
+		return SyncResult{}, err
+	}
+	
+	var result SyncResult
+	err = a.runJob(JobKindSync, a.messages().T(MsgJobPull), func(job *JobHandle) error {
+		var pullErr error
+		result, pullErr = a.syncService.Pull(job.Context(), repoPath, remote, force)
+		return pullErr
+	})
+	if err != nil {
+		a.logger.Error("Failed to pull plan directory", err)
+		return result, err
 	}
 	
-	planFile := filepath.Join(activeRepoPath, "plan", "plan.md")
-	a.logger.InfoWithFields("Loading plan", map[string]interface{}{
-		"plan_file": planFile,
+	// Pulled files may be encrypted, or task.json may have been replaced
+	a.useEncryption(repoPath)
+	a.taskService.SetTaskFile(a.taskService.GetTaskFile())
+	if _, err := a.taskService.LoadTasks(); err != nil {
+		return result, fmt.Errorf("failed to load pulled tasks: %w", err)
+	}
+	a.recordEvent(EventPlanPulled, 0, map[string]interface{}{
+		"revision":  result.Revision,
+		"files":     len(result.Pulled),
+		"conflicts": len(result.Conflicts),
+		"force":     force,
 	})
+	return result, nil
+}
 
-	content, err := readFileContent(planFile)
+// Logging API methods
+
+// SetLogLevel changes the log threshold at runtime and persists it
+func (a *App) SetLogLevel(level string) error {
+	parsed, err := ParseLogLevel(level)
 	if err != nil {
-		a.logger.Error("Failed to load plan.md", err)
-		return "", fmt.Errorf("failed to read plan.md: %w", err)
+		return ValidationError("invalid log level", err)
+	}
+	
+	if leveled, ok := a.logger.(interface{ SetLevel(LogLevel) }); ok {
+		leveled.SetLevel(parsed)
+	}
+	
+	a.logger.InfoWithFields("Log level changed", map[string]interface{}{
+		"level": strings.ToLower(parsed.String()),
+	})
+	
+	if a.configService == nil {
+		return nil
+	}
+	if err := a.configService.SetLogLevel(strings.ToLower(parsed.String())); err != nil {
+		return err
 	}
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"logLevel": strings.ToLower(parsed.String()),
+	})
+	return nil
+}
 
-	a.logger.Info("Plan loaded successfully")
-	return content, nil
+// GetLoggingConfig returns the log level, format, rotation, retention,
+// compression and location settings
+func (a *App) GetLoggingConfig() LoggingConfig {
+	if a.configService == nil {
+		return LoggingConfig{}
+	}
+	return a.configService.GetLoggingConfig()
 }
 
-// SavePlan saves content to the plan.md file
-func (a *App) SavePlan(content string) error {
-	activeRepoPath, err := a.getActiveRepositoryPath()
+// SetLoggingConfig validates, saves and applies the logging settings. A new
+// Dir moves the app's existing log files there.
+func (a *App) SetLoggingConfig(logging LoggingConfig) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	level, err := ParseLogLevel(logging.Level)
+	if err != nil {
+		return ValidationError("invalid log level", err)
+	}
+	logging.Level = strings.ToLower(level.String())
+	logging.Format = strings.ToLower(strings.TrimSpace(logging.Format))
+	if logging.Format != "" && logging.Format != "text" && logging.Format != "json" {
+		return ValidationError("log format must be text or json", nil).WithContext("format", logging.Format)
+	}
+	if logging.MaxSizeMB < 0 || logging.MaxBackups < 0 || logging.RetentionDays < 0 {
+		return ValidationError("log size, backup and retention limits can't be negative", nil)
+	}
+	logging.Dir = strings.TrimSpace(logging.Dir)
+	if logging.Dir != "" && !filepath.IsAbs(logging.Dir) {
+		return ValidationError("log directory must be an absolute path", nil).WithContext("dir", logging.Dir)
+	}
+	repo, err := a.configService.GetActiveRepository()
 	if err != nil {
 		return err
 	}
 	
-	planFile := filepath.Join(activeRepoPath, "plan", "plan.md")
-	a.logger.InfoWithFields("Saving plan", map[string]interface{}{
-		"plan_file": planFile,
+	if err := a.configService.SetLoggingConfig(logging); err != nil {
+		return err
+	}
+	if configurable, ok := a.logger.(interface{ Configure(LoggingConfig) error }); ok {
+		configurable.Configure(logging)
+	}
+	if logging.MaxSizeMB > 0 {
+		if limited, ok := a.agentService.(interface{ SetLogLimit(int64) }); ok {
+			limited.SetLogLimit(int64(logging.MaxSizeMB) * 1024 * 1024)
+		}
+	}
+	if retained, ok := a.agentService.(interface{ SetLogRetention(int) }); ok {
+		retained.SetLogRetention(logging.RetentionDays)
+	}
+	if relocatable, ok := a.logger.(interface {
+		Dir() string
+		SetDir(dir string)
+	}); ok {
+		previous, dir := relocatable.Dir(), repositoryLogDir(logging.Dir, *repo)
+		relocatable.SetDir(dir)
+		if moved, err := migrateFiles(previous, dir, "universal_logs-*"); err != nil {
+			a.logger.Error("Failed to move log files", err)
+		} else if moved > 0 {
+			a.logger.InfoWithFields("Moved log files", map[string]interface{}{
+				"from":  previous,
+				"to":    dir,
+				"count": moved,
+			})
+		}
+	}
+	
+	a.recordEvent(EventConfigChanged, 0, map[string]interface{}{
+		"logLevel":         logging.Level,
+		"logRetentionDays": logging.RetentionDays,
+		"logCompress":      logging.Compress,
+		"logDirRelocated":  logging.Dir != "",
 	})
+	return nil
+}
 
-	// Create backup of plan.md
-	if err := createFileBackup(planFile); err != nil {
-		a.logger.Error("Failed to create backup of plan.md", err)
-		// Continue with save even if backup fails
+// GetLogUsage reports how much disk space the repository's logs take, by
+// kind, including the app's logs if they were moved out of the repository
+func (a *App) GetLogUsage() (LogUsage, error) {
+	dir := getLogDirectory(a.agentService.GetProjectRoot())
+	appDir := dir
+	if located, ok := a.logger.(interface{ Dir() string }); ok {
+		appDir = located.Dir()
+	}
+	usage, err := collectLogUsage(dir, appDir)
+	if err != nil {
+		return usage, fmt.Errorf("failed to measure logs: %v", err)
 	}
+	return usage, nil
+}
 
-	// Write the new content
-	if err := writeFileContent(planFile, content); err != nil {
-		a.logger.Error("Failed to save plan.md", err)
-		return fmt.Errorf("failed to write plan.md: %w", err)
+// PruneLogs applies log retention and compression now instead of at the
+// next hourly sweep, and returns the usage afterwards
+func (a *App) PruneLogs() (LogUsage, error) {
+	if prunable, ok := a.logger.(interface{ Prune() }); ok {
+		prunable.Prune()
 	}
+	if _, err := a.agentService.PruneAgentLogs(); err != nil {
+		a.logger.Error("Failed to prune agent logs", err)
+	}
+	return a.GetLogUsage()
+}
+
+// Job-related API methods
+
+// runJob runs fn as a tracked job whose progress is streamed as job:progress events
+func (a *App) runJob(kind, title string, fn func(job *JobHandle) error) error {
+	return a.jobService.Run(a.ctx, kind, title, fn)
+}
+
+// GetJobs returns running and recently finished long-running operations
+func (a *App) GetJobs() []Job {
+	return a.jobService.GetJobs()
+}
+
+// CancelJob requests cancellation of a running job
+func (a *App) CancelJob(id string) error {
+	return a.jobService.Cancel(id)
+}
+
+// Telemetry API methods
+
+// GetTelemetry returns the locally aggregated usage counters
+func (a *App) GetTelemetry() TelemetrySnapshot {
+	return a.telemetry.Snapshot()
+}
 
-	a.logger.Info("Plan saved successfully")
+// SetTelemetryEnabled opts in to or out of local usage counting
+func (a *App) SetTelemetryEnabled(enabled bool) error {
+	if a.configService == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	if err := a.configService.SetTelemetryEnabled(enabled); err != nil {
+		return err
+	}
+	a.telemetry.SetEnabled(enabled)
 	return nil
 }
 
-// Terminal-related API methods
+// ExportTelemetry writes the usage counters to a JSON file in the temp
+// directory, for users to attach when asked, and returns its path
+func (a *App) ExportTelemetry() (string, error) {
+	name := fmt.Sprintf("taskwrapper-telemetry-%s.json", time.Now().Format("20060102-150405"))
+	dest := filepath.Join(os.TempDir(), name)
+	if err := a.telemetry.Export(dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
 
-// StartTerminalSession creates a new terminal session and returns its ID
-func (a *App) StartTerminalSession() string {
-	return a.terminalService.StartTerminalSession()
+// ResetTelemetry clears the usage counters
+func (a *App) ResetTelemetry() error {
+	return a.telemetry.Reset()
 }
 
-// Agent-related API methods
+// GetPerformanceStats returns latency stats for task, plan, git and agent operations
+func (a *App) GetPerformanceStats() []OperationStats {
+	return a.perf.Stats()
+}
 
-// GetAgentStatus returns the current status of all subagents
-func (a *App) GetAgentStatus() (AgentStatusInfo, error) {
-	return a.agentService.GetAgentStatus()
+// Diagnostics API methods
+
+// GetHealth checks each subsystem and returns the combined status
+func (a *App) GetHealth() HealthReport {
+	return a.healthService.Check(HealthSources{
+		ConfigService:   a.configService,
+		TaskFile:        a.taskService.GetTaskFile(),
+		RepoPath:        a.agentService.GetProjectRoot(),
+		WebSocketStatus: a.terminalService.WebSocketStatus,
+		Claude: func() ClaudeCapabilities {
+			return a.agentService.ClaudeCapabilities(false)
+		},
+	})
+}
+
+// GetClaudeCapabilities returns the installed claude CLI's version and the
+// flags it supports; refresh probes again instead of using the cached result
+func (a *App) GetClaudeCapabilities(refresh bool) ClaudeCapabilities {
+	return a.agentService.ClaudeCapabilities(refresh)
+}
+
+// requireClaude refuses to launch an agent when the claude CLI is missing
+// or too old for the flags agent_spawn.sh passes
+func (a *App) requireClaude() error {
+	caps := a.agentService.ClaudeCapabilities(false)
+	if !caps.Installed {
+		return NotFoundError("claude CLI not found; install it with `npm install -g @anthropic-ai/claude-code`", nil).
+			WithContext("error", caps.Error)
+	}
+	if missing := caps.Missing(claudeRequiredFlags); len(missing) > 0 {
+		return ConflictError(fmt.Sprintf("%s is too old to launch agents; %s", caps.label(), claudeUpgradeHint), nil).
+			WithContext("missing", missing)
+	}
+	return nil
+}
+
+// ExportDiagnostics writes a zip of recent logs, redacted config, agent state
+// and runs, and tool versions to the temp directory and returns its path
+func (a *App) ExportDiagnostics() (string, error) {
+	a.telemetry.Increment("feature.diagnostics")
+	sources := DiagnosticsSources{}
+	if repoPath, err := a.getActiveRepositoryPath(); err == nil {
+		sources.RepoPath = repoPath
+		sources.LogDir = getLogDirectory(repoPath)
+	}
+	if located, ok := a.logger.(interface{ Dir() string }); ok {
+		sources.AppLogDir = located.Dir()
+	}
+	if a.configService != nil {
+		if config, err := a.configService.GetConfig(); err == nil {
+			sources.Config = config
+		}
+	}
+	if status, err := a.GetAgentStatus(); err == nil {
+		sources.AgentStatus = &status
+	}
+	if runs, err := a.agentRunHistory(time.Now().Add(-diagnosticsLogAge), a.taskService.GetTasks()); err == nil {
+		sources.AgentRuns = runs
+	}
+	sources.Performance = a.perf.Stats()
+	
+	name := fmt.Sprintf("taskwrapper-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+	dest := filepath.Join(os.TempDir(), name)
+	if err := a.diagnostics.Export(dest, sources); err != nil {
+		a.logger.Error("Failed to export diagnostics", err)
+		return "", err
+	}
+	return dest, nil
 }
 
 // Configuration API methods
@@ -521,7 +5536,16 @@ func (a *App) AddRepository(name, path string) (*Repository, error) {
 	if a.configService == nil {
 		return nil, fmt.Errorf("configuration not initialized")
 	}
-	return a.configService.AddRepository(name, path)
+	repo, err := a.configService.AddRepository(name, path)
+	if err != nil {
+		return nil, err
+	}
+	a.recordEvent(EventRepoAdded, 0, map[string]interface{}{
+		"id":   repo.ID,
+		"name": repo.Name,
+		"path": repo.Path,
+	})
+	return repo, nil
 }
 
 // RemoveRepository removes a repository from the configuration
@@ -529,7 +5553,13 @@ func (a *App) RemoveRepository(id string) error {
 	if a.configService == nil {
 		return fmt.Errorf("configuration not initialized")
 	}
-	return a.configService.RemoveRepository(id)
+	if err := a.configService.RemoveRepository(id); err != nil {
+		return err
+	}
+	a.recordEvent(EventRepoRemoved, 0, map[string]interface{}{
+		"id": id,
+	})
+	return nil
 }
 
 // SetActiveRepository switches the active repository
@@ -552,9 +5582,34 @@ func (a *App) SetActiveRepository(id string) error {
 	// Update task service with new task file path
 	taskFile := filepath.Join(activeRepo.Path, "plan", "task.json")
 	a.taskService.SetTaskFile(taskFile)
+	a.planService.SetPlanFile(filepath.Join(activeRepo.Path, "plan", "plan.md"))
+	a.milestones.SetMilestoneFile(filepath.Join(activeRepo.Path, "plan", "milestones.json"))
+	a.releases.SetReleaseFile(filepath.Join(activeRepo.Path, "plan", "releases.json"))
+	a.reviewComments.SetReviewCommentFile(filepath.Join(activeRepo.Path, "plan", "review_comments.json"))
+	a.backupDir = ""
+	a.useBackupDir(activeRepo.Path, repositoryBackupDir(a.configService.GetBackupConfig().Dir, *activeRepo))
+	a.useEncryption(activeRepo.Path)
 	
-	// Update agent service with new project root
+	// Update agent service with new project root, its pinned scripts, its
+	// mainline branch, how that is updated, where and how approvals merge,
+	// and how its agents are spawned
 	a.agentService.SetProjectRoot(activeRepo.Path)
+	a.applyScriptChecksums(activeRepo.ScriptChecksums)
+	a.applyMainlineSync(activeRepo.MainlineSync)
+	a.applyMainlineBranch(activeRepo.MainlineBranch)
+	a.applyApprovalMode(activeRepo.ApprovalMode)
+	a.applySigning(activeRepo.Signing)
+	a.applySpawner(SpawnerSettings{Mode: activeRepo.Spawner, MaxAgents: activeRepo.MaxAgents})
+	
+	// Journal into the new repository from here on
+	if a.journalService != nil {
+		a.journalService.SetRepository(getLogDirectory(activeRepo.Path), activeRepo.Path)
+	}
+	a.auditService.SetRepository(getLogDirectory(activeRepo.Path), activeRepo.Path)
+	a.commandHistory.SetRepository(getLogDirectory(activeRepo.Path), activeRepo.Path)
+	if relocatable, ok := a.logger.(interface{ SetDir(dir string) }); ok {
+		relocatable.SetDir(repositoryLogDir(a.configService.GetLoggingConfig().Dir, *activeRepo))
+	}
 	
 	// Reload tasks from new repository
 	if _, err := a.taskService.LoadTasks(); err != nil {
@@ -566,6 +5621,10 @@ func (a *App) SetActiveRepository(id string) error {
 		"name": activeRepo.Name,
 		"path": activeRepo.Path,
 	})
+	a.recordEvent(EventRepoSwitched, 0, map[string]interface{}{
+		"id":   id,
+		"name": activeRepo.Name,
+	})
 	
 	return nil
 }
@@ -583,7 +5642,14 @@ func (a *App) FindRepositories(searchPath string) ([]Repository, error) {
 	if a.configService == nil {
 		return nil, fmt.Errorf("configuration not initialized")
 	}
-	return a.configService.FindRepositories(searchPath)
+	
+	var repos []Repository
+	err := a.runJob(JobKindRepoScan, a.messages().T(MsgJobRepoScan, searchPath), func(job *JobHandle) error {
+		var err error
+		repos, err = a.configService.FindRepositoriesContext(job.Context(), searchPath)
+		return err
+	})
+	return repos, err
 }
 
 // OpenDirectoryDialog opens a directory selection dialog
@@ -611,6 +5677,10 @@ func (a *App) OpenDirectoryDialog() (string, error) {
 
 // WebSocket handling (delegated to terminal service)
 func (a *App) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if err := a.requireExecution("opening terminals"); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	a.terminalService.HandleWebSocket(w, r)
 }
 
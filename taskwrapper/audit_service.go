@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditBranchMerged       = "branch.merged"
+	AuditBranchForceDeleted = "branch.force_deleted"
+	AuditAgentSpawned       = "agent.spawned"
+	AuditTerminalCreated    = "terminal.created"
+	AuditTerminalBroadcast  = "terminal.broadcast"
+	AuditFileUploaded       = "file.uploaded"
+	AuditFileDownloaded     = "file.downloaded"
+	AuditCommandExecuted    = "command.executed"
+	AuditWorktreeRemoved    = "worktree.removed"
+	AuditWorktreeAdded      = "worktree.added"
+)
+
+// Audit outcomes
+const (
+	AuditOutcomeSucceeded = "succeeded"
+	AuditOutcomeFailed    = "failed"
+)
+
+const (
+	// auditFileName is the audit log inside a repository's logs directory
+	auditFileName = "audit.jsonl"
+	// auditHeadFileName records the newest entry, so truncating the log
+	// is detected as well as editing it
+	auditHeadFileName = "audit.head"
+)
+
+// AuditEntry is one privileged operation. Each entry's hash covers its
+// contents and the previous entry's hash, so editing, removing or reordering
+// entries breaks the chain.
+type AuditEntry struct {
+	Seq      int64                  `json:"seq"`
+	Time     time.Time              `json:"time"`
+	Action   string                 `json:"action"`
+	Actor    string                 `json:"actor"`
+	Repo     string                 `json:"repo,omitempty"`
+	TaskID   int                    `json:"taskId,omitempty"`
+	Outcome  string                 `json:"outcome"`
+	Error    string                 `json:"error,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	PrevHash string                 `json:"prevHash"`
+	Hash     string                 `json:"hash"`
+}
+
+// AuditQuery filters audit entries; zero values match everything
+type AuditQuery struct {
+	Actions []string  `json:"actions,omitempty"`
+	TaskID  int       `json:"taskId,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+	Until   time.Time `json:"until,omitempty"`
+	Limit   int       `json:"limit,omitempty"` // keep only the newest N matches
+}
+
+// AuditVerification is the result of checking the hash chain
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int    `json:"entries"`
+	BrokenAt int64  `json:"brokenAt,omitempty"` // seq of the first entry that fails
+	Reason   string `json:"reason,omitempty"`
+}
+
+// AuditService appends privileged operations to a hash-chained log
+type AuditService struct {
+	mu       sync.Mutex
+	logDir   string
+	repo     string
+	actor    string
+	logger   Logger
+	loaded   bool // lastSeq and lastHash reflect the file
+	lastSeq  int64
+	lastHash string
+}
+
+// NewAuditService creates an audit log writing to logDir/audit.jsonl
+func NewAuditService(logDir, repo string, logger Logger) *AuditService {
+	return &AuditService{
+		logDir: logDir,
+		repo:   repo,
+		actor:  currentActor(),
+		logger: logger,
+	}
+}
+
+// currentActor identifies who is running TaskWrapper, as user@host
+func currentActor() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+}
+
+// SetRepository points the audit log at another repository's log directory
+func (as *AuditService) SetRepository(logDir, repo string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.logDir = logDir
+	as.repo = repo
+	as.loaded = false
+}
+
+// Record appends an entry for action. err marks the operation as failed.
+// Write failures are logged, not returned, like the journal's.
+func (as *AuditService) Record(action string, taskID int, details map[string]interface{}, err error) AuditEntry {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		Action:  action,
+		Actor:   as.actor,
+		Repo:    as.repo,
+		TaskID:  taskID,
+		Outcome: AuditOutcomeSucceeded,
+		Details: details,
+	}
+	if err != nil {
+		entry.Outcome = AuditOutcomeFailed
+		entry.Error = err.Error()
+	}
+
+	if appendErr := as.append(&entry); appendErr != nil {
+		as.logger.ErrorWithFields("Failed to write audit entry", appendErr, map[string]interface{}{
+			"action": action,
+		})
+	}
+	return entry
+}
+
+// append chains entry onto the log (must be called with lock held)
+func (as *AuditService) append(entry *AuditEntry) error {
+	if !as.loaded {
+		if err := as.loadHead(); err != nil {
+			return err
+		}
+	}
+	entry.Seq = as.lastSeq + 1
+	entry.PrevHash = as.lastHash
+	hash, err := auditEntryHash(*entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %v", err)
+	}
+	if err := os.MkdirAll(as.logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+	f, err := os.OpenFile(as.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append audit entry: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %v", err)
+	}
+
+	as.lastSeq = entry.Seq
+	as.lastHash = entry.Hash
+	head := fmt.Sprintf("%d %s\n", entry.Seq, entry.Hash)
+	return os.WriteFile(as.headPath(), []byte(head), 0600)
+}
+
+// loadHead finds the newest entry to chain from (must be called with lock held)
+func (as *AuditService) loadHead() error {
+	entries, err := as.readAll()
+	if err != nil {
+		return err
+	}
+	as.lastSeq, as.lastHash = 0, ""
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		as.lastSeq, as.lastHash = last.Seq, last.Hash
+	}
+	as.loaded = true
+	return nil
+}
+
+// auditEntryHash returns the SHA-256 over the entry with its hash cleared.
+// PrevHash is part of the encoding, which is what chains the entries.
+func auditEntryHash(entry AuditEntry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readAll decodes every entry in file order (must be called with lock held).
+// Unlike the journal, unreadable lines are not skipped: they are tampering
+// or damage, and Verify reports them.
+func (as *AuditService) readAll() ([]AuditEntry, error) {
+	f, err := os.Open(as.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []AuditEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer f.Close()
+
+	entries := []AuditEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return entries, CorruptedError("audit log has an unreadable entry", err).WithContext("line", line)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return entries, nil
+}
+
+// Query returns matching entries, oldest first
+func (as *AuditService) Query(query AuditQuery) ([]AuditEntry, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	all, err := as.readAll()
+	if err != nil {
+		return nil, err
+	}
+	actions := make(map[string]bool, len(query.Actions))
+	for _, action := range query.Actions {
+		actions[action] = true
+	}
+
+	entries := []AuditEntry{}
+	for _, entry := range all {
+		if len(actions) > 0 && !actions[entry.Action] {
+			continue
+		}
+		if query.TaskID != 0 && entry.TaskID != query.TaskID {
+			continue
+		}
+		if !query.Since.IsZero() && entry.Time.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && entry.Time.After(query.Until) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+// Verify walks the hash chain and checks it ends at the recorded head
+func (as *AuditService) Verify() (AuditVerification, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	entries, err := as.readAll()
+	result := AuditVerification{Entries: len(entries)}
+	if err != nil {
+		if appErr, ok := err.(*AppError); ok && appErr.Type == ErrorTypeCorrupted {
+			result.BrokenAt = int64(len(entries) + 1)
+			result.Reason = appErr.Message
+			return result, nil
+		}
+		return result, err
+	}
+
+	prevHash := ""
+	for i, entry := range entries {
+		reason := ""
+		hash, err := auditEntryHash(entry)
+		switch {
+		case err != nil:
+			return result, err
+		case entry.Seq != int64(i+1):
+			reason = "entry is out of sequence"
+		case entry.PrevHash != prevHash:
+			reason = "entry does not follow the previous one"
+		case entry.Hash != hash:
+			reason = "entry was modified"
+		}
+		if reason != "" {
+			result.BrokenAt = entry.Seq
+			result.Reason = reason
+			return result, nil
+		}
+		prevHash = entry.Hash
+	}
+
+	// Compare the chain's end with the head file to catch truncation
+	if raw, err := os.ReadFile(as.headPath()); err == nil {
+		fields := strings.Fields(string(raw))
+		if len(fields) == 2 {
+			seq, _ := strconv.ParseInt(fields[0], 10, 64)
+			if seq != int64(len(entries)) || fields[1] != prevHash {
+				result.BrokenAt = int64(len(entries) + 1)
+				result.Reason = fmt.Sprintf("log ends before recorded entry %d", seq)
+				return result, nil
+			}
+		}
+	} else if len(entries) > 0 {
+		result.Reason = "head file missing; truncation can't be ruled out"
+	}
+
+	result.Valid = true
+	return result, nil
+}
+
+// path returns the audit log location (must be called with lock held)
+func (as *AuditService) path() string {
+	return filepath.Join(as.logDir, auditFileName)
+}
+
+// headPath returns the head file location (must be called with lock held)
+func (as *AuditService) headPath() string {
+	return filepath.Join(as.logDir, auditHeadFileName)
+}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// TriggerTaskStale fires for tasks left in one status for too long; every
+// other trigger names a journal event type
+const TriggerTaskStale = "task.stale"
+
+// Rule action types
+const (
+	ActionWebhook     = "webhook"
+	ActionNotify      = "notify"
+	ActionSetPriority = "set_priority"
+)
+
+const (
+	// automationCheckInterval is how often stale-task rules are evaluated
+	automationCheckInterval = 5 * time.Minute
+	// automationCommandTimeout bounds a command condition such as a test run
+	automationCommandTimeout = 10 * time.Minute
+	// automationWebhookTimeout bounds a webhook delivery
+	automationWebhookTimeout = 10 * time.Second
+)
+
+// AutomationNotice is shown in the UI by a notify action
+type AutomationNotice struct {
+	Rule    string `json:"rule"`
+	TaskID  int    `json:"taskId"`
+	Message string `json:"message"`
+}
+
+// ruleContext is what a rule's conditions and message templates see
+type ruleContext struct {
+	Rule    string
+	Task    Task
+	Trigger JournalEntry
+
+	// reviewers looks up the task's suggested reviewers; only run when a
+	// template asks for {{.Reviewers}}
+	reviewers func() string
+}
+
+// Reviewers lists the people who recently worked on the files the task's
+// branch changes, for templates such as "please review: {{.Reviewers}}"
+func (ctx ruleContext) Reviewers() string {
+	if ctx.reviewers == nil {
+		return ""
+	}
+	return ctx.reviewers()
+}
+
+// AutomationService runs board automation rules. Journal events are handed
+// to it as they're recorded; stale-task rules are checked on a timer.
+// Entries recorded by a rule's own actions carry the rule's name and never
+// trigger rules, so rules can't loop.
+type AutomationService struct {
+	mu     sync.RWMutex
+	rules  []AutomationRule
+	app    *App
+	runner ProcessRunner
+	client *http.Client
+	logger Logger
+	now    func() time.Time
+	wg     sync.WaitGroup
+	stop   chan struct{}
+	once   sync.Once
+
+	// notified holds the stale tasks already announced, by task and arrival
+	notified map[string]bool
+}
+
+// NewAutomationService creates a rules engine acting on app
+func NewAutomationService(app *App, rules []AutomationRule, logger Logger) *AutomationService {
+	return &AutomationService{
+		rules:  rules,
+		app:    app,
+		runner: NewExecRunner(),
+		client: &http.Client{Timeout: automationWebhookTimeout},
+		logger: logger,
+		now:    time.Now,
+		stop:   make(chan struct{}),
+
+		notified: map[string]bool{},
+	}
+}
+
+// ValidateAutomationRules checks rules before they are saved
+func ValidateAutomationRules(rules []AutomationRule) error {
+	names := map[string]bool{}
+	for i, rule := range rules {
+		invalid := func(message string) error {
+			return ValidationError(message, nil).WithContext("rule", rule.Name).WithContext("index", i)
+		}
+		if strings.TrimSpace(rule.Name) == "" {
+			return invalid("automation rule needs a name")
+		}
+		if names[rule.Name] {
+			return invalid("automation rule names must be unique")
+		}
+		names[rule.Name] = true
+
+		if rule.Trigger.Event == "" {
+			return invalid("automation rule needs a trigger event")
+		}
+		if rule.Trigger.Status != "" && !TaskStatus(rule.Trigger.Status).Valid() {
+			return invalid("invalid trigger status " + rule.Trigger.Status)
+		}
+		if rule.Trigger.Event == TriggerTaskStale {
+			if rule.Trigger.Status == "" {
+				return invalid("task.stale trigger needs a status")
+			}
+			if _, err := parseRuleDuration(rule.Trigger.After); err != nil {
+				return invalid("task.stale trigger needs an after duration such as 72h or 3d")
+			}
+		}
+
+		for _, cond := range rule.Conditions {
+			if (cond.Field == "") == (cond.Command == "") {
+				return invalid("a condition needs either a field or a command")
+			}
+		}
+		if len(rule.Actions) == 0 {
+			return invalid("automation rule needs at least one action")
+		}
+		for _, action := range rule.Actions {
+			switch action.Type {
+			case ActionWebhook:
+				if !strings.HasPrefix(action.URL, "https://") && !strings.HasPrefix(action.URL, "http://") {
+					return invalid("webhook action needs an http(s) URL")
+				}
+			case ActionNotify:
+			case ActionSetPriority:
+				if !TaskPriority(action.Priority).Valid() {
+					return invalid("invalid priority " + action.Priority)
+				}
+			default:
+				return invalid("unknown action type " + action.Type)
+			}
+			if _, err := template.New(rule.Name).Parse(action.Message); err != nil {
+				return ValidationError("invalid action message template", err).WithContext("rule", rule.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// parseRuleDuration parses a Go duration, also accepting whole days ("3d")
+func parseRuleDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// SetRules replaces the rules
+func (as *AutomationService) SetRules(rules []AutomationRule) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.rules = rules
+}
+
+// Rules returns the current rules
+func (as *AutomationService) Rules() []AutomationRule {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return append([]AutomationRule{}, as.rules...)
+}
+
+// Dispatch evaluates rules for a journal entry in the background
+func (as *AutomationService) Dispatch(entry JournalEntry) {
+	as.wg.Add(1)
+	as.app.errorHandler.Go("automation", func() {
+		defer as.wg.Done()
+		as.HandleEvent(entry)
+	})
+}
+
+// Wait blocks until dispatched events have been handled
+func (as *AutomationService) Wait() {
+	as.wg.Wait()
+}
+
+// Start checks stale-task rules, stale tasks to notify about and SLA
+// breaches, tops up the warm worktree pool, collects agent results and prunes
+// old agent logs, every interval until Stop
+func (as *AutomationService) Start(interval time.Duration) {
+	as.app.errorHandler.Go("automation timer", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				as.CheckStale()
+				as.NotifyStale()
+				as.CheckSLA()
+				as.keepWorktreesWarm()
+				if _, err := as.app.CollectAgentResults(); err != nil {
+					as.logger.Error("Failed to collect agent results", err)
+				}
+				if _, err := as.app.agentService.PruneAgentLogs(); err != nil {
+					as.logger.Error("Failed to prune agent logs", err)
+				}
+			case <-as.stop:
+				return
+			}
+		}
+	})
+}
+
+// keepWorktreesWarm runs WarmWorktrees when a warm pool is configured,
+// logging rather than returning failures; it's called from the background
+func (as *AutomationService) keepWorktreesWarm() {
+	if as.app.GetWorktreeConfig().WarmPool == 0 || as.app.IsSafeMode() {
+		return
+	}
+	if _, err := as.app.WarmWorktrees(); err != nil {
+		as.logger.Error("Failed to warm agent worktrees", err)
+	}
+}
+
+// Stop ends the stale-task timer and waits for running rules to finish
+func (as *AutomationService) Stop() {
+	as.once.Do(func() { close(as.stop) })
+	as.Wait()
+}
+
+// HandleEvent runs the rules triggered by a journal entry
+func (as *AutomationService) HandleEvent(entry JournalEntry) {
+	if entry.Type == EventAutomationFired || entry.Data["rule"] != nil {
+		return
+	}
+	var task Task
+	found := false
+	if entry.TaskID != 0 {
+		task, found = as.findTask(entry.TaskID)
+	}
+
+	for _, rule := range as.Rules() {
+		if rule.Disabled || rule.Trigger.Event != entry.Type {
+			continue
+		}
+		if rule.Trigger.Status != "" && fmt.Sprint(entry.Data["to"]) != rule.Trigger.Status {
+			continue
+		}
+		if entry.TaskID != 0 && !found {
+			continue
+		}
+		as.run(rule, ruleContext{Rule: rule.Name, Task: task, Trigger: entry, reviewers: as.reviewersOf(task.ID)}, nil)
+	}
+}
+
+// CheckStale runs task.stale rules for tasks that have sat in a status past
+// the rule's limit. Each rule fires once per task per stay in that status.
+func (as *AutomationService) CheckStale() {
+	var stale []AutomationRule
+	for _, rule := range as.Rules() {
+		if !rule.Disabled && rule.Trigger.Event == TriggerTaskStale {
+			stale = append(stale, rule)
+		}
+	}
+	if len(stale) == 0 || as.app.journalService == nil {
+		return
+	}
+
+	history, err := as.app.journalService.Query(JournalQuery{
+		Types: []string{EventTaskCreated, EventTaskMoved, EventAutomationFired},
+	})
+	if err != nil {
+		as.logger.Error("Failed to read journal for automation", err)
+		return
+	}
+	entered := taskArrivals(history)
+	fired := map[string]bool{}
+	for _, entry := range history {
+		if entry.Type == EventAutomationFired {
+			fired[staleKey(fmt.Sprint(entry.Data["rule"]), entry.TaskID, fmt.Sprint(entry.Data["since"]))] = true
+		}
+	}
+
+	now := as.now()
+	for _, task := range as.app.taskService.GetTasks() {
+		arrival, ok := entered[task.ID]
+		if !ok || !arrivedIn(arrival, task.Status) {
+			continue // no record of when it got here
+		}
+		since := arrival.Time.UTC().Format(time.RFC3339Nano)
+		for _, rule := range stale {
+			after, _ := parseRuleDuration(rule.Trigger.After)
+			if string(task.Status) != rule.Trigger.Status || now.Sub(arrival.Time) < after {
+				continue
+			}
+			if fired[staleKey(rule.Name, task.ID, since)] {
+				continue
+			}
+			trigger := JournalEntry{Time: now, Type: TriggerTaskStale, TaskID: task.ID}
+			as.run(rule, ruleContext{Rule: rule.Name, Task: task, Trigger: trigger, reviewers: as.reviewersOf(task.ID)}, map[string]interface{}{
+				"since": since,
+			})
+		}
+	}
+}
+
+// NotifyStale tells the frontend about tasks that have gone stale since the
+// last check, when stale notifications are turned on. Each task is announced
+// once per stay in a status.
+func (as *AutomationService) NotifyStale() {
+	if as.app.configService == nil || !as.app.configService.GetStaleConfig().Notify {
+		return
+	}
+	report, err := as.staleReport(nil, as.now())
+	if err != nil {
+		as.logger.Error("Failed to check for stale tasks", err)
+		return
+	}
+
+	as.mu.Lock()
+	fresh := []StaleTask{}
+	for _, task := range report.Tasks {
+		key := staleKey("", task.ID, task.Since.UTC().Format(time.RFC3339Nano))
+		if !as.notified[key] {
+			as.notified[key] = true
+			fresh = append(fresh, task)
+		}
+	}
+	as.mu.Unlock()
+
+	if len(fresh) > 0 {
+		report.Tasks = fresh
+		as.app.emitEvent(RuntimeTasksStale, report)
+	}
+}
+
+// staleReport checks the board against the configured thresholds with
+// overrides laid on top
+func (as *AutomationService) staleReport(overrides map[string]int, now time.Time) (StaleReport, error) {
+	thresholds := as.app.GetStaleConfig().Thresholds
+	for status, days := range overrides {
+		thresholds[status] = days
+	}
+	arrivals := map[int]JournalEntry{}
+	if as.app.journalService != nil {
+		history, err := as.app.journalService.Query(JournalQuery{Types: []string{EventTaskCreated, EventTaskMoved}})
+		if err != nil {
+			return StaleReport{}, err
+		}
+		arrivals = taskArrivals(history)
+	}
+	return staleTasks(as.app.taskService.GetTasks(), arrivals, thresholds, now), nil
+}
+
+// CheckSLA journals an sla.breached event for each open task that has gone
+// past its SLA target, once per SLA clock. Automation rules and the frontend
+// pick breaches up from the journal like any other event.
+func (as *AutomationService) CheckSLA() {
+	if as.app.journalService == nil || len(as.app.GetSLAConfig().Targets) == 0 {
+		return
+	}
+	slas, err := as.slaStatus(as.now())
+	if err != nil {
+		as.logger.Error("Failed to check SLAs", err)
+		return
+	}
+	breaches, err := as.app.journalService.Query(JournalQuery{Types: []string{EventSLABreached}})
+	if err != nil {
+		as.logger.Error("Failed to read journal for SLA breaches", err)
+		return
+	}
+	reported := map[string]bool{}
+	for _, entry := range breaches {
+		reported[fmt.Sprintf("%d|%v", entry.TaskID, entry.Data["started"])] = true
+	}
+
+	for _, sla := range slas {
+		if sla.State != SLABreached {
+			continue
+		}
+		started := sla.Started.UTC().Format(time.RFC3339Nano)
+		if reported[fmt.Sprintf("%d|%s", sla.TaskID, started)] {
+			continue
+		}
+		as.app.recordEvent(EventSLABreached, sla.TaskID, map[string]interface{}{
+			"priority": sla.Priority,
+			"target":   sla.Target,
+			"started":  started,
+			"due":      sla.Due.UTC().Format(time.RFC3339Nano),
+		})
+	}
+}
+
+// slaStatus computes SLA states at now from the journaled task moves
+func (as *AutomationService) slaStatus(now time.Time) ([]TaskSLA, error) {
+	clocks := map[int]slaClock{}
+	if as.app.journalService != nil {
+		history, err := as.app.journalService.Query(JournalQuery{
+			Types: []string{EventTaskMoved, EventTaskApproved, EventTaskRejected},
+		})
+		if err != nil {
+			return nil, err
+		}
+		clocks = slaClocks(history)
+	}
+	return taskSLAs(as.app.taskService.GetTasks(), clocks, as.app.GetSLAConfig().Targets, now), nil
+}
+
+// arrivedIn reports whether a created or moved entry put its task in status
+func arrivedIn(entry JournalEntry, status TaskStatus) bool {
+	if entry.Type == EventTaskCreated {
+		return status == StatusBacklog
+	}
+	return fmt.Sprint(entry.Data["to"]) == string(status)
+}
+
+// staleKey identifies one firing of a stale rule
+func staleKey(rule string, taskID int, since string) string {
+	return fmt.Sprintf("%s|%d|%s", rule, taskID, since)
+}
+
+// findTask returns the task with id
+func (as *AutomationService) findTask(id int) (Task, bool) {
+	for _, task := range as.app.taskService.GetTasks() {
+		if task.ID == id {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+// run checks a rule's conditions and performs its actions, journaling the outcome
+func (as *AutomationService) run(rule AutomationRule, ctx ruleContext, extra map[string]interface{}) {
+	for _, cond := range rule.Conditions {
+		if ok, reason := as.holds(cond, ctx); !ok {
+			as.logger.InfoWithFields("Automation rule condition not met", map[string]interface{}{
+				"rule":    rule.Name,
+				"task_id": ctx.Task.ID,
+				"reason":  reason,
+			})
+			return
+		}
+	}
+
+	data := map[string]interface{}{
+		"rule":    rule.Name,
+		"trigger": ctx.Trigger.Type,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	var failures []string
+	for _, action := range rule.Actions {
+		if err := as.perform(action, ctx); err != nil {
+			as.logger.ErrorWithFields("Automation action failed", err, map[string]interface{}{
+				"rule":   rule.Name,
+				"action": action.Type,
+			})
+			failures = append(failures, action.Type+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		data["error"] = strings.Join(failures, "; ")
+	}
+	as.app.recordEvent(EventAutomationFired, ctx.Task.ID, data)
+}
+
+// holds evaluates one condition, returning why it failed if it did
+func (as *AutomationService) holds(cond RuleCondition, ctx ruleContext) (bool, string) {
+	if cond.Command != "" {
+		return as.commandSucceeds(cond.Command, ctx.Task)
+	}
+
+	var value string
+	switch {
+	case cond.Field == "id":
+		value = strconv.Itoa(ctx.Task.ID)
+	case cond.Field == "title":
+		value = ctx.Task.Title
+	case cond.Field == "status":
+		value = string(ctx.Task.Status)
+	case cond.Field == "priority":
+		value = string(ctx.Task.Priority)
+	case strings.HasPrefix(cond.Field, "data."):
+		if v, ok := ctx.Trigger.Data[strings.TrimPrefix(cond.Field, "data.")]; ok {
+			value = fmt.Sprint(v)
+		}
+	default:
+		return false, "unknown field " + cond.Field
+	}
+	if cond.Equals != "" && value != cond.Equals {
+		return false, fmt.Sprintf("%s is %q, not %q", cond.Field, value, cond.Equals)
+	}
+	if cond.NotEquals != "" && value == cond.NotEquals {
+		return false, fmt.Sprintf("%s is %q", cond.Field, value)
+	}
+	return true, ""
+}
+
+// commandSucceeds runs command in the task's worktree
+func (as *AutomationService) commandSucceeds(command string, task Task) (bool, string) {
+	if err := as.app.requireExecution("running automation commands"); err != nil {
+		return false, err.Error()
+	}
+	worktree, err := as.app.agentService.FindTaskWorktree(task.ID)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), automationCommandTimeout)
+	defer cancel()
+	if _, err := as.runner.CombinedOutput(ctx, Command{Name: "sh", Args: []string{"-c", command}, Dir: worktree}); err != nil {
+		return false, fmt.Sprintf("%q failed: %v", command, err)
+	}
+	return true, ""
+}
+
+// perform carries out one action
+func (as *AutomationService) perform(action RuleAction, ctx ruleContext) error {
+	message, err := renderRuleMessage(action.Message, ctx)
+	if err != nil {
+		return err
+	}
+
+	switch action.Type {
+	case ActionWebhook:
+		return as.postWebhook(action.URL, message)
+	case ActionNotify:
+		as.app.emitEvent(RuntimeAutomationNotice, AutomationNotice{Rule: ctx.Rule, TaskID: ctx.Task.ID, Message: message})
+		return nil
+	case ActionSetPriority:
+		if ctx.Task.ID == 0 {
+			return fmt.Errorf("no task to change")
+		}
+		task := ctx.Task
+		task.Priority = TaskPriority(action.Priority)
+		if err := as.app.taskService.UpdateTask(task); err != nil {
+			return err
+		}
+		as.app.recordEvent(EventTaskUpdated, task.ID, map[string]interface{}{
+			"priority": task.Priority,
+			"rule":     ctx.Rule,
+		})
+		return nil
+	default:
+		return fmt.Errorf("unknown action type %s", action.Type)
+	}
+}
+
+// reviewersOf returns a lookup of a task's suggested reviewers, empty when
+// the task has no branch or git can't be read
+func (as *AutomationService) reviewersOf(taskID int) func() string {
+	return func() string {
+		if taskID == 0 {
+			return ""
+		}
+		ownership, err := as.app.GetReviewOwners(taskID)
+		if err != nil {
+			return ""
+		}
+		return ownership.String()
+	}
+}
+
+// renderRuleMessage expands a message template, defaulting to a summary
+func renderRuleMessage(message string, ctx ruleContext) (string, error) {
+	if message == "" {
+		message = "{{.Rule}}: task #{{.Task.ID}} {{.Task.Title}} ({{.Task.Status}})"
+	}
+	tmpl, err := template.New(ctx.Rule).Parse(message)
+	if err != nil {
+		return "", fmt.Errorf("invalid message template: %v", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return "", fmt.Errorf("failed to render message: %v", err)
+	}
+	return out.String(), nil
+}
+
+// postWebhook sends message as {"text": message}
+func (as *AutomationService) postWebhook(webhookURL, message string) error {
+	body, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		return err
+	}
+	resp, err := as.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// Webhook URLs embed their secret, so keep it out of the journal
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
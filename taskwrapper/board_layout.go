@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ways GetBoardLayout can group tasks into swimlanes. Tasks carry no tags, so
+// milestones are the free-form grouping.
+const (
+	GroupByParent    = "parent"    // one lane per top-level task (epic) and its subtasks
+	GroupByPriority  = "priority"  // one lane per priority, high first
+	GroupByMilestone = "milestone" // one lane per milestone, in milestones.json order
+)
+
+// laneNone is the key of the lane holding tasks that fit no other lane
+const laneNone = "none"
+
+// Swimlane is one row of the board, split into status columns
+type Swimlane struct {
+	Key     string                `json:"key"` // e.g. "parent:3", "priority:high", "none"
+	Title   string                `json:"title"`
+	Epic    *Task                 `json:"epic,omitempty"` // the lane's parent task when grouping by parent
+	Columns map[TaskStatus][]Task `json:"columns"`        // every status, each in task ID order
+	Total   int                   `json:"total"`
+	Done    int                   `json:"done"`
+	Percent int                   `json:"percent"`
+}
+
+// BoardLayout is the board grouped into swimlanes
+type BoardLayout struct {
+	GroupBy string     `json:"groupBy"`
+	Lanes   []Swimlane `json:"lanes"`
+}
+
+// newSwimlane creates an empty lane with a column for every status
+func newSwimlane(key, title string) *Swimlane {
+	lane := &Swimlane{Key: key, Title: title, Columns: map[TaskStatus][]Task{}}
+	for _, status := range AllStatuses() {
+		lane.Columns[status] = []Task{}
+	}
+	return lane
+}
+
+// add places task in its status column and updates the roll-up
+func (lane *Swimlane) add(task Task) {
+	lane.Columns[task.Status] = append(lane.Columns[task.Status], task)
+	lane.Total++
+	if task.Status == StatusDone {
+		lane.Done++
+	}
+	lane.Percent = lane.Done * 100 / lane.Total
+}
+
+// buildBoardLayout groups tasks into swimlanes. Lanes are listed in a stable
+// order with the catch-all lane last; empty catch-all lanes are left out.
+func buildBoardLayout(groupBy string, tasks []Task, milestones []Milestone) (BoardLayout, error) {
+	tasks = canonicalTasks(tasks)
+	var lanes []*Swimlane
+	none := newSwimlane(laneNone, "Ungrouped")
+
+	switch strings.ToLower(strings.TrimSpace(groupBy)) {
+	case GroupByParent:
+		groupBy = GroupByParent
+		none.Title = "No epic"
+		byRoot := map[int]*Swimlane{}
+		hasChildren := map[int]bool{}
+		for _, task := range tasks {
+			if task.Parent != nil {
+				hasChildren[*task.Parent] = true
+			}
+		}
+		for _, task := range tasks {
+			if task.Parent == nil && hasChildren[task.ID] {
+				epic := task
+				lane := newSwimlane(fmt.Sprintf("parent:%d", task.ID), task.Title)
+				lane.Epic = &epic
+				byRoot[task.ID] = lane
+				lanes = append(lanes, lane)
+			}
+		}
+		for _, task := range tasks {
+			if _, isEpic := byRoot[task.ID]; isEpic {
+				continue
+			}
+			if lane, ok := byRoot[rootTaskID(task, tasks)]; ok {
+				lane.add(task)
+			} else {
+				none.add(task)
+			}
+		}
+
+	case GroupByPriority:
+		groupBy = GroupByPriority
+		byPriority := map[TaskPriority]*Swimlane{}
+		for _, priority := range AllPriorities() {
+			lane := newSwimlane("priority:"+string(priority), strings.Title(string(priority)))
+			byPriority[priority] = lane
+			lanes = append(lanes, lane)
+		}
+		for _, task := range tasks {
+			if lane, ok := byPriority[task.Priority]; ok {
+				lane.add(task)
+			} else {
+				none.add(task)
+			}
+		}
+
+	case GroupByMilestone:
+		groupBy = GroupByMilestone
+		none.Title = "No milestone"
+		grouped := map[int]bool{}
+		for _, milestone := range milestones {
+			lane := newSwimlane("milestone:"+milestone.ID, milestone.Name)
+			for _, id := range milestone.TaskIDs {
+				if task, ok := findTask(tasks, id); ok {
+					lane.add(task)
+					grouped[id] = true
+				}
+			}
+			lanes = append(lanes, lane)
+		}
+		for _, task := range tasks {
+			if !grouped[task.ID] {
+				none.add(task)
+			}
+		}
+
+	default:
+		return BoardLayout{}, ValidationError("tasks can be grouped by parent, priority or milestone", nil).
+			WithContext("groupBy", groupBy)
+	}
+
+	if none.Total > 0 {
+		lanes = append(lanes, none)
+	}
+	layout := BoardLayout{GroupBy: groupBy, Lanes: make([]Swimlane, 0, len(lanes))}
+	for _, lane := range lanes {
+		layout.Lanes = append(layout.Lanes, *lane)
+	}
+	return layout, nil
+}
+
+// rootTaskID follows task's parents up to its top-level ancestor. A parent
+// that doesn't exist, or a cycle, ends the walk at the last task reached.
+func rootTaskID(task Task, tasks []Task) int {
+	seen := map[int]bool{task.ID: true}
+	for task.Parent != nil && !seen[*task.Parent] {
+		parent, ok := findTask(tasks, *task.Parent)
+		if !ok {
+			break
+		}
+		seen[parent.ID] = true
+		task = parent
+	}
+	return task.ID
+}
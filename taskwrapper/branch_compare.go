@@ -0,0 +1,73 @@
+package main
+
+import "sort"
+
+// BranchSide is one of two compared branches
+type BranchSide struct {
+	Branch    string      `json:"branch"`
+	TaskID    int         `json:"taskId,omitempty"` // set for task_N branches
+	Commits   []GitCommit `json:"commits"`          // commits the other branch doesn't have, newest first
+	Files     int         `json:"files"`            // files changed since forking from the mainline
+	Additions int         `json:"additions"`
+	Deletions int         `json:"deletions"`
+}
+
+// FileComparison is one file either branch changed
+type FileComparison struct {
+	Path string           `json:"path"`
+	A    *DiffFileSummary `json:"a,omitempty"` // A's change against the mainline; nil if A left it alone
+	B    *DiffFileSummary `json:"b,omitempty"`
+	Same bool             `json:"same"` // both branches end with the same content
+}
+
+// BranchComparison sets two attempts at the same work side by side
+type BranchComparison struct {
+	Base      string           `json:"base"` // the mainline both are measured against
+	A         BranchSide       `json:"a"`
+	B         BranchSide       `json:"b"`
+	Files     []FileComparison `json:"files"`
+	Diff      []DiffFile       `json:"diff"` // going from A's tip to B's
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+// branchSide totals a branch's changes against the mainline
+func branchSide(branch string, commits []GitCommit, files []DiffFileSummary) BranchSide {
+	side := BranchSide{Branch: branch, Commits: commits, Files: len(files)}
+	side.TaskID, _ = taskBranchID(branch)
+	for _, file := range files {
+		side.Additions += file.Additions
+		side.Deletions += file.Deletions
+	}
+	return side
+}
+
+// compareBranchFiles pairs up the files two branches changed against the
+// mainline. between is the diff from one tip to the other; a file missing
+// from it ends the same on both.
+func compareBranchFiles(aFiles, bFiles, between []DiffFileSummary) []FileComparison {
+	differs := map[string]bool{}
+	for _, file := range between {
+		differs[file.OldPath] = true
+		differs[file.NewPath] = true
+	}
+	byPath := map[string]*FileComparison{}
+	compared := func(path string) *FileComparison {
+		if byPath[path] == nil {
+			byPath[path] = &FileComparison{Path: path, Same: !differs[path]}
+		}
+		return byPath[path]
+	}
+	for i := range aFiles {
+		compared(aFiles[i].path()).A = &aFiles[i]
+	}
+	for i := range bFiles {
+		compared(bFiles[i].path()).B = &bFiles[i]
+	}
+
+	files := make([]FileComparison, 0, len(byPath))
+	for _, file := range byPath {
+		files = append(files, *file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
@@ -0,0 +1,85 @@
+package main
+
+import "sort"
+
+// Kinds of mismatch between task branches and tasks
+const (
+	OrphanBranch = "branch_without_task"   // a task_N branch whose task doesn't exist
+	OrphanReview = "review_without_branch" // a pending_review task with no branch to merge
+)
+
+// Fixes FixBranchOrphan can apply
+const (
+	FixCreateTask   = "create_task"   // add a pending_review stub task for the branch
+	FixDeleteBranch = "delete_branch" // force-delete the branch
+	FixRequeue      = "requeue_agent" // move the task back to todo for another agent
+)
+
+// BranchOrphan is a task branch or task missing its counterpart
+type BranchOrphan struct {
+	Kind   string   `json:"kind"`
+	TaskID int      `json:"taskId"`
+	Branch string   `json:"branch"`
+	Title  string   `json:"title,omitempty"` // the task's, for reviews without a branch
+	Fixes  []string `json:"fixes"`
+}
+
+// BranchReconciliation matches task_* branches against the board
+type BranchReconciliation struct {
+	Branches []string       `json:"branches"` // every task branch, in task ID order
+	Orphans  []BranchOrphan `json:"orphans"`
+}
+
+// reconcileBranches reports task branches with no task and tasks waiting
+// for review with no branch, in task ID order
+func reconcileBranches(branches []string, tasks []Task) BranchReconciliation {
+	report := BranchReconciliation{Branches: []string{}, Orphans: []BranchOrphan{}}
+	hasBranch := map[int]bool{}
+	for _, branch := range branches {
+		id, ok := taskBranchID(branch)
+		if !ok {
+			continue
+		}
+		hasBranch[id] = true
+		report.Branches = append(report.Branches, branch)
+		if _, found := findTask(tasks, id); !found {
+			report.Orphans = append(report.Orphans, BranchOrphan{
+				Kind:   OrphanBranch,
+				TaskID: id,
+				Branch: branch,
+				Fixes:  []string{FixCreateTask, FixDeleteBranch},
+			})
+		}
+	}
+	for _, task := range tasks {
+		if task.Status == StatusPendingReview && !hasBranch[task.ID] {
+			report.Orphans = append(report.Orphans, BranchOrphan{
+				Kind:   OrphanReview,
+				TaskID: task.ID,
+				Branch: taskBranch(task.ID),
+				Title:  task.Title,
+				Fixes:  []string{FixRequeue},
+			})
+		}
+	}
+
+	sort.SliceStable(report.Branches, func(i, j int) bool {
+		a, _ := taskBranchID(report.Branches[i])
+		b, _ := taskBranchID(report.Branches[j])
+		return a < b
+	})
+	sort.SliceStable(report.Orphans, func(i, j int) bool {
+		return report.Orphans[i].TaskID < report.Orphans[j].TaskID
+	})
+	return report
+}
+
+// findOrphan returns the orphan for taskID, if it still is one
+func findOrphan(report BranchReconciliation, taskID int) (BranchOrphan, bool) {
+	for _, orphan := range report.Orphans {
+		if orphan.TaskID == taskID {
+			return orphan, true
+		}
+	}
+	return BranchOrphan{}, false
+}
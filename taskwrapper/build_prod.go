@@ -0,0 +1,6 @@
+//go:build !dev
+
+package main
+
+// devBuild is true under `wails dev`, which builds with the dev tag
+const devBuild = false
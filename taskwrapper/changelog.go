@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// changelogFileName is the changelog kept at the repository root
+const changelogFileName = "CHANGELOG.md"
+
+// Changelog groups, in the order they're written. A task's group comes from
+// a conventional-commit prefix on its title ("fix: ...") or else its first word.
+const (
+	ChangeAdded   = "Added"
+	ChangeChanged = "Changed"
+	ChangeFixed   = "Fixed"
+	ChangeRemoved = "Removed"
+)
+
+var changelogGroupOrder = []string{ChangeAdded, ChangeChanged, ChangeFixed, ChangeRemoved}
+
+// changeTypeWords maps conventional-commit types and leading verbs to groups
+var changeTypeWords = map[string]string{
+	"feat": ChangeAdded, "add": ChangeAdded, "adds": ChangeAdded, "implement": ChangeAdded,
+	"create": ChangeAdded, "introduce": ChangeAdded, "support": ChangeAdded, "new": ChangeAdded,
+	"fix": ChangeFixed, "fixes": ChangeFixed, "bugfix": ChangeFixed, "resolve": ChangeFixed,
+	"correct": ChangeFixed, "repair": ChangeFixed,
+	"remove": ChangeRemoved, "delete": ChangeRemoved, "drop": ChangeRemoved, "deprecate": ChangeRemoved,
+}
+
+// taskMergeSubject matches the merge commits ApproveTask creates
+var taskMergeSubject = regexp.MustCompile(`^Merge task #(\d+): (.*)$`)
+
+// conventionalPrefix matches "type: " and "type(scope)!: " title prefixes
+var conventionalPrefix = regexp.MustCompile(`^([a-zA-Z]+)(\([^)]*\))?!?:\s*`)
+
+// GitCommit is one commit from git log
+type GitCommit struct {
+	Hash    string    `json:"hash"`
+	Subject string    `json:"subject"`
+	Time    time.Time `json:"time"`
+}
+
+// ChangelogEntry is one merged task in the changelog
+type ChangelogEntry struct {
+	TaskID int       `json:"taskId"`
+	Title  string    `json:"title"`
+	Commit string    `json:"commit"`
+	Merged time.Time `json:"merged"`
+}
+
+// ChangelogGroup is the entries of one change type
+type ChangelogGroup struct {
+	Type    string           `json:"type"`
+	Entries []ChangelogEntry `json:"entries"`
+}
+
+// ChangelogPreview is the changelog section for tasks merged since a tag
+type ChangelogPreview struct {
+	Version   string           `json:"version,omitempty"` // the release it heads; Unreleased when empty
+	Since     string           `json:"since,omitempty"`   // empty when the repository has no tags yet
+	Date      time.Time        `json:"date"`
+	Groups    []ChangelogGroup `json:"groups"`
+	Markdown  string           `json:"markdown"`            // the section as it's written to CHANGELOG.md
+	File      string           `json:"file,omitempty"`      // set once written
+	Committed bool             `json:"committed,omitempty"` // the changelog was committed
+}
+
+// parseTaskMerge returns the task ID and title of a task merge commit subject
+func parseTaskMerge(subject string) (int, string, bool) {
+	match := taskMergeSubject.FindStringSubmatch(strings.TrimSpace(subject))
+	if match == nil {
+		return 0, "", false
+	}
+	var id int
+	fmt.Sscanf(match[1], "%d", &id)
+	return id, match[2], true
+}
+
+// changeType returns a title's changelog group and the title without any
+// conventional-commit prefix
+func changeType(title string) (string, string) {
+	if match := conventionalPrefix.FindStringSubmatch(title); match != nil {
+		rest := title[len(match[0]):]
+		if group, ok := changeTypeWords[strings.ToLower(match[1])]; ok {
+			return group, rest
+		}
+		return ChangeChanged, rest
+	}
+	words := strings.Fields(title)
+	if len(words) > 0 {
+		if group, ok := changeTypeWords[strings.ToLower(strings.Trim(words[0], ".,:"))]; ok {
+			return group, title
+		}
+	}
+	return ChangeChanged, title
+}
+
+// buildChangelog groups the task merges among commits, newest first, using
+// each task's current title when it's still on the board. A task merged
+// twice is listed once, at its latest merge.
+func buildChangelog(since string, commits []GitCommit, tasks []Task, now time.Time, l *Localizer) ChangelogPreview {
+	preview := ChangelogPreview{Since: since, Date: now, Groups: []ChangelogGroup{}}
+	seen := map[int]bool{}
+	grouped := map[string][]ChangelogEntry{}
+	sorted := append([]GitCommit(nil), commits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.After(sorted[j].Time) })
+	for _, commit := range sorted {
+		id, title, ok := parseTaskMerge(commit.Subject)
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if task, found := findTask(tasks, id); found {
+			title = task.Title
+		}
+		group, title := changeType(title)
+		grouped[group] = append(grouped[group], ChangelogEntry{TaskID: id, Title: title, Commit: commit.Hash, Merged: commit.Time})
+	}
+	for _, group := range changelogGroupOrder {
+		if entries := grouped[group]; len(entries) > 0 {
+			preview.Groups = append(preview.Groups, ChangelogGroup{Type: group, Entries: entries})
+		}
+	}
+	preview.Markdown = renderChangelogSection(preview, l)
+	return preview
+}
+
+// renderChangelogSection writes a preview as a CHANGELOG.md section in l's
+// locale
+func renderChangelogSection(preview ChangelogPreview, l *Localizer) string {
+	var b strings.Builder
+	version := preview.Version
+	if version == "" {
+		version = "Unreleased"
+	}
+	fmt.Fprintf(&b, "## %s - %s\n\n", version, preview.Date.Format("2006-01-02"))
+	if preview.Since != "" {
+		b.WriteString(l.T(MsgChangelogSince, preview.Since) + "\n\n")
+	}
+	if len(preview.Groups) == 0 {
+		b.WriteString(l.T(MsgChangelogEmpty) + "\n")
+	}
+	for _, group := range preview.Groups {
+		fmt.Fprintf(&b, "### %s\n\n", l.T(changelogGroupKeys[group.Type]))
+		for _, entry := range group.Entries {
+			commit := entry.Commit
+			if len(commit) > 7 {
+				commit = commit[:7]
+			}
+			fmt.Fprintf(&b, "- %s (#%d, %s)\n", entry.Title, entry.TaskID, commit)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// insertChangelogSection puts section above the newest release in an
+// existing changelog, replacing an earlier Unreleased section, or starts a
+// new changelog
+func insertChangelogSection(existing, section string) string {
+	if strings.TrimSpace(existing) == "" {
+		return "# Changelog\n\n" + section
+	}
+	lines := strings.SplitAfter(existing, "\n")
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if start == -1 {
+			start = i
+			if !strings.HasPrefix(line, "## Unreleased") {
+				end = i
+				break
+			}
+			continue
+		}
+		end = i
+		break
+	}
+	if start == -1 {
+		return strings.TrimRight(existing, "\n") + "\n\n" + section
+	}
+	rest := strings.Join(lines[end:], "")
+	if rest != "" {
+		section += "\n"
+	}
+	return strings.Join(lines[:start], "") + section + rest
+}
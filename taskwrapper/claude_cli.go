@@ -0,0 +1,90 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// claudeProbeTTL is how long a probe of the claude CLI is trusted before it
+// runs again
+const claudeProbeTTL = 10 * time.Minute
+
+// claude CLI flags the orchestrator knows about
+const (
+	ClaudeFlagAddDir          = "--add-dir"
+	ClaudeFlagSkipPermissions = "--dangerously-skip-permissions"
+	ClaudeFlagOutputFormat    = "--output-format" // --output-format json
+	ClaudeFlagResume          = "--resume"
+	ClaudeFlagSessionID       = "--session-id"
+)
+
+// claudeRequiredFlags are passed by agent_spawn.sh; without them no agent
+// can be launched
+var claudeRequiredFlags = []string{ClaudeFlagAddDir, ClaudeFlagSkipPermissions}
+
+// claudeOptionalFlags enable features beyond a plain launch
+var claudeOptionalFlags = []string{ClaudeFlagOutputFormat, ClaudeFlagResume, ClaudeFlagSessionID}
+
+// claudeUpgradeHint tells the user how to get a newer claude CLI
+const claudeUpgradeHint = "upgrade with `claude update` or `npm install -g @anthropic-ai/claude-code`"
+
+// claudeVersionPattern finds the version number in `claude --version`
+var claudeVersionPattern = regexp.MustCompile(`\d+\.\d+\.\d+`)
+
+// ClaudeCapabilities is what the installed claude CLI reported about itself
+type ClaudeCapabilities struct {
+	Installed bool            `json:"installed"`
+	Version   string          `json:"version,omitempty"`
+	Flags     map[string]bool `json:"flags,omitempty"` // nil when --help couldn't be read
+	Error     string          `json:"error,omitempty"`
+	ProbedAt  time.Time       `json:"probedAt"`
+}
+
+// parseClaudeCapabilities reads the version and known flags out of the
+// output of `claude --version` and `claude --help`
+func parseClaudeCapabilities(version, help string) ClaudeCapabilities {
+	caps := ClaudeCapabilities{Installed: true, Version: claudeVersionPattern.FindString(version)}
+	if caps.Version == "" {
+		caps.Version = strings.TrimSpace(version)
+	}
+	if strings.TrimSpace(help) == "" {
+		return caps
+	}
+	caps.Flags = map[string]bool{}
+	for _, flag := range append(append([]string{}, claudeRequiredFlags...), claudeOptionalFlags...) {
+		caps.Flags[flag] = strings.Contains(help, flag)
+	}
+	if caps.Flags[ClaudeFlagOutputFormat] {
+		caps.Flags[ClaudeFlagOutputFormat] = strings.Contains(help, "json")
+	}
+	return caps
+}
+
+// Supports reports whether the CLI is known to accept flag
+func (caps ClaudeCapabilities) Supports(flag string) bool {
+	return caps.Flags[flag]
+}
+
+// Missing returns the flags the CLI is known not to accept. Nothing is
+// missing when its flags couldn't be read.
+func (caps ClaudeCapabilities) Missing(flags []string) []string {
+	missing := []string{}
+	if caps.Flags == nil {
+		return missing
+	}
+	for _, flag := range flags {
+		if !caps.Flags[flag] {
+			missing = append(missing, flag)
+		}
+	}
+	return missing
+}
+
+// label names the CLI in messages, with its version when known
+func (caps ClaudeCapabilities) label() string {
+	if caps.Version == "" {
+		return "claude"
+	}
+	return "claude " + caps.Version
+}
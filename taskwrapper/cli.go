@@ -0,0 +1,500 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// newRootCommand builds the taskwrapper command tree. The root command opens
+// the desktop app; subcommands drive the same services from the shell.
+func newRootCommand() *cobra.Command {
+	var serve bool
+	var addr string
+	var pprof bool
+
+	root := &cobra.Command{
+		Use:          "taskwrapper",
+		Short:        "Kanban board and agent launcher for plan/task.json",
+		Version:      AppVersion,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			if cliApp != nil {
+				cliApp.shutdown(context.Background())
+			}
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lock, proceed := acquireInstance(serve)
+			if !proceed {
+				return nil // the running instance was brought to the front
+			}
+			app := NewApp()
+			if lock != nil {
+				app.holdInstanceLock(lock)
+			}
+			if serve {
+				runServeMode(app, addr, pprof)
+				return nil
+			}
+			runDesktop(app)
+			return nil
+		},
+	}
+	root.Flags().BoolVar(&serve, "serve", false, "serve the board over HTTP instead of opening a window")
+	root.Flags().StringVar(&addr, "addr", "", "listen address for --serve (default "+defaultRemoteAddr+")")
+	root.Flags().BoolVar(&pprof, "pprof", false, "with --serve, expose net/http/pprof under /debug/pprof/ to full-access tokens")
+	root.PersistentFlags().BoolVar(&safeModeFlag, "safe-mode", false, "disable agent launches, terminals and git operations")
+	root.Flags().BoolVar(&readOnlyFlag, "read-only", false, "if another instance is running, open read-only instead of focusing it")
+
+	root.AddCommand(
+		newListCommand(),
+		newAddCommand(),
+		newMoveCommand(),
+		newLaunchCommand(),
+		newAgentStatusCommand(),
+		newReviewCommand("approve", "Merge a task branch and mark the task done", (*App).ApproveTask),
+		newReviewCommand("reject", "Delete a task branch and mark the task NOT MERGED", (*App).RejectTask),
+		newRepoCommand(),
+		newDiagnosticsCommand(),
+		newHealthCommand(),
+		newSyncCommand(),
+		newEncryptionCommand("encrypt", "Encrypt task.json, plan.md and their backups with a key kept in the OS keychain", "encrypted", (*App).EncryptRepository),
+		newEncryptionCommand("decrypt", "Decrypt task.json, plan.md and their backups and remove the key", "decrypted", (*App).DecryptRepository),
+	)
+
+	return root
+}
+
+// safeModeFlag is set by --safe-mode and forces safe mode on for every App
+var safeModeFlag bool
+
+// readOnlyFlag is set by --read-only, or when another instance owns the
+// config, and opens every App read-only
+var readOnlyFlag bool
+
+// acquireInstance takes the single-instance lock for a desktop or --serve
+// run. If another instance holds it, a desktop run asks that instance to
+// bring its window forward and reports that this one should exit; with
+// --read-only, under --serve, or when the holder doesn't answer the focus
+// request, the run goes ahead read-only instead.
+func acquireInstance(serve bool) (*InstanceLock, bool) {
+	path, err := instanceLockPath()
+	if err == nil {
+		var lock *InstanceLock
+		var holder *InstanceInfo
+		if lock, holder, err = AcquireInstanceLock(path); err == nil {
+			if lock != nil {
+				return lock, true
+			}
+			if !serve && !readOnlyFlag && holder.Focus() == nil {
+				println("TaskWrapper is already running (pid", holder.PID, "); brought its window to the front")
+				return nil, false
+			}
+			println("TaskWrapper is already running (pid", holder.PID, "); opening read-only")
+			readOnlyFlag = true
+			return nil, true
+		}
+	}
+	println("Warning: running without the single-instance lock:", err.Error())
+	return nil, true
+}
+
+// cliApp is the App created by the running subcommand, shut down after it
+// finishes so buffered state (e.g. telemetry) is saved
+var cliApp *App
+
+// newCLIApp creates a headless App with tasks loaded from the active repository
+func newCLIApp() *App {
+	app := NewApp()
+	app.headless = true
+	app.startup(context.Background())
+	cliApp = app
+	return app
+}
+
+// printJSON writes v as indented JSON to stdout
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// parseTaskIDArg parses a positional task ID argument
+func parseTaskIDArg(arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid task ID %q", arg)
+	}
+	return id, nil
+}
+
+func newListCommand() *cobra.Command {
+	var status string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List tasks, optionally filtered by status",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := newCLIApp()
+
+			var tasks []Task
+			var err error
+			if status != "" {
+				tasks, err = app.GetTasksByStatus(status)
+			} else {
+				tasks, err = app.LoadTasks()
+			}
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return printJSON(tasks)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSTATUS\tPRIORITY\tTITLE")
+			for _, task := range tasks {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", task.ID, task.Status, task.Priority, task.Title)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVarP(&status, "status", "s", "", "only show tasks with this status")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print tasks as JSON")
+	return cmd
+}
+
+func newAddCommand() *cobra.Command {
+	var priority string
+
+	cmd := &cobra.Command{
+		Use:   "add <title>",
+		Short: "Add a task to the backlog",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			task, err := newCLIApp().CreateTask(args[0], priority)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created task #%d: %s\n", task.ID, task.Title)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&priority, "priority", "p", string(PriorityMedium), "task priority (high, medium, low)")
+	return cmd
+}
+
+func newMoveCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "move <id> <status>",
+		Short: "Move a task to another column (todo → doing launches an agent)",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseTaskIDArg(args[0])
+			if err != nil {
+				return err
+			}
+			if err := newCLIApp().moveTask(id, args[1], true, force); err != nil {
+				return err
+			}
+			fmt.Printf("Moved task #%d to %s\n", id, args[1])
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "start the task even if its dependencies aren't done")
+	return cmd
+}
+
+func newLaunchCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "launch <id>",
+		Short: "Launch a Claude agent for a task without moving it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseTaskIDArg(args[0])
+			if err != nil {
+				return err
+			}
+			app := newCLIApp()
+			for _, task := range app.taskService.GetTasks() {
+				if task.ID == id {
+					return app.launchAgent(task)
+				}
+			}
+			return fmt.Errorf("task with ID %d not found", id)
+		},
+	}
+}
+
+func newAgentStatusCommand() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "agents",
+		Short: "Show subagent worktree status",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status, err := newCLIApp().GetAgentStatus()
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return printJSON(status)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "WORKTREE\tSTATUS\tTASK")
+			for _, wt := range status.Worktrees {
+				task := ""
+				if wt.TaskID != "" {
+					task = "#" + wt.TaskID + " " + wt.TaskTitle
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", wt.Name, wt.Status, task)
+			}
+			w.Flush()
+			fmt.Printf("\n%d busy, %d idle, max %d\n", status.BusyCount, status.IdleCount, status.MaxSubagents)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print status as JSON")
+	return cmd
+}
+
+// newReviewCommand builds approve/reject, which share argument handling
+func newReviewCommand(name, short string, action func(*App, int) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   name + " <id>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseTaskIDArg(args[0])
+			if err != nil {
+				return err
+			}
+			if err := action(newCLIApp(), id); err != nil {
+				return err
+			}
+			fmt.Printf("Task #%d %sd\n", id, name)
+			return nil
+		},
+	}
+}
+
+func newRepoCommand() *cobra.Command {
+	repo := &cobra.Command{
+		Use:   "repo",
+		Short: "List or switch configured repositories",
+	}
+
+	repo.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List configured repositories",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := newCLIApp()
+			repos, err := app.GetRepositories()
+			if err != nil {
+				return err
+			}
+			active, _ := app.getActiveRepositoryPath()
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "\tID\tNAME\tPATH")
+			for _, r := range repos {
+				marker := ""
+				if r.Path == active {
+					marker = "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, r.ID, r.Name, r.Path)
+			}
+			return w.Flush()
+		},
+	})
+
+	repo.AddCommand(&cobra.Command{
+		Use:       "confirm <action>",
+		Short:     "Allow agent launches (agent_spawn) or merges (merge) in the active repository",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{ConfirmAgentSpawn, ConfirmMerge},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := newCLIApp().ConfirmRepositoryAction(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Confirmed %s for the active repository\n", args[0])
+			return nil
+		},
+	})
+
+	var unpin bool
+	pin := &cobra.Command{
+		Use:   "pin-scripts",
+		Short: "Pin the active repository's helper scripts so only these exact versions run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := newCLIApp()
+			if unpin {
+				if err := app.UnpinHelperScripts(); err != nil {
+					return err
+				}
+				fmt.Println("Helper scripts unpinned")
+				return nil
+			}
+			if err := app.PinHelperScripts(); err != nil {
+				return err
+			}
+			scripts, err := app.GetHelperScripts()
+			if err != nil {
+				return err
+			}
+			for _, script := range scripts {
+				fmt.Printf("%s  %s\n", script.Pinned, script.Name)
+			}
+			return nil
+		},
+	}
+	pin.Flags().BoolVar(&unpin, "clear", false, "stop verifying helper scripts")
+	repo.AddCommand(pin)
+
+	repo.AddCommand(&cobra.Command{
+		Use:   "use <id>",
+		Short: "Make a repository the active one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := newCLIApp().SetActiveRepository(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Active repository set to %s\n", args[0])
+			return nil
+		},
+	})
+
+	return repo
+}
+
+func newDiagnosticsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diagnostics",
+		Short: "Write a diagnostics bundle to attach to bug reports",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := newCLIApp().ExportDiagnostics()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Diagnostics written to %s\n", path)
+			return nil
+		},
+	}
+}
+
+func newHealthCommand() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Check the task file, config, git and worktree setup",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := newCLIApp().GetHealth()
+			if asJSON {
+				if err := printJSON(report); err != nil {
+					return err
+				}
+			} else {
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "CHECK\tSTATUS\tDETAILS")
+				for _, check := range report.Checks {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Status, check.Message)
+				}
+				w.Flush()
+			}
+			if report.Status == HealthError {
+				return fmt.Errorf("health check failed")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	return cmd
+}
+
+// newEncryptionCommand builds a command that migrates the active repository's
+// plan data to or from encrypted storage
+func newEncryptionCommand(use, short, done string, migrate func(*App) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app := newCLIApp()
+			if err := migrate(app); err != nil {
+				return err
+			}
+			fmt.Printf("Repository %s: %s\n", done, app.agentService.GetProjectRoot())
+			return nil
+		},
+	}
+}
+
+func newSyncCommand() *cobra.Command {
+	sync := &cobra.Command{
+		Use:   "sync",
+		Short: "Push or pull the plan directory to the repository's sync remote",
+	}
+
+	for _, spec := range []struct {
+		use, short string
+		run        func(*App, bool) (SyncResult, error)
+	}{
+		{"push", "Upload plan files changed since the last sync", (*App).PushPlan},
+		{"pull", "Download plan files changed on the remote", (*App).PullPlan},
+	} {
+		spec := spec
+		var force bool
+		cmd := &cobra.Command{
+			Use:   spec.use,
+			Short: spec.short,
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				result, err := spec.run(newCLIApp(), force)
+				if err != nil {
+					if appErr, ok := err.(*AppError); ok && appErr.Type == ErrorTypeConflict {
+						printSyncConflicts(appErr.Context["conflicts"])
+					}
+					return err
+				}
+				for _, path := range result.Pushed {
+					fmt.Printf("pushed  %s\n", path)
+				}
+				for _, path := range result.Pulled {
+					fmt.Printf("pulled  %s\n", path)
+				}
+				printSyncConflicts(result.Conflicts)
+				fmt.Printf("Revision %s\n", result.Revision)
+				return nil
+			},
+		}
+		cmd.Flags().BoolVar(&force, "force", false, "overwrite changes on the other side instead of stopping at conflicts")
+		sync.AddCommand(cmd)
+	}
+
+	return sync
+}
+
+// printSyncConflicts lists files changed on both machines since the last sync
+func printSyncConflicts(conflicts interface{}) {
+	list, _ := conflicts.([]SyncConflict)
+	for _, conflict := range list {
+		fmt.Printf("conflict  %s (changed locally and on the remote)\n", conflict.Path)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+// defaultMaxSubagents is agent_status.sh's worktree limit when it can't be
+// asked for its own
+const defaultMaxSubagents = 5
+
+// Why StartAgentsForColumn left a todo task alone
+const (
+	LaunchSkippedBlocked = "blocked" // a dependency isn't done yet
+	LaunchSkippedLimit   = "limit"   // no worktree or requested slot left
+)
+
+// ColumnLaunchSkip is a todo task that didn't get an agent
+type ColumnLaunchSkip struct {
+	TaskID int    `json:"taskId"`
+	Reason string `json:"reason"`
+}
+
+// ColumnLaunch is the outcome of a bulk agent launch
+type ColumnLaunch struct {
+	Started []int              `json:"started"` // tasks moved to doing, in launch order
+	Skipped []ColumnLaunchSkip `json:"skipped"`
+	Slots   int                `json:"slots"` // free agent worktrees before the launch
+}
+
+// columnLaunchPlan picks up to limit unblocked todo tasks, highest priority
+// first. A task is unblocked once all of its dependencies are done.
+func columnLaunchPlan(tasks []Task, limit int) ColumnLaunch {
+	plan := ColumnLaunch{Started: []int{}, Skipped: []ColumnLaunchSkip{}}
+	done := doneTaskIDs(tasks)
+	for _, task := range queuedTasks(tasks) {
+		switch {
+		case len(unfinishedDeps(task, done)) > 0:
+			plan.Skipped = append(plan.Skipped, ColumnLaunchSkip{TaskID: task.ID, Reason: LaunchSkippedBlocked})
+		case len(plan.Started) >= limit:
+			plan.Skipped = append(plan.Skipped, ColumnLaunchSkip{TaskID: task.ID, Reason: LaunchSkippedLimit})
+		default:
+			plan.Started = append(plan.Started, task.ID)
+		}
+	}
+	return plan
+}
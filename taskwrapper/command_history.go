@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// commandHistoryFileName is the command history inside a repository's
+	// logs directory, next to the journal
+	commandHistoryFileName = "commands.jsonl"
+	// commandSearchLimit caps the commands one search returns
+	commandSearchLimit = 200
+	// maxCaptureSequence caps an unterminated escape sequence kept between
+	// reads, so a stray prefix can't grow without bound
+	maxCaptureSequence = 16 * 1024
+)
+
+// commandCapturePrefix starts the shell integration escape sequences, the
+// private OSC 633 the hook below prints after every command. Terminals
+// ignore OSC sequences they don't know, so nothing shows on screen.
+const commandCapturePrefix = "\x1b]633;"
+
+// commandCaptureHook is the PROMPT_COMMAND managed shells run before each
+// prompt: the last history entry, its exit status, then the directory the
+// next command starts in. A .bashrc that replaces PROMPT_COMMAND turns
+// capture off.
+const commandCaptureHook = `__tw_status=$?; printf '\033]633;E;%s\007\033]633;D;%s\007\033]633;P;Cwd=%s\007' "$(HISTTIMEFORMAT= history 1)" "$__tw_status" "$PWD"`
+
+// CommandRecord is one command run in a managed terminal
+type CommandRecord struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Dir        string    `json:"dir,omitempty"` // where it ran
+	ExitCode   int       `json:"exitCode"`
+	TerminalID string    `json:"terminalId,omitempty"`
+	Repo       string    `json:"repo,omitempty"`
+}
+
+// commandCapture follows one terminal's output for the hook's sequences
+// and turns them into commands
+type commandCapture struct {
+	partial []byte // a sequence split across reads
+	started bool   // the first prompt's history entry predates the terminal
+	number  string // history number of the last command seen
+	command string // finished, waiting for its exit status
+	pending bool
+	dir     string
+}
+
+// Feed scans a chunk of terminal output and returns the commands it finished
+func (cc *commandCapture) Feed(data []byte) []CommandRecord {
+	buf := append(cc.partial, data...)
+	cc.partial = nil
+	records := []CommandRecord{}
+	for {
+		start := bytes.Index(buf, []byte(commandCapturePrefix))
+		if start < 0 {
+			// Keep a prefix cut off at the end of the chunk
+			for k := len(commandCapturePrefix) - 1; k > 0; k-- {
+				if bytes.HasSuffix(buf, []byte(commandCapturePrefix[:k])) {
+					cc.partial = append([]byte(nil), buf[len(buf)-k:]...)
+					break
+				}
+			}
+			return records
+		}
+		end := bytes.IndexByte(buf[start:], '\a')
+		if end < 0 {
+			if len(buf)-start <= maxCaptureSequence {
+				cc.partial = append([]byte(nil), buf[start:]...)
+			}
+			return records
+		}
+		if record, ok := cc.handle(string(buf[start+len(commandCapturePrefix) : start+end])); ok {
+			records = append(records, record)
+		}
+		buf = buf[start+end+1:]
+	}
+}
+
+// handle applies one sequence, E the history entry, D the exit status and
+// P;Cwd= the working directory
+func (cc *commandCapture) handle(sequence string) (CommandRecord, bool) {
+	kind, value, _ := strings.Cut(sequence, ";")
+	switch kind {
+	case "E":
+		number, command, _ := strings.Cut(strings.TrimLeft(value, " "), " ")
+		command = strings.TrimSpace(command)
+		if !cc.started {
+			cc.started = true
+			cc.number = number
+			return CommandRecord{}, false
+		}
+		// An empty line, or a command the shell kept out of its history,
+		// leaves the last entry in place
+		if number == cc.number || command == "" {
+			cc.pending = false
+			return CommandRecord{}, false
+		}
+		cc.number = number
+		cc.command = command
+		cc.pending = true
+	case "D":
+		if !cc.pending {
+			return CommandRecord{}, false
+		}
+		cc.pending = false
+		exitCode, _ := strconv.Atoi(strings.TrimSpace(value))
+		return CommandRecord{Command: cc.command, Dir: cc.dir, ExitCode: exitCode}, true
+	case "P":
+		if dir, ok := strings.CutPrefix(value, "Cwd="); ok {
+			cc.dir = dir
+		}
+	}
+	return CommandRecord{}, false
+}
+
+// CommandHistoryService appends the commands run in managed terminals to
+// the active repository's commands.jsonl
+type CommandHistoryService struct {
+	mu     sync.Mutex
+	logDir string
+	repo   string
+	logger Logger
+}
+
+// NewCommandHistoryService creates a history writing to logDir/commands.jsonl
+func NewCommandHistoryService(logDir, repo string, logger Logger) *CommandHistoryService {
+	return &CommandHistoryService{
+		logDir: logDir,
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// SetRepository points the history at another repository's log directory
+func (hs *CommandHistoryService) SetRepository(logDir, repo string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.logDir = logDir
+	hs.repo = repo
+}
+
+// Record appends a command. Failures are logged, not returned, since
+// there's no one to return them to from a terminal's reader.
+func (hs *CommandHistoryService) Record(record CommandRecord) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+	record.Repo = hs.repo
+	if err := hs.append(record); err != nil {
+		hs.logger.Error("Failed to record terminal command", err)
+	}
+}
+
+// append writes one JSON line (must be called with lock held)
+func (hs *CommandHistoryService) append(record CommandRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode command: %v", err)
+	}
+	if err := os.MkdirAll(hs.logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+	f, err := os.OpenFile(hs.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open command history: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append command: %v", err)
+	}
+	return nil
+}
+
+// Search returns the commands containing every word of query, ignoring
+// case, newest first; an empty query returns the latest commands
+func (hs *CommandHistoryService) Search(query string) ([]CommandRecord, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	f, err := os.Open(hs.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []CommandRecord{}, nil
+		}
+		return nil, fmt.Errorf("failed to open command history: %v", err)
+	}
+	defer f.Close()
+
+	words := strings.Fields(strings.ToLower(query))
+	matches := []CommandRecord{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record CommandRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if commandMatches(record, words) {
+			matches = append(matches, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read command history: %v", err)
+	}
+
+	results := make([]CommandRecord, 0, commandSearchLimit)
+	for i := len(matches) - 1; i >= 0 && len(results) < commandSearchLimit; i-- {
+		results = append(results, matches[i])
+	}
+	return results, nil
+}
+
+// commandMatches reports whether every word appears in the command or
+// its directory
+func commandMatches(record CommandRecord, words []string) bool {
+	text := strings.ToLower(record.Command + "\n" + record.Dir)
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// path returns the history file location (must be called with lock held)
+func (hs *CommandHistoryService) path() string {
+	return filepath.Join(hs.logDir, commandHistoryFileName)
+}
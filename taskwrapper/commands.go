@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Command names accepted by ExecuteCommand
+const (
+	CommandMoveTask     = "task.move"
+	CommandStartAgent   = "agent.start"
+	CommandOpenTerminal = "terminal.open"
+	CommandSwitchRepo   = "repo.switch"
+)
+
+// CommandArg describes one argument of a command
+type CommandArg struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // "int" or "string"
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// CommandSpec describes a command for the palette
+type CommandSpec struct {
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Args        []CommandArg `json:"args"`
+}
+
+// command is a registered command and its handler
+type command struct {
+	spec CommandSpec
+	run  func(a *App, args commandArgs) (interface{}, error)
+}
+
+// taskIDArg is the argument naming the task a command acts on
+var taskIDArg = CommandArg{Name: "taskId", Type: "int", Required: true, Description: "task to act on"}
+
+// commands is every action the palette and automation can run
+var commands = map[string]command{
+	CommandMoveTask: {
+		spec: CommandSpec{
+			Name:        CommandMoveTask,
+			Title:       "Move task",
+			Description: "Move a task to another column; moving to doing launches an agent",
+			Args: []CommandArg{
+				taskIDArg,
+				{Name: "status", Type: "string", Required: true, Description: "backlog, todo, doing, pending_review or done"},
+			},
+		},
+		run: func(a *App, args commandArgs) (interface{}, error) {
+			taskID, err := args.Int("taskId")
+			if err != nil {
+				return nil, err
+			}
+			status, err := args.String("status")
+			if err != nil {
+				return nil, err
+			}
+			return nil, a.MoveTask(taskID, status)
+		},
+	},
+	CommandStartAgent: {
+		spec: CommandSpec{
+			Name:        CommandStartAgent,
+			Title:       "Start agent",
+			Description: "Launch a Claude agent for a task without moving it",
+			Args:        []CommandArg{taskIDArg},
+		},
+		run: func(a *App, args commandArgs) (interface{}, error) {
+			task, err := args.Task(a)
+			if err != nil {
+				return nil, err
+			}
+			return nil, a.launchAgent(task)
+		},
+	},
+	CommandOpenTerminal: {
+		spec: CommandSpec{
+			Name:        CommandOpenTerminal,
+			Title:       "Open terminal in worktree",
+			Description: "Open a terminal in the worktree of a task's agent; returns the terminal ID",
+			Args:        []CommandArg{taskIDArg},
+		},
+		run: func(a *App, args commandArgs) (interface{}, error) {
+			taskID, err := args.Int("taskId")
+			if err != nil {
+				return nil, err
+			}
+			if err := a.requireExecution("opening terminals"); err != nil {
+				return nil, err
+			}
+			worktree, err := a.agentService.FindTaskWorktree(taskID)
+			if err != nil {
+				return nil, err
+			}
+			return a.startTerminal(taskID, worktree)
+		},
+	},
+	CommandSwitchRepo: {
+		spec: CommandSpec{
+			Name:        CommandSwitchRepo,
+			Title:       "Switch repository",
+			Description: "Make another configured repository active",
+			Args: []CommandArg{
+				{Name: "id", Type: "string", Required: true, Description: "repository ID"},
+			},
+		},
+		run: func(a *App, args commandArgs) (interface{}, error) {
+			id, err := args.String("id")
+			if err != nil {
+				return nil, err
+			}
+			return nil, a.SetActiveRepository(id)
+		},
+	},
+}
+
+// commandArgs are a command's arguments as decoded from JSON
+type commandArgs map[string]interface{}
+
+// Int returns a whole-number argument. JSON numbers arrive as float64, and
+// numeric strings are accepted for scripts.
+func (args commandArgs) Int(name string) (int, error) {
+	switch v := args[name].(type) {
+	case int:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n, nil
+		}
+	case nil:
+		return 0, ValidationError("missing argument "+name, nil)
+	}
+	return 0, ValidationError(fmt.Sprintf("argument %s must be a whole number", name), nil).
+		WithContext("value", args[name])
+}
+
+// String returns a non-empty string argument
+func (args commandArgs) String(name string) (string, error) {
+	v, ok := args[name].(string)
+	if !ok || strings.TrimSpace(v) == "" {
+		return "", ValidationError("missing argument "+name, nil)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// Task returns the task named by the taskId argument
+func (args commandArgs) Task(a *App) (Task, error) {
+	taskID, err := args.Int("taskId")
+	if err != nil {
+		return Task{}, err
+	}
+	for _, task := range a.taskService.GetTasks() {
+		if task.ID == taskID {
+			return task, nil
+		}
+	}
+	return Task{}, NotFoundError("task not found", nil).WithContext("task_id", taskID)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Signature formats for the merge commits the app creates, as git's
+// gpg.format names them
+const (
+	SigningGPG  = "openpgp"
+	SigningSSH  = "ssh"
+	SigningX509 = "x509"
+)
+
+// signingCheckMessage is the message of the throwaway commit the pre-flight
+// check signs
+const signingCheckMessage = "TaskWrapper signing check"
+
+// SigningConfig is how a repository's merge commits are signed. Git's own
+// signing settings (gpg.program, gpg.ssh.program and so on) still apply.
+type SigningConfig struct {
+	Format string `json:"format"`        // openpgp, ssh or x509
+	Key    string `json:"key,omitempty"` // key ID, or for ssh a key file or "key::" literal; empty uses git's user.signingkey
+}
+
+// SigningCheck is the result of signing a throwaway commit with a repository's
+// signing configuration
+type SigningCheck struct {
+	Format string `json:"format"`
+	Key    string `json:"key,omitempty"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// validate checks the format and key
+func (sc SigningConfig) validate() error {
+	switch sc.Format {
+	case SigningGPG, SigningSSH, SigningX509:
+	default:
+		return fmt.Errorf("signing format must be openpgp, ssh or x509")
+	}
+	if strings.ContainsAny(sc.Key, "\n\r") {
+		return fmt.Errorf("signing key must be a single line")
+	}
+	return nil
+}
+
+// signingArgs returns the git options that sign the commits of the command
+// they precede; nil leaves signing to git's configuration
+func signingArgs(signing *SigningConfig) []string {
+	if signing == nil {
+		return nil
+	}
+	args := []string{"-c", "gpg.format=" + signing.Format}
+	if signing.Key != "" {
+		args = append(args, "-c", "user.signingkey="+signing.Key)
+	}
+	return args
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Queue policies SimulateCompletion can start tasks by
+const (
+	QueueByPriority = "priority" // highest priority first, as StartAgentsForColumn does
+	QueueByID       = "fifo"     // oldest task first
+)
+
+const (
+	// defaultSimulatedRun is how long a task is assumed to take when there
+	// are no past runs to estimate from
+	defaultSimulatedRun = time.Hour
+	// defaultAgentHoursPerDay is how long agents work a day when the
+	// simulation isn't told: someone has to launch and review them
+	defaultAgentHoursPerDay = 8
+	// maxForecastDays caps a forecast whose queue would take too long to drain
+	maxForecastDays = 365
+	// maxSimulatedFailureRate keeps a history of failures from making the
+	// retries in a forecast endless
+	maxSimulatedFailureRate = 0.9
+)
+
+// SimulationParams are the what-ifs of a completion forecast; zero values
+// use the repository's real settings
+type SimulationParams struct {
+	MaxAgents      int     `json:"maxAgents"`      // agents at once; 0 is the subagent limit
+	HoursPerDay    float64 `json:"hoursPerDay"`    // hours a day agents run; 0 is defaultAgentHoursPerDay
+	Policy         string  `json:"policy"`         // QueueByPriority (default) or QueueByID
+	IncludeBacklog bool    `json:"includeBacklog"` // drain the backlog after todo
+}
+
+// ForecastDay is one day of a completion forecast
+type ForecastDay struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Completed []int  `json:"completed"`
+	Remaining int    `json:"remaining"` // tasks left at the end of the day
+}
+
+// CompletionForecast projects when the queue drains at the simulated
+// throughput
+type CompletionForecast struct {
+	Agents      int           `json:"agents"`
+	HoursPerDay float64       `json:"hoursPerDay"`
+	Policy      string        `json:"policy"`
+	Tasks       int           `json:"tasks"`       // tasks simulated, those already in doing included
+	FailureRate float64       `json:"failureRate"` // of past runs; failed work is assumed to be run again
+	Unestimated []int         `json:"unestimated"` // tasks with no past runs to go by, assumed to take defaultSimulatedRun
+	Blocked     []int         `json:"blocked"`     // tasks that can't start: a dependency is outside the simulation and not done
+	Days        []ForecastDay `json:"days"`
+	DrainDate   string        `json:"drainDate,omitempty"` // the day the last task finishes; empty if it doesn't within maxForecastDays
+}
+
+// simulatedTask is a task holding an agent in the simulation
+type simulatedTask struct {
+	task   Task
+	finish time.Duration // simulated agent time it finishes at, retries included
+}
+
+// simulateCompletion plays the queue through params.MaxAgents agents,
+// starting each task once its dependencies finish, and buckets the
+// finishes by day from now. Tasks in doing are already running; their
+// agents are assumed to be part way through.
+func simulateCompletion(tasks []Task, runs []AgentRun, params SimulationParams, now time.Time) CompletionForecast {
+	forecast := CompletionForecast{
+		Agents:      params.MaxAgents,
+		HoursPerDay: params.HoursPerDay,
+		Policy:      params.Policy,
+		Unestimated: []int{},
+		Blocked:     []int{},
+		Days:        []ForecastDay{},
+	}
+	var history AgentDashboard
+	summarizeAgentRuns(&history, runs)
+	forecast.FailureRate = history.FailureRate
+	retries := 1 / (1 - minFloat(history.FailureRate, maxSimulatedFailureRate))
+
+	duration := func(task Task) time.Duration {
+		estimate := estimateAgentDuration(task, tasks, runs)
+		if estimate.Samples == 0 {
+			forecast.Unestimated = append(forecast.Unestimated, task.ID)
+			return time.Duration(float64(defaultSimulatedRun) * retries)
+		}
+		return time.Duration(estimate.MedianSeconds * retries * float64(time.Second))
+	}
+
+	// Agents already at work finish what's left of their estimate
+	started := map[int]time.Time{}
+	for _, run := range runs {
+		if run.Outcome == RunRunning {
+			started[run.TaskID] = run.Started
+		}
+	}
+	running := []simulatedTask{}
+	queue := []Task{}
+	for _, task := range tasks {
+		if task.Status == StatusDoing {
+			left := duration(task)
+			if since, ok := started[task.ID]; ok {
+				left -= now.Sub(since)
+			}
+			if left < 0 {
+				left = 0
+			}
+			running = append(running, simulatedTask{task: task, finish: left})
+		}
+	}
+	queue = append(queue, queuedTasks(tasks)...)
+	if params.IncludeBacklog {
+		backlog := []Task{}
+		for _, task := range tasks {
+			if task.Status == StatusBacklog {
+				backlog = append(backlog, task)
+			}
+		}
+		queue = append(queue, sortByPriority(backlog)...)
+	}
+	if params.Policy == QueueByID {
+		sort.SliceStable(queue, func(i, j int) bool { return queue[i].ID < queue[j].ID })
+	}
+	forecast.Tasks = len(running) + len(queue)
+
+	// A dependency is satisfied once it is done, or waiting for review, or
+	// finishes in the simulation; one left in a column the simulation
+	// doesn't drain never is
+	simulated := map[int]bool{}
+	for _, entry := range running {
+		simulated[entry.task.ID] = true
+	}
+	for _, task := range queue {
+		simulated[task.ID] = true
+	}
+	finished := map[int]bool{}
+	stuck := map[int]bool{}
+	for _, task := range tasks {
+		if task.Status == StatusDone || task.Status == StatusPendingReview {
+			finished[task.ID] = true
+		} else if !simulated[task.ID] {
+			stuck[task.ID] = true
+		}
+	}
+	ready := func(task Task) (bool, bool) {
+		for _, dep := range task.Deps {
+			if stuck[dep] {
+				return false, true
+			}
+			if simulated[dep] && !finished[dep] {
+				return false, false
+			}
+		}
+		return true, false
+	}
+
+	// Play it out: fill free agents in queue order, then jump to the next
+	// finish
+	finishes := map[int]time.Duration{}
+	var clock time.Duration
+	for {
+		for i := 0; i < len(queue) && len(running) < params.MaxAgents; {
+			ok, blocked := ready(queue[i])
+			switch {
+			case blocked:
+				stuck[queue[i].ID] = true
+				forecast.Blocked = append(forecast.Blocked, queue[i].ID)
+				queue = append(queue[:i], queue[i+1:]...)
+				i = 0 // tasks behind it may now be blocked too
+			case ok:
+				running = append(running, simulatedTask{task: queue[i], finish: clock + duration(queue[i])})
+				queue = append(queue[:i], queue[i+1:]...)
+			default:
+				i++
+			}
+		}
+		if len(running) == 0 {
+			break
+		}
+		next := 0
+		for i := range running {
+			if running[i].finish < running[next].finish {
+				next = i
+			}
+		}
+		clock = running[next].finish
+		finished[running[next].task.ID] = true
+		finishes[running[next].task.ID] = clock
+		running = append(running[:next], running[next+1:]...)
+	}
+	for _, task := range queue {
+		forecast.Blocked = append(forecast.Blocked, task.ID)
+	}
+	sort.Ints(forecast.Blocked)
+	sort.Ints(forecast.Unestimated)
+
+	// Bucket the finishes into working days starting today
+	day := time.Duration(params.HoursPerDay * float64(time.Hour))
+	byDay := map[int][]int{}
+	lastDay := -1
+	for id, at := range finishes {
+		index := int(at / day)
+		if at > 0 && at%day == 0 {
+			index-- // finishing exactly at the end of a day counts for that day
+		}
+		byDay[index] = append(byDay[index], id)
+		if index > lastDay {
+			lastDay = index
+		}
+	}
+	remaining := forecast.Tasks
+	for index := 0; index <= lastDay && index < maxForecastDays; index++ {
+		completed := byDay[index]
+		sort.Ints(completed)
+		if completed == nil {
+			completed = []int{}
+		}
+		remaining -= len(completed)
+		forecast.Days = append(forecast.Days, ForecastDay{
+			Date:      now.AddDate(0, 0, index).Format("2006-01-02"),
+			Completed: completed,
+			Remaining: remaining,
+		})
+	}
+	if lastDay >= 0 && lastDay < maxForecastDays {
+		forecast.DrainDate = now.AddDate(0, 0, lastDay).Format("2006-01-02")
+	}
+	return forecast
+}
+
+// minFloat returns the smaller of a and b
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
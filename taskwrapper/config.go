@@ -1,26 +1,245 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// configVersion is written to new and migrated configs. 1.1.0 switched
+// repository IDs from timestamps to UUIDs.
+const configVersion = "1.1.0"
+
 // Config represents the application configuration
 type Config struct {
 	Version          string       `json:"version"`
 	ActiveRepository string       `json:"activeRepository"`
 	Repositories     []Repository `json:"repositories"`
+	Remote           RemoteConfig `json:"remote"`
+	Editor           string       `json:"editor,omitempty"` // e.g. "code", "idea" or "subl {path}:{line}"
+	QuickAddHotkey   string       `json:"quickAddHotkey,omitempty"` // e.g. "cmdorctrl+shift+space"
+	Logging          LoggingConfig `json:"logging"`
+	Telemetry        TelemetryConfig `json:"telemetry"`
+	Backups          BackupConfig `json:"backups"`
+	Integrity        IntegrityConfig `json:"integrity"`
+	TaskFile         TaskFileConfig `json:"taskFile"`
+	PlanFile         PlanFileConfig `json:"planFile"`
+	Security         SecurityPolicy `json:"security"`
+	SafeMode         bool         `json:"safeMode,omitempty"` // no agents, terminals or git operations
+	Quota            QuotaConfig  `json:"quota"`
+	Redaction        RedactionConfig `json:"redaction"`
+	Automation       AutomationConfig `json:"automation"`
+	Stale            StaleConfig  `json:"stale"`
+	SLA              SLAConfig    `json:"sla"`
+	Worktrees        WorktreeConfig `json:"worktrees"`
+	SandboxAgents    bool         `json:"sandboxAgents,omitempty"` // agents may only write inside their worktree
+	Terminals        *TerminalLayout `json:"terminals,omitempty"` // the shells to reopen with the app
+	RestrictedTerminal RestrictedTerminalConfig `json:"restrictedTerminal"`
+	FeatureFlags map[string]bool `json:"featureFlags,omitempty"` // feature flags set for every repository
+	Locale string `json:"locale,omitempty"` // locale of notifications, reports and prompts; empty is DefaultLocale
+	Timezone string `json:"timezone,omitempty"` // zone times are shown, reported and scheduled in, e.g. Europe/Berlin; empty is the system's
+}
+
+// SecurityPolicy is the user-editable part of SecurityConfig. Empty fields
+// keep the built-in defaults.
+type SecurityPolicy struct {
+	AllowedOrigins  []string `json:"allowedOrigins,omitempty"`  // extra origins allowed to open WebSockets
+	AllowedPaths    []string `json:"allowedPaths,omitempty"`    // repositories and files must be under one of these
+	RestrictedPaths []string `json:"restrictedPaths,omitempty"` // never accessed, even inside an allowed path
+	MaxPathDepth    int      `json:"maxPathDepth,omitempty"`    // most path components a validated path may have
+}
+
+// IntegrityConfig controls corruption detection for task.json
+type IntegrityConfig struct {
+	Checksums bool `json:"checksums"` // keep task.json.sha256 and verify it on load
+}
+
+// TaskFileConfig controls how task.json is written. Tasks are always
+// written sorted by ID so the file diffs cleanly when committed.
+type TaskFileConfig struct {
+	StripVolatile bool `json:"stripVolatile,omitempty"` // leave out empty deps and null parents, which writers disagree on
+}
+
+// PlanFileConfig limits plan.md. Larger documents can still be read in
+// chunks, but not saved or loaded whole.
+type PlanFileConfig struct {
+	MaxSizeKB int `json:"maxSizeKB,omitempty"` // 0 keeps the built-in limit of 5 MB
+}
+
+// BackupConfig controls where automatic backups are kept
+type BackupConfig struct {
+	Dir string `json:"dir,omitempty"` // base directory; defaults to ~/.local/share/taskwrapper
+}
+
+// QuotaConfig caps agent launches. Zero means no limit.
+type QuotaConfig struct {
+	HourlyLaunches     int `json:"hourlyLaunches,omitempty"`     // across all repositories
+	DailyLaunches      int `json:"dailyLaunches,omitempty"`      // across all repositories
+	RepoHourlyLaunches int `json:"repoHourlyLaunches,omitempty"` // in any one repository
+	RepoDailyLaunches  int `json:"repoDailyLaunches,omitempty"`  // in any one repository
+}
+
+// RedactionConfig controls which secrets are masked in logs and output buffers
+type RedactionConfig struct {
+	Patterns        []string `json:"patterns,omitempty"`        // extra regular expressions; a capturing group limits what's masked
+	DisableDefaults bool     `json:"disableDefaults,omitempty"` // turn off the built-in token patterns
+}
+
+// AutomationConfig holds the board automation rules
+type AutomationConfig struct {
+	Rules []AutomationRule `json:"rules,omitempty"`
+}
+
+// StaleConfig controls when tasks are reported as needing attention
+type StaleConfig struct {
+	Thresholds map[string]int `json:"thresholds,omitempty"` // status -> days; unset statuses keep the defaults
+	Notify     bool           `json:"notify,omitempty"`     // tell the frontend when a task goes stale
+}
+
+// SLAConfig holds target turnaround times per priority, from entering todo
+// to done
+type SLAConfig struct {
+	Targets map[string]string `json:"targets,omitempty"` // priority -> duration, e.g. "high": "2d"
+}
+
+// WorktreeConfig keeps agent worktrees within a disk budget and a few idle
+// ones ready ahead of launches
+type WorktreeConfig struct {
+	MaxTotalGB float64 `json:"maxTotalGB,omitempty"` // 0 means no limit
+	// WarmPool is how many idle worktrees to keep checked out on main so a
+	// launch doesn't wait for a checkout; 0 turns it off. The disk budget
+	// wins: nothing is added while the pool is over it.
+	WarmPool int `json:"warmPool,omitempty"`
+}
+
+// AutomationRule runs its actions when its trigger fires and every condition holds
+type AutomationRule struct {
+	Name       string          `json:"name"`
+	Disabled   bool            `json:"disabled,omitempty"`
+	Trigger    RuleTrigger     `json:"trigger"`
+	Conditions []RuleCondition `json:"conditions,omitempty"`
+	Actions    []RuleAction    `json:"actions"`
+}
+
+// RuleTrigger is what starts a rule: a journal event such as "task.moved",
+// or "task.stale" for a task left in Status for After
+type RuleTrigger struct {
+	Event  string `json:"event"`
+	Status string `json:"status,omitempty"` // task.moved: the status entered; task.stale: the status stuck in
+	After  string `json:"after,omitempty"`  // task.stale: e.g. "72h" or "3d"
+}
+
+// RuleCondition must hold for a rule to run. A field condition compares a
+// task field ("id", "title", "status", "priority") or "data.<key>" from the
+// triggering event; a command condition runs in the task's worktree and
+// holds if it exits 0 (e.g. "make test").
+type RuleCondition struct {
+	Field     string `json:"field,omitempty"`
+	Equals    string `json:"equals,omitempty"`
+	NotEquals string `json:"notEquals,omitempty"`
+	Command   string `json:"command,omitempty"`
+}
+
+// RuleAction is something a rule does. Message is a text/template over
+// .Rule and .Task.
+type RuleAction struct {
+	Type     string `json:"type"`               // "webhook", "notify" or "set_priority"
+	URL      string `json:"url,omitempty"`      // webhook: receives {"text": message}, as Slack incoming webhooks expect
+	Message  string `json:"message,omitempty"`  // webhook and notify
+	Priority string `json:"priority,omitempty"` // set_priority
+}
+
+// TelemetryConfig controls local usage metrics; nothing is sent anywhere
+type TelemetryConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
+// LoggingConfig controls log level, output format, rotation and retention
+type LoggingConfig struct {
+	Level         string `json:"level,omitempty"`         // debug, info, warn, error
+	Format        string `json:"format,omitempty"`        // text (default) or json
+	MaxSizeMB     int    `json:"maxSizeMB,omitempty"`     // rotate a day's file past this size
+	MaxBackups    int    `json:"maxBackups,omitempty"`    // rotated files kept per day
+	RetentionDays int    `json:"retentionDays,omitempty"` // delete log files older than this
+	Compress bool `json:"compress,omitempty"` // gzip log files from earlier days
+	// Dir moves the app's logs out of the repository, into a folder per
+	// repository under it; empty keeps them in the repository's logs folder
+	Dir string `json:"dir,omitempty"`
+}
+
+// Remote access roles
+const (
+	RemoteRoleReadOnly = "read-only"
+	RemoteRoleFull     = "full"
+)
+
+// RemoteConfig holds settings for the --serve companion web UI
+type RemoteConfig struct {
+	Addr   string        `json:"addr,omitempty"`
+	Tokens []RemoteToken `json:"tokens,omitempty"`
+	Pprof  bool          `json:"pprof,omitempty"` // serve net/http/pprof under /debug/pprof/ to full-access tokens
+	// API is where the desktop app serves the REST API for scripts and CI,
+	// such as "127.0.0.1:8091"; empty leaves it off. --serve always has it.
+	API string `json:"api,omitempty"`
+}
+
+// RemoteToken grants a role to a bearer token
+type RemoteToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Role  string `json:"role"`
 }
 
 // Repository represents a single repository configuration
 type Repository struct {
-	ID      string    `json:"id"`
-	Name    string    `json:"name"`
-	Path    string    `json:"path"`
-	AddedAt time.Time `json:"addedAt"`
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	AddedAt   time.Time   `json:"addedAt"`
+	Sync      *SyncConfig `json:"sync,omitempty"`      // remote the plan directory syncs with
+	Confirmed []string    `json:"confirmed,omitempty"` // first-time actions the user has allowed here
+	// ScriptChecksums pins helper scripts (name -> SHA-256) the app may run
+	// here. It lives in the user's config so a commit can't change it.
+	ScriptChecksums map[string]string `json:"scriptChecksums,omitempty"`
+	MainlineSync    string            `json:"mainlineSync,omitempty"`    // how main is updated before a spawn; empty uses local main
+	MainlineBranch  string            `json:"mainlineBranch,omitempty"`  // branch agents start from and approvals merge into; empty is main
+	ApprovalMode    string            `json:"approvalMode,omitempty"`    // where approvals merge, one of the Approve modes
+	Signing         *SigningConfig    `json:"signing,omitempty"`         // how the app signs merge commits; nil leaves it to git
+	ChangelogCommit bool              `json:"changelogCommit,omitempty"` // GenerateChangelog commits CHANGELOG.md
+	ReviewChecks    []string          `json:"reviewChecks,omitempty"`    // commands run in an agent's worktree when it hands a task over for review
+	FeatureFlags    map[string]bool   `json:"featureFlags,omitempty"`    // feature flags set for this repository, over the global ones
+	// AgentEnv and TaskEnv are variables injected into agents launched here,
+	// TaskEnv's only into that task's runs. A "secret:NAME" value is read
+	// from the keychain at launch; AgentSecrets lists the names stored.
+	AgentEnv     map[string]string         `json:"agentEnv,omitempty"`
+	TaskEnv      map[int]map[string]string `json:"taskEnv,omitempty"`
+	AgentSecrets []string                  `json:"agentSecrets,omitempty"`
+	Triage       *TriageConfig             `json:"triage,omitempty"` // default priority and tag routing for new tasks
+	Spawner      string                    `json:"spawner,omitempty"`   // how agents are launched, one of the Spawner modes
+	MaxAgents    int                       `json:"maxAgents,omitempty"` // agents running at once; 0 is the spawner's default
+	AgentHours   *ScheduleWindow           `json:"agentHours,omitempty"` // when auto-pilot launches agents; nil is any time
+}
+
+// Actions that need confirming the first time they happen in a repository
+const (
+	ConfirmAgentSpawn = "agent_spawn"
+	ConfirmMerge      = "merge"
+)
+
+// HasConfirmed reports whether the user has allowed action in this repository
+func (r Repository) HasConfirmed(action string) bool {
+	for _, confirmed := range r.Confirmed {
+		if confirmed == action {
+			return true
+		}
+	}
+	return false
 }
 
 // ConfigManager handles loading and saving configuration
@@ -28,6 +247,7 @@ type ConfigManager struct {
 	configPath string
 	config     *Config
 	repoUtils  *RepositoryUtils
+	readOnly   bool // refuse saves, for an instance that doesn't own the config
 }
 
 // NewConfigManager creates a new configuration manager
@@ -76,6 +296,52 @@ func getConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// getDataDir returns the directory for application data such as backups,
+// following XDG_DATA_HOME when set
+func getDataDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "taskwrapper"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "share", "taskwrapper"), nil
+}
+
+// repositoryBackupDir returns where a repository's backups are kept:
+// <baseDir>/<repo-id>/backups. Repositories without a configured ID (fallback
+// mode) are keyed by a hash of their path. Returns "" if no base directory
+// is available, which keeps backups next to the files.
+func repositoryBackupDir(baseDir string, repo Repository) string {
+	if baseDir == "" {
+		dataDir, err := getDataDir()
+		if err != nil {
+			return ""
+		}
+		baseDir = dataDir
+	}
+	return filepath.Join(baseDir, repositoryKey(repo), "backups")
+}
+
+// repositoryLogDir returns where the app's logs for repo are written: the
+// repository's logs folder, or a folder per repository under baseDir
+func repositoryLogDir(baseDir string, repo Repository) string {
+	if baseDir == "" {
+		return getLogDirectory(repo.Path)
+	}
+	return filepath.Join(baseDir, repositoryKey(repo), "logs")
+}
+
+// repositoryKey names repo's folder in directories shared by repositories
+func repositoryKey(repo Repository) string {
+	if repo.ID != "" {
+		return repo.ID
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(repo.Path)))
+	return hex.EncodeToString(sum[:8])
+}
+
 // Load reads the configuration from disk
 func (cm *ConfigManager) Load() error {
 	data, err := os.ReadFile(cm.configPath)
@@ -89,11 +355,40 @@ func (cm *ConfigManager) Load() error {
 	}
 	
 	cm.config = &config
+	
+	if cm.migrate() {
+		if err := cm.Save(); err != nil {
+			return fmt.Errorf("failed to save migrated config: %v", err)
+		}
+	}
 	return nil
 }
 
+// migrate upgrades configs written by older versions, returning true if
+// anything changed. Timestamp-based repository IDs, which could collide when
+// several repositories were added at once, are replaced with UUIDs.
+func (cm *ConfigManager) migrate() bool {
+	changed := false
+	seen := make(map[string]bool, len(cm.config.Repositories))
+	for i, repo := range cm.config.Repositories {
+		if _, err := uuid.Parse(repo.ID); err != nil || seen[repo.ID] {
+			cm.config.Repositories[i].ID = generateID()
+			changed = true
+		}
+		seen[cm.config.Repositories[i].ID] = true
+	}
+	if cm.config.Version != configVersion {
+		cm.config.Version = configVersion
+		changed = true
+	}
+	return changed
+}
+
 // Save writes the configuration to disk
 func (cm *ConfigManager) Save() error {
+	if cm.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
 	data, err := json.MarshalIndent(cm.config, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
@@ -120,7 +415,7 @@ func (cm *ConfigManager) createDefaultConfig() *Config {
 	currentRepo := cm.detectCurrentRepository()
 	
 	config := &Config{
-		Version:          "1.0.0",
+		Version:          configVersion,
 		ActiveRepository: currentRepo.Path,
 		Repositories:     []Repository{currentRepo},
 	}
@@ -144,7 +439,7 @@ func (cm *ConfigManager) detectCurrentRepository() Repository {
 			ID:      generateID(),
 			Name:    GetRepositoryName(repoPath),
 			Path:    repoPath,
-			AddedAt: time.Now(),
+			AddedAt: time.Now().UTC(),
 		}
 	}
 	
@@ -156,7 +451,7 @@ func (cm *ConfigManager) detectCurrentRepository() Repository {
 		ID:      generateID(),
 		Name:    "No Repository",
 		Path:    fallbackPath,
-		AddedAt: time.Now(),
+		AddedAt: time.Now().UTC(),
 	}
 }
 
@@ -221,7 +516,7 @@ func (cm *ConfigManager) AddRepository(name, path string) (*Repository, error) {
 		ID:      generateID(),
 		Name:    name,
 		Path:    path,
-		AddedAt: time.Now(),
+		AddedAt: time.Now().UTC(),
 	}
 	
 	cm.config.Repositories = append(cm.config.Repositories, repo)
@@ -291,6 +586,327 @@ func (cm *ConfigManager) SetActiveRepository(id string) error {
 	return cm.Save()
 }
 
+// SetEditor sets the editor command used for deep links
+func (cm *ConfigManager) SetEditor(command string) error {
+	cm.config.Editor = command
+	return cm.Save()
+}
+
+// SetLogLevel sets the persisted log level
+func (cm *ConfigManager) SetLogLevel(level string) error {
+	cm.config.Logging.Level = level
+	return cm.Save()
+}
+
+// SetLoggingConfig replaces the logging settings
+func (cm *ConfigManager) SetLoggingConfig(logging LoggingConfig) error {
+	cm.config.Logging = logging
+	return cm.Save()
+}
+
+// SetTelemetryEnabled opts in to or out of local usage metrics
+func (cm *ConfigManager) SetTelemetryEnabled(enabled bool) error {
+	cm.config.Telemetry.Enabled = enabled
+	return cm.Save()
+}
+
+// SetBackupDir sets the base directory for automatic backups
+func (cm *ConfigManager) SetBackupDir(dir string) error {
+	cm.config.Backups.Dir = dir
+	return cm.Save()
+}
+
+// SetSecurityPolicy replaces the user's security policy
+func (cm *ConfigManager) SetSecurityPolicy(policy SecurityPolicy) error {
+	cm.config.Security = policy
+	return cm.Save()
+}
+
+// SetQuotaConfig replaces the agent launch limits
+func (cm *ConfigManager) SetQuotaConfig(quota QuotaConfig) error {
+	cm.config.Quota = quota
+	return cm.Save()
+}
+
+// SetRedactionConfig replaces the secret patterns
+func (cm *ConfigManager) SetRedactionConfig(redaction RedactionConfig) error {
+	cm.config.Redaction = redaction
+	return cm.Save()
+}
+
+// SetAutomationConfig replaces the automation rules
+func (cm *ConfigManager) SetAutomationConfig(automation AutomationConfig) error {
+	cm.config.Automation = automation
+	return cm.Save()
+}
+
+// SetStaleConfig replaces the stale task thresholds
+func (cm *ConfigManager) SetStaleConfig(stale StaleConfig) error {
+	cm.config.Stale = stale
+	return cm.Save()
+}
+
+// SetSLAConfig replaces the SLA targets
+func (cm *ConfigManager) SetSLAConfig(sla SLAConfig) error {
+	cm.config.SLA = sla
+	return cm.Save()
+}
+
+// SetWorktreeConfig replaces the worktree disk budget and warm pool size
+func (cm *ConfigManager) SetWorktreeConfig(worktrees WorktreeConfig) error {
+	cm.config.Worktrees = worktrees
+	return cm.Save()
+}
+
+// SetPlanFileConfig replaces the plan.md size limit
+func (cm *ConfigManager) SetPlanFileConfig(plan PlanFileConfig) error {
+	cm.config.PlanFile = plan
+	return cm.Save()
+}
+
+// SetRestrictedTerminal replaces the restricted terminal mode and allowlist
+func (cm *ConfigManager) SetRestrictedTerminal(restricted RestrictedTerminalConfig) error {
+	cm.config.RestrictedTerminal = restricted
+	return cm.Save()
+}
+
+// SetTerminalLayout replaces the saved terminal layout
+func (cm *ConfigManager) SetTerminalLayout(layout TerminalLayout) error {
+	cm.config.Terminals = &layout
+	return cm.Save()
+}
+
+// SetSandboxAgents turns agent write confinement on or off
+func (cm *ConfigManager) SetSandboxAgents(enabled bool) error {
+	cm.config.SandboxAgents = enabled
+	return cm.Save()
+}
+
+// SetSafeMode turns safe mode on or off
+func (cm *ConfigManager) SetSafeMode(enabled bool) error {
+	cm.config.SafeMode = enabled
+	return cm.Save()
+}
+
+// ConfirmRepositoryAction remembers that the user allowed action in a repository
+func (cm *ConfigManager) ConfirmRepositoryAction(id, action string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			if cm.config.Repositories[i].HasConfirmed(action) {
+				return nil
+			}
+			cm.config.Repositories[i].Confirmed = append(cm.config.Repositories[i].Confirmed, action)
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryScriptChecksums pins, or with nil unpins, a repository's helper scripts
+func (cm *ConfigManager) SetRepositoryScriptChecksums(id string, pins map[string]string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].ScriptChecksums = pins
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositorySync sets or, with nil, clears a repository's sync remote
+func (cm *ConfigManager) SetRepositorySync(id string, sync *SyncConfig) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].Sync = sync
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryMainlineSync sets how a repository's main is updated before a spawn
+func (cm *ConfigManager) SetRepositoryMainlineSync(id, mode string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].MainlineSync = mode
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryMainlineBranch sets the branch a repository's agents start
+// from and its approvals merge into
+func (cm *ConfigManager) SetRepositoryMainlineBranch(id, branch string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].MainlineBranch = branch
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryApprovalMode sets where approving a repository's tasks
+// merges their branches
+func (cm *ConfigManager) SetRepositoryApprovalMode(id, mode string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].ApprovalMode = mode
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetFeatureFlag sets or, with nil, clears a feature flag for every
+// repository
+func (cm *ConfigManager) SetFeatureFlag(name string, enabled *bool) error {
+	cm.config.FeatureFlags = setFeatureFlag(cm.config.FeatureFlags, name, enabled)
+	return cm.Save()
+}
+
+// SetLocale sets the locale of backend-generated strings
+func (cm *ConfigManager) SetLocale(locale string) error {
+	cm.config.Locale = locale
+	return cm.Save()
+}
+
+// SetTimezone sets the zone times are shown, reported and scheduled in
+func (cm *ConfigManager) SetTimezone(timezone string) error {
+	cm.config.Timezone = timezone
+	return cm.Save()
+}
+
+// SetRepositoryAgentHours sets when auto-pilot may launch a repository's
+// agents; nil is any time
+func (cm *ConfigManager) SetRepositoryAgentHours(id string, hours *ScheduleWindow) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].AgentHours = hours
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryFeatureFlag sets or, with nil, clears a feature flag for
+// one repository
+func (cm *ConfigManager) SetRepositoryFeatureFlag(id, name string, enabled *bool) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].FeatureFlags = setFeatureFlag(cm.config.Repositories[i].FeatureFlags, name, enabled)
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryAgentEnv sets the variables injected into a repository's
+// agents, or with taskID into one task's; an empty env clears them
+func (cm *ConfigManager) SetRepositoryAgentEnv(id string, taskID int, env map[string]string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID != id {
+			continue
+		}
+		repo := &cm.config.Repositories[i]
+		if len(env) == 0 {
+			env = nil
+		}
+		if taskID == 0 {
+			repo.AgentEnv = env
+		} else if env == nil {
+			delete(repo.TaskEnv, taskID)
+			if len(repo.TaskEnv) == 0 {
+				repo.TaskEnv = nil
+			}
+		} else {
+			if repo.TaskEnv == nil {
+				repo.TaskEnv = map[int]map[string]string{}
+			}
+			repo.TaskEnv[taskID] = env
+		}
+		return cm.Save()
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryAgentSecrets sets the names of a repository's agent secrets
+func (cm *ConfigManager) SetRepositoryAgentSecrets(id string, names []string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].AgentSecrets = names
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositorySigning sets or, with nil, clears how a repository's merge
+// commits are signed
+func (cm *ConfigManager) SetRepositorySigning(id string, signing *SigningConfig) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].Signing = signing
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryChangelogCommit sets whether a repository's generated
+// changelog is committed
+func (cm *ConfigManager) SetRepositoryChangelogCommit(id string, commit bool) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].ChangelogCommit = commit
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryReviewChecks sets the commands run when an agent hands a task over for review
+func (cm *ConfigManager) SetRepositoryReviewChecks(id string, checks []string) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].ReviewChecks = checks
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositoryTriage sets how new tasks are triaged in a repository
+func (cm *ConfigManager) SetRepositoryTriage(id string, triage TriageConfig) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].Triage = &triage
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetRepositorySpawner sets how a repository's agents are launched and how
+// many run at once
+func (cm *ConfigManager) SetRepositorySpawner(id, mode string, maxAgents int) error {
+	for i := range cm.config.Repositories {
+		if cm.config.Repositories[i].ID == id {
+			cm.config.Repositories[i].Spawner = mode
+			cm.config.Repositories[i].MaxAgents = maxAgents
+			return cm.Save()
+		}
+	}
+	return fmt.Errorf("repository not found")
+}
+
+// SetQuickAddHotkey sets the global quick-add hotkey
+func (cm *ConfigManager) SetQuickAddHotkey(spec string) error {
+	cm.config.QuickAddHotkey = spec
+	return cm.Save()
+}
+
 // validateRepositoryPath validates that a path contains a valid task dashboard repository
 func validateRepositoryPath(path string) error {
 	// Check if path exists
@@ -307,8 +923,8 @@ func validateRepositoryPath(path string) error {
 	return nil
 }
 
-// generateID generates a unique ID for a repository
+// generateID generates a unique ID for repositories, jobs and terminals
 func generateID() string {
-	return fmt.Sprintf("%d", time.Now().UnixNano())
+	return uuid.New().String()
 }
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"sync"
 )
@@ -205,6 +206,11 @@ func (cs *ConfigService) ValidateRepositoryPath(path string) (*RepositoryInfo, e
 
 // FindRepositories searches for repositories in a directory
 func (cs *ConfigService) FindRepositories(searchPath string) ([]Repository, error) {
+	return cs.FindRepositoriesContext(context.Background(), searchPath)
+}
+
+// FindRepositoriesContext searches for repositories, stopping early if ctx is cancelled
+func (cs *ConfigService) FindRepositoriesContext(ctx context.Context, searchPath string) ([]Repository, error) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	
@@ -212,7 +218,7 @@ func (cs *ConfigService) FindRepositories(searchPath string) ([]Repository, erro
 		"search_path": searchPath,
 	})
 	
-	repos, err := FindRepositoriesInDirectory(searchPath)
+	repos, err := FindRepositoriesInDirectoryContext(ctx, searchPath)
 	if err != nil {
 		cs.logger.ErrorWithFields("Failed to find repositories", err, map[string]interface{}{
 			"search_path": searchPath,
@@ -257,4 +263,918 @@ func (cs *ConfigService) GetConfigPath() (string, error) {
 	}
 	
 	return cs.configManager.configPath, nil
-}
\ No newline at end of file
+}
+// GetRemoteConfig returns the companion web UI settings
+func (cs *ConfigService) GetRemoteConfig() RemoteConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return RemoteConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Remote
+}
+
+// GetEditor returns the configured editor command, if any
+func (cs *ConfigService) GetEditor() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return ""
+	}
+	
+	return cs.configManager.GetConfig().Editor
+}
+
+// SetEditor updates the editor command used for deep links
+func (cs *ConfigService) SetEditor(command string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetEditor(command); err != nil {
+		cs.logger.Error("Failed to save editor setting", err)
+		return err
+	}
+	
+	cs.logger.InfoWithFields("Editor command updated", map[string]interface{}{
+		"editor": command,
+	})
+	
+	return nil
+}
+
+// GetLoggingConfig returns the logging settings
+func (cs *ConfigService) GetLoggingConfig() LoggingConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return LoggingConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Logging
+}
+
+// SetLogLevel persists the minimum log level
+func (cs *ConfigService) SetLogLevel(level string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetLogLevel(level); err != nil {
+		cs.logger.Error("Failed to save log level", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetLoggingConfig persists the logging settings
+func (cs *ConfigService) SetLoggingConfig(logging LoggingConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetLoggingConfig(logging); err != nil {
+		cs.logger.Error("Failed to save logging settings", err)
+		return err
+	}
+	
+	return nil
+}
+
+// GetTelemetryConfig returns the usage metrics settings
+func (cs *ConfigService) GetTelemetryConfig() TelemetryConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return TelemetryConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Telemetry
+}
+
+// SetTelemetryEnabled persists the usage metrics opt-in
+func (cs *ConfigService) SetTelemetryEnabled(enabled bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetTelemetryEnabled(enabled); err != nil {
+		cs.logger.Error("Failed to save telemetry setting", err)
+		return err
+	}
+	
+	cs.logger.InfoWithFields("Telemetry setting updated", map[string]interface{}{
+		"enabled": enabled,
+	})
+	
+	return nil
+}
+
+// GetQuickAddHotkey returns the configured quick-add hotkey, if any
+func (cs *ConfigService) GetQuickAddHotkey() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return ""
+	}
+	
+	return cs.configManager.GetConfig().QuickAddHotkey
+}
+
+// GetBackupConfig returns where automatic backups are kept
+func (cs *ConfigService) GetBackupConfig() BackupConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return BackupConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Backups
+}
+
+// GetSecurityPolicy returns the user's security policy as stored
+func (cs *ConfigService) GetSecurityPolicy() SecurityPolicy {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return SecurityPolicy{}
+	}
+	
+	return cs.configManager.GetConfig().Security
+}
+
+// GetIntegrityConfig returns the task file integrity settings
+func (cs *ConfigService) GetIntegrityConfig() IntegrityConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return IntegrityConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Integrity
+}
+
+// GetTaskFileConfig returns how task.json is written
+func (cs *ConfigService) GetTaskFileConfig() TaskFileConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return TaskFileConfig{}
+	}
+	
+	return cs.configManager.GetConfig().TaskFile
+}
+
+// GetPlanFileConfig returns the plan.md size limit
+func (cs *ConfigService) GetPlanFileConfig() PlanFileConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return PlanFileConfig{}
+	}
+	
+	return cs.configManager.GetConfig().PlanFile
+}
+
+// GetQuotaConfig returns the agent launch limits
+func (cs *ConfigService) GetQuotaConfig() QuotaConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return QuotaConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Quota
+}
+
+// GetRedactionConfig returns the secret patterns
+func (cs *ConfigService) GetRedactionConfig() RedactionConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return RedactionConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Redaction
+}
+
+// GetAutomationConfig returns the board automation rules
+func (cs *ConfigService) GetAutomationConfig() AutomationConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return AutomationConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Automation
+}
+
+// GetStaleConfig returns the stale task thresholds
+func (cs *ConfigService) GetStaleConfig() StaleConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return StaleConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Stale
+}
+
+// GetSLAConfig returns the SLA targets
+func (cs *ConfigService) GetSLAConfig() SLAConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return SLAConfig{}
+	}
+	
+	return cs.configManager.GetConfig().SLA
+}
+
+// GetWorktreeConfig returns the worktree disk budget and warm pool size
+func (cs *ConfigService) GetWorktreeConfig() WorktreeConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return WorktreeConfig{}
+	}
+	
+	return cs.configManager.GetConfig().Worktrees
+}
+
+// GetRestrictedTerminal returns the restricted terminal mode and allowlist
+func (cs *ConfigService) GetRestrictedTerminal() RestrictedTerminalConfig {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return RestrictedTerminalConfig{}
+	}
+	
+	return cs.configManager.GetConfig().RestrictedTerminal
+}
+
+// GetTerminalLayout returns the saved terminal layout, empty if none was saved
+func (cs *ConfigService) GetTerminalLayout() TerminalLayout {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil || cs.configManager.GetConfig().Terminals == nil {
+		return TerminalLayout{Split: TerminalTabs, Panes: []TerminalPane{}}
+	}
+	
+	return *cs.configManager.GetConfig().Terminals
+}
+
+// GetSandboxAgents reports whether agents are confined to their worktrees
+func (cs *ConfigService) GetSandboxAgents() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return false
+	}
+	
+	return cs.configManager.GetConfig().SandboxAgents
+}
+
+// GetSafeMode reports whether safe mode is saved as on
+func (cs *ConfigService) GetSafeMode() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return false
+	}
+	
+	return cs.configManager.GetConfig().SafeMode
+}
+
+// SetBackupDir persists the base directory for automatic backups
+func (cs *ConfigService) SetBackupDir(dir string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetBackupDir(dir); err != nil {
+		cs.logger.Error("Failed to save backup directory", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetSecurityPolicy persists the user's security policy
+func (cs *ConfigService) SetSecurityPolicy(policy SecurityPolicy) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetSecurityPolicy(policy); err != nil {
+		cs.logger.Error("Failed to save security policy", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetQuotaConfig persists the agent launch limits
+func (cs *ConfigService) SetQuotaConfig(quota QuotaConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetQuotaConfig(quota); err != nil {
+		cs.logger.Error("Failed to save launch quota", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRedactionConfig persists the secret patterns
+func (cs *ConfigService) SetRedactionConfig(redaction RedactionConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRedactionConfig(redaction); err != nil {
+		cs.logger.Error("Failed to save redaction patterns", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetAutomationConfig persists the board automation rules
+func (cs *ConfigService) SetAutomationConfig(automation AutomationConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetAutomationConfig(automation); err != nil {
+		cs.logger.Error("Failed to save automation rules", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetStaleConfig persists the stale task thresholds
+func (cs *ConfigService) SetStaleConfig(stale StaleConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetStaleConfig(stale); err != nil {
+		cs.logger.Error("Failed to save stale task thresholds", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetSLAConfig persists the SLA targets
+func (cs *ConfigService) SetSLAConfig(sla SLAConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetSLAConfig(sla); err != nil {
+		cs.logger.Error("Failed to save SLA targets", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetWorktreeConfig persists the worktree disk budget and warm pool size
+func (cs *ConfigService) SetWorktreeConfig(worktrees WorktreeConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetWorktreeConfig(worktrees); err != nil {
+		cs.logger.Error("Failed to save worktree settings", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetPlanFileConfig persists the plan.md size limit
+func (cs *ConfigService) SetPlanFileConfig(plan PlanFileConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetPlanFileConfig(plan); err != nil {
+		cs.logger.Error("Failed to save plan file settings", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRestrictedTerminal persists the restricted terminal mode and allowlist
+func (cs *ConfigService) SetRestrictedTerminal(restricted RestrictedTerminalConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRestrictedTerminal(restricted); err != nil {
+		cs.logger.Error("Failed to save restricted terminal config", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetTerminalLayout persists the terminal layout
+func (cs *ConfigService) SetTerminalLayout(layout TerminalLayout) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetTerminalLayout(layout); err != nil {
+		cs.logger.Error("Failed to save terminal layout", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetSandboxAgents persists whether agents are confined to their worktrees
+func (cs *ConfigService) SetSandboxAgents(enabled bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetSandboxAgents(enabled); err != nil {
+		cs.logger.Error("Failed to save agent sandbox setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetReadOnly makes every configuration change fail to save
+func (cs *ConfigService) SetReadOnly(readOnly bool) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager != nil {
+		cs.configManager.readOnly = readOnly
+	}
+}
+
+// SetSafeMode persists whether safe mode is on
+func (cs *ConfigService) SetSafeMode(enabled bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetSafeMode(enabled); err != nil {
+		cs.logger.Error("Failed to save safe mode", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositorySync persists a repository's sync remote
+func (cs *ConfigService) SetRepositorySync(id string, sync *SyncConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositorySync(id, sync); err != nil {
+		cs.logger.Error("Failed to save sync settings", err)
+		return err
+	}
+	
+	return nil
+}
+
+// ConfirmRepositoryAction persists the user's go-ahead for action in a repository
+func (cs *ConfigService) ConfirmRepositoryAction(id, action string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.ConfirmRepositoryAction(id, action); err != nil {
+		cs.logger.ErrorWithFields("Failed to save confirmation", err, map[string]interface{}{
+			"id":     id,
+			"action": action,
+		})
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryScriptChecksums persists a repository's pinned helper scripts
+func (cs *ConfigService) SetRepositoryScriptChecksums(id string, pins map[string]string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryScriptChecksums(id, pins); err != nil {
+		cs.logger.Error("Failed to save helper script checksums", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryMainlineSync persists how a repository's main is updated before a spawn
+func (cs *ConfigService) SetRepositoryMainlineSync(id, mode string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryMainlineSync(id, mode); err != nil {
+		cs.logger.Error("Failed to save mainline sync setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryMainlineBranch persists the branch a repository's agents
+// start from and its approvals merge into
+func (cs *ConfigService) SetRepositoryMainlineBranch(id, branch string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryMainlineBranch(id, branch); err != nil {
+		cs.logger.Error("Failed to save mainline branch setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryApprovalMode persists where approving a repository's tasks
+// merges their branches
+func (cs *ConfigService) SetRepositoryApprovalMode(id, mode string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryApprovalMode(id, mode); err != nil {
+		cs.logger.Error("Failed to save approval mode setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// GetFeatureFlags returns the feature flags set for every repository
+func (cs *ConfigService) GetFeatureFlags() map[string]bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return nil
+	}
+	
+	return cs.configManager.GetConfig().FeatureFlags
+}
+
+// SetFeatureFlag persists a feature flag for every repository
+func (cs *ConfigService) SetFeatureFlag(name string, enabled *bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetFeatureFlag(name, enabled); err != nil {
+		cs.logger.Error("Failed to save feature flag", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryFeatureFlag persists a feature flag for one repository
+func (cs *ConfigService) SetRepositoryFeatureFlag(id, name string, enabled *bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryFeatureFlag(id, name, enabled); err != nil {
+		cs.logger.Error("Failed to save repository feature flag", err)
+		return err
+	}
+	
+	return nil
+}
+
+// GetLocale returns the locale of backend-generated strings
+func (cs *ConfigService) GetLocale() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return ""
+	}
+	
+	return cs.configManager.GetConfig().Locale
+}
+
+// SetLocale persists the locale of backend-generated strings
+func (cs *ConfigService) SetLocale(locale string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetLocale(locale); err != nil {
+		cs.logger.Error("Failed to save locale", err)
+		return err
+	}
+	
+	return nil
+}
+
+// GetTimezone returns the zone times are shown, reported and scheduled in
+func (cs *ConfigService) GetTimezone() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	
+	if cs.configManager == nil || cs.configManager.GetConfig() == nil {
+		return ""
+	}
+	
+	return cs.configManager.GetConfig().Timezone
+}
+
+// SetTimezone persists the zone times are shown, reported and scheduled in
+func (cs *ConfigService) SetTimezone(timezone string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetTimezone(timezone); err != nil {
+		cs.logger.Error("Failed to save time zone", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryAgentHours persists when auto-pilot may launch a
+// repository's agents
+func (cs *ConfigService) SetRepositoryAgentHours(id string, hours *ScheduleWindow) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryAgentHours(id, hours); err != nil {
+		cs.logger.Error("Failed to save agent hours", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryAgentEnv persists the variables injected into a repository's
+// agents, or into one task's
+func (cs *ConfigService) SetRepositoryAgentEnv(id string, taskID int, env map[string]string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryAgentEnv(id, taskID, env); err != nil {
+		cs.logger.Error("Failed to save agent environment", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryAgentSecrets persists the names of a repository's agent secrets
+func (cs *ConfigService) SetRepositoryAgentSecrets(id string, names []string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryAgentSecrets(id, names); err != nil {
+		cs.logger.Error("Failed to save agent secret names", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositorySigning persists how a repository's merge commits are signed
+func (cs *ConfigService) SetRepositorySigning(id string, signing *SigningConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositorySigning(id, signing); err != nil {
+		cs.logger.Error("Failed to save commit signing setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryChangelogCommit persists whether a repository's generated
+// changelog is committed
+func (cs *ConfigService) SetRepositoryChangelogCommit(id string, commit bool) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryChangelogCommit(id, commit); err != nil {
+		cs.logger.Error("Failed to save changelog commit setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryReviewChecks persists the commands run when an agent hands a task over for review
+func (cs *ConfigService) SetRepositoryReviewChecks(id string, checks []string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryReviewChecks(id, checks); err != nil {
+		cs.logger.Error("Failed to save review checks", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositoryTriage persists how new tasks are triaged in a repository
+func (cs *ConfigService) SetRepositoryTriage(id string, triage TriageConfig) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositoryTriage(id, triage); err != nil {
+		cs.logger.Error("Failed to save triage rules", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetRepositorySpawner persists how a repository's agents are launched and
+// how many run at once
+func (cs *ConfigService) SetRepositorySpawner(id, mode string, maxAgents int) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetRepositorySpawner(id, mode, maxAgents); err != nil {
+		cs.logger.Error("Failed to save spawner setting", err)
+		return err
+	}
+	
+	return nil
+}
+
+// SetQuickAddHotkey updates the global quick-add hotkey
+func (cs *ConfigService) SetQuickAddHotkey(spec string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	
+	if cs.configManager == nil {
+		return fmt.Errorf("configuration not initialized")
+	}
+	
+	if err := cs.configManager.SetQuickAddHotkey(spec); err != nil {
+		cs.logger.Error("Failed to save quick-add hotkey", err)
+		return err
+	}
+	
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import "sort"
+
+// CriticalPathTask is one unfinished task's place in the schedule, in
+// points from now. A task with no slack can't slip without moving the end.
+type CriticalPathTask struct {
+	TaskID         int    `json:"taskId"`
+	Title          string `json:"title"`
+	Points         int    `json:"points"` // its estimate, or defaultTaskPoints if it has none
+	EarliestStart  int    `json:"earliestStart"`
+	EarliestFinish int    `json:"earliestFinish"`
+	LatestStart    int    `json:"latestStart"`
+	LatestFinish   int    `json:"latestFinish"`
+	Slack          int    `json:"slack"`
+	Critical       bool   `json:"critical"`
+}
+
+// CriticalPath is the longest chain of unfinished dependent tasks, which
+// decides how soon the board, or a milestone, can be done
+type CriticalPath struct {
+	MilestoneID string             `json:"milestoneId,omitempty"`
+	Length      int                `json:"length"`      // points along the path
+	Path        []int              `json:"path"`        // task IDs, dependencies first
+	Tasks       []CriticalPathTask `json:"tasks"`       // every task considered, by ID
+	Unestimated []int              `json:"unestimated"` // tasks counted at defaultTaskPoints
+}
+
+// computeCriticalPath schedules the unfinished tasks among scope, and the
+// unfinished tasks they depend on, as early as their dependencies allow.
+// Done tasks and dependencies that aren't on the board hold nothing up; nil
+// scope means the whole board. A dependency cycle has no schedule, so it
+// is returned as the task IDs caught in or behind it.
+func computeCriticalPath(tasks []Task, scope []int) (CriticalPath, []int) {
+	path := CriticalPath{Path: []int{}, Tasks: []CriticalPathTask{}, Unestimated: []int{}}
+
+	open := map[int]Task{}
+	for _, task := range tasks {
+		if task.Status != StatusDone {
+			open[task.ID] = task
+		}
+	}
+	included := map[int]bool{}
+	if scope == nil {
+		for id := range open {
+			included[id] = true
+		}
+	} else {
+		pending := append([]int(nil), scope...)
+		for len(pending) > 0 {
+			id := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			task, ok := open[id]
+			if !ok || included[id] {
+				continue
+			}
+			included[id] = true
+			pending = append(pending, task.Deps...)
+		}
+	}
+
+	// Order the tasks so dependencies come first, lowest ID first among
+	// those ready at once
+	ids := make([]int, 0, len(included))
+	waiting := map[int]int{}
+	dependents := map[int][]int{}
+	for id := range included {
+		ids = append(ids, id)
+		for _, dep := range open[id].Deps {
+			if included[dep] {
+				waiting[id]++
+				dependents[dep] = append(dependents[dep], id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	ready := []int{}
+	for _, id := range ids {
+		if waiting[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	order := make([]int, 0, len(ids))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+		for _, dependent := range dependents[id] {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(order) < len(ids) {
+		stuck := []int{}
+		for _, id := range ids {
+			if waiting[id] > 0 {
+				stuck = append(stuck, id)
+			}
+		}
+		return path, stuck
+	}
+
+	// Forward pass: start each task once its last dependency finishes
+	schedule := map[int]*CriticalPathTask{}
+	for _, id := range order {
+		task := open[id]
+		entry := &CriticalPathTask{TaskID: id, Title: task.Title, Points: taskPoints(task)}
+		for _, dep := range task.Deps {
+			if before, ok := schedule[dep]; ok && before.EarliestFinish > entry.EarliestStart {
+				entry.EarliestStart = before.EarliestFinish
+			}
+		}
+		entry.EarliestFinish = entry.EarliestStart + entry.Points
+		if entry.EarliestFinish > path.Length {
+			path.Length = entry.EarliestFinish
+		}
+		if task.Estimate <= 0 {
+			path.Unestimated = append(path.Unestimated, id)
+		}
+		schedule[id] = entry
+	}
+
+	// Backward pass: finish each task before its first dependent must start
+	for i := len(order) - 1; i >= 0; i-- {
+		entry := schedule[order[i]]
+		entry.LatestFinish = path.Length
+		for _, dependent := range dependents[entry.TaskID] {
+			if after := schedule[dependent]; after.LatestStart < entry.LatestFinish {
+				entry.LatestFinish = after.LatestStart
+			}
+		}
+		entry.LatestStart = entry.LatestFinish - entry.Points
+		entry.Slack = entry.LatestStart - entry.EarliestStart
+		entry.Critical = entry.Slack == 0
+	}
+
+	// Walk back from the critical task that finishes last, lowest ID first
+	var last *CriticalPathTask
+	for _, id := range ids {
+		if entry := schedule[id]; entry.Critical && entry.EarliestFinish == path.Length && last == nil {
+			last = entry
+		}
+	}
+	for last != nil {
+		path.Path = append([]int{last.TaskID}, path.Path...)
+		var previous *CriticalPathTask
+		for _, dep := range open[last.TaskID].Deps {
+			before, ok := schedule[dep]
+			if !ok || !before.Critical || before.EarliestFinish != last.EarliestStart {
+				continue
+			}
+			if previous == nil || before.TaskID < previous.TaskID {
+				previous = before
+			}
+		}
+		last = previous
+	}
+
+	for _, id := range ids {
+		path.Tasks = append(path.Tasks, *schedule[id])
+	}
+	return path, nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Limits on what goes into a diagnostics bundle
+const (
+	diagnosticsLogAge      = 72 * time.Hour
+	diagnosticsMaxLogBytes = 5 * 1024 * 1024 // keep the tail of larger files
+	diagnosticsCmdTimeout  = 5 * time.Second
+	redactedValue          = "[REDACTED]"
+)
+
+// DiagnosticsSources is everything the bundle is built from
+type DiagnosticsSources struct {
+	LogDir      string
+	AppLogDir   string // the app's logs when they're kept outside LogDir
+	RepoPath    string
+	Config      *Config
+	AgentStatus *AgentStatusInfo
+	AgentRuns   []AgentRun
+	Performance []OperationStats
+}
+
+// DiagnosticsEnvironment describes the machine and tool versions
+type DiagnosticsEnvironment struct {
+	AppVersion    string    `json:"appVersion"`
+	GoVersion     string    `json:"goVersion"`
+	OS            string    `json:"os"`
+	Arch          string    `json:"arch"`
+	GitVersion    string    `json:"gitVersion"`
+	ClaudeVersion string    `json:"claudeVersion"`
+	RepoPath      string    `json:"repoPath"`
+	GeneratedAt   time.Time `json:"generatedAt"`
+}
+
+// DiagnosticsService builds zip bundles users can attach to bug reports
+type DiagnosticsService struct {
+	logger Logger
+}
+
+// NewDiagnosticsService creates a new diagnostics service
+func NewDiagnosticsService(logger Logger) *DiagnosticsService {
+	return &DiagnosticsService{
+		logger: logger,
+	}
+}
+
+// Export writes a diagnostics zip to dest
+func (ds *DiagnosticsService) Export(dest string, sources DiagnosticsSources) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create diagnostics bundle: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	if err := writeZipJSON(zw, "environment.json", collectEnvironment(sources.RepoPath)); err != nil {
+		return err
+	}
+	if sources.Config != nil {
+		if err := writeZipJSON(zw, "config.json", redactConfig(*sources.Config)); err != nil {
+			return err
+		}
+	}
+	if sources.AgentStatus != nil {
+		if err := writeZipJSON(zw, "agents.json", sources.AgentStatus); err != nil {
+			return err
+		}
+	}
+	if sources.AgentRuns != nil {
+		if err := writeZipJSON(zw, "agent_runs.json", sources.AgentRuns); err != nil {
+			return err
+		}
+	}
+	if sources.Performance != nil {
+		if err := writeZipJSON(zw, "performance.json", sources.Performance); err != nil {
+			return err
+		}
+	}
+	if err := ds.addLogs(zw, sources.LogDir, "logs"); err != nil {
+		return err
+	}
+	if sources.AppLogDir != "" && filepath.Clean(sources.AppLogDir) != filepath.Clean(sources.LogDir) {
+		if err := ds.addLogs(zw, sources.AppLogDir, "logs/app"); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finalize diagnostics bundle: %v", err)
+	}
+
+	ds.logger.InfoWithFields("Diagnostics bundle exported", map[string]interface{}{
+		"file": dest,
+	})
+	return nil
+}
+
+// addLogs copies recent log files, the event journal and crash reports
+// into the bundle under prefix; compressed logs are left out
+func (ds *DiagnosticsService) addLogs(zw *zip.Writer, logDir, prefix string) error {
+	if logDir == "" {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-diagnosticsLogAge)
+	return filepath.Walk(logDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || info.ModTime().Before(cutoff) || strings.HasSuffix(path, ".gz") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(logDir, path)
+		if err != nil {
+			return err
+		}
+		if err := addZipFileTail(zw, prefix+"/"+filepath.ToSlash(rel), path, diagnosticsMaxLogBytes); err != nil {
+			ds.logger.ErrorWithFields("Failed to add log to diagnostics bundle", err, map[string]interface{}{
+				"file": path,
+			})
+		}
+		return nil
+	})
+}
+
+// collectEnvironment gathers version information for the bundle
+func collectEnvironment(repoPath string) DiagnosticsEnvironment {
+	return DiagnosticsEnvironment{
+		AppVersion:    AppVersion,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		GitVersion:    commandVersion("git", "--version"),
+		ClaudeVersion: commandVersion("claude", "--version"),
+		RepoPath:      repoPath,
+		GeneratedAt:   time.Now().UTC(),
+	}
+}
+
+// commandVersion runs a version command, returning its output or the failure reason
+func commandVersion(name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsCmdTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return fmt.Sprintf("unavailable: %v", err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// redactConfig returns a copy of config with secrets replaced: remote
+// tokens, webhook URLs, agent environment values other than keychain
+// references, and where and as whom the plan directory syncs. The live
+// config is left alone.
+func redactConfig(config Config) Config {
+	tokens := make([]RemoteToken, len(config.Remote.Tokens))
+	for i, token := range config.Remote.Tokens {
+		token.Token = redactedValue
+		tokens[i] = token
+	}
+	config.Remote.Tokens = tokens
+
+	rules := make([]AutomationRule, len(config.Automation.Rules))
+	for i, rule := range config.Automation.Rules {
+		actions := make([]RuleAction, len(rule.Actions))
+		for j, action := range rule.Actions {
+			if action.URL != "" {
+				action.URL = redactedValue
+			}
+			actions[j] = action
+		}
+		rule.Actions = actions
+		rules[i] = rule
+	}
+	config.Automation.Rules = rules
+
+	repos := make([]Repository, len(config.Repositories))
+	for i, repo := range config.Repositories {
+		repo.AgentEnv = redactEnv(repo.AgentEnv)
+		if repo.TaskEnv != nil {
+			taskEnv := make(map[int]map[string]string, len(repo.TaskEnv))
+			for taskID, env := range repo.TaskEnv {
+				taskEnv[taskID] = redactEnv(env)
+			}
+			repo.TaskEnv = taskEnv
+		}
+		if repo.Sync != nil {
+			sync := *repo.Sync
+			for _, field := range []*string{&sync.Endpoint, &sync.Bucket, &sync.Username} {
+				if *field != "" {
+					*field = redactedValue
+				}
+			}
+			repo.Sync = &sync
+		}
+		repos[i] = repo
+	}
+	config.Repositories = repos
+	return config
+}
+
+// redactEnv copies env with its values replaced, keeping references to
+// keychain secrets, which name a secret without revealing it
+func redactEnv(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(env))
+	for name, value := range env {
+		if !strings.HasPrefix(value, agentSecretPrefix) {
+			value = redactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// writeZipJSON adds v to the archive as indented JSON
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %v", name, err)
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %v", name, err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// addZipFileTail adds at most the last maxBytes of path to the archive
+func addZipFileTail(zw *zip.Writer, name, path string, maxBytes int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxBytes {
+		if _, err := f.Seek(-maxBytes, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits that keep a huge patch from stalling the diff viewer
+const (
+	maxDiffLines      = 20000 // lines parsed across all files; the rest is dropped
+	maxWordDiffTokens = 400   // longer line pairs are marked changed as a whole
+	diffPageLines     = 2000  // lines per page of GetTaskDiffFile
+	largeDiffLines    = 5000  // files with more changed lines are loaded a page at a time
+)
+
+// Kinds of diff line
+const (
+	DiffContext = "context"
+	DiffAdded   = "added"
+	DiffDeleted = "deleted"
+)
+
+// Kinds of file change
+const (
+	FileAdded    = "added"
+	FileDeleted  = "deleted"
+	FileModified = "modified"
+	FileRenamed  = "renamed"
+)
+
+// diffLanguages maps file extensions to the language hint the viewer
+// highlights with
+var diffLanguages = map[string]string{
+	".go": "go", ".js": "javascript", ".jsx": "javascript", ".mjs": "javascript",
+	".ts": "typescript", ".tsx": "typescript", ".py": "python", ".rb": "ruby",
+	".rs": "rust", ".java": "java", ".kt": "kotlin", ".swift": "swift",
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".m": "objectivec", ".php": "php", ".sh": "bash", ".bash": "bash", ".zsh": "bash",
+	".json": "json", ".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".xml": "xml",
+	".html": "html", ".css": "css", ".scss": "scss", ".md": "markdown", ".sql": "sql",
+	".vue": "vue", ".svelte": "svelte", ".lua": "lua", ".dart": "dart",
+}
+
+// diffFileNames maps extensionless file names to language hints
+var diffFileNames = map[string]string{
+	"Makefile": "makefile", "Dockerfile": "dockerfile", "go.mod": "go", "go.sum": "plaintext",
+}
+
+// DiffSegment is a run of a changed line; Changed marks the words that
+// differ from the paired line on the other side
+type DiffSegment struct {
+	Text    string `json:"text"`
+	Changed bool   `json:"changed,omitempty"`
+}
+
+// DiffLine is one line of a hunk. Segments are set on added and deleted
+// lines paired with a line on the other side.
+type DiffLine struct {
+	Kind     string        `json:"kind"`
+	Text     string        `json:"text"`
+	Old      int           `json:"old,omitempty"` // line number before the change; 0 for added lines
+	New      int           `json:"new,omitempty"` // line number after the change; 0 for deleted lines
+	Segments []DiffSegment `json:"segments,omitempty"`
+}
+
+// DiffHunk is one @@ section of a file's diff
+type DiffHunk struct {
+	Header   string     `json:"header"` // the text after the second @@, usually the enclosing function
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffFile is one file of a patch
+type DiffFile struct {
+	OldPath   string     `json:"oldPath,omitempty"` // empty for added files
+	NewPath   string     `json:"newPath,omitempty"` // empty for deleted files
+	Status    string     `json:"status"`
+	Language  string     `json:"language,omitempty"` // highlighting hint; empty for unknown types
+	Binary    bool       `json:"binary,omitempty"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	Hunks     []DiffHunk `json:"hunks"`
+}
+
+// TaskDiff is a task branch's changes against the mainline, ready to render
+type TaskDiff struct {
+	TaskID    int        `json:"taskId"`
+	Branch    string     `json:"branch"`
+	Base      string     `json:"base"`
+	Files     []DiffFile `json:"files"`
+	Truncated bool       `json:"truncated,omitempty"` // the patch went past maxDiffLines
+}
+
+// DiffFileSummary is one file of a task diff without its hunks
+type DiffFileSummary struct {
+	OldPath   string `json:"oldPath,omitempty"`
+	NewPath   string `json:"newPath,omitempty"`
+	Status    string `json:"status"`
+	Language  string `json:"language,omitempty"`
+	Binary    bool   `json:"binary,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Large     bool   `json:"large,omitempty"` // past largeDiffLines; load it a page at a time
+}
+
+// TaskDiffSummary lists a task branch's changed files so a review can show
+// them before loading any file's diff
+type TaskDiffSummary struct {
+	TaskID    int               `json:"taskId"`
+	Branch    string            `json:"branch"`
+	Base      string            `json:"base"`
+	Files     []DiffFileSummary `json:"files"`
+	Additions int               `json:"additions"`
+	Deletions int               `json:"deletions"`
+}
+
+// DiffFilePage is one page of a file's diff. Hunks longer than a page are
+// split, each part numbered from where it starts.
+type DiffFilePage struct {
+	TaskID int      `json:"taskId"`
+	File   DiffFile `json:"file"` // Hunks holds this page's hunks only
+	Page   int      `json:"page"` // zero-based
+	Pages  int      `json:"pages"`
+}
+
+// path returns the summary's current path, or its old one for deletions
+func (fs DiffFileSummary) path() string {
+	if fs.NewPath != "" {
+		return fs.NewPath
+	}
+	return fs.OldPath
+}
+
+// parseDiffSummary parses the output of git diff --raw --numstat -z, which
+// lists each file's raw entry and then, in the same order, its line counts
+func parseDiffSummary(output string) []DiffFileSummary {
+	files := []DiffFileSummary{}
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	counted := 0
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.HasPrefix(field, ":") {
+			// ":100644 100644 abc def M" then the path, or both paths for
+			// renames and copies
+			parts := strings.Fields(field)
+			if len(parts) < 5 || i+1 >= len(fields) {
+				break
+			}
+			file := DiffFileSummary{Status: FileModified}
+			switch parts[4][0] {
+			case 'A':
+				file.Status = FileAdded
+				file.NewPath = fields[i+1]
+			case 'D':
+				file.Status = FileDeleted
+				file.OldPath = fields[i+1]
+			case 'R', 'C':
+				if i+2 >= len(fields) {
+					break
+				}
+				file.Status = FileRenamed
+				file.OldPath, file.NewPath = fields[i+1], fields[i+2]
+				i++
+			default:
+				file.OldPath, file.NewPath = fields[i+1], fields[i+1]
+			}
+			i++
+			file.Language = diffLanguage(file.path())
+			files = append(files, file)
+			continue
+		}
+
+		// Tab-separated added and deleted counts and the path; a rename has
+		// an empty path followed by both paths. Binary files count "-".
+		counts := strings.SplitN(field, "\t", 3)
+		if len(counts) != 3 {
+			continue
+		}
+		if counts[2] == "" {
+			i += 2
+		}
+		if counted >= len(files) {
+			continue
+		}
+		file := &files[counted]
+		counted++
+		if counts[0] == "-" {
+			file.Binary = true
+			continue
+		}
+		file.Additions, _ = strconv.Atoi(counts[0])
+		file.Deletions, _ = strconv.Atoi(counts[1])
+		file.Large = file.Additions+file.Deletions > largeDiffLines
+	}
+	return files
+}
+
+// paginateHunks splits hunks into pages of about pageLines lines. Hunks are
+// kept whole unless one alone is longer than a page.
+func paginateHunks(hunks []DiffHunk, pageLines int) [][]DiffHunk {
+	pages := [][]DiffHunk{}
+	page := []DiffHunk{}
+	size := 0
+	for _, hunk := range hunks {
+		for _, part := range splitHunk(hunk, pageLines) {
+			if size > 0 && size+len(part.Lines) > pageLines {
+				pages = append(pages, page)
+				page, size = []DiffHunk{}, 0
+			}
+			page = append(page, part)
+			size += len(part.Lines)
+		}
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// splitHunk cuts a hunk into parts of at most maxLines lines, each with the
+// start lines and counts of the lines it holds
+func splitHunk(hunk DiffHunk, maxLines int) []DiffHunk {
+	if len(hunk.Lines) <= maxLines {
+		return []DiffHunk{hunk}
+	}
+	parts := []DiffHunk{}
+	oldLine, newLine := hunk.OldStart, hunk.NewStart
+	for start := 0; start < len(hunk.Lines); start += maxLines {
+		end := start + maxLines
+		if end > len(hunk.Lines) {
+			end = len(hunk.Lines)
+		}
+		part := DiffHunk{Header: hunk.Header, OldStart: oldLine, NewStart: newLine, Lines: hunk.Lines[start:end]}
+		for _, line := range part.Lines {
+			if line.Kind != DiffAdded {
+				part.OldLines++
+			}
+			if line.Kind != DiffDeleted {
+				part.NewLines++
+			}
+		}
+		oldLine += part.OldLines
+		newLine += part.NewLines
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// diffLanguage returns the highlighting hint for path
+func diffLanguage(path string) string {
+	base := filepath.Base(path)
+	if language, ok := diffFileNames[base]; ok {
+		return language
+	}
+	return diffLanguages[strings.ToLower(filepath.Ext(base))]
+}
+
+// parseDiff parses the output of git diff into files and hunks, with word
+// segments on changed line pairs. It stops after maxLines lines and reports
+// whether it did.
+func parseDiff(patch string, maxLines int) ([]DiffFile, bool) {
+	return parsePatch(patch, maxLines, true)
+}
+
+// parsePatch is parseDiff with the word segments optional, for callers that
+// only need them on part of the patch. maxLines 0 parses it all.
+func parsePatch(patch string, maxLines int, words bool) ([]DiffFile, bool) {
+	files := []DiffFile{}
+	var file *DiffFile
+	var hunk *DiffHunk
+	oldLine, newLine := 0, 0
+	finishHunk := func() {
+		if hunk != nil {
+			if words {
+				addWordSegments(hunk.Lines)
+			}
+			file.Hunks = append(file.Hunks, *hunk)
+			hunk = nil
+		}
+	}
+	finishFile := func() {
+		finishHunk()
+		if file != nil {
+			if file.Language == "" {
+				path := file.NewPath
+				if path == "" {
+					path = file.OldPath
+				}
+				file.Language = diffLanguage(path)
+			}
+			files = append(files, *file)
+			file = nil
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(patch, "\n"), "\n")
+	truncated := false
+	for count, line := range lines {
+		if maxLines > 0 && count >= maxLines {
+			truncated = true
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			finishFile()
+			oldPath, newPath := splitDiffGitPaths(strings.TrimPrefix(line, "diff --git "))
+			file = &DiffFile{OldPath: oldPath, NewPath: newPath, Status: FileModified, Hunks: []DiffHunk{}}
+		case file == nil:
+			continue
+		case hunk != nil && (strings.HasPrefix(line, " ") || line == ""):
+			oldLine++
+			newLine++
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: DiffContext, Text: strings.TrimPrefix(line, " "), Old: oldLine, New: newLine})
+		case hunk != nil && strings.HasPrefix(line, "+"):
+			newLine++
+			file.Additions++
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: DiffAdded, Text: line[1:], New: newLine})
+		case hunk != nil && strings.HasPrefix(line, "-"):
+			oldLine++
+			file.Deletions++
+			hunk.Lines = append(hunk.Lines, DiffLine{Kind: DiffDeleted, Text: line[1:], Old: oldLine})
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		case hunk == nil && strings.HasPrefix(line, "--- "):
+			if path := diffPath(line[4:]); path != "" {
+				file.OldPath = path
+			}
+		case hunk == nil && strings.HasPrefix(line, "+++ "):
+			if path := diffPath(line[4:]); path != "" {
+				file.NewPath = path
+			}
+		case strings.HasPrefix(line, "@@ "):
+			finishHunk()
+			parsed, ok := parseHunkHeader(line)
+			if !ok {
+				continue
+			}
+			hunk = &parsed
+			oldLine, newLine = hunk.OldStart-1, hunk.NewStart-1
+		case strings.HasPrefix(line, "new file mode"):
+			file.Status = FileAdded
+			file.OldPath = ""
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.Status = FileDeleted
+			file.NewPath = ""
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = FileRenamed
+			file.OldPath = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "rename to "):
+			file.Status = FileRenamed
+			file.NewPath = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			file.Binary = true
+		}
+	}
+	finishFile()
+	return files, truncated
+}
+
+// splitDiffGitPaths splits the "a/old b/new" of a diff --git line. Paths
+// with spaces are ambiguous there, so the ---/+++ lines replace them when
+// the file has any; binary files don't.
+func splitDiffGitPaths(paths string) (string, string) {
+	if len(paths)%2 == 1 {
+		middle := len(paths) / 2
+		if paths[middle] == ' ' && strings.TrimPrefix(paths[:middle], "a/") == strings.TrimPrefix(paths[middle+1:], "b/") {
+			name := strings.TrimPrefix(paths[:middle], "a/")
+			return name, name
+		}
+	}
+	if index := strings.Index(paths, " b/"); index >= 0 {
+		return strings.TrimPrefix(paths[:index], "a/"), paths[index+3:]
+	}
+	return paths, paths
+}
+
+// diffPath returns the path of a ---/+++ line without its a/ or b/ prefix,
+// or "" for /dev/null
+func diffPath(value string) string {
+	value = strings.TrimSuffix(value, "\t")
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	if value == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(value, "a/") || strings.HasPrefix(value, "b/") {
+		return value[2:]
+	}
+	return value
+}
+
+// parseHunkHeader parses "@@ -1,3 +1,4 @@ func main() {"
+func parseHunkHeader(line string) (DiffHunk, bool) {
+	parts := strings.SplitN(line, "@@", 3)
+	if len(parts) < 3 {
+		return DiffHunk{}, false
+	}
+	hunk := DiffHunk{Header: strings.TrimSpace(parts[2]), Lines: []DiffLine{}}
+	for _, field := range strings.Fields(parts[1]) {
+		start, count := parseHunkRange(field[1:])
+		switch field[0] {
+		case '-':
+			hunk.OldStart, hunk.OldLines = start, count
+		case '+':
+			hunk.NewStart, hunk.NewLines = start, count
+		}
+	}
+	return hunk, true
+}
+
+// parseHunkRange parses "start,count"; a missing count is 1
+func parseHunkRange(value string) (int, int) {
+	startText, countText, hasCount := strings.Cut(value, ",")
+	start, _ := strconv.Atoi(startText)
+	count := 1
+	if hasCount {
+		count, _ = strconv.Atoi(countText)
+	}
+	return start, count
+}
+
+// addWordSegments pairs each run of deleted lines with the added lines that
+// follow it, line by line, and splits each pair into changed and unchanged
+// words
+func addWordSegments(lines []DiffLine) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != DiffDeleted {
+			i++
+			continue
+		}
+		deletedStart := i
+		for i < len(lines) && lines[i].Kind == DiffDeleted {
+			i++
+		}
+		addedStart := i
+		for i < len(lines) && lines[i].Kind == DiffAdded {
+			i++
+		}
+		pairs := addedStart - deletedStart
+		if added := i - addedStart; added < pairs {
+			pairs = added
+		}
+		for p := 0; p < pairs; p++ {
+			before, after := &lines[deletedStart+p], &lines[addedStart+p]
+			before.Segments, after.Segments = wordDiff(before.Text, after.Text)
+		}
+	}
+}
+
+// wordDiff splits two versions of a line into segments, marking the tokens
+// not in their longest common subsequence as changed
+func wordDiff(before, after string) ([]DiffSegment, []DiffSegment) {
+	a, b := diffTokens(before), diffTokens(after)
+	if len(a) > maxWordDiffTokens || len(b) > maxWordDiffTokens {
+		return []DiffSegment{{Text: before, Changed: true}}, []DiffSegment{{Text: after, Changed: true}}
+	}
+
+	// lcs[i][j] is the common subsequence length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var oldSegments, newSegments []DiffSegment
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			oldSegments = appendSegment(oldSegments, a[i], false)
+			newSegments = appendSegment(newSegments, b[j], false)
+			i++
+			j++
+		case j >= len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			oldSegments = appendSegment(oldSegments, a[i], true)
+			i++
+		default:
+			newSegments = appendSegment(newSegments, b[j], true)
+			j++
+		}
+	}
+	return oldSegments, newSegments
+}
+
+// appendSegment adds text to segments, merging it into the last segment
+// when both are changed or both unchanged
+func appendSegment(segments []DiffSegment, text string, changed bool) []DiffSegment {
+	if last := len(segments) - 1; last >= 0 && segments[last].Changed == changed {
+		segments[last].Text += text
+		return segments
+	}
+	return append(segments, DiffSegment{Text: text, Changed: changed})
+}
+
+// diffTokens splits a line into words, runs of whitespace and single
+// punctuation characters
+func diffTokens(line string) []string {
+	tokens := []string{}
+	runes := []rune(line)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		switch {
+		case isWordRune(runes[start]):
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+		case unicode.IsSpace(runes[start]):
+			for end < len(runes) && unicode.IsSpace(runes[end]) {
+				end++
+			}
+		}
+		tokens = append(tokens, string(runes[start:end]))
+		start = end
+	}
+	return tokens
+}
+
+// isWordRune reports whether r is part of an identifier or number
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
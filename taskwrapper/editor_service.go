@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// jetBrainsEditors are launcher names that take "--line N path"
+var jetBrainsEditors = map[string]bool{
+	"idea": true, "goland": true, "webstorm": true, "pycharm": true,
+	"clion": true, "rubymine": true, "phpstorm": true, "rider": true,
+}
+
+// terminalEditors need a terminal to draw in; started from the app they
+// would have none
+var terminalEditors = map[string]bool{
+	"vi": true, "vim": true, "nvim": true, "nano": true, "pico": true,
+	"micro": true, "hx": true, "helix": true, "kak": true, "joe": true,
+	"ne": true, "mg": true, "jed": true, "ed": true,
+}
+
+// EditorService launches external editors at a file or folder
+type EditorService struct {
+	logger        Logger
+	errorHandler  *ErrorHandler
+	mu            sync.RWMutex
+	pathValidator *PathValidator
+}
+
+// NewEditorService creates a new editor service
+func NewEditorService(logger Logger) *EditorService {
+	return &EditorService{
+		logger:        logger,
+		errorHandler:  NewErrorHandler(logger),
+		pathValidator: NewPathValidator(DefaultSecurityConfig(), logger),
+	}
+}
+
+// SetErrorHandler sets the handler used to recover panics while reaping
+// editor processes
+func (es *EditorService) SetErrorHandler(errorHandler *ErrorHandler) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.errorHandler = errorHandler
+}
+
+// SetSecurityConfig replaces the policy opened paths are checked against
+func (es *EditorService) SetSecurityConfig(config *SecurityConfig) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.pathValidator = NewPathValidator(config, es.logger)
+}
+
+// Open launches editorCommand on path, jumping to line when it is > 0.
+// An empty editorCommand falls back to $VISUAL, $EDITOR and finally VS Code,
+// passing over terminal editors.
+func (es *EditorService) Open(editorCommand, path string, line int) error {
+	es.mu.RLock()
+	pathValidator, errorHandler := es.pathValidator, es.errorHandler
+	es.mu.RUnlock()
+
+	validPath, err := pathValidator.ValidatePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid editor path: %w", err)
+	}
+	if _, err := os.Stat(validPath); err != nil {
+		return fmt.Errorf("path not found: %w", err)
+	}
+
+	if editorCommand == "" {
+		editorCommand = resolveDefaultEditor()
+	}
+
+	argv := buildEditorArgs(editorCommand, validPath, line)
+	if len(argv) == 0 {
+		return fmt.Errorf("editor command is empty")
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if err := cmd.Start(); err != nil {
+		es.logger.ErrorWithFields("Failed to launch editor", err, map[string]interface{}{
+			"editor": argv[0],
+			"path":   validPath,
+		})
+		return fmt.Errorf("failed to launch editor %s: %v", argv[0], err)
+	}
+
+	// Reap the editor process without blocking the caller
+	errorHandler.Go("editor reaper", func() { cmd.Wait() })
+
+	es.logger.InfoWithFields("Opened in editor", map[string]interface{}{
+		"editor": argv[0],
+		"path":   validPath,
+		"line":   line,
+	})
+
+	return nil
+}
+
+// resolveDefaultEditor picks an editor from the environment, passing over
+// terminal editors since the app has no terminal to give them
+func resolveDefaultEditor() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" && !isTerminalEditor(editor) {
+			return editor
+		}
+	}
+	return "code"
+}
+
+// isTerminalEditor reports whether editorCommand runs in a terminal, such
+// as vim or emacs -nw
+func isTerminalEditor(editorCommand string) bool {
+	fields := strings.Fields(editorCommand)
+	if len(fields) == 0 {
+		return false
+	}
+	name := strings.TrimSuffix(filepath.Base(fields[0]), ".exe")
+	if terminalEditors[name] {
+		return true
+	}
+	if name == "emacs" || name == "emacsclient" {
+		for _, arg := range fields[1:] {
+			if arg == "-nw" || arg == "-t" || arg == "--no-window-system" || arg == "-tty" || arg == "--tty" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildEditorArgs expands an editor command into argv for the given target.
+// Commands containing {path} or {line} placeholders are used verbatim;
+// otherwise the line-jump syntax is chosen from the editor's name.
+func buildEditorArgs(editorCommand, path string, line int) []string {
+	fields := strings.Fields(editorCommand)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if strings.Contains(editorCommand, "{path}") || strings.Contains(editorCommand, "{line}") {
+		lineStr := "1"
+		if line > 0 {
+			lineStr = strconv.Itoa(line)
+		}
+		argv := make([]string, len(fields))
+		for i, field := range fields {
+			field = strings.ReplaceAll(field, "{path}", path)
+			argv[i] = strings.ReplaceAll(field, "{line}", lineStr)
+		}
+		return argv
+	}
+
+	name := strings.TrimSuffix(filepath.Base(fields[0]), ".exe")
+	argv := append([]string{}, fields...)
+	if line <= 0 {
+		return append(argv, path)
+	}
+
+	switch {
+	case name == "code" || name == "code-insiders" || name == "codium" || name == "cursor":
+		return append(argv, "-g", fmt.Sprintf("%s:%d", path, line))
+	case jetBrainsEditors[name]:
+		return append(argv, "--line", strconv.Itoa(line), path)
+	case name == "subl" || name == "zed":
+		return append(argv, fmt.Sprintf("%s:%d", path, line))
+	case name == "vim" || name == "nvim" || name == "vi" || name == "emacs" || name == "nano":
+		return append(argv, "+"+strconv.Itoa(line), path)
+	default:
+		return append(argv, path)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// encryptedFileMagic starts every file written with encryption on, so
+// readers can tell encrypted from plain files without knowing the mode
+const encryptedFileMagic = "TWENC1\n"
+
+// keychainService is the service name repository keys are stored under
+const keychainService = "taskwrapper"
+
+// EncryptionKeySize is the length of a repository key in bytes
+const EncryptionKeySize = 32
+
+// ErrKeyNotFound is returned by a KeyStore with no key for an account
+var ErrKeyNotFound = errors.New("encryption key not found")
+
+// KeyStore keeps repository encryption keys, normally in the OS keychain
+type KeyStore interface {
+	Get(account string) ([]byte, error)
+	Set(account string, key []byte) error
+	Delete(account string) error
+}
+
+// Cipher seals and opens file contents with NaCl secretbox
+type Cipher struct {
+	key [EncryptionKeySize]byte
+}
+
+// NewCipher creates a cipher from a 32-byte key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	c := &Cipher{}
+	copy(c.key[:], key)
+	return c, nil
+}
+
+// GenerateKey returns a new random repository key
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate encryption key: %v", err)
+	}
+	return key, nil
+}
+
+// Encrypt seals plaintext behind the magic header and a random nonce
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	out := make([]byte, 0, len(encryptedFileMagic)+len(nonce)+len(plaintext)+secretbox.Overhead)
+	out = append(out, encryptedFileMagic...)
+	out = append(out, nonce[:]...)
+	return secretbox.Seal(out, plaintext, &nonce, &c.key), nil
+}
+
+// Decrypt opens data written by Encrypt
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	if !isEncryptedData(data) {
+		return nil, ValidationError("file is not encrypted", nil)
+	}
+	body := data[len(encryptedFileMagic):]
+	if len(body) < 24+secretbox.Overhead {
+		return nil, CorruptedError("encrypted file is truncated", nil)
+	}
+	var nonce [24]byte
+	copy(nonce[:], body[:24])
+	plaintext, ok := secretbox.Open(nil, body[24:], &nonce, &c.key)
+	if !ok {
+		return nil, CorruptedError("failed to decrypt file: wrong key or damaged data", nil)
+	}
+	return plaintext, nil
+}
+
+// isEncryptedData reports whether data starts with the encrypted file header
+func isEncryptedData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedFileMagic))
+}
+
+// fileIsEncrypted reports whether the file at path has the encrypted header
+func fileIsEncrypted(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, len(encryptedFileMagic))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return isEncryptedData(header)
+}
+
+// repositoryKeyAccount names a repository's key in the keychain. The path is
+// hashed so keychain listings don't reveal where repositories live.
+func repositoryKeyAccount(repoPath string) string {
+	sum := sha256.Sum256([]byte(repoPath))
+	return "repo-" + hex.EncodeToString(sum[:8])
+}
+
+// syncSecretAccount names a repository's sync password or secret key in the keychain
+func syncSecretAccount(repoPath string) string {
+	return "sync-" + strings.TrimPrefix(repositoryKeyAccount(repoPath), "repo-")
+}
+
+// loadRepositoryCipher returns the cipher for a repository's key
+func loadRepositoryCipher(keys KeyStore, repoPath string) (*Cipher, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("no keychain available")
+	}
+	key, err := keys.Get(repositoryKeyAccount(repoPath))
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(key)
+}
+
+// recryptFile rewrites the file at path so it is sealed with to, or plain
+// when to is nil. Encrypted input is opened with from. Missing files are
+// skipped.
+func recryptFile(path string, from, to *Cipher) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if isEncryptedData(data) {
+		if from == nil {
+			return ValidationError("file is encrypted and no key is loaded", nil).WithContext("file", path)
+		}
+		if data, err = from.Decrypt(data); err != nil {
+			return err
+		}
+	}
+	if to != nil {
+		if data, err = to.Encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.tmp.%d", path, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// recryptFiles rewrites each file with recryptFile, returning how many
+// were rewritten before any error
+func recryptFiles(paths []string, from, to *Cipher) (int, error) {
+	for i, path := range paths {
+		if err := recryptFile(path, from, to); err != nil {
+			return i, fmt.Errorf("failed to rewrite %s: %w", filepath.Base(path), err)
+		}
+	}
+	return len(paths), nil
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"time"
 )
 
 // ErrorType represents different categories of errors
@@ -20,6 +26,8 @@ const (
 	ErrorTypeTimeout      ErrorType = "timeout"
 	ErrorTypeCancelled    ErrorType = "cancelled"
 	ErrorTypeUnsupported  ErrorType = "unsupported"
+	ErrorTypeCorrupted    ErrorType = "corrupted"
+	ErrorTypeQuota        ErrorType = "quota_exceeded"
 )
 
 // AppError provides structured error information
@@ -122,14 +130,40 @@ func InternalError(message string, err error) *AppError {
 	return NewAppError(ErrorTypeInternal, message, err)
 }
 
+// CorruptedError creates an error for data that failed an integrity check
+func CorruptedError(message string, err error) *AppError {
+	return NewAppError(ErrorTypeCorrupted, message, err)
+}
+
 // TimeoutError creates a timeout error
 func TimeoutError(message string, err error) *AppError {
 	return NewAppError(ErrorTypeTimeout, message, err)
 }
 
+// QuotaError creates an error for an action refused by a usage limit
+func QuotaError(message string, err error) *AppError {
+	return NewAppError(ErrorTypeQuota, message, err)
+}
+
+// CrashReport describes a recovered panic
+type CrashReport struct {
+	Time      time.Time `json:"time"`
+	Goroutine string    `json:"goroutine"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	Version   string    `json:"version"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	File      string    `json:"file,omitempty"`
+}
+
 // ErrorHandler provides centralized error handling
 type ErrorHandler struct {
 	logger Logger
+
+	mu       sync.RWMutex
+	crashDir string
+	onCrash  func(CrashReport)
 }
 
 // NewErrorHandler creates a new error handler
@@ -201,24 +235,107 @@ func (eh *ErrorHandler) logAppError(err *AppError) {
 	eh.logger.ErrorWithFields(err.Message, err.Err, fields)
 }
 
+// SetCrashReportDir sets where crash reports are written; empty disables files
+func (eh *ErrorHandler) SetCrashReportDir(dir string) {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	eh.crashDir = dir
+}
+
+// OnCrash registers a callback invoked after each recovered panic
+func (eh *ErrorHandler) OnCrash(fn func(CrashReport)) {
+	eh.mu.Lock()
+	defer eh.mu.Unlock()
+	eh.onCrash = fn
+}
+
 // RecoverPanic recovers from panics and converts them to errors
 func (eh *ErrorHandler) RecoverPanic() {
 	if r := recover(); r != nil {
-		err := fmt.Errorf("panic recovered: %v", r)
-		appErr := InternalError("panic occurred", err).
-			WithContext("panic_value", r).
-			WithContext("stack_trace", getStackTrace(3))
-		eh.logAppError(appErr)
+		eh.reportCrash("unknown", r)
 	}
 }
 
+// RecoverGoroutine recovers a panic in the named goroutine. It must be
+// deferred directly: defer eh.RecoverGoroutine("pty reader")
+func (eh *ErrorHandler) RecoverGoroutine(name string) {
+	if r := recover(); r != nil {
+		eh.reportCrash(name, r)
+	}
+}
+
+// Go runs fn in a new goroutine that reports instead of crashing on panic
+func (eh *ErrorHandler) Go(name string, fn func()) {
+	go func() {
+		defer eh.RecoverGoroutine(name)
+		fn()
+	}()
+}
+
 // WithRecover wraps a function with panic recovery
 func (eh *ErrorHandler) WithRecover(fn func() error) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = InternalError("panic in function", fmt.Errorf("%v", r))
-			eh.Handle(err)
+			report := eh.reportCrash("call", r)
+			err = InternalError("panic in function", fmt.Errorf("%v", r)).
+				WithContext("crash_report", report.File)
 		}
 	}()
 	return fn()
-}
\ No newline at end of file
+}
+
+// reportCrash logs a recovered panic, writes a crash report file and
+// notifies the OnCrash callback
+func (eh *ErrorHandler) reportCrash(goroutine string, r interface{}) CrashReport {
+	report := CrashReport{
+		Time:      time.Now().UTC(),
+		Goroutine: goroutine,
+		Panic:     fmt.Sprintf("%v", r),
+		Stack:     string(debug.Stack()),
+		Version:   AppVersion,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	eh.mu.RLock()
+	crashDir, onCrash := eh.crashDir, eh.onCrash
+	eh.mu.RUnlock()
+
+	if crashDir != "" {
+		file, err := writeCrashReport(crashDir, report)
+		if err != nil {
+			eh.logger.Error("Failed to write crash report", err)
+		}
+		report.File = file
+	}
+
+	appErr := InternalError("panic occurred", fmt.Errorf("panic recovered: %v", r)).
+		WithContext("goroutine", goroutine).
+		WithContext("crash_report", report.File)
+	appErr.StackTrace = report.Stack
+	eh.logAppError(appErr)
+
+	if onCrash != nil {
+		onCrash(report)
+	}
+	return report
+}
+
+// writeCrashReport saves report as JSON in dir and returns the file path
+func writeCrashReport(dir string, report CrashReport) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode crash report: %v", err)
+	}
+
+	name := fmt.Sprintf("crash-%s.json", report.Time.Format("20060102-150405.000000000"))
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %v", err)
+	}
+	return file, nil
+}
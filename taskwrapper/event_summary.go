@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Summaries are one-sentence descriptions of journal entries and runtime
+// events, written here so screen-reader announcements and notifications
+// read the same everywhere. Their messages are keyed "summary." plus the
+// event type or name, with a suffix for variants.
+const summaryKeyPrefix = "summary."
+
+// Message keys shared by summaries
+const (
+	MsgSummaryTask         = "summary.task"          // a task with its title
+	MsgSummaryTaskUntitled = "summary.task.untitled" // a task whose title isn't known
+	MsgSummaryUnknown      = "summary.unknown"       // an event with no summary of its own
+)
+
+// statusLabelKeys are the keys of the board's column names
+var statusLabelKeys = map[TaskStatus]string{
+	StatusBacklog:       "status.backlog",
+	StatusTodo:          "status.todo",
+	StatusDoing:         "status.doing",
+	StatusPendingReview: "status.pending_review",
+	StatusDone:          "status.done",
+}
+
+// summaryData reads values from a journal entry's data, which holds Go
+// values when it is fresh and JSON ones once read back from disk
+type summaryData map[string]interface{}
+
+func (d summaryData) str(key string) string {
+	if value, ok := d[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+func (d summaryData) num(key string) int {
+	switch value := d[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return 0
+}
+
+func (d summaryData) flag(key string) bool {
+	value, _ := d[key].(bool)
+	return value
+}
+
+// summarizer writes summaries in one locale, naming tasks by their titles
+// on the board, which tasks reads only when an event lacks the title
+type summarizer struct {
+	l     *Localizer
+	tasks func() []Task
+}
+
+// task names a task, with its title from data when given, else from the board
+func (s summarizer) task(id int, title string) string {
+	if title == "" && s.tasks != nil {
+		if task, ok := findTask(s.tasks(), id); ok {
+			title = task.Title
+		}
+	}
+	if title == "" {
+		return s.l.T(MsgSummaryTaskUntitled, id)
+	}
+	return s.l.T(MsgSummaryTask, id, title)
+}
+
+// status names a board column
+func (s summarizer) status(status string) string {
+	if key, ok := statusLabelKeys[TaskStatus(status)]; ok {
+		return s.l.T(key)
+	}
+	return status
+}
+
+// entry describes a journal entry
+func (s summarizer) entry(entry JournalEntry) string {
+	data := summaryData(entry.Data)
+	key := summaryKeyPrefix + entry.Type
+	task := s.task(entry.TaskID, data.str("title"))
+	switch entry.Type {
+	case EventTaskCreated, EventTaskUpdated, EventTaskApproved, EventTaskRejected,
+		EventAgentLaunched, EventAgentInterrupted:
+		return s.l.T(key, task)
+	case EventTaskMoved:
+		if data.flag("external") {
+			key += ".external"
+		}
+		return s.l.T(key, task, s.status(data.str("to")))
+	case EventAgentFailed:
+		return s.l.T(key, task, data.str("error"))
+	case EventAgentResult:
+		if !data.flag("success") {
+			return s.l.T(key+".failed", task)
+		}
+		return s.l.T(key, task)
+	case EventReviewChecked:
+		switch {
+		case data.str("skipped") != "":
+			return s.l.T(key+".skipped", task, data.str("skipped"))
+		case data.num("failed") > 0:
+			return s.l.T(key+".failed", task, data.num("failed"), data.num("checks"))
+		}
+		return s.l.T(key, task)
+	case EventChangesRequested:
+		return s.l.N(key, data.num("comments"), task, data.num("comments"))
+	case EventSLABreached:
+		return s.l.T(key, task, data.str("target"))
+	case EventBranchDeleted:
+		return s.l.T(key, data.str("branch"))
+	case EventTasksSaved:
+		return s.l.N(key, data.num("count"), data.num("count"))
+	case EventTasksImported:
+		return s.l.N(key, data.num("count"), data.num("count"), data.str("format"))
+	case EventTasksRestored:
+		return s.l.T(key, data.str("backup"))
+	case EventPlanSaved, EventPlanPushed, EventPlanPulled, EventRepoEncrypted, EventRepoDecrypted,
+		EventChangelogWritten:
+		return s.l.T(key)
+	case EventSnapshotCreated, EventSnapshotRestored:
+		label := data.str("label")
+		if label == "" {
+			label = data.str("id")
+		}
+		return s.l.T(key, label)
+	case EventConfigChanged:
+		settings := make([]string, 0, len(data))
+		for setting := range data {
+			settings = append(settings, setting)
+		}
+		sort.Strings(settings)
+		return s.l.T(key, strings.Join(settings, ", "))
+	case EventRepoAdded, EventRepoSwitched:
+		return s.l.T(key, data.str("name"))
+	case EventRepoRemoved:
+		return s.l.T(key, data.str("id"))
+	case EventAutoPilotChanged:
+		return s.toggle(key, data.flag("paused"))
+	case EventAppCrashed:
+		return s.l.T(key, data.str("goroutine"))
+	case EventAutomationFired:
+		if data.str("error") != "" {
+			return s.l.T(key+".failed", data.str("rule"), data.str("error"))
+		}
+		return s.l.T(key, data.str("rule"))
+	case EventMilestoneCreated, EventMilestoneUpdated:
+		return s.l.T(key, data.str("name"))
+	case EventMilestoneDeleted:
+		return s.l.T(key, data.str("milestone"))
+	case EventWorktreesPruned:
+		return s.l.N(key, data.num("removed"), data.num("removed"), formatBytes(int64(data.num("freedBytes"))))
+	case EventWorktreesWarmed:
+		return s.l.N(key, data.num("created"), data.num("created"))
+	case EventAgentsDetached:
+		tasks, _ := data["tasks"].([]int)
+		count := len(tasks)
+		if loaded, ok := data["tasks"].([]interface{}); ok {
+			count = len(loaded)
+		}
+		return s.l.N(key, count, count)
+	case EventReleaseCreated:
+		return s.l.T(key, data.str("version"))
+	}
+	return s.l.T(MsgSummaryUnknown, entry.Type)
+}
+
+// toggle picks the ".on" or ".off" variant of key
+func (s summarizer) toggle(key string, on bool) string {
+	if on {
+		return s.l.T(key + ".on")
+	}
+	return s.l.T(key + ".off")
+}
+
+// runtime describes a runtime event and its payload
+func (s summarizer) runtime(name RuntimeEvent, payload interface{}) string {
+	key := summaryKeyPrefix + string(name)
+	switch value := payload.(type) {
+	case JournalEntry:
+		return s.entry(value)
+	case TasksCorruptedEvent:
+		return s.l.T(key, filepath.Base(value.File))
+	case TaskStatus:
+		return s.l.T(key, s.status(string(value)))
+	case StaleReport:
+		return s.l.N(key, len(value.Tasks), len(value.Tasks))
+	case ReviewReadyEvent:
+		failed := 0
+		for _, check := range value.Checks {
+			if !check.Passed {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return s.l.T(key+".failed", s.task(value.TaskID, value.Title), failed, len(value.Checks))
+		}
+		return s.l.T(key, s.task(value.TaskID, value.Title))
+	case []Task, QuotaStatus:
+		return s.l.T(key)
+	case bool:
+		return s.toggle(key, value)
+	case AutomationNotice:
+		return value.Message
+	case []InterruptedTask:
+		return s.l.N(key, len(value), len(value))
+	case ConfirmationRequest:
+		return s.l.T(key+"."+value.Action, value.Name)
+	case TerminalTitleEvent:
+		return s.l.T(key, value.Title)
+	case Job:
+		switch value.State {
+		case JobSucceeded, JobFailed, JobCancelled:
+			return s.l.T(key+"."+string(value.State), value.Title)
+		}
+		if value.Progress < 0 {
+			return s.l.T(key+".running", value.Title)
+		}
+		return s.l.T(key, value.Title, int(value.Progress*100))
+	case TaskLoadProgress:
+		if value.Done {
+			return s.l.T(key+".done", value.Tasks)
+		}
+		return s.l.T(key, int(value.Fraction()*100))
+	case CrashReport:
+		return s.l.T(key, value.Goroutine)
+	case HealthReport:
+		return s.l.T(key)
+	case []RunningAgent:
+		return s.l.N(key, len(value), len(value))
+	case nil:
+		return s.l.T(key)
+	}
+	return s.l.T(MsgSummaryUnknown, string(name))
+}
@@ -0,0 +1,108 @@
+package main
+
+import "reflect"
+
+// RuntimeEvent names an event sent to the frontend through the Wails runtime.
+// Unlike journal events these are not persisted; they tell an open window
+// something changed.
+type RuntimeEvent string
+
+// Runtime events, grouped by the part of the app that emits them
+const (
+	// Tasks
+	RuntimeTasksCorrupted RuntimeEvent = "tasks:corrupted"
+	RuntimeJournalEntry   RuntimeEvent = "journal:entry"
+	RuntimeQuickAddOpen   RuntimeEvent = "quickadd:open"
+	RuntimeNavigate       RuntimeEvent = "navigate"
+	RuntimeTasksStale     RuntimeEvent = "tasks:stale"
+	RuntimeReviewReady    RuntimeEvent = "review:ready"
+	RuntimeTasksChanged   RuntimeEvent = "tasks:changed"
+	RuntimeTasksLoading   RuntimeEvent = "tasks:loading"
+
+	// Agents
+	RuntimeQuotaExceeded     RuntimeEvent = "quota:exceeded"
+	RuntimeAutoPilotChanged  RuntimeEvent = "autopilot:changed"
+	RuntimeAutomationNotice  RuntimeEvent = "automation:notice"
+	RuntimeAgentsInterrupted RuntimeEvent = "agents:interrupted"
+
+	// Config and repositories
+	RuntimeSafeModeChanged      RuntimeEvent = "safemode:changed"
+	RuntimeConfirmationRequired RuntimeEvent = "confirmation:required"
+
+	// Terminals
+	RuntimeTerminalTitle RuntimeEvent = "terminal:title"
+
+	// App and background work
+	RuntimeJobProgress       RuntimeEvent = "job:progress"
+	RuntimeAppCrash          RuntimeEvent = "app:crash"
+	RuntimeHealthDegraded    RuntimeEvent = "health:degraded"
+	RuntimeShutdownRequested RuntimeEvent = "shutdown:requested"
+)
+
+// TasksCorruptedEvent is sent when task.json fails its integrity check
+type TasksCorruptedEvent struct {
+	File   string `json:"file"`
+	Backup string `json:"backup,omitempty"` // newest valid backup to offer for restore
+}
+
+// ConfirmationRequest asks the user to confirm an action for a repository
+type ConfirmationRequest struct {
+	Action     string `json:"action"`
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+}
+
+// RuntimeEventSpec documents one runtime event and its payload
+type RuntimeEventSpec struct {
+	Name        RuntimeEvent `json:"name"`
+	Payload     string       `json:"payload,omitempty"` // Go type of the payload; empty if the event has none
+	Description string       `json:"description"`
+	payloadType reflect.Type
+}
+
+// runtimeEventSpec builds a catalog entry; payload is a zero value of the
+// payload type, or nil for none
+func runtimeEventSpec(name RuntimeEvent, payload interface{}, description string) RuntimeEventSpec {
+	spec := RuntimeEventSpec{Name: name, Description: description}
+	if payload != nil {
+		spec.payloadType = reflect.TypeOf(payload)
+		spec.Payload = spec.payloadType.String()
+	}
+	return spec
+}
+
+// runtimeEvents is the contract between emitters and the frontend. Every
+// event the backend sends is listed here with the one payload type it carries.
+// The payload is followed by a localized one-line summary of the event, so
+// announcements and notifications don't have to be written in the frontend.
+var runtimeEvents = []RuntimeEventSpec{
+	runtimeEventSpec(RuntimeTasksCorrupted, TasksCorruptedEvent{}, "task.json failed its integrity check"),
+	runtimeEventSpec(RuntimeJournalEntry, JournalEntry{}, "an action was recorded in the journal"),
+	runtimeEventSpec(RuntimeQuickAddOpen, nil, "the quick-add hotkey was pressed"),
+	runtimeEventSpec(RuntimeNavigate, TaskStatus(""), "the tray asked to show a board column"),
+	runtimeEventSpec(RuntimeTasksStale, StaleReport{}, "tasks went past their stale threshold"),
+	runtimeEventSpec(RuntimeReviewReady, ReviewReadyEvent{}, "an agent handed a task over for review and its checks ran"),
+	runtimeEventSpec(RuntimeTasksChanged, []Task{}, "task.json was edited outside the app and reloaded"),
+	runtimeEventSpec(RuntimeTasksLoading, TaskLoadProgress{}, "a large task.json is loading, or has loaded (done)"),
+	runtimeEventSpec(RuntimeQuotaExceeded, QuotaStatus{}, "an agent launch was refused by the launch quota"),
+	runtimeEventSpec(RuntimeAutoPilotChanged, false, "auto-pilot was paused (true) or resumed (false)"),
+	runtimeEventSpec(RuntimeAutomationNotice, AutomationNotice{}, "an automation rule's notify action ran"),
+	runtimeEventSpec(RuntimeAgentsInterrupted, []InterruptedTask{}, "startup found tasks in doing whose agents had died"),
+	runtimeEventSpec(RuntimeSafeModeChanged, false, "safe mode was turned on or off"),
+	runtimeEventSpec(RuntimeConfirmationRequired, ConfirmationRequest{}, "an action needs confirming for this repository"),
+	runtimeEventSpec(RuntimeTerminalTitle, TerminalTitleEvent{}, "a terminal's window title changed"),
+	runtimeEventSpec(RuntimeJobProgress, Job{}, "a long-running job progressed"),
+	runtimeEventSpec(RuntimeAppCrash, CrashReport{}, "a background goroutine panicked"),
+	runtimeEventSpec(RuntimeHealthDegraded, HealthReport{}, "the startup self-check found problems"),
+	runtimeEventSpec(RuntimeShutdownRequested, []RunningAgent{}, "quitting is waiting for ConfirmShutdown because agents are running"),
+}
+
+// lookupRuntimeEvent returns the catalog entry for name
+func lookupRuntimeEvent(name RuntimeEvent) (RuntimeEventSpec, bool) {
+	for _, spec := range runtimeEvents {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return RuntimeEventSpec{}, false
+}
@@ -0,0 +1,92 @@
+package main
+
+import "sort"
+
+// Feature flags for risky or experimental subsystems, so they can ship
+// dark and be turned on for one repository or everywhere
+const (
+	FeatureAutoPilot   = "auto-pilot"   // launch an agent when a task moves from todo to doing
+	FeaturePRMode      = "pr-mode"      // approvals open a pull request instead of merging
+	FeatureSQLiteStore = "sqlite-store" // keep tasks in SQLite instead of task.json
+)
+
+// Scopes a feature flag can be set in; a repository's setting wins over
+// the global one, which wins over the flag's default
+const (
+	FeatureScopeGlobal     = "global"
+	FeatureScopeRepository = "repository"
+)
+
+// featureFlagSpec describes a flag the backend knows about
+type featureFlagSpec struct {
+	name        string
+	defaultOn   bool
+	description string
+}
+
+// featureFlagSpecs are the known flags; setting any other is refused, so a
+// typo can't silently do nothing
+var featureFlagSpecs = []featureFlagSpec{
+	{FeatureAutoPilot, true, "Launch an agent when a task moves from todo to doing"},
+	{FeaturePRMode, false, "Open a pull request on approval instead of merging (not built yet; nothing reads it)"},
+	{FeatureSQLiteStore, false, "Store tasks in SQLite instead of task.json (not built yet; nothing reads it)"},
+}
+
+// FeatureFlag is a flag's settings and the value they resolve to for the
+// active repository
+type FeatureFlag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	Global      *bool  `json:"global,omitempty"`     // nil when not set globally
+	Repository  *bool  `json:"repository,omitempty"` // nil when not set for the active repository
+	Enabled     bool   `json:"enabled"`
+}
+
+// findFeatureFlag returns the spec of a known flag
+func findFeatureFlag(name string) (featureFlagSpec, bool) {
+	for _, spec := range featureFlagSpecs {
+		if spec.name == name {
+			return spec, true
+		}
+	}
+	return featureFlagSpec{}, false
+}
+
+// resolveFeatureFlags combines the defaults with the global and repository
+// settings, in name order
+func resolveFeatureFlags(global, repository map[string]bool) []FeatureFlag {
+	flags := make([]FeatureFlag, 0, len(featureFlagSpecs))
+	for _, spec := range featureFlagSpecs {
+		flag := FeatureFlag{Name: spec.name, Description: spec.description, Default: spec.defaultOn, Enabled: spec.defaultOn}
+		if on, ok := global[spec.name]; ok {
+			flag.Global = &on
+			flag.Enabled = on
+		}
+		if on, ok := repository[spec.name]; ok {
+			flag.Repository = &on
+			flag.Enabled = on
+		}
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// setFeatureFlag returns flags with name set to enabled, or cleared when
+// enabled is nil; the map is copied so a saved config isn't changed in place
+func setFeatureFlag(flags map[string]bool, name string, enabled *bool) map[string]bool {
+	updated := make(map[string]bool, len(flags)+1)
+	for key, value := range flags {
+		updated[key] = value
+	}
+	if enabled == nil {
+		delete(updated, name)
+	} else {
+		updated[name] = *enabled
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+	return updated
+}
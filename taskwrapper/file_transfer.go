@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxTransferBytes caps a file moved in or out through the app; anything
+// bigger should go through git or scp
+const maxTransferBytes = 10 * 1024 * 1024
+
+// FileTransfer describes a file moved between the UI and the repository
+type FileTransfer struct {
+	Path string `json:"path"` // relative to the repository root
+	Size int    `json:"size"`
+}
+
+// FileTransferService reads and writes small files inside a repository for
+// clients that can't reach its filesystem, such as the remote web UI. Every
+// transfer is audited.
+type FileTransferService struct {
+	logger        Logger
+	mu            sync.RWMutex
+	pathValidator *PathValidator
+	audit         *AuditService
+}
+
+// NewFileTransferService creates a file transfer service
+func NewFileTransferService(logger Logger) *FileTransferService {
+	return &FileTransferService{
+		logger:        logger,
+		pathValidator: NewPathValidator(DefaultSecurityConfig(), logger),
+	}
+}
+
+// SetSecurityConfig replaces the policy transferred paths are checked against
+func (ft *FileTransferService) SetSecurityConfig(config *SecurityConfig) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.pathValidator = NewPathValidator(config, ft.logger)
+}
+
+// SetAuditLog records transfers into audit
+func (ft *FileTransferService) SetAuditLog(audit *AuditService) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.audit = audit
+}
+
+// Upload writes data to path inside root, creating missing directories and
+// replacing an existing file
+func (ft *FileTransferService) Upload(root, path string, data []byte) (FileTransfer, error) {
+	if len(data) > maxTransferBytes {
+		return FileTransfer{}, ValidationError(fmt.Sprintf("file is larger than %d MB", maxTransferBytes/(1024*1024)), nil).
+			WithContext("size", len(data))
+	}
+	target, rel, err := ft.resolve(root, path)
+	if err != nil {
+		return FileTransfer{}, err
+	}
+	if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+		return FileTransfer{}, ConflictError("path exists and is not a regular file", nil).WithContext("path", rel)
+	}
+	// Check the deepest directory that exists before creating the rest, so
+	// a symlinked directory can't have directories made outside the root
+	existing := filepath.Dir(target)
+	for {
+		if _, err := os.Stat(existing); err == nil || existing == filepath.Dir(existing) {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	if err := withinRoot(root, existing); err != nil {
+		return FileTransfer{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return FileTransfer{}, fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := NewFileUtils(ft.logger).AtomicWrite(target, data); err != nil {
+		return FileTransfer{}, fmt.Errorf("failed to write %s: %v", rel, err)
+	}
+	transfer := FileTransfer{Path: rel, Size: len(data)}
+	ft.record(AuditFileUploaded, transfer)
+	return transfer, nil
+}
+
+// Download reads path inside root
+func (ft *FileTransferService) Download(root, path string) ([]byte, FileTransfer, error) {
+	target, rel, err := ft.resolve(root, path)
+	if err != nil {
+		return nil, FileTransfer{}, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, FileTransfer{}, NotFoundError("file not found", err).WithContext("path", rel)
+		}
+		return nil, FileTransfer{}, fmt.Errorf("failed to read %s: %v", rel, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, FileTransfer{}, ValidationError("path is not a regular file", nil).WithContext("path", rel)
+	}
+	if info.Size() > maxTransferBytes {
+		return nil, FileTransfer{}, ValidationError(fmt.Sprintf("file is larger than %d MB", maxTransferBytes/(1024*1024)), nil).
+			WithContext("path", rel)
+	}
+	if err := withinRoot(root, target); err != nil {
+		return nil, FileTransfer{}, err
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		return nil, FileTransfer{}, fmt.Errorf("failed to read %s: %v", rel, err)
+	}
+	transfer := FileTransfer{Path: rel, Size: len(data)}
+	ft.record(AuditFileDownloaded, transfer)
+	return data, transfer, nil
+}
+
+// record audits a finished transfer
+func (ft *FileTransferService) record(action string, transfer FileTransfer) {
+	ft.mu.RLock()
+	audit := ft.audit
+	ft.mu.RUnlock()
+
+	if audit != nil {
+		audit.Record(action, 0, map[string]interface{}{
+			"path": transfer.Path,
+			"size": transfer.Size,
+		}, nil)
+	}
+}
+
+// resolve turns a path relative to root into an absolute one that the
+// security policy allows, returning it with the cleaned relative path
+func (ft *FileTransferService) resolve(root, path string) (string, string, error) {
+	if root == "" {
+		return "", "", fmt.Errorf("no repository is open")
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimSpace(path)))
+	if path == "" || rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", ValidationError("path must be relative to the repository root", nil).WithContext("path", path)
+	}
+	// Case-insensitive file systems open .GIT as .git, and submodules and
+	// nested repositories keep theirs further down
+	for _, segment := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.EqualFold(segment, ".git") {
+			return "", "", PermissionError("the .git directory can't be transferred", nil).WithContext("path", path)
+		}
+	}
+
+	ft.mu.RLock()
+	pathValidator := ft.pathValidator
+	ft.mu.RUnlock()
+
+	target, err := pathValidator.ValidatePath(filepath.Join(root, rel))
+	if err != nil {
+		return "", "", PermissionError(err.Error(), err).WithContext("path", path)
+	}
+	return target, filepath.ToSlash(rel), nil
+}
+
+// withinRoot refuses a path whose symlinks lead outside root
+func withinRoot(root, path string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve repository root: %v", err)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", path, err)
+	}
+	if rel, err := filepath.Rel(realRoot, realPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return PermissionError("path leads outside the repository", nil).WithContext("path", path)
+	}
+	return nil
+}
@@ -6,12 +6,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 // FileUtils provides atomic file operations with backup and rollback
 type FileUtils struct {
-	logger Logger
+	logger    Logger
+	backupDir string  // empty keeps backups next to the file
+	cipher    *Cipher // nil writes plain files
 }
 
 // NewFileUtils creates a new file utilities instance
@@ -51,8 +54,17 @@ func (fu *FileUtils) AtomicWriteJSON(filePath string, data interface{}) error {
 	return nil
 }
 
-// AtomicWrite performs an atomic file write operation
+// AtomicWrite performs an atomic file write operation, encrypting data when
+// a cipher is set
 func (fu *FileUtils) AtomicWrite(filePath string, data []byte) error {
+	if fu.cipher != nil {
+		sealed, err := fu.cipher.Encrypt(data)
+		if err != nil {
+			return err
+		}
+		data = sealed
+	}
+
 	// Ensure directory exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -85,6 +97,42 @@ func (fu *FileUtils) AtomicWrite(filePath string, data []byte) error {
 	return nil
 }
 
+// ReadFile reads a file written by AtomicWrite, decrypting it if needed.
+// Plain files are returned as-is, so a repository can be read mid-migration.
+func (fu *FileUtils) ReadFile(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil || !isEncryptedData(data) {
+		return data, err
+	}
+	if fu.cipher == nil {
+		return nil, ValidationError("file is encrypted and no key is loaded", nil).WithContext("file", filePath)
+	}
+	return fu.cipher.Decrypt(data)
+}
+
+// SetCipher turns encryption on for subsequent writes; nil turns it off
+func (fu *FileUtils) SetCipher(cipher *Cipher) {
+	fu.cipher = cipher
+}
+
+// Encrypted reports whether writes are encrypted
+func (fu *FileUtils) Encrypted() bool {
+	return fu.cipher != nil
+}
+
+// SetBackupDir sets where backups are written; empty keeps them next to the file
+func (fu *FileUtils) SetBackupDir(dir string) {
+	fu.backupDir = dir
+}
+
+// BackupDir returns the directory holding backups of filePath
+func (fu *FileUtils) BackupDir(filePath string) string {
+	if fu.backupDir == "" {
+		return filepath.Dir(filePath)
+	}
+	return fu.backupDir
+}
+
 // CreateBackup creates a timestamped backup of a file
 func (fu *FileUtils) CreateBackup(filePath string) (string, error) {
 	// Check if file exists
@@ -93,8 +141,10 @@ func (fu *FileUtils) CreateBackup(filePath string) (string, error) {
 	}
 
 	// Generate backup filename
-	timestamp := time.Now().Format("20060102_150405")
-	backupPath := fmt.Sprintf("%s.backup.%s", filePath, timestamp)
+	backupPath := backupFilePath(filePath, fu.backupDir, time.Now())
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
 
 	// Copy file
 	if err := fu.CopyFile(filePath, backupPath); err != nil {
@@ -198,4 +248,56 @@ func (fu *FileUtils) CleanupOldBackups(pattern string, maxAge time.Duration) err
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// backupFilePath names the backup of filePath taken at t, in backupDir or
+// next to the file when backupDir is empty
+func backupFilePath(filePath, backupDir string, t time.Time) string {
+	name := fmt.Sprintf("%s.backup.%s", filepath.Base(filePath), t.Format("20060102_150405"))
+	if backupDir == "" {
+		return filepath.Join(filepath.Dir(filePath), name)
+	}
+	return filepath.Join(backupDir, name)
+}
+
+// migrateBackups moves backup files from srcDir into destDir, returning how
+// many were moved
+func migrateBackups(srcDir, destDir string) (int, error) {
+	return migrateFiles(srcDir, destDir, "*.backup.*")
+}
+
+// migrateFiles moves the files in srcDir matching pattern into destDir,
+// skipping ones still being written, and returns how many were moved
+func migrateFiles(srcDir, destDir, pattern string) (int, error) {
+	if filepath.Clean(srcDir) == filepath.Clean(destDir) {
+		return 0, nil
+	}
+	files, err := filepath.Glob(filepath.Join(srcDir, pattern))
+	if err != nil || len(files) == 0 {
+		return 0, err
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create directory %s: %w", destDir, err)
+	}
+
+	moved := 0
+	for _, src := range files {
+		if strings.Contains(filepath.Base(src), ".tmp.") {
+			continue
+		}
+		dest := filepath.Join(destDir, filepath.Base(src))
+		if err := os.Rename(src, dest); err != nil {
+			// Rename fails across filesystems; fall back to copy and remove
+			data, readErr := os.ReadFile(src)
+			if readErr != nil {
+				return moved, readErr
+			}
+			if writeErr := os.WriteFile(dest, data, 0644); writeErr != nil {
+				return moved, writeErr
+			}
+			os.Remove(src)
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -3,8 +3,10 @@ import { DragDropContext, DropResult } from '@hello-pangea/dnd';
 import { motion } from 'framer-motion';
 import { Task, STATUS_LABELS } from '../types/task';
 import { LoadTasks, SaveTasks, MoveTask, ApproveTask, RejectTask } from '../../wailsjs/go/main/App';
+import { EventsOn } from '../../wailsjs/runtime/runtime';
 import Column from './Column';
 import Header from './Header';
+import QuickAdd from './QuickAdd';
 
 const KanbanBoard: React.FC = () => {
   const [tasks, setTasks] = useState<Task[]>([]);
@@ -12,12 +14,38 @@ const KanbanBoard: React.FC = () => {
   const [error, setError] = useState<string | null>(null);
   const [lastSaved, setLastSaved] = useState<Date | null>(null);
   const [hideComplete, setHideComplete] = useState(false);
+  const [announcement, setAnnouncement] = useState('');
+  const [quickAddOpen, setQuickAddOpen] = useState(false);
 
   // Load tasks on component mount
   useEffect(() => {
     loadTasks();
   }, []);
 
+  // Pick up edits made to task.json outside the app, such as an agent
+  // handing its task over for review
+  useEffect(() => {
+    return EventsOn('tasks:changed', (changed: Task[]) => {
+      setTasks(changed || []);
+    });
+  }, []);
+
+  // Read out what just happened; every event ends with a summary written
+  // by the backend in the user's locale
+  useEffect(() => {
+    return EventsOn('journal:entry', (_entry: unknown, summary: string) => {
+      setAnnouncement(summary || '');
+    });
+  }, []);
+
+  // The global quick-add hotkey brings the window up and opens the capture
+  // prompt
+  useEffect(() => {
+    return EventsOn('quickadd:open', () => {
+      setQuickAddOpen(true);
+    });
+  }, []);
+
   const loadTasks = async () => {
     try {
       setLoading(true);
@@ -163,6 +191,9 @@ const KanbanBoard: React.FC = () => {
 
   return (
     <div className="h-full flex flex-col bg-gray-50">
+      <div role="status" aria-live="polite" className="sr-only">
+        {announcement}
+      </div>
       <Header 
         lastSaved={lastSaved} 
         error={error} 
@@ -191,6 +222,12 @@ const KanbanBoard: React.FC = () => {
           </div>
         </DragDropContext>
       </main>
+      {quickAddOpen && (
+        <QuickAdd
+          onCreateTask={(title) => createTask(title, 'backlog')}
+          onClose={() => setQuickAddOpen(false)}
+        />
+      )}
     </div>
   );
 };
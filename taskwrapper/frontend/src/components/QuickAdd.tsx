@@ -0,0 +1,67 @@
+import React from 'react';
+import { motion } from 'framer-motion';
+import { Plus } from 'lucide-react';
+
+interface QuickAddProps {
+  onCreateTask: (title: string) => void;
+  onClose: () => void;
+}
+
+// QuickAdd is the capture prompt the global quick-add hotkey opens: type a
+// title, press Enter, and it lands in the backlog
+const QuickAdd: React.FC<QuickAddProps> = ({ onCreateTask, onClose }) => {
+  const [title, setTitle] = React.useState('');
+
+  const handleCreate = () => {
+    if (title.trim()) {
+      onCreateTask(title.trim());
+      onClose();
+    }
+  };
+
+  const handleKeyDown = (e: React.KeyboardEvent) => {
+    if (e.key === 'Enter') {
+      handleCreate();
+    } else if (e.key === 'Escape') {
+      onClose();
+    }
+  };
+
+  return (
+    <div
+      className="fixed inset-0 z-50 flex items-start justify-center pt-32 bg-black bg-opacity-30"
+      onClick={onClose}
+    >
+      <motion.div
+        initial={{ y: -20, opacity: 0 }}
+        animate={{ y: 0, opacity: 1 }}
+        role="dialog"
+        aria-label="Quick add task"
+        className="w-full max-w-md p-4 bg-white rounded-lg shadow-xl"
+        onClick={(e) => e.stopPropagation()}
+      >
+        <div className="flex items-center space-x-2">
+          <Plus className="w-4 h-4 text-gray-400" />
+          <input
+            type="text"
+            value={title}
+            onChange={(e) => setTitle(e.target.value)}
+            onKeyDown={handleKeyDown}
+            placeholder="Add a task to the backlog..."
+            className="flex-1 px-3 py-2 text-sm border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-primary-500 focus:border-transparent"
+            autoFocus
+          />
+          <button
+            onClick={handleCreate}
+            disabled={!title.trim()}
+            className="px-3 py-2 text-sm font-medium text-white bg-primary-600 rounded-md hover:bg-primary-700 disabled:opacity-50 disabled:cursor-not-allowed"
+          >
+            Add
+          </button>
+        </div>
+      </motion.div>
+    </div>
+  );
+};
+
+export default QuickAdd;
@@ -205,8 +205,9 @@ const Terminal: React.FC<TerminalProps> = ({ className = '' }) => {
         wsRef.current = null;
       }
 
-      // Connect to WebSocket server running on the Wails backend
-      const wsUrl = `ws://localhost:8080/ws/terminal/${termId}`;
+      // Connect to WebSocket server running on the Wails backend, which
+      // listens on the loopback address only (terminalWSAddr)
+      const wsUrl = `ws://127.0.0.1:8080/ws/terminal/${termId}`;
       const ws = new WebSocket(wsUrl);
       
       let isRestoring = false;
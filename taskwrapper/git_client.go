@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GitWorktree is one entry of `git worktree list --porcelain`
+type GitWorktree struct {
+	Path   string
+	Branch string // short branch name, empty when detached
+}
+
+// GitClient is the set of git operations AgentService needs
+type GitClient interface {
+	BranchExists(ctx context.Context, dir, branch string) (bool, error)
+	Merge(ctx context.Context, dir, branch, message string, signing *SigningConfig) error
+	CheckSigning(ctx context.Context, dir string, signing *SigningConfig) error
+	AbortMerge(ctx context.Context, dir string) error
+	DeleteBranch(ctx context.Context, dir, branch string, force bool) error
+	ListWorktrees(ctx context.Context, dir string) ([]GitWorktree, error)
+	IsClean(ctx context.Context, dir string) (bool, error)
+	RemoveWorktree(ctx context.Context, dir, path string) error
+	AddWorktree(ctx context.Context, dir, path, ref string) error
+	AddBranchWorktree(ctx context.Context, dir, path, branch string) error
+	CurrentBranch(ctx context.Context, dir string) (string, error)
+	HeadCommit(ctx context.Context, dir string) (string, error)
+	ListMerges(ctx context.Context, dir, revRange string) ([]GitCommit, error)
+	LatestTag(ctx context.Context, dir, ref string) (string, error)
+	CommitFile(ctx context.Context, dir, path, message string, signing *SigningConfig) error
+	TagExists(ctx context.Context, dir, tag string) (bool, error)
+	CreateTag(ctx context.Context, dir, tag, ref, message string, signing *SigningConfig) error
+	ListCommits(ctx context.Context, dir, revRange string) ([]GitCommit, error)
+	Diff(ctx context.Context, dir, revRange string, paths ...string) (string, error)
+	DiffSummary(ctx context.Context, dir, revRange string) (string, error)
+	FileAuthors(ctx context.Context, dir, ref, since, path string) ([]FileAuthor, error)
+	UserEmail(ctx context.Context, dir string) (string, error)
+	CheckoutDetached(ctx context.Context, dir, ref string) error
+	Fetch(ctx context.Context, dir, remote string) error
+	FastForward(ctx context.Context, dir, branch, upstream string) error
+	ListBranches(ctx context.Context, dir, pattern string) ([]string, error)
+	PruneWorktrees(ctx context.Context, dir string) error
+	ResetWorktree(ctx context.Context, dir string) error
+	CheckoutBranch(ctx context.Context, dir, branch string) error
+	CheckoutNewBranch(ctx context.Context, dir, branch string) error
+}
+
+// CLIGitClient implements GitClient by running the git binary
+type CLIGitClient struct {
+	runner ProcessRunner
+}
+
+// NewGitClient creates a git client that runs commands through runner
+func NewGitClient(runner ProcessRunner) *CLIGitClient {
+	return &CLIGitClient{runner: runner}
+}
+
+// git runs a git subcommand in dir, returning combined output
+func (gc *CLIGitClient) git(ctx context.Context, dir string, args ...string) (string, error) {
+	output, err := gc.runner.CombinedOutput(ctx, Command{Name: "git", Args: args, Dir: dir})
+	return string(output), err
+}
+
+// BranchExists reports whether a local branch exists
+func (gc *CLIGitClient) BranchExists(ctx context.Context, dir, branch string) (bool, error) {
+	output, err := gc.git(ctx, dir, "branch", "--list", branch)
+	if err != nil {
+		return false, fmt.Errorf("git branch check failed: %v", err)
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// Merge merges branch into the current branch with a merge commit, signed
+// as signing says when it's set
+func (gc *CLIGitClient) Merge(ctx context.Context, dir, branch, message string, signing *SigningConfig) error {
+	args := append(signingArgs(signing), "merge", branch, "--no-ff", "-m", message)
+	if signing != nil {
+		args = append(args, "-S")
+	}
+	output, err := gc.git(ctx, dir, args...)
+	if err != nil {
+		return fmt.Errorf("git merge failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// CheckSigning signs a throwaway commit of HEAD's tree, which no ref points
+// to, to prove signing works before a merge depends on it
+func (gc *CLIGitClient) CheckSigning(ctx context.Context, dir string, signing *SigningConfig) error {
+	args := append(signingArgs(signing), "commit-tree", "-S", "HEAD^{tree}", "-p", "HEAD", "-m", signingCheckMessage)
+	output, err := gc.git(ctx, dir, args...)
+	if err != nil {
+		return fmt.Errorf("git commit signing failed: %v - %s", err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// AbortMerge abandons an in-progress merge
+func (gc *CLIGitClient) AbortMerge(ctx context.Context, dir string) error {
+	output, err := gc.git(ctx, dir, "merge", "--abort")
+	if err != nil {
+		return fmt.Errorf("git merge abort failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// ListBranches returns the local branches matching a glob pattern
+func (gc *CLIGitClient) ListBranches(ctx context.Context, dir, pattern string) ([]string, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"branch", "--list", pattern, "--format=%(refname:short)"}, Dir: dir})
+	if err != nil {
+		return nil, fmt.Errorf("git branch list failed: %v", err)
+	}
+	branches := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			branches = append(branches, line)
+		}
+	}
+	return branches, nil
+}
+
+// DeleteBranch deletes a local branch; force also deletes unmerged branches
+func (gc *CLIGitClient) DeleteBranch(ctx context.Context, dir, branch string, force bool) error {
+	flag, label := "-d", "git branch delete"
+	if force {
+		flag, label = "-D", "git branch force delete"
+	}
+	output, err := gc.git(ctx, dir, "branch", flag, branch)
+	if err != nil {
+		return fmt.Errorf("%s failed: %v - %s", label, err, output)
+	}
+	return nil
+}
+
+// ListWorktrees returns the repository's worktrees
+func (gc *CLIGitClient) ListWorktrees(ctx context.Context, dir string) ([]GitWorktree, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"worktree", "list", "--porcelain"}, Dir: dir})
+	if err != nil {
+		return nil, fmt.Errorf("git worktree list failed: %v", err)
+	}
+	return parseWorktreeList(string(output)), nil
+}
+
+// IsClean reports whether a checkout has no uncommitted or untracked changes
+func (gc *CLIGitClient) IsClean(ctx context.Context, dir string) (bool, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"status", "--porcelain"}, Dir: dir})
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %v", err)
+	}
+	return strings.TrimSpace(string(output)) == "", nil
+}
+
+// RemoveWorktree deletes a worktree of the repository in dir. Git refuses
+// worktrees with local changes.
+func (gc *CLIGitClient) RemoveWorktree(ctx context.Context, dir, path string) error {
+	output, err := gc.git(ctx, dir, "worktree", "remove", path)
+	if err != nil {
+		return fmt.Errorf("git worktree remove failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// AddWorktree checks ref out, detached, into a new worktree at path
+func (gc *CLIGitClient) AddWorktree(ctx context.Context, dir, path, ref string) error {
+	output, err := gc.git(ctx, dir, "worktree", "add", "--detach", path, ref)
+	if err != nil {
+		return fmt.Errorf("git worktree add failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// AddBranchWorktree checks branch itself out into a new worktree at path.
+// Git refuses a branch that's checked out elsewhere.
+func (gc *CLIGitClient) AddBranchWorktree(ctx context.Context, dir, path, branch string) error {
+	output, err := gc.git(ctx, dir, "worktree", "add", path, branch)
+	if err != nil {
+		return fmt.Errorf("git worktree add failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// CurrentBranch returns the branch checked out in dir, or "" on a detached HEAD
+func (gc *CLIGitClient) CurrentBranch(ctx context.Context, dir string) (string, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"rev-parse", "--abbrev-ref", "HEAD"}, Dir: dir})
+	if err != nil {
+		return "", fmt.Errorf("git current branch failed: %v", err)
+	}
+	branch := strings.TrimSpace(string(output))
+	if branch == "HEAD" {
+		return "", nil
+	}
+	return branch, nil
+}
+
+// HeadCommit returns the commit checked out in dir
+func (gc *CLIGitClient) HeadCommit(ctx context.Context, dir string) (string, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"rev-parse", "HEAD"}, Dir: dir})
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListMerges returns the merge commits in revRange, newest first
+func (gc *CLIGitClient) ListMerges(ctx context.Context, dir, revRange string) ([]GitCommit, error) {
+	return gc.log(ctx, dir, "--merges", revRange)
+}
+
+// ListCommits returns the commits in revRange, newest first
+func (gc *CLIGitClient) ListCommits(ctx context.Context, dir, revRange string) ([]GitCommit, error) {
+	return gc.log(ctx, dir, revRange)
+}
+
+// log lists the commits git log selects with args
+func (gc *CLIGitClient) log(ctx context.Context, dir string, args ...string) ([]GitCommit, error) {
+	args = append([]string{"log", "--format=%H%x1f%cI%x1f%s"}, args...)
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: args, Dir: dir})
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %v", err)
+	}
+	commits := []GitCommit{}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, "\x1f", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		committed, _ := time.Parse(time.RFC3339, fields[1])
+		commits = append(commits, GitCommit{Hash: fields[0], Time: committed, Subject: fields[2]})
+	}
+	return commits, nil
+}
+
+// LatestTag returns the newest tag reachable from ref, or "" if there is none
+func (gc *CLIGitClient) LatestTag(ctx context.Context, dir, ref string) (string, error) {
+	output, err := gc.git(ctx, dir, "describe", "--tags", "--abbrev=0", ref)
+	if err != nil {
+		if strings.Contains(output, "No names found") || strings.Contains(output, "No tags can describe") {
+			return "", nil
+		}
+		return "", fmt.Errorf("git describe failed: %v - %s", err, output)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// CommitFile commits path alone, leaving anything else staged in dir
+// uncommitted, signed as signing says when it's set
+func (gc *CLIGitClient) CommitFile(ctx context.Context, dir, path, message string, signing *SigningConfig) error {
+	if output, err := gc.git(ctx, dir, "add", "--", path); err != nil {
+		return fmt.Errorf("git add failed: %v - %s", err, output)
+	}
+	args := append(signingArgs(signing), "commit", "-m", message)
+	if signing != nil {
+		args = append(args, "-S")
+	}
+	output, err := gc.git(ctx, dir, append(args, "--", path)...)
+	if err != nil {
+		return fmt.Errorf("git commit failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// TagExists reports whether a tag exists
+func (gc *CLIGitClient) TagExists(ctx context.Context, dir, tag string) (bool, error) {
+	output, err := gc.git(ctx, dir, "tag", "--list", tag)
+	if err != nil {
+		return false, fmt.Errorf("git tag check failed: %v", err)
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// CreateTag creates an annotated tag on ref, signed as signing says when
+// it's set
+func (gc *CLIGitClient) CreateTag(ctx context.Context, dir, tag, ref, message string, signing *SigningConfig) error {
+	args := append(signingArgs(signing), "tag", "-a")
+	if signing != nil {
+		args = append(args, "-s")
+	}
+	output, err := gc.git(ctx, dir, append(args, "-m", message, tag, ref)...)
+	if err != nil {
+		return fmt.Errorf("git tag failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// Diff returns the patch of revRange, with renames detected, limited to
+// paths when any are given. base...branch is what branch changed since it
+// forked from base; a..b compares the two tips.
+func (gc *CLIGitClient) Diff(ctx context.Context, dir, revRange string, paths ...string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "-M", revRange}
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: args, Dir: dir})
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %v", err)
+	}
+	return string(output), nil
+}
+
+// DiffSummary returns the files revRange changed as git diff --raw
+// --numstat -z prints them
+func (gc *CLIGitClient) DiffSummary(ctx context.Context, dir, revRange string) (string, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"diff", "--no-color", "-M", "-z", "--raw", "--numstat", revRange}, Dir: dir})
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %v", err)
+	}
+	return string(output), nil
+}
+
+// FileAuthors returns who committed to path on ref since the given date,
+// merges aside, as git shortlog counts them
+func (gc *CLIGitClient) FileAuthors(ctx context.Context, dir, ref, since, path string) ([]FileAuthor, error) {
+	output, err := gc.runner.Output(ctx, Command{Name: "git", Args: []string{"shortlog", "-sne", "--no-merges", "--since=" + since, ref, "--", path}, Dir: dir})
+	if err != nil {
+		return nil, fmt.Errorf("git shortlog failed: %v", err)
+	}
+	return parseShortlog(string(output)), nil
+}
+
+// UserEmail returns git's user.email in dir, or "" when it isn't set
+func (gc *CLIGitClient) UserEmail(ctx context.Context, dir string) (string, error) {
+	output, err := gc.git(ctx, dir, "config", "user.email")
+	if err != nil {
+		if strings.TrimSpace(output) == "" {
+			// git config exits 1 for an unset key
+			return "", nil
+		}
+		return "", fmt.Errorf("git config failed: %v - %s", err, output)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// CheckoutDetached moves the checkout in dir to ref with a detached HEAD
+func (gc *CLIGitClient) CheckoutDetached(ctx context.Context, dir, ref string) error {
+	output, err := gc.git(ctx, dir, "checkout", "--detach", ref)
+	if err != nil {
+		return fmt.Errorf("git checkout failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// CheckoutBranch switches the checkout in dir to an existing branch
+func (gc *CLIGitClient) CheckoutBranch(ctx context.Context, dir, branch string) error {
+	output, err := gc.git(ctx, dir, "checkout", branch)
+	if err != nil {
+		return fmt.Errorf("git checkout failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// CheckoutNewBranch creates branch at the checkout's HEAD and switches to
+// it, refusing if the branch already exists
+func (gc *CLIGitClient) CheckoutNewBranch(ctx context.Context, dir, branch string) error {
+	output, err := gc.git(ctx, dir, "checkout", "-b", branch)
+	if err != nil {
+		return fmt.Errorf("git checkout -b failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// ResetWorktree throws away a checkout's uncommitted changes and untracked
+// files, leaving ignored files alone
+func (gc *CLIGitClient) ResetWorktree(ctx context.Context, dir string) error {
+	if output, err := gc.git(ctx, dir, "reset", "--hard", "HEAD"); err != nil {
+		return fmt.Errorf("git reset failed: %v - %s", err, output)
+	}
+	if output, err := gc.git(ctx, dir, "clean", "-fd"); err != nil {
+		return fmt.Errorf("git clean failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// PruneWorktrees forgets worktrees whose directories have been deleted
+func (gc *CLIGitClient) PruneWorktrees(ctx context.Context, dir string) error {
+	output, err := gc.git(ctx, dir, "worktree", "prune")
+	if err != nil {
+		return fmt.Errorf("git worktree prune failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// Fetch updates the remote-tracking branches of remote
+func (gc *CLIGitClient) Fetch(ctx context.Context, dir, remote string) error {
+	output, err := gc.git(ctx, dir, "fetch", "--quiet", remote)
+	if err != nil {
+		return fmt.Errorf("git fetch failed: %v - %s", err, output)
+	}
+	return nil
+}
+
+// FastForward moves branch up to upstream, refusing if they have diverged.
+// A checked-out branch is merged so the working tree follows; otherwise only
+// the ref moves.
+func (gc *CLIGitClient) FastForward(ctx context.Context, dir, branch, upstream string) error {
+	current, err := gc.git(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	args := []string{"fetch", "--quiet", ".", upstream + ":" + branch}
+	if err == nil && strings.TrimSpace(current) == branch {
+		args = []string{"merge", "--ff-only", "--quiet", upstream}
+	}
+	output, err := gc.git(ctx, dir, args...)
+	if err != nil {
+		return fmt.Errorf("git fast-forward of %s failed: %v - %s", branch, err, output)
+	}
+	return nil
+}
+
+// parseWorktreeList parses `git worktree list --porcelain` output
+func parseWorktreeList(output string) []GitWorktree {
+	var worktrees []GitWorktree
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktrees = append(worktrees, GitWorktree{Path: strings.TrimPrefix(line, "worktree ")})
+		case strings.HasPrefix(line, "branch ") && len(worktrees) > 0:
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
+		}
+	}
+	return worktrees
+}
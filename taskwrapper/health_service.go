@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Health states, ordered from best to worst
+const (
+	HealthOK      = "ok"
+	HealthWarning = "warning"
+	HealthError   = "error"
+)
+
+// healthCheckTimeout bounds each external command a check runs
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is the result of checking one subsystem
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HealthReport summarises all subsystem checks; Status is the worst of them
+type HealthReport struct {
+	Status    string        `json:"status"`
+	Checks    []HealthCheck `json:"checks"`
+	CheckedAt time.Time     `json:"checkedAt"`
+}
+
+// HealthSources is what the health checks inspect
+type HealthSources struct {
+	ConfigService   ConfigServiceInterface
+	TaskFile        string
+	RepoPath        string
+	WebSocketStatus func() (bool, error)
+	Claude          func() ClaudeCapabilities
+}
+
+// HealthService runs subsystem self-checks
+type HealthService struct {
+	logger Logger
+}
+
+// NewHealthService creates a new health service
+func NewHealthService(logger Logger) *HealthService {
+	return &HealthService{
+		logger: logger,
+	}
+}
+
+// Check runs every subsystem check and returns the combined report
+func (hs *HealthService) Check(sources HealthSources) HealthReport {
+	report := HealthReport{
+		Status: HealthOK,
+		Checks: []HealthCheck{
+			checkConfigHealth(sources.ConfigService),
+			checkTaskFileHealth(sources.TaskFile),
+			checkGitHealth(sources.RepoPath),
+			checkWebSocketHealth(sources.WebSocketStatus),
+			checkWorktreeHealth(sources.RepoPath),
+			checkClaudeHealth(sources.Claude),
+		},
+		CheckedAt: time.Now(),
+	}
+
+	for _, check := range report.Checks {
+		if healthRank(check.Status) > healthRank(report.Status) {
+			report.Status = check.Status
+		}
+		if check.Status != HealthOK {
+			hs.logger.InfoWithFields("Health check not ok", map[string]interface{}{
+				"check":   check.Name,
+				"status":  check.Status,
+				"message": check.Message,
+			})
+		}
+	}
+
+	return report
+}
+
+// healthRank orders health states so the worst one wins
+func healthRank(status string) int {
+	switch status {
+	case HealthError:
+		return 2
+	case HealthWarning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// checkConfigHealth verifies the configuration was loaded
+func checkConfigHealth(configService ConfigServiceInterface) HealthCheck {
+	check := HealthCheck{Name: "config"}
+	if configService == nil {
+		check.Status = HealthWarning
+		check.Message = "configuration not loaded; running against the current directory"
+		return check
+	}
+	if _, err := configService.GetConfig(); err != nil {
+		check.Status = HealthError
+		check.Message = err.Error()
+		return check
+	}
+	check.Status = HealthOK
+	check.Message = "configuration loaded"
+	return check
+}
+
+// checkTaskFileHealth verifies the task file can be read and parsed
+func checkTaskFileHealth(taskFile string) HealthCheck {
+	check := HealthCheck{Name: "task_file"}
+	data, err := os.ReadFile(taskFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			check.Status = HealthWarning
+			check.Message = fmt.Sprintf("%s does not exist yet", taskFile)
+		} else {
+			check.Status = HealthError
+			check.Message = fmt.Sprintf("cannot read %s: %v", taskFile, err)
+		}
+		return check
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("cannot parse %s: %v", taskFile, err)
+		return check
+	}
+
+	check.Status = HealthOK
+	check.Message = fmt.Sprintf("%d tasks", len(tasks))
+	return check
+}
+
+// checkGitHealth verifies git is installed and the repository is a git work tree
+func checkGitHealth(repoPath string) HealthCheck {
+	check := HealthCheck{Name: "git"}
+	if _, err := runHealthCommand(repoPath, "git", "--version"); err != nil {
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("git not available: %v", err)
+		return check
+	}
+	if _, err := runHealthCommand(repoPath, "git", "rev-parse", "--git-dir"); err != nil {
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("%s is not a git repository", repoPath)
+		return check
+	}
+	check.Status = HealthOK
+	check.Message = "git reachable"
+	return check
+}
+
+// checkWebSocketHealth reports whether the terminal WebSocket server is bound
+func checkWebSocketHealth(status func() (bool, error)) HealthCheck {
+	check := HealthCheck{Name: "websocket"}
+	if status == nil {
+		check.Status = HealthWarning
+		check.Message = "terminal service unavailable"
+		return check
+	}
+
+	running, err := status()
+	switch {
+	case err != nil:
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("terminal server failed: %v", err)
+	case running:
+		check.Status = HealthOK
+		check.Message = "terminal server listening on :8080"
+	default:
+		check.Status = HealthOK
+		check.Message = "terminal server starts with the first terminal session"
+	}
+	return check
+}
+
+// checkWorktreeHealth verifies the agent helper scripts and git worktrees are usable
+func checkWorktreeHealth(repoPath string) HealthCheck {
+	check := HealthCheck{Name: "worktrees"}
+	for _, script := range helperScripts {
+		if _, err := os.Stat(helperScriptPath(repoPath, script)); err != nil {
+			check.Status = HealthWarning
+			check.Message = fmt.Sprintf("missing helper script %s; agents are launched natively", script)
+			return check
+		}
+	}
+
+	output, err := runHealthCommand(repoPath, "git", "worktree", "list", "--porcelain")
+	if err != nil {
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("git worktree list failed: %v", err)
+		return check
+	}
+
+	check.Status = HealthOK
+	check.Message = fmt.Sprintf("%d worktrees", strings.Count(output, "worktree "))
+	return check
+}
+
+// checkClaudeHealth reports whether agents can be launched with the
+// installed claude CLI, and what an upgrade would add
+func checkClaudeHealth(probe func() ClaudeCapabilities) HealthCheck {
+	check := HealthCheck{Name: "claude"}
+	if probe == nil {
+		check.Status = HealthWarning
+		check.Message = "claude CLI not probed"
+		return check
+	}
+	caps := probe()
+	switch missing := caps.Missing(claudeRequiredFlags); {
+	case !caps.Installed:
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("claude CLI not found (%s); install it with `npm install -g @anthropic-ai/claude-code` to launch agents", caps.Error)
+	case len(missing) > 0:
+		check.Status = HealthError
+		check.Message = fmt.Sprintf("%s lacks %s, so agents can't be launched; %s", caps.label(), strings.Join(missing, ", "), claudeUpgradeHint)
+	case caps.Flags == nil:
+		check.Status = HealthWarning
+		check.Message = fmt.Sprintf("%s installed, but its flags couldn't be read", caps.label())
+	case len(caps.Missing(claudeOptionalFlags)) > 0:
+		check.Status = HealthWarning
+		check.Message = fmt.Sprintf("%s lacks %s; %s", caps.label(), strings.Join(caps.Missing(claudeOptionalFlags), ", "), claudeUpgradeHint)
+	default:
+		check.Status = HealthOK
+		check.Message = caps.label()
+	}
+	return check
+}
+
+// runHealthCommand runs a short command in dir with a timeout
+func runHealthCommand(dir, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return string(output), err
+}
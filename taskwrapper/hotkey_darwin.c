@@ -0,0 +1,47 @@
+#include <Carbon/Carbon.h>
+#include <dispatch/dispatch.h>
+#include <pthread.h>
+
+extern void goHotkeyPressed(void);
+
+static EventHandlerRef handlerRef = NULL;
+static EventHotKeyRef hotKeyRef = NULL;
+
+static OSStatus hotkeyHandler(EventHandlerCallRef next, EventRef event, void *data) {
+	goHotkeyPressed();
+	return noErr;
+}
+
+// Carbon event APIs must be called on the main thread, where Wails runs its loop
+static void onMainThread(dispatch_block_t block) {
+	if (pthread_main_np()) {
+		block();
+	} else {
+		dispatch_sync(dispatch_get_main_queue(), block);
+	}
+}
+
+int registerCarbonHotkey(int keyCode, int modifiers) {
+	__block OSStatus status = noErr;
+	onMainThread(^{
+		if (handlerRef == NULL) {
+			EventTypeSpec spec = {kEventClassKeyboard, kEventHotKeyPressed};
+			status = InstallEventHandler(GetApplicationEventTarget(), hotkeyHandler, 1, &spec, NULL, &handlerRef);
+			if (status != noErr) {
+				return;
+			}
+		}
+		EventHotKeyID hotKeyID = {'twqa', 1};
+		status = RegisterEventHotKey(keyCode, modifiers, hotKeyID, GetApplicationEventTarget(), 0, &hotKeyRef);
+	});
+	return (int)status;
+}
+
+void unregisterCarbonHotkey(void) {
+	onMainThread(^{
+		if (hotKeyRef != NULL) {
+			UnregisterEventHotKey(hotKeyRef);
+			hotKeyRef = NULL;
+		}
+	});
+}
@@ -0,0 +1,87 @@
+//go:build darwin
+
+package main
+
+/*
+#cgo LDFLAGS: -framework Carbon
+int registerCarbonHotkey(int keyCode, int modifiers);
+void unregisterCarbonHotkey(void);
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Carbon modifier masks
+const (
+	carbonCmdKey     = 0x0100
+	carbonShiftKey   = 0x0200
+	carbonOptionKey  = 0x0800
+	carbonControlKey = 0x1000
+)
+
+// carbonKeyCodes maps hotkey key names to macOS virtual key codes (kVK_*)
+var carbonKeyCodes = map[string]int{
+	"a": 0x00, "s": 0x01, "d": 0x02, "f": 0x03, "h": 0x04, "g": 0x05, "z": 0x06, "x": 0x07,
+	"c": 0x08, "v": 0x09, "b": 0x0B, "q": 0x0C, "w": 0x0D, "e": 0x0E, "r": 0x0F, "y": 0x10,
+	"t": 0x11, "1": 0x12, "2": 0x13, "3": 0x14, "4": 0x15, "6": 0x16, "5": 0x17, "9": 0x19,
+	"7": 0x1A, "8": 0x1C, "0": 0x1D, "o": 0x1F, "u": 0x20, "i": 0x22, "p": 0x23, "l": 0x25,
+	"j": 0x26, "k": 0x28, "n": 0x2D, "m": 0x2E, "return": 0x24, "space": 0x31,
+	"f1": 0x7A, "f2": 0x78, "f3": 0x63, "f4": 0x76, "f5": 0x60, "f6": 0x61,
+	"f7": 0x62, "f8": 0x64, "f9": 0x65, "f10": 0x6D, "f11": 0x67, "f12": 0x6F,
+}
+
+var (
+	darwinHotkeyMu       sync.Mutex
+	darwinHotkeyCallback func()
+)
+
+//export goHotkeyPressed
+func goHotkeyPressed() {
+	darwinHotkeyMu.Lock()
+	callback := darwinHotkeyCallback
+	darwinHotkeyMu.Unlock()
+
+	if callback != nil {
+		callback()
+	}
+}
+
+// registerGlobalHotkey registers the hotkey through Carbon's RegisterEventHotKey
+func registerGlobalHotkey(hk Hotkey, callback func(), errorHandler *ErrorHandler) (func(), error) {
+	code, ok := carbonKeyCodes[hk.Key]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key %s", hk.Key)
+	}
+
+	mods := 0
+	if hk.Modifiers&ModCtrl != 0 {
+		mods |= carbonControlKey
+	}
+	if hk.Modifiers&ModShift != 0 {
+		mods |= carbonShiftKey
+	}
+	if hk.Modifiers&ModAlt != 0 {
+		mods |= carbonOptionKey
+	}
+	if hk.Modifiers&ModSuper != 0 {
+		mods |= carbonCmdKey
+	}
+
+	darwinHotkeyMu.Lock()
+	darwinHotkeyCallback = func() { errorHandler.Go("hotkey callback", callback) }
+	darwinHotkeyMu.Unlock()
+
+	if status := C.registerCarbonHotkey(C.int(code), C.int(mods)); status != 0 {
+		return nil, fmt.Errorf("RegisterEventHotKey failed with status %d", int(status))
+	}
+
+	return func() {
+		C.unregisterCarbonHotkey()
+		darwinHotkeyMu.Lock()
+		darwinHotkeyCallback = nil
+		darwinHotkeyMu.Unlock()
+	}, nil
+}
@@ -0,0 +1,132 @@
+//go:build linux && cgo
+
+package main
+
+/*
+#cgo LDFLAGS: -lX11
+#include <stdlib.h>
+#include <X11/Xlib.h>
+#include <X11/keysym.h>
+
+static int grabFailed;
+
+// onGrabError records a failed grab; Xlib's default handler would exit
+static int onGrabError(Display *d, XErrorEvent *ev) {
+	grabFailed = 1;
+	return 0;
+}
+
+// grabKey returns -1 if another client already holds the combination
+static int grabKey(Display *d, KeyCode code, unsigned int mods) {
+	Window root = DefaultRootWindow(d);
+	// Grab with and without CapsLock/NumLock so they don't swallow the hotkey
+	unsigned int extra[] = {0, LockMask, Mod2Mask, LockMask | Mod2Mask};
+	XSync(d, False);
+	grabFailed = 0;
+	XErrorHandler previous = XSetErrorHandler(onGrabError);
+	for (int i = 0; i < 4; i++) {
+		XGrabKey(d, code, mods | extra[i], root, False, GrabModeAsync, GrabModeAsync);
+	}
+	XSync(d, False);
+	XSetErrorHandler(previous);
+	return grabFailed ? -1 : 0;
+}
+
+static void ungrabKey(Display *d, KeyCode code, unsigned int mods) {
+	Window root = DefaultRootWindow(d);
+	unsigned int extra[] = {0, LockMask, Mod2Mask, LockMask | Mod2Mask};
+	for (int i = 0; i < 4; i++) {
+		XUngrabKey(d, code, mods | extra[i], root);
+	}
+	XSync(d, False);
+}
+
+// nextKeyPress drains pending events and reports whether a key press was seen
+static int nextKeyPress(Display *d) {
+	int pressed = 0;
+	while (XPending(d) > 0) {
+		XEvent ev;
+		XNextEvent(d, &ev);
+		if (ev.type == KeyPress) {
+			pressed = 1;
+		}
+	}
+	return pressed;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// registerGlobalHotkey grabs the key combination on the X11 root window
+func registerGlobalHotkey(hk Hotkey, callback func(), errorHandler *ErrorHandler) (func(), error) {
+	display := C.XOpenDisplay(nil)
+	if display == nil {
+		return nil, fmt.Errorf("cannot open X11 display")
+	}
+
+	keysymName := hk.Key
+	switch {
+	case hk.Key == "return":
+		keysymName = "Return"
+	case len(hk.Key) > 1 && hk.Key[0] == 'f':
+		keysymName = "F" + hk.Key[1:]
+	}
+
+	cName := C.CString(keysymName)
+	defer C.free(unsafe.Pointer(cName))
+	keysym := C.XStringToKeysym(cName)
+	if keysym == C.NoSymbol {
+		C.XCloseDisplay(display)
+		return nil, fmt.Errorf("unknown key %s", hk.Key)
+	}
+	keycode := C.XKeysymToKeycode(display, keysym)
+
+	var mods C.uint
+	if hk.Modifiers&ModCtrl != 0 {
+		mods |= C.ControlMask
+	}
+	if hk.Modifiers&ModShift != 0 {
+		mods |= C.ShiftMask
+	}
+	if hk.Modifiers&ModAlt != 0 {
+		mods |= C.Mod1Mask
+	}
+	if hk.Modifiers&ModSuper != 0 {
+		mods |= C.Mod4Mask
+	}
+
+	if C.grabKey(display, keycode, mods) != 0 {
+		C.XCloseDisplay(display)
+		return nil, fmt.Errorf("XGrabKey failed: another client holds the combination")
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	errorHandler.Go("hotkey listener", func() {
+		defer close(done)
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if C.nextKeyPress(display) != 0 {
+					errorHandler.Go("hotkey callback", callback)
+				}
+			}
+		}
+	})
+
+	return func() {
+		close(stop)
+		<-done
+		C.ungrabKey(display, keycode, mods)
+		C.XCloseDisplay(display)
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !windows && !(linux && cgo)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// registerGlobalHotkey reports that global hotkeys are unavailable on this platform
+func registerGlobalHotkey(hk Hotkey, callback func(), errorHandler *ErrorHandler) (func(), error) {
+	return nil, fmt.Errorf("global hotkeys are not supported on %s", runtime.GOOS)
+}
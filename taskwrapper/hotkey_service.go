@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// DefaultQuickAddHotkey is used when no hotkey is configured
+const DefaultQuickAddHotkey = "cmdorctrl+shift+space"
+
+// HotkeyModifier is a platform-neutral modifier bit
+type HotkeyModifier int
+
+const (
+	ModCtrl HotkeyModifier = 1 << iota
+	ModShift
+	ModAlt
+	ModSuper
+)
+
+// Hotkey is a parsed key combination such as "ctrl+shift+k"
+type Hotkey struct {
+	Modifiers HotkeyModifier
+	Key       string // lowercase: a-z, 0-9, space, return, f1-f12
+}
+
+// ParseHotkey parses a "+"-separated hotkey description
+func ParseHotkey(spec string) (Hotkey, error) {
+	var hk Hotkey
+	parts := strings.Split(strings.ToLower(strings.ReplaceAll(spec, " ", "")), "+")
+
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			if !isSupportedHotkeyKey(part) {
+				return Hotkey{}, fmt.Errorf("unsupported hotkey key %q in %q", part, spec)
+			}
+			hk.Key = part
+			break
+		}
+
+		switch part {
+		case "ctrl", "control":
+			hk.Modifiers |= ModCtrl
+		case "shift":
+			hk.Modifiers |= ModShift
+		case "alt", "option", "opt":
+			hk.Modifiers |= ModAlt
+		case "cmd", "command", "super", "win", "meta":
+			hk.Modifiers |= ModSuper
+		case "cmdorctrl":
+			if runtime.GOOS == "darwin" {
+				hk.Modifiers |= ModSuper
+			} else {
+				hk.Modifiers |= ModCtrl
+			}
+		default:
+			return Hotkey{}, fmt.Errorf("unknown hotkey modifier %q in %q", part, spec)
+		}
+	}
+
+	if hk.Modifiers == 0 {
+		return Hotkey{}, fmt.Errorf("hotkey %q needs at least one modifier", spec)
+	}
+
+	return hk, nil
+}
+
+// isSupportedHotkeyKey reports whether every platform backend can map the key
+func isSupportedHotkeyKey(key string) bool {
+	if len(key) == 1 {
+		c := key[0]
+		return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+	}
+	switch key {
+	case "space", "return", "f1", "f2", "f3", "f4", "f5", "f6", "f7", "f8", "f9", "f10", "f11", "f12":
+		return true
+	}
+	return false
+}
+
+// HotkeyService owns the system-wide quick-add hotkey registration
+type HotkeyService struct {
+	logger       Logger
+	errorHandler *ErrorHandler
+	mu           sync.Mutex
+	spec         string
+	unregister   func()
+}
+
+// NewHotkeyService creates a new hotkey service
+func NewHotkeyService(logger Logger) *HotkeyService {
+	return &HotkeyService{
+		logger:       logger,
+		errorHandler: NewErrorHandler(logger),
+	}
+}
+
+// SetErrorHandler sets the handler used to recover panics in the hotkey
+// listener and its callbacks
+func (hs *HotkeyService) SetErrorHandler(errorHandler *ErrorHandler) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.errorHandler = errorHandler
+}
+
+// Register installs spec as the global hotkey, replacing any previous one
+func (hs *HotkeyService) Register(spec string, callback func()) error {
+	if spec == "" {
+		spec = DefaultQuickAddHotkey
+	}
+
+	hk, err := ParseHotkey(spec)
+	if err != nil {
+		return err
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.unregister != nil {
+		hs.unregister()
+		hs.unregister = nil
+	}
+
+	unregister, err := registerGlobalHotkey(hk, callback, hs.errorHandler)
+	if err != nil {
+		hs.logger.ErrorWithFields("Failed to register global hotkey", err, map[string]interface{}{
+			"hotkey": spec,
+		})
+		return fmt.Errorf("failed to register hotkey %s: %v", spec, err)
+	}
+
+	hs.spec = spec
+	hs.unregister = unregister
+	hs.logger.InfoWithFields("Global hotkey registered", map[string]interface{}{
+		"hotkey": spec,
+	})
+
+	return nil
+}
+
+// Unregister removes the current global hotkey, if any
+func (hs *HotkeyService) Unregister() {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.unregister != nil {
+		hs.unregister()
+		hs.unregister = nil
+		hs.logger.InfoWithFields("Global hotkey unregistered", map[string]interface{}{
+			"hotkey": hs.spec,
+		})
+	}
+}
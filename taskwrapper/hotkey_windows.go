@@ -0,0 +1,128 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32                = syscall.NewLazyDLL("user32.dll")
+	kernel32              = syscall.NewLazyDLL("kernel32.dll")
+	procRegisterHotKey    = user32.NewProc("RegisterHotKey")
+	procUnregisterHotKey  = user32.NewProc("UnregisterHotKey")
+	procGetMessageW       = user32.NewProc("GetMessageW")
+	procPostThreadMessage = user32.NewProc("PostThreadMessageW")
+	procGetCurrentThread  = kernel32.NewProc("GetCurrentThreadId")
+)
+
+const (
+	winModAlt      = 0x0001
+	winModControl  = 0x0002
+	winModShift    = 0x0004
+	winModWin      = 0x0008
+	winModNoRepeat = 0x4000
+	winWMHotkey    = 0x0312
+	winWMQuit      = 0x0012
+	winHotkeyID    = 1
+)
+
+// winMsg mirrors the Win32 MSG structure
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	ptX     int32
+	ptY     int32
+}
+
+// registerGlobalHotkey registers the hotkey with RegisterHotKey on a dedicated thread
+func registerGlobalHotkey(hk Hotkey, callback func(), errorHandler *ErrorHandler) (func(), error) {
+	vk, err := windowsVirtualKey(hk.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	mods := uintptr(winModNoRepeat)
+	if hk.Modifiers&ModCtrl != 0 {
+		mods |= winModControl
+	}
+	if hk.Modifiers&ModShift != 0 {
+		mods |= winModShift
+	}
+	if hk.Modifiers&ModAlt != 0 {
+		mods |= winModAlt
+	}
+	if hk.Modifiers&ModSuper != 0 {
+		mods |= winModWin
+	}
+
+	ready := make(chan error, 1)
+	threadID := make(chan uintptr, 1)
+	done := make(chan struct{})
+
+	errorHandler.Go("hotkey listener", func() {
+		// The hotkey belongs to the thread that registered it, so keep this goroutine pinned
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(done)
+
+		tid, _, _ := procGetCurrentThread.Call()
+		if ok, _, callErr := procRegisterHotKey.Call(0, winHotkeyID, mods, vk); ok == 0 {
+			ready <- fmt.Errorf("RegisterHotKey failed: %v", callErr)
+			return
+		}
+		defer procUnregisterHotKey.Call(0, winHotkeyID)
+
+		threadID <- tid
+		ready <- nil
+
+		var msg winMsg
+		for {
+			ret, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(ret) <= 0 {
+				return
+			}
+			if msg.message == winWMHotkey {
+				errorHandler.Go("hotkey callback", callback)
+			}
+		}
+	})
+
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	tid := <-threadID
+
+	return func() {
+		procPostThreadMessage.Call(tid, winWMQuit, 0, 0)
+		<-done
+	}, nil
+}
+
+// windowsVirtualKey maps a hotkey key name to a Win32 virtual-key code
+func windowsVirtualKey(key string) (uintptr, error) {
+	if len(key) == 1 {
+		c := key[0]
+		if c >= 'a' && c <= 'z' {
+			return uintptr(c - 'a' + 'A'), nil
+		}
+		return uintptr(c), nil // '0'-'9' share their ASCII codes
+	}
+	switch key {
+	case "space":
+		return 0x20, nil
+	case "return":
+		return 0x0D, nil
+	}
+	var n int
+	if _, err := fmt.Sscanf(key, "f%d", &n); err == nil && n >= 1 && n <= 12 {
+		return uintptr(0x70 + n - 1), nil
+	}
+	return 0, fmt.Errorf("unsupported key %s", key)
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the locale every catalog falls back to. Its catalog has
+// every message.
+const DefaultLocale = "en"
+
+// Keys of the user-visible backend strings. Strings other programs parse
+// stay untranslated: the "Merge task #N: title" subject of approval merges
+// (the changelog and releases find tasks by it) and the "Unreleased"
+// changelog heading (a regenerated section replaces it).
+const (
+	MsgTrayOpenBoard          = "tray.openBoard"
+	MsgTrayOpenBoardTip       = "tray.openBoard.tip"
+	MsgTrayPendingReviews     = "tray.pendingReviews"
+	MsgTrayPendingReviewsTip  = "tray.pendingReviews.tip"
+	MsgTrayPendingReviewCount = "tray.pendingReviews.count"
+	MsgTrayPauseAutoPilot     = "tray.pauseAutoPilot"
+	MsgTrayPauseAutoPilotTip  = "tray.pauseAutoPilot.tip"
+	MsgTrayQuit               = "tray.quit"
+	MsgTrayQuitTip            = "tray.quit.tip"
+	MsgTrayStatus             = "tray.status"
+
+	MsgChangelogSince = "changelog.since"
+	MsgChangelogEmpty = "changelog.empty"
+	MsgReleaseTag     = "release.tag"
+
+	MsgJobAgentLaunch = "job.agentLaunch"
+	MsgJobMerge       = "job.merge"
+	MsgJobPush        = "job.push"
+	MsgJobPull        = "job.pull"
+	MsgJobRepoScan    = "job.repoScan"
+
+	MsgPromptTask        = "prompt.task"
+	MsgPromptDescription = "prompt.description"
+	MsgPromptNotes       = "prompt.notes"
+	MsgPromptWorktree    = "prompt.worktree"
+	MsgPromptResume      = "prompt.resume"
+	MsgPromptFeedback    = "prompt.feedback"
+	MsgPromptSandbox     = "prompt.sandbox"
+)
+
+// changelogGroupKeys are the keys of the changelog group headings
+var changelogGroupKeys = map[string]string{
+	ChangeAdded:   "changelog.added",
+	ChangeChanged: "changelog.changed",
+	ChangeFixed:   "changelog.fixed",
+	ChangeRemoved: "changelog.removed",
+}
+
+// messageCatalogs are the built-in translations by locale
+var messageCatalogs = map[string]map[string]string{
+	"en": {
+		MsgTrayOpenBoard:          "Open Board",
+		MsgTrayOpenBoardTip:       "Show the TaskWrapper window",
+		MsgTrayPendingReviews:     "Pending Reviews",
+		MsgTrayPendingReviewsTip:  "Jump to tasks awaiting review",
+		MsgTrayPendingReviewCount: "Pending Reviews (%d)",
+		MsgTrayPauseAutoPilot:     "Pause Auto-Pilot",
+		MsgTrayPauseAutoPilotTip:  "Stop launching agents on todo → doing",
+		MsgTrayQuit:               "Quit",
+		MsgTrayQuitTip:            "Quit TaskWrapper",
+		MsgTrayStatus:             "%s: %d busy agent(s), %d pending review(s)",
+		MsgChangelogSince:         "Changes since %s.",
+		MsgChangelogEmpty:         "No tasks merged.",
+		"changelog.added":         "Added",
+		"changelog.changed":       "Changed",
+		"changelog.fixed":         "Fixed",
+		"changelog.removed":       "Removed",
+		MsgReleaseTag:             "Release %s",
+		MsgJobAgentLaunch:         "Launching agent for task #%d",
+		MsgJobMerge:               "Merging task #%d",
+		MsgJobPush:                "Pushing plan directory",
+		MsgJobPull:                "Pulling plan directory",
+		MsgJobRepoScan:            "Scanning %s",
+		MsgPromptTask:             "Review plan.md and task.json. Begin task #%d: %s. Update task.json status to 'pending_review' when done, commit to branch task_%d.",
+		MsgPromptDescription:      "Description:",
+		MsgPromptNotes:            "Notes (add to them in task.json as you learn things the next person should know):",
+		MsgPromptWorktree:         "You're working in a separate worktree. Commit your work to branch task_%d, but set the task's status in %s, the main checkout's task.json, so the dashboard sees it.",
+		MsgPromptResume:           "Task #%d: %s has been sent back to you. Check task.json and plan.md for feedback, then pick up where you left off on branch task_%d.",
+		MsgPromptFeedback:         "Address this review feedback:",
+		MsgPromptSandbox:          "You are sandboxed: you can only write inside %s, plus %s for the status update.",
+
+		MsgSummaryTask:                              "#%d '%s'",
+		MsgSummaryTaskUntitled:                      "#%d",
+		MsgSummaryUnknown:                           "%s",
+		"status.backlog":                            "Backlog",
+		"status.todo":                               "To Do",
+		"status.doing":                              "In Progress",
+		"status.pending_review":                     "Pending Review",
+		"status.done":                               "Done",
+		"summary.task.created":                      "Task %s created",
+		"summary.task.updated":                      "Task %s updated",
+		"summary.task.moved":                        "Task %s moved to %s",
+		"summary.task.moved.external":               "Task %s moved to %s in task.json",
+		"summary.task.approved":                     "Task %s approved and merged",
+		"summary.task.rejected":                     "Task %s rejected",
+		"summary.agent.launched":                    "Agent started on task %s",
+		"summary.agent.interrupted":                 "Agent on task %s was interrupted",
+		"summary.agent.failed":                      "Agent for task %s failed to start: %s",
+		"summary.agent.result":                      "Agent finished task %s",
+		"summary.agent.result.failed":               "Agent on task %s stopped without finishing",
+		"summary.review.checked":                    "Review checks passed for task %s",
+		"summary.review.checked.failed":             "Review checks failed for task %s: %d of %d",
+		"summary.review.checked.skipped":            "Review checks skipped for task %s: %s",
+		"summary.review.changes_requested":          "Changes requested on task %s: %d comments",
+		"summary.review.changes_requested.one":      "Changes requested on task %s: %d comment",
+		"summary.sla.breached":                      "Task %s missed its %s target",
+		"summary.branch.deleted":                    "Branch %s deleted",
+		"summary.tasks.saved":                       "Board saved with %d tasks",
+		"summary.tasks.saved.one":                   "Board saved with %d task",
+		"summary.tasks.imported":                    "%d tasks imported from %s",
+		"summary.tasks.imported.one":                "%d task imported from %s",
+		"summary.tasks.restored":                    "Tasks restored from backup %s",
+		"summary.plan.saved":                        "Plan saved",
+		"summary.plan.pushed":                       "Plan pushed to the sync remote",
+		"summary.plan.pulled":                       "Plan pulled from the sync remote",
+		"summary.repo.encrypted":                    "Plan files encrypted",
+		"summary.repo.decrypted":                    "Plan files decrypted",
+		"summary.snapshot.created":                  "Snapshot %s created",
+		"summary.snapshot.restored":                 "Snapshot %s restored",
+		"summary.config.changed":                    "Settings changed: %s",
+		"summary.repo.added":                        "Repository %s added",
+		"summary.repo.switched":                     "Switched to repository %s",
+		"summary.repo.removed":                      "Repository %s removed",
+		"summary.autopilot.changed.on":              "Auto-pilot paused",
+		"summary.autopilot.changed.off":             "Auto-pilot resumed",
+		"summary.app.crashed":                       "Background task %s crashed",
+		"summary.automation.fired":                  "Automation rule %s ran",
+		"summary.automation.fired.failed":           "Automation rule %s failed: %s",
+		"summary.milestone.created":                 "Milestone %s created",
+		"summary.milestone.updated":                 "Milestone %s updated",
+		"summary.milestone.deleted":                 "Milestone %s deleted",
+		"summary.worktrees.pruned":                  "%d worktrees pruned, %s freed",
+		"summary.worktrees.pruned.one":              "%d worktree pruned, %s freed",
+		"summary.worktrees.warmed":                  "%d worktrees prepared",
+		"summary.worktrees.warmed.one":              "%d worktree prepared",
+		"summary.agents.detached":                   "%d agents left running",
+		"summary.agents.detached.one":               "%d agent left running",
+		"summary.changelog.written":                 "Changelog written",
+		"summary.release.created":                   "Release %s created",
+		"summary.tasks:corrupted":                   "%s is damaged and was not loaded",
+		"summary.navigate":                          "Showing %s",
+		"summary.tasks:stale":                       "%d tasks have gone stale",
+		"summary.tasks:stale.one":                   "%d task has gone stale",
+		"summary.review:ready":                      "Task %s is ready for review",
+		"summary.review:ready.failed":               "Task %s is ready for review, %d of %d checks failed",
+		"summary.tasks:changed":                     "Board reloaded from task.json",
+		"summary.tasks:loading":                     "Loading tasks: %d%%",
+		"summary.tasks:loading.done":                "%d tasks loaded",
+		"summary.quota:exceeded":                    "Agent launch refused: the launch quota is used up",
+		"summary.autopilot:changed.on":              "Auto-pilot paused",
+		"summary.autopilot:changed.off":             "Auto-pilot resumed",
+		"summary.safemode:changed.on":               "Safe mode on",
+		"summary.safemode:changed.off":              "Safe mode off",
+		"summary.agents:interrupted":                "%d agents were interrupted",
+		"summary.agents:interrupted.one":            "%d agent was interrupted",
+		"summary.confirmation:required.agent_spawn": "Confirm launching agents in %s",
+		"summary.confirmation:required.merge":       "Confirm merging in %s",
+		"summary.terminal:title":                    "Terminal: %s",
+		"summary.job:progress":                      "%s: %d%%",
+		"summary.job:progress.running":              "%s in progress",
+		"summary.job:progress.succeeded":            "%s finished",
+		"summary.job:progress.failed":               "%s failed",
+		"summary.job:progress.cancelled":            "%s cancelled",
+		"summary.app:crash":                         "Background task %s crashed",
+		"summary.health:degraded":                   "The startup check found problems",
+		"summary.shutdown:requested":                "%d agents are still running",
+		"summary.shutdown:requested.one":            "%d agent is still running",
+		"summary.quickadd:open":                     "Quick add opened",
+	},
+	"es": {
+		MsgTrayOpenBoard:          "Abrir tablero",
+		MsgTrayOpenBoardTip:       "Mostrar la ventana de TaskWrapper",
+		MsgTrayPendingReviews:     "Revisiones pendientes",
+		MsgTrayPendingReviewsTip:  "Ir a las tareas que esperan revisión",
+		MsgTrayPendingReviewCount: "Revisiones pendientes (%d)",
+		MsgTrayPauseAutoPilot:     "Pausar piloto automático",
+		MsgTrayPauseAutoPilotTip:  "Dejar de lanzar agentes al pasar de todo a doing",
+		MsgTrayQuit:               "Salir",
+		MsgTrayQuitTip:            "Salir de TaskWrapper",
+		MsgTrayStatus:             "%s: %d agente(s) ocupado(s), %d revisión(es) pendiente(s)",
+		MsgChangelogSince:         "Cambios desde %s.",
+		MsgChangelogEmpty:         "No se fusionó ninguna tarea.",
+		"changelog.added":         "Añadido",
+		"changelog.changed":       "Cambiado",
+		"changelog.fixed":         "Corregido",
+		"changelog.removed":       "Eliminado",
+		MsgReleaseTag:             "Versión %s",
+		MsgJobAgentLaunch:         "Lanzando agente para la tarea #%d",
+		MsgJobMerge:               "Fusionando la tarea #%d",
+		MsgJobPush:                "Subiendo el directorio del plan",
+		MsgJobPull:                "Descargando el directorio del plan",
+		MsgJobRepoScan:            "Buscando en %s",
+		MsgPromptTask:             "Revisa plan.md y task.json. Empieza la tarea #%d: %s. Al terminar, cambia su estado en task.json a 'pending_review' y haz commit en la rama task_%d.",
+		MsgPromptDescription:      "Descripción:",
+		MsgPromptNotes:            "Notas (amplíalas en task.json con lo que aprendas y deba saber la próxima persona):",
+		MsgPromptWorktree:         "Trabajas en un worktree aparte. Haz commit de tu trabajo en la rama task_%d, pero cambia el estado de la tarea en %s, el task.json de la copia principal, para que el panel lo vea.",
+		MsgPromptResume:           "La tarea #%d: %s ha vuelto a ti. Busca los comentarios en task.json y plan.md y continúa donde lo dejaste en la rama task_%d.",
+		MsgPromptFeedback:         "Atiende estos comentarios de revisión:",
+		MsgPromptSandbox:          "Estás en un entorno aislado: solo puedes escribir en %s y en %s para actualizar el estado.",
+
+		MsgSummaryTask:                              "#%d «%s»",
+		MsgSummaryTaskUntitled:                      "#%d",
+		MsgSummaryUnknown:                           "%s",
+		"status.backlog":                            "Backlog",
+		"status.todo":                               "Por hacer",
+		"status.doing":                              "En curso",
+		"status.pending_review":                     "Pendiente de revisión",
+		"status.done":                               "Hecho",
+		"summary.task.created":                      "Tarea %s creada",
+		"summary.task.updated":                      "Tarea %s actualizada",
+		"summary.task.moved":                        "Tarea %s movida a %s",
+		"summary.task.moved.external":               "Tarea %s movida a %s en task.json",
+		"summary.task.approved":                     "Tarea %s aprobada y fusionada",
+		"summary.task.rejected":                     "Tarea %s rechazada",
+		"summary.agent.launched":                    "Agente iniciado en la tarea %s",
+		"summary.agent.interrupted":                 "Se interrumpió el agente de la tarea %s",
+		"summary.agent.failed":                      "El agente de la tarea %s no pudo iniciarse: %s",
+		"summary.agent.result":                      "El agente terminó la tarea %s",
+		"summary.agent.result.failed":               "El agente de la tarea %s se detuvo sin terminar",
+		"summary.review.checked":                    "Comprobaciones de revisión superadas en la tarea %s",
+		"summary.review.checked.failed":             "Comprobaciones de revisión fallidas en la tarea %s: %d de %d",
+		"summary.review.checked.skipped":            "Comprobaciones de revisión omitidas en la tarea %s: %s",
+		"summary.review.changes_requested":          "Cambios solicitados en la tarea %s: %d comentarios",
+		"summary.review.changes_requested.one":      "Cambios solicitados en la tarea %s: %d comentario",
+		"summary.sla.breached":                      "La tarea %s incumplió su objetivo de %s",
+		"summary.branch.deleted":                    "Rama %s eliminada",
+		"summary.tasks.saved":                       "Tablero guardado con %d tareas",
+		"summary.tasks.saved.one":                   "Tablero guardado con %d tarea",
+		"summary.tasks.imported":                    "%d tareas importadas desde %s",
+		"summary.tasks.imported.one":                "%d tarea importada desde %s",
+		"summary.tasks.restored":                    "Tareas restauradas desde la copia %s",
+		"summary.plan.saved":                        "Plan guardado",
+		"summary.plan.pushed":                       "Plan subido al remoto de sincronización",
+		"summary.plan.pulled":                       "Plan descargado del remoto de sincronización",
+		"summary.repo.encrypted":                    "Archivos del plan cifrados",
+		"summary.repo.decrypted":                    "Archivos del plan descifrados",
+		"summary.snapshot.created":                  "Instantánea %s creada",
+		"summary.snapshot.restored":                 "Instantánea %s restaurada",
+		"summary.config.changed":                    "Ajustes cambiados: %s",
+		"summary.repo.added":                        "Repositorio %s añadido",
+		"summary.repo.switched":                     "Cambiado al repositorio %s",
+		"summary.repo.removed":                      "Repositorio %s eliminado",
+		"summary.autopilot.changed.on":              "Piloto automático en pausa",
+		"summary.autopilot.changed.off":             "Piloto automático reanudado",
+		"summary.app.crashed":                       "La tarea en segundo plano %s falló",
+		"summary.automation.fired":                  "Regla de automatización %s ejecutada",
+		"summary.automation.fired.failed":           "La regla de automatización %s falló: %s",
+		"summary.milestone.created":                 "Hito %s creado",
+		"summary.milestone.updated":                 "Hito %s actualizado",
+		"summary.milestone.deleted":                 "Hito %s eliminado",
+		"summary.worktrees.pruned":                  "%d worktrees eliminados, %s liberados",
+		"summary.worktrees.pruned.one":              "%d worktree eliminado, %s liberados",
+		"summary.worktrees.warmed":                  "%d worktrees preparados",
+		"summary.worktrees.warmed.one":              "%d worktree preparado",
+		"summary.agents.detached":                   "%d agentes siguen en ejecución",
+		"summary.agents.detached.one":               "%d agente sigue en ejecución",
+		"summary.changelog.written":                 "Registro de cambios escrito",
+		"summary.release.created":                   "Versión %s creada",
+		"summary.tasks:corrupted":                   "%s está dañado y no se cargó",
+		"summary.navigate":                          "Mostrando %s",
+		"summary.tasks:stale":                       "%d tareas se han estancado",
+		"summary.tasks:stale.one":                   "%d tarea se ha estancado",
+		"summary.review:ready":                      "La tarea %s está lista para revisión",
+		"summary.review:ready.failed":               "La tarea %s está lista para revisión, fallaron %d de %d comprobaciones",
+		"summary.tasks:changed":                     "Tablero recargado desde task.json",
+		"summary.tasks:loading":                     "Cargando tareas: %d%%",
+		"summary.tasks:loading.done":                "%d tareas cargadas",
+		"summary.quota:exceeded":                    "Lanzamiento de agente rechazado: se agotó la cuota de lanzamientos",
+		"summary.autopilot:changed.on":              "Piloto automático en pausa",
+		"summary.autopilot:changed.off":             "Piloto automático reanudado",
+		"summary.safemode:changed.on":               "Modo seguro activado",
+		"summary.safemode:changed.off":              "Modo seguro desactivado",
+		"summary.agents:interrupted":                "Se interrumpieron %d agentes",
+		"summary.agents:interrupted.one":            "Se interrumpió %d agente",
+		"summary.confirmation:required.agent_spawn": "Confirma el lanzamiento de agentes en %s",
+		"summary.confirmation:required.merge":       "Confirma la fusión en %s",
+		"summary.terminal:title":                    "Terminal: %s",
+		"summary.job:progress":                      "%s: %d%%",
+		"summary.job:progress.running":              "%s en curso",
+		"summary.job:progress.succeeded":            "%s terminado",
+		"summary.job:progress.failed":               "%s falló",
+		"summary.job:progress.cancelled":            "%s cancelado",
+		"summary.app:crash":                         "La tarea en segundo plano %s falló",
+		"summary.health:degraded":                   "La comprobación de inicio encontró problemas",
+		"summary.shutdown:requested":                "%d agentes siguen en ejecución",
+		"summary.shutdown:requested.one":            "%d agente sigue en ejecución",
+		"summary.quickadd:open":                     "Alta rápida abierta",
+	},
+	"de": {
+		MsgTrayOpenBoard:          "Board öffnen",
+		MsgTrayOpenBoardTip:       "TaskWrapper-Fenster anzeigen",
+		MsgTrayPendingReviews:     "Ausstehende Reviews",
+		MsgTrayPendingReviewsTip:  "Zu den Aufgaben springen, die auf ein Review warten",
+		MsgTrayPendingReviewCount: "Ausstehende Reviews (%d)",
+		MsgTrayPauseAutoPilot:     "Autopilot pausieren",
+		MsgTrayPauseAutoPilotTip:  "Beim Wechsel von todo nach doing keine Agenten mehr starten",
+		MsgTrayQuit:               "Beenden",
+		MsgTrayQuitTip:            "TaskWrapper beenden",
+		MsgTrayStatus:             "%s: %d Agent(en) beschäftigt, %d Review(s) ausstehend",
+		MsgChangelogSince:         "Änderungen seit %s.",
+		MsgChangelogEmpty:         "Keine Aufgaben gemergt.",
+		"changelog.added":         "Hinzugefügt",
+		"changelog.changed":       "Geändert",
+		"changelog.fixed":         "Behoben",
+		"changelog.removed":       "Entfernt",
+		MsgReleaseTag:             "Release %s",
+		MsgJobAgentLaunch:         "Agent für Aufgabe #%d wird gestartet",
+		MsgJobMerge:               "Aufgabe #%d wird gemergt",
+		MsgJobPush:                "Plan-Verzeichnis wird hochgeladen",
+		MsgJobPull:                "Plan-Verzeichnis wird heruntergeladen",
+		MsgJobRepoScan:            "%s wird durchsucht",
+		MsgPromptTask:             "Lies plan.md und task.json. Beginne mit Aufgabe #%d: %s. Setze ihren Status in task.json auf 'pending_review', wenn du fertig bist, und committe auf den Branch task_%d.",
+		MsgPromptDescription:      "Beschreibung:",
+		MsgPromptNotes:            "Notizen (ergänze sie in task.json um alles, was die nächste Person wissen sollte):",
+		MsgPromptWorktree:         "Du arbeitest in einem eigenen Worktree. Committe deine Arbeit auf den Branch task_%d, aber setze den Status der Aufgabe in %s, der task.json des Haupt-Checkouts, damit das Dashboard ihn sieht.",
+		MsgPromptResume:           "Aufgabe #%d: %s wurde an dich zurückgegeben. Lies das Feedback in task.json und plan.md und mach auf dem Branch task_%d dort weiter, wo du aufgehört hast.",
+		MsgPromptFeedback:         "Geh auf dieses Review-Feedback ein:",
+		MsgPromptSandbox:          "Du bist in einer Sandbox: Du darfst nur in %s schreiben, und in %s für das Status-Update.",
+
+		MsgSummaryTask:                              "#%d „%s“",
+		MsgSummaryTaskUntitled:                      "#%d",
+		MsgSummaryUnknown:                           "%s",
+		"status.backlog":                            "Backlog",
+		"status.todo":                               "Zu erledigen",
+		"status.doing":                              "In Arbeit",
+		"status.pending_review":                     "Review ausstehend",
+		"status.done":                               "Erledigt",
+		"summary.task.created":                      "Aufgabe %s erstellt",
+		"summary.task.updated":                      "Aufgabe %s aktualisiert",
+		"summary.task.moved":                        "Aufgabe %s nach %s verschoben",
+		"summary.task.moved.external":               "Aufgabe %s in task.json nach %s verschoben",
+		"summary.task.approved":                     "Aufgabe %s freigegeben und gemergt",
+		"summary.task.rejected":                     "Aufgabe %s abgelehnt",
+		"summary.agent.launched":                    "Agent für Aufgabe %s gestartet",
+		"summary.agent.interrupted":                 "Agent für Aufgabe %s wurde unterbrochen",
+		"summary.agent.failed":                      "Agent für Aufgabe %s konnte nicht starten: %s",
+		"summary.agent.result":                      "Agent hat Aufgabe %s abgeschlossen",
+		"summary.agent.result.failed":               "Agent für Aufgabe %s hat ohne Abschluss aufgehört",
+		"summary.review.checked":                    "Review-Prüfungen für Aufgabe %s bestanden",
+		"summary.review.checked.failed":             "Review-Prüfungen für Aufgabe %s fehlgeschlagen: %d von %d",
+		"summary.review.checked.skipped":            "Review-Prüfungen für Aufgabe %s übersprungen: %s",
+		"summary.review.changes_requested":          "Änderungen an Aufgabe %s angefordert: %d Kommentare",
+		"summary.review.changes_requested.one":      "Änderungen an Aufgabe %s angefordert: %d Kommentar",
+		"summary.sla.breached":                      "Aufgabe %s hat ihr Ziel von %s verfehlt",
+		"summary.branch.deleted":                    "Branch %s gelöscht",
+		"summary.tasks.saved":                       "Board mit %d Aufgaben gespeichert",
+		"summary.tasks.saved.one":                   "Board mit %d Aufgabe gespeichert",
+		"summary.tasks.imported":                    "%d Aufgaben aus %s importiert",
+		"summary.tasks.imported.one":                "%d Aufgabe aus %s importiert",
+		"summary.tasks.restored":                    "Aufgaben aus Backup %s wiederhergestellt",
+		"summary.plan.saved":                        "Plan gespeichert",
+		"summary.plan.pushed":                       "Plan zum Sync-Remote hochgeladen",
+		"summary.plan.pulled":                       "Plan vom Sync-Remote heruntergeladen",
+		"summary.repo.encrypted":                    "Plan-Dateien verschlüsselt",
+		"summary.repo.decrypted":                    "Plan-Dateien entschlüsselt",
+		"summary.snapshot.created":                  "Snapshot %s erstellt",
+		"summary.snapshot.restored":                 "Snapshot %s wiederhergestellt",
+		"summary.config.changed":                    "Einstellungen geändert: %s",
+		"summary.repo.added":                        "Repository %s hinzugefügt",
+		"summary.repo.switched":                     "Zu Repository %s gewechselt",
+		"summary.repo.removed":                      "Repository %s entfernt",
+		"summary.autopilot.changed.on":              "Autopilot pausiert",
+		"summary.autopilot.changed.off":             "Autopilot fortgesetzt",
+		"summary.app.crashed":                       "Hintergrundaufgabe %s ist abgestürzt",
+		"summary.automation.fired":                  "Automatisierungsregel %s ausgeführt",
+		"summary.automation.fired.failed":           "Automatisierungsregel %s fehlgeschlagen: %s",
+		"summary.milestone.created":                 "Meilenstein %s erstellt",
+		"summary.milestone.updated":                 "Meilenstein %s aktualisiert",
+		"summary.milestone.deleted":                 "Meilenstein %s gelöscht",
+		"summary.worktrees.pruned":                  "%d Worktrees entfernt, %s freigegeben",
+		"summary.worktrees.pruned.one":              "%d Worktree entfernt, %s freigegeben",
+		"summary.worktrees.warmed":                  "%d Worktrees vorbereitet",
+		"summary.worktrees.warmed.one":              "%d Worktree vorbereitet",
+		"summary.agents.detached":                   "%d Agenten laufen weiter",
+		"summary.agents.detached.one":               "%d Agent läuft weiter",
+		"summary.changelog.written":                 "Changelog geschrieben",
+		"summary.release.created":                   "Release %s erstellt",
+		"summary.tasks:corrupted":                   "%s ist beschädigt und wurde nicht geladen",
+		"summary.navigate":                          "%s wird angezeigt",
+		"summary.tasks:stale":                       "%d Aufgaben sind liegen geblieben",
+		"summary.tasks:stale.one":                   "%d Aufgabe ist liegen geblieben",
+		"summary.review:ready":                      "Aufgabe %s ist bereit für das Review",
+		"summary.review:ready.failed":               "Aufgabe %s ist bereit für das Review, %d von %d Prüfungen fehlgeschlagen",
+		"summary.tasks:changed":                     "Board aus task.json neu geladen",
+		"summary.tasks:loading":                     "Aufgaben werden geladen: %d%%",
+		"summary.tasks:loading.done":                "%d Aufgaben geladen",
+		"summary.quota:exceeded":                    "Agentenstart abgelehnt: das Startkontingent ist aufgebraucht",
+		"summary.autopilot:changed.on":              "Autopilot pausiert",
+		"summary.autopilot:changed.off":             "Autopilot fortgesetzt",
+		"summary.safemode:changed.on":               "Abgesicherter Modus an",
+		"summary.safemode:changed.off":              "Abgesicherter Modus aus",
+		"summary.agents:interrupted":                "%d Agenten wurden unterbrochen",
+		"summary.agents:interrupted.one":            "%d Agent wurde unterbrochen",
+		"summary.confirmation:required.agent_spawn": "Start von Agenten in %s bestätigen",
+		"summary.confirmation:required.merge":       "Mergen in %s bestätigen",
+		"summary.terminal:title":                    "Terminal: %s",
+		"summary.job:progress":                      "%s: %d%%",
+		"summary.job:progress.running":              "%s läuft",
+		"summary.job:progress.succeeded":            "%s abgeschlossen",
+		"summary.job:progress.failed":               "%s fehlgeschlagen",
+		"summary.job:progress.cancelled":            "%s abgebrochen",
+		"summary.app:crash":                         "Hintergrundaufgabe %s ist abgestürzt",
+		"summary.health:degraded":                   "Die Startprüfung hat Probleme gefunden",
+		"summary.shutdown:requested":                "%d Agenten laufen noch",
+		"summary.shutdown:requested.one":            "%d Agent läuft noch",
+		"summary.quickadd:open":                     "Schnelles Hinzufügen geöffnet",
+	},
+}
+
+// localePattern matches locale tags such as "de" and "pt-BR"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// formatVerbPattern matches the fmt verbs in a message, and
+// formatIndexPattern their explicit argument indexes
+var (
+	formatVerbPattern  = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+	formatIndexPattern = regexp.MustCompile(`\[\d+\]`)
+)
+
+// LocaleSettings is the configured locale and the locales there are
+// catalogs for
+type LocaleSettings struct {
+	Locale    string   `json:"locale"`
+	Available []string `json:"available"`
+}
+
+// Localizer formats user-visible backend strings in one locale. It is
+// immutable, so services share one and a locale change swaps in a new one.
+type Localizer struct {
+	locale   string
+	messages map[string]string
+}
+
+// DefaultLocalizer formats messages in DefaultLocale
+func DefaultLocalizer() *Localizer {
+	return &Localizer{locale: DefaultLocale, messages: messageCatalogs[DefaultLocale]}
+}
+
+// Locale returns the locale the localizer formats in
+func (l *Localizer) Locale() string {
+	if l == nil {
+		return DefaultLocale
+	}
+	return l.locale
+}
+
+// T formats the message for key with args. A message missing from the
+// locale falls back to DefaultLocale; a nil Localizer uses DefaultLocale.
+func (l *Localizer) T(key string, args ...interface{}) string {
+	message, ok := "", false
+	if l != nil {
+		message, ok = l.messages[key]
+	}
+	if !ok {
+		if message, ok = messageCatalogs[DefaultLocale][key]; !ok {
+			message = key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// N formats the message for key with args, using the key+".one" message
+// when count is 1
+func (l *Localizer) N(key string, count int, args ...interface{}) string {
+	if count == 1 {
+		key += ".one"
+	}
+	return l.T(key, args...)
+}
+
+// localeCatalogDir is where user catalogs, one <locale>.json of key to
+// message each, add locales or override built-in messages; "" if there
+// is no config directory
+func localeCatalogDir() string {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "locales")
+}
+
+// loadLocalizer builds the localizer for locale: its built-in messages,
+// then a user catalog in dir over them. "pt-BR" falls back to "pt"
+// message by message, then to DefaultLocale.
+func loadLocalizer(locale, dir string) (*Localizer, error) {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	if !localePattern.MatchString(locale) {
+		return nil, ValidationError("invalid locale", nil).WithContext("locale", locale)
+	}
+	available := false
+	for _, known := range availableLocales(dir) {
+		if known == locale {
+			available = true
+		}
+	}
+	if !available {
+		return nil, NotFoundError("no message catalog for locale", nil).WithContext("locale", locale)
+	}
+
+	// Most general first, so the specific locale's messages win
+	chain := []string{}
+	for tag := locale; ; {
+		chain = append([]string{tag}, chain...)
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	messages := map[string]string{}
+	for _, tag := range chain {
+		for key, message := range messageCatalogs[tag] {
+			messages[key] = message
+		}
+		user, err := readLocaleCatalog(dir, tag)
+		if err != nil {
+			return nil, err
+		}
+		for key, message := range user {
+			messages[key] = message
+		}
+	}
+	return &Localizer{locale: locale, messages: messages}, nil
+}
+
+// readLocaleCatalog reads the user catalog for locale in dir, or nil if
+// there is none. Every message must be a known key with the same format
+// verbs as the English one, or a translation could garble its arguments.
+func readLocaleCatalog(dir, locale string) (map[string]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(dir, locale+".json")
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, ValidationError("invalid message catalog", err).WithContext("file", path)
+	}
+	for key, message := range messages {
+		if err := checkMessage(key, message); err != nil {
+			return nil, err.WithContext("file", path)
+		}
+	}
+	return messages, nil
+}
+
+// checkMessage verifies a translation of key takes the English message's
+// arguments
+func checkMessage(key, message string) *AppError {
+	english, ok := messageCatalogs[DefaultLocale][key]
+	if !ok {
+		return ValidationError("unknown message key", nil).WithContext("key", key)
+	}
+	if want, got := formatVerbs(english), formatVerbs(message); want != got {
+		return ValidationError("message doesn't take the same arguments as the English one", nil).
+			WithContext("key", key).WithContext("expected", want)
+	}
+	return nil
+}
+
+// formatVerbs lists the fmt verbs in message, sorted and without argument
+// indexes, so a translation may reorder its arguments with %[2]s
+func formatVerbs(message string) string {
+	verbs := []string{}
+	for _, verb := range formatVerbPattern.FindAllString(message, -1) {
+		verbs = append(verbs, formatIndexPattern.ReplaceAllString(verb, ""))
+	}
+	sort.Strings(verbs)
+	return strings.Join(verbs, " ")
+}
+
+// availableLocales lists the built-in locales and those with a user
+// catalog in dir, sorted
+func availableLocales(dir string) []string {
+	seen := map[string]bool{}
+	for locale := range messageCatalogs {
+		seen[locale] = true
+	}
+	if dir != "" {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		for _, match := range matches {
+			if locale := strings.TrimSuffix(filepath.Base(match), ".json"); localePattern.MatchString(locale) {
+				seen[locale] = true
+			}
+		}
+	}
+	locales := make([]string, 0, len(seen))
+	for locale := range seen {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
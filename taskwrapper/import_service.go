@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Supported board export formats
+const (
+	ImportFormatTrello = "trello"
+	ImportFormatGitHub = "github"
+)
+
+// trelloExport is the subset of a Trello board export we understand
+type trelloExport struct {
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		Name   string `json:"name"`
+		IDList string `json:"idList"`
+		Closed bool   `json:"closed"`
+		Labels []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+}
+
+// githubProjectExport matches the output of `gh project item-list --format json`
+type githubProjectExport struct {
+	Items []struct {
+		Title    string   `json:"title"`
+		Status   string   `json:"status"`
+		Priority string   `json:"priority"`
+		Labels   []string `json:"labels"`
+		Content  struct {
+			Title string `json:"title"`
+		} `json:"content"`
+	} `json:"items"`
+}
+
+// importedCard is the format-neutral shape of a card before it becomes a Task
+type importedCard struct {
+	Title    string
+	Column   string
+	Priority string
+	Tags     []string
+}
+
+// ImportService converts external board exports into tasks
+type ImportService struct {
+	logger Logger
+}
+
+// NewImportService creates a new import service
+func NewImportService(logger Logger) *ImportService {
+	return &ImportService{
+		logger: logger,
+	}
+}
+
+// ImportBoard parses an export and returns new tasks numbered after existing ones.
+// mapping maps column/list names (case-insensitive) to task statuses; unmapped
+// columns fall back to a best guess based on the column name. Cards without
+// a priority get triage's default, and cards landing in the backlog are
+// routed by its rules.
+func (is *ImportService) ImportBoard(format string, data []byte, mapping map[string]string, existing []Task, triage TriageConfig) ([]Task, error) {
+	var cards []importedCard
+	var err error
+
+	switch strings.ToLower(format) {
+	case ImportFormatTrello:
+		cards, err = parseTrelloExport(data)
+	case ImportFormatGitHub:
+		cards, err = parseGitHubProjectExport(data)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	statusMap, err := normalizeStatusMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	nextID := 1
+	for _, task := range existing {
+		if task.ID >= nextID {
+			nextID = task.ID + 1
+		}
+	}
+
+	tasks := make([]Task, 0, len(cards))
+	for _, card := range cards {
+		title := strings.TrimSpace(card.Title)
+		if title == "" {
+			continue
+		}
+
+		status, ok := statusMap[strings.ToLower(strings.TrimSpace(card.Column))]
+		if !ok {
+			status = guessStatus(card.Column)
+		}
+
+		task := Task{
+			ID:       nextID,
+			Title:    title,
+			Status:   status,
+			Priority: guessPriority(card.Priority, triage.defaultPriority()),
+			Deps:     []int{},
+			Parent:   nil,
+			Tags:     normalizeTags(card.Tags),
+		}
+		triage.triage(&task)
+		tasks = append(tasks, task)
+		nextID++
+	}
+
+	is.logger.InfoWithFields("Board export parsed", map[string]interface{}{
+		"format":   format,
+		"cards":    len(cards),
+		"imported": len(tasks),
+	})
+
+	return tasks, nil
+}
+
+// parseTrelloExport extracts open cards from a Trello board export
+func parseTrelloExport(data []byte) ([]importedCard, error) {
+	var export trelloExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse Trello export: %v", err)
+	}
+
+	listNames := make(map[string]string)
+	closedLists := make(map[string]bool)
+	for _, list := range export.Lists {
+		listNames[list.ID] = list.Name
+		closedLists[list.ID] = list.Closed
+	}
+
+	var cards []importedCard
+	for _, card := range export.Cards {
+		if card.Closed || closedLists[card.IDList] {
+			continue
+		}
+
+		priority := ""
+		var tags []string
+		for _, label := range card.Labels {
+			if priority == "" {
+				priority = labelPriority(label.Name, label.Color)
+			}
+			tags = append(tags, label.Name)
+		}
+
+		cards = append(cards, importedCard{
+			Title:    card.Name,
+			Column:   listNames[card.IDList],
+			Priority: priority,
+			Tags:     tags,
+		})
+	}
+
+	return cards, nil
+}
+
+// parseGitHubProjectExport extracts items from a GitHub Projects item list
+func parseGitHubProjectExport(data []byte) ([]importedCard, error) {
+	var export githubProjectExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub Projects export: %v", err)
+	}
+
+	cards := make([]importedCard, 0, len(export.Items))
+	for _, item := range export.Items {
+		title := item.Title
+		if title == "" {
+			title = item.Content.Title
+		}
+		cards = append(cards, importedCard{
+			Title:    title,
+			Column:   item.Status,
+			Priority: item.Priority,
+			Tags:     item.Labels,
+		})
+	}
+
+	return cards, nil
+}
+
+// normalizeStatusMapping validates a user mapping and lowercases its keys
+func normalizeStatusMapping(mapping map[string]string) (map[string]TaskStatus, error) {
+	result := make(map[string]TaskStatus, len(mapping))
+	for column, statusName := range mapping {
+		status, err := ParseTaskStatus(statusName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping for column %q: %v", column, err)
+		}
+		result[strings.ToLower(strings.TrimSpace(column))] = status
+	}
+	return result, nil
+}
+
+// columnStatuses maps the column names boards commonly use, as words
+// joined by single spaces, to a status
+var columnStatuses = map[string]TaskStatus{
+	"backlog":          StatusBacklog,
+	"icebox":           StatusBacklog,
+	"ideas":            StatusBacklog,
+	"not started":      StatusBacklog,
+	"todo":             StatusTodo,
+	"to do":            StatusTodo,
+	"ready":            StatusTodo,
+	"up next":          StatusTodo,
+	"next":             StatusTodo,
+	"doing":            StatusDoing,
+	"in progress":      StatusDoing,
+	"wip":              StatusDoing,
+	"review":           StatusPendingReview,
+	"in review":        StatusPendingReview,
+	"ready for review": StatusPendingReview,
+	"done":             StatusDone,
+	"complete":         StatusDone,
+	"completed":        StatusDone,
+	"closed":           StatusDone,
+	"finished":         StatusDone,
+	"shipped":          StatusDone,
+}
+
+// negations are the words that turn a column name into its opposite, as in
+// "Not done", so it says nothing about the status
+var negations = map[string]bool{"not": true, "no": true, "non": true, "never": true}
+
+// guessStatus maps common column names to a status, defaulting to backlog.
+// Names not in columnStatuses are matched word by word, so "Incomplete" is
+// not taken for done nor "Not ready" for todo.
+func guessStatus(column string) TaskStatus {
+	words := strings.FieldsFunc(strings.ToLower(column), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if status, ok := columnStatuses[strings.Join(words, " ")]; ok {
+		return status
+	}
+
+	has := map[string]bool{}
+	for i, word := range words {
+		if negations[word] {
+			return StatusBacklog
+		}
+		has[word] = true
+		if word == "to" && i+1 < len(words) && words[i+1] == "do" {
+			has["todo"] = true
+		}
+	}
+	switch {
+	case has["done"], has["complete"], has["completed"], has["closed"], has["finished"]:
+		return StatusDone
+	case has["review"], has["reviewing"]:
+		return StatusPendingReview
+	case has["doing"], has["progress"], has["wip"]:
+		return StatusDoing
+	case has["todo"], has["ready"]:
+		return StatusTodo
+	default:
+		return StatusBacklog
+	}
+}
+
+// guessPriority maps a free-form priority value to a TaskPriority, or to
+// fallback when it names none
+func guessPriority(value string, fallback TaskPriority) TaskPriority {
+	name := strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case strings.Contains(name, "high"), strings.Contains(name, "urgent"):
+		return PriorityHigh
+	case strings.Contains(name, "low"):
+		return PriorityLow
+	case strings.Contains(name, "medium"), strings.Contains(name, "normal"):
+		return PriorityMedium
+	default:
+		return fallback
+	}
+}
+
+// labelPriority derives a priority from a Trello label name or colour
+func labelPriority(name, color string) string {
+	if priority, err := ParseTaskPriority(strings.ToLower(strings.TrimSpace(name))); err == nil {
+		return priority.String()
+	}
+	switch color {
+	case "red":
+		return PriorityHigh.String()
+	case "orange", "yellow":
+		return PriorityMedium.String()
+	case "green":
+		return PriorityLow.String()
+	}
+	return ""
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// instanceLockFileName is the lock the running instance holds in the
+	// config directory
+	instanceLockFileName = "instance.lock"
+	// instanceLockGrace is how long a lock file may stay empty while its
+	// owner starts up before it is treated as abandoned
+	instanceLockGrace = 5 * time.Second
+	// instancePingTimeout bounds a call to another instance
+	instancePingTimeout = 2 * time.Second
+	// instanceTokenHeader carries the lock's token on calls to an instance
+	instanceTokenHeader = "X-Instance-Token"
+)
+
+// readOnlyReason is why writes fail in an instance opened read-only
+const readOnlyReason = "another TaskWrapper instance is running; this one is read-only"
+
+// InstanceInfo is what the lock file says about the instance holding it
+type InstanceInfo struct {
+	PID       int       `json:"pid"`
+	Addr      string    `json:"addr"` // loopback address of its ping endpoint
+	Token     string    `json:"token"`
+	Version   string    `json:"version"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// InstanceLock keeps a second TaskWrapper from running against the same
+// config. The holder serves /ping and /focus on a loopback port recorded in
+// the lock file, so a later instance can tell a live holder from a stale
+// file and hand over to it.
+type InstanceLock struct {
+	path   string
+	info   InstanceInfo
+	server *http.Server
+
+	mu      sync.Mutex
+	onFocus func()
+}
+
+// instanceLockPath is the lock file in the config directory
+func instanceLockPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, instanceLockFileName), nil
+}
+
+// AcquireInstanceLock takes the lock at path. If a live instance already
+// holds it, the lock is nil and its info is returned instead; a lock left
+// by an instance that no longer answers is taken over.
+func AcquireInstanceLock(path string) (*InstanceLock, *InstanceInfo, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			lock, err := startInstanceLock(path, file)
+			return lock, nil, err
+		}
+		if !os.IsExist(err) {
+			return nil, nil, fmt.Errorf("failed to create instance lock: %v", err)
+		}
+
+		holder, live := readInstanceLock(path)
+		if live {
+			return nil, holder, nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("failed to remove stale instance lock: %v", err)
+		}
+	}
+	return nil, nil, fmt.Errorf("instance lock at %s keeps reappearing", path)
+}
+
+// readInstanceLock reads the lock file and reports whether its holder is
+// still running
+func readInstanceLock(path string) (*InstanceInfo, bool) {
+	data, err := os.ReadFile(path)
+	var info InstanceInfo
+	if err != nil || json.Unmarshal(data, &info) != nil || info.Addr == "" {
+		// The holder may not have written it yet
+		stat, statErr := os.Stat(path)
+		return &InstanceInfo{}, statErr == nil && time.Since(stat.ModTime()) < instanceLockGrace
+	}
+	return &info, info.Ping() == nil
+}
+
+// startInstanceLock serves the ping endpoint and records it in file
+func startInstanceLock(path string, file *os.File) (*InstanceLock, error) {
+	defer file.Close()
+	release := func(err error) (*InstanceLock, error) {
+		os.Remove(path)
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return release(fmt.Errorf("failed to listen for other instances: %v", err))
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		listener.Close()
+		return release(err)
+	}
+	lock := &InstanceLock{
+		path: path,
+		info: InstanceInfo{
+			PID:       os.Getpid(),
+			Addr:      listener.Addr().String(),
+			Token:     hex.EncodeToString(token),
+			Version:   AppVersion,
+			StartedAt: time.Now().UTC(),
+		},
+	}
+	lock.server = &http.Server{Handler: lock.handler(), ReadHeaderTimeout: instancePingTimeout}
+	// The App's handler doesn't exist yet; this one logs to the console
+	NewErrorHandler(NewConsoleLogger()).Go("instance lock server", func() { lock.server.Serve(listener) })
+
+	data, _ := json.Marshal(lock.info)
+	if _, err := file.Write(data); err != nil {
+		lock.server.Close()
+		return release(fmt.Errorf("failed to write instance lock: %v", err))
+	}
+	return lock, nil
+}
+
+// handler answers pings and focus requests from later instances
+func (l *InstanceLock) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"pid": l.info.PID, "version": l.info.Version})
+	})
+	mux.HandleFunc("/focus", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		l.mu.Lock()
+		onFocus := l.onFocus
+		l.mu.Unlock()
+		if onFocus != nil {
+			onFocus()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// Only processes that can read the lock file know the token
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(instanceTokenHeader) != l.info.Token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// OnFocus sets what a later instance's focus request does
+func (l *InstanceLock) OnFocus(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onFocus = fn
+}
+
+// Info describes this instance as the lock file does
+func (l *InstanceLock) Info() InstanceInfo {
+	return l.info
+}
+
+// Release stops the ping endpoint and removes the lock file, unless another
+// instance has since taken it over
+func (l *InstanceLock) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), instancePingTimeout)
+	defer cancel()
+	l.server.Shutdown(ctx)
+	if holder, _ := readInstanceLock(l.path); holder != nil && holder.Token == l.info.Token {
+		os.Remove(l.path)
+	}
+}
+
+// call sends a request to the instance's endpoint
+func (info *InstanceInfo) call(method, path string) error {
+	req, err := http.NewRequest(method, "http://"+info.Addr+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(instanceTokenHeader, info.Token)
+	client := &http.Client{Timeout: instancePingTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("instance answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Ping checks the instance is running and answering
+func (info *InstanceInfo) Ping() error {
+	return info.call(http.MethodGet, "/ping")
+}
+
+// Focus asks the instance to bring its window to the front
+func (info *InstanceInfo) Focus() error {
+	return info.call(http.MethodPost, "/focus")
+}
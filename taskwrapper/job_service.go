@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobState is the lifecycle state of a long-running operation
+type JobState string
+
+const (
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// Job kinds
+const (
+	JobKindAgentLaunch = "agent_launch"
+	JobKindMerge       = "merge"
+	JobKindRepoScan    = "repo_scan"
+	JobKindSync        = "sync"
+)
+
+// Job retention and event throttling
+const (
+	maxFinishedJobs        = 20
+	jobProgressMinInterval = 100 * time.Millisecond
+)
+
+// Job is a snapshot of a long-running operation's progress.
+// Progress is 0..1, or -1 when the operation can't estimate it.
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Title      string     `json:"title"`
+	State      JobState   `json:"state"`
+	Progress   float64    `json:"progress"`
+	Message    string     `json:"message,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// JobHandle lets an operation report progress and observe cancellation
+type JobHandle struct {
+	id      string
+	ctx     context.Context
+	service *JobService
+}
+
+// jobContextKey carries the JobHandle through contexts passed to services
+type jobContextKey struct{}
+
+// JobService tracks running and recently finished jobs
+type JobService struct {
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	cancels  map[string]context.CancelFunc
+	lastEmit map[string]time.Time
+	logger   Logger
+	onUpdate func(Job)
+}
+
+// NewJobService creates a new job service
+func NewJobService(logger Logger) *JobService {
+	return &JobService{
+		jobs:     make(map[string]*Job),
+		cancels:  make(map[string]context.CancelFunc),
+		lastEmit: make(map[string]time.Time),
+		logger:   logger,
+	}
+}
+
+// OnUpdate registers a callback invoked with each job state or progress change
+func (js *JobService) OnUpdate(fn func(Job)) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.onUpdate = fn
+}
+
+// Run registers a job, runs fn synchronously and records its outcome
+func (js *JobService) Run(parent context.Context, kind, title string, fn func(job *JobHandle) error) error {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	job := &Job{
+		ID:        generateID(),
+		Kind:      kind,
+		Title:     title,
+		State:     JobRunning,
+		Progress:  -1,
+		StartedAt: time.Now(),
+	}
+	handle := &JobHandle{id: job.ID, service: js}
+	handle.ctx = context.WithValue(ctx, jobContextKey{}, handle)
+
+	js.mu.Lock()
+	js.jobs[job.ID] = job
+	js.cancels[job.ID] = cancel
+	js.mu.Unlock()
+	js.notify(job.ID, true)
+
+	err := fn(handle)
+	js.finish(job.ID, ctx.Err(), err)
+	return err
+}
+
+// finish records the final state of a job
+func (js *JobService) finish(id string, ctxErr, err error) {
+	js.mu.Lock()
+	job, ok := js.jobs[id]
+	if !ok {
+		js.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	switch {
+	case ctxErr == context.Canceled:
+		job.State = JobCancelled
+	case err != nil:
+		job.State = JobFailed
+		job.Error = err.Error()
+	default:
+		job.State = JobSucceeded
+		job.Progress = 1
+	}
+	delete(js.cancels, id)
+	delete(js.lastEmit, id)
+	js.pruneLocked()
+	js.mu.Unlock()
+
+	js.notify(id, true)
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs
+func (js *JobService) pruneLocked() {
+	var finished []*Job
+	for _, job := range js.jobs {
+		if job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(js.jobs, job.ID)
+	}
+}
+
+// GetJobs returns running and recently finished jobs, oldest first
+func (js *JobService) GetJobs() []Job {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	jobs := make([]Job, 0, len(js.jobs))
+	for _, job := range js.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartedAt.Before(jobs[j].StartedAt)
+	})
+	return jobs
+}
+
+// Cancel requests cancellation of a running job
+func (js *JobService) Cancel(id string) error {
+	js.mu.Lock()
+	job, ok := js.jobs[id]
+	cancel := js.cancels[id]
+	js.mu.Unlock()
+
+	if !ok {
+		return NotFoundError("job not found", nil).WithContext("job_id", id)
+	}
+	if cancel == nil || job.FinishedAt != nil {
+		return ConflictError("job already finished", nil).WithContext("job_id", id)
+	}
+
+	cancel()
+	js.logger.InfoWithFields("Job cancellation requested", map[string]interface{}{
+		"job_id": id,
+		"kind":   job.Kind,
+	})
+	return nil
+}
+
+// notify sends the job's current snapshot to the update callback. Progress
+// updates are throttled; state changes (force) always go through.
+func (js *JobService) notify(id string, force bool) {
+	js.mu.Lock()
+	job, ok := js.jobs[id]
+	if !ok || js.onUpdate == nil {
+		js.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(js.lastEmit[id]) < jobProgressMinInterval {
+		js.mu.Unlock()
+		return
+	}
+	js.lastEmit[id] = now
+	snapshot, onUpdate := *job, js.onUpdate
+	js.mu.Unlock()
+
+	onUpdate(snapshot)
+}
+
+// ID returns the job's identifier
+func (h *JobHandle) ID() string {
+	return h.id
+}
+
+// Context is cancelled when the job is cancelled; pass it to commands the job runs
+func (h *JobHandle) Context() context.Context {
+	return h.ctx
+}
+
+// Progress reports completion (0..1, or -1 if unknown) and a status message
+func (h *JobHandle) Progress(fraction float64, message string) {
+	h.service.mu.Lock()
+	job, ok := h.service.jobs[h.id]
+	if ok {
+		job.Progress = fraction
+		job.Message = message
+	}
+	h.service.mu.Unlock()
+
+	if ok {
+		h.service.notify(h.id, false)
+	}
+}
+
+// reportProgress updates the job carried by ctx, if any. Services call it so
+// they can report progress without knowing whether they run inside a job.
+func reportProgress(ctx context.Context, fraction float64, message string) {
+	if ctx == nil {
+		return
+	}
+	if handle, ok := ctx.Value(jobContextKey{}).(*JobHandle); ok {
+		handle.Progress(fraction, message)
+	}
+}
+
+// jobCancelled returns an error if ctx has been cancelled
+func jobCancelled(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("operation cancelled: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Journal event types
+const (
+	EventTaskCreated      = "task.created"
+	EventTaskUpdated      = "task.updated"
+	EventTaskMoved        = "task.moved"
+	EventTasksSaved       = "tasks.saved"
+	EventTasksImported    = "tasks.imported"
+	EventTasksRestored    = "tasks.restored"
+	EventTaskApproved     = "task.approved"
+	EventTaskRejected     = "task.rejected"
+	EventAgentLaunched    = "agent.launched"
+	EventAgentFailed      = "agent.failed"
+	EventPlanSaved        = "plan.saved"
+	EventPlanPushed       = "plan.pushed"
+	EventPlanPulled       = "plan.pulled"
+	EventSnapshotCreated  = "snapshot.created"
+	EventSnapshotRestored = "snapshot.restored"
+	EventConfigChanged    = "config.changed"
+	EventRepoAdded        = "repo.added"
+	EventRepoRemoved      = "repo.removed"
+	EventRepoSwitched     = "repo.switched"
+	EventRepoEncrypted    = "repo.encrypted"
+	EventRepoDecrypted    = "repo.decrypted"
+	EventAutoPilotChanged = "autopilot.changed"
+	EventAppCrashed       = "app.crashed"
+	EventAutomationFired  = "automation.fired"
+	EventMilestoneCreated = "milestone.created"
+	EventMilestoneUpdated = "milestone.updated"
+	EventMilestoneDeleted = "milestone.deleted"
+	EventSLABreached      = "sla.breached"
+	EventWorktreesPruned  = "worktrees.pruned"
+	EventWorktreesWarmed  = "worktrees.warmed"
+	EventAgentResult      = "agent.result"
+	EventBranchDeleted    = "branch.deleted"
+	EventReviewChecked    = "review.checked"
+	EventAgentInterrupted = "agent.interrupted"
+	EventAgentsDetached   = "agents.detached"
+	EventChangelogWritten = "changelog.written"
+	EventReleaseCreated   = "release.created"
+	EventChangesRequested = "review.changes_requested"
+)
+
+// journalFileName is the journal file inside a repository's logs directory
+const journalFileName = "events.jsonl"
+
+// JournalEntry is a single recorded action
+type JournalEntry struct {
+	Time   time.Time              `json:"time"`
+	Type   string                 `json:"type"`
+	Repo   string                 `json:"repo,omitempty"`
+	TaskID int                    `json:"taskId,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+	// Summary describes the entry in the current locale; it is written when
+	// the entry is sent or read, never stored
+	Summary string `json:"summary,omitempty"`
+}
+
+// JournalQuery filters journal entries; zero values match everything
+type JournalQuery struct {
+	Types  []string  `json:"types,omitempty"`
+	TaskID int       `json:"taskId,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+	Limit  int       `json:"limit,omitempty"` // keep only the newest N matches
+}
+
+// JournalService appends entries to the repository's events.jsonl
+type JournalService struct {
+	mu     sync.Mutex
+	logDir string
+	repo   string
+	logger Logger
+}
+
+// NewJournalService creates a journal writing to logDir/events.jsonl
+func NewJournalService(logDir, repo string, logger Logger) *JournalService {
+	return &JournalService{
+		logDir: logDir,
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// SetRepository points the journal at another repository's log directory
+func (js *JournalService) SetRepository(logDir, repo string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.logDir = logDir
+	js.repo = repo
+}
+
+// Record appends an entry to the journal. Failures are logged, not returned,
+// so journaling never blocks the action being recorded.
+func (js *JournalService) Record(eventType string, taskID int, data map[string]interface{}) JournalEntry {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	entry := JournalEntry{
+		Time:   time.Now().UTC(),
+		Type:   eventType,
+		Repo:   js.repo,
+		TaskID: taskID,
+		Data:   data,
+	}
+
+	if err := js.append(entry); err != nil {
+		js.logger.ErrorWithFields("Failed to write journal entry", err, map[string]interface{}{
+			"type": eventType,
+		})
+	}
+	return entry
+}
+
+// append writes one JSON line to the journal (must be called with lock held)
+func (js *JournalService) append(entry JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %v", err)
+	}
+
+	if err := os.MkdirAll(js.logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %v", err)
+	}
+
+	f, err := os.OpenFile(js.path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append journal entry: %v", err)
+	}
+	return nil
+}
+
+// Query returns matching entries, oldest first
+func (js *JournalService) Query(query JournalQuery) ([]JournalEntry, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	f, err := os.Open(js.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []JournalEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer f.Close()
+
+	types := make(map[string]bool, len(query.Types))
+	for _, t := range query.Types {
+		types[t] = true
+	}
+
+	entries := []JournalEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn final line from a crash shouldn't hide the rest of the history
+			continue
+		}
+		if len(types) > 0 && !types[entry.Type] {
+			continue
+		}
+		if query.TaskID != 0 && entry.TaskID != query.TaskID {
+			continue
+		}
+		if !query.Since.IsZero() && entry.Time.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && entry.Time.After(query.Until) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+// path returns the journal file location (must be called with lock held)
+func (js *JournalService) path() string {
+	return filepath.Join(js.logDir, journalFileName)
+}
@@ -0,0 +1,56 @@
+//go:build darwin
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// systemKeyStore keeps keys in the login keychain through the security tool
+type systemKeyStore struct {
+	runner ProcessRunner
+}
+
+// newSystemKeyStore returns the macOS keychain
+func newSystemKeyStore(runner ProcessRunner) KeyStore {
+	return &systemKeyStore{runner: runner}
+}
+
+// Get reads the key stored for account
+func (ks *systemKeyStore) Get(account string) ([]byte, error) {
+	out, err := ks.runner.Output(context.Background(), Command{
+		Name: "security",
+		Args: []string{"find-generic-password", "-s", keychainService, "-a", account, "-w"},
+	})
+	if err != nil {
+		return nil, ErrKeyNotFound
+	}
+	return hex.DecodeString(strings.TrimSpace(string(out)))
+}
+
+// Set stores key for account, replacing any existing key
+func (ks *systemKeyStore) Set(account string, key []byte) error {
+	out, err := ks.runner.CombinedOutput(context.Background(), Command{
+		Name: "security",
+		Args: []string{"add-generic-password", "-U", "-s", keychainService, "-a", account, "-w", hex.EncodeToString(key)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store key in keychain: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Delete removes the key stored for account
+func (ks *systemKeyStore) Delete(account string) error {
+	out, err := ks.runner.CombinedOutput(context.Background(), Command{
+		Name: "security",
+		Args: []string{"delete-generic-password", "-s", keychainService, "-a", account},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete key from keychain: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// systemKeyStore keeps keys in the Secret Service (GNOME Keyring, KWallet)
+// through secret-tool
+type systemKeyStore struct {
+	runner ProcessRunner
+}
+
+// newSystemKeyStore returns the Secret Service keychain
+func newSystemKeyStore(runner ProcessRunner) KeyStore {
+	return &systemKeyStore{runner: runner}
+}
+
+// Get reads the key stored for account
+func (ks *systemKeyStore) Get(account string) ([]byte, error) {
+	out, err := ks.runner.Output(context.Background(), Command{
+		Name: "secret-tool",
+		Args: []string{"lookup", "service", keychainService, "account", account},
+	})
+	if err != nil || len(strings.TrimSpace(string(out))) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return hex.DecodeString(strings.TrimSpace(string(out)))
+}
+
+// Set stores key for account, replacing any existing key. The key is passed
+// on stdin so it never appears in the process list.
+func (ks *systemKeyStore) Set(account string, key []byte) error {
+	out, err := ks.runner.CombinedOutput(context.Background(), Command{
+		Name:  "secret-tool",
+		Args:  []string{"store", "--label=TaskWrapper " + account, "service", keychainService, "account", account},
+		Stdin: []byte(hex.EncodeToString(key)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store key in keychain: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Delete removes the key stored for account
+func (ks *systemKeyStore) Delete(account string) error {
+	out, err := ks.runner.CombinedOutput(context.Background(), Command{
+		Name: "secret-tool",
+		Args: []string{"clear", "service", keychainService, "account", account},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete key from keychain: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build !darwin && !linux
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// unsupportedKeyStore reports that no keychain is available on this platform
+type unsupportedKeyStore struct{}
+
+// newSystemKeyStore returns a key store that always fails
+func newSystemKeyStore(runner ProcessRunner) KeyStore {
+	return unsupportedKeyStore{}
+}
+
+func (unsupportedKeyStore) Get(account string) ([]byte, error) {
+	return nil, fmt.Errorf("keychain is not supported on %s", runtime.GOOS)
+}
+
+func (unsupportedKeyStore) Set(account string, key []byte) error {
+	return fmt.Errorf("keychain is not supported on %s", runtime.GOOS)
+}
+
+func (unsupportedKeyStore) Delete(account string) error {
+	return fmt.Errorf("keychain is not supported on %s", runtime.GOOS)
+}
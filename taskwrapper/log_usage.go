@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LogUsage reports the disk space a repository's logs take, so users can
+// see what is worth cleaning up
+type LogUsage struct {
+	Dir        string          `json:"dir"`    // the repository's logs folder
+	AppDir     string          `json:"appDir"` // where the app's own logs go
+	Relocated  bool            `json:"relocated"`
+	TotalBytes int64           `json:"totalBytes"`
+	Entries    []LogUsageEntry `json:"entries"` // largest first
+}
+
+// LogUsageEntry is one kind of log: the daily app logs, a log file, or a
+// folder of them such as agents/
+type LogUsageEntry struct {
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// collectLogUsage sizes up the logs in dir, and the app's logs in appDir
+// when they were moved out of it
+func collectLogUsage(dir, appDir string) (LogUsage, error) {
+	usage := LogUsage{
+		Dir:       dir,
+		AppDir:    appDir,
+		Relocated: filepath.Clean(dir) != filepath.Clean(appDir),
+		Entries:   []LogUsageEntry{},
+	}
+	entries := map[string]*LogUsageEntry{}
+	add := func(name, path string, size int64) {
+		entry, ok := entries[path]
+		if !ok {
+			entry = &LogUsageEntry{Name: name, Path: path}
+			entries[path] = entry
+		}
+		entry.Files++
+		entry.Bytes += size
+		usage.TotalBytes += size
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		switch {
+		case len(parts) == 2:
+			add(parts[0]+"/", filepath.Join(dir, parts[0]), info.Size())
+		case strings.HasPrefix(rel, "universal_logs-"):
+			add("universal_logs", filepath.Join(dir, "universal_logs-*"), info.Size())
+		default:
+			add(rel, path, info.Size())
+		}
+		return nil
+	})
+	if err != nil {
+		return usage, err
+	}
+
+	if usage.Relocated {
+		files, err := filepath.Glob(filepath.Join(appDir, "universal_logs-*"))
+		if err != nil {
+			return usage, err
+		}
+		for _, file := range files {
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				add("universal_logs", filepath.Join(appDir, "universal_logs-*"), info.Size())
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		usage.Entries = append(usage.Entries, *entry)
+	}
+	sort.Slice(usage.Entries, func(i, j int) bool {
+		if usage.Entries[i].Bytes != usage.Entries[j].Bytes {
+			return usage.Entries[i].Bytes > usage.Entries[j].Bytes
+		}
+		return usage.Entries[i].Path < usage.Entries[j].Path
+	})
+	return usage, nil
+}
@@ -1,87 +1,296 @@
 package main
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// LogLevel orders log severities; messages below the threshold are dropped
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the upper-case level name used in log lines
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// ParseLogLevel converts a level name (debug, info, warn, error) to a LogLevel
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("invalid log level: %s", s)
+	}
+}
+
+// Default rotation and retention limits
+const (
+	defaultLogMaxSizeMB     = 10
+	defaultLogMaxBackups    = 5
+	defaultLogRetentionDays = 14
+)
+
 // FileLogger implements Logger interface with file-based logging
 type FileLogger struct {
 	logDir string
+
+	mu            sync.Mutex
+	level         LogLevel
+	jsonFormat    bool
+	maxSize       int64
+	maxBackups    int
+	retention     time.Duration
+	lastRetention time.Time
+	compress      bool
+	redactor      *Redactor
 }
 
 // NewFileLogger creates a new file-based logger
 func NewFileLogger(logDir string) *FileLogger {
 	return &FileLogger{
-		logDir: logDir,
+		logDir:     logDir,
+		level:      LevelInfo,
+		maxSize:    defaultLogMaxSizeMB * 1024 * 1024,
+		maxBackups: defaultLogMaxBackups,
+		retention:  defaultLogRetentionDays * 24 * time.Hour,
+		redactor:   DefaultRedactor(),
+	}
+}
+
+// Configure applies level, format, rotation and retention settings
+func (fl *FileLogger) Configure(config LoggingConfig) error {
+	level, err := ParseLogLevel(config.Level)
+	if err != nil {
+		return err
+	}
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	fl.level = level
+	fl.jsonFormat = strings.EqualFold(config.Format, "json")
+	fl.maxSize = defaultLogMaxSizeMB * 1024 * 1024
+	if config.MaxSizeMB > 0 {
+		fl.maxSize = int64(config.MaxSizeMB) * 1024 * 1024
 	}
+	fl.maxBackups = defaultLogMaxBackups
+	if config.MaxBackups > 0 {
+		fl.maxBackups = config.MaxBackups
+	}
+	fl.retention = defaultLogRetentionDays * 24 * time.Hour
+	if config.RetentionDays > 0 {
+		fl.retention = time.Duration(config.RetentionDays) * 24 * time.Hour
+	}
+	fl.compress = config.Compress
+	// Apply the new retention and compression with the next entry
+	fl.lastRetention = time.Time{}
+	return nil
+}
+
+// Dir returns the directory log files are written to
+func (fl *FileLogger) Dir() string {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	return fl.logDir
+}
+
+// SetDir writes log files to dir from the next entry on
+func (fl *FileLogger) SetDir(dir string) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.logDir = dir
+	fl.lastRetention = time.Time{}
+}
+
+// Prune applies retention and compression now rather than at the next
+// hourly sweep
+func (fl *FileLogger) Prune() {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.lastRetention = time.Time{}
+	fl.enforceRetention(time.Now())
+}
+
+// SetRedactor replaces the patterns masked out of log entries
+func (fl *FileLogger) SetRedactor(redactor *Redactor) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.redactor = redactor
+}
+
+// SetLevel changes the minimum level written to the log
+func (fl *FileLogger) SetLevel(level LogLevel) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.level = level
+}
+
+// Debug logs a debug message
+func (fl *FileLogger) Debug(message string) {
+	fl.write(LevelDebug, message, nil, nil)
 }
 
 // Info logs an info message
 func (fl *FileLogger) Info(message string) {
-	fl.logToFile("INFO", message)
+	fl.write(LevelInfo, message, nil, nil)
+}
+
+// Warn logs a warning message
+func (fl *FileLogger) Warn(message string) {
+	fl.write(LevelWarn, message, nil, nil)
 }
 
 // Error logs an error message
 func (fl *FileLogger) Error(message string, err error) {
-	fullMessage := fmt.Sprintf("%s: %v", message, err)
-	fl.logToFile("ERROR", fullMessage)
+	fl.write(LevelError, message, err, nil)
 }
 
 // InfoWithFields logs an info message with structured fields
 func (fl *FileLogger) InfoWithFields(message string, fields map[string]interface{}) {
-	fieldsStr := fl.formatFields(fields)
-	fullMessage := fmt.Sprintf("%s %s", message, fieldsStr)
-	fl.logToFile("INFO", fullMessage)
+	fl.write(LevelInfo, message, nil, fields)
 }
 
 // ErrorWithFields logs an error message with structured fields
 func (fl *FileLogger) ErrorWithFields(message string, err error, fields map[string]interface{}) {
-	fieldsStr := fl.formatFields(fields)
-	fullMessage := fmt.Sprintf("%s: %v %s", message, err, fieldsStr)
-	fl.logToFile("ERROR", fullMessage)
+	fl.write(LevelError, message, err, fields)
+}
+
+// write formats an entry and appends it to today's log file
+func (fl *FileLogger) write(level LogLevel, message string, err error, fields map[string]interface{}) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if level < fl.level {
+		return
+	}
+
+	now := time.Now()
+	var entry string
+	if fl.jsonFormat {
+		entry = fl.formatJSON(now, level, message, err, fields)
+	} else {
+		entry = fl.formatText(now, level, message, err, fields)
+	}
+	fl.logToFile(now, fl.redactor.Redact(entry))
+}
+
+// formatText renders the classic "[time] LEVEL taskwrapper: message" line
+func (fl *FileLogger) formatText(now time.Time, level LogLevel, message string, err error, fields map[string]interface{}) string {
+	if level == LevelError {
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+	if fieldsStr := formatLogFields(fields); fieldsStr != "" {
+		message = message + " " + fieldsStr
+	}
+	timestamp := now.Format("2006-01-02 15:04:05")
+	return fmt.Sprintf("[%s] %s taskwrapper: %s\n", timestamp, level, message)
 }
 
-// formatFields formats structured fields for logging
-func (fl *FileLogger) formatFields(fields map[string]interface{}) string {
+// formatJSON renders a single JSON object per line for log ingestion
+func (fl *FileLogger) formatJSON(now time.Time, level LogLevel, message string, err error, fields map[string]interface{}) string {
+	record := map[string]interface{}{
+		"time":   now.Format(time.RFC3339Nano),
+		"level":  strings.ToLower(level.String()),
+		"source": "taskwrapper",
+		"msg":    message,
+	}
+	if err != nil {
+		record["error"] = err.Error()
+	}
+	if len(fields) > 0 {
+		safe := make(map[string]interface{}, len(fields))
+		for key, value := range fields {
+			if e, ok := value.(error); ok {
+				value = e.Error()
+			}
+			safe[key] = value
+		}
+		record["fields"] = safe
+	}
+
+	data, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		data, _ = json.Marshal(map[string]interface{}{
+			"time":  record["time"],
+			"level": record["level"],
+			"msg":   fmt.Sprintf("%s (unserializable fields: %v)", message, marshalErr),
+		})
+	}
+	return string(data) + "\n"
+}
+
+// formatLogFields formats structured fields as "[k=v, ...]" with sorted keys
+func formatLogFields(fields map[string]interface{}) string {
 	if len(fields) == 0 {
 		return ""
 	}
-	
-	result := "["
-	first := true
-	for key, value := range fields {
-		if !first {
-			result += ", "
-		}
-		result += fmt.Sprintf("%s=%v", key, value)
-		first = false
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", key, fields[key])
 	}
-	result += "]"
-	return result
+	return "[" + strings.Join(parts, ", ") + "]"
 }
 
-// logToFile writes log entries to the universal log file
-func (fl *FileLogger) logToFile(level, message string) {
-	// Get current date for log file
-	now := time.Now()
-	logDate := now.Format("2006-01-02")
-	
-	logFile := filepath.Join(fl.logDir, "universal_logs-"+logDate+".log")
-	
+// logFilePath returns today's log file; JSON logs use a separate .jsonl file
+// so text lines appended by the helper scripts don't corrupt them
+func (fl *FileLogger) logFilePath(now time.Time) string {
+	ext := ".log"
+	if fl.jsonFormat {
+		ext = ".jsonl"
+	}
+	return filepath.Join(fl.logDir, "universal_logs-"+now.Format("2006-01-02")+ext)
+}
+
+// logToFile writes log entries to the universal log file (must be called with lock held)
+func (fl *FileLogger) logToFile(now time.Time, logEntry string) {
 	// Ensure log directory exists
 	if err := os.MkdirAll(fl.logDir, 0755); err != nil {
 		log.Printf("Failed to create log directory: %v", err)
 		return
 	}
-	
-	// Format log entry
-	timestamp := now.Format("2006-01-02 15:04:05")
-	logEntry := fmt.Sprintf("[%s] %s taskwrapper: %s\n", timestamp, level, message)
-	
+
+	logFile := fl.logFilePath(now)
+	fl.rotateIfNeeded(logFile, int64(len(logEntry)))
+	fl.enforceRetention(now)
+
 	// Append to log file
 	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -89,23 +298,143 @@ func (fl *FileLogger) logToFile(level, message string) {
 		return
 	}
 	defer f.Close()
-	
+
 	if _, err := f.WriteString(logEntry); err != nil {
 		log.Printf("Failed to write to log file: %v", err)
 	}
 }
 
+// rotateIfNeeded shifts logFile to logFile.1 (and older copies up) once it
+// would grow past maxSize
+func (fl *FileLogger) rotateIfNeeded(logFile string, incoming int64) {
+	info, err := os.Stat(logFile)
+	if err != nil || info.Size()+incoming <= fl.maxSize {
+		return
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", logFile, fl.maxBackups))
+	for i := fl.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", logFile, i), fmt.Sprintf("%s.%d", logFile, i+1))
+	}
+	if err := os.Rename(logFile, logFile+".1"); err != nil {
+		log.Printf("Failed to rotate log file: %v", err)
+	}
+}
+
+// enforceRetention deletes log files older than the retention window and,
+// if compression is on, gzips the ones from earlier days, at most hourly
+func (fl *FileLogger) enforceRetention(now time.Time) {
+	if now.Sub(fl.lastRetention) < time.Hour {
+		return
+	}
+	fl.lastRetention = now
+
+	files, err := filepath.Glob(filepath.Join(fl.logDir, "universal_logs-*"))
+	if err != nil {
+		return
+	}
+
+	cutoff := now.Add(-fl.retention)
+	// Today's files, rotated copies included, may still be written or
+	// renamed; helper scripts append to them too
+	today := "universal_logs-" + now.Format("2006-01-02")
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if info.ModTime().Before(cutoff) {
+			os.Remove(file)
+			continue
+		}
+		if fl.compress && !strings.HasSuffix(file, ".gz") && !strings.HasPrefix(filepath.Base(file), today) {
+			if err := compressLogFile(file, info); err != nil {
+				log.Printf("Failed to compress log file: %v", err)
+			}
+		}
+	}
+}
+
+// compressLogFile replaces file with file.gz, keeping its modification time
+// so retention still counts from when it was last written
+func compressLogFile(file string, info os.FileInfo) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := file + ".tmp.gz"
+	dest, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dest)
+	zw.Name = filepath.Base(file)
+	zw.ModTime = info.ModTime()
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dest.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmp, file+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(file)
+}
+
 // ConsoleLogger implements Logger interface with console output
-type ConsoleLogger struct{}
+type ConsoleLogger struct {
+	mu    sync.Mutex
+	level LogLevel
+}
 
 // NewConsoleLogger creates a new console logger
 func NewConsoleLogger() *ConsoleLogger {
-	return &ConsoleLogger{}
+	return &ConsoleLogger{level: LevelInfo}
+}
+
+// SetLevel changes the minimum level printed to the console
+func (cl *ConsoleLogger) SetLevel(level LogLevel) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.level = level
+}
+
+// enabled reports whether messages at level should be printed
+func (cl *ConsoleLogger) enabled(level LogLevel) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return level >= cl.level
+}
+
+// Debug logs a debug message to console
+func (cl *ConsoleLogger) Debug(message string) {
+	if cl.enabled(LevelDebug) {
+		log.Printf("[DEBUG] %s", message)
+	}
 }
 
 // Info logs an info message to console
 func (cl *ConsoleLogger) Info(message string) {
-	log.Printf("[INFO] %s", message)
+	if cl.enabled(LevelInfo) {
+		log.Printf("[INFO] %s", message)
+	}
+}
+
+// Warn logs a warning message to console
+func (cl *ConsoleLogger) Warn(message string) {
+	if cl.enabled(LevelWarn) {
+		log.Printf("[WARN] %s", message)
+	}
 }
 
 // Error logs an error message to console
@@ -115,29 +444,12 @@ func (cl *ConsoleLogger) Error(message string, err error) {
 
 // InfoWithFields logs an info message with fields to console
 func (cl *ConsoleLogger) InfoWithFields(message string, fields map[string]interface{}) {
-	log.Printf("[INFO] %s %s", message, cl.formatFields(fields))
+	if cl.enabled(LevelInfo) {
+		log.Printf("[INFO] %s %s", message, formatLogFields(fields))
+	}
 }
 
 // ErrorWithFields logs an error message with fields to console
 func (cl *ConsoleLogger) ErrorWithFields(message string, err error, fields map[string]interface{}) {
-	log.Printf("[ERROR] %s: %v %s", message, err, cl.formatFields(fields))
+	log.Printf("[ERROR] %s: %v %s", message, err, formatLogFields(fields))
 }
-
-// formatFields formats structured fields for console output
-func (cl *ConsoleLogger) formatFields(fields map[string]interface{}) string {
-	if len(fields) == 0 {
-		return ""
-	}
-	
-	result := "["
-	first := true
-	for key, value := range fields {
-		if !first {
-			result += ", "
-		}
-		result += fmt.Sprintf("%s=%v", key, value)
-		first = false
-	}
-	result += "]"
-	return result
-}
\ No newline at end of file
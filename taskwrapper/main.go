@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"io/fs"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -18,9 +21,14 @@ const (
 var assets embed.FS
 
 func main() {
-	// Create an instance of the app structure
-	app := NewApp()
+	// Without a subcommand the root command opens the desktop window
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
 
+// runDesktop runs the Wails desktop application
+func runDesktop(app *App) {
 	// Create application with options
 	err := wails.Run(&options.App{
 		Title:  AppName + " v" + AppVersion,
@@ -31,6 +39,8 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnBeforeClose:    app.beforeClose, // Hides the window instead while the tray is up
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
@@ -40,3 +50,31 @@ func main() {
 		println("Error:", err.Error())
 	}
 }
+
+// runServeMode runs the backend headless and hosts the frontend for remote browsers
+func runServeMode(app *App, addr string, pprof bool) {
+	app.headless = true
+	app.startup(context.Background())
+
+	var remoteConfig RemoteConfig
+	if app.configService != nil {
+		remoteConfig = app.configService.GetRemoteConfig()
+	}
+	if addr == "" {
+		addr = remoteConfig.Addr
+	}
+	if pprof {
+		remoteConfig.Pprof = true
+	}
+
+	dist, err := fs.Sub(assets, "frontend/dist")
+	if err != nil {
+		println("Error:", err.Error())
+		return
+	}
+
+	server := NewRemoteServer(app, dist, remoteConfig, app.logger)
+	if err := server.ListenAndServe(addr); err != nil {
+		println("Error:", err.Error())
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// How a repository's mainline is brought up to date before an agent is
+// spawned, so agents don't branch from a stale main
+const (
+	MainlineLocal       = ""             // use local main as it is
+	MainlineFastForward = "fast_forward" // fetch origin and fast-forward local main
+	MainlineOrigin      = "origin"       // fetch origin and base the worktree on origin/main
+)
+
+// Where approving a task merges its branch
+const (
+	ApproveInCheckout = ""         // in the checkout that has the mainline, usually the user's
+	ApproveInWorktree = "worktree" // in a temporary worktree, then fast-forward the mainline
+)
+
+// The remote agents start from, and the branch they start from unless the
+// repository sets its own
+const (
+	mainlineRemote        = "origin"
+	defaultMainlineBranch = "main"
+)
+
+// mainlineSyncTimeout bounds the fetch and fast-forward before a spawn
+const mainlineSyncTimeout = time.Minute
+
+// validMainlineBranch reports whether name can be a mainline branch: empty
+// for the default, or a plain local branch name
+func validMainlineBranch(name string) bool {
+	if name == "" {
+		return true
+	}
+	if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") ||
+		strings.HasSuffix(name, ".lock") || strings.Contains(name, "..") || strings.Contains(name, "//") {
+		return false
+	}
+	return !strings.ContainsAny(name, " ~^:?*[\\\t\n") && !strings.Contains(name, "@{")
+}
+
+// validApprovalMode reports whether mode is one of the Approve modes
+func validApprovalMode(mode string) bool {
+	return mode == ApproveInCheckout || mode == ApproveInWorktree
+}
+
+// validMainlineSync reports whether mode is one of the mainline sync modes
+func validMainlineSync(mode string) bool {
+	switch mode {
+	case MainlineLocal, MainlineFastForward, MainlineOrigin:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// milestoneDateLayout is the format of Milestone.DueDate
+const milestoneDateLayout = "2006-01-02"
+
+// Milestone groups tasks into a release
+type Milestone struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	DueDate string `json:"dueDate,omitempty"` // YYYY-MM-DD
+	TaskIDs []int  `json:"taskIds"`           // in the order the release lists them
+}
+
+// MilestoneProgress is how far a milestone's tasks have got
+type MilestoneProgress struct {
+	Milestone
+	Total       int    `json:"total"` // tasks that still exist on the board
+	Done        int    `json:"done"`
+	Percent     int    `json:"percent"`
+	Overdue     bool   `json:"overdue"` // past the due date with work left
+	Summary     string `json:"summary"` // e.g. "v1.2: 7/12 done"
+	DoneTaskIDs []int  `json:"doneTaskIds"`
+}
+
+// MilestoneService stores milestones in plan/milestones.json, next to
+// task.json, so they are versioned with the plan
+type MilestoneService struct {
+	path      string
+	fileUtils *FileUtils
+	mu        sync.Mutex
+	logger    Logger
+}
+
+// NewMilestoneService creates a milestone service backed by path
+func NewMilestoneService(path string, logger Logger) *MilestoneService {
+	return &MilestoneService{
+		path:      path,
+		fileUtils: NewFileUtils(logger),
+		logger:    logger,
+	}
+}
+
+// SetMilestoneFile points the service at another repository's milestones.json
+func (ms *MilestoneService) SetMilestoneFile(path string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.path = path
+}
+
+// GetMilestoneFile returns the milestones.json path
+func (ms *MilestoneService) GetMilestoneFile() string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.path
+}
+
+// SetBackupDir sets where milestones.json backups are written
+func (ms *MilestoneService) SetBackupDir(dir string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.fileUtils.SetBackupDir(dir)
+}
+
+// SetCipher turns at-rest encryption of milestones.json on or off
+func (ms *MilestoneService) SetCipher(cipher *Cipher) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.fileUtils.SetCipher(cipher)
+}
+
+// List returns the milestones in file order
+func (ms *MilestoneService) List() ([]Milestone, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.load()
+}
+
+// Create adds a milestone
+func (ms *MilestoneService) Create(milestone Milestone) (Milestone, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	milestones, err := ms.load()
+	if err != nil {
+		return Milestone{}, err
+	}
+	milestone.ID = generateID()
+	milestone, err = normalizeMilestone(milestone, milestones)
+	if err != nil {
+		return Milestone{}, err
+	}
+	if err := ms.save(append(milestones, milestone)); err != nil {
+		return Milestone{}, err
+	}
+	return milestone, nil
+}
+
+// Update replaces the milestone with the same ID
+func (ms *MilestoneService) Update(milestone Milestone) (Milestone, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	milestones, err := ms.load()
+	if err != nil {
+		return Milestone{}, err
+	}
+	index := milestoneIndex(milestones, milestone.ID)
+	if index < 0 {
+		return Milestone{}, NotFoundError("milestone not found", nil).WithContext("milestone", milestone.ID)
+	}
+	others := append(append([]Milestone{}, milestones[:index]...), milestones[index+1:]...)
+	milestone, err = normalizeMilestone(milestone, others)
+	if err != nil {
+		return Milestone{}, err
+	}
+	milestones[index] = milestone
+	if err := ms.save(milestones); err != nil {
+		return Milestone{}, err
+	}
+	return milestone, nil
+}
+
+// Delete removes a milestone; its tasks are left alone
+func (ms *MilestoneService) Delete(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	milestones, err := ms.load()
+	if err != nil {
+		return err
+	}
+	index := milestoneIndex(milestones, id)
+	if index < 0 {
+		return NotFoundError("milestone not found", nil).WithContext("milestone", id)
+	}
+	return ms.save(append(milestones[:index], milestones[index+1:]...))
+}
+
+// milestoneIndex returns the position of the milestone with id, or -1
+func milestoneIndex(milestones []Milestone, id string) int {
+	for i, m := range milestones {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeMilestone validates a milestone against the others and drops
+// repeated task IDs, keeping the first occurrence
+func normalizeMilestone(milestone Milestone, others []Milestone) (Milestone, error) {
+	milestone.Name = strings.TrimSpace(milestone.Name)
+	if milestone.Name == "" {
+		return Milestone{}, ValidationError("milestone name is required", nil)
+	}
+	for _, other := range others {
+		if strings.EqualFold(other.Name, milestone.Name) {
+			return Milestone{}, ConflictError("a milestone with this name already exists", nil).
+				WithContext("name", milestone.Name)
+		}
+	}
+	milestone.DueDate = strings.TrimSpace(milestone.DueDate)
+	if milestone.DueDate != "" {
+		if _, err := time.Parse(milestoneDateLayout, milestone.DueDate); err != nil {
+			return Milestone{}, ValidationError("due date must be YYYY-MM-DD", err).
+				WithContext("dueDate", milestone.DueDate)
+		}
+	}
+
+	seen := make(map[int]bool, len(milestone.TaskIDs))
+	taskIDs := []int{}
+	for _, id := range milestone.TaskIDs {
+		if !seen[id] {
+			seen[id] = true
+			taskIDs = append(taskIDs, id)
+		}
+	}
+	milestone.TaskIDs = taskIDs
+	return milestone, nil
+}
+
+// load reads milestones.json; a missing file means no milestones
+// (must be called with lock held)
+func (ms *MilestoneService) load() ([]Milestone, error) {
+	data, err := ms.fileUtils.ReadFile(ms.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Milestone{}, nil
+		}
+		return nil, fmt.Errorf("failed to read milestones: %v", err)
+	}
+	milestones := []Milestone{}
+	if err := json.Unmarshal(data, &milestones); err != nil {
+		return nil, CorruptedError("milestones file is not valid JSON", err).WithContext("file", ms.path)
+	}
+	return milestones, nil
+}
+
+// save writes milestones.json (must be called with lock held)
+func (ms *MilestoneService) save(milestones []Milestone) error {
+	if err := ms.fileUtils.AtomicWriteJSON(ms.path, milestones); err != nil {
+		ms.logger.Error("Failed to save milestones", err)
+		return fmt.Errorf("failed to save milestones: %v", err)
+	}
+	return nil
+}
+
+// milestoneProgress counts a milestone's done tasks. Task IDs no longer on
+// the board are left out of the total.
+func milestoneProgress(milestone Milestone, tasks []Task, now time.Time) MilestoneProgress {
+	status := make(map[int]TaskStatus, len(tasks))
+	for _, task := range tasks {
+		status[task.ID] = task.Status
+	}
+
+	progress := MilestoneProgress{Milestone: milestone, DoneTaskIDs: []int{}}
+	for _, id := range milestone.TaskIDs {
+		s, ok := status[id]
+		if !ok {
+			continue
+		}
+		progress.Total++
+		if s == StatusDone {
+			progress.Done++
+			progress.DoneTaskIDs = append(progress.DoneTaskIDs, id)
+		}
+	}
+	if progress.Total > 0 {
+		progress.Percent = progress.Done * 100 / progress.Total
+	}
+	if due, err := time.ParseInLocation(milestoneDateLayout, milestone.DueDate, now.Location()); err == nil {
+		// Due dates are inclusive: a milestone due today isn't overdue yet
+		progress.Overdue = progress.Done < progress.Total && now.After(due.AddDate(0, 0, 1))
+	}
+	progress.Summary = fmt.Sprintf("%s: %d/%d done", milestone.Name, progress.Done, progress.Total)
+	return progress
+}
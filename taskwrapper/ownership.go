@@ -0,0 +1,123 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ownershipWindow is how far back git shortlog looks for a file's authors
+	ownershipWindow = "6 months ago"
+	// maxFileOwners caps the authors listed for one file
+	maxFileOwners = 3
+	// maxOwnershipFiles caps the files looked up for one review; the rest
+	// of a huge change is left out
+	maxOwnershipFiles = 50
+)
+
+// botAuthorMarkers identify commit authors that aren't people
+var botAuthorMarkers = []string{"[bot]", "noreply@anthropic.com", "claude", "dependabot", "renovate"}
+
+// FileAuthor is someone who committed to a file, with their commit count
+type FileAuthor struct {
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Commits int    `json:"commits"`
+}
+
+// FileOwnership is the recent human authors of one changed file
+type FileOwnership struct {
+	Path    string       `json:"path"`
+	Authors []FileAuthor `json:"authors"` // most commits first
+}
+
+// ReviewOwnership suggests who else should look at a task's changes
+type ReviewOwnership struct {
+	Files     []FileOwnership `json:"files"`
+	Reviewers []FileAuthor    `json:"reviewers"`           // everyone above, commits summed, most first
+	Truncated bool            `json:"truncated,omitempty"` // more than maxOwnershipFiles files changed
+}
+
+// parseShortlog parses git shortlog -sne output, "   5\tName <email>" per line
+func parseShortlog(output string) []FileAuthor {
+	authors := []FileAuthor{}
+	for _, line := range strings.Split(output, "\n") {
+		count, author, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		commits, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			continue
+		}
+		name, email := strings.TrimSpace(author), ""
+		if open := strings.LastIndex(name, "<"); open >= 0 && strings.HasSuffix(name, ">") {
+			name, email = strings.TrimSpace(name[:open]), name[open+1:len(name)-1]
+		}
+		authors = append(authors, FileAuthor{Name: name, Email: email, Commits: commits})
+	}
+	return authors
+}
+
+// humanAuthors drops bots and the current user, whose identity agents
+// commit under too, and keeps the top maxFileOwners
+func humanAuthors(authors []FileAuthor, self string) []FileAuthor {
+	humans := []FileAuthor{}
+	for _, author := range authors {
+		if self != "" && strings.EqualFold(author.Email, self) {
+			continue
+		}
+		identity := strings.ToLower(author.Name + " " + author.Email)
+		bot := false
+		for _, marker := range botAuthorMarkers {
+			if strings.Contains(identity, marker) {
+				bot = true
+				break
+			}
+		}
+		if !bot {
+			humans = append(humans, author)
+		}
+	}
+	sort.SliceStable(humans, func(i, j int) bool { return humans[i].Commits > humans[j].Commits })
+	if len(humans) > maxFileOwners {
+		humans = humans[:maxFileOwners]
+	}
+	return humans
+}
+
+// suggestedReviewers merges the files' authors, by email or else name
+func suggestedReviewers(files []FileOwnership) []FileAuthor {
+	index := map[string]int{}
+	reviewers := []FileAuthor{}
+	for _, file := range files {
+		for _, author := range file.Authors {
+			key := strings.ToLower(author.Email)
+			if key == "" {
+				key = author.Name
+			}
+			if i, ok := index[key]; ok {
+				reviewers[i].Commits += author.Commits
+				continue
+			}
+			index[key] = len(reviewers)
+			reviewers = append(reviewers, author)
+		}
+	}
+	sort.SliceStable(reviewers, func(i, j int) bool { return reviewers[i].Commits > reviewers[j].Commits })
+	return reviewers
+}
+
+// String formats the reviewers for a notification, "Ann <ann@x>, Bo"
+func (ro ReviewOwnership) String() string {
+	names := make([]string, 0, len(ro.Reviewers))
+	for _, reviewer := range ro.Reviewers {
+		if reviewer.Email != "" {
+			names = append(names, reviewer.Name+" <"+reviewer.Email+">")
+		} else {
+			names = append(names, reviewer.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timed operation names
+const (
+	OpTaskLoad    = "task.load"
+	OpTaskSave    = "task.save"
+	OpPlanLoad    = "plan.load"
+	OpPlanSave    = "plan.save"
+	OpGitMerge    = "git.merge"
+	OpGitFetch    = "git.fetch"
+	OpAgentSpawn  = "agent.spawn"
+	OpAgentStatus = "agent.status"
+)
+
+// Sample retention and slow-operation logging
+const (
+	perfSampleWindow   = 200
+	slowOperationLimit = 2 * time.Second
+)
+
+// OperationStats summarises recorded durations for one operation, in milliseconds
+type OperationStats struct {
+	Operation string    `json:"operation"`
+	Count     int64     `json:"count"`
+	Errors    int64     `json:"errors"`
+	AvgMs     float64   `json:"avgMs"`
+	MinMs     float64   `json:"minMs"`
+	MaxMs     float64   `json:"maxMs"`
+	P95Ms     float64   `json:"p95Ms"` // over the most recent samples
+	LastMs    float64   `json:"lastMs"`
+	LastAt    time.Time `json:"lastAt"`
+}
+
+// operationSamples accumulates durations for one operation
+type operationSamples struct {
+	count   int64
+	errors  int64
+	total   time.Duration
+	min     time.Duration
+	max     time.Duration
+	last    time.Duration
+	lastAt  time.Time
+	recent  []time.Duration // ring buffer of the last perfSampleWindow samples
+	nextIdx int
+}
+
+// PerformanceRecorder keeps in-memory latency stats for file and git operations
+type PerformanceRecorder struct {
+	mu     sync.Mutex
+	ops    map[string]*operationSamples
+	logger Logger
+}
+
+// NewPerformanceRecorder creates a new performance recorder
+func NewPerformanceRecorder(logger Logger) *PerformanceRecorder {
+	return &PerformanceRecorder{
+		ops:    make(map[string]*operationSamples),
+		logger: logger,
+	}
+}
+
+// Time runs fn and records how long it took under op
+func (pr *PerformanceRecorder) Time(op string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	pr.Record(op, time.Since(start), err)
+	return err
+}
+
+// Record adds one duration sample for op
+func (pr *PerformanceRecorder) Record(op string, d time.Duration, err error) {
+	pr.mu.Lock()
+	samples, ok := pr.ops[op]
+	if !ok {
+		samples = &operationSamples{min: d}
+		pr.ops[op] = samples
+	}
+	samples.count++
+	if err != nil {
+		samples.errors++
+	}
+	samples.total += d
+	if d < samples.min {
+		samples.min = d
+	}
+	if d > samples.max {
+		samples.max = d
+	}
+	samples.last = d
+	samples.lastAt = time.Now()
+	if len(samples.recent) < perfSampleWindow {
+		samples.recent = append(samples.recent, d)
+	} else {
+		samples.recent[samples.nextIdx] = d
+		samples.nextIdx = (samples.nextIdx + 1) % perfSampleWindow
+	}
+	pr.mu.Unlock()
+
+	if d >= slowOperationLimit {
+		pr.logger.InfoWithFields("Slow operation", map[string]interface{}{
+			"operation":   op,
+			"duration_ms": durationMs(d),
+		})
+	}
+}
+
+// Stats returns a summary per operation, sorted by name
+func (pr *PerformanceRecorder) Stats() []OperationStats {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	stats := make([]OperationStats, 0, len(pr.ops))
+	for op, samples := range pr.ops {
+		stats = append(stats, OperationStats{
+			Operation: op,
+			Count:     samples.count,
+			Errors:    samples.errors,
+			AvgMs:     durationMs(samples.total / time.Duration(samples.count)),
+			MinMs:     durationMs(samples.min),
+			MaxMs:     durationMs(samples.max),
+			P95Ms:     durationMs(percentile(samples.recent, 0.95)),
+			LastMs:    durationMs(samples.last),
+			LastAt:    samples.lastAt,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Operation < stats[j].Operation
+	})
+	return stats
+}
+
+// Reset discards all recorded samples
+func (pr *PerformanceRecorder) Reset() {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.ops = make(map[string]*operationSamples)
+}
+
+// percentile returns the p-th percentile (0..1) of samples
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
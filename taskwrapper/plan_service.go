@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// defaultMaxPlanBytes is the largest plan.md saved, or loaded whole, when
+// no limit is configured. Larger documents are read with LoadPlanChunk.
+const defaultMaxPlanBytes = 5 << 20
+
+// maxPlanChunkBytes is the most LoadPlanChunk returns at once
+const maxPlanChunkBytes = 1 << 20
+
+// PlanChunk is a piece of plan.md, cut on a character boundary
+type PlanChunk struct {
+	Content string `json:"content"`
+	Offset  int64  `json:"offset"` // byte offset of Content in the file
+	Next    int64  `json:"next"`   // offset of the following chunk
+	Size    int64  `json:"size"`   // the whole file, in bytes
+	EOF     bool   `json:"eof"`    // Content runs to the end of the file
+}
+
+// PlanService handles reading and writing plan/plan.md
+type PlanService struct {
+	planFile  string
+	fileUtils *FileUtils
+	mu        sync.RWMutex
+	logger    Logger
+	perf      *PerformanceRecorder
+	readOnly  bool  // refuse saves, for an instance that doesn't own the board
+	maxBytes  int64 // largest plan saved or loaded whole; 0 is defaultMaxPlanBytes
+}
+
+// NewPlanService creates a new plan service
+func NewPlanService(planFile string, logger Logger) *PlanService {
+	return &PlanService{
+		planFile:  planFile,
+		fileUtils: NewFileUtils(logger),
+		logger:    logger,
+		perf:      NewPerformanceRecorder(logger),
+	}
+}
+
+// SetPerformanceRecorder sets where plan read/write latencies are recorded
+func (ps *PlanService) SetPerformanceRecorder(perf *PerformanceRecorder) {
+	ps.perf = perf
+}
+
+// SetBackupDir sets where plan.md backups are written
+func (ps *PlanService) SetBackupDir(dir string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.fileUtils.SetBackupDir(dir)
+}
+
+// SetCipher turns at-rest encryption of plan.md and its backups on or off
+func (ps *PlanService) SetCipher(cipher *Cipher) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.fileUtils.SetCipher(cipher)
+}
+
+// SetReadOnly makes saving the plan fail
+func (ps *PlanService) SetReadOnly(readOnly bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.readOnly = readOnly
+}
+
+// SetMaxSize sets the largest plan saved or loaded whole, in bytes; 0
+// restores the default
+func (ps *PlanService) SetMaxSize(bytes int64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.maxBytes = bytes
+}
+
+// limit is the largest plan saved or loaded whole. Caller holds ps.mu.
+func (ps *PlanService) limit() int64 {
+	if ps.maxBytes > 0 {
+		return ps.maxBytes
+	}
+	return defaultMaxPlanBytes
+}
+
+// SetPlanFile points the service at another repository's plan.md
+func (ps *PlanService) SetPlanFile(path string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.planFile = path
+}
+
+// GetPlanFile returns the plan.md path
+func (ps *PlanService) GetPlanFile() string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.planFile
+}
+
+// LoadPlan returns the content of plan.md
+func (ps *PlanService) LoadPlan() (string, error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	ps.logger.InfoWithFields("Loading plan", map[string]interface{}{
+		"plan_file": ps.planFile,
+	})
+
+	// Refuse before reading rather than after
+	if info, err := os.Stat(ps.planFile); err == nil && info.Size() > ps.limit() {
+		return "", ValidationError(fmt.Sprintf("plan.md is over the %d KB limit; load it in chunks", ps.limit()/1024), nil).
+			WithContext("file", ps.planFile).
+			WithContext("bytes", info.Size())
+	}
+
+	var content string
+	err := ps.perf.Time(OpPlanLoad, func() error {
+		data, readErr := ps.fileUtils.ReadFile(ps.planFile)
+		content = string(data)
+		return readErr
+	})
+	if err != nil {
+		ps.logger.Error("Failed to load plan.md", err)
+		return "", fmt.Errorf("failed to read plan.md: %w", err)
+	}
+	if offset := binaryOffset([]byte(content)); offset >= 0 {
+		return "", notTextError(ps.planFile, int64(offset))
+	}
+
+	ps.logger.Info("Plan loaded successfully")
+	return content, nil
+}
+
+// LoadPlanChunk returns up to length bytes of plan.md from offset, cut back
+// to the last whole character, for documents too large to load whole. A
+// length of 0, or over maxPlanChunkBytes, is maxPlanChunkBytes. An
+// encrypted plan is decrypted whole and then cut.
+func (ps *PlanService) LoadPlanChunk(offset int64, length int) (PlanChunk, error) {
+	if offset < 0 || length < 0 {
+		return PlanChunk{}, ValidationError("plan chunk offset and length must not be negative", nil).
+			WithContext("offset", offset).
+			WithContext("length", length)
+	}
+	if length == 0 || length > maxPlanChunkBytes {
+		length = maxPlanChunkBytes
+	}
+	// Room for one whole character
+	if length < utf8.UTFMax {
+		length = utf8.UTFMax
+	}
+
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var chunk PlanChunk
+	err := ps.perf.Time(OpPlanLoad, func() error {
+		var err error
+		chunk, err = ps.readChunk(offset, length)
+		return err
+	})
+	if err != nil {
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			return PlanChunk{}, err
+		}
+		ps.logger.Error("Failed to load plan.md chunk", err)
+		return PlanChunk{}, fmt.Errorf("failed to read plan.md: %w", err)
+	}
+	return chunk, nil
+}
+
+// readChunk reads length bytes of plan.md from offset. Caller holds ps.mu.
+func (ps *PlanService) readChunk(offset int64, length int) (PlanChunk, error) {
+	var data []byte
+	var size int64
+	if fileIsEncrypted(ps.planFile) {
+		plain, err := ps.fileUtils.ReadFile(ps.planFile)
+		if err != nil {
+			return PlanChunk{}, err
+		}
+		size = int64(len(plain))
+		if offset <= size {
+			data = plain[offset:min(offset+int64(length), size)]
+		}
+	} else {
+		file, err := os.Open(ps.planFile)
+		if err != nil {
+			return PlanChunk{}, err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return PlanChunk{}, err
+		}
+		size = info.Size()
+		if offset <= size {
+			data = make([]byte, min(int64(length), size-offset))
+			if _, err := file.ReadAt(data, offset); err != nil && err != io.EOF {
+				return PlanChunk{}, err
+			}
+		}
+	}
+	if offset > size {
+		return PlanChunk{}, ValidationError("plan chunk offset is past the end of plan.md", nil).
+			WithContext("offset", offset).
+			WithContext("size", size)
+	}
+	if len(data) > 0 && !utf8.RuneStart(data[0]) {
+		return PlanChunk{}, ValidationError("plan chunk offset is inside a character", nil).
+			WithContext("offset", offset)
+	}
+
+	end := offset + int64(len(data))
+	if end < size {
+		data = data[:wholeRunes(data)]
+		end = offset + int64(len(data))
+	}
+	if at := binaryOffset(data); at >= 0 {
+		return PlanChunk{}, notTextError(ps.planFile, offset+int64(at))
+	}
+	return PlanChunk{
+		Content: string(data),
+		Offset:  offset,
+		Next:    end,
+		Size:    size,
+		EOF:     end == size,
+	}, nil
+}
+
+// wholeRunes is the length of data without a character cut off at its end
+func wholeRunes(data []byte) int {
+	start := len(data) - 1
+	for start > 0 && len(data)-start < utf8.UTFMax && !utf8.RuneStart(data[start]) {
+		start--
+	}
+	if start >= 0 && !utf8.FullRune(data[start:]) {
+		return start
+	}
+	return len(data)
+}
+
+// binaryOffset returns where data stops being text, at a NUL byte or
+// invalid UTF-8, or -1 if it is text throughout
+func binaryOffset(data []byte) int {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == 0 || (r == utf8.RuneError && size == 1) {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// notTextError reports plan content that isn't text
+func notTextError(file string, offset int64) *AppError {
+	return ValidationError("plan.md must be UTF-8 text; found binary content", nil).
+		WithContext("file", file).
+		WithContext("offset", offset)
+}
+
+// SavePlan backs up plan.md and writes content to it
+func (ps *PlanService) SavePlan(content string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
+	if int64(len(content)) > ps.limit() {
+		return ValidationError(fmt.Sprintf("plan is over the %d KB limit", ps.limit()/1024), nil).
+			WithContext("bytes", len(content))
+	}
+	if offset := binaryOffset([]byte(content)); offset >= 0 {
+		return notTextError(ps.planFile, int64(offset))
+	}
+
+	ps.logger.InfoWithFields("Saving plan", map[string]interface{}{
+		"plan_file": ps.planFile,
+	})
+
+	// Create backup of plan.md
+	if _, err := ps.fileUtils.CreateBackup(ps.planFile); err != nil {
+		ps.logger.Error("Failed to create backup of plan.md", err)
+		// Continue with save even if backup fails
+	}
+
+	// Write the new content
+	err := ps.perf.Time(OpPlanSave, func() error {
+		return ps.fileUtils.AtomicWrite(ps.planFile, []byte(content))
+	})
+	if err != nil {
+		ps.logger.Error("Failed to save plan.md", err)
+		return fmt.Errorf("failed to write plan.md: %w", err)
+	}
+
+	ps.logger.Info("Plan saved successfully")
+	return nil
+}
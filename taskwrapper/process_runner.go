@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+)
+
+// Command describes an external process to run
+type Command struct {
+	Name  string
+	Args  []string
+	Dir   string
+	Env   []string // nil inherits the current environment
+	Stdin []byte   // optional input, e.g. secrets kept off the command line
+}
+
+// ProcessRunner runs external commands. AgentService and GitClient go through
+// it so tests can substitute canned output for real processes.
+type ProcessRunner interface {
+	// Output runs the command and returns its stdout
+	Output(ctx context.Context, cmd Command) ([]byte, error)
+	// CombinedOutput runs the command and returns stdout and stderr together
+	CombinedOutput(ctx context.Context, cmd Command) ([]byte, error)
+}
+
+// ProcessStarter starts long-running commands, such as agents, without
+// waiting for them. Runners that implement it can launch agents natively.
+type ProcessStarter interface {
+	// Start runs the command in the background, writing its output to
+	// stdout and stderr, until it exits on its own or is signalled
+	Start(cmd Command, stdout, stderr io.Writer) (StartedProcess, error)
+}
+
+// StartedProcess is a command started by a ProcessStarter
+type StartedProcess interface {
+	Pid() int
+	// Wait blocks until the process exits
+	Wait() error
+}
+
+// ExecRunner is the ProcessRunner backed by os/exec
+type ExecRunner struct{}
+
+// NewExecRunner creates a runner that starts real processes
+func NewExecRunner() *ExecRunner {
+	return &ExecRunner{}
+}
+
+// Output runs the command and returns its stdout
+func (ExecRunner) Output(ctx context.Context, cmd Command) ([]byte, error) {
+	return buildExecCmd(ctx, cmd).Output()
+}
+
+// CombinedOutput runs the command and returns stdout and stderr together
+func (ExecRunner) CombinedOutput(ctx context.Context, cmd Command) ([]byte, error) {
+	return buildExecCmd(ctx, cmd).CombinedOutput()
+}
+
+// Start runs the command in the background. It isn't bound to a context, so
+// it outlives the launch that started it.
+func (ExecRunner) Start(cmd Command, stdout, stderr io.Writer) (StartedProcess, error) {
+	c := buildExecCmd(context.Background(), cmd)
+	c.Stdout = stdout
+	c.Stderr = stderr
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	return execProcess{c}, nil
+}
+
+// execProcess is a StartedProcess backed by os/exec
+type execProcess struct {
+	cmd *exec.Cmd
+}
+
+func (p execProcess) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+func (p execProcess) Wait() error {
+	return p.cmd.Wait()
+}
+
+// buildExecCmd converts a Command into an exec.Cmd bound to ctx
+func buildExecCmd(ctx context.Context, cmd Command) *exec.Cmd {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c := exec.CommandContext(ctx, cmd.Name, cmd.Args...)
+	c.Dir = cmd.Dir
+	c.Env = cmd.Env
+	if cmd.Stdin != nil {
+		c.Stdin = bytes.NewReader(cmd.Stdin)
+	}
+	return c
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// quotaWindow is the longest window limits look back over; older launches are dropped
+const quotaWindow = 24 * time.Hour
+
+// LaunchRecord is one counted agent launch
+type LaunchRecord struct {
+	Time time.Time `json:"time"`
+	Repo string    `json:"repo"`
+}
+
+// QuotaUsage is launches used against one limit
+type QuotaUsage struct {
+	Used    int        `json:"used"`
+	Limit   int        `json:"limit"`             // 0 means no limit
+	ResetAt *time.Time `json:"resetAt,omitempty"` // when a launch frees up, if the limit is reached
+}
+
+// QuotaStatus is launch usage for all repositories and the given one
+type QuotaStatus struct {
+	Repo       string     `json:"repo"`
+	Hourly     QuotaUsage `json:"hourly"`
+	Daily      QuotaUsage `json:"daily"`
+	RepoHourly QuotaUsage `json:"repoHourly"`
+	RepoDaily  QuotaUsage `json:"repoDaily"`
+}
+
+// QuotaService counts agent launches and refuses ones over the configured
+// limits. Launches are kept in a file so the desktop app and CLI share counts.
+type QuotaService struct {
+	mu        sync.Mutex
+	path      string
+	limits    QuotaConfig
+	launches  []LaunchRecord
+	logger    Logger
+	fileUtils FileUtilsInterface
+	now       func() time.Time
+}
+
+// NewQuotaService creates a quota service recording launches at path;
+// an empty path keeps them in memory
+func NewQuotaService(path string, limits QuotaConfig, logger Logger) *QuotaService {
+	return &QuotaService{
+		path:      path,
+		limits:    limits,
+		logger:    logger,
+		fileUtils: NewFileUtils(logger),
+		now:       time.Now,
+	}
+}
+
+// SetLimits replaces the launch limits
+func (qs *QuotaService) SetLimits(limits QuotaConfig) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.limits = limits
+}
+
+// Acquire counts a launch in repo, or returns a quota error if any limit
+// has been reached
+func (qs *QuotaService) Acquire(repo string) error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	qs.load()
+	status := qs.statusLocked(repo)
+	for _, check := range []struct {
+		name  string
+		usage QuotaUsage
+	}{
+		{"hourly", status.Hourly},
+		{"daily", status.Daily},
+		{"repository hourly", status.RepoHourly},
+		{"repository daily", status.RepoDaily},
+	} {
+		if check.usage.Limit > 0 && check.usage.Used >= check.usage.Limit {
+			err := QuotaError(fmt.Sprintf("%s agent launch limit of %d reached", check.name, check.usage.Limit), nil).
+				WithContext("repository", repo)
+			if check.usage.ResetAt != nil {
+				err.WithContext("resetAt", check.usage.ResetAt.Format(time.RFC3339))
+			}
+			return err
+		}
+	}
+
+	qs.launches = append(qs.launches, LaunchRecord{Time: qs.now().UTC(), Repo: repo})
+	if err := qs.save(); err != nil {
+		qs.logger.Error("Failed to save agent launch count", err)
+	}
+	return nil
+}
+
+// Status returns launch usage against the limits
+func (qs *QuotaService) Status(repo string) QuotaStatus {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.load()
+	return qs.statusLocked(repo)
+}
+
+// statusLocked counts launches in each window (must be called with lock held)
+func (qs *QuotaService) statusLocked(repo string) QuotaStatus {
+	now := qs.now()
+	usage := func(window time.Duration, limit int, repoOnly bool) QuotaUsage {
+		u := QuotaUsage{Limit: limit}
+		var oldest time.Time
+		for _, launch := range qs.launches {
+			if now.Sub(launch.Time) >= window || (repoOnly && launch.Repo != repo) {
+				continue
+			}
+			if u.Used == 0 || launch.Time.Before(oldest) {
+				oldest = launch.Time
+			}
+			u.Used++
+		}
+		if limit > 0 && u.Used >= limit {
+			resetAt := oldest.Add(window)
+			u.ResetAt = &resetAt
+		}
+		return u
+	}
+
+	return QuotaStatus{
+		Repo:       repo,
+		Hourly:     usage(time.Hour, qs.limits.HourlyLaunches, false),
+		Daily:      usage(quotaWindow, qs.limits.DailyLaunches, false),
+		RepoHourly: usage(time.Hour, qs.limits.RepoHourlyLaunches, true),
+		RepoDaily:  usage(quotaWindow, qs.limits.RepoDailyLaunches, true),
+	}
+}
+
+// load reads launches recorded by any process, dropping expired ones
+// (must be called with lock held)
+func (qs *QuotaService) load() {
+	if qs.path != "" {
+		if raw, err := os.ReadFile(qs.path); err == nil {
+			var launches []LaunchRecord
+			if err := json.Unmarshal(raw, &launches); err != nil {
+				qs.logger.Error("Ignoring unreadable agent launch counts", err)
+			} else {
+				qs.launches = launches
+			}
+		}
+	}
+
+	now := qs.now()
+	kept := qs.launches[:0]
+	for _, launch := range qs.launches {
+		if now.Sub(launch.Time) < quotaWindow {
+			kept = append(kept, launch)
+		}
+	}
+	qs.launches = kept
+}
+
+// save writes the launches to disk (must be called with lock held)
+func (qs *QuotaService) save() error {
+	if qs.path == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(qs.launches, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode agent launches: %v", err)
+	}
+	return qs.fileUtils.AtomicWrite(qs.path, raw)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// redactedText replaces secrets in logs and output buffers
+const redactedText = "[REDACTED]"
+
+// defaultRedactionPatterns match well-known credential formats. In a pattern
+// with a capturing group only the group is replaced, so "api_key=" survives
+// and shows what was removed.
+var defaultRedactionPatterns = []string{
+	`\bsk-ant-[A-Za-z0-9_-]{20,}`,               // Anthropic
+	`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`,         // OpenAI
+	`\bgh[pousr]_[A-Za-z0-9]{36,}`,              // GitHub tokens
+	`\bgithub_pat_[A-Za-z0-9_]{22,}`,            // GitHub fine-grained tokens
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}`,               // AWS access key IDs
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}`,           // Slack
+	`\bAIza[0-9A-Za-z_-]{35}`,                   // Google API keys
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,        // PEM private keys
+	`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`, // Authorization headers
+	`(?i)(?:api[_-]?key|secret|token|password)\b["']?\s*[:=]\s*["']?([^\s"',;]{8,})`, // key=value assignments
+}
+
+// Redactor masks secrets matched by a set of patterns. It is immutable, so
+// services share one and a configuration change swaps in a new one.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor compiles config's patterns plus, unless disabled, the defaults
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	sources := config.Patterns
+	if !config.DisableDefaults {
+		sources = append(append([]string{}, defaultRedactionPatterns...), sources...)
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	for _, source := range sources {
+		pattern, err := regexp.Compile(source)
+		if err != nil {
+			return nil, ValidationError(fmt.Sprintf("invalid redaction pattern %q", source), err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return &Redactor{patterns: patterns}, nil
+}
+
+// DefaultRedactor masks the well-known credential formats
+func DefaultRedactor() *Redactor {
+	redactor, _ := NewRedactor(RedactionConfig{})
+	return redactor
+}
+
+// Redact returns s with every match replaced. A nil Redactor returns s unchanged.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+
+	for _, pattern := range r.patterns {
+		if pattern.NumSubexp() == 0 {
+			s = pattern.ReplaceAllLiteralString(s, redactedText)
+			continue
+		}
+		s = redactGroup(pattern, s)
+	}
+	return s
+}
+
+// redactGroup replaces only the first capturing group of each match
+func redactGroup(pattern *regexp.Regexp, s string) string {
+	matches := pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2], m[3]
+		if start < 0 {
+			start, end = m[0], m[1]
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(redactedText)
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
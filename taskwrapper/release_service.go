@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Release is a tagged version and the tasks it shipped
+type Release struct {
+	Version   string           `json:"version"` // also the git tag
+	Date      time.Time        `json:"date"`
+	Since     string           `json:"since,omitempty"` // the previous tag; empty for the first release
+	TaskIDs   []int            `json:"taskIds"`
+	Groups    []ChangelogGroup `json:"groups"`
+	Changelog string           `json:"changelog"`        // the section written into the tag's message
+	Signed    bool             `json:"signed,omitempty"` // the tag was signed
+}
+
+// ReleaseService stores releases in plan/releases.json, next to task.json,
+// newest first
+type ReleaseService struct {
+	path      string
+	fileUtils *FileUtils
+	mu        sync.Mutex
+	logger    Logger
+}
+
+// NewReleaseService creates a release service backed by path
+func NewReleaseService(path string, logger Logger) *ReleaseService {
+	return &ReleaseService{
+		path:      path,
+		fileUtils: NewFileUtils(logger),
+		logger:    logger,
+	}
+}
+
+// SetReleaseFile points the service at another repository's releases.json
+func (rs *ReleaseService) SetReleaseFile(path string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.path = path
+}
+
+// GetReleaseFile returns the releases.json path
+func (rs *ReleaseService) GetReleaseFile() string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.path
+}
+
+// SetBackupDir sets where releases.json backups are written
+func (rs *ReleaseService) SetBackupDir(dir string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.fileUtils.SetBackupDir(dir)
+}
+
+// SetCipher turns at-rest encryption of releases.json on or off
+func (rs *ReleaseService) SetCipher(cipher *Cipher) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.fileUtils.SetCipher(cipher)
+}
+
+// List returns the releases, newest first
+func (rs *ReleaseService) List() ([]Release, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.load()
+}
+
+// Find returns the release of version
+func (rs *ReleaseService) Find(version string) (Release, bool, error) {
+	releases, err := rs.List()
+	if err != nil {
+		return Release{}, false, err
+	}
+	for _, release := range releases {
+		if release.Version == version {
+			return release, true, nil
+		}
+	}
+	return Release{}, false, nil
+}
+
+// Add records a release ahead of the earlier ones
+func (rs *ReleaseService) Add(release Release) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	releases, err := rs.load()
+	if err != nil {
+		return err
+	}
+	for _, other := range releases {
+		if other.Version == release.Version {
+			return ConflictError("release already recorded", nil).WithContext("version", release.Version)
+		}
+	}
+	return rs.save(append([]Release{release}, releases...))
+}
+
+// load reads releases.json; a missing file means no releases
+// (must be called with lock held)
+func (rs *ReleaseService) load() ([]Release, error) {
+	data, err := rs.fileUtils.ReadFile(rs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Release{}, nil
+		}
+		return nil, fmt.Errorf("failed to read releases: %v", err)
+	}
+	releases := []Release{}
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, CorruptedError("releases file is not valid JSON", err).WithContext("file", rs.path)
+	}
+	return releases, nil
+}
+
+// save writes releases.json (must be called with lock held)
+func (rs *ReleaseService) save(releases []Release) error {
+	if err := rs.fileUtils.AtomicWriteJSON(rs.path, releases); err != nil {
+		rs.logger.Error("Failed to save releases", err)
+		return fmt.Errorf("failed to save releases: %v", err)
+	}
+	return nil
+}
+
+// validReleaseVersion reports whether version can name a tag
+func validReleaseVersion(version string) bool {
+	return version != "" && !strings.HasPrefix(version, ".") && validMainlineBranch(version)
+}
+
+// releaseCommits picks each listed task's latest merge from commits and
+// returns the IDs that have none
+func releaseCommits(commits []GitCommit, taskIDs []int) ([]GitCommit, []int) {
+	latest := map[int]GitCommit{}
+	for _, commit := range commits {
+		id, _, ok := parseTaskMerge(commit.Subject)
+		if !ok {
+			continue
+		}
+		if seen, found := latest[id]; !found || commit.Time.After(seen.Time) {
+			latest[id] = commit
+		}
+	}
+	picked := []GitCommit{}
+	unmerged := []int{}
+	for _, id := range taskIDs {
+		if commit, ok := latest[id]; ok {
+			picked = append(picked, commit)
+		} else {
+			unmerged = append(unmerged, id)
+		}
+	}
+	return picked, unmerged
+}
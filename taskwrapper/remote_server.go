@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	defaultRemoteAddr = ":8090"
+	remoteTokenCookie = "taskwrapper_token"
+)
+
+// remoteShim stands in for the Wails IPC bridge when the frontend is served
+// over plain HTTP: bound methods become fetch calls and runtime events are no-ops.
+const remoteShim = `(function () {
+  function call(method, args) {
+    return fetch('/api/call/' + method, {
+      method: 'POST',
+      credentials: 'same-origin',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify(args)
+    }).then(function (res) {
+      return res.json().then(function (body) {
+        if (!res.ok) { throw body.error || res.statusText; }
+        return body.result;
+      });
+    });
+  }
+  window.go = {main: {App: new Proxy({}, {
+    get: function (_, method) { return function () { return call(method, Array.prototype.slice.call(arguments)); }; }
+  })}};
+  var noop = function () { return function () {}; };
+  window.runtime = new Proxy({}, {get: function () { return noop; }});
+})();`
+
+// RemoteServer serves the built frontend and proxies bound App methods over HTTP
+type RemoteServer struct {
+	app    *App
+	assets fs.FS
+	tokens []RemoteToken
+	pprof  bool
+	logger Logger
+}
+
+// NewRemoteServer creates a companion web UI server for the given app
+func NewRemoteServer(app *App, assets fs.FS, config RemoteConfig, logger Logger) *RemoteServer {
+	tokens := append([]RemoteToken{}, config.Tokens...)
+	if envToken := os.Getenv("TASKWRAPPER_TOKEN"); envToken != "" {
+		tokens = append(tokens, RemoteToken{Name: "env", Token: envToken, Role: RemoteRoleFull})
+	}
+
+	return &RemoteServer{
+		app:    app,
+		assets: assets,
+		tokens: tokens,
+		pprof:  config.Pprof,
+		logger: logger,
+	}
+}
+
+// ListenAndServe starts the HTTP server and blocks until it stops
+func (rs *RemoteServer) ListenAndServe(addr string) error {
+	if len(rs.tokens) == 0 {
+		return fmt.Errorf("no remote access tokens configured; add one under \"remote.tokens\" or set TASKWRAPPER_TOKEN")
+	}
+	if addr == "" {
+		addr = defaultRemoteAddr
+	}
+
+	rs.logger.InfoWithFields("Starting remote companion server", map[string]interface{}{
+		"addr":   addr,
+		"tokens": len(rs.tokens),
+		"pprof":  rs.pprof,
+	})
+
+	return http.ListenAndServe(addr, rs.Handler())
+}
+
+// Handler returns the HTTP handler with authentication applied
+func (rs *RemoteServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/call/", rs.handleCall)
+	mux.Handle(restAPIPrefix+"/", http.StripPrefix(restAPIPrefix, rs.restAPI()))
+	mux.HandleFunc("/remote/shim.js", rs.handleShim)
+	mux.HandleFunc("/", rs.handleAssets)
+	if rs.pprof {
+		mux.Handle("/debug/pprof/", requireFullRole(http.HandlerFunc(pprof.Index)))
+		mux.Handle("/debug/pprof/cmdline", requireFullRole(http.HandlerFunc(pprof.Cmdline)))
+		mux.Handle("/debug/pprof/profile", requireFullRole(http.HandlerFunc(pprof.Profile)))
+		mux.Handle("/debug/pprof/symbol", requireFullRole(http.HandlerFunc(pprof.Symbol)))
+		mux.Handle("/debug/pprof/trace", requireFullRole(http.HandlerFunc(pprof.Trace)))
+	}
+	return rs.authenticate(mux)
+}
+
+// requireFullRole rejects callers without a full-access token; profiles
+// expose command lines and memory contents
+func requireFullRole(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value(remoteRoleKey{}).(string); role != RemoteRoleFull {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate resolves the caller's role from a bearer token, ?token= or cookie
+func (rs *RemoteServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		fromQuery := false
+		if token == "" {
+			token = r.URL.Query().Get("token")
+			fromQuery = token != ""
+		}
+		if token == "" {
+			if cookie, err := r.Cookie(remoteTokenCookie); err == nil {
+				token = cookie.Value
+			}
+		}
+
+		role := rs.roleForToken(token)
+		if role == "" {
+			rs.logger.InfoWithFields("Rejected unauthenticated remote request", map[string]interface{}{
+				"path":   r.URL.Path,
+				"remote": r.RemoteAddr,
+			})
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Persist query tokens so the browser can load assets and call the API
+		if fromQuery {
+			http.SetCookie(w, &http.Cookie{
+				Name:     remoteTokenCookie,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), remoteRoleKey{}, role)))
+	})
+}
+
+// remoteRoleKey is the request context key holding the caller's role
+type remoteRoleKey struct{}
+
+// roleForToken returns the role granted to a token, or "" if unknown
+func (rs *RemoteServer) roleForToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	for _, t := range rs.tokens {
+		if t.Token != "" && subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			if t.Role == RemoteRoleFull {
+				return RemoteRoleFull
+			}
+			return RemoteRoleReadOnly
+		}
+	}
+	return ""
+}
+
+// readOnlyMethods are the bound methods a read-only token may call. They
+// only read state, and none returns a secret: methods that would, such as
+// GetSyncConfig, GetAgentEnvironment and GetAutomationRules, need a full
+// token, and GetConfig and GetRepositories are redacted for every caller.
+var readOnlyMethods = map[string]bool{
+	"GetAgentDashboard":    true,
+	"GetAgentHours":        true,
+	"GetAgentLog":          true,
+	"GetAgentStatus":       true,
+	"GetAgentTranscript":   true,
+	"GetBlockedTasks":      true,
+	"GetBoardLayout":       true,
+	"GetConfig":            true,
+	"GetCriticalPath":      true,
+	"GetFeatureFlags":      true,
+	"GetHealth":            true,
+	"GetInterruptedTasks":  true,
+	"GetJobs":              true,
+	"GetLocaleSettings":    true,
+	"GetMilestoneProgress": true,
+	"GetMilestones":        true,
+	"GetQuotaStatus":       true,
+	"GetReleases":          true,
+	"GetRepositories":      true,
+	"GetReviewChecks":      true,
+	"GetReviewComments":    true,
+	"GetReviewOwners":      true,
+	"GetRunningAgents":     true,
+	"GetRuntimeEvents":     true,
+	"GetSLAStatus":         true,
+	"GetStaleTasks":        true,
+	"GetTaskDiff":          true,
+	"GetTaskDiffFile":      true,
+	"GetTaskDiffSummary":   true,
+	"GetTasksByStatus":     true,
+	"GetTerminalLayout":    true,
+	"GetTimezone":          true,
+	"GetWorktreeDiskUsage": true,
+	"LoadAllTasks":         true,
+	"LoadPlan":             true,
+	"LoadPlanChunk":        true,
+	"LoadTasks":            true,
+}
+
+// isReadOnlyMethod reports whether a read-only token may call a bound method
+func isReadOnlyMethod(method string) bool {
+	return readOnlyMethods[method]
+}
+
+// redactRemoteResult replaces the secrets in a remote call's result, which
+// leaves the machine whatever the caller's role
+func redactRemoteResult(result interface{}) interface{} {
+	switch value := result.(type) {
+	case *Config:
+		if value != nil {
+			redacted := redactConfig(*value)
+			return &redacted
+		}
+	case []Repository:
+		return redactConfig(Config{Repositories: value}).Repositories
+	}
+	return result
+}
+
+// handleCall invokes a bound App method with JSON-encoded positional arguments
+func (rs *RemoteServer) handleCall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeRemoteError(w, http.StatusMethodNotAllowed, "POST required")
+		return
+	}
+
+	methodName := strings.TrimPrefix(r.URL.Path, "/api/call/")
+	role, _ := r.Context().Value(remoteRoleKey{}).(string)
+	if role != RemoteRoleFull && !isReadOnlyMethod(methodName) {
+		writeRemoteError(w, http.StatusForbidden, "method not allowed for read-only role")
+		return
+	}
+
+	method := reflect.ValueOf(rs.app).MethodByName(methodName)
+	if !method.IsValid() {
+		writeRemoteError(w, http.StatusNotFound, "unknown method: "+methodName)
+		return
+	}
+
+	var rawArgs []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&rawArgs); err != nil && err != io.EOF {
+		writeRemoteError(w, http.StatusBadRequest, "invalid arguments: "+err.Error())
+		return
+	}
+
+	methodType := method.Type()
+	if len(rawArgs) != methodType.NumIn() {
+		writeRemoteError(w, http.StatusBadRequest, fmt.Sprintf("%s expects %d arguments, got %d", methodName, methodType.NumIn(), len(rawArgs)))
+		return
+	}
+
+	args := make([]reflect.Value, len(rawArgs))
+	for i, raw := range rawArgs {
+		arg := reflect.New(methodType.In(i))
+		if err := json.Unmarshal(raw, arg.Interface()); err != nil {
+			writeRemoteError(w, http.StatusBadRequest, fmt.Sprintf("invalid argument %d: %v", i+1, err))
+			return
+		}
+		args[i] = arg.Elem()
+	}
+
+	rs.logger.InfoWithFields("Remote call", map[string]interface{}{
+		"method": methodName,
+		"role":   role,
+	})
+
+	var result interface{}
+	err := rs.app.errorHandler.WithRecover(func() error {
+		var callErr error
+		result, callErr = splitResults(method.Call(args))
+		return callErr
+	})
+	if err != nil {
+		writeRemoteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": redactRemoteResult(result)})
+}
+
+// splitResults separates a method's value result from its trailing error
+func splitResults(results []reflect.Value) (interface{}, error) {
+	var value interface{}
+	var err error
+	for _, result := range results {
+		if result.Type().Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+			if !result.IsNil() {
+				err = result.Interface().(error)
+			}
+			continue
+		}
+		value = result.Interface()
+	}
+	return value, err
+}
+
+// writeRemoteError writes a JSON error body
+func writeRemoteError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// handleShim serves the IPC replacement script
+func (rs *RemoteServer) handleShim(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript")
+	io.WriteString(w, remoteShim)
+}
+
+// handleAssets serves the built frontend, injecting the shim into index.html
+func (rs *RemoteServer) handleAssets(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path != "" && path != "index.html" {
+		http.FileServer(http.FS(rs.assets)).ServeHTTP(w, r)
+		return
+	}
+
+	index, err := fs.ReadFile(rs.assets, "index.html")
+	if err != nil {
+		http.Error(w, "frontend assets not built", http.StatusNotFound)
+		return
+	}
+
+	shimTag := []byte(`<script src="/remote/shim.js"></script></head>`)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(bytes.Replace(index, []byte("</head>"), shimTag, 1))
+}
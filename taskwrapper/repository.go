@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -27,7 +27,7 @@ func ValidateRepository(path string) (*RepositoryInfo, error) {
 		Path:    path,
 		Name:    GetRepositoryName(path),
 		IsValid: true,
-		AddedAt: time.Now(),
+		AddedAt: time.Now().UTC(),
 	}
 	
 	// Check if path exists
@@ -78,12 +78,7 @@ func loadTasksFromPath(taskFile string) ([]Task, error) {
 		return nil, err
 	}
 	
-	var tasks []Task
-	if err := json.Unmarshal(data, &tasks); err != nil {
-		return nil, err
-	}
-	
-	return tasks, nil
+	return decodeTasks(data)
 }
 
 // GetRepositoryName attempts to determine a good display name for a repository
@@ -113,11 +108,22 @@ func GetRepositoryName(path string) string {
 
 // FindRepositoriesInDirectory searches for task dashboard repositories in a directory
 func FindRepositoriesInDirectory(searchPath string) ([]Repository, error) {
+	return FindRepositoriesInDirectoryContext(context.Background(), searchPath)
+}
+
+// FindRepositoriesInDirectoryContext searches like FindRepositoriesInDirectory,
+// reporting progress and stopping early if ctx is cancelled
+func FindRepositoriesInDirectoryContext(ctx context.Context, searchPath string) ([]Repository, error) {
 	var repositories []Repository
 	
 	// Walk the directory tree, but not too deep
 	maxDepth := 3
 	err := walkDirectoryWithDepth(searchPath, maxDepth, func(path string) error {
+		if err := jobCancelled(ctx); err != nil {
+			return err
+		}
+		reportProgress(ctx, -1, "Scanning "+path)
+		
 		// Check if this is a repository
 		taskFile := filepath.Join(path, "plan", "task.json")
 		if _, err := os.Stat(taskFile); err == nil {
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// restAPIPrefix is where the REST API is mounted
+	restAPIPrefix = "/api/v1"
+	// maxRestBodyBytes caps a REST request body
+	maxRestBodyBytes = 1 << 20
+)
+
+// restErrorStatus maps error types to the HTTP status REST clients see;
+// other errors are 500s
+var restErrorStatus = map[ErrorType]int{
+	ErrorTypeValidation:  http.StatusBadRequest,
+	ErrorTypePermission:  http.StatusForbidden,
+	ErrorTypeNotFound:    http.StatusNotFound,
+	ErrorTypeConflict:    http.StatusConflict,
+	ErrorTypeUnsupported: http.StatusNotImplemented,
+	ErrorTypeQuota:       http.StatusTooManyRequests,
+	ErrorTypeTimeout:     http.StatusGatewayTimeout,
+	ErrorTypeExternal:    http.StatusBadGateway,
+}
+
+// RestCreateTask is the body of POST /api/v1/tasks
+type RestCreateTask struct {
+	Title    string `json:"title"`
+	Priority string `json:"priority,omitempty"` // empty uses the repository's triage default
+}
+
+// RestMoveTask is the body of POST /api/v1/tasks/{id}/move
+type RestMoveTask struct {
+	Status string `json:"status"`
+	Force  bool   `json:"force,omitempty"` // start the task even if its dependencies aren't done
+}
+
+// restAPI serves the REST API for scripts and CI. Handlers call the same
+// App methods as the Wails bindings, so requests are validated and
+// journaled the same way. Read-only tokens may only GET.
+func (rs *RemoteServer) restAPI() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", rs.restListTasks)
+	mux.HandleFunc("POST /tasks", rs.restCreateTask)
+	mux.HandleFunc("GET /tasks/{id}", rs.restGetTask)
+	mux.HandleFunc("POST /tasks/{id}/move", rs.restMoveTask)
+	mux.HandleFunc("POST /tasks/{id}/approve", rs.restReviewTask(rs.app.ApproveTask))
+	mux.HandleFunc("POST /tasks/{id}/reject", rs.restReviewTask(rs.app.RejectTask))
+	mux.HandleFunc("GET /agents", rs.restListAgents)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(remoteRoleKey{}).(string)
+		if role != RemoteRoleFull && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeRemoteError(w, http.StatusForbidden, "method not allowed for read-only role")
+			return
+		}
+		rs.logger.InfoWithFields("REST API request", map[string]interface{}{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"role":   role,
+		})
+		r.Body = http.MaxBytesReader(w, r.Body, maxRestBodyBytes)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// APIHandler returns the REST API alone, with authentication applied, for
+// serving next to the desktop app
+func (rs *RemoteServer) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(restAPIPrefix+"/", http.StripPrefix(restAPIPrefix, rs.restAPI()))
+	return rs.authenticate(mux)
+}
+
+// ListenAndServeAPI serves the REST API alone and blocks until it stops
+func (rs *RemoteServer) ListenAndServeAPI(addr string) error {
+	if len(rs.tokens) == 0 {
+		return fmt.Errorf("no remote access tokens configured; add one under \"remote.tokens\" or set TASKWRAPPER_TOKEN")
+	}
+	rs.logger.InfoWithFields("Starting REST API server", map[string]interface{}{
+		"addr":   addr,
+		"tokens": len(rs.tokens),
+	})
+	return http.ListenAndServe(addr, rs.APIHandler())
+}
+
+// restListTasks lists the board, or one column of it with ?status=
+func (rs *RemoteServer) restListTasks(w http.ResponseWriter, r *http.Request) {
+	tasks := rs.app.taskService.GetTasks()
+	if filter := r.URL.Query().Get("status"); filter != "" {
+		status, err := ParseTaskStatus(filter)
+		if err != nil {
+			writeRestError(w, ValidationError("invalid task status", err).WithContext("status", filter))
+			return
+		}
+		column := []Task{}
+		for _, task := range tasks {
+			if task.Status == status {
+				column = append(column, task)
+			}
+		}
+		tasks = column
+	}
+	writeRestJSON(w, http.StatusOK, tasks)
+}
+
+// restCreateTask adds a backlog task
+func (rs *RemoteServer) restCreateTask(w http.ResponseWriter, r *http.Request) {
+	var body RestCreateTask
+	if !decodeRestBody(w, r, &body) {
+		return
+	}
+	task, err := rs.app.CreateTask(body.Title, body.Priority)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	writeRestJSON(w, http.StatusCreated, task)
+}
+
+// restGetTask returns one task
+func (rs *RemoteServer) restGetTask(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := restTaskID(w, r)
+	if !ok {
+		return
+	}
+	task, found := findTask(rs.app.taskService.GetTasks(), taskID)
+	if !found {
+		writeRestError(w, NotFoundError("task not found", nil).WithContext("task_id", taskID))
+		return
+	}
+	writeRestJSON(w, http.StatusOK, task)
+}
+
+// restMoveTask moves a task, launching its agent on todo → doing as the
+// board does, and returns it as moved
+func (rs *RemoteServer) restMoveTask(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := restTaskID(w, r)
+	if !ok {
+		return
+	}
+	var body RestMoveTask
+	if !decodeRestBody(w, r, &body) {
+		return
+	}
+	move := rs.app.MoveTask
+	if body.Force {
+		move = rs.app.ForceMoveTask
+	}
+	if err := move(taskID, body.Status); err != nil {
+		writeRestError(w, err)
+		return
+	}
+	task, _ := findTask(rs.app.taskService.GetTasks(), taskID)
+	writeRestJSON(w, http.StatusOK, task)
+}
+
+// restReviewTask approves or rejects a task awaiting review
+func (rs *RemoteServer) restReviewTask(review func(taskID int) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskID, ok := restTaskID(w, r)
+		if !ok {
+			return
+		}
+		if err := review(taskID); err != nil {
+			writeRestError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// restListAgents lists the agents running in the active repository
+func (rs *RemoteServer) restListAgents(w http.ResponseWriter, r *http.Request) {
+	agents, err := rs.app.GetRunningAgents()
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	writeRestJSON(w, http.StatusOK, agents)
+}
+
+// restTaskID reads the {id} path segment, answering 400 when it isn't a number
+func restTaskID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	taskID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeRestError(w, ValidationError("task ID must be a number", err).WithContext("id", r.PathValue("id")))
+		return 0, false
+	}
+	return taskID, true
+}
+
+// decodeRestBody decodes a JSON request body into v, answering 400 when it can't
+func decodeRestBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeRestError(w, ValidationError("invalid request body", err))
+		return false
+	}
+	return true
+}
+
+// writeRestJSON writes v as the JSON response body
+func writeRestJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeRestError writes an error with the status for its type, and its
+// type and context so scripts can act on it
+func writeRestError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	body := map[string]interface{}{"error": err.Error()}
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		if mapped, ok := restErrorStatus[appErr.Type]; ok {
+			status = mapped
+		}
+		body["type"] = appErr.Type
+		if len(appErr.Context) > 0 {
+			body["context"] = appErr.Context
+		}
+	}
+	writeRestJSON(w, status, body)
+}
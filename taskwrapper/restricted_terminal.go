@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// restrictedPrompt starts each line of a restricted terminal
+	restrictedPrompt = "restricted $ "
+	// restrictedCommandTimeout stops a restricted command that runs too long
+	restrictedCommandTimeout = 5 * time.Minute
+	// maxRestrictedLine caps a typed line
+	maxRestrictedLine = 4096
+)
+
+// shellSyntax is what a restricted line can't contain outside quotes; lines
+// run without a shell, so pipes, redirects and substitutions would only
+// mislead
+const shellSyntax = "|&;<>`$()"
+
+// RestrictedTerminalConfig turns terminals into consoles that only run
+// allowlisted commands, for users who should look around a repository but
+// not change it
+type RestrictedTerminalConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Commands are the allowed command prefixes, e.g. "git status" or "ls";
+	// a line runs when its first words are one of them. Arguments after
+	// the prefix are passed through, so list only commands that are safe
+	// with any arguments.
+	Commands []string `json:"commands,omitempty"`
+}
+
+// commandAllowlist matches command lines against allowed prefixes
+type commandAllowlist struct {
+	prefixes [][]string
+}
+
+// newCommandAllowlist parses the allowed command prefixes
+func newCommandAllowlist(commands []string) (*commandAllowlist, error) {
+	allowlist := &commandAllowlist{}
+	for _, command := range commands {
+		words, err := splitCommandLine(command)
+		if err != nil {
+			return nil, fmt.Errorf("allowed command %q: %v", command, err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("allowed commands must not be empty")
+		}
+		allowlist.prefixes = append(allowlist.prefixes, words)
+	}
+	return allowlist, nil
+}
+
+// allows reports whether argv starts with an allowed prefix
+func (al *commandAllowlist) allows(argv []string) bool {
+	for _, prefix := range al.prefixes {
+		if len(argv) < len(prefix) {
+			continue
+		}
+		matched := true
+		for i, word := range prefix {
+			if argv[i] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// String lists the allowed prefixes, one per line
+func (al *commandAllowlist) String() string {
+	lines := make([]string, 0, len(al.prefixes))
+	for _, prefix := range al.prefixes {
+		lines = append(lines, "  "+strings.Join(prefix, " "))
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// splitCommandLine splits a line into words the way a shell would for
+// plain commands: single and double quotes group, backslash escapes
+func splitCommandLine(line string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord, quote, escaped := false, rune(0), false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case strings.ContainsRune(shellSyntax, r):
+			return nil, fmt.Errorf("%q needs a shell, which a restricted terminal doesn't have", r)
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// restrictedConsole stands in for a shell: it edits a line from the
+// terminal's input and runs it directly, without a shell, if the allowlist
+// allows it
+type restrictedConsole struct {
+	dir       string
+	env       []string
+	allowlist *commandAllowlist
+	output    func(text string)
+	onCommand func(record CommandRecord)
+	// errorHandler recovers panics in the goroutine running a command
+	errorHandler *ErrorHandler
+
+	mu      sync.Mutex
+	line    []rune
+	escape  bool // inside an escape sequence, such as an arrow key
+	running context.CancelFunc
+	closed  bool
+}
+
+// Prompt prints the first prompt
+func (rc *restrictedConsole) Prompt() {
+	rc.output(restrictedPrompt)
+}
+
+// Input handles keystrokes: printable characters are echoed and collected,
+// Enter runs the line, Backspace, Ctrl-U and Ctrl-C edit it, and Ctrl-C
+// stops a running command. Other input is dropped; commands get no stdin.
+func (rc *restrictedConsole) Input(data string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var echo strings.Builder
+	for _, r := range data {
+		switch {
+		case rc.closed:
+			return
+		case rc.running != nil:
+			if r == 0x03 {
+				rc.running()
+			}
+		case rc.escape:
+			// CSI sequences end with a letter or ~, others right after ESC
+			rc.escape = !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '~')
+		case r == 0x1b:
+			rc.escape = true
+		case r == '\r' || r == '\n':
+			echo.WriteString("\r\n")
+			line := strings.TrimSpace(string(rc.line))
+			rc.line = rc.line[:0]
+			rc.output(echo.String())
+			echo.Reset()
+			rc.run(line)
+		case r == 0x7f || r == 0x08:
+			if len(rc.line) > 0 {
+				rc.line = rc.line[:len(rc.line)-1]
+				echo.WriteString("\b \b")
+			}
+		case r == 0x15:
+			echo.WriteString(strings.Repeat("\b \b", len(rc.line)))
+			rc.line = rc.line[:0]
+		case r == 0x03:
+			rc.line = rc.line[:0]
+			echo.WriteString("^C\r\n" + restrictedPrompt)
+		case r < 0x20:
+		default:
+			if len(rc.line) < maxRestrictedLine {
+				rc.line = append(rc.line, r)
+				echo.WriteRune(r)
+			}
+		}
+	}
+	if echo.Len() > 0 {
+		rc.output(echo.String())
+	}
+}
+
+// run starts line if it's allowed, or explains why not (must be called
+// with mu held)
+func (rc *restrictedConsole) run(line string) {
+	if line == "" {
+		rc.output(restrictedPrompt)
+		return
+	}
+	argv, err := splitCommandLine(line)
+	switch {
+	case err != nil:
+		rc.output(fmt.Sprintf("%v\r\n%s", err, restrictedPrompt))
+		return
+	case len(argv) == 1 && argv[0] == "help":
+		rc.output("This terminal is restricted. Allowed commands:\r\n" + rc.allowlist.String() + "\r\n" + restrictedPrompt)
+		return
+	case !rc.allowlist.allows(argv):
+		rc.output(fmt.Sprintf("%s: not allowed here (type help for the allowed commands)\r\n%s", argv[0], restrictedPrompt))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), restrictedCommandTimeout)
+	rc.running = cancel
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = rc.dir
+	cmd.Env = rc.env
+	writer := &crlfWriter{output: rc.output}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	rc.errorHandler.Go("restricted command", func() {
+		defer cancel()
+		exitCode := 0
+		if err := cmd.Run(); err != nil {
+			exitCode = -1
+			if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() >= 0 {
+				exitCode = exitErr.ExitCode()
+			} else {
+				writer.Write([]byte(err.Error() + "\n"))
+			}
+		}
+
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.running = nil
+		if rc.onCommand != nil {
+			rc.onCommand(CommandRecord{Command: line, Dir: rc.dir, ExitCode: exitCode})
+		}
+		if !rc.closed {
+			rc.output(restrictedPrompt)
+		}
+	})
+}
+
+// Close stops a running command; further input is ignored
+func (rc *restrictedConsole) Close() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.closed = true
+	if rc.running != nil {
+		rc.running()
+	}
+}
+
+// crlfWriter turns a command's newlines into the CRLFs a terminal needs
+type crlfWriter struct {
+	mu     sync.Mutex
+	output func(text string)
+}
+
+// Write sends p to the terminal
+func (cw *crlfWriter) Write(p []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	text := strings.ReplaceAll(strings.ReplaceAll(string(p), "\r\n", "\n"), "\n", "\r\n")
+	cw.output(text)
+	return len(p), nil
+}
+
+// restrictedEnv is the environment restricted commands run with
+func restrictedEnv() []string {
+	return []string{
+		"TERM=dumb",
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + os.Getenv("HOME"),
+		"USER=" + os.Getenv("USER"),
+		"LANG=en_US.UTF-8",
+		"GIT_PAGER=cat",
+		"PAGER=cat",
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "time"
+
+const (
+	// taskWatchInterval is how often task.json is checked for edits made
+	// outside the app, such as an agent marking its task ready for review,
+	// when the OS can't watch it
+	taskWatchInterval = 2 * time.Second
+	// reviewCheckTimeout bounds one post-agent check
+	reviewCheckTimeout = 10 * time.Minute
+	// reviewCheckOutputTail is how much of a check's output is kept
+	reviewCheckOutputTail = 4096
+)
+
+// ReviewCheck is the outcome of one post-agent check command, run in the
+// task's worktree
+type ReviewCheck struct {
+	Command         string  `json:"command"`
+	Passed          bool    `json:"passed"`
+	Output          string  `json:"output,omitempty"` // the end of the combined output
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// ReviewReadyEvent is sent when an agent hands a task over for review
+type ReviewReadyEvent struct {
+	TaskID    int              `json:"taskId"`
+	Title     string           `json:"title"`
+	Branch    string           `json:"branch"`
+	Checks    []ReviewCheck    `json:"checks"`
+	Skipped   string           `json:"skipped,omitempty"`   // why the checks didn't run
+	Ownership *ReviewOwnership `json:"ownership,omitempty"` // who else might review; nil without a task branch
+}
+
+// statusChange is a task whose status differs between two reads of task.json
+type statusChange struct {
+	Task Task
+	From TaskStatus
+}
+
+// statusChanges returns the tasks in after whose status isn't what it was in
+// before. New tasks are left out; they weren't moved.
+func statusChanges(before, after []Task) []statusChange {
+	changes := []statusChange{}
+	for _, task := range after {
+		if old, ok := findTask(before, task.ID); ok && old.Status != task.Status {
+			changes = append(changes, statusChange{Task: task, From: old.Status})
+		}
+	}
+	return changes
+}
+
+// handedForReview reports whether a change is an agent finishing its work
+func (c statusChange) handedForReview() bool {
+	return c.From == StatusDoing && c.Task.Status == StatusPendingReview
+}
+
+// outputTail keeps the last limit bytes of output
+func outputTail(output string, limit int) string {
+	if len(output) <= limit {
+		return output
+	}
+	return output[len(output)-limit:]
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Sides of a diff a review comment can point at
+const (
+	ReviewSideOld = "old" // a deleted or context line, numbered before the change
+	ReviewSideNew = "new" // an added or context line, numbered after the change
+)
+
+// maxReviewFeedbackBytes caps the feedback handed to an agent, which
+// travels in its environment
+const maxReviewFeedbackBytes = 32 * 1024
+
+// ReviewComment is a reviewer's note on a line of a task's diff
+type ReviewComment struct {
+	ID        string     `json:"id"`
+	TaskID    int        `json:"taskId"`
+	File      string     `json:"file"`
+	Line      int        `json:"line"` // 0 comments on the whole file
+	Side      string     `json:"side"`
+	Body      string     `json:"body"`
+	Author    string     `json:"author"`
+	Created   time.Time  `json:"created"`
+	Updated   *time.Time `json:"updated,omitempty"`
+	Submitted *time.Time `json:"submitted,omitempty"` // when it went to the agent with a change request
+}
+
+// ReviewCommentService stores review comments in plan/review_comments.json,
+// next to task.json, in the order they were made
+type ReviewCommentService struct {
+	path      string
+	fileUtils *FileUtils
+	mu        sync.Mutex
+	logger    Logger
+}
+
+// NewReviewCommentService creates a review comment service backed by path
+func NewReviewCommentService(path string, logger Logger) *ReviewCommentService {
+	return &ReviewCommentService{
+		path:      path,
+		fileUtils: NewFileUtils(logger),
+		logger:    logger,
+	}
+}
+
+// SetReviewCommentFile points the service at another repository's
+// review_comments.json
+func (rs *ReviewCommentService) SetReviewCommentFile(path string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.path = path
+}
+
+// GetReviewCommentFile returns the review_comments.json path
+func (rs *ReviewCommentService) GetReviewCommentFile() string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.path
+}
+
+// SetBackupDir sets where review_comments.json backups are written
+func (rs *ReviewCommentService) SetBackupDir(dir string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.fileUtils.SetBackupDir(dir)
+}
+
+// SetCipher turns at-rest encryption of review_comments.json on or off
+func (rs *ReviewCommentService) SetCipher(cipher *Cipher) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.fileUtils.SetCipher(cipher)
+}
+
+// List returns a task's comments, oldest first
+func (rs *ReviewCommentService) List(taskID int) ([]ReviewComment, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	comments, err := rs.load()
+	if err != nil {
+		return nil, err
+	}
+	forTask := []ReviewComment{}
+	for _, comment := range comments {
+		if comment.TaskID == taskID {
+			forTask = append(forTask, comment)
+		}
+	}
+	return forTask, nil
+}
+
+// Add stores a new comment
+func (rs *ReviewCommentService) Add(comment ReviewComment, now time.Time) (ReviewComment, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	comment, err := normalizeReviewComment(comment)
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	comments, err := rs.load()
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	comment.ID = generateID()
+	comment.Created = now
+	comment.Updated = nil
+	comment.Submitted = nil
+	if err := rs.save(append(comments, comment)); err != nil {
+		return ReviewComment{}, err
+	}
+	return comment, nil
+}
+
+// Update replaces a comment's body. Comments already sent to an agent are
+// kept as they were sent.
+func (rs *ReviewCommentService) Update(id, body string, now time.Time) (ReviewComment, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	comments, err := rs.load()
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	index := reviewCommentIndex(comments, id)
+	if index < 0 {
+		return ReviewComment{}, NotFoundError("review comment not found", nil).WithContext("comment", id)
+	}
+	if comments[index].Submitted != nil {
+		return ReviewComment{}, ConflictError("review comment was already sent to the agent", nil).WithContext("comment", id)
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return ReviewComment{}, ValidationError("review comment must not be empty", nil)
+	}
+	comments[index].Body = body
+	comments[index].Updated = &now
+	if err := rs.save(comments); err != nil {
+		return ReviewComment{}, err
+	}
+	return comments[index], nil
+}
+
+// Delete removes a comment
+func (rs *ReviewCommentService) Delete(id string) (ReviewComment, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	comments, err := rs.load()
+	if err != nil {
+		return ReviewComment{}, err
+	}
+	index := reviewCommentIndex(comments, id)
+	if index < 0 {
+		return ReviewComment{}, NotFoundError("review comment not found", nil).WithContext("comment", id)
+	}
+	deleted := comments[index]
+	return deleted, rs.save(append(comments[:index], comments[index+1:]...))
+}
+
+// MarkSubmitted stamps the comments with ids as sent to the agent
+func (rs *ReviewCommentService) MarkSubmitted(ids []string, now time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	comments, err := rs.load()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if index := reviewCommentIndex(comments, id); index >= 0 {
+			submitted := now
+			comments[index].Submitted = &submitted
+		}
+	}
+	return rs.save(comments)
+}
+
+// reviewCommentIndex returns the position of the comment with id, or -1
+func reviewCommentIndex(comments []ReviewComment, id string) int {
+	for i, c := range comments {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeReviewComment validates a new comment, defaulting the side to
+// the new one and the author to whoever runs TaskWrapper
+func normalizeReviewComment(comment ReviewComment) (ReviewComment, error) {
+	comment.File = strings.TrimSpace(comment.File)
+	if comment.File == "" {
+		return ReviewComment{}, ValidationError("review comment needs a file", nil)
+	}
+	if comment.Line < 0 {
+		return ReviewComment{}, ValidationError("line must not be negative", nil).WithContext("line", comment.Line)
+	}
+	if comment.Side == "" {
+		comment.Side = ReviewSideNew
+	}
+	if comment.Side != ReviewSideOld && comment.Side != ReviewSideNew {
+		return ReviewComment{}, ValidationError("side must be old or new", nil).WithContext("side", comment.Side)
+	}
+	comment.Body = strings.TrimSpace(comment.Body)
+	if comment.Body == "" {
+		return ReviewComment{}, ValidationError("review comment must not be empty", nil)
+	}
+	comment.Author = strings.TrimSpace(comment.Author)
+	if comment.Author == "" {
+		comment.Author = currentActor()
+	}
+	return comment, nil
+}
+
+// load reads review_comments.json; a missing file means no comments
+// (must be called with lock held)
+func (rs *ReviewCommentService) load() ([]ReviewComment, error) {
+	data, err := rs.fileUtils.ReadFile(rs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ReviewComment{}, nil
+		}
+		return nil, fmt.Errorf("failed to read review comments: %v", err)
+	}
+	comments := []ReviewComment{}
+	if err := json.Unmarshal(data, &comments); err != nil {
+		return nil, CorruptedError("review comments file is not valid JSON", err).WithContext("file", rs.path)
+	}
+	return comments, nil
+}
+
+// save writes review_comments.json (must be called with lock held)
+func (rs *ReviewCommentService) save(comments []ReviewComment) error {
+	if err := rs.fileUtils.AtomicWriteJSON(rs.path, comments); err != nil {
+		rs.logger.Error("Failed to save review comments", err)
+		return fmt.Errorf("failed to save review comments: %v", err)
+	}
+	return nil
+}
+
+// reviewFeedback compiles a change request and its comments into the
+// feedback an agent gets on its next run, trimmed to maxReviewFeedbackBytes
+func reviewFeedback(task Task, summary string, comments []ReviewComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changes were requested on task #%d: %s.\n", task.ID, task.Title)
+	if summary = strings.TrimSpace(summary); summary != "" {
+		fmt.Fprintf(&b, "\n%s\n", summary)
+	}
+	if len(comments) > 0 {
+		b.WriteString("\nReview comments:\n")
+	}
+	for _, comment := range comments {
+		location := comment.File
+		if comment.Line > 0 {
+			location = fmt.Sprintf("%s:%d", comment.File, comment.Line)
+			if comment.Side == ReviewSideOld {
+				location += " (before the change)"
+			}
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", location, strings.ReplaceAll(comment.Body, "\n", "\n  "))
+	}
+	feedback := b.String()
+	if len(feedback) > maxReviewFeedbackBytes {
+		const more = "\n[more comments in the dashboard]\n"
+		cut := maxReviewFeedbackBytes - len(more)
+		for cut > 0 && !utf8.RuneStart(feedback[cut]) {
+			cut--
+		}
+		feedback = feedback[:cut] + more
+	}
+	return feedback
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// helperScripts are the repository scripts the app executes
+var helperScripts = []string{"agent_spawn.sh", "agent_status.sh"}
+
+// HelperScriptStatus compares a helper script with its pinned checksum
+type HelperScriptStatus struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Pinned  string `json:"pinned,omitempty"` // SHA-256 recorded in the config; empty if not pinned
+	Actual  string `json:"actual,omitempty"` // SHA-256 of the script on disk
+	Matches bool   `json:"matches"`
+	Error   string `json:"error,omitempty"`
+}
+
+// helperScriptPath returns where a helper script lives in a repository
+func helperScriptPath(repoPath, name string) string {
+	return filepath.Join(repoPath, "plan", "helpers_and_tools", name)
+}
+
+// fileSHA256 returns the hex SHA-256 of a file's contents
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// pinHelperScripts returns the current checksum of every helper script in repoPath
+func pinHelperScripts(repoPath string) (map[string]string, error) {
+	pins := make(map[string]string, len(helperScripts))
+	for _, name := range helperScripts {
+		sum, err := fileSHA256(helperScriptPath(repoPath, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", name, err)
+		}
+		pins[name] = sum
+	}
+	return pins, nil
+}
+
+// helperScriptStatuses reports each helper script against pins
+func helperScriptStatuses(repoPath string, pins map[string]string) []HelperScriptStatus {
+	statuses := make([]HelperScriptStatus, 0, len(helperScripts))
+	for _, name := range helperScripts {
+		status := HelperScriptStatus{
+			Name:   name,
+			Path:   helperScriptPath(repoPath, name),
+			Pinned: pins[name],
+		}
+		sum, err := fileSHA256(status.Path)
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Actual = sum
+			status.Matches = status.Pinned == "" || status.Pinned == sum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// verifyHelperScript refuses to run a script that differs from its pinned
+// checksum. With no pins every script runs; once any are pinned, an unpinned
+// script is refused too.
+func verifyHelperScript(pins map[string]string, path string) error {
+	if len(pins) == 0 {
+		return nil
+	}
+	name := filepath.Base(path)
+	pinned, ok := pins[name]
+	if !ok {
+		return PermissionError("helper script is not pinned", nil).WithContext("script", path)
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", name, err)
+	}
+	if sum != pinned {
+		return PermissionError("helper script changed since it was pinned; review it and pin again", nil).
+			WithContext("script", path).
+			WithContext("expected", pinned).
+			WithContext("actual", sum)
+	}
+	return nil
+}
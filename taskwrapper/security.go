@@ -2,15 +2,19 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // SecurityConfig holds security-related configuration
 type SecurityConfig struct {
 	AllowedOrigins   []string
+	AllowLocalhost   bool // accept any localhost origin; dev builds only by default
 	AllowedPaths     []string
 	RestrictedPaths  []string
 	MaxPathDepth     int
@@ -21,13 +25,20 @@ type SecurityConfig struct {
 func DefaultSecurityConfig() *SecurityConfig {
 	homeDir, _ := os.UserHomeDir()
 	
+	origins := []string{
+		"wails://wails",          // Wails webview on macOS and Linux
+		"http://wails.localhost", // Wails webview on Windows
+	}
+	if devBuild {
+		origins = append(origins,
+			"http://localhost:5173",  // Vite dev server
+			"http://localhost:34115", // Wails dev server
+		)
+	}
+	
 	return &SecurityConfig{
-		AllowedOrigins: []string{
-			"http://localhost:5173",     // Vite dev server
-			"http://localhost:34115",    // Wails dev server
-			"wails://wails",            // Wails internal
-			"file://",                  // Local file protocol
-		},
+		AllowedOrigins: origins,
+		AllowLocalhost: devBuild,
 		AllowedPaths: []string{
 			homeDir,
 		},
@@ -163,22 +174,36 @@ func (pv *PathValidator) ValidateExecutable(execPath string) (string, error) {
 	return validPath, nil
 }
 
-// SanitizeFilename removes potentially dangerous characters from a filename
+// SanitizeFilename makes filename safe to use as a single path component on
+// any platform. Only characters that filesystems reject or that would change
+// the directory are replaced; spaces, quotes and the like are kept, since
+// file names never pass through a shell. Command arguments need sanitizeArg
+// instead.
 func (pv *PathValidator) SanitizeFilename(filename string) string {
-	// Remove path separators and other dangerous characters
-	dangerous := []string{"/", "\\", "..", "~", "$", "`", "|", "&", ";", "(", ")", "[", "]", "{", "}", "<", ">", "!", "?", "*", "'", "\"", "\n", "\r", "\t"}
-	
-	sanitized := filename
-	for _, char := range dangerous {
-		sanitized = strings.ReplaceAll(sanitized, char, "_")
-	}
-
-	// Limit length
-	if len(sanitized) > 255 {
-		sanitized = sanitized[:255]
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\':
+			return '_'
+		case strings.ContainsRune(`<>:"|?*`, r): // reserved on Windows
+			return '_'
+		case unicode.IsControl(r):
+			return '_'
+		}
+		return r
+	}, filename)
+
+	// Leading dots hide files or, as "..", climb directories; Windows drops
+	// trailing dots and spaces
+	sanitized = strings.TrimLeft(sanitized, ".")
+	sanitized = strings.TrimRight(sanitized, ". ")
+	sanitized = strings.TrimSpace(sanitized)
+
+	// Limit length without splitting a multi-byte character
+	for len(sanitized) > 255 {
+		_, size := utf8.DecodeLastRuneInString(sanitized)
+		sanitized = sanitized[:len(sanitized)-size]
 	}
 
-	// Ensure it's not empty
 	if sanitized == "" {
 		sanitized = "unnamed"
 	}
@@ -186,33 +211,172 @@ func (pv *PathValidator) SanitizeFilename(filename string) string {
 	return sanitized
 }
 
-// OriginValidator provides origin validation for WebSocket connections
+// sanitizeArg prepares free text (e.g. a task title) for passing as a single
+// argv element. Arguments never pass through a shell, so only control
+// characters are replaced: a newline would let a title forge extra lines in
+// the key=value files the helper scripts write.
+func sanitizeArg(arg string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, arg))
+}
+
+// shellQuote quotes s for POSIX shells. Use it only where a command line is
+// read by a shell; everywhere else pass arguments as argv.
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// shellJoin quotes and joins a command line, e.g. to log a command in a form
+// that can be pasted into a terminal
+func shellJoin(name string, args ...string) string {
+	quoted := []string{shellQuote(name)}
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Bounds for SecurityPolicy.MaxPathDepth
+const (
+	minPathDepth = 3
+	maxPathDepth = 64
+)
+
+// NewSecurityConfig returns the default configuration with the user's policy
+// applied. Origins add to the defaults; path settings replace them.
+func NewSecurityConfig(policy SecurityPolicy) *SecurityConfig {
+	config := DefaultSecurityConfig()
+	config.AllowedOrigins = append(config.AllowedOrigins, policy.AllowedOrigins...)
+	if len(policy.AllowedPaths) > 0 {
+		config.AllowedPaths = policy.AllowedPaths
+	}
+	if len(policy.RestrictedPaths) > 0 {
+		config.RestrictedPaths = policy.RestrictedPaths
+	}
+	if policy.MaxPathDepth > 0 {
+		config.MaxPathDepth = policy.MaxPathDepth
+	}
+	return config
+}
+
+// EffectiveSecurityPolicy fills the empty fields of policy with the defaults
+// it stands for, so an editor shows what is actually enforced
+func EffectiveSecurityPolicy(policy SecurityPolicy) SecurityPolicy {
+	config := NewSecurityConfig(SecurityPolicy{
+		AllowedPaths:    policy.AllowedPaths,
+		RestrictedPaths: policy.RestrictedPaths,
+		MaxPathDepth:    policy.MaxPathDepth,
+	})
+	return SecurityPolicy{
+		AllowedOrigins:  append([]string{}, policy.AllowedOrigins...),
+		AllowedPaths:    config.AllowedPaths,
+		RestrictedPaths: config.RestrictedPaths,
+		MaxPathDepth:    config.MaxPathDepth,
+	}
+}
+
+// ValidateSecurityPolicy checks a policy before it is saved, returning the
+// cleaned policy. Paths must be absolute and origins must be full URLs.
+func ValidateSecurityPolicy(policy SecurityPolicy) (SecurityPolicy, error) {
+	cleaned := SecurityPolicy{MaxPathDepth: policy.MaxPathDepth}
+	if policy.MaxPathDepth != 0 && (policy.MaxPathDepth < minPathDepth || policy.MaxPathDepth > maxPathDepth) {
+		return cleaned, ValidationError(fmt.Sprintf("max path depth must be between %d and %d", minPathDepth, maxPathDepth), nil).
+			WithContext("maxPathDepth", policy.MaxPathDepth)
+	}
+
+	cleanPaths := func(field string, paths []string) ([]string, error) {
+		result := []string{}
+		for _, path := range paths {
+			path = strings.TrimSpace(path)
+			if path == "" {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				return nil, ValidationError(field+" must be absolute paths", nil).WithContext("path", path)
+			}
+			result = append(result, filepath.Clean(path))
+		}
+		return result, nil
+	}
+	var err error
+	if cleaned.AllowedPaths, err = cleanPaths("allowed paths", policy.AllowedPaths); err != nil {
+		return cleaned, err
+	}
+	if cleaned.RestrictedPaths, err = cleanPaths("restricted paths", policy.RestrictedPaths); err != nil {
+		return cleaned, err
+	}
+
+	cleaned.AllowedOrigins = []string{}
+	for _, origin := range policy.AllowedOrigins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+			return cleaned, ValidationError("allowed origins must look like scheme://host[:port]", err).WithContext("origin", origin)
+		}
+		cleaned.AllowedOrigins = append(cleaned.AllowedOrigins, origin)
+	}
+	return cleaned, nil
+}
+
+// OriginValidator provides origin validation for WebSocket connections. Every
+// WebSocket endpoint checks handshakes through CheckRequest.
 type OriginValidator struct {
 	allowedOrigins map[string]bool
+	allowLocalhost bool
 	logger         Logger
 }
 
-// NewOriginValidator creates a new origin validator
-func NewOriginValidator(origins []string, logger Logger) *OriginValidator {
+// NewOriginValidator creates an origin validator from config; nil uses the defaults
+func NewOriginValidator(config *SecurityConfig, logger Logger) *OriginValidator {
+	if config == nil {
+		config = DefaultSecurityConfig()
+	}
 	allowed := make(map[string]bool)
-	for _, origin := range origins {
-		allowed[origin] = true
+	for _, origin := range config.AllowedOrigins {
+		allowed[strings.TrimRight(origin, "/")] = true
 	}
 	
 	return &OriginValidator{
 		allowedOrigins: allowed,
+		allowLocalhost: config.AllowLocalhost,
 		logger:         logger,
 	}
 }
 
-// ValidateOrigin checks if an origin is allowed
-func (ov *OriginValidator) ValidateOrigin(origin string) bool {
-	// If no origins specified, allow all (development mode)
-	if len(ov.allowedOrigins) == 0 {
-		ov.logger.Info("No origin restrictions configured (development mode)")
+// CheckRequest decides whether a WebSocket handshake may proceed. Requests
+// without an Origin header don't come from a browser page and are allowed;
+// any other must be in the allowlist. An Origin matching the Host header is
+// not trusted on its own, since DNS rebinding lets a hostile page have both.
+func (ov *OriginValidator) CheckRequest(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
 		return true
 	}
+	return ov.ValidateOrigin(origin)
+}
 
+// ValidateOrigin checks if an origin is in the allowlist
+func (ov *OriginValidator) ValidateOrigin(origin string) bool {
 	// Parse the origin URL
 	originURL, err := url.Parse(origin)
 	if err != nil {
@@ -233,13 +397,10 @@ func (ov *OriginValidator) ValidateOrigin(origin string) bool {
 		return true
 	}
 
-	// Check wildcard for local development
-	if originURL.Hostname() == "localhost" || originURL.Hostname() == "127.0.0.1" {
-		for allowed := range ov.allowedOrigins {
-			if strings.Contains(allowed, "localhost") || strings.Contains(allowed, "127.0.0.1") {
-				return true
-			}
-		}
+	// Any local dev server, in dev builds only: in production a page served
+	// from some other local port must not reach the terminal
+	if ov.allowLocalhost && (originURL.Hostname() == "localhost" || originURL.Hostname() == "127.0.0.1") {
+		return true
 	}
 
 	ov.logger.ErrorWithFields("Origin not allowed", nil, map[string]interface{}{
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// slaAtRiskFraction is how much of its target a task may use up before it
+// is reported at risk
+const slaAtRiskFraction = 0.75
+
+// SLA states of a task
+const (
+	SLANone     = "none"     // no target for its priority, or it hasn't reached todo
+	SLAOnTrack  = "on_track" // open, with time to spare
+	SLAAtRisk   = "at_risk"  // open, most of its target used up
+	SLABreached = "breached" // open, past its target
+	SLAMet      = "met"      // done within its target
+	SLAMissed   = "missed"   // done, but late
+)
+
+// TaskSLA is a task's turnaround against the target for its priority. The
+// clock starts when the task first enters todo and stops when it's done;
+// moving back to the backlog resets it.
+type TaskSLA struct {
+	TaskID           int          `json:"taskId"`
+	Title            string       `json:"title"`
+	Priority         TaskPriority `json:"priority"`
+	Target           string       `json:"target,omitempty"`
+	Started          *time.Time   `json:"started,omitempty"`
+	Due              *time.Time   `json:"due,omitempty"`
+	Completed        *time.Time   `json:"completed,omitempty"`
+	RemainingSeconds float64      `json:"remainingSeconds,omitempty"` // negative once overdue
+	State            string       `json:"state"`
+}
+
+// validateSLATargets checks targets name real priorities and positive durations
+func validateSLATargets(targets map[string]string) error {
+	for priority, target := range targets {
+		if !TaskPriority(priority).Valid() {
+			return ValidationError("unknown priority in SLA targets", nil).WithContext("priority", priority)
+		}
+		if _, err := parseRuleDuration(target); err != nil {
+			return ValidationError(fmt.Sprintf("SLA target for %s must be a duration such as 48h or 2d", priority), err).
+				WithContext("target", target)
+		}
+	}
+	return nil
+}
+
+// slaClock is when a task's SLA clock started and stopped
+type slaClock struct {
+	started, completed time.Time
+}
+
+// slaClocks replays task moves from the journal into each task's SLA clock
+func slaClocks(entries []JournalEntry) map[int]slaClock {
+	clocks := map[int]slaClock{}
+	for _, entry := range entries {
+		clock := clocks[entry.TaskID]
+		to := fmt.Sprint(entry.Data["to"])
+		switch {
+		case entry.Type == EventTaskMoved && to == string(StatusBacklog):
+			clock = slaClock{}
+		case entry.Type == EventTaskMoved && to == string(StatusTodo) && clock.started.IsZero():
+			clock.started = entry.Time
+		case entry.Type == EventTaskMoved && to == string(StatusDone), entry.Type == EventTaskApproved:
+			clock.completed = entry.Time
+		case entry.Type == EventTaskMoved || entry.Type == EventTaskRejected:
+			clock.completed = time.Time{} // reopened
+		}
+		clocks[entry.TaskID] = clock
+	}
+	return clocks
+}
+
+// taskSLAs computes every task's SLA state at now
+func taskSLAs(tasks []Task, clocks map[int]slaClock, targets map[string]string, now time.Time) []TaskSLA {
+	slas := make([]TaskSLA, 0, len(tasks))
+	for _, task := range tasks {
+		sla := TaskSLA{TaskID: task.ID, Title: task.Title, Priority: task.Priority, State: SLANone}
+		target, err := parseRuleDuration(targets[string(task.Priority)])
+		clock := clocks[task.ID]
+		if err != nil || clock.started.IsZero() {
+			slas = append(slas, sla)
+			continue
+		}
+
+		started, due := clock.started, clock.started.Add(target)
+		sla.Target = targets[string(task.Priority)]
+		sla.Started, sla.Due = &started, &due
+		end := now
+		if task.Status == StatusDone && !clock.completed.IsZero() {
+			completed := clock.completed
+			sla.Completed = &completed
+			end = completed
+		}
+		remaining := due.Sub(end)
+		sla.RemainingSeconds = remaining.Seconds()
+
+		switch {
+		case sla.Completed != nil && remaining >= 0:
+			sla.State = SLAMet
+		case sla.Completed != nil:
+			sla.State = SLAMissed
+		case remaining < 0:
+			sla.State = SLABreached
+		case end.Sub(started) >= time.Duration(float64(target)*slaAtRiskFraction):
+			sla.State = SLAAtRisk
+		default:
+			sla.State = SLAOnTrack
+		}
+		slas = append(slas, sla)
+	}
+	return slas
+}
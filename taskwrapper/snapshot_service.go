@@ -0,0 +1,308 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// snapshotMetaFile is the metadata entry stored in every snapshot archive
+const snapshotMetaFile = "snapshot.json"
+
+// SnapshotInfo describes one archived copy of a repository's plan directory
+type SnapshotInfo struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label"`
+	CreatedAt time.Time `json:"createdAt"`
+	Files     []string  `json:"files"`     // paths relative to plan/
+	Bytes     int64     `json:"bytes"`     // uncompressed size of the files
+	TaskCount int       `json:"taskCount"` // tasks in task.json at the time
+}
+
+// SnapshotService archives and restores plan/ as a whole
+type SnapshotService struct {
+	logger Logger
+}
+
+// NewSnapshotService creates a new snapshot service
+func NewSnapshotService(logger Logger) *SnapshotService {
+	return &SnapshotService{
+		logger: logger,
+	}
+}
+
+// snapshotDir returns where a repository's snapshots are kept
+func snapshotDir(repoPath string) string {
+	return filepath.Join(repoPath, ".taskwrapper", "snapshots")
+}
+
+// snapshotPath returns the archive for snapshot id
+func snapshotPath(repoPath, id string) string {
+	return filepath.Join(snapshotDir(repoPath), "snapshot-"+id+".zip")
+}
+
+// validateSnapshotID rejects IDs that weren't generated by Create
+func validateSnapshotID(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ValidationError("invalid snapshot id", err).WithContext("snapshot_id", id)
+	}
+	return nil
+}
+
+// skipSnapshotFile reports whether a plan/ file is transient and left out of snapshots
+func skipSnapshotFile(name string) bool {
+	return strings.Contains(name, ".backup.") ||
+		strings.Contains(name, ".tmp.") ||
+		strings.HasSuffix(name, ".journal")
+}
+
+// Create archives repoPath/plan into a new compressed snapshot
+func (ss *SnapshotService) Create(repoPath, label string) (SnapshotInfo, error) {
+	planDir := filepath.Join(repoPath, "plan")
+	info := SnapshotInfo{
+		ID:        generateID(),
+		Label:     strings.TrimSpace(label),
+		CreatedAt: time.Now().UTC(),
+		Files:     []string{},
+	}
+	if info.Label == "" {
+		info.Label = "Snapshot " + info.CreatedAt.Local().Format("2006-01-02 15:04")
+	}
+
+	if err := os.MkdirAll(snapshotDir(repoPath), 0755); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	dest := snapshotPath(repoPath, info.ID)
+	f, err := os.Create(dest)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("failed to create snapshot: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	err = filepath.Walk(planDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || skipSnapshotFile(fi.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(planDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if err := addZipFile(zw, "plan/"+rel, path, fi); err != nil {
+			return fmt.Errorf("failed to archive %s: %v", rel, err)
+		}
+		info.Files = append(info.Files, rel)
+		info.Bytes += fi.Size()
+		if rel == "task.json" {
+			info.TaskCount = countTasksInFile(path)
+		}
+		return nil
+	})
+	if err == nil {
+		err = writeZipJSON(zw, snapshotMetaFile, info)
+	}
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(dest)
+		return SnapshotInfo{}, fmt.Errorf("failed to create snapshot: %v", err)
+	}
+
+	ss.logger.InfoWithFields("Snapshot created", map[string]interface{}{
+		"id":    info.ID,
+		"label": info.Label,
+		"files": len(info.Files),
+	})
+	return info, nil
+}
+
+// List returns the repository's snapshots, newest first
+func (ss *SnapshotService) List(repoPath string) ([]SnapshotInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(snapshotDir(repoPath), "snapshot-*.zip"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []SnapshotInfo{}
+	for _, path := range paths {
+		info, err := readSnapshotInfo(path)
+		if err != nil {
+			ss.logger.ErrorWithFields("Skipping unreadable snapshot", err, map[string]interface{}{
+				"file": path,
+			})
+			continue
+		}
+		snapshots = append(snapshots, info)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// Restore writes the snapshot's files back into repoPath/plan. Files created
+// since the snapshot are left in place.
+func (ss *SnapshotService) Restore(repoPath, id string) (SnapshotInfo, error) {
+	if err := validateSnapshotID(id); err != nil {
+		return SnapshotInfo{}, err
+	}
+	path := snapshotPath(repoPath, id)
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SnapshotInfo{}, NotFoundError("snapshot not found", err).WithContext("snapshot_id", id)
+		}
+		return SnapshotInfo{}, fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer zr.Close()
+
+	info, err := readSnapshotMeta(&zr.Reader)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	planDir := filepath.Join(repoPath, "plan")
+	for _, zf := range zr.File {
+		if !strings.HasPrefix(zf.Name, "plan/") {
+			continue
+		}
+		rel := filepath.FromSlash(strings.TrimPrefix(zf.Name, "plan/"))
+		target := filepath.Join(planDir, rel)
+		// Refuse entries that would escape plan/
+		if !strings.HasPrefix(target, filepath.Clean(planDir)+string(os.PathSeparator)) {
+			return SnapshotInfo{}, ValidationError("snapshot contains an invalid path", nil).WithContext("path", zf.Name)
+		}
+		if err := extractZipFile(zf, target); err != nil {
+			return SnapshotInfo{}, fmt.Errorf("failed to restore %s: %v", rel, err)
+		}
+	}
+
+	ss.logger.InfoWithFields("Snapshot restored", map[string]interface{}{
+		"id":    info.ID,
+		"label": info.Label,
+	})
+	return info, nil
+}
+
+// Delete removes a snapshot
+func (ss *SnapshotService) Delete(repoPath, id string) error {
+	if err := validateSnapshotID(id); err != nil {
+		return err
+	}
+	if err := os.Remove(snapshotPath(repoPath, id)); err != nil {
+		if os.IsNotExist(err) {
+			return NotFoundError("snapshot not found", err).WithContext("snapshot_id", id)
+		}
+		return fmt.Errorf("failed to delete snapshot: %v", err)
+	}
+	return nil
+}
+
+// readSnapshotInfo reads the metadata of the snapshot archive at path
+func readSnapshotInfo(path string) (SnapshotInfo, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	defer zr.Close()
+	return readSnapshotMeta(&zr.Reader)
+}
+
+// readSnapshotMeta decodes the metadata entry of an open snapshot archive
+func readSnapshotMeta(zr *zip.Reader) (SnapshotInfo, error) {
+	for _, zf := range zr.File {
+		if zf.Name != snapshotMetaFile {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return SnapshotInfo{}, err
+		}
+		defer rc.Close()
+		var info SnapshotInfo
+		if err := json.NewDecoder(rc).Decode(&info); err != nil {
+			return SnapshotInfo{}, fmt.Errorf("invalid snapshot metadata: %v", err)
+		}
+		return info, nil
+	}
+	return SnapshotInfo{}, fmt.Errorf("snapshot metadata missing")
+}
+
+// addZipFile adds the file at path to the archive, keeping its mode
+func addZipFile(zw *zip.Writer, name, path string, fi os.FileInfo) error {
+	header, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// extractZipFile writes an archive entry to target with its stored mode
+func extractZipFile(zf *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	mode := zf.Mode().Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	tmp := target + ".restore"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// countTasksInFile returns the number of tasks in a task.json, or 0 if unreadable
+func countTasksInFile(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return 0
+	}
+	return len(tasks)
+}
@@ -0,0 +1,112 @@
+package main
+
+import "sort"
+
+// defaultTaskPoints is what an unestimated task costs when planning a sprint
+const defaultTaskPoints = 1
+
+// Why PlanSprint left a backlog task out
+const (
+	DeferredBlocked  = "blocked"  // a dependency is still in the backlog and wasn't pulled
+	DeferredCapacity = "capacity" // it would not fit in the points left
+)
+
+// SprintTask is a backlog task proposed for todo
+type SprintTask struct {
+	Task
+	Points int `json:"points"`
+}
+
+// SprintDeferral is a backlog task PlanSprint did not propose
+type SprintDeferral struct {
+	TaskID int    `json:"taskId"`
+	Points int    `json:"points"`
+	Reason string `json:"reason"`
+}
+
+// SprintPlan is a proposal of backlog tasks to pull into todo, in the order
+// they were picked. Nothing moves until it is accepted.
+type SprintPlan struct {
+	Capacity int              `json:"capacity"`
+	Points   int              `json:"points"`
+	Tasks    []SprintTask     `json:"tasks"`
+	Deferred []SprintDeferral `json:"deferred"`
+}
+
+// taskPoints returns what task costs against sprint capacity
+func taskPoints(task Task) int {
+	if task.Estimate > 0 {
+		return task.Estimate
+	}
+	return defaultTaskPoints
+}
+
+// planSprint picks backlog tasks that fit in capacity, highest priority
+// first. A task is only picked once every dependency is out of the backlog
+// or picked ahead of it, so the proposal never leaves todo blocked.
+func planSprint(tasks []Task, capacity int) SprintPlan {
+	plan := SprintPlan{Capacity: capacity, Tasks: []SprintTask{}, Deferred: []SprintDeferral{}}
+
+	rank := map[TaskPriority]int{}
+	for i, priority := range AllPriorities() {
+		rank[priority] = i
+	}
+	backlog := []Task{}
+	inBacklog := map[int]bool{}
+	for _, task := range tasks {
+		if task.Status == StatusBacklog {
+			backlog = append(backlog, task)
+			inBacklog[task.ID] = true
+		}
+	}
+	sort.SliceStable(backlog, func(i, j int) bool {
+		if rank[backlog[i].Priority] != rank[backlog[j].Priority] {
+			return rank[backlog[i].Priority] < rank[backlog[j].Priority]
+		}
+		return backlog[i].ID < backlog[j].ID
+	})
+
+	// Picking a task can unblock a higher-priority one, so repeat until a
+	// pass picks nothing
+	picked := map[int]bool{}
+	for progress := true; progress; {
+		progress = false
+		for _, task := range backlog {
+			if picked[task.ID] || !sprintReady(task, inBacklog, picked) {
+				continue
+			}
+			points := taskPoints(task)
+			if plan.Points+points > capacity {
+				continue
+			}
+			picked[task.ID] = true
+			plan.Points += points
+			plan.Tasks = append(plan.Tasks, SprintTask{Task: task, Points: points})
+			progress = true
+			break
+		}
+	}
+
+	for _, task := range backlog {
+		if picked[task.ID] {
+			continue
+		}
+		reason := DeferredCapacity
+		if !sprintReady(task, inBacklog, picked) {
+			reason = DeferredBlocked
+		}
+		plan.Deferred = append(plan.Deferred, SprintDeferral{TaskID: task.ID, Points: taskPoints(task), Reason: reason})
+	}
+	return plan
+}
+
+// sprintReady reports whether none of task's dependencies would be left
+// behind in the backlog
+func sprintReady(task Task, inBacklog, picked map[int]bool) bool {
+	for _, dep := range task.Deps {
+		if inBacklog[dep] && !picked[dep] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// defaultStaleThresholds is how many days a task may sit in a status before
+// it needs attention. Statuses without a threshold never go stale.
+var defaultStaleThresholds = map[string]int{
+	string(StatusDoing):         3,
+	string(StatusPendingReview): 2,
+}
+
+// StaleTask is a task that has sat in its status past the threshold
+type StaleTask struct {
+	Task
+	Since         time.Time `json:"since"` // when it entered its status
+	AgeDays       float64   `json:"ageDays"`
+	ThresholdDays int       `json:"thresholdDays"`
+}
+
+// StaleReport lists the tasks needing attention, oldest first
+type StaleReport struct {
+	Tasks      []StaleTask    `json:"tasks"`
+	Thresholds map[string]int `json:"thresholds"`
+	// Untracked are watched tasks the journal has no arrival for (they got
+	// there before journaling started), so their age is unknown
+	Untracked []int `json:"untracked"`
+}
+
+// effectiveStaleThresholds lays thresholds over the defaults
+func effectiveStaleThresholds(thresholds map[string]int) map[string]int {
+	effective := make(map[string]int, len(defaultStaleThresholds)+len(thresholds))
+	for status, days := range defaultStaleThresholds {
+		effective[status] = days
+	}
+	for status, days := range thresholds {
+		effective[status] = days
+	}
+	return effective
+}
+
+// validateStaleThresholds checks thresholds name real statuses and whole days;
+// 0 turns a status's threshold off
+func validateStaleThresholds(thresholds map[string]int) error {
+	for status, days := range thresholds {
+		if !TaskStatus(status).Valid() {
+			return ValidationError("unknown status in stale thresholds", nil).WithContext("status", status)
+		}
+		if days < 0 {
+			return ValidationError(fmt.Sprintf("stale threshold for %s must not be negative", status), nil).
+				WithContext("days", days)
+		}
+	}
+	return nil
+}
+
+// taskArrivals returns each task's newest created or moved entry, which
+// says when it arrived in the status it's in now
+func taskArrivals(entries []JournalEntry) map[int]JournalEntry {
+	arrivals := map[int]JournalEntry{}
+	for _, entry := range entries {
+		if entry.Type == EventTaskCreated || entry.Type == EventTaskMoved {
+			arrivals[entry.TaskID] = entry
+		}
+	}
+	return arrivals
+}
+
+// staleTasks reports the tasks that have been in a status longer than its
+// threshold in days
+func staleTasks(tasks []Task, arrivals map[int]JournalEntry, thresholds map[string]int, now time.Time) StaleReport {
+	report := StaleReport{Tasks: []StaleTask{}, Thresholds: thresholds, Untracked: []int{}}
+	for _, task := range tasks {
+		days := thresholds[string(task.Status)]
+		if days <= 0 {
+			continue
+		}
+		arrival, ok := arrivals[task.ID]
+		if !ok || !arrivedIn(arrival, task.Status) {
+			report.Untracked = append(report.Untracked, task.ID)
+			continue
+		}
+		age := now.Sub(arrival.Time)
+		if age < time.Duration(days)*24*time.Hour {
+			continue
+		}
+		report.Tasks = append(report.Tasks, StaleTask{
+			Task:          task,
+			Since:         arrival.Time,
+			AgeDays:       age.Hours() / 24,
+			ThresholdDays: days,
+		})
+	}
+	sort.SliceStable(report.Tasks, func(i, j int) bool {
+		return report.Tasks[i].Since.Before(report.Tasks[j].Since)
+	})
+	return report
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sync providers
+const (
+	SyncProviderS3     = "s3"
+	SyncProviderWebDAV = "webdav"
+)
+
+// errRemoteNotFound is returned by a SyncRemote for a missing object
+var errRemoteNotFound = errors.New("remote object not found")
+
+// SyncRemote stores plan files by name under a configured prefix
+type SyncRemote interface {
+	Get(ctx context.Context, name string) ([]byte, error)
+	Put(ctx context.Context, name string, data []byte) error
+}
+
+// newSyncRemote creates the remote for cfg, authenticating with secret
+func newSyncRemote(cfg SyncConfig, secret string) (SyncRemote, error) {
+	if cfg.Endpoint == "" {
+		return nil, ValidationError("sync endpoint is required", nil)
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, ValidationError("sync endpoint must be an absolute URL", err).WithContext("endpoint", cfg.Endpoint)
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	switch cfg.Provider {
+	case SyncProviderS3:
+		if cfg.Bucket == "" {
+			return nil, ValidationError("S3 sync needs a bucket", nil)
+		}
+		region := cfg.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		return &s3Remote{
+			endpoint:  endpoint,
+			bucket:    cfg.Bucket,
+			prefix:    strings.Trim(cfg.Prefix, "/"),
+			region:    region,
+			accessKey: cfg.Username,
+			secretKey: secret,
+			client:    client,
+		}, nil
+	case SyncProviderWebDAV:
+		return &webdavRemote{
+			endpoint: endpoint,
+			prefix:   strings.Trim(cfg.Prefix, "/"),
+			username: cfg.Username,
+			password: secret,
+			client:   client,
+		}, nil
+	default:
+		return nil, ValidationError("unknown sync provider", nil).WithContext("provider", cfg.Provider)
+	}
+}
+
+// joinRemotePath joins a prefix and an object name with slashes
+func joinRemotePath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// checkRemoteResponse turns a non-2xx response into an error
+func checkRemoteResponse(resp *http.Response, op, name string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errRemoteNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s %s failed: %s: %s", op, name, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// webdavRemote stores files on a WebDAV server (Nextcloud, ownCloud, ...)
+type webdavRemote struct {
+	endpoint *url.URL
+	prefix   string
+	username string
+	password string
+	client   *http.Client
+}
+
+// url returns the address of a path under the endpoint
+func (w *webdavRemote) url(path string) string {
+	u := *w.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + path
+	return u.String()
+}
+
+// do sends an authenticated request
+func (w *webdavRemote) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, w.url(path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	return w.client.Do(req)
+}
+
+// Get downloads a file
+func (w *webdavRemote) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := w.do(ctx, http.MethodGet, joinRemotePath(w.prefix, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkRemoteResponse(resp, "GET", name); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put uploads a file, creating its parent collections first
+func (w *webdavRemote) Put(ctx context.Context, name string, data []byte) error {
+	path := joinRemotePath(w.prefix, name)
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		resp, err := w.do(ctx, "MKCOL", strings.Join(parts[:i], "/")+"/", nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		// 405 means the collection already exists
+		if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("MKCOL %s failed: %s", strings.Join(parts[:i], "/"), resp.Status)
+		}
+	}
+
+	resp, err := w.do(ctx, http.MethodPut, path, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkRemoteResponse(resp, "PUT", name)
+}
+
+// s3Remote stores files in an S3-compatible bucket (AWS, MinIO, R2, ...)
+// using path-style addressing and Signature Version 4
+type s3Remote struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// Get downloads an object
+func (s *s3Remote) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, joinRemotePath(s.prefix, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkRemoteResponse(resp, "GET", name); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put uploads an object
+func (s *s3Remote) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, joinRemotePath(s.prefix, name), data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkRemoteResponse(resp, "PUT", name)
+}
+
+// do sends a signed request for key
+func (s *s3Remote) do(ctx context.Context, method, key string, body []byte) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (s *s3Remote) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
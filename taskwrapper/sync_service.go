@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// syncManifestName is the remote object listing the synced files and their hashes
+const syncManifestName = "manifest.json"
+
+// SyncConfig points a repository's plan directory at a remote. The secret
+// (S3 secret key or WebDAV password) is kept in the OS keychain.
+type SyncConfig struct {
+	Provider string `json:"provider"`           // s3 or webdav
+	Endpoint string `json:"endpoint"`           // e.g. https://s3.amazonaws.com or https://cloud.example.com/remote.php/dav/files/me
+	Bucket   string `json:"bucket,omitempty"`   // S3 only
+	Region   string `json:"region,omitempty"`   // S3 only; defaults to us-east-1
+	Prefix   string `json:"prefix,omitempty"`   // folder for this repository on the remote
+	Username string `json:"username,omitempty"` // S3 access key ID or WebDAV user
+}
+
+// SyncManifest is the remote's record of the last push. Its revision hashes
+// the file list, so any change on either machine shows up as a new revision.
+type SyncManifest struct {
+	Revision  string            `json:"revision"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+	Machine   string            `json:"machine"`
+	Files     map[string]string `json:"files"` // path relative to plan/ -> SHA-256
+}
+
+// syncState is this machine's view of the remote as of the last push or pull
+type syncState struct {
+	Revision string            `json:"revision"`
+	Files    map[string]string `json:"files"`
+}
+
+// SyncConflict is a file changed both locally and on the remote since the last sync
+type SyncConflict struct {
+	Path       string `json:"path"`
+	LocalHash  string `json:"localHash"`
+	RemoteHash string `json:"remoteHash"`
+}
+
+// SyncResult reports what a push or pull did
+type SyncResult struct {
+	Revision  string         `json:"revision"`
+	Pushed    []string       `json:"pushed"`
+	Pulled    []string       `json:"pulled"`
+	Conflicts []SyncConflict `json:"conflicts"`
+}
+
+// SyncService pushes and pulls a repository's plan directory to a remote
+type SyncService struct {
+	logger Logger
+}
+
+// NewSyncService creates a new sync service
+func NewSyncService(logger Logger) *SyncService {
+	return &SyncService{
+		logger: logger,
+	}
+}
+
+// syncStatePath returns where a repository's sync state is kept
+func syncStatePath(repoPath string) string {
+	return filepath.Join(repoPath, ".taskwrapper", "sync.json")
+}
+
+// loadSyncState reads the sync state; a repository never synced has none
+func loadSyncState(repoPath string) syncState {
+	state := syncState{Files: map[string]string{}}
+	data, err := os.ReadFile(syncStatePath(repoPath))
+	if err == nil {
+		json.Unmarshal(data, &state)
+	}
+	if state.Files == nil {
+		state.Files = map[string]string{}
+	}
+	return state
+}
+
+// saveSyncState records the sync state
+func saveSyncState(repoPath string, state syncState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(syncStatePath(repoPath)), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(syncStatePath(repoPath), data, 0644)
+}
+
+// syncRevision hashes a file list into a revision ID
+func syncRevision(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s %s\n", files[path], path)
+	}
+	return sha256Hex([]byte(b.String()))
+}
+
+// localPlanFiles hashes the files in repoPath/plan that are synced. Files are
+// hashed as stored, so encrypted repositories only ever upload ciphertext.
+func localPlanFiles(repoPath string) (map[string]string, error) {
+	planDir := filepath.Join(repoPath, "plan")
+	files := map[string]string{}
+	err := filepath.Walk(planDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == planDir {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() || skipSnapshotFile(fi.Name()) || strings.HasSuffix(fi.Name(), ".sha256") {
+			return nil
+		}
+		rel, err := filepath.Rel(planDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = sha256Hex(data)
+		return nil
+	})
+	return files, err
+}
+
+// fetchManifest downloads the remote manifest, or nil if nothing was pushed yet
+func fetchManifest(ctx context.Context, remote SyncRemote) (*SyncManifest, error) {
+	data, err := remote.Get(ctx, syncManifestName)
+	if err == errRemoteNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sync manifest: %w", err)
+	}
+	var manifest SyncManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, CorruptedError("remote sync manifest is unreadable", err)
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]string{}
+	}
+	return &manifest, nil
+}
+
+// syncConflicts lists files changed both locally and remotely since state
+func syncConflicts(state syncState, local, remote map[string]string) []SyncConflict {
+	conflicts := []SyncConflict{}
+	for path, remoteHash := range remote {
+		base := state.Files[path]
+		localHash, ok := local[path]
+		if !ok || localHash == remoteHash || localHash == base || remoteHash == base {
+			continue
+		}
+		conflicts = append(conflicts, SyncConflict{Path: path, LocalHash: localHash, RemoteHash: remoteHash})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Path < conflicts[j].Path
+	})
+	return conflicts
+}
+
+// Push uploads changed plan files and a new manifest. It refuses when the
+// remote moved on since this machine last synced, unless force is set, so one
+// machine never silently overwrites the other's edits.
+func (ss *SyncService) Push(ctx context.Context, repoPath string, remote SyncRemote, force bool) (SyncResult, error) {
+	result := SyncResult{Pushed: []string{}, Pulled: []string{}, Conflicts: []SyncConflict{}}
+	state := loadSyncState(repoPath)
+	local, err := localPlanFiles(repoPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read plan directory: %v", err)
+	}
+	manifest, err := fetchManifest(ctx, remote)
+	if err != nil {
+		return result, err
+	}
+
+	remoteFiles := map[string]string{}
+	if manifest != nil {
+		remoteFiles = manifest.Files
+		if manifest.Revision != state.Revision && !force {
+			result.Conflicts = syncConflicts(state, local, remoteFiles)
+			return result, ConflictError("remote changed since the last sync; pull first", nil).
+				WithContext("revision", manifest.Revision).
+				WithContext("machine", manifest.Machine).
+				WithContext("conflicts", result.Conflicts)
+		}
+	}
+
+	planDir := filepath.Join(repoPath, "plan")
+	for path, hash := range local {
+		if remoteFiles[path] == hash {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(planDir, filepath.FromSlash(path)))
+		if err != nil {
+			return result, err
+		}
+		if err := remote.Put(ctx, "plan/"+path, data); err != nil {
+			return result, fmt.Errorf("failed to upload %s: %w", path, err)
+		}
+		result.Pushed = append(result.Pushed, path)
+	}
+	sort.Strings(result.Pushed)
+
+	// The manifest goes last: until it lands, other machines still see the
+	// previous revision, and a pull racing the upload fails its hash check
+	// rather than mixing revisions
+	machine, _ := os.Hostname()
+	next := SyncManifest{Revision: syncRevision(local), UpdatedAt: time.Now().UTC(), Machine: machine, Files: local}
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	if err := remote.Put(ctx, syncManifestName, data); err != nil {
+		return result, fmt.Errorf("failed to upload sync manifest: %w", err)
+	}
+	if err := saveSyncState(repoPath, syncState{Revision: next.Revision, Files: local}); err != nil {
+		ss.logger.Error("Failed to save sync state", err)
+	}
+
+	result.Revision = next.Revision
+	ss.logger.InfoWithFields("Plan directory pushed", map[string]interface{}{
+		"revision": next.Revision,
+		"files":    len(result.Pushed),
+	})
+	return result, nil
+}
+
+// Pull downloads plan files changed on the remote since the last sync. Files
+// also changed locally are reported as conflicts and left alone, unless force
+// is set, in which case the remote copy wins.
+func (ss *SyncService) Pull(ctx context.Context, repoPath string, remote SyncRemote, force bool) (SyncResult, error) {
+	result := SyncResult{Pushed: []string{}, Pulled: []string{}, Conflicts: []SyncConflict{}}
+	state := loadSyncState(repoPath)
+	local, err := localPlanFiles(repoPath)
+	if err != nil {
+		return result, fmt.Errorf("failed to read plan directory: %v", err)
+	}
+	manifest, err := fetchManifest(ctx, remote)
+	if err != nil {
+		return result, err
+	}
+	if manifest == nil {
+		return result, NotFoundError("nothing has been pushed to the remote yet", nil)
+	}
+
+	if !force {
+		result.Conflicts = syncConflicts(state, local, manifest.Files)
+	}
+	conflicted := map[string]bool{}
+	for _, conflict := range result.Conflicts {
+		conflicted[conflict.Path] = true
+	}
+
+	planDir := filepath.Join(repoPath, "plan")
+	next := syncState{Revision: manifest.Revision, Files: map[string]string{}}
+	for path, remoteHash := range manifest.Files {
+		if conflicted[path] {
+			// Keep the old base so the conflict is still reported next time
+			next.Files[path] = state.Files[path]
+			continue
+		}
+		next.Files[path] = remoteHash
+		localHash, exists := local[path]
+		if localHash == remoteHash {
+			continue
+		}
+		// A local edit to a file the remote didn't touch is kept for the next push
+		if exists && remoteHash == state.Files[path] && !force {
+			continue
+		}
+
+		data, err := remote.Get(ctx, "plan/"+path)
+		if err != nil {
+			return result, fmt.Errorf("failed to download %s: %w", path, err)
+		}
+		if sha256Hex(data) != remoteHash {
+			return result, CorruptedError("downloaded file does not match the manifest", nil).WithContext("path", path)
+		}
+		target := filepath.Join(planDir, filepath.FromSlash(path))
+		if !strings.HasPrefix(target, filepath.Clean(planDir)+string(os.PathSeparator)) {
+			return result, ValidationError("sync manifest contains an invalid path", nil).WithContext("path", path)
+		}
+		if err := writeSyncedFile(target, data); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		result.Pulled = append(result.Pulled, path)
+	}
+	sort.Strings(result.Pulled)
+
+	if len(result.Conflicts) > 0 {
+		// Not fully in sync: a later push must still be refused until resolved
+		next.Revision = state.Revision
+	}
+	if err := saveSyncState(repoPath, next); err != nil {
+		ss.logger.Error("Failed to save sync state", err)
+	}
+
+	result.Revision = manifest.Revision
+	ss.logger.InfoWithFields("Plan directory pulled", map[string]interface{}{
+		"revision":  manifest.Revision,
+		"files":     len(result.Pulled),
+		"conflicts": len(result.Conflicts),
+	})
+	return result, nil
+}
+
+// writeSyncedFile replaces target with data atomically
+func writeSyncedFile(target string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.tmp.%d", target, time.Now().UnixNano())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskBackup is one timestamped copy of task.json written before a save
+type TaskBackup struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	Bytes     int64     `json:"bytes"`
+	TaskCount int       `json:"taskCount"`
+}
+
+// TaskFieldChange is a field whose value differs between the current tasks and a backup
+type TaskFieldChange struct {
+	TaskID  int         `json:"taskId"`
+	Field   string      `json:"field"`
+	Current interface{} `json:"current"`
+	Backup  interface{} `json:"backup"`
+}
+
+// TaskBackupPreview shows what restoring a backup would change
+type TaskBackupPreview struct {
+	Backup  TaskBackup        `json:"backup"`
+	Tasks   []Task            `json:"tasks"`   // the backup's tasks
+	Added   []Task            `json:"added"`   // in the backup but not current
+	Removed []Task            `json:"removed"` // current tasks the backup lacks
+	Changed []TaskFieldChange `json:"changed"`
+}
+
+// backupPrefix returns the file name prefix of the task file's backups
+func (ts *TaskService) backupPrefix() string {
+	return filepath.Base(ts.taskFile) + ".backup."
+}
+
+// backupPath resolves a backup name from ListBackups to its path, rejecting
+// anything that isn't a backup of the current task file
+func (ts *TaskService) backupPath(name string) (string, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, ts.backupPrefix()) {
+		return "", ValidationError("invalid backup name", nil).WithContext("backup", name)
+	}
+	return filepath.Join(ts.fileUtils.BackupDir(ts.taskFile), name), nil
+}
+
+// ListBackups returns the task file's backups, newest first
+func (ts *TaskService) ListBackups() ([]TaskBackup, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.listBackups()
+}
+
+// listBackups lists backups newest first. Caller holds ts.mu.
+func (ts *TaskService) listBackups() ([]TaskBackup, error) {
+	paths, err := filepath.Glob(filepath.Join(ts.fileUtils.BackupDir(ts.taskFile), ts.backupPrefix()+"*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	backups := []TaskBackup{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, TaskBackup{
+			Name:      filepath.Base(path),
+			CreatedAt: info.ModTime(),
+			Bytes:     info.Size(),
+			TaskCount: ts.countBackupTasks(path),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// countBackupTasks returns the number of tasks in a backup, or 0 if unreadable
+func (ts *TaskService) countBackupTasks(path string) int {
+	data, err := ts.fileUtils.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return 0
+	}
+	return len(tasks)
+}
+
+// readBackup loads the tasks stored in a backup. Caller holds ts.mu.
+func (ts *TaskService) readBackup(name string) (TaskBackup, []Task, error) {
+	path, err := ts.backupPath(name)
+	if err != nil {
+		return TaskBackup{}, nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return TaskBackup{}, nil, NotFoundError("backup not found", err).WithContext("backup", name)
+		}
+		return TaskBackup{}, nil, err
+	}
+	data, err := ts.fileUtils.ReadFile(path)
+	if err != nil {
+		return TaskBackup{}, nil, fmt.Errorf("failed to read backup: %v", err)
+	}
+	tasks, err := decodeTasks(data)
+	if err != nil {
+		return TaskBackup{}, nil, ValidationError("backup is not a valid task file", err).WithContext("backup", name)
+	}
+	backup := TaskBackup{Name: name, CreatedAt: info.ModTime(), Bytes: info.Size(), TaskCount: len(tasks)}
+	return backup, tasks, nil
+}
+
+// PreviewBackup compares a backup with the current tasks
+func (ts *TaskService) PreviewBackup(name string) (TaskBackupPreview, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	backup, tasks, err := ts.readBackup(name)
+	if err != nil {
+		return TaskBackupPreview{}, err
+	}
+	preview := TaskBackupPreview{
+		Backup:  backup,
+		Tasks:   tasks,
+		Added:   []Task{},
+		Removed: []Task{},
+		Changed: []TaskFieldChange{},
+	}
+
+	current := indexTasks(ts.tasks)
+	backupByID := indexTasks(tasks)
+	for _, b := range tasks {
+		c, ok := current[b.ID]
+		if !ok {
+			preview.Added = append(preview.Added, b)
+			continue
+		}
+		for _, field := range mergeableTaskFields {
+			cv, bv := field.get(c), field.get(b)
+			if !reflect.DeepEqual(cv, bv) {
+				preview.Changed = append(preview.Changed, TaskFieldChange{TaskID: b.ID, Field: field.name, Current: cv, Backup: bv})
+			}
+		}
+	}
+	for _, c := range ts.tasks {
+		if _, ok := backupByID[c.ID]; !ok {
+			preview.Removed = append(preview.Removed, c)
+		}
+	}
+	return preview, nil
+}
+
+// RestoreBackup replaces the tasks with a backup's. Writing the task file
+// backs up the current state first, so a restore can itself be undone.
+func (ts *TaskService) RestoreBackup(name string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
+
+	_, tasks, err := ts.readBackup(name)
+	if err != nil {
+		return err
+	}
+	if err := ts.validateTasks(tasks); err != nil {
+		return err
+	}
+
+	// Restoring is deliberate: overwrite rather than merge with the file on disk
+	ts.tasks = tasks
+	ts.hasBase = false
+	if err := ts.compact(); err != nil {
+		return err
+	}
+
+	ts.logger.InfoWithFields("Tasks restored from backup", map[string]interface{}{
+		"backup": name,
+		"tasks":  len(tasks),
+	})
+	return nil
+}
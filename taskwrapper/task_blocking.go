@@ -0,0 +1,48 @@
+package main
+
+// BlockedTask is a task that can't start until other tasks are done
+type BlockedTask struct {
+	TaskID    int        `json:"taskId"`
+	Title     string     `json:"title"`
+	Status    TaskStatus `json:"status"`
+	BlockedBy []int      `json:"blockedBy"` // dependencies not done yet, sorted
+}
+
+// doneTaskIDs returns the IDs of the tasks that are done
+func doneTaskIDs(tasks []Task) map[int]bool {
+	done := map[int]bool{}
+	for _, task := range tasks {
+		if task.Status == StatusDone {
+			done[task.ID] = true
+		}
+	}
+	return done
+}
+
+// unfinishedDeps returns task's dependencies that aren't done, sorted. A
+// dependency missing from the board counts as unfinished.
+func unfinishedDeps(task Task, done map[int]bool) []int {
+	var deps []int
+	for _, dep := range canonicalDeps(append([]int(nil), task.Deps...)) {
+		if !done[dep] {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// blockedTasks lists the backlog and todo tasks with unfinished
+// dependencies, in board order
+func blockedTasks(tasks []Task) []BlockedTask {
+	done := doneTaskIDs(tasks)
+	blocked := []BlockedTask{}
+	for _, task := range tasks {
+		if task.Status != StatusBacklog && task.Status != StatusTodo {
+			continue
+		}
+		if deps := unfinishedDeps(task, done); len(deps) > 0 {
+			blocked = append(blocked, BlockedTask{TaskID: task.ID, Title: task.Title, Status: task.Status, BlockedBy: deps})
+		}
+	}
+	return blocked
+}
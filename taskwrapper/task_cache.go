@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// The tasks in memory stand in for task.json while its size and
+// modification time are unchanged, so the frontend's frequent refreshes
+// don't re-read it. When they change but its contents don't, such as after
+// a touch or an identical rewrite, it is read but not parsed again.
+
+// taskCacheRacyWindow is how long after task.json was modified its size and
+// modification time are not trusted alone: a second write within the file
+// system's timestamp granularity can leave both as they were
+const taskCacheRacyWindow = 2 * time.Second
+
+// taskFileHash is the hash LoadTasks compares task file contents by
+func taskFileHash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+// cacheFresh reports whether the tasks in memory are still what task.json
+// holds, without reading it. Caller holds ts.mu.
+func (ts *TaskService) cacheFresh() bool {
+	if !ts.cached || !ts.hasBase || ts.pendingOps > 0 || ts.diskChanged() {
+		return false
+	}
+	if ts.diskSeenAt.Sub(ts.diskModTime) <= taskCacheRacyWindow {
+		return false
+	}
+	// Another instance's journaled edits are replayed on load
+	_, err := os.Stat(ts.journalPath())
+	return os.IsNotExist(err)
+}
+
+// sameContents reports whether hash is that of what task.json held when it
+// was last parsed into the tasks in memory. Caller holds ts.mu.
+func (ts *TaskService) sameContents(hash []byte) bool {
+	return ts.hasBase && ts.diskHash != nil && bytes.Equal(hash, ts.diskHash)
+}
+
+// forgetCache makes the next LoadTasks read and parse task.json. Caller
+// holds ts.mu.
+func (ts *TaskService) forgetCache() {
+	ts.cached = false
+	ts.diskHash = nil
+}
+
+// invalidateCache makes the next LoadTasks read task.json, for the watcher
+// to call on every event for it
+func (ts *TaskService) invalidateCache() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.cached = false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// strippedTask is how a task is written with volatile fields stripped:
+// empty deps and a null parent are left out rather than written as defaults
+type strippedTask struct {
+	ID          int          `json:"id"`
+	Title       string       `json:"title"`
+	Status      TaskStatus   `json:"status"`
+	Priority    TaskPriority `json:"priority"`
+	Deps        []int        `json:"deps,omitempty"`
+	Parent      *int         `json:"parent,omitempty"`
+	Estimate    int          `json:"estimate,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Notes       string       `json:"notes,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Triaged     bool         `json:"triaged,omitempty"`
+}
+
+// SetStripVolatile turns stripping of empty deps and null parents from
+// task.json on or off
+func (ts *TaskService) SetStripVolatile(enabled bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.stripVolatile = enabled
+}
+
+// canonicalTasks returns a copy of tasks in the order task.json is written:
+// sorted by ID, with each task's deps sorted and de-duplicated. Saves that
+// only reorder tasks then leave the file unchanged, so committed diffs show
+// real edits. Field order is fixed by the struct, so output is byte-stable.
+func canonicalTasks(tasks []Task) []Task {
+	canonical := cloneTasks(tasks)
+	sort.SliceStable(canonical, func(i, j int) bool {
+		return canonical[i].ID < canonical[j].ID
+	})
+	for i := range canonical {
+		canonical[i].Deps = canonicalDeps(canonical[i].Deps)
+	}
+	return canonical
+}
+
+// canonicalDeps sorts and de-duplicates deps; nil becomes empty so the file
+// never alternates between null and []
+func canonicalDeps(deps []int) []int {
+	if len(deps) == 0 {
+		return []int{}
+	}
+	sort.Ints(deps)
+	unique := deps[:1]
+	for _, dep := range deps[1:] {
+		if dep != unique[len(unique)-1] {
+			unique = append(unique, dep)
+		}
+	}
+	return unique
+}
+
+// taskFileContents returns the value to encode as task.json
+func taskFileContents(tasks []Task, stripVolatile bool) interface{} {
+	if !stripVolatile {
+		return tasks
+	}
+	stripped := make([]strippedTask, len(tasks))
+	for i, t := range tasks {
+		stripped[i] = strippedTask(t)
+	}
+	return stripped
+}
+
+// decodeTasks parses a task file, restoring defaults a stripped file leaves out
+func decodeTasks(data []byte) ([]Task, error) {
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		if tasks[i].Deps == nil {
+			tasks[i].Deps = []int{}
+		}
+	}
+	return tasks, nil
+}
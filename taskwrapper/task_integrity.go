@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumPath returns the sidecar checksum file for a task file
+func checksumPath(taskFile string) string {
+	return taskFile + ".sha256"
+}
+
+// writeChecksum records the SHA-256 of data in the sidecar file, in the
+// format `sha256sum -c` understands
+func writeChecksum(taskFile string, data []byte) error {
+	sum := sha256.Sum256(data)
+	return writeSum(taskFile, sum[:])
+}
+
+// writeSum records an already computed SHA-256 in the sidecar file
+func writeSum(taskFile string, sum []byte) error {
+	line := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum), filepath.Base(taskFile))
+	return os.WriteFile(checksumPath(taskFile), []byte(line), 0644)
+}
+
+// checksumMatches reports whether data matches the recorded checksum.
+// recorded is false when there is no readable sidecar.
+func checksumMatches(taskFile string, data []byte) (matches, recorded bool) {
+	sum := sha256.Sum256(data)
+	return sumMatches(taskFile, sum[:])
+}
+
+// sumMatches is checksumMatches for an already computed SHA-256
+func sumMatches(taskFile string, sum []byte) (matches, recorded bool) {
+	raw, err := os.ReadFile(checksumPath(taskFile))
+	if err != nil {
+		return false, false
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return false, false
+	}
+	return fields[0] == hex.EncodeToString(sum), true
+}
+
+// SetChecksums turns the task.json.sha256 sidecar on or off
+func (ts *TaskService) SetChecksums(enabled bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.checksums = enabled
+}
+
+// updateChecksum rewrites the sidecar from the file now on disk. The sum
+// covers the decrypted contents, so it survives encrypting the repository.
+// Caller holds ts.mu.
+func (ts *TaskService) updateChecksum() {
+	if !ts.checksums {
+		return
+	}
+	data, err := ts.fileUtils.ReadFile(ts.taskFile)
+	if err == nil {
+		err = writeChecksum(ts.taskFile, data)
+	}
+	if err != nil {
+		ts.logger.Error("Failed to update task file checksum", err)
+	}
+}
+
+// parseTaskData decodes and validates task file contents
+func (ts *TaskService) parseTaskData(data []byte) ([]Task, error) {
+	var tasks []Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, err
+	}
+	if err := ts.validateTasks(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// checkIntegrity decides whether freshly read task file contents can be
+// trusted. Unparseable contents are always corrupt. With checksums on, a
+// mismatching file that still validates was edited by another tool (e.g. an
+// agent) and its checksum is refreshed; one that doesn't validate is corrupt.
+// Corruption errors name the newest valid backup so it can be offered for
+// restore. Caller holds ts.mu.
+func (ts *TaskService) checkIntegrity(data []byte) error {
+	var tasks []Task
+	err := json.Unmarshal(data, &tasks)
+	sum := sha256.Sum256(data)
+	return ts.checkDecoded(tasks, sum[:], err)
+}
+
+// checkDecoded is checkIntegrity for task file contents already decoded,
+// with their SHA-256 and the error decoding them. Caller holds ts.mu.
+func (ts *TaskService) checkDecoded(tasks []Task, sum []byte, err error) error {
+	if err == nil && ts.checksums {
+		matches, recorded := sumMatches(ts.taskFile, sum)
+		if recorded && !matches {
+			if err = ts.validateTasks(tasks); err == nil {
+				ts.logger.Info("Task file changed outside TaskWrapper, refreshing checksum")
+			}
+		}
+		if err == nil && !matches && !ts.readOnly {
+			if writeErr := writeSum(ts.taskFile, sum); writeErr != nil {
+				ts.logger.Error("Failed to update task file checksum", writeErr)
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	corrupted := CorruptedError("task file is corrupted", err).WithContext("file", ts.taskFile)
+	if backup, ok := ts.newestValidBackup(); ok {
+		corrupted.WithContext("backup", backup.Name)
+	}
+	ts.logger.ErrorWithFields("Task file failed integrity check", err, corrupted.Context)
+	return corrupted
+}
+
+// newestValidBackup returns the most recent backup that parses and
+// validates. Caller holds ts.mu.
+func (ts *TaskService) newestValidBackup() (TaskBackup, bool) {
+	backups, err := ts.listBackups()
+	if err != nil {
+		return TaskBackup{}, false
+	}
+	for _, backup := range backups {
+		path, err := ts.backupPath(backup.Name)
+		if err != nil {
+			continue
+		}
+		data, err := ts.fileUtils.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if _, err := ts.parseTaskData(data); err == nil {
+			return backup, true
+		}
+	}
+	return TaskBackup{}, false
+}
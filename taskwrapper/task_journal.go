@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Single-task edits are appended to task.journal and folded into task.json
+// after taskJournalCompactDelay, or sooner once taskJournalCompactOps pile up
+const (
+	taskJournalCompactOps   = 100
+	taskJournalCompactDelay = 2 * time.Second
+)
+
+// Task journal operations
+const (
+	taskOpCreate = "create"
+	taskOpUpdate = "update"
+	taskOpMove   = "move"
+)
+
+// taskJournalOp is one line of task.journal
+type taskJournalOp struct {
+	Op     string     `json:"op"`
+	Time   time.Time  `json:"time"`
+	Task   *Task      `json:"task,omitempty"`   // create, update
+	TaskID int        `json:"taskId,omitempty"` // move
+	Status TaskStatus `json:"status,omitempty"` // move
+}
+
+// taskJournalPath returns the journal of a task file, kept in dir with its
+// backups rather than in the repository
+func taskJournalPath(dir, taskFile string) string {
+	name := filepath.Base(taskFile)
+	return filepath.Join(dir, strings.TrimSuffix(name, filepath.Ext(name))+".journal")
+}
+
+// journalPath returns where this service journals edits. Caller holds ts.mu.
+func (ts *TaskService) journalPath() string {
+	return taskJournalPath(ts.fileUtils.BackupDir(ts.taskFile), ts.taskFile)
+}
+
+// moveJournal moves a journal left at previous, by an earlier run or before
+// the backup directory changed, to where the service now journals. Caller
+// holds ts.mu.
+func (ts *TaskService) moveJournal(previous string) {
+	current := ts.journalPath()
+	if previous == current {
+		return
+	}
+	if _, err := os.Stat(previous); err != nil {
+		return
+	}
+	if _, err := os.Stat(current); err == nil {
+		ts.logger.Warn("Leaving task journal at " + previous + ": one is already at " + current)
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(current), 0755); err != nil {
+		ts.logger.Error("Failed to move task journal", err)
+		return
+	}
+	if err := os.Rename(previous, current); err != nil {
+		ts.logger.Error("Failed to move task journal", err)
+	}
+}
+
+// appendTaskJournal appends op to the journal at path
+func appendTaskJournal(path string, op taskJournalOp) error {
+	line, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("failed to encode task journal entry: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open task journal: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to task journal: %v", err)
+	}
+	return nil
+}
+
+// readTaskJournal returns the operations recorded at path. A torn final line
+// from an interrupted write is skipped.
+func readTaskJournal(path string) ([]taskJournalOp, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var ops []taskJournalOp
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var op taskJournalOp
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// applyTaskOp replays one journal operation onto tasks
+func applyTaskOp(tasks []Task, op taskJournalOp) []Task {
+	switch op.Op {
+	case taskOpCreate, taskOpUpdate:
+		if op.Task == nil {
+			return tasks
+		}
+		for i := range tasks {
+			if tasks[i].ID == op.Task.ID {
+				tasks[i] = *op.Task
+				return tasks
+			}
+		}
+		return append(tasks, *op.Task)
+	case taskOpMove:
+		for i := range tasks {
+			if tasks[i].ID == op.TaskID {
+				tasks[i].Status = op.Status
+			}
+		}
+	}
+	return tasks
+}
+
+// recordOp persists an edit already applied to ts.tasks, which held
+// previous before it. It appends to the journal, or compacts straight away
+// when task.json changed on disk (so conflicts surface to the caller) or the
+// journal is due. An edit that conflicts with the file stays journaled until
+// ResolveConflict settles it; after any other failure ts.tasks is put back
+// to previous. Caller holds ts.mu.
+func (ts *TaskService) recordOp(op taskJournalOp, previous []Task) error {
+	op.Time = time.Now().UTC()
+	var err error
+	// The journal is plain JSON, so encrypted repositories write through
+	if ts.fileUtils.Encrypted() || ts.diskChanged() || ts.pendingOps+1 >= taskJournalCompactOps {
+		err = ts.compact()
+	} else if err = ts.appendJournal(op); err != nil {
+		ts.logger.Error("Failed to append task journal, writing task file instead", err)
+		err = ts.compact()
+	} else {
+		ts.scheduleCompaction()
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+
+	if isConflictError(err) && !ts.fileUtils.Encrypted() && ts.appendJournal(op) == nil {
+		ts.conflicted = true
+		return err
+	}
+	ts.tasks = previous
+	return err
+}
+
+// appendJournal appends op to the journal. Caller holds ts.mu.
+func (ts *TaskService) appendJournal(op taskJournalOp) error {
+	if err := os.MkdirAll(filepath.Dir(ts.journalPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create task journal directory: %v", err)
+	}
+	if err := appendTaskJournal(ts.journalPath(), op); err != nil {
+		return err
+	}
+	ts.pendingOps++
+	return nil
+}
+
+// scheduleCompaction folds the journal into task.json after
+// taskJournalCompactDelay, unless that is already due. Caller holds ts.mu.
+func (ts *TaskService) scheduleCompaction() {
+	if ts.compactTimer != nil || ts.conflicted {
+		return
+	}
+	errorHandler := ts.errorHandler
+	ts.compactTimer = time.AfterFunc(taskJournalCompactDelay, func() {
+		defer errorHandler.RecoverGoroutine("task journal compaction")
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		ts.compactTimer = nil
+		if err := ts.compact(); err != nil {
+			ts.logger.Error("Failed to compact task journal", err)
+		}
+	})
+}
+
+// isConflictError reports whether err is a ConflictError, such as edits
+// on disk that can't be merged with ours
+func isConflictError(err error) bool {
+	var appErr *AppError
+	return errors.As(err, &appErr) && appErr.Type == ErrorTypeConflict
+}
+
+// compact writes the in-memory tasks to task.json and clears the journal.
+// Edits that conflict with the file leave the service conflicted until
+// ResolveConflict. Caller holds ts.mu.
+func (ts *TaskService) compact() error {
+	if ts.compactTimer != nil {
+		ts.compactTimer.Stop()
+		ts.compactTimer = nil
+	}
+	if err := ts.saveTasks(); err != nil {
+		if isConflictError(err) && ts.pendingOps > 0 {
+			ts.conflicted = true
+		}
+		return err
+	}
+	ts.clearJournal()
+	return nil
+}
+
+// clearJournal removes the journal file. Caller holds ts.mu.
+func (ts *TaskService) clearJournal() {
+	if err := os.Remove(ts.journalPath()); err != nil && !os.IsNotExist(err) {
+		ts.logger.Error("Failed to remove task journal", err)
+	}
+	ts.pendingOps = 0
+	ts.conflicted = false
+}
+
+// replayJournal applies a journal left behind by a previous run to the tasks
+// just read from task.json and compacts the result. A read-only instance
+// only shows the edits: the journal belongs to the instance that owns the
+// board, which folds it in itself. Caller holds ts.mu.
+func (ts *TaskService) replayJournal() error {
+	ops, err := readTaskJournal(ts.journalPath())
+	if err != nil {
+		return fmt.Errorf("failed to read task journal: %v", err)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	for _, op := range ops {
+		ts.tasks = applyTaskOp(ts.tasks, op)
+	}
+	ts.logger.InfoWithFields("Replayed task journal", map[string]interface{}{
+		"operations": len(ops),
+		"read_only":  ts.readOnly,
+	})
+	if ts.readOnly {
+		return nil
+	}
+	return ts.compact()
+}
+
+// rememberDiskState records task.json's size and modification time so later
+// writes can tell cheaply whether something else changed it. Caller holds ts.mu.
+func (ts *TaskService) rememberDiskState() {
+	ts.diskSeenAt = time.Now()
+	info, err := os.Stat(ts.taskFile)
+	if err != nil {
+		ts.diskModTime, ts.diskSize = time.Time{}, -1
+		return
+	}
+	ts.diskModTime, ts.diskSize = info.ModTime(), info.Size()
+}
+
+// diskChanged reports whether task.json differs from when it was last read
+// or written. Caller holds ts.mu.
+func (ts *TaskService) diskChanged() bool {
+	if !ts.hasBase {
+		return false
+	}
+	info, err := os.Stat(ts.taskFile)
+	if err != nil {
+		return ts.diskSize != -1
+	}
+	return !info.ModTime().Equal(ts.diskModTime) || info.Size() != ts.diskSize
+}
+
+// DiskChanged reports whether task.json has been edited outside this service
+// since it was last read or written
+func (ts *TaskService) DiskChanged() bool {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.diskChanged()
+}
+
+// ResolveConflict settles journaled edits that conflict with edits made to
+// task.json on disk: keepMine writes the edits over the file, otherwise
+// they are dropped and the file is read again
+func (ts *TaskService) ResolveConflict(keepMine bool) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if !ts.conflicted {
+		return nil
+	}
+	if ts.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
+	if keepMine {
+		// Without a base there is nothing to merge with, so ours is written as is
+		ts.hasBase = false
+		if err := ts.compact(); err != nil {
+			ts.hasBase = true
+			return err
+		}
+		return nil
+	}
+	ts.logger.Warn("Discarding journaled task edits that conflict with the task file")
+	ts.clearJournal()
+	ts.forgetCache()
+	return nil
+}
+
+// Flush folds journaled edits into task.json immediately
+func (ts *TaskService) Flush() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.pendingOps == 0 {
+		return nil
+	}
+	return ts.compact()
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TaskConflict is a field both sides changed to different values since the
+// common base. Field is "task" when one side deleted a task the other edited.
+type TaskConflict struct {
+	TaskID int         `json:"taskId"`
+	Field  string      `json:"field"`
+	Ours   interface{} `json:"ours"`
+	Theirs interface{} `json:"theirs"`
+}
+
+// taskField reads and writes one mergeable field of a Task
+type taskField struct {
+	name string
+	get  func(Task) interface{}
+	set  func(*Task, Task)
+}
+
+// mergeableTaskFields lists the fields three-way merged independently
+var mergeableTaskFields = []taskField{
+	{"title", func(t Task) interface{} { return t.Title }, func(dst *Task, src Task) { dst.Title = src.Title }},
+	{"status", func(t Task) interface{} { return t.Status }, func(dst *Task, src Task) { dst.Status = src.Status }},
+	{"priority", func(t Task) interface{} { return t.Priority }, func(dst *Task, src Task) { dst.Priority = src.Priority }},
+	{"deps", func(t Task) interface{} { return normalizeDeps(t.Deps) }, func(dst *Task, src Task) { dst.Deps = src.Deps }},
+	{"parent", func(t Task) interface{} {
+		if t.Parent == nil {
+			return nil
+		}
+		return *t.Parent
+	}, func(dst *Task, src Task) { dst.Parent = src.Parent }},
+	{"estimate", func(t Task) interface{} { return t.Estimate }, func(dst *Task, src Task) { dst.Estimate = src.Estimate }},
+	{"description", func(t Task) interface{} { return t.Description }, func(dst *Task, src Task) { dst.Description = src.Description }},
+	{"notes", func(t Task) interface{} { return t.Notes }, func(dst *Task, src Task) { dst.Notes = src.Notes }},
+	{"tags", func(t Task) interface{} { return strings.Join(t.Tags, ",") }, func(dst *Task, src Task) { dst.Tags = src.Tags }},
+	{"triaged", func(t Task) interface{} { return t.Triaged }, func(dst *Task, src Task) { dst.Triaged = src.Triaged }},
+}
+
+// mergeTasks three-way merges ours (in-memory edits) and theirs (the file on
+// disk) against base, the version both started from. Fields changed on only
+// one side are taken from that side; fields changed identically on both are
+// fine. Conflicting fields keep our value and are reported. Tasks created on
+// both sides with the same ID are kept, with ours renumbered.
+func mergeTasks(base, ours, theirs []Task) ([]Task, []TaskConflict) {
+	baseByID := indexTasks(base)
+	theirsByID := indexTasks(theirs)
+	handled := make(map[int]bool, len(ours)) // IDs whose theirs version has been accounted for
+
+	nextID := 1
+	for _, list := range [][]Task{base, ours, theirs} {
+		for _, t := range list {
+			if t.ID >= nextID {
+				nextID = t.ID + 1
+			}
+		}
+	}
+
+	var merged, renumbered []Task
+	var conflicts []TaskConflict
+
+	for _, o := range ours {
+		b, inBase := baseByID[o.ID]
+		t, inTheirs := theirsByID[o.ID]
+		handled[o.ID] = true
+
+		switch {
+		case inBase && inTheirs:
+			result, fieldConflicts := mergeTask(b, o, t)
+			merged = append(merged, result)
+			conflicts = append(conflicts, fieldConflicts...)
+		case inBase && !inTheirs:
+			// They deleted it; keep it only if we edited it meanwhile
+			if !tasksEqual(b, o) {
+				merged = append(merged, o)
+				conflicts = append(conflicts, TaskConflict{TaskID: o.ID, Field: "task", Ours: o, Theirs: nil})
+			}
+		case !inBase && inTheirs && !tasksEqual(o, t):
+			// Both sides created a task with this ID
+			handled[o.ID] = false
+			o.ID = nextID
+			nextID++
+			renumbered = append(renumbered, o)
+		default:
+			merged = append(merged, o)
+		}
+	}
+
+	for _, t := range theirs {
+		if handled[t.ID] {
+			continue
+		}
+		b, inBase := baseByID[t.ID]
+		switch {
+		case !inBase:
+			merged = append(merged, t)
+		case !tasksEqual(b, t):
+			// We deleted it but they edited it
+			merged = append(merged, t)
+			conflicts = append(conflicts, TaskConflict{TaskID: t.ID, Field: "task", Ours: nil, Theirs: t})
+		}
+	}
+
+	return append(merged, renumbered...), conflicts
+}
+
+// mergeTask merges one task present in all three versions field by field
+func mergeTask(base, ours, theirs Task) (Task, []TaskConflict) {
+	result := ours
+	var conflicts []TaskConflict
+	for _, field := range mergeableTaskFields {
+		b, o, t := field.get(base), field.get(ours), field.get(theirs)
+		switch {
+		case reflect.DeepEqual(o, b):
+			field.set(&result, theirs)
+		case reflect.DeepEqual(t, b), reflect.DeepEqual(o, t):
+			// Only we changed it, or both changed it the same way
+		default:
+			conflicts = append(conflicts, TaskConflict{TaskID: ours.ID, Field: field.name, Ours: o, Theirs: t})
+		}
+	}
+	return result, conflicts
+}
+
+// tasksEqual compares the mergeable fields of two tasks
+func tasksEqual(a, b Task) bool {
+	if a.ID != b.ID {
+		return false
+	}
+	for _, field := range mergeableTaskFields {
+		if !reflect.DeepEqual(field.get(a), field.get(b)) {
+			return false
+		}
+	}
+	return true
+}
+
+// taskListsEqual reports whether two task lists hold the same tasks in the same order
+func taskListsEqual(a, b []Task) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !tasksEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexTasks maps task ID to task
+func indexTasks(tasks []Task) map[int]Task {
+	byID := make(map[int]Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	return byID
+}
+
+// normalizeDeps treats nil and empty dependency lists as equal
+func normalizeDeps(deps []int) []int {
+	if len(deps) == 0 {
+		return []int{}
+	}
+	return deps
+}
+
+// cloneTasks deep-copies tasks so later in-place edits don't alter the copy
+func cloneTasks(tasks []Task) []Task {
+	clone := make([]Task, len(tasks))
+	for i, t := range tasks {
+		if t.Deps != nil {
+			t.Deps = append([]int(nil), t.Deps...)
+		}
+		if t.Parent != nil {
+			parent := *t.Parent
+			t.Parent = &parent
+		}
+		if t.Tags != nil {
+			t.Tags = append([]string(nil), t.Tags...)
+		}
+		clone[i] = t
+	}
+	return clone
+}
+
+// describeConflicts summarises conflicts for an error message
+func describeConflicts(conflicts []TaskConflict) string {
+	summary := ""
+	for i, c := range conflicts {
+		if i > 0 {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("task #%d %s", c.TaskID, c.Field)
+	}
+	return summary
+}
@@ -1,66 +1,185 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
 
+// maxTaskTextBytes caps a task's description and its notes, which agents
+// get in their prompt
+const maxTaskTextBytes = 64 * 1024
+
 // TaskService handles task-related operations
 type TaskService struct {
 	taskFile  string
 	mu        sync.RWMutex
 	tasks     []Task
 	logger    Logger
-	fileUtils *FileUtils
+	fileUtils FileUtilsInterface
+	perf      *PerformanceRecorder
+	
+	// errorHandler recovers panics in compaction and backup cleanup
+	errorHandler *ErrorHandler
+	
+	// base is the task list last read from or written to disk; external
+	// edits made since then are merged into ours before saving
+	base    []Task
+	hasBase bool
+	
+	// Single-task edits are journaled rather than rewriting task.json.
+	// conflicted is set while journaled edits conflict with the file.
+	pendingOps   int
+	conflicted   bool
+	compactTimer *time.Timer
+	diskModTime  time.Time
+	diskSize     int64
+	diskSeenAt   time.Time // when diskModTime and diskSize were read
+	
+	// cached is set while the tasks in memory match task.json, and diskHash
+	// is the hash of its contents when last parsed; see task_cache.go
+	cached   bool
+	diskHash []byte
+	
+	// checksums maintains task.json.sha256 and verifies it on load
+	checksums bool
+	
+	// stripVolatile leaves empty deps and null parents out of task.json
+	stripVolatile bool
+	
+	// readOnly refuses every change, for an instance that doesn't own the board
+	readOnly bool
+	
+	// onLoadProgress is told how far a large task file has loaded
+	onLoadProgress func(TaskLoadProgress)
 }
 
 // NewTaskService creates a new task service
 func NewTaskService(taskFile string, logger Logger) *TaskService {
+	return NewTaskServiceWithFileUtils(taskFile, logger, NewFileUtils(logger))
+}
+
+// NewTaskServiceWithFileUtils creates a task service that persists through fileUtils
+func NewTaskServiceWithFileUtils(taskFile string, logger Logger, fileUtils FileUtilsInterface) *TaskService {
 	return &TaskService{
-		taskFile:  taskFile,
-		tasks:     []Task{},
-		logger:    logger,
-		fileUtils: NewFileUtils(logger),
+		taskFile:     taskFile,
+		tasks:        []Task{},
+		logger:       logger,
+		fileUtils:    fileUtils,
+		perf:         NewPerformanceRecorder(logger),
+		errorHandler: NewErrorHandler(logger),
 	}
 }
 
-// LoadTasks reloads tasks from disk and returns them
+// SetPerformanceRecorder sets where task file read/write latencies are recorded
+func (ts *TaskService) SetPerformanceRecorder(perf *PerformanceRecorder) {
+	ts.perf = perf
+}
+
+// SetErrorHandler sets the handler used to recover panics in the service's
+// background work
+func (ts *TaskService) SetErrorHandler(errorHandler *ErrorHandler) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.errorHandler = errorHandler
+}
+
+// LoadTasks reloads tasks from disk and returns them, unless task.json is
+// unchanged since it was last read or written
 func (ts *TaskService) LoadTasks() ([]Task, error) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
 	
-	// Reload from disk to pick up external changes
-	data, err := os.ReadFile(ts.taskFile)
+	// Fold our journaled edits into task.json before re-reading it. If they
+	// conflict with edits made on disk, they stay journaled until the user
+	// picks a side with ResolveConflict.
+	if ts.pendingOps > 0 {
+		if err := ts.compact(); err != nil {
+			ts.logger.Error("Failed to fold journaled task edits into the task file", err)
+			return ts.tasks, err
+		}
+	}
+	
+	// Nothing to read if task.json is unchanged since it was last read or written
+	start := time.Now()
+	if ts.cacheFresh() {
+		ts.perf.Record(OpTaskLoad, time.Since(start), nil)
+		return ts.tasks, nil
+	}
+	
+	// Reload from disk to pick up external changes; a large file is decoded
+	// as it is read
+	size, err := ts.streamSize()
+	if err != nil {
+		ts.perf.Record(OpTaskLoad, time.Since(start), err)
+		ts.logger.Error("Refused to load task file", err)
+		return ts.tasks, err
+	}
+	if size > 0 {
+		return ts.streamTasks(size, start)
+	}
+	data, err := ts.fileUtils.ReadFile(ts.taskFile)
+	ts.perf.Record(OpTaskLoad, time.Since(start), err)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Create empty task file
+			// Create empty task file, unless this instance may not write
 			ts.tasks = []Task{}
+			if ts.readOnly {
+				return ts.tasks, nil
+			}
 			if writeErr := ts.fileUtils.AtomicWriteJSON(ts.taskFile, ts.tasks); writeErr != nil {
 				ts.logger.Error("Failed to create empty task file", writeErr)
 				return ts.tasks, writeErr
 			}
+			ts.diskHash = nil
+			ts.updateChecksum()
 		} else {
 			ts.logger.Error("Failed to read task file", err)
 			return ts.tasks, fmt.Errorf("failed to read task file: %v", err)
 		}
+	} else if hash := taskFileHash(data); ts.sameContents(hash) {
+		// Touched or rewritten as it was; the tasks in memory still match
 	} else {
-		if err := json.Unmarshal(data, &ts.tasks); err != nil {
+		if err := ts.checkIntegrity(data); err != nil {
+			return ts.tasks, err
+		}
+		tasks, err := decodeTasks(data)
+		if err != nil {
 			ts.logger.Error("Failed to parse task file", err)
 			return ts.tasks, fmt.Errorf("failed to parse task file: %v", err)
 		}
+		ts.tasks = tasks
+		ts.diskHash = hash
+	}
+	return ts.loaded(), nil
+}
+
+// loaded records the tasks just read as what task.json holds and replays
+// any journal left behind. Caller holds ts.mu.
+func (ts *TaskService) loaded() []Task {
+	ts.base = cloneTasks(ts.tasks)
+	ts.hasBase = true
+	ts.rememberDiskState()
+	ts.cached = true
+	
+	// Recover edits journaled by a previous run that exited before compacting
+	if err := ts.replayJournal(); err != nil {
+		ts.logger.Error("Failed to replay task journal", err)
 	}
 	
 	ts.logger.Info("Tasks reloaded successfully from disk")
-	return ts.tasks, nil
+	return ts.tasks
 }
 
 // SaveTasks writes tasks to the plan/task.json file with atomic operation
 func (ts *TaskService) SaveTasks(tasks []Task) error {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
+	if ts.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
 	
 	// Validate tasks
 	if err := ts.validateTasks(tasks); err != nil {
@@ -70,28 +189,80 @@ func (ts *TaskService) SaveTasks(tasks []Task) error {
 	// Update in-memory tasks
 	ts.tasks = tasks
 	
-	// Save to disk
-	if err := ts.saveTasks(); err != nil {
+	// Save to disk; a full save supersedes any journaled edits
+	if err := ts.compact(); err != nil {
 		return err
 	}
 	
 	return nil
 }
 
+// CreateTask appends a new task with the next free ID
+func (ts *TaskService) CreateTask(title string, priority string) (Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.readOnly {
+		return Task{}, PermissionError(readOnlyReason, nil)
+	}
+	
+	if priority == "" {
+		priority = string(PriorityMedium)
+	}
+	
+	task := Task{
+		Title:    title,
+		Status:   StatusBacklog,
+		Priority: TaskPriority(priority),
+		Deps:     []int{},
+		Parent:   nil,
+	}
+	
+	// Pick up tasks agents added on disk so the new ID is really free
+	if err := ts.reconcileWithDisk(); err != nil {
+		return Task{}, err
+	}
+	for _, t := range ts.tasks {
+		if t.ID >= task.ID {
+			task.ID = t.ID + 1
+		}
+	}
+	if task.ID == 0 {
+		task.ID = 1
+	}
+	
+	if err := ts.validateTasks([]Task{task}); err != nil {
+		return Task{}, err
+	}
+	
+	previous := ts.tasks
+	ts.tasks = append(ts.tasks, task)
+	if err := ts.recordOp(taskJournalOp{Op: taskOpCreate, Task: &task}, previous); err != nil {
+		return Task{}, err
+	}
+	
+	ts.logger.Info(fmt.Sprintf("Task %d created", task.ID))
+	return task, nil
+}
+
 // UpdateTask updates a specific task
 func (ts *TaskService) UpdateTask(task Task) error {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
+	if ts.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
 	
 	// Validate single task
 	if err := ts.validateTasks([]Task{task}); err != nil {
 		return err
 	}
 	
-	// Find and update the task
+	// Find and update the task in a copy, so a failed save leaves ours alone
+	previous := ts.tasks
 	found := false
 	for i, t := range ts.tasks {
 		if t.ID == task.ID {
+			ts.tasks = append([]Task(nil), previous...)
 			ts.tasks[i] = task
 			found = true
 			break
@@ -103,7 +274,7 @@ func (ts *TaskService) UpdateTask(task Task) error {
 	}
 	
 	// Save updated tasks
-	if err := ts.saveTasks(); err != nil {
+	if err := ts.recordOp(taskJournalOp{Op: taskOpUpdate, Task: &task}, previous); err != nil {
 		return err
 	}
 	
@@ -121,13 +292,19 @@ func (ts *TaskService) MoveTask(taskID int, newStatus string) error {
 	
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
+	if ts.readOnly {
+		return PermissionError(readOnlyReason, nil)
+	}
 	
-	// Find and update the task status
+	// Find and update the task status in a copy, so a failed save leaves
+	// ours alone
+	previous := ts.tasks
 	found := false
 	var oldStatus TaskStatus
 	for i, task := range ts.tasks {
 		if task.ID == taskID {
 			oldStatus = task.Status
+			ts.tasks = append([]Task(nil), previous...)
 			ts.tasks[i].Status = status
 			found = true
 			break
@@ -139,7 +316,7 @@ func (ts *TaskService) MoveTask(taskID int, newStatus string) error {
 	}
 	
 	// Save updated tasks
-	if err := ts.saveTasks(); err != nil {
+	if err := ts.recordOp(taskJournalOp{Op: taskOpMove, TaskID: taskID, Status: status}, previous); err != nil {
 		return err
 	}
 	
@@ -179,11 +356,50 @@ func (ts *TaskService) GetTasks() []Task {
 	return tasksCopy
 }
 
+// SetReadOnly makes every change to the tasks fail
+func (ts *TaskService) SetReadOnly(readOnly bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.readOnly = readOnly
+}
+
+// SetBackupDir sets where task.json backups and the task journal are
+// written, taking the journal along
+func (ts *TaskService) SetBackupDir(dir string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	previous := ts.journalPath()
+	ts.fileUtils.SetBackupDir(dir)
+	ts.moveJournal(previous)
+}
+
+// SetCipher turns at-rest encryption of the task file and its backups on or
+// off; nil writes plain JSON
+func (ts *TaskService) SetCipher(cipher *Cipher) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.fileUtils.SetCipher(cipher)
+}
+
 // SetTaskFile changes the task file path
 func (ts *TaskService) SetTaskFile(path string) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
+	if ts.pendingOps > 0 {
+		if err := ts.compact(); err != nil {
+			ts.logger.Error("Failed to save journaled task edits before switching task file", err)
+		}
+	}
 	ts.taskFile = path
+	ts.base = nil
+	ts.hasBase = false
+}
+
+// GetTaskFile returns the path of the task file in use
+func (ts *TaskService) GetTaskFile() string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.taskFile
 }
 
 // Private helper methods
@@ -200,27 +416,86 @@ func (ts *TaskService) validateTasks(tasks []Task) error {
 		if !task.Priority.Valid() {
 			return fmt.Errorf("task with ID %d has invalid priority: %s", task.ID, task.Priority)
 		}
+		if task.Estimate < 0 {
+			return fmt.Errorf("task with ID %d has negative estimate: %d", task.ID, task.Estimate)
+		}
+		if len(task.Description) > maxTaskTextBytes || len(task.Notes) > maxTaskTextBytes {
+			return fmt.Errorf("task with ID %d has a description or notes over %d KB", task.ID, maxTaskTextBytes/1024)
+		}
 	}
 	return nil
 }
 
+// reconcileWithDisk merges changes made to the task file since it was last
+// read or written (typically by agents) into the in-memory tasks. Only edits
+// to the same field of the same task on both sides are reported as conflicts.
+func (ts *TaskService) reconcileWithDisk() error {
+	if !ts.hasBase {
+		return nil
+	}
+	
+	data, err := ts.fileUtils.ReadFile(ts.taskFile)
+	if err != nil {
+		// Nothing to merge with if the file is gone
+		return nil
+	}
+	theirs, err := decodeTasks(data)
+	if err != nil {
+		ts.logger.Error("Task file on disk is unreadable, overwriting it", err)
+		return nil
+	}
+	if taskListsEqual(theirs, ts.base) {
+		return nil
+	}
+	
+	merged, conflicts := mergeTasks(ts.base, ts.tasks, theirs)
+	if len(conflicts) > 0 {
+		ts.logger.Warn("Task file changed on disk with conflicting edits: " + describeConflicts(conflicts))
+		return ConflictError("task file changed on disk with conflicting edits: "+describeConflicts(conflicts), nil).
+			WithContext("conflicts", conflicts)
+	}
+	
+	ts.logger.InfoWithFields("Merged external task file changes", map[string]interface{}{
+		"tasks": len(merged),
+	})
+	ts.tasks = merged
+	ts.base = theirs
+	return nil
+}
+
 // saveTasks persists the current in-memory tasks to disk
 func (ts *TaskService) saveTasks() error {
+	ts.forgetCache()
+	if err := ts.reconcileWithDisk(); err != nil {
+		return err
+	}
+	
+	// Write in canonical order so reordering alone never changes the file
+	ts.tasks = canonicalTasks(ts.tasks)
+	
 	// Use FileUtils for atomic write with automatic backup
-	if err := ts.fileUtils.AtomicWriteJSON(ts.taskFile, ts.tasks); err != nil {
+	err := ts.perf.Time(OpTaskSave, func() error {
+		return ts.fileUtils.AtomicWriteJSON(ts.taskFile, taskFileContents(ts.tasks, ts.stripVolatile))
+	})
+	if err != nil {
 		ts.logger.Error("Failed to save tasks", err)
 		return fmt.Errorf("failed to save tasks: %v", err)
 	}
+	ts.base = cloneTasks(ts.tasks)
+	ts.hasBase = true
+	ts.rememberDiskState()
+	ts.cached = true
+	ts.updateChecksum()
 	
 	ts.logger.Info("Tasks saved successfully")
 	
 	// Clean up old backups (older than 7 days)
-	go func() {
-		pattern := ts.taskFile + ".backup.*"
+	pattern := filepath.Join(ts.fileUtils.BackupDir(ts.taskFile), filepath.Base(ts.taskFile)+".backup.*")
+	ts.errorHandler.Go("task backup cleanup", func() {
 		if err := ts.fileUtils.CleanupOldBackups(pattern, 7*24*time.Hour); err != nil {
 			ts.logger.Error("Failed to cleanup old backups", err)
 		}
-	}()
+	})
 	
 	return nil
 }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+)
+
+// A task.json of taskStreamMinBytes or more, with long descriptions and
+// notes inline, is decoded a task at a time as it is read rather than read
+// whole and then parsed, so loading it never holds two copies in memory.
+const (
+	taskStreamMinBytes = 4 << 20
+	// maxTaskFileBytes is the largest task file that is loaded at all
+	maxTaskFileBytes = 512 << 20
+	// maxTaskJSONBytes is the most of the file one task may take
+	maxTaskJSONBytes = 1 << 20
+	// taskLoadProgressBytes is how much is read between progress reports
+	taskLoadProgressBytes = 1 << 20
+)
+
+// TaskLoadProgress reports how far loading a large task file has got
+type TaskLoadProgress struct {
+	File       string `json:"file"`
+	BytesRead  int64  `json:"bytesRead"`
+	TotalBytes int64  `json:"totalBytes"`
+	Tasks      int    `json:"tasks"` // decoded so far
+	Done       bool   `json:"done"`
+}
+
+// Fraction is how much of the file has been read, 0..1
+func (p TaskLoadProgress) Fraction() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	return float64(p.BytesRead) / float64(p.TotalBytes)
+}
+
+// OnLoadProgress registers a callback invoked as a large task file loads.
+// It runs while the service is locked, so must not call back into it.
+func (ts *TaskService) OnLoadProgress(fn func(TaskLoadProgress)) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.onLoadProgress = fn
+}
+
+// streamSize returns the size of task.json if it should be streamed, or 0
+// to read it whole. Caller holds ts.mu.
+func (ts *TaskService) streamSize() (int64, error) {
+	info, err := os.Stat(ts.taskFile)
+	if err != nil || info.Size() < taskStreamMinBytes {
+		return 0, nil
+	}
+	if info.Size() > maxTaskFileBytes {
+		return 0, ValidationError(fmt.Sprintf("task file is over %d MB", maxTaskFileBytes>>20), nil).
+			WithContext("file", ts.taskFile).
+			WithContext("size", info.Size())
+	}
+	// Decrypting needs the whole file
+	if fileIsEncrypted(ts.taskFile) {
+		return 0, nil
+	}
+	return info.Size(), nil
+}
+
+// streamTasks loads task.json, of size bytes, a task at a time. Caller
+// holds ts.mu.
+func (ts *TaskService) streamTasks(size int64, start time.Time) ([]Task, error) {
+	file, err := os.Open(ts.taskFile)
+	if err != nil {
+		ts.perf.Record(OpTaskLoad, time.Since(start), err)
+		ts.logger.Error("Failed to read task file", err)
+		return ts.tasks, fmt.Errorf("failed to read task file: %v", err)
+	}
+	defer file.Close()
+
+	reader := &taskStreamReader{r: file, hash: sha256.New()}
+	progress := TaskLoadProgress{File: ts.taskFile, TotalBytes: size}
+	tasks, parseErr := decodeTaskStream(reader, func(decoded int, offset int64) {
+		if ts.onLoadProgress != nil && offset-progress.BytesRead >= taskLoadProgressBytes {
+			progress.BytesRead, progress.Tasks = offset, decoded
+			ts.onLoadProgress(progress)
+		}
+	})
+	ts.perf.Record(OpTaskLoad, time.Since(start), reader.err)
+	if reader.err != nil {
+		var appErr *AppError
+		if errors.As(reader.err, &appErr) {
+			ts.logger.Error("Refused to load task file", reader.err)
+			return ts.tasks, reader.err
+		}
+		ts.logger.Error("Failed to read task file", reader.err)
+		return ts.tasks, fmt.Errorf("failed to read task file: %v", reader.err)
+	}
+
+	if err := ts.checkDecoded(tasks, reader.hash.Sum(nil), parseErr); err != nil {
+		return ts.tasks, err
+	}
+	ts.tasks = tasks
+	ts.diskHash = reader.hash.Sum(nil)
+	if ts.onLoadProgress != nil {
+		progress.BytesRead, progress.Tasks, progress.Done = reader.read, len(tasks), true
+		ts.onLoadProgress(progress)
+	}
+	ts.logger.InfoWithFields("Streamed large task file", map[string]interface{}{
+		"bytes": reader.read,
+		"tasks": len(tasks),
+	})
+	return ts.loaded(), nil
+}
+
+// decodeTaskStream decodes a task list from r a task at a time, calling
+// progress after each with the count so far and how far into r it is. Like
+// decodeTasks, it restores the defaults a stripped file leaves out.
+func decodeTaskStream(r *taskStreamReader, progress func(decoded int, offset int64)) ([]Task, error) {
+	dec := json.NewDecoder(r)
+	if token, err := dec.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('[') {
+		return nil, fmt.Errorf("task file is not a list of tasks")
+	}
+
+	tasks := []Task{}
+	for dec.More() {
+		r.mark = dec.InputOffset()
+		var task Task
+		if err := dec.Decode(&task); err != nil {
+			return nil, err
+		}
+		if task.Deps == nil {
+			task.Deps = []int{}
+		}
+		tasks = append(tasks, task)
+		r.mark = dec.InputOffset()
+		progress(len(tasks), r.mark)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the task list")
+	}
+	return tasks, nil
+}
+
+// taskStreamReader hashes what the decoder reads and stops it buffering
+// more than maxTaskJSONBytes of one task. err is set when reading, rather
+// than decoding, failed.
+type taskStreamReader struct {
+	r    io.Reader
+	hash hash.Hash
+	read int64
+	mark int64 // where the task being decoded starts
+	err  error
+}
+
+func (sr *taskStreamReader) Read(p []byte) (int, error) {
+	// The decoder only reads on when the value it is decoding is incomplete
+	if sr.read-sr.mark > maxTaskJSONBytes {
+		sr.err = ValidationError(fmt.Sprintf("a task in the task file is over %d MB", maxTaskJSONBytes>>20), nil).
+			WithContext("offset", sr.mark)
+		return 0, sr.err
+	}
+	if sr.read > maxTaskFileBytes {
+		sr.err = ValidationError(fmt.Sprintf("task file is over %d MB", maxTaskFileBytes>>20), nil)
+		return 0, sr.err
+	}
+	n, err := sr.r.Read(p)
+	sr.read += int64(n)
+	sr.hash.Write(p[:n])
+	if err != nil && err != io.EOF {
+		sr.err = err
+	}
+	return n, err
+}
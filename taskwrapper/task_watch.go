@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// taskWatchSettle lets a burst of writes, such as a save's write then
+	// rename, settle into one reload
+	taskWatchSettle = 200 * time.Millisecond
+	// taskWatchRescan is how often a watched task.json is checked anyway,
+	// for edits the OS didn't report and for a switch of repository
+	taskWatchRescan = 30 * time.Second
+)
+
+// Watch calls changed when task.json is written by anything but this
+// service, until stop is closed. The plan directory is watched rather than
+// the file, since saves replace the file instead of writing to it. An
+// error means the OS can't watch it; the caller should poll DiskChanged.
+func (ts *TaskService) Watch(stop <-chan struct{}, changed func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// The task file moves when the repository is switched
+	watched := ""
+	follow := func() error {
+		dir := filepath.Dir(ts.GetTaskFile())
+		if dir == watched {
+			return nil
+		}
+		if watched != "" {
+			watcher.Remove(watched)
+			watched = ""
+		}
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+		watched = dir
+		return nil
+	}
+	if err := follow(); err != nil {
+		return err
+	}
+
+	rescan := time.NewTicker(taskWatchRescan)
+	defer rescan.Stop()
+	var settle <-chan time.Time
+	check := func() {
+		if ts.DiskChanged() {
+			changed()
+		}
+	}
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(ts.GetTaskFile()) {
+				ts.invalidateCache()
+				settle = time.After(taskWatchSettle)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// An overflowed event queue loses edits; the rescan catches them
+			ts.logger.Error("Task file watcher reported an error", err)
+		case <-settle:
+			settle = nil
+			check()
+		case <-rescan.C:
+			if err := follow(); err != nil {
+				ts.logger.Error("Failed to watch the task file's directory", err)
+			}
+			check()
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// telemetryFlushDelay batches counter updates into one write
+const telemetryFlushDelay = 5 * time.Second
+
+// TelemetryData is the locally stored aggregate
+type TelemetryData struct {
+	Since    time.Time                   `json:"since"`
+	Counters map[string]int64            `json:"counters"`
+	Daily    map[string]map[string]int64 `json:"daily"` // YYYY-MM-DD -> counter -> count
+}
+
+// TelemetrySnapshot is what users share: counts plus coarse platform info,
+// no paths, task titles or repository names
+type TelemetrySnapshot struct {
+	Enabled    bool                        `json:"enabled"`
+	AppVersion string                      `json:"appVersion"`
+	OS         string                      `json:"os"`
+	Arch       string                      `json:"arch"`
+	Since      time.Time                   `json:"since"`
+	Counters   map[string]int64            `json:"counters"`
+	Daily      map[string]map[string]int64 `json:"daily"`
+}
+
+// TelemetryService counts feature usage locally when the user opts in
+type TelemetryService struct {
+	mu        sync.Mutex
+	path      string
+	enabled   bool
+	data      TelemetryData
+	flushing  bool
+	logger    Logger
+	fileUtils FileUtilsInterface
+}
+
+// NewTelemetryService loads counters from path; counting starts disabled
+func NewTelemetryService(path string, logger Logger) *TelemetryService {
+	ts := &TelemetryService{
+		path:      path,
+		logger:    logger,
+		fileUtils: NewFileUtils(logger),
+		data:      newTelemetryData(),
+	}
+	ts.load()
+	return ts
+}
+
+// newTelemetryData returns empty counters starting now
+func newTelemetryData() TelemetryData {
+	return TelemetryData{
+		Since:    time.Now().UTC(),
+		Counters: map[string]int64{},
+		Daily:    map[string]map[string]int64{},
+	}
+}
+
+// load reads stored counters, starting fresh if the file is missing or corrupt
+func (ts *TelemetryService) load() {
+	if ts.path == "" {
+		return
+	}
+	raw, err := os.ReadFile(ts.path)
+	if err != nil {
+		return
+	}
+	var data TelemetryData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		ts.logger.Error("Ignoring unreadable telemetry file", err)
+		return
+	}
+	if data.Counters == nil {
+		data.Counters = map[string]int64{}
+	}
+	if data.Daily == nil {
+		data.Daily = map[string]map[string]int64{}
+	}
+	ts.data = data
+}
+
+// SetEnabled turns counting on or off
+func (ts *TelemetryService) SetEnabled(enabled bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.enabled = enabled
+}
+
+// Increment bumps a counter if telemetry is enabled
+func (ts *TelemetryService) Increment(name string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if !ts.enabled {
+		return
+	}
+
+	day := time.Now().UTC().Format("2006-01-02")
+	ts.data.Counters[name]++
+	if ts.data.Daily[day] == nil {
+		ts.data.Daily[day] = map[string]int64{}
+	}
+	ts.data.Daily[day][name]++
+
+	if !ts.flushing {
+		ts.flushing = true
+		time.AfterFunc(telemetryFlushDelay, func() {
+			if err := ts.Flush(); err != nil {
+				ts.logger.Error("Failed to save telemetry", err)
+			}
+		})
+	}
+}
+
+// Flush writes the counters to disk
+func (ts *TelemetryService) Flush() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.flushing = false
+	if ts.path == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(ts.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry: %v", err)
+	}
+	return ts.fileUtils.AtomicWrite(ts.path, raw)
+}
+
+// Snapshot returns a copy of the counters suitable for sharing
+func (ts *TelemetryService) Snapshot() TelemetrySnapshot {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	counters := make(map[string]int64, len(ts.data.Counters))
+	for name, count := range ts.data.Counters {
+		counters[name] = count
+	}
+
+	days := make([]string, 0, len(ts.data.Daily))
+	for day := range ts.data.Daily {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	daily := make(map[string]map[string]int64, len(days))
+	for _, day := range days {
+		counts := make(map[string]int64, len(ts.data.Daily[day]))
+		for name, count := range ts.data.Daily[day] {
+			counts[name] = count
+		}
+		daily[day] = counts
+	}
+
+	return TelemetrySnapshot{
+		Enabled:    ts.enabled,
+		AppVersion: AppVersion,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		Since:      ts.data.Since,
+		Counters:   counters,
+		Daily:      daily,
+	}
+}
+
+// Export writes the snapshot as JSON to dest
+func (ts *TelemetryService) Export(dest string) error {
+	raw, err := json.MarshalIndent(ts.Snapshot(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry: %v", err)
+	}
+	if err := os.WriteFile(dest, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write telemetry export: %v", err)
+	}
+	return nil
+}
+
+// Reset clears all counters
+func (ts *TelemetryService) Reset() error {
+	ts.mu.Lock()
+	ts.data = newTelemetryData()
+	ts.mu.Unlock()
+	return ts.Flush()
+}
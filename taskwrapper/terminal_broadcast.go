@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// terminalBroadcastTTL is how long a previewed broadcast waits to be
+	// confirmed before it has to be previewed again
+	terminalBroadcastTTL = time.Minute
+	// maxBroadcastInputBytes caps one broadcast, which is meant for a
+	// command line, not a paste
+	maxBroadcastInputBytes = 4096
+)
+
+// TerminalBroadcastGroup is a named set of terminals that input can be sent
+// to at once
+type TerminalBroadcastGroup struct {
+	Name        string   `json:"name"`
+	TerminalIDs []string `json:"terminalIds"`
+}
+
+// TerminalBroadcastMember is one terminal a broadcast would be written to
+type TerminalBroadcastMember struct {
+	TerminalID string `json:"terminalId"`
+	Dir        string `json:"dir,omitempty"`
+	Attached   bool   `json:"attached"` // false until its shell is started; such terminals are skipped
+}
+
+// TerminalBroadcast is a previewed broadcast waiting for the user's go-ahead
+type TerminalBroadcast struct {
+	Token   string                    `json:"token"` // passed to BroadcastTerminalInput to confirm
+	Group   string                    `json:"group"`
+	Input   string                    `json:"input"`
+	Members []TerminalBroadcastMember `json:"members"`
+	Expires time.Time                 `json:"expires"`
+}
+
+// TerminalBroadcastResult reports which terminals got a broadcast
+type TerminalBroadcastResult struct {
+	Group   string   `json:"group"`
+	Written []string `json:"written"`
+	Skipped []string `json:"skipped"` // not attached, or the write failed
+}
+
+// normalizeBroadcastGroup trims the group name and drops repeated terminals
+func normalizeBroadcastGroup(name string, terminalIDs []string) (string, []string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("broadcast group needs a name")
+	}
+	seen := map[string]bool{}
+	members := []string{}
+	for _, id := range terminalIDs {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			members = append(members, id)
+		}
+	}
+	return name, members, nil
+}
+
+// validBroadcastInput checks input before it's offered for confirmation
+func validBroadcastInput(input string) error {
+	if input == "" {
+		return fmt.Errorf("broadcast input must not be empty")
+	}
+	if len(input) > maxBroadcastInputBytes {
+		return fmt.Errorf("broadcast input must be at most %d bytes", maxBroadcastInputBytes)
+	}
+	return nil
+}
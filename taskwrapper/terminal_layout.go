@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// How the terminal panes are arranged
+const (
+	TerminalTabs       = "tabs"       // one pane shown at a time
+	TerminalHorizontal = "horizontal" // side by side
+	TerminalVertical   = "vertical"   // stacked
+	TerminalGrid       = "grid"
+)
+
+// maxTerminalPanes caps the shells a layout restores
+const maxTerminalPanes = 12
+
+// TerminalPane is one shell of the saved layout
+type TerminalPane struct {
+	Title      string  `json:"title,omitempty"`
+	Dir        string  `json:"dir,omitempty"`        // empty starts in the app's working directory
+	Size       float64 `json:"size,omitempty"`       // share of the split, 0 to 1; 0 splits evenly
+	TerminalID string  `json:"terminalId,omitempty"` // set by RestoreTerminalLayout, never saved
+}
+
+// TerminalLayout is the set of shells to reopen with the app
+type TerminalLayout struct {
+	Split  string         `json:"split,omitempty"` // tabs when empty
+	Panes  []TerminalPane `json:"panes"`
+	Active int            `json:"active"` // index of the focused pane
+}
+
+// normalize validates a layout to save and drops the session IDs, which
+// only mean something to the running app
+func (tl TerminalLayout) normalize() (TerminalLayout, error) {
+	switch tl.Split {
+	case "":
+		tl.Split = TerminalTabs
+	case TerminalTabs, TerminalHorizontal, TerminalVertical, TerminalGrid:
+	default:
+		return TerminalLayout{}, fmt.Errorf("split must be tabs, horizontal, vertical or grid")
+	}
+	if len(tl.Panes) > maxTerminalPanes {
+		return TerminalLayout{}, fmt.Errorf("a layout can have at most %d terminals", maxTerminalPanes)
+	}
+	panes := make([]TerminalPane, 0, len(tl.Panes))
+	for _, pane := range tl.Panes {
+		pane.Title = strings.TrimSpace(pane.Title)
+		if pane.Dir != "" {
+			if !filepath.IsAbs(pane.Dir) {
+				return TerminalLayout{}, fmt.Errorf("terminal directory %q is not absolute", pane.Dir)
+			}
+			pane.Dir = filepath.Clean(pane.Dir)
+		}
+		if pane.Size < 0 || pane.Size > 1 {
+			return TerminalLayout{}, fmt.Errorf("terminal size must be between 0 and 1")
+		}
+		pane.TerminalID = ""
+		panes = append(panes, pane)
+	}
+	tl.Panes = panes
+	if tl.Active < 0 || (tl.Active > 0 && tl.Active >= len(panes)) {
+		tl.Active = 0
+	}
+	return tl, nil
+}
@@ -4,45 +4,143 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
-	"github.com/google/uuid"
 )
 
 // TerminalService handles terminal session management and WebSocket connections
 type TerminalService struct {
 	terminals       map[string]*Terminal
+	issued          map[string]string // IDs handed out by StartTerminalSession, with their working directory
 	mu              sync.RWMutex
 	wsStarted       sync.Once
 	upgrader        websocket.Upgrader
 	logger          Logger
 	ctx             context.Context
 	originValidator *OriginValidator
+	errorHandler    *ErrorHandler
+	redactor        *Redactor
+
+	// broadcastGroups maps a group name to its terminals; broadcasts holds
+	// previews waiting for confirmation, by token
+	broadcastGroups map[string][]string
+	broadcasts      map[string]TerminalBroadcast
+
+	// onCommand receives the commands run in terminals, already redacted
+	onCommand func(record CommandRecord)
+
+	// titles holds each terminal's window title; onTitle hears when one changes
+	titles  map[string]string
+	onTitle func(terminalID, title string)
+
+	// restriction, when set, makes new terminals restricted consoles
+	restriction *commandAllowlist
+
+	// wsErr holds the bind error if the WebSocket server failed to start
+	wsRunning bool
+	wsErr     error
 }
 
-// NewTerminalService creates a new terminal service
-func NewTerminalService(logger Logger, allowedOrigins []string) *TerminalService {
-	originValidator := NewOriginValidator(allowedOrigins, logger)
-	
-	upgrader := websocket.Upgrader{
+// terminalWSAddr is where the terminal WebSocket server listens
+const terminalWSAddr = "127.0.0.1:8080"
+
+// NewTerminalService creates a new terminal service. security decides which
+// origins may open terminal WebSockets; nil uses the defaults.
+func NewTerminalService(logger Logger, security *SecurityConfig) *TerminalService {
+	ts := &TerminalService{
+		terminals:       make(map[string]*Terminal),
+		issued:          make(map[string]string),
+		logger:          logger,
+		originValidator: NewOriginValidator(security, logger),
+		errorHandler:    NewErrorHandler(logger),
+		redactor:        DefaultRedactor(),
+		broadcastGroups: make(map[string][]string),
+		broadcasts:      make(map[string]TerminalBroadcast),
+		titles:          make(map[string]string),
+	}
+	ts.upgrader = websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
-			origin := r.Header.Get("Origin")
-			return originValidator.ValidateOrigin(origin)
+			ts.mu.RLock()
+			validator := ts.originValidator
+			ts.mu.RUnlock()
+			return validator.CheckRequest(r)
 		},
 	}
+	return ts
+}
 
-	return &TerminalService{
-		terminals:       make(map[string]*Terminal),
-		upgrader:        upgrader,
-		logger:          logger,
-		originValidator: originValidator,
+// SetSecurityConfig replaces the origins allowed to open terminal WebSockets
+func (ts *TerminalService) SetSecurityConfig(config *SecurityConfig) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.originValidator = NewOriginValidator(config, ts.logger)
+}
+
+// SetRedactor replaces the patterns masked out of kept scrollback
+func (ts *TerminalService) SetRedactor(redactor *Redactor) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.redactor = redactor
+}
+
+// OnCommand registers where commands captured from terminals are recorded
+func (ts *TerminalService) OnCommand(record func(record CommandRecord)) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.onCommand = record
+}
+
+// OnTitle registers who hears about terminal window title changes
+func (ts *TerminalService) OnTitle(changed func(terminalID, title string)) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.onTitle = changed
+}
+
+// TerminalTitles returns the window title of every terminal that set one
+func (ts *TerminalService) TerminalTitles() map[string]string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	titles := make(map[string]string, len(ts.titles))
+	for id, title := range ts.titles {
+		titles[id] = title
 	}
+	return titles
+}
+
+// SetRestriction turns restricted mode on or off. Turning it on closes the
+// shells already open, so no unrestricted terminal outlives it.
+func (ts *TerminalService) SetRestriction(config RestrictedTerminalConfig) error {
+	var restriction *commandAllowlist
+	if config.Enabled {
+		allowlist, err := newCommandAllowlist(config.Commands)
+		if err != nil {
+			return ValidationError(err.Error(), err)
+		}
+		restriction = allowlist
+	}
+	
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.restriction = restriction
+	if restriction != nil {
+		for _, terminal := range ts.terminals {
+			if terminal.console == nil {
+				ts.cleanupTerminal(terminal)
+			}
+		}
+	}
+	return nil
 }
 
 // SetContext sets the application context
@@ -50,17 +148,41 @@ func (ts *TerminalService) SetContext(ctx context.Context) {
 	ts.ctx = ctx
 }
 
+// SetErrorHandler sets the handler used to recover panics in terminal goroutines
+func (ts *TerminalService) SetErrorHandler(errorHandler *ErrorHandler) {
+	ts.errorHandler = errorHandler
+}
+
 // StartTerminalSession creates a new terminal session and returns its ID
 func (ts *TerminalService) StartTerminalSession() string {
-	terminalID := uuid.New().String()
+	return ts.StartTerminalSessionIn("")
+}
+
+// StartTerminalSessionIn creates a terminal session whose shell starts in
+// dir; an empty dir uses the app's working directory
+func (ts *TerminalService) StartTerminalSessionIn(dir string) string {
+	terminalID := generateID()
 	ts.logger.Info(fmt.Sprintf("Creating terminal session: %s", terminalID))
 	
+	ts.mu.Lock()
+	ts.issued[terminalID] = dir
+	ts.mu.Unlock()
+	
 	// Start WebSocket server if not already running
-	go ts.startWebSocketServer()
+	ts.errorHandler.Go("websocket server startup", ts.startWebSocketServer)
 	
 	return terminalID
 }
 
+// TerminalIssued reports whether a session ID handed out earlier can still
+// be attached to; a session is forgotten once its shell exits
+func (ts *TerminalService) TerminalIssued(terminalID string) bool {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	_, issued := ts.issued[terminalID]
+	return issued
+}
+
 // GetTerminal retrieves a terminal by ID
 func (ts *TerminalService) GetTerminal(terminalID string) (*Terminal, bool) {
 	ts.mu.RLock()
@@ -69,22 +191,161 @@ func (ts *TerminalService) GetTerminal(terminalID string) (*Terminal, bool) {
 	return terminal, exists
 }
 
+// SetBroadcastGroup replaces the terminals of a broadcast group; no
+// terminals removes the group
+func (ts *TerminalService) SetBroadcastGroup(name string, terminalIDs []string) (TerminalBroadcastGroup, error) {
+	name, members, err := normalizeBroadcastGroup(name, terminalIDs)
+	if err != nil {
+		return TerminalBroadcastGroup{}, ValidationError(err.Error(), err)
+	}
+	
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	
+	for _, id := range members {
+		if _, issued := ts.issued[id]; !issued {
+			return TerminalBroadcastGroup{}, NotFoundError("terminal not found", nil).WithContext("terminal_id", id)
+		}
+	}
+	if len(members) == 0 {
+		delete(ts.broadcastGroups, name)
+	} else {
+		ts.broadcastGroups[name] = members
+	}
+	return TerminalBroadcastGroup{Name: name, TerminalIDs: members}, nil
+}
+
+// BroadcastGroups returns the broadcast groups by name
+func (ts *TerminalService) BroadcastGroups() []TerminalBroadcastGroup {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	
+	groups := make([]TerminalBroadcastGroup, 0, len(ts.broadcastGroups))
+	for name, members := range ts.broadcastGroups {
+		groups = append(groups, TerminalBroadcastGroup{Name: name, TerminalIDs: append([]string(nil), members...)})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// PreviewBroadcast lists the terminals input would be written to and holds
+// it until Broadcast confirms it with the returned token
+func (ts *TerminalService) PreviewBroadcast(group, input string, now time.Time) (TerminalBroadcast, error) {
+	if err := validBroadcastInput(input); err != nil {
+		return TerminalBroadcast{}, ValidationError(err.Error(), err)
+	}
+	
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	
+	members, ok := ts.broadcastGroups[strings.TrimSpace(group)]
+	if !ok {
+		return TerminalBroadcast{}, NotFoundError("broadcast group not found", nil).WithContext("group", group)
+	}
+	broadcast := TerminalBroadcast{
+		Token:   generateID(),
+		Group:   strings.TrimSpace(group),
+		Input:   input,
+		Members: make([]TerminalBroadcastMember, 0, len(members)),
+		Expires: now.Add(terminalBroadcastTTL),
+	}
+	for _, id := range members {
+		terminal, attached := ts.terminals[id]
+		broadcast.Members = append(broadcast.Members, TerminalBroadcastMember{
+			TerminalID: id,
+			Dir:        ts.issued[id],
+			Attached:   attached && terminal.Pty != nil,
+		})
+	}
+	for token, pending := range ts.broadcasts {
+		if !now.Before(pending.Expires) {
+			delete(ts.broadcasts, token)
+		}
+	}
+	ts.broadcasts[broadcast.Token] = broadcast
+	return broadcast, nil
+}
+
+// Broadcast writes a previewed broadcast to its terminals' PTYs. Each token
+// works once; the group's current members are used, so a terminal that
+// exited since the preview is skipped.
+func (ts *TerminalService) Broadcast(token string, now time.Time) (TerminalBroadcastResult, error) {
+	ts.mu.Lock()
+	broadcast, ok := ts.broadcasts[token]
+	delete(ts.broadcasts, token)
+	if !ok || !now.Before(broadcast.Expires) {
+		ts.mu.Unlock()
+		return TerminalBroadcastResult{}, NotFoundError("broadcast not previewed or expired", nil).WithContext("token", token)
+	}
+	targets := []*Terminal{}
+	result := TerminalBroadcastResult{Group: broadcast.Group, Written: []string{}, Skipped: []string{}}
+	for _, member := range broadcast.Members {
+		if terminal, attached := ts.terminals[member.TerminalID]; attached && terminal.Pty != nil {
+			targets = append(targets, terminal)
+		} else {
+			result.Skipped = append(result.Skipped, member.TerminalID)
+		}
+	}
+	ts.mu.Unlock()
+	
+	// Written outside the lock, since a PTY whose shell isn't reading blocks
+	for _, terminal := range targets {
+		if _, err := terminal.Pty.Write([]byte(broadcast.Input)); err != nil {
+			ts.logger.Error(fmt.Sprintf("Failed to broadcast to terminal %s", terminal.ID), err)
+			result.Skipped = append(result.Skipped, terminal.ID)
+			continue
+		}
+		result.Written = append(result.Written, terminal.ID)
+	}
+	return result, nil
+}
+
 // startWebSocketServer starts the WebSocket server for terminal sessions
 func (ts *TerminalService) startWebSocketServer() {
 	ts.wsStarted.Do(func() {
-		http.HandleFunc("/ws/terminal/", ts.HandleWebSocket)
+		// A private mux and a loopback listener keep terminals off the
+		// network and away from handlers registered on the default mux
+		mux := http.NewServeMux()
+		mux.HandleFunc("/ws/terminal/", ts.HandleWebSocket)
+		
+		ts.logger.Info("Starting WebSocket server on " + terminalWSAddr)
+		listener, err := net.Listen("tcp", terminalWSAddr)
+		if err != nil {
+			ts.setWebSocketState(false, err)
+			ts.logger.Error("WebSocket server failed", err)
+			return
+		}
+		ts.setWebSocketState(true, nil)
 		
-		go func() {
-			ts.logger.Info("Starting WebSocket server on :8080")
-			if err := http.ListenAndServe(":8080", nil); err != nil {
+		ts.errorHandler.Go("websocket server", func() {
+			if err := http.Serve(listener, mux); err != nil {
+				ts.setWebSocketState(false, err)
 				ts.logger.Error("WebSocket server failed", err)
 			}
-		}()
+		})
 	})
 }
 
+// setWebSocketState records whether the WebSocket server is serving
+func (ts *TerminalService) setWebSocketState(running bool, err error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.wsRunning = running
+	ts.wsErr = err
+}
+
+// WebSocketStatus reports whether the WebSocket server is bound, and the
+// error if binding or serving failed
+func (ts *TerminalService) WebSocketStatus() (bool, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.wsRunning, ts.wsErr
+}
+
 // HandleWebSocket handles WebSocket connections for terminal sessions
 func (ts *TerminalService) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	defer ts.errorHandler.RecoverGoroutine("websocket handler")
+	
 	// Extract terminal ID from URL path
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) < 4 {
@@ -93,6 +354,16 @@ func (ts *TerminalService) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	}
 	terminalID := pathParts[3]
 	
+	// Only sessions started through the app can be attached to
+	ts.mu.RLock()
+	dir, issued := ts.issued[terminalID]
+	ts.mu.RUnlock()
+	if !issued {
+		ts.logger.Warn(fmt.Sprintf("Rejected WebSocket for unknown terminal: %s", terminalID))
+		http.Error(w, "Unknown terminal", http.StatusNotFound)
+		return
+	}
+	
 	ts.logger.Info(fmt.Sprintf("WebSocket connection for terminal: %s", terminalID))
 	
 	// Upgrade connection to WebSocket
@@ -104,24 +375,18 @@ func (ts *TerminalService) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	defer conn.Close()
 	
 	// Check if terminal already exists (reconnection)
-	ts.mu.Lock()
+	ts.mu.RLock()
 	terminal, exists := ts.terminals[terminalID]
+	ts.mu.RUnlock()
 	if exists {
-		// Reconnect to existing terminal
-		terminal.Conn = conn
 		ts.logger.Info(fmt.Sprintf("Reconnected to existing terminal: %s", terminalID))
-		
-		// Send terminal history to reconnecting client
-		go ts.sendTerminalHistory(terminal)
-	}
-	ts.mu.Unlock()
-	
-	if !exists {
+	} else {
 		// Create new terminal session
 		var err error
-		terminal, err = ts.createTerminal(terminalID, conn)
+		terminal, err = ts.createTerminal(terminalID, dir)
 		if err != nil {
 			ts.logger.Error("Failed to create terminal", err)
+			closeTerminalConn(conn, websocket.CloseInternalServerErr, "failed to start shell")
 			return
 		}
 		
@@ -131,12 +396,84 @@ func (ts *TerminalService) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		ts.mu.Unlock()
 	}
 	
-	// Handle messages
-	ts.handleTerminalMessages(terminal)
+	resume, err := strconv.ParseInt(r.URL.Query().Get("resume"), 10, 64)
+	if err != nil {
+		resume = -1
+	}
+	if !ts.attach(terminal, conn, r.URL.Query().Get("epoch"), resume) {
+		return
+	}
+	
+	// Ping until the connection ends, then handle messages
+	done := make(chan struct{})
+	defer close(done)
+	ts.errorHandler.Go("terminal heartbeat", func() { ts.heartbeat(conn, done) })
+	ts.handleTerminalMessages(terminal, conn)
+}
+
+// attach makes conn the terminal's connection, closing any earlier one,
+// and replays the output the client hasn't seen: from resume on if epoch
+// is the shell's and that output is still kept, all kept output otherwise.
+// It reports whether conn is still usable.
+func (ts *TerminalService) attach(terminal *Terminal, conn *websocket.Conn, epoch string, resume int64) bool {
+	terminal.connMu.Lock()
+	defer terminal.connMu.Unlock()
+	
+	if previous := terminal.Conn; previous != nil && previous != conn {
+		closeTerminalConn(previous, TerminalCloseSuperseded, "another connection took this terminal")
+	}
+	terminal.Conn = conn
+	
+	mode := TerminalReplayReset
+	lines, ends, ok := terminal.Buffer.Since(resume)
+	if ok && resume >= 0 && epoch != "" && epoch == terminal.Epoch {
+		mode = TerminalReplayResume
+	} else if ok {
+		lines, ends, _ = terminal.Buffer.Since(-1)
+	}
+	
+	messages := []TerminalMessage{{Type: "hello", Data: mode, Epoch: terminal.Epoch, Offset: terminal.Buffer.Offset()}}
+	for i, line := range lines {
+		messages = append(messages, TerminalMessage{Type: "history", Data: line, Offset: ends[i]})
+	}
+	for _, message := range messages {
+		if err := writeTerminalMessage(conn, message); err != nil {
+			ts.logger.Error("Failed to send terminal history", err)
+			closeTerminalConn(conn, TerminalCloseWriteFailed, "failed to send history")
+			terminal.Conn = nil
+			return false
+		}
+	}
+	ts.logger.Info(fmt.Sprintf("Sent %d lines of history to terminal %s (%s)", len(lines), terminal.ID, mode))
+	return true
 }
 
-// createTerminal creates a new terminal process with PTY
-func (ts *TerminalService) createTerminal(terminalID string, conn *websocket.Conn) (*Terminal, error) {
+// heartbeat pings conn every terminalPingInterval until done is closed or
+// a ping can't be sent
+func (ts *TerminalService) heartbeat(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(terminalPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(terminalWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// createTerminal creates a new terminal process with PTY, started in dir
+func (ts *TerminalService) createTerminal(terminalID, dir string) (*Terminal, error) {
+	ts.mu.RLock()
+	restriction := ts.restriction
+	ts.mu.RUnlock()
+	if restriction != nil {
+		return ts.createRestrictedTerminal(terminalID, dir, restriction), nil
+	}
+	
 	// Use context for process lifecycle management
 	ctx := ts.ctx
 	if ctx == nil {
@@ -145,6 +482,7 @@ func (ts *TerminalService) createTerminal(terminalID string, conn *websocket.Con
 	
 	// Create a new shell process with context
 	cmd := exec.CommandContext(ctx, "/bin/bash")
+	cmd.Dir = dir
 	
 	// Set restricted environment variables
 	cmd.Env = []string{
@@ -154,6 +492,7 @@ func (ts *TerminalService) createTerminal(terminalID string, conn *websocket.Con
 		"USER=" + os.Getenv("USER"),
 		"LANG=en_US.UTF-8",
 		"SHELL=/bin/bash",
+		"PROMPT_COMMAND=" + commandCaptureHook,
 	}
 	
 	// Start the command with a PTY
@@ -166,39 +505,100 @@ func (ts *TerminalService) createTerminal(terminalID string, conn *websocket.Con
 		ID:     terminalID,
 		Cmd:    cmd,
 		Pty:    ptmx,
-		Conn:   conn,
 		Done:   make(chan bool),
 		Buffer: NewTerminalBuffer(),
+		Epoch:  generateID(),
 	}
+	// Scrollback replayed on reconnect is kept redacted; the live stream isn't
+	ts.mu.RLock()
+	terminal.Buffer.redactor = ts.redactor
+	ts.mu.RUnlock()
 	
 	ts.logger.Info(fmt.Sprintf("Terminal process started for session %s (PID: %d)", terminalID, cmd.Process.Pid))
 	
 	// Start goroutine to read from PTY and send to WebSocket
-	go ts.readFromPty(terminal)
+	ts.errorHandler.Go("pty reader", func() { ts.readFromPty(terminal) })
 	
 	return terminal, nil
 }
 
-// handleTerminalMessages handles the message loop for a terminal session
-func (ts *TerminalService) handleTerminalMessages(terminal *Terminal) {
+// createRestrictedTerminal creates a terminal whose input goes to a
+// restricted console instead of a shell
+func (ts *TerminalService) createRestrictedTerminal(terminalID, dir string, allowlist *commandAllowlist) *Terminal {
+	terminal := &Terminal{
+		ID:     terminalID,
+		Done:   make(chan bool),
+		Buffer: NewTerminalBuffer(),
+		Epoch:  generateID(),
+	}
+	ts.mu.RLock()
+	terminal.Buffer.redactor = ts.redactor
+	ts.mu.RUnlock()
+	
+	terminal.console = &restrictedConsole{
+		dir:          dir,
+		env:          restrictedEnv(),
+		allowlist:    allowlist,
+		output:       func(text string) { ts.sendOutput(terminal, text) },
+		onCommand:    func(record CommandRecord) { ts.recordCommands(terminalID, []CommandRecord{record}) },
+		errorHandler: ts.errorHandler,
+	}
+	terminal.console.Prompt()
+	ts.logger.Info(fmt.Sprintf("Restricted terminal started for session %s", terminalID))
+	return terminal
+}
+
+// handleTerminalMessages handles the message loop for one connection to a
+// terminal session
+func (ts *TerminalService) handleTerminalMessages(terminal *Terminal, conn *websocket.Conn) {
 	defer func() {
-		// Only close the WebSocket connection, keep terminal running
-		if terminal.Conn != nil {
-			terminal.Conn.Close()
+		// Only close the WebSocket connection, keep terminal running. A
+		// connection that was superseded leaves its successor alone.
+		terminal.connMu.Lock()
+		if terminal.Conn == conn {
 			terminal.Conn = nil
 		}
+		terminal.connMu.Unlock()
+		conn.Close()
 		ts.logger.Info(fmt.Sprintf("WebSocket disconnected for terminal %s, terminal continues running", terminal.ID))
 	}()
+	
+	// Pongs and messages both show the client is alive
+	conn.SetReadDeadline(time.Now().Add(terminalPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(terminalPongWait))
+	})
 
 	// Handle WebSocket messages
 	for {
 		var message TerminalMessage
-		err := terminal.Conn.ReadJSON(&message)
+		err := conn.ReadJSON(&message)
 		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				closeTerminalConn(conn, TerminalCloseHeartbeat, "no heartbeat")
+			}
 			ts.logger.Error("Failed to read WebSocket message", err)
 			break
 		}
+		conn.SetReadDeadline(time.Now().Add(terminalPongWait))
 		
+		if message.Type == "ping" {
+			terminal.connMu.Lock()
+			err := writeTerminalMessage(conn, TerminalMessage{Type: "pong"})
+			terminal.connMu.Unlock()
+			if err != nil {
+				break
+			}
+			continue
+		}
+		if message.Type == "resume" {
+			ts.resumeOutput(terminal)
+			continue
+		}
+		if message.Type == "input" && terminal.console != nil {
+			terminal.console.Input(message.Data)
+			continue
+		}
 		if message.Type == "input" {
 			// Write input to PTY
 			_, err := terminal.Pty.Write([]byte(message.Data))
@@ -212,39 +612,179 @@ func (ts *TerminalService) handleTerminalMessages(terminal *Terminal) {
 
 // readFromPty reads output from PTY and sends to WebSocket
 func (ts *TerminalService) readFromPty(terminal *Terminal) {
-	buffer := make([]byte, 1024)
+	buffer := make([]byte, 32*1024)
+	capture := &commandCapture{}
+	titles := &titleCapture{}
 	
 	for {
 		n, err := terminal.Pty.Read(buffer)
 		if err != nil {
 			if err == io.EOF {
 				ts.logger.Info(fmt.Sprintf("Terminal %s process ended", terminal.ID))
+				terminal.connMu.Lock()
+				if terminal.Conn != nil {
+					closeTerminalConn(terminal.Conn, TerminalCloseExited, "terminal exited")
+				}
+				terminal.connMu.Unlock()
 				// Only now actually clean up the terminal since process ended
+				ts.mu.Lock()
 				ts.cleanupTerminal(terminal)
+				ts.mu.Unlock()
 			} else {
 				ts.logger.Error("Failed to read from PTY", err)
 			}
 			break
 		}
 		
-		// Store output in buffer for reconnection
 		outputData := string(buffer[:n])
-		terminal.Buffer.AddLine(outputData)
-		
-		// Send output to WebSocket if still connected
+		ts.recordCommands(terminal.ID, capture.Feed(buffer[:n]))
+		if title, ok := titles.Feed(buffer[:n]); ok {
+			ts.setTitle(terminal.ID, title)
+		}
+		ts.sendOutput(terminal, outputData)
+	}
+}
+
+// sendOutput stores output in the buffer for reconnection and sends it to
+// the connected client, if any, unless the terminal's output is paused
+func (ts *TerminalService) sendOutput(terminal *Terminal, output string) {
+	terminal.connMu.Lock()
+	defer terminal.connMu.Unlock()
+	
+	now := time.Now()
+	switch terminal.throttle.admit(len(output), now) {
+	case throttleSend:
+		ts.deliver(terminal, output)
+	case throttlePause:
+		ts.logger.Info(fmt.Sprintf("Terminal %s output paused at %s/s", terminal.ID, formatBytes(terminalThrottleBytes)))
+		ts.deliver(terminal, "\r\n\x1b[7m output paused: the program is writing faster than the terminal can show \x1b[0m\r\n")
+		ts.notify(terminal, TerminalMessage{Type: "paused", Skipped: int64(len(output))})
+	case throttleSkip:
+		if skipped, ok := terminal.throttle.notice(now); ok {
+			ts.notify(terminal, TerminalMessage{Type: "paused", Skipped: skipped})
+		}
+	}
+}
+
+// resumeOutput lets a paused terminal's output through again, leaving a
+// note of what was skipped in its scrollback
+func (ts *TerminalService) resumeOutput(terminal *Terminal) {
+	terminal.connMu.Lock()
+	defer terminal.connMu.Unlock()
+	
+	skipped, ok := terminal.throttle.resume(time.Now())
+	if !ok {
+		return
+	}
+	ts.deliver(terminal, fmt.Sprintf("\x1b[7m output resumed: %s skipped \x1b[0m\r\n", formatBytes(skipped)))
+	ts.notify(terminal, TerminalMessage{Type: "resumed", Skipped: skipped})
+}
+
+// ResumeOutput lets a terminal's output through again after it was paused
+// for flooding
+func (ts *TerminalService) ResumeOutput(terminalID string) error {
+	terminal, ok := ts.GetTerminal(terminalID)
+	if !ok {
+		return NotFoundError("terminal not found", nil).WithContext("terminalId", terminalID)
+	}
+	ts.resumeOutput(terminal)
+	return nil
+}
+
+// TerminalStats returns each terminal's output statistics, by ID
+func (ts *TerminalService) TerminalStats() []TerminalStats {
+	ts.mu.RLock()
+	stats := make([]TerminalStats, 0, len(ts.terminals))
+	for id, terminal := range ts.terminals {
+		snapshot := terminal.throttle.snapshot()
+		snapshot.TerminalID = id
+		stats = append(stats, snapshot)
+	}
+	ts.mu.RUnlock()
+	
+	sort.Slice(stats, func(i, j int) bool { return stats[i].TerminalID < stats[j].TerminalID })
+	return stats
+}
+
+// deliver keeps output in the buffer and sends it to the connected client.
+// A failed send closes the connection so the client reconnects and resumes
+// from the buffer instead of losing output. Callers hold connMu.
+func (ts *TerminalService) deliver(terminal *Terminal, output string) {
+	offset := terminal.Buffer.AddLine(output)
+	if terminal.Conn == nil {
+		// No client; the terminal keeps running and the output waits in the buffer
+		return
+	}
+	ts.notify(terminal, TerminalMessage{
+		Type:   "output",
+		Data:   output,
+		Offset: offset,
+	})
+}
+
+// notify sends message to the connected client, if any, closing the
+// connection if it can't be sent (callers hold connMu)
+func (ts *TerminalService) notify(terminal *Terminal, message TerminalMessage) {
+	if terminal.Conn == nil {
+		return
+	}
+	if err := writeTerminalMessage(terminal.Conn, message); err != nil {
+		ts.logger.Error("Failed to send terminal output to WebSocket", err)
+		terminal.throttle.failed()
+		closeTerminalConn(terminal.Conn, TerminalCloseWriteFailed, "failed to send output")
+		terminal.Conn = nil
+	}
+}
+
+// CloseConnections ends every terminal connection with TerminalCloseShutdown
+// as the app quits; the shells are stopped with the app's context
+func (ts *TerminalService) CloseConnections() {
+	ts.mu.RLock()
+	terminals := make([]*Terminal, 0, len(ts.terminals))
+	for _, terminal := range ts.terminals {
+		terminals = append(terminals, terminal)
+	}
+	ts.mu.RUnlock()
+	
+	for _, terminal := range terminals {
+		terminal.connMu.Lock()
 		if terminal.Conn != nil {
-			message := TerminalMessage{
-				Type: "output",
-				Data: outputData,
-			}
-			
-			if err := terminal.Conn.WriteJSON(message); err != nil {
-				ts.logger.Error("Failed to send terminal output to WebSocket", err)
-				// Don't break here - just log and continue, WebSocket might reconnect
-				terminal.Conn = nil
-			}
+			closeTerminalConn(terminal.Conn, TerminalCloseShutdown, "app is quitting")
+			terminal.Conn = nil
 		}
-		// If no WebSocket connection, just continue reading (terminal keeps running)
+		terminal.connMu.Unlock()
+	}
+}
+
+// recordCommands hands captured commands to the OnCommand handler
+func (ts *TerminalService) recordCommands(terminalID string, records []CommandRecord) {
+	if len(records) == 0 {
+		return
+	}
+	ts.mu.RLock()
+	record, redactor := ts.onCommand, ts.redactor
+	ts.mu.RUnlock()
+	if record == nil {
+		return
+	}
+	for _, r := range records {
+		r.TerminalID = terminalID
+		r.Command = redactor.Redact(r.Command)
+		record(r)
+	}
+}
+
+// setTitle stores a terminal's title, redacted, and reports it if it changed
+func (ts *TerminalService) setTitle(terminalID, title string) {
+	ts.mu.Lock()
+	title = ts.redactor.Redact(title)
+	changed := ts.titles[terminalID] != title
+	ts.titles[terminalID] = title
+	notify := ts.onTitle
+	ts.mu.Unlock()
+	
+	if changed && notify != nil {
+		notify(terminalID, title)
 	}
 }
 
@@ -266,6 +806,9 @@ func (ts *TerminalService) cleanupTerminal(terminal *Terminal) {
 	if terminal.Pty != nil {
 		terminal.Pty.Close()
 	}
+	if terminal.console != nil {
+		terminal.console.Close()
+	}
 	if terminal.Cmd != nil && terminal.Cmd.Process != nil {
 		terminal.Cmd.Process.Kill()
 	}
@@ -275,38 +818,20 @@ func (ts *TerminalService) cleanupTerminal(terminal *Terminal) {
 	
 	// Remove from active terminals map
 	delete(ts.terminals, terminal.ID)
-	ts.logger.Info(fmt.Sprintf("Terminal %s cleaned up", terminal.ID))
-}
-
-// sendTerminalHistory sends stored terminal history to a reconnecting client
-func (ts *TerminalService) sendTerminalHistory(terminal *Terminal) {
-	if terminal.Conn == nil || terminal.Buffer == nil {
-		return
-	}
-	
-	history := terminal.Buffer.GetHistory()
-	if len(history) == 0 {
-		return
-	}
-	
-	// Send history as a special message type
-	for _, line := range history {
-		// Check if connection is still valid before each send
-		if terminal.Conn == nil {
-			break
-		}
-		
-		message := TerminalMessage{
-			Type: "history",
-			Data: line,
+	delete(ts.issued, terminal.ID)
+	delete(ts.titles, terminal.ID)
+	for name, members := range ts.broadcastGroups {
+		for i, id := range members {
+			if id == terminal.ID {
+				members = append(members[:i], members[i+1:]...)
+				break
+			}
 		}
-		
-		if err := terminal.Conn.WriteJSON(message); err != nil {
-			ts.logger.Error("Failed to send terminal history", err)
-			terminal.Conn = nil
-			break
+		if len(members) == 0 {
+			delete(ts.broadcastGroups, name)
+		} else {
+			ts.broadcastGroups[name] = members
 		}
 	}
-	
-	ts.logger.Info(fmt.Sprintf("Sent %d lines of history to terminal %s", len(history), terminal.ID))
+	ts.logger.Info(fmt.Sprintf("Terminal %s cleaned up", terminal.ID))
 }
\ No newline at end of file
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// The terminal WebSocket protocol
+//
+// A client connects to ws://127.0.0.1:8080/ws/terminal/{id}, optionally
+// with ?epoch=E&resume=N from an earlier connection to the same session.
+// The server answers with a "hello" message carrying the session's epoch
+// and the output offset the client will be at once the replay that follows
+// is done. Data is "resume" when E matched and output from offset N on was
+// still kept: only the "history" messages the client missed follow. It is
+// "reset" otherwise, and the client should clear the screen before the
+// kept scrollback follows. Every "history" and "output" message carries the
+// offset after it; the epoch and the last offset seen are the client's
+// resume token.
+//
+// The server pings every terminalPingInterval and closes the connection
+// with TerminalCloseHeartbeat if nothing, pong or message, arrives within
+// terminalPongWait. Browsers answer pings themselves but don't expose
+// them, so a client watching for a dead server sends {"type":"ping"} and
+// expects {"type":"pong"}.
+//
+// A program that writes more than terminalThrottleBytes within a second
+// has its output paused: the server sends {"type":"paused"} with the bytes
+// skipped so far, again about every second while it lasts, and drops the
+// output until the client sends {"type":"resume"}. It then answers with
+// {"type":"resumed"} and the total skipped. The program keeps running.
+//
+// Clients reconnect after the close codes that say so, backing off from
+// TerminalProtocol.MinBackoff, doubling up to MaxBackoff with jitter, and
+// resetting once a connection has stayed up for StableAfter. A handshake
+// refused with HTTP 404 means the session is gone.
+const (
+	terminalPingInterval = 20 * time.Second
+	terminalPongWait     = 45 * time.Second
+	terminalWriteWait    = 10 * time.Second
+)
+
+// Close codes the terminal server ends connections with
+const (
+	TerminalCloseExited      = websocket.CloseNormalClosure     // the shell exited; don't reconnect
+	TerminalCloseShutdown    = websocket.CloseGoingAway         // the app is quitting; reconnect
+	TerminalCloseWriteFailed = websocket.CloseInternalServerErr // output couldn't be sent; reconnect and resume
+	TerminalCloseSuperseded  = 4000                             // another connection took the session; don't reconnect
+	TerminalCloseHeartbeat   = 4008                             // no pong or message in time; reconnect
+)
+
+// Replay modes of the "hello" message
+const (
+	TerminalReplayResume = "resume"
+	TerminalReplayReset  = "reset"
+)
+
+// TerminalProtocol spells out the reconnect contract for clients
+type TerminalProtocol struct {
+	PingInterval   time.Duration `json:"pingInterval"` // durations are in nanoseconds
+	PongWait       time.Duration `json:"pongWait"`
+	MinBackoff     time.Duration `json:"minBackoff"`
+	MaxBackoff     time.Duration `json:"maxBackoff"`
+	StableAfter    time.Duration `json:"stableAfter"`
+	ReconnectCodes []int         `json:"reconnectCodes"` // close codes to reconnect after; any other means stop
+}
+
+// terminalProtocol is the contract the server keeps
+var terminalProtocol = TerminalProtocol{
+	PingInterval:   terminalPingInterval,
+	PongWait:       terminalPongWait,
+	MinBackoff:     500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	StableAfter:    10 * time.Second,
+	ReconnectCodes: []int{TerminalCloseShutdown, TerminalCloseWriteFailed, TerminalCloseHeartbeat, websocket.CloseAbnormalClosure},
+}
+
+// writeTerminalMessage sends message, giving up after terminalWriteWait
+// (callers hold the terminal's connMu)
+func writeTerminalMessage(conn *websocket.Conn, message TerminalMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(terminalWriteWait))
+	return conn.WriteJSON(message)
+}
+
+// closeTerminalConn ends a connection with code, telling the client why
+func closeTerminalConn(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(terminalWriteWait))
+	conn.Close()
+}
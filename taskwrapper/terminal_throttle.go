@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// terminalThrottleWindow is how often a terminal's output rate is measured
+	terminalThrottleWindow = time.Second
+	// terminalThrottleBytes pauses a terminal's output when more than this
+	// arrives within one window; xterm.js falls behind well before a program
+	// that dumps gigabytes does
+	terminalThrottleBytes = 8 * 1024 * 1024
+)
+
+// Output throttle decisions
+const (
+	throttleSend  = iota // deliver the output
+	throttlePause        // output just paused; deliver the pause notice
+	throttleSkip         // paused; drop the output
+)
+
+// TerminalStats is a terminal's output throughput and what was dropped
+type TerminalStats struct {
+	TerminalID     string `json:"terminalId"`
+	BytesRead      int64  `json:"bytesRead"`      // produced by the shell
+	BytesSent      int64  `json:"bytesSent"`      // delivered to the client or kept for it
+	BytesSkipped   int64  `json:"bytesSkipped"`   // dropped while output was paused
+	DroppedChunks  int64  `json:"droppedChunks"`  // reads dropped while output was paused
+	SendFailures   int64  `json:"sendFailures"`   // writes to the client that failed
+	BytesPerSecond int64  `json:"bytesPerSecond"` // over the last full window
+	PeakPerSecond  int64  `json:"peakPerSecond"`
+	Paused         bool   `json:"paused"`
+	Pauses         int    `json:"pauses"`
+}
+
+// outputThrottle measures a terminal's output and pauses it when a program
+// floods it, until the user resumes
+type outputThrottle struct {
+	mu          sync.Mutex
+	stats       TerminalStats
+	windowStart time.Time
+	windowBytes int64
+	skipped     int64     // bytes dropped since the current pause began
+	lastNotice  time.Time // when the client was last told the skipped count
+}
+
+// admit counts n bytes of output arriving at now and decides what to do
+// with them
+func (ot *outputThrottle) admit(n int, now time.Time) int {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	ot.stats.BytesRead += int64(n)
+	if now.Sub(ot.windowStart) >= terminalThrottleWindow {
+		// A window with no output at all in between reads as zero
+		if now.Sub(ot.windowStart) < 2*terminalThrottleWindow {
+			ot.stats.BytesPerSecond = ot.windowBytes
+		} else {
+			ot.stats.BytesPerSecond = 0
+		}
+		ot.windowStart, ot.windowBytes = now, 0
+	}
+	ot.windowBytes += int64(n)
+	if ot.windowBytes > ot.stats.PeakPerSecond {
+		ot.stats.PeakPerSecond = ot.windowBytes
+	}
+
+	if ot.stats.Paused {
+		ot.skipped += int64(n)
+		ot.stats.BytesSkipped += int64(n)
+		ot.stats.DroppedChunks++
+		return throttleSkip
+	}
+	if ot.windowBytes > terminalThrottleBytes {
+		ot.stats.Paused = true
+		ot.stats.Pauses++
+		ot.skipped = int64(n)
+		ot.stats.BytesSkipped += int64(n)
+		ot.stats.DroppedChunks++
+		ot.lastNotice = now
+		return throttlePause
+	}
+	ot.stats.BytesSent += int64(n)
+	return throttleSend
+}
+
+// notice reports the bytes skipped so far in this pause if the client
+// hasn't been told for a window
+func (ot *outputThrottle) notice(now time.Time) (int64, bool) {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	if !ot.stats.Paused || now.Sub(ot.lastNotice) < terminalThrottleWindow {
+		return 0, false
+	}
+	ot.lastNotice = now
+	return ot.skipped, true
+}
+
+// resume lets output through again, returning the bytes skipped while it
+// was paused; false means it wasn't paused
+func (ot *outputThrottle) resume(now time.Time) (int64, bool) {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	if !ot.stats.Paused {
+		return 0, false
+	}
+	skipped := ot.skipped
+	ot.stats.Paused = false
+	ot.skipped = 0
+	// Start a fresh window so the flood that paused it doesn't pause it again
+	ot.windowStart, ot.windowBytes = now, 0
+	return skipped, true
+}
+
+// failed counts a write to the client that didn't go through
+func (ot *outputThrottle) failed() {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	ot.stats.SendFailures++
+}
+
+// snapshot returns the terminal's statistics so far
+func (ot *outputThrottle) snapshot() TerminalStats {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+	return ot.stats
+}
+
+// formatBytes renders a byte count the way the pause notice shows it
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1024*1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxTitleSequence caps an unterminated title sequence kept between reads
+	maxTitleSequence = 4096
+	// maxTerminalTitle caps a title's length in runes
+	maxTerminalTitle = 200
+)
+
+// TerminalTitleEvent is sent when a shell or the program in it sets the
+// window title, e.g. "vim foo.go"
+type TerminalTitleEvent struct {
+	TerminalID string `json:"terminalId"`
+	Title      string `json:"title"`
+}
+
+// titleCapture follows one terminal's output for OSC 0 and OSC 2, the
+// sequences that set the window title, ended by BEL or ST
+type titleCapture struct {
+	partial []byte // a sequence split across reads
+}
+
+// Feed scans a chunk of terminal output and returns the last title it set
+func (tc *titleCapture) Feed(data []byte) (string, bool) {
+	buf := append(tc.partial, data...)
+	tc.partial = nil
+	title, found := "", false
+	for {
+		start := bytes.Index(buf, []byte("\x1b]"))
+		if start < 0 {
+			if bytes.HasSuffix(buf, []byte("\x1b")) {
+				tc.partial = []byte("\x1b")
+			}
+			return title, found
+		}
+		body := buf[start+2:]
+		if len(body) < 2 {
+			tc.partial = append([]byte(nil), buf[start:]...)
+			return title, found
+		}
+		if (body[0] != '0' && body[0] != '2') || body[1] != ';' {
+			buf = body
+			continue
+		}
+		end, terminator := bytes.IndexByte(body, '\a'), 1
+		if st := bytes.Index(body, []byte("\x1b\\")); st >= 0 && (end < 0 || st < end) {
+			end, terminator = st, 2
+		}
+		if end < 0 {
+			if len(buf)-start <= maxTitleSequence {
+				tc.partial = append([]byte(nil), buf[start:]...)
+			}
+			return title, found
+		}
+		title, found = cleanTerminalTitle(string(body[2:end])), true
+		buf = body[end+terminator:]
+	}
+}
+
+// cleanTerminalTitle turns control characters into spaces and trims a
+// title to maxTerminalTitle runes
+func cleanTerminalTitle(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, title)
+	title = strings.TrimSpace(title)
+	if runes := []rune(title); len(runes) > maxTerminalTitle {
+		title = string(runes[:maxTerminalTitle])
+	}
+	return title
+}
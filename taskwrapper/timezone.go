@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Zones resolve even where the OS has no zone database, such as Windows
+	_ "time/tzdata"
+)
+
+// Times are stored in UTC. The configured time zone only decides how they
+// are shown and reported, and where days and schedule windows begin and end.
+
+// scheduleClockLayout is the format of ScheduleWindow.Start and End
+const scheduleClockLayout = "15:04"
+
+// scheduleDays are the day names a ScheduleWindow accepts, by time.Weekday
+var scheduleDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleWindow is a weekly span of wall-clock time in the configured
+// zone, such as agent hours. Being wall-clock time, 09:00 stays 09:00 across
+// a DST change.
+type ScheduleWindow struct {
+	Days  []string `json:"days,omitempty"` // mon, tue...; empty is every day
+	Start string   `json:"start"`          // HH:MM
+	End   string   `json:"end"`            // HH:MM; before Start for a window past midnight, equal for all day
+}
+
+// TimezoneSettings is the configured zone and the zone it resolves to
+type TimezoneSettings struct {
+	Timezone string `json:"timezone"` // empty follows the system
+	Resolved string `json:"resolved"` // the zone in use, e.g. Europe/Berlin or Local
+	Offset   string `json:"offset"`   // its current UTC offset, e.g. +02:00
+}
+
+// loadTimezone resolves a zone name such as Europe/Berlin; empty is the
+// system's zone
+func loadTimezone(name string) (*time.Location, error) {
+	if strings.TrimSpace(name) == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(strings.TrimSpace(name))
+	if err != nil {
+		return nil, ValidationError("unknown time zone", err).WithContext("timezone", name)
+	}
+	return loc, nil
+}
+
+// validateSchedule checks a window's days and clock times and returns it
+// with its days normalized
+func validateSchedule(w ScheduleWindow) (ScheduleWindow, error) {
+	for _, clock := range []string{w.Start, w.End} {
+		if _, err := time.Parse(scheduleClockLayout, clock); err != nil {
+			return ScheduleWindow{}, ValidationError("schedule times must be HH:MM", err).WithContext("time", clock)
+		}
+	}
+	days := []string{}
+	for _, day := range w.Days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if len(day) > 3 {
+			day = day[:3]
+		}
+		if scheduleDay(day) < 0 {
+			return ScheduleWindow{}, ValidationError("unknown day in schedule", nil).WithContext("day", day)
+		}
+		days = append(days, day)
+	}
+	w.Days = days
+	return w, nil
+}
+
+// scheduleDay returns the weekday of a day name, or -1
+func scheduleDay(name string) time.Weekday {
+	for day, known := range scheduleDays {
+		if known == name {
+			return time.Weekday(day)
+		}
+	}
+	return -1
+}
+
+// clockMinutes is minutes past midnight of an HH:MM time
+func clockMinutes(clock string) int {
+	t, _ := time.Parse(scheduleClockLayout, clock)
+	return t.Hour()*60 + t.Minute()
+}
+
+// onDay reports whether the window opens on day
+func (w ScheduleWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, name := range w.Days {
+		if scheduleDay(name) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether t falls inside the window, read in loc. A window
+// past midnight belongs to the day it opens on.
+func (w ScheduleWindow) Contains(t time.Time, loc *time.Location) bool {
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	start, end := clockMinutes(w.Start), clockMinutes(w.End)
+	today, yesterday := local.Weekday(), (local.Weekday()+6)%7
+	switch {
+	case start == end:
+		return w.onDay(today)
+	case start < end:
+		return w.onDay(today) && now >= start && now < end
+	}
+	return (w.onDay(today) && now >= start) || (w.onDay(yesterday) && now < end)
+}
+
+// Next returns when the window next opens after t, or t itself if it is
+// open. Opening times are built from the wall clock in loc, so they follow
+// DST; one that falls in a spring-forward gap moves to just after it.
+func (w ScheduleWindow) Next(t time.Time, loc *time.Location) time.Time {
+	if w.Contains(t, loc) {
+		return t
+	}
+	local := t.In(loc)
+	start := clockMinutes(w.Start)
+	for days := 0; days <= 7; days++ {
+		open := time.Date(local.Year(), local.Month(), local.Day()+days, start/60, start%60, 0, 0, loc)
+		if open.After(t) && w.onDay(open.Weekday()) {
+			return open
+		}
+	}
+	return t
+}
+
+// String describes the window, e.g. "mon,tue 09:00-17:00"
+func (w ScheduleWindow) String() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+	return fmt.Sprintf("%s %s-%s", days, w.Start, w.End)
+}
+
+// timezoneSettings describes loc as configured by name
+func timezoneSettings(name string, loc *time.Location, now time.Time) TimezoneSettings {
+	return TimezoneSettings{
+		Timezone: name,
+		Resolved: loc.String(),
+		Offset:   now.In(loc).Format("-07:00"),
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/systray"
+)
+
+//go:embed frontend/src/assets/images/logo-universal.png
+var trayIcon []byte
+
+// trayRefreshInterval controls how often the tray title and counts are updated
+const trayRefreshInterval = 10 * time.Second
+
+// TrayService shows a menubar/tray presence with quick actions backed by App
+type TrayService struct {
+	app    *App
+	logger Logger
+	end    func()
+	stop   chan struct{}
+	once   sync.Once
+	ready  atomic.Bool // the menu is built and its clicks are served
+}
+
+// NewTrayService creates a new tray service for the given app
+func NewTrayService(app *App, logger Logger) *TrayService {
+	return &TrayService{
+		app:    app,
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Start registers the tray icon alongside the Wails event loop
+func (ts *TrayService) Start() {
+	start, end := systray.RunWithExternalLoop(ts.onReady, nil)
+	ts.end = end
+	start()
+	ts.logger.Info("System tray started")
+}
+
+// Ready reports whether the tray icon and menu are up, so closing the
+// window can leave the app running there
+func (ts *TrayService) Ready() bool {
+	return ts != nil && ts.ready.Load()
+}
+
+// Stop removes the tray icon and stops background refreshes
+func (ts *TrayService) Stop() {
+	ts.once.Do(func() {
+		ts.ready.Store(false)
+		close(ts.stop)
+		if ts.end != nil {
+			ts.end()
+		}
+	})
+}
+
+// onReady builds the tray menu and leaves serving its click channels to a
+// goroutine: systray only shows the menu once onReady returns
+func (ts *TrayService) onReady() {
+	defer ts.app.errorHandler.RecoverGoroutine("system tray")
+	systray.SetIcon(trayIcon)
+	systray.SetTooltip(AppName)
+
+	messages := ts.app.messages()
+	menu := trayMenu{
+		openBoard: systray.AddMenuItem(messages.T(MsgTrayOpenBoard), messages.T(MsgTrayOpenBoardTip)),
+		reviews:   systray.AddMenuItem(messages.T(MsgTrayPendingReviews), messages.T(MsgTrayPendingReviewsTip)),
+		autoPilot: systray.AddMenuItemCheckbox(messages.T(MsgTrayPauseAutoPilot), messages.T(MsgTrayPauseAutoPilotTip), ts.app.IsAutoPilotPaused()),
+	}
+	systray.AddSeparator()
+	menu.quit = systray.AddMenuItem(messages.T(MsgTrayQuit), messages.T(MsgTrayQuitTip))
+
+	ts.refresh(menu)
+	ts.ready.Store(true)
+	ts.app.errorHandler.Go("system tray", func() { ts.serve(menu) })
+}
+
+// serve handles menu clicks and refreshes the tray until it is stopped
+func (ts *TrayService) serve(menu trayMenu) {
+	defer ts.ready.Store(false)
+	ticker := time.NewTicker(trayRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-menu.openBoard.ClickedCh:
+			ts.app.ShowWindow()
+		case <-menu.reviews.ClickedCh:
+			ts.app.ShowWindow()
+			ts.app.emitEvent(RuntimeNavigate, StatusPendingReview)
+		case <-menu.autoPilot.ClickedCh:
+			ts.app.SetAutoPilotPaused(!ts.app.IsAutoPilotPaused())
+			ts.refresh(menu)
+		case <-menu.quit.ClickedCh:
+			ts.app.Quit()
+			return
+		case <-ticker.C:
+			ts.refresh(menu)
+		case <-ts.stop:
+			return
+		}
+	}
+}
+
+// trayMenu holds the tray's menu items, relabelled on each refresh so a
+// locale change reaches them
+type trayMenu struct {
+	openBoard, reviews, autoPilot, quit *systray.MenuItem
+}
+
+// refresh updates the tray title with busy agents and the review count,
+// the menu labels with the current locale, and the auto-pilot checkbox
+func (ts *TrayService) refresh(menu trayMenu) {
+	busy := 0
+	if status, err := ts.app.agentService.GetAgentStatus(); err == nil {
+		busy = status.BusyCount
+	}
+
+	pending := 0
+	for _, task := range ts.app.taskService.GetTasks() {
+		if task.Status == StatusPendingReview {
+			pending++
+		}
+	}
+
+	if busy > 0 {
+		systray.SetTitle(fmt.Sprintf("%d", busy))
+	} else {
+		systray.SetTitle("")
+	}
+	messages := ts.app.messages()
+	systray.SetTooltip(messages.T(MsgTrayStatus, AppName, busy, pending))
+	menu.openBoard.SetTitle(messages.T(MsgTrayOpenBoard))
+	menu.openBoard.SetTooltip(messages.T(MsgTrayOpenBoardTip))
+	menu.reviews.SetTitle(messages.T(MsgTrayPendingReviewCount, pending))
+	menu.reviews.SetTooltip(messages.T(MsgTrayPendingReviewsTip))
+	menu.autoPilot.SetTitle(messages.T(MsgTrayPauseAutoPilot))
+	menu.autoPilot.SetTooltip(messages.T(MsgTrayPauseAutoPilotTip))
+	// Auto-pilot is also paused and resumed from the board
+	if ts.app.IsAutoPilotPaused() {
+		menu.autoPilot.Check()
+	} else {
+		menu.autoPilot.Uncheck()
+	}
+	menu.quit.SetTitle(messages.T(MsgTrayQuit))
+	menu.quit.SetTooltip(messages.T(MsgTrayQuitTip))
+}
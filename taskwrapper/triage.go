@@ -0,0 +1,141 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// titleTagPattern matches the #tags typed into a new task's title. A tag
+// starts with a letter, so "#12" stays a task reference.
+var titleTagPattern = regexp.MustCompile(`(?:^|\s)#([A-Za-z][\w-]*)`)
+
+// TriageRule routes new tasks carrying a tag
+type TriageRule struct {
+	Tag      string       `json:"tag"`
+	Status   TaskStatus   `json:"status"`             // backlog or todo
+	Priority TaskPriority `json:"priority,omitempty"` // empty keeps the task's priority
+}
+
+// TriageConfig is how a repository triages new tasks, whether created on
+// the board or imported
+type TriageConfig struct {
+	// DefaultPriority is given to tasks created or imported without a
+	// priority; empty is medium
+	DefaultPriority TaskPriority `json:"defaultPriority,omitempty"`
+	// Rules are tried in order; the first whose tag the task has routes it
+	Rules []TriageRule `json:"rules,omitempty"`
+}
+
+// TriageResult is the outcome of RunTriage
+type TriageResult struct {
+	Triaged   []int `json:"triaged"`   // tasks a rule routed this run
+	Untriaged []int `json:"untriaged"` // backlog tasks no rule matches yet
+}
+
+// defaultPriority is the priority of tasks that arrive without one
+func (c TriageConfig) defaultPriority() TaskPriority {
+	if c.DefaultPriority == "" {
+		return PriorityMedium
+	}
+	return c.DefaultPriority
+}
+
+// match returns the first rule for one of task's tags
+func (c TriageConfig) match(task Task) (TriageRule, bool) {
+	for _, rule := range c.Rules {
+		for _, tag := range task.Tags {
+			if normalizeTag(tag) == rule.Tag {
+				return rule, true
+			}
+		}
+	}
+	return TriageRule{}, false
+}
+
+// triage routes an untriaged task by the first matching rule, returning
+// the rule if one matched. Tasks no rule matches stay untriaged, so rules
+// added later still apply to them.
+func (c TriageConfig) triage(task *Task) (TriageRule, bool) {
+	if !untriaged(*task) {
+		return TriageRule{}, false
+	}
+	rule, ok := c.match(*task)
+	if !ok {
+		return TriageRule{}, false
+	}
+	task.Status = rule.Status
+	if rule.Priority != "" {
+		task.Priority = rule.Priority
+	}
+	task.Triaged = true
+	return rule, true
+}
+
+// untriaged reports whether task is waiting in the backlog for a rule
+func untriaged(task Task) bool {
+	return task.Status == StatusBacklog && !task.Triaged
+}
+
+// validateTriage checks a triage config and returns it with its tags normalized
+func validateTriage(c TriageConfig) (TriageConfig, error) {
+	if c.DefaultPriority != "" && !c.DefaultPriority.Valid() {
+		return TriageConfig{}, ValidationError("invalid default priority", nil).
+			WithContext("priority", c.DefaultPriority)
+	}
+	cleaned := TriageConfig{DefaultPriority: c.DefaultPriority, Rules: make([]TriageRule, 0, len(c.Rules))}
+	seen := map[string]bool{}
+	for _, rule := range c.Rules {
+		rule.Tag = normalizeTag(rule.Tag)
+		switch {
+		case rule.Tag == "":
+			return TriageConfig{}, ValidationError("triage rule needs a tag", nil)
+		case seen[rule.Tag]:
+			return TriageConfig{}, ValidationError("duplicate triage rule", nil).WithContext("tag", rule.Tag)
+		case rule.Status != StatusBacklog && rule.Status != StatusTodo:
+			return TriageConfig{}, ValidationError("triage rules route to backlog or todo", nil).
+				WithContext("tag", rule.Tag).WithContext("status", rule.Status)
+		case rule.Priority != "" && !rule.Priority.Valid():
+			return TriageConfig{}, ValidationError("invalid triage rule priority", nil).
+				WithContext("tag", rule.Tag).WithContext("priority", rule.Priority)
+		}
+		seen[rule.Tag] = true
+		cleaned.Rules = append(cleaned.Rules, rule)
+	}
+	return cleaned, nil
+}
+
+// normalizeTag lowercases a tag and drops its leading #
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// normalizeTags normalizes, de-duplicates and sorts tags, dropping empty ones
+func normalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// titleTags takes the #tags out of a new task's title
+func titleTags(title string) (string, []string) {
+	var tags []string
+	for _, match := range titleTagPattern.FindAllStringSubmatch(title, -1) {
+		tags = append(tags, match[1])
+	}
+	if len(tags) == 0 {
+		return title, nil
+	}
+	// A title of nothing but tags keeps them as its text
+	if stripped := strings.Join(strings.Fields(titleTagPattern.ReplaceAllString(title, " ")), " "); stripped != "" {
+		title = stripped
+	}
+	return title, normalizeTags(tags)
+}
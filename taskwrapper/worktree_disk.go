@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// bytesPerGB converts WorktreeConfig.MaxTotalGB to bytes
+const bytesPerGB = 1 << 30
+
+// WorktreeUsage is the disk an agent worktree takes up
+type WorktreeUsage struct {
+	AgentWorktreeState
+	Bytes    int64     `json:"bytes"`
+	LastUsed time.Time `json:"lastUsed"` // newest file modification inside it
+	Clean    bool      `json:"clean"`    // no uncommitted or untracked changes
+	Error    string    `json:"error,omitempty"`
+}
+
+// WorktreeDiskUsage is the agent worktree pool against its budget
+type WorktreeDiskUsage struct {
+	Worktrees   []WorktreeUsage `json:"worktrees"` // least recently used first
+	TotalBytes  int64           `json:"totalBytes"`
+	BudgetBytes int64           `json:"budgetBytes,omitempty"` // 0 means no limit
+	OverBudget  bool            `json:"overBudget"`
+}
+
+// WorktreePrune is what PruneWorktrees removed
+type WorktreePrune struct {
+	Removed    []string `json:"removed"`
+	FreedBytes int64    `json:"freedBytes"`
+	TotalBytes int64    `json:"totalBytes"` // pool size afterwards
+	OverBudget bool     `json:"overBudget"` // still over once every evictable worktree is gone
+}
+
+// WorktreeDiskUsage measures each agent worktree, least recently used first,
+// against the disk budget in worktrees. tasks tell idle worktrees from busy
+// ones.
+func (as *AgentService) WorktreeDiskUsage(tasks []Task, worktrees WorktreeConfig) (WorktreeDiskUsage, error) {
+	listed, err := as.ListWorktrees()
+	if err != nil {
+		return WorktreeDiskUsage{}, err
+	}
+	usage := WorktreeDiskUsage{
+		Worktrees:   []WorktreeUsage{},
+		BudgetBytes: int64(worktrees.MaxTotalGB * bytesPerGB),
+	}
+	for _, state := range agentWorktreeStates(listed, tasks) {
+		worktree := WorktreeUsage{AgentWorktreeState: state}
+		if worktree.Bytes, worktree.LastUsed, err = dirUsage(state.Path); err != nil {
+			worktree.Error = err.Error()
+		} else if worktree.Clean, err = as.WorktreeIsClean(state.Path); err != nil {
+			worktree.Error = err.Error()
+		}
+		usage.TotalBytes += worktree.Bytes
+		usage.Worktrees = append(usage.Worktrees, worktree)
+	}
+	sortWorktreesByUse(usage.Worktrees)
+	usage.OverBudget = usage.BudgetBytes > 0 && usage.TotalBytes > usage.BudgetBytes
+	return usage, nil
+}
+
+// PruneWorktrees removes idle, clean worktrees, least recently used first,
+// until the pool fits the disk budget in worktrees. Worktrees holding a task
+// in doing or review, or with local changes, are never removed.
+func (as *AgentService) PruneWorktrees(tasks []Task, worktrees WorktreeConfig) (WorktreePrune, error) {
+	prune := WorktreePrune{Removed: []string{}}
+	usage, err := as.WorktreeDiskUsage(tasks, worktrees)
+	if err != nil {
+		return prune, err
+	}
+	prune.TotalBytes = usage.TotalBytes
+
+	for _, worktree := range usage.Worktrees {
+		if usage.BudgetBytes == 0 || prune.TotalBytes <= usage.BudgetBytes {
+			break
+		}
+		if !worktree.evictable() {
+			continue
+		}
+		if err := as.RemoveWorktree(worktree.Path); err != nil {
+			as.logger.Error("Failed to prune worktree "+worktree.Path, err)
+			continue
+		}
+		prune.Removed = append(prune.Removed, worktree.Path)
+		prune.FreedBytes += worktree.Bytes
+		prune.TotalBytes -= worktree.Bytes
+	}
+	prune.OverBudget = usage.BudgetBytes > 0 && prune.TotalBytes > usage.BudgetBytes
+	return prune, nil
+}
+
+// dirUsage adds up the size of the files under dir and finds the newest
+// modification time. The worktree's .git link is counted like any file; the
+// object store it points to belongs to the primary checkout.
+func dirUsage(dir string) (int64, time.Time, error) {
+	var total int64
+	var newest time.Time
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		if !d.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, newest, err
+}
+
+// sortWorktreesByUse orders worktrees least recently used first
+func sortWorktreesByUse(worktrees []WorktreeUsage) {
+	sort.SliceStable(worktrees, func(i, j int) bool {
+		return worktrees[i].LastUsed.Before(worktrees[j].LastUsed)
+	})
+}
+
+// evictable reports whether a worktree may be pruned: it holds no task in
+// doing or review and has nothing uncommitted to lose
+func (usage WorktreeUsage) evictable() bool {
+	idle := usage.State == WorktreeIdle || usage.State == WorktreeStale
+	return idle && usage.Clean && usage.Error == ""
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// agentLockFile is the file agent_spawn.sh keeps in a worktree while its
+// agent runs
+const agentLockFile = ".agent_state"
+
+// Why WarmWorktrees couldn't fill the warm pool
+const (
+	WarmSkippedBudget = "budget" // the worktrees are over the disk budget
+	WarmSkippedSlots  = "slots"  // every subagent slot already has a worktree
+)
+
+// WorktreeWarmup is what WarmWorktrees did to the pool of idle worktrees
+type WorktreeWarmup struct {
+	Target    int      `json:"target"`
+	Warm      int      `json:"warm"`      // idle worktrees ready on main afterwards
+	Created   []string `json:"created"`   // worktrees checked out for the pool
+	Refreshed []string `json:"refreshed"` // idle worktrees moved up to main
+	Skipped   string   `json:"skipped,omitempty"`
+}
+
+// WarmWorktrees tops up the pool of idle worktrees checked out on main to
+// the size in worktrees, creating them in free slots up to limit. Idle
+// worktrees already in the pool are moved up to the current main.
+func (as *AgentService) WarmWorktrees(tasks []Task, worktrees WorktreeConfig, limit int) (WorktreeWarmup, error) {
+	warmup := WorktreeWarmup{
+		Target:    worktrees.WarmPool,
+		Created:   []string{},
+		Refreshed: []string{},
+	}
+	if warmup.Target == 0 {
+		return warmup, nil
+	}
+	as.warmMu.Lock()
+	defer as.warmMu.Unlock()
+
+	listed, err := as.ListWorktrees()
+	if err != nil {
+		return warmup, err
+	}
+	for _, state := range agentWorktreeStates(listed, tasks) {
+		if !warmable(state) {
+			continue
+		}
+		if clean, err := as.WorktreeIsClean(state.Path); err != nil || !clean {
+			continue
+		}
+		if err := as.RefreshWorktree(state.Path); err != nil {
+			as.logger.Error("Failed to refresh worktree "+state.Path, err)
+			continue
+		}
+		warmup.Refreshed = append(warmup.Refreshed, state.Path)
+		warmup.Warm++
+	}
+	if warmup.Warm >= warmup.Target {
+		return warmup, nil
+	}
+
+	if worktrees.MaxTotalGB > 0 {
+		usage, err := as.WorktreeDiskUsage(tasks, worktrees)
+		if err != nil {
+			return warmup, err
+		}
+		if usage.OverBudget {
+			warmup.Skipped = WarmSkippedBudget
+			return warmup, nil
+		}
+	}
+	projectRoot := as.GetProjectRoot()
+	for _, slot := range freeWorktreeSlots(projectRoot, listed, limit) {
+		if warmup.Warm >= warmup.Target {
+			break
+		}
+		path := agentWorktreePath(projectRoot, slot)
+		if err := as.AddWorktree(path); err != nil {
+			return warmup, fmt.Errorf("failed to create worktree %s: %v", path, err)
+		}
+		warmup.Created = append(warmup.Created, path)
+		warmup.Warm++
+	}
+	if warmup.Warm < warmup.Target {
+		warmup.Skipped = WarmSkippedSlots
+	}
+	return warmup, nil
+}
+
+// agentWorktreePath returns where agent_spawn.sh keeps subagent slot n: next
+// to the primary checkout, named after it
+func agentWorktreePath(projectRoot string, n int) string {
+	return filepath.Join(filepath.Dir(projectRoot), fmt.Sprintf("%s-subagent%d", filepath.Base(projectRoot), n))
+}
+
+// worktreeLocked reports whether an agent holds the worktree at path
+func worktreeLocked(path string) bool {
+	_, err := os.Stat(filepath.Join(path, agentLockFile))
+	return err == nil
+}
+
+// warmable reports whether a worktree is ready for the spawner to reuse: on
+// a detached HEAD, as agent_spawn.sh leaves it, and not held by an agent
+func warmable(state AgentWorktreeState) bool {
+	return state.State == WorktreeIdle && state.Branch == "" && !worktreeLocked(state.Path)
+}
+
+// freeWorktreeSlots returns the subagent slots up to limit that have no
+// worktree or leftover directory, lowest first
+func freeWorktreeSlots(projectRoot string, worktrees []GitWorktree, limit int) []int {
+	taken := map[string]bool{}
+	for _, worktree := range worktrees {
+		taken[filepath.Clean(worktree.Path)] = true
+	}
+	slots := []int{}
+	for n := 1; n <= limit; n++ {
+		path := agentWorktreePath(projectRoot, n)
+		if taken[path] {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		slots = append(slots, n)
+	}
+	return slots
+}